@@ -179,6 +179,34 @@ func BenchmarkLengthSimilarity(b *testing.B) {
 	})
 }
 
+// BenchmarkShortPairs compares Compute with a reused Session on many short pairs
+func BenchmarkShortPairs(b *testing.B) {
+	original := "The quick brown fox jumps over the lazy dog"
+	augmented := "A quick brown fox jumped over a lazy dog today"
+	ctx := context.Background()
+
+	b.Run("Compute", func(b *testing.B) {
+		ls, _ := word.New(word.WithOptimizedNormalizer())
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_ = ls.Compute(ctx, original, augmented)
+		}
+	})
+
+	b.Run("Session", func(b *testing.B) {
+		ls, _ := word.New(word.WithOptimizedNormalizer())
+		session := ls.NewSession()
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_ = session.Compute(ctx, original, augmented)
+		}
+	})
+}
+
 // BenchmarkStreamingSimilarity benchmarks the streaming similarity implementation
 func BenchmarkStreamingSimilarity(b *testing.B) {
 	// Create text samples
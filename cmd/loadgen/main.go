@@ -0,0 +1,208 @@
+// Command loadgen drives the similarity server or library with configurable
+// concurrency, payload sizes and duration, and reports latency percentiles and
+// error rates. Use it to validate tuning changes before rolling them out.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+	"github.com/valyala/fasthttp"
+)
+
+// Default load settings
+const (
+	DefaultConcurrency = 8
+	DefaultDuration    = 30 * time.Second
+	DefaultPayloadSize = 4 * 1024
+	DefaultTimeout     = 30 * time.Second
+)
+
+// operation performs one comparison and reports whether it failed
+type operation func(ctx context.Context, original, augmented string) error
+
+func main() {
+	target := flag.String("target", "", "Server base URL, e.g. http://localhost:8080 (empty = drive the library in-process)")
+	metric := flag.String("metric", "length", "Metric to exercise: length, character, streaming or efficient")
+	concurrency := flag.Int("concurrency", DefaultConcurrency, "Number of concurrent workers")
+	duration := flag.Duration("duration", DefaultDuration, "How long to generate load")
+	payloadSize := flag.Int("payload-size", DefaultPayloadSize, "Size in bytes of each original text")
+	ratio := flag.Float64("ratio", 0.9, "Augmented text size as a fraction of the original")
+	timeout := flag.Duration("timeout", DefaultTimeout, "Per-request timeout")
+	flag.Parse()
+
+	if *concurrency < 1 || *payloadSize < 1 || *ratio <= 0 {
+		fmt.Fprintln(os.Stderr, "concurrency, payload-size and ratio must be positive")
+		os.Exit(2)
+	}
+
+	var op operation
+	var err error
+	if *target != "" {
+		op, err = serverOperation(*target, *metric, *timeout)
+	} else {
+		op, err = libraryOperation(*metric, *timeout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	original := generateText(*payloadSize)
+	augmented := generateText(int(float64(*payloadSize) * *ratio))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Running %s load against %s: %d workers, %v, %d-byte payloads\n",
+		*metric, describeTarget(*target), *concurrency, *duration, *payloadSize)
+
+	stats := run(ctx, op, *concurrency, original, augmented)
+	stats.report(os.Stdout)
+
+	if stats.errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// run executes op from concurrency workers until ctx is done
+func run(ctx context.Context, op operation, concurrency int, original, augmented string) *stats {
+	results := make([]*stats, concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := range results {
+		results[i] = &stats{}
+		wg.Add(1)
+		go func(s *stats) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				began := time.Now()
+				err := op(ctx, original, augmented)
+				// Requests cut off by the end of the run are not failures
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+				s.record(time.Since(began), err)
+			}
+		}(results[i])
+	}
+
+	wg.Wait()
+	return mergeStats(results, time.Since(start))
+}
+
+// serverOperation posts comparisons to a running server
+func serverOperation(target, metric string, timeout time.Duration) (operation, error) {
+	switch metric {
+	case "length", "character", "streaming", "efficient":
+	default:
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	url := strings.TrimRight(target, "/") + "/" + metric
+	client := &fasthttp.Client{MaxConnsPerHost: 4096}
+
+	return func(ctx context.Context, original, augmented string) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		req.SetRequestURI(url)
+		req.Header.SetMethod(fasthttp.MethodPost)
+		req.Header.SetContentType("application/json")
+		req.SetBody(encodeRequest(original, augmented))
+
+		if err := client.DoTimeout(req, resp, timeout); err != nil {
+			return err
+		}
+		if resp.StatusCode() != fasthttp.StatusOK {
+			return fmt.Errorf("status %d", resp.StatusCode())
+		}
+		return nil
+	}, nil
+}
+
+// libraryOperation calls the library in-process
+func libraryOperation(metric string, timeout time.Duration) (operation, error) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		return nil, err
+	}
+
+	withTimeout := func(fn func(ctx context.Context, original, augmented string) error) operation {
+		return func(ctx context.Context, original, augmented string) error {
+			c, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return fn(c, original, augmented)
+		}
+	}
+
+	switch metric {
+	case "length":
+		ls, err := word.New(word.WithLogger(lg), word.WithFastNormalizer())
+		if err != nil {
+			return nil, err
+		}
+		return withTimeout(func(ctx context.Context, original, augmented string) error {
+			return detailsError(ls.Compute(ctx, original, augmented).Details)
+		}), nil
+	case "character":
+		cs, err := character.NewCharacterSimilarity(character.WithLogger(lg), character.WithOptimizedNormalizer())
+		if err != nil {
+			return nil, err
+		}
+		return withTimeout(func(ctx context.Context, original, augmented string) error {
+			return detailsError(cs.Compute(ctx, original, augmented).Details)
+		}), nil
+	case "streaming":
+		ss, err := streaming.NewStreamingSimilarity(streaming.WithStreamingLogger(lg), streaming.WithOptimizedNormalizer())
+		if err != nil {
+			return nil, err
+		}
+		return withTimeout(func(ctx context.Context, original, augmented string) error {
+			return detailsError(ss.ComputeFromStrings(ctx, original, augmented).Details)
+		}), nil
+	case "efficient":
+		es, err := streaming.NewAllocationEfficientStreamingSimilarity(streaming.WithEfficientLogger(lg), streaming.WithEfficientParallel(true))
+		if err != nil {
+			return nil, err
+		}
+		return withTimeout(func(ctx context.Context, original, augmented string) error {
+			return detailsError(es.ComputeFromStrings(ctx, original, augmented).Details)
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+}
+
+// detailsError turns an "error" entry in result details into an error
+func detailsError(details map[string]interface{}) error {
+	if msg, ok := details["error"]; ok {
+		return fmt.Errorf("%v", msg)
+	}
+	return nil
+}
+
+// describeTarget names the load target for the banner
+func describeTarget(target string) string {
+	if target == "" {
+		return "the in-process library"
+	}
+	return target
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxErrorSamples bounds the distinct error messages kept per run
+const maxErrorSamples = 5
+
+// stats collects latencies and errors for one worker, then for the whole run
+type stats struct {
+	latencies []time.Duration
+	errors    int
+	samples   map[string]int
+	elapsed   time.Duration
+}
+
+// record adds one completed operation
+func (s *stats) record(latency time.Duration, err error) {
+	s.latencies = append(s.latencies, latency)
+	if err == nil {
+		return
+	}
+
+	s.errors++
+	if s.samples == nil {
+		s.samples = make(map[string]int)
+	}
+	if _, seen := s.samples[err.Error()]; seen || len(s.samples) < maxErrorSamples {
+		s.samples[err.Error()]++
+	}
+}
+
+// mergeStats combines per-worker stats into a sorted run summary
+func mergeStats(workers []*stats, elapsed time.Duration) *stats {
+	total := &stats{elapsed: elapsed, samples: make(map[string]int)}
+	for _, w := range workers {
+		total.latencies = append(total.latencies, w.latencies...)
+		total.errors += w.errors
+		for msg, n := range w.samples {
+			if _, seen := total.samples[msg]; seen || len(total.samples) < maxErrorSamples {
+				total.samples[msg] += n
+			}
+		}
+	}
+
+	sort.Slice(total.latencies, func(i, j int) bool { return total.latencies[i] < total.latencies[j] })
+	return total
+}
+
+// percentile returns the latency at quantile q (0-1) of the sorted latencies
+func (s *stats) percentile(q float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	idx := int(q*float64(len(s.latencies))+0.5) - 1
+	idx = max(0, min(idx, len(s.latencies)-1))
+	return s.latencies[idx]
+}
+
+// report prints a human-readable summary
+func (s *stats) report(w io.Writer) {
+	n := len(s.latencies)
+	throughput := 0.0
+	errorRate := 0.0
+	if s.elapsed > 0 {
+		throughput = float64(n) / s.elapsed.Seconds()
+	}
+	if n > 0 {
+		errorRate = float64(s.errors) / float64(n) * 100
+	}
+
+	fmt.Fprintf(w, "\nRequests:   %d in %v (%.1f req/s)\n", n, s.elapsed.Round(time.Millisecond), throughput)
+	fmt.Fprintf(w, "Errors:     %d (%.2f%%)\n", s.errors, errorRate)
+	fmt.Fprintf(w, "Latency:    p50 %v  p90 %v  p99 %v  max %v\n",
+		s.percentile(0.50), s.percentile(0.90), s.percentile(0.99), s.percentile(1))
+
+	if len(s.samples) > 0 {
+		fmt.Fprintln(w, "Error samples:")
+		for msg, count := range s.samples {
+			fmt.Fprintf(w, "  %6d  %s\n", count, msg)
+		}
+	}
+}
+
+// encodeRequest builds the JSON body for a server comparison
+func encodeRequest(original, augmented string) []byte {
+	body, _ := json.Marshal(map[string]string{"original": original, "augmented": augmented})
+	return body
+}
+
+// generateText returns roughly size bytes of English-like text
+func generateText(size int) string {
+	const sample = "The quick brown fox jumps over the lazy dog while the patient reviewer reads every line twice. "
+
+	var sb strings.Builder
+	sb.Grow(size + len(sample))
+	for sb.Len() < size {
+		sb.WriteString(sample)
+	}
+	return sb.String()[:size]
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMergeStatsPercentiles(t *testing.T) {
+	a, b := &stats{}, &stats{}
+	for i := 1; i <= 100; i++ {
+		w := a
+		if i%2 == 0 {
+			w = b
+		}
+		var err error
+		if i%10 == 0 {
+			err = errors.New("status 500")
+		}
+		w.record(time.Duration(i)*time.Millisecond, err)
+	}
+
+	total := mergeStats([]*stats{a, b}, time.Second)
+	if total.errors != 10 || total.samples["status 500"] != 10 {
+		t.Fatalf("errors = %d, samples = %v", total.errors, total.samples)
+	}
+
+	for q, want := range map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.99: 99 * time.Millisecond, 1: 100 * time.Millisecond} {
+		if got := total.percentile(q); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", q, got, want)
+		}
+	}
+}
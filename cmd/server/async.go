@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMaxBatchItems caps the number of comparisons in one /batch request
+const DefaultMaxBatchItems = 1000
+
+// maxBatchItems is configured from flags in main
+var maxBatchItems = DefaultMaxBatchItems
+
+// fingerprintHasher fingerprints request bodies for Idempotency-Key checks; set from the -hash flag
+var fingerprintHasher ports.Hasher = hasher.Default()
+
+// JobRequest submits one comparison for async processing
+type JobRequest struct {
+	Request
+	Metric string `json:"metric"`
+	// WebhookURL receives the finished job as a signed POST
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// BatchItem is one comparison within a batch
+type BatchItem struct {
+	Request
+	ID string `json:"id,omitempty"`
+}
+
+// BatchRequest runs several comparisons with the same metric
+type BatchRequest struct {
+	Metric string      `json:"metric"`
+	Items  []BatchItem `json:"items"`
+}
+
+// BatchItemResult is the outcome of one batch item
+type BatchItemResult struct {
+	ID     string    `json:"id,omitempty"`
+	Result *Response `json:"result,omitempty"`
+	Error  *APIError `json:"error,omitempty"`
+}
+
+// BatchResponse holds the outcomes of a batch in request order
+type BatchResponse struct {
+	Metric  string            `json:"metric"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// noStoreKey is the user value a handler sets to keep withIdempotency from
+// storing a response that a retry could improve on
+const noStoreKey = "idempotency-no-store"
+
+// withIdempotency runs handler at most once per Idempotency-Key and replays its
+// stored response for retries with the same key and body. Server errors, 429
+// rejections and responses marked with noStoreKey are not stored, so they can be retried.
+func withIdempotency(ctx *fasthttp.RequestCtx, handler func(ctx *fasthttp.RequestCtx)) {
+	key := string(ctx.Request.Header.Peek("Idempotency-Key"))
+	if key == "" {
+		handler(ctx)
+		return
+	}
+
+	// Keys are scoped to the endpoint they were first used on
+	scopedKey := string(ctx.Path()) + "\x00" + key
+	fingerprint := string(fingerprintHasher.Sum(append(append([]byte(nil), ctx.Method()...), ctx.PostBody()...)))
+
+	if entry := jobs.reserveKey(scopedKey, fingerprint); entry != nil {
+		switch {
+		case entry.fingerprint != fingerprint:
+			writeAPIError(ctx, newAPIError(fasthttp.StatusUnprocessableEntity, ErrCodeIdempotencyReuse,
+				"Idempotency-Key was already used with a different request", "Idempotency-Key"))
+		case !entry.done:
+			writeAPIError(ctx, newAPIError(fasthttp.StatusConflict, ErrCodeIdempotencyBusy,
+				"A request with this Idempotency-Key is still being processed", "Idempotency-Key"))
+		default:
+			ctx.Response.Header.Set("Idempotent-Replayed", "true")
+			ctx.SetStatusCode(entry.status)
+			ctx.SetBody(entry.body)
+		}
+		return
+	}
+
+	handler(ctx)
+
+	status := ctx.Response.StatusCode()
+	if status < fasthttp.StatusInternalServerError && status != fasthttp.StatusTooManyRequests && ctx.UserValue(noStoreKey) == nil {
+		jobs.completeKey(scopedKey, status, ctx.Response.Body())
+	} else {
+		jobs.releaseKey(scopedKey)
+	}
+}
+
+// handleBatch handles synchronous batch requests
+func handleBatch(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	withIdempotency(ctx, func(ctx *fasthttp.RequestCtx) {
+		var req BatchRequest
+		if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+			writeAPIError(ctx, errInvalidJSON(err))
+			return
+		}
+
+		budget, ok := metricDeadline(req.Metric)
+		if !ok {
+			writeAPIError(ctx, errUnknownMetric(req.Metric))
+			return
+		}
+		if len(req.Items) == 0 {
+			writeAPIError(ctx, newAPIError(fasthttp.StatusBadRequest, ErrCodeMissingField, "items must not be empty", "items"))
+			return
+		}
+		if len(req.Items) > maxBatchItems {
+			writeAPIError(ctx, newAPIError(fasthttp.StatusRequestEntityTooLarge, ErrCodeBatchTooLarge,
+				"batch exceeds "+strconv.Itoa(maxBatchItems)+" items", "items"))
+			return
+		}
+
+		// The whole batch shares the metric's deadline budget
+		c, cancel := requestContext(ctx, budget)
+		defer cancel()
+
+		response := BatchResponse{Metric: req.Metric, Results: make([]BatchItemResult, len(req.Items))}
+		if !runBatch(c, req.Metric, req.Items, response.Results) {
+			// Items that ran out of time may finish on a retry
+			ctx.SetUserValue(noStoreKey, true)
+		}
+
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		writeJSONResponse(ctx, response)
+	})
+}
+
+// runBatch computes every item into results, spreading the items over workers
+// reserved from the shared parallelism budget. An item that produced no score
+// gets an error instead of a result. It returns false if any item ran out of time.
+func runBatch(c context.Context, metric string, items []BatchItem, results []BatchItemResult) bool {
+	workers, release := parallel.Acquire(min(len(items), parallel.DefaultWorkers()))
+	defer release()
+
+	var next atomic.Int64
+	var timedOut atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(items) {
+					return
+				}
+
+				results[i].ID = items[i].ID
+				if apiErr := items[i].validate(); apiErr != nil {
+					results[i].Error = apiErr
+					continue
+				}
+				result := computeMetric(c, metric, items[i].Request, nil)
+				if apiErr := computeError(c, result); apiErr != nil {
+					results[i].Error = apiErr
+					if apiErr.Code == ErrCodeDeadline {
+						timedOut.Store(true)
+					}
+					continue
+				}
+				results[i].Result = &result
+			}
+		}()
+	}
+	wg.Wait()
+	return !timedOut.Load()
+}
+
+// handleJobs handles async job submission
+func handleJobs(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	withIdempotency(ctx, func(ctx *fasthttp.RequestCtx) {
+		var req JobRequest
+		if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+			writeAPIError(ctx, errInvalidJSON(err))
+			return
+		}
+
+		budget, ok := metricDeadline(req.Metric)
+		if !ok {
+			writeAPIError(ctx, errUnknownMetric(req.Metric))
+			return
+		}
+		if apiErr := req.validate(); apiErr != nil {
+			writeAPIError(ctx, apiErr)
+			return
+		}
+		if apiErr := webhooks.validateURL(req.WebhookURL); apiErr != nil {
+			writeAPIError(ctx, apiErr)
+			return
+		}
+
+		if !jobs.acquireSlot() {
+			writeAPIError(ctx, newAPIError(fasthttp.StatusTooManyRequests, ErrCodeTooManyJobs,
+				"too many jobs are running; retry later", ""))
+			return
+		}
+
+		// Jobs outlive the request, so their deadline starts when they are accepted
+		c, cancel := context.WithTimeout(context.Background(), budget)
+		tracker := newProgressTracker(req.Request)
+		job := jobs.create(req.Metric, cancel, tracker)
+		go runJob(c, cancel, job.ID, req, tracker)
+
+		ctx.Response.Header.Set("Location", "/jobs/"+job.ID)
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+		writeJSONResponse(ctx, job)
+	})
+}
+
+// runJob computes an async job and records its outcome, then frees its slot.
+// A cancelled or failed job records only the progress made before it stopped;
+// a score from partial counts is not a result.
+func runJob(c context.Context, cancel context.CancelFunc, id string, req JobRequest, tracker *progressTracker) {
+	defer cancel()
+	defer jobs.releaseSlot()
+
+	jobs.update(id, func(job *Job) {
+		if job.Status == JobPending {
+			job.Status = JobRunning
+		}
+	})
+
+	result := computeMetric(c, req.Metric, req.Request, tracker)
+
+	jobs.update(id, func(job *Job) {
+		job.Progress = tracker.snapshot()
+		switch err := c.Err(); {
+		case job.Status == JobCancelled:
+			// Keep the cancellation requested via DELETE
+		case err != nil:
+			job.Status = JobFailed
+			job.Error = newAPIError(fasthttp.StatusGatewayTimeout, ErrCodeDeadline, "job did not finish: "+err.Error(), "")
+		default:
+			job.Status = JobSucceeded
+			job.Result = &result
+		}
+	})
+
+	if job, ok := jobs.get(id); ok {
+		webhooks.notify(req.WebhookURL, job)
+	}
+}
+
+// handleJob returns the state of one async job
+func handleJob(ctx *fasthttp.RequestCtx) {
+	id := strings.TrimPrefix(string(ctx.Path()), "/jobs/")
+
+	switch {
+	case ctx.IsGet():
+		job, ok := jobs.get(id)
+		if !ok {
+			writeAPIError(ctx, errNotFound())
+			return
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		writeJSONResponse(ctx, job)
+	case ctx.IsDelete():
+		job, ok := jobs.cancel(id)
+		if !ok {
+			writeAPIError(ctx, errNotFound())
+			return
+		}
+		if job.Status != JobCancelled {
+			writeAPIError(ctx, newAPIError(fasthttp.StatusConflict, ErrCodeJobFinished,
+				"job already finished with status "+string(job.Status), ""))
+			return
+		}
+		// The runner records final progress once the engine observes the cancellation
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+		writeJSONResponse(ctx, job)
+	default:
+		writeAPIError(ctx, errMethodNotAllowed())
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newPostCtx(path, key, body string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI(path)
+	ctx.Request.Header.Set("Idempotency-Key", key)
+	ctx.Request.SetBodyString(body)
+	return ctx
+}
+
+func TestWithIdempotencyReplaysResponse(t *testing.T) {
+	jobs = newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+
+	calls := 0
+	handler := func(ctx *fasthttp.RequestCtx) {
+		calls++
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+		ctx.SetBodyString(`{"id":"first"}`)
+	}
+
+	first := newPostCtx("/jobs", "k1", `{"metric":"length"}`)
+	withIdempotency(first, handler)
+
+	retry := newPostCtx("/jobs", "k1", `{"metric":"length"}`)
+	withIdempotency(retry, handler)
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if got := string(retry.Response.Body()); got != `{"id":"first"}` || retry.Response.StatusCode() != fasthttp.StatusAccepted {
+		t.Errorf("replay = %d %s, want 202 with the first body", retry.Response.StatusCode(), got)
+	}
+	if string(retry.Response.Header.Peek("Idempotent-Replayed")) != "true" {
+		t.Error("replay missing Idempotent-Replayed header")
+	}
+
+	// Same key, different body
+	mismatch := newPostCtx("/jobs", "k1", `{"metric":"character"}`)
+	withIdempotency(mismatch, handler)
+	if mismatch.Response.StatusCode() != fasthttp.StatusUnprocessableEntity {
+		t.Errorf("mismatched body status = %d, want 422", mismatch.Response.StatusCode())
+	}
+
+	// Same key on another endpoint is independent
+	other := newPostCtx("/batch", "k1", `{"metric":"length"}`)
+	withIdempotency(other, handler)
+	if calls != 2 {
+		t.Errorf("handler ran %d times after other endpoint, want 2", calls)
+	}
+}
+
+func TestWithIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	jobs = newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+
+	calls := 0
+	handler := func(ctx *fasthttp.RequestCtx) {
+		calls++
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	}
+
+	withIdempotency(newPostCtx("/batch", "k2", `{}`), handler)
+	withIdempotency(newPostCtx("/batch", "k2", `{}`), handler)
+
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2 after a server error", calls)
+	}
+}
+
+func TestJobStoreCancel(t *testing.T) {
+	store := newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+
+	cancelled := false
+	job := store.create(MetricStreaming, func() { cancelled = true }, newProgressTracker(Request{Original: "abc", Augmented: "de"}))
+
+	got, ok := store.cancel(job.ID)
+	if !ok || got.Status != JobCancelled || !cancelled {
+		t.Fatalf("cancel = %+v, %v (cancel called %v); want cancelled", got, ok, cancelled)
+	}
+	if got, _ := store.get(job.ID); got.Progress == nil || got.Progress.OriginalBytes != 3 {
+		t.Errorf("cancelled job progress = %+v, want original size 3", got.Progress)
+	}
+
+	done := store.create(MetricLength, func() {}, nil)
+	store.update(done.ID, func(job *Job) { job.Status = JobSucceeded })
+	if got, _ := store.cancel(done.ID); got.Status != JobSucceeded {
+		t.Errorf("cancel finished job status = %s, want %s", got.Status, JobSucceeded)
+	}
+
+	if _, ok := store.cancel("missing"); ok {
+		t.Error("cancel missing job: want not found")
+	}
+}
+
+func TestHandleJobsRejectsWhenFull(t *testing.T) {
+	useBulkCalculators(t)
+	jobs = newJobStore(DefaultJobTTL, 1)
+	t.Cleanup(func() { jobs = newJobStore(DefaultJobTTL, DefaultMaxRunningJobs) })
+
+	body := `{"metric":"length","original":"one two three four","augmented":"one two three"}`
+	if !jobs.acquireSlot() {
+		t.Fatal("acquireSlot on an empty store failed")
+	}
+	full := newPostCtx("/jobs", "k3", body)
+	handleJobs(full)
+	if full.Response.StatusCode() != fasthttp.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 with every slot taken", full.Response.StatusCode())
+	}
+	jobs.releaseSlot()
+
+	// The rejection was not stored, so a retry with the same key runs
+	retry := newPostCtx("/jobs", "k3", body)
+	handleJobs(retry)
+	if retry.Response.StatusCode() != fasthttp.StatusAccepted {
+		t.Fatalf("retry status = %d, want 202", retry.Response.StatusCode())
+	}
+	var job Job
+	if err := json.Unmarshal(retry.Response.Body(), &job); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := jobs.get(job.ID)
+		if got.Status == JobSucceeded && got.Result != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v, want it to succeed with a result", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The finished job gave its slot back
+	if !jobs.acquireSlot() {
+		t.Error("slot not released after the job finished")
+	}
+}
+
+func TestRunJobOmitsResultWhenStopped(t *testing.T) {
+	useBulkCalculators(t)
+	jobs = newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+
+	req := JobRequest{Request: Request{Original: "one two three four", Augmented: "one two three"}, Metric: MetricLength}
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	tracker := newProgressTracker(req.Request)
+	job := jobs.create(req.Metric, cancel, tracker)
+
+	jobs.acquireSlot()
+	runJob(c, cancel, job.ID, req, tracker)
+
+	got, _ := jobs.get(job.ID)
+	if got.Status != JobFailed || got.Result != nil || got.Error == nil || got.Progress == nil {
+		t.Errorf("stopped job = %+v, want failed with an error, progress and no result", got)
+	}
+}
+
+func TestHandleBatchReportsTimedOutItems(t *testing.T) {
+	useBulkCalculators(t)
+	set, err := newCalculatorSet(CalculatorConfig{Deadlines: MetricDeadlines{Efficient: time.Nanosecond}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+	jobs = newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+
+	body := `{"metric":"efficient","items":[{"id":"a","original":"one\ntwo\n","augmented":"one\n"}]}`
+	for attempt := 0; attempt < 2; attempt++ {
+		ctx := newPostCtx("/batch", "k4", body)
+		handleBatch(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("status = %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		// A response holding timed-out items is not stored, so the retry runs again
+		if ctx.Response.Header.Peek("Idempotent-Replayed") != nil {
+			t.Fatalf("attempt %d replayed a response with timed-out items", attempt)
+		}
+		var response BatchResponse
+		if err := json.Unmarshal(ctx.Response.Body(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if item := response.Results[0]; item.Result != nil || item.Error == nil || item.Error.Code != ErrCodeDeadline {
+			t.Errorf("timed-out item = %+v, want a %s error and no result", item, ErrCodeDeadline)
+		}
+	}
+
+	results := make([]BatchItemResult, 1)
+	items := []BatchItem{{ID: "b", Request: Request{Original: "one two three four", Augmented: "one two three"}}}
+	if ok := runBatch(context.Background(), MetricLength, items, results); !ok || results[0].Result == nil || results[0].Error != nil {
+		t.Errorf("runBatch = %v, %+v; want a result for an item with time left", ok, results[0])
+	}
+}
+
+func TestJobStoreWaitsForRunningJobs(t *testing.T) {
+	store := newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+	if !store.acquireSlot() {
+		t.Fatal("acquireSlot on an empty store failed")
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		store.wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("wait returned while a job was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	store.releaseSlot()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after the job finished")
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/pkg/bulk"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+// bulkFlushEvery is how many records the bulk JSONL output buffers between flushes
+const bulkFlushEvery = 1000
+
+// bulkMaxFieldSize is the largest bulk field accepted, set from -max-request-size
+var bulkMaxFieldSize = DefaultMaxRequestSize
+
+// bulkSummary is written back on a bulk connection once its stream ends
+type bulkSummary struct {
+	Records int64  `json:"records"`
+	Errors  int64  `json:"errors"`
+	Error   string `json:"error,omitempty"`
+}
+
+// openBulkSink opens -bulk-output: a JSONL file, a Parquet file when the
+// name ends in .parquet, or standard output for "-"
+func openBulkSink(path string) (sink.ResultSink, error) {
+	if path == "-" {
+		return sink.NewJSONLSink(os.Stdout, sink.WithFlushEvery(bulkFlushEvery)), nil
+	}
+	if strings.EqualFold(filepath.Ext(path), ".parquet") {
+		return sink.NewParquetFileSink(path)
+	}
+	return sink.NewJSONLFileSink(path, sink.WithFlushEvery(bulkFlushEvery))
+}
+
+// bulkRecord computes one pair under its metric's deadline. A pair that
+// cannot be computed, runs out of time or is cancelled becomes a record whose
+// details hold the error and code, and is reported as not ok.
+func bulkRecord(c context.Context, pair bulk.Pair) (sink.Record, bool) {
+	deadline, ok := metricDeadline(pair.Metric)
+	if !ok {
+		return bulkErrorRecord(pair, errUnknownMetric(pair.Metric)), false
+	}
+	if apiErr := validateTexts(pair.Original == "", pair.Augmented == ""); apiErr != nil {
+		return bulkErrorRecord(pair, apiErr), false
+	}
+
+	c, cancel := context.WithTimeout(c, deadline)
+	defer cancel()
+	response := computeMetric(c, pair.Metric, Request{Original: pair.Original, Augmented: pair.Augmented}, nil)
+	if apiErr := computeError(c, response); apiErr != nil {
+		return bulkErrorRecord(pair, apiErr), false
+	}
+	return sink.Record{
+		ID:              pair.ID,
+		Metric:          pair.Metric,
+		Engine:          response.Engine,
+		Mode:            response.Mode,
+		Score:           response.Score,
+		Passed:          response.Passed,
+		OriginalLength:  response.OriginalLength,
+		AugmentedLength: response.AugmentedLength,
+		LengthRatio:     response.LengthRatio,
+		Threshold:       response.Threshold,
+		BytesProcessed:  response.BytesProcessed,
+		ProcessingTime:  response.ProcessingTime,
+		Details:         response.Details,
+		Timestamp:       time.Now(),
+	}, true
+}
+
+// bulkErrorRecord reports a pair that was rejected or produced no score
+func bulkErrorRecord(pair bulk.Pair, apiErr *APIError) sink.Record {
+	return sink.Record{
+		ID:        pair.ID,
+		Metric:    pair.Metric,
+		Details:   map[string]interface{}{"error": apiErr.Message, "code": apiErr.Code},
+		Timestamp: time.Now(),
+	}
+}
+
+// serveBulk computes every pair read from r into out, spreading the pairs
+// over workers reserved from the shared parallelism budget. Records are
+// written as they finish, so their order follows completion, not input.
+func serveBulk(c context.Context, r io.Reader, out sink.ResultSink) bulkSummary {
+	c, cancel := context.WithCancel(c)
+	defer cancel()
+
+	workers, release := parallel.Acquire(parallel.DefaultWorkers())
+	defer release()
+
+	var summary bulkSummary
+	var records, failed atomic.Int64
+	var sinkErr error
+	var sinkErrOnce sync.Once
+
+	pairs := make(chan bulk.Pair, workers*4)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pair := range pairs {
+				rec, ok := bulkRecord(c, pair)
+				if err := out.Write(c, rec); err != nil {
+					sinkErrOnce.Do(func() {
+						sinkErr = err
+						cancel()
+					})
+					continue
+				}
+				records.Add(1)
+				if !ok {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+
+	reader := bulk.NewReader(r, bulkMaxFieldSize)
+	var readErr error
+	for c.Err() == nil {
+		pair, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+		pairs <- pair
+	}
+	close(pairs)
+	wg.Wait()
+
+	summary.Records, summary.Errors = records.Load(), failed.Load()
+	switch {
+	case sinkErr != nil:
+		summary.Error = "writing results: " + sinkErr.Error()
+	case c.Err() != nil:
+		// A cancelled stream may also have had its read interrupted; report the cause
+		summary.Error = c.Err().Error()
+	case readErr != nil:
+		summary.Error = readErr.Error()
+	}
+	return summary
+}
+
+// runBulkFile processes a bulk stream from path, or standard input for "-"
+func runBulkFile(c context.Context, path string, out sink.ResultSink) bulkSummary {
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return bulkSummary{Error: err.Error()}
+		}
+		defer f.Close()
+		in = f
+	}
+	return serveBulk(c, in, out)
+}
+
+// serveBulkListener accepts bulk connections until ln is closed, then waits
+// for the open connections to finish. Each connection sends one stream and,
+// once it has been computed, receives a bulkSummary as a line of JSON.
+// Cancelling c ends the open streams early, recording their unfinished pairs
+// as errors.
+func serveBulkListener(c context.Context, ln net.Listener, out sink.ResultSink) error {
+	var conns sync.WaitGroup
+	defer conns.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			defer conn.Close()
+			// Interrupt a read from a client that has gone quiet once c is cancelled
+			stop := context.AfterFunc(c, func() { conn.SetReadDeadline(time.Now()) })
+			defer stop()
+			summary := serveBulk(c, conn, out)
+			if err := out.Flush(); err != nil && summary.Error == "" {
+				summary.Error = "writing results: " + err.Error()
+			}
+			logger.Info("Bulk stream finished",
+				"remote", conn.RemoteAddr().String(),
+				"records", summary.Records,
+				"errors", summary.Errors,
+				"error", summary.Error,
+			)
+			json.NewEncoder(conn).Encode(summary)
+		}()
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/bulk"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+	"github.com/baditaflorin/l"
+)
+
+// useBulkCalculators installs default calculators and a silent logger for a test
+func useBulkCalculators(t *testing.T) {
+	t.Helper()
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = lg
+	set, err := newCalculatorSet(CalculatorConfig{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+	t.Cleanup(func() { logger = nil; calculators.Store(nil) })
+}
+
+// encodeBulk writes pairs as a bulk stream
+func encodeBulk(t *testing.T, pairs ...bulk.Pair) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := bulk.NewWriter(&buf)
+	for _, p := range pairs {
+		if err := w.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// collectRecords reads back what a JSONL sink wrote, keyed by ID
+func collectRecords(t *testing.T, data []byte) map[string]sink.Record {
+	t.Helper()
+	records, err := sink.ReadJSONL(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]sink.Record, len(records))
+	for _, rec := range records {
+		byID[rec.ID] = rec
+	}
+	return byID
+}
+
+func TestServeBulk(t *testing.T) {
+	useBulkCalculators(t)
+
+	stream := encodeBulk(t,
+		bulk.Pair{ID: "same", Metric: MetricLength, Original: "one two three", Augmented: "one two three"},
+		bulk.Pair{ID: "short", Metric: MetricCharacter, Original: "a much longer original text", Augmented: "short"},
+		bulk.Pair{ID: "stream", Metric: MetricEfficient, Original: "line one\nline two\n", Augmented: "line one\n"},
+		bulk.Pair{ID: "unknown", Metric: "bleu", Original: "a", Augmented: "b"},
+		bulk.Pair{ID: "empty", Metric: MetricLength, Original: "", Augmented: "b"},
+	)
+
+	var out bytes.Buffer
+	summary := serveBulk(context.Background(), bytes.NewReader(stream), sink.NewJSONLSink(&out))
+	if summary != (bulkSummary{Records: 5, Errors: 2}) {
+		t.Fatalf("summary = %+v", summary)
+	}
+
+	records := collectRecords(t, out.Bytes())
+	if rec := records["same"]; rec.Score != 1 || !rec.Passed || rec.Engine == "" {
+		t.Errorf("same = %+v", rec)
+	}
+	if rec := records["short"]; rec.Passed || rec.Metric != MetricCharacter {
+		t.Errorf("short = %+v", rec)
+	}
+	if rec := records["stream"]; rec.BytesProcessed == 0 {
+		t.Errorf("stream = %+v", rec)
+	}
+	for _, id := range []string{"unknown", "empty"} {
+		if records[id].Details["error"] == nil {
+			t.Errorf("%s has no error: %+v", id, records[id])
+		}
+	}
+}
+
+func TestServeBulkReportsTruncatedStream(t *testing.T) {
+	useBulkCalculators(t)
+
+	stream := encodeBulk(t, bulk.Pair{ID: "a", Metric: MetricLength, Original: "x y", Augmented: "x y"})
+	var out bytes.Buffer
+	summary := serveBulk(context.Background(), bytes.NewReader(stream[:len(stream)-1]), sink.NewJSONLSink(&out))
+	if summary.Records != 0 || summary.Error == "" {
+		t.Errorf("summary = %+v, want no records and an error", summary)
+	}
+
+	summary = serveBulk(context.Background(), bytes.NewReader([]byte("not a bulk stream")), sink.NewJSONLSink(&out))
+	if summary.Error == "" {
+		t.Errorf("summary = %+v, want a bad magic error", summary)
+	}
+}
+
+func TestServeBulkListener(t *testing.T) {
+	useBulkCalculators(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	out := sink.NewChannelSink(16)
+	go serveBulkListener(context.Background(), ln, out)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(encodeBulk(t,
+		bulk.Pair{ID: "1", Metric: MetricLength, Original: "a b c", Augmented: "a b c"},
+		bulk.Pair{ID: "2", Metric: MetricStreaming, Original: "a b c", Augmented: "a b"},
+	))
+	conn.(*net.TCPConn).CloseWrite()
+
+	var summary bulkSummary
+	if err := json.NewDecoder(conn).Decode(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary != (bulkSummary{Records: 2}) {
+		t.Errorf("summary = %+v", summary)
+	}
+	for i := 0; i < 2; i++ {
+		if rec := <-out.Records(); rec.ID != "1" && rec.ID != "2" {
+			t.Errorf("unexpected record %+v", rec)
+		}
+	}
+}
+
+func TestServeBulkListenerShutdown(t *testing.T) {
+	useBulkCalculators(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	returned := make(chan error, 1)
+	go func() { returned <- serveBulkListener(c, ln, sink.NewJSONLSink(io.Discard)) }()
+
+	// A client that sends one pair and then goes quiet without closing
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(encodeBulk(t, bulk.Pair{ID: "1", Metric: MetricLength, Original: "a b c", Augmented: "a b c"}))
+	time.Sleep(50 * time.Millisecond)
+
+	ln.Close()
+	select {
+	case <-returned:
+		t.Fatal("listener returned with a stream still open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Errorf("serveBulkListener = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not return after cancellation")
+	}
+
+	var summary bulkSummary
+	if err := json.NewDecoder(conn).Decode(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Error != context.Canceled.Error() {
+		t.Errorf("summary = %+v, want the stream reported as cancelled", summary)
+	}
+}
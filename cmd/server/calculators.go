@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/features"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+)
+
+// MetricConfig tunes one metric's calculator; zero values keep the calculator's default
+type MetricConfig struct {
+	Threshold    float64 `json:"threshold,omitempty"`
+	MaxDiffRatio float64 `json:"max_diff_ratio,omitempty"`
+}
+
+// CalculatorConfig holds the settings the calculators are built from. Flags
+// set the starting values; a -config file overrides them and is re-read on SIGHUP.
+type CalculatorConfig struct {
+	ReportResources   bool         `json:"report_resources"`
+	ReportUncertainty bool         `json:"report_uncertainty"`
+	Length            MetricConfig `json:"length"`
+	Character         MetricConfig `json:"character"`
+	Streaming         MetricConfig `json:"streaming"`
+	Efficient         MetricConfig `json:"efficient"`
+	// Features is a flag list such as "zero_copy,-parallel_chunks"; a file
+	// that sets it replaces the -features list rather than adding to it
+	Features features.Set `json:"features"`
+	// Deadlines come from the -*-deadline flags only; a reload keeps them
+	Deadlines MetricDeadlines `json:"-"`
+}
+
+// withOverrides returns a copy of c with the settings in a JSON config file
+// applied on top. Settings the file leaves out keep their value from c, so a
+// file only needs the settings it changes. Unknown keys are rejected to catch typos.
+func (c CalculatorConfig) withOverrides(data []byte) (CalculatorConfig, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return CalculatorConfig{}, err
+	}
+	return c, nil
+}
+
+// metric returns the settings of one metric
+func (c CalculatorConfig) metric(metric string) MetricConfig {
+	switch metric {
+	case MetricLength:
+		return c.Length
+	case MetricCharacter:
+		return c.Character
+	case MetricStreaming:
+		return c.Streaming
+	case MetricEfficient:
+		return c.Efficient
+	}
+	return MetricConfig{}
+}
+
+// calculatorSet is one generation of calculators, all built from config.
+// Requests load the current set once and use it to the end, so a reload never
+// changes the calculators under a request in flight.
+type calculatorSet struct {
+	config    CalculatorConfig
+	length    *word.LengthSimilarity
+	character *character.CharacterSimilarity
+	streaming *streaming.StreamingSimilarity
+	efficient *streaming.AllocationEfficientStreamingSimilarity
+}
+
+// calculators holds the current set; reloads replace it atomically
+var calculators atomic.Pointer[calculatorSet]
+
+// currentCalculators returns the calculators new requests should use
+func currentCalculators() *calculatorSet {
+	return calculators.Load()
+}
+
+// newCalculatorSet builds the calculators with performance optimizations.
+// It fails without side effects if any of them cannot be built.
+func newCalculatorSet(config CalculatorConfig, warmUp bool) (*calculatorSet, error) {
+	config.Deadlines = config.Deadlines.withDefaults()
+	if err := config.Deadlines.validate(); err != nil {
+		return nil, err
+	}
+
+	// Length similarity calculator with fast normalizer
+	opts := []word.LengthSimilarityOption{
+		word.WithFastNormalizer(),
+		word.WithResourceReport(config.ReportResources),
+		word.WithUncertainty(config.ReportUncertainty),
+		word.WithWarmUp(warmUp),
+		word.WithLogger(logger),
+		word.WithMetrics(serverMetrics),
+	}
+	if th := config.Length.Threshold; th != 0 {
+		opts = append(opts, word.WithThreshold(th))
+	}
+	if ratio := config.Length.MaxDiffRatio; ratio != 0 {
+		opts = append(opts, word.WithMaxDiffRatio(ratio))
+	}
+	lengthSimilarity, err := word.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("length similarity: %w", err)
+	}
+
+	// Character similarity calculator with optimized normalizer
+	charOpts := []character.CharacterSimilarityOption{
+		character.WithOptimizedNormalizer(),
+		character.WithResourceReport(config.ReportResources),
+		character.WithUncertainty(config.ReportUncertainty),
+		character.WithWarmUp(warmUp),
+		character.WithLogger(logger),
+		character.WithMetrics(serverMetrics),
+	}
+	if th := config.Character.Threshold; th != 0 {
+		charOpts = append(charOpts, character.WithThreshold(th))
+	}
+	if ratio := config.Character.MaxDiffRatio; ratio != 0 {
+		charOpts = append(charOpts, character.WithMaxDiffRatio(ratio))
+	}
+	charSimilarity, err := character.NewCharacterSimilarity(charOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("character similarity: %w", err)
+	}
+
+	// Streaming similarity calculator
+	streamOpts := []streaming.StreamingOption{
+		streaming.WithOptimizedNormalizer(),
+		streaming.WithStreamingLogger(logger),
+		streaming.WithStreamingMetrics(serverMetrics),
+		streaming.WithStreamingResourceReport(config.ReportResources),
+		streaming.WithStreamingUncertainty(config.ReportUncertainty),
+	}
+	if th := config.Streaming.Threshold; th != 0 {
+		streamOpts = append(streamOpts, streaming.WithStreamingThreshold(th))
+	}
+	if ratio := config.Streaming.MaxDiffRatio; ratio != 0 {
+		streamOpts = append(streamOpts, streaming.WithStreamingMaxDiffRatio(ratio))
+	}
+	streamingSimilarity, err := streaming.NewStreamingSimilarity(streamOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("streaming similarity: %w", err)
+	}
+
+	// Allocation-efficient streaming similarity calculator
+	efficientOpts := []streaming.AllocationEfficientOption{
+		streaming.WithEfficientLogger(logger),
+		streaming.WithEfficientMetrics(serverMetrics),
+		streaming.WithEfficientParallel(config.Features.Enabled(features.ParallelChunks)),
+		streaming.WithEfficientResourceReport(config.ReportResources),
+		streaming.WithEfficientUncertainty(config.ReportUncertainty),
+	}
+	if th := config.Efficient.Threshold; th != 0 {
+		efficientOpts = append(efficientOpts, streaming.WithEfficientThreshold(th))
+	}
+	if ratio := config.Efficient.MaxDiffRatio; ratio != 0 {
+		efficientOpts = append(efficientOpts, streaming.WithEfficientMaxDiffRatio(ratio))
+	}
+	efficientSimilarity, err := streaming.NewAllocationEfficientStreamingSimilarity(efficientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("efficient streaming similarity: %w", err)
+	}
+
+	return &calculatorSet{
+		config:    config,
+		length:    lengthSimilarity,
+		character: charSimilarity,
+		streaming: streamingSimilarity,
+		efficient: efficientSimilarity,
+	}, nil
+}
+
+// close releases the pools and caches held by the set's calculators. The
+// logger is shared with the server, so it is flushed rather than closed.
+func (set *calculatorSet) close() error {
+	return errors.Join(
+		set.length.Close(),
+		set.character.Close(),
+		set.streaming.Close(),
+		set.efficient.Close(),
+	)
+}
+
+// closeCalculators closes the current set on shutdown. The set stays current:
+// calculators stay usable after close, so a late background job still works.
+func closeCalculators() {
+	if set := currentCalculators(); set != nil {
+		if err := set.close(); err != nil {
+			logger.Error("Error closing similarity calculators", "error", err)
+		}
+	}
+}
+
+// initSimilarityCalculators builds the first calculator set, exiting on failure
+func initSimilarityCalculators(config CalculatorConfig, warmUp bool) {
+	set, err := newCalculatorSet(config, warmUp)
+	if err != nil {
+		logger.Error("Failed to initialize similarity calculators", "error", err)
+		exit(1)
+	}
+	calculators.Store(set)
+	applyFeatures(config.Features)
+
+	logger.Info("Similarity calculators initialized successfully",
+		"warm_up", warmUp,
+		"workers", parallel.DefaultWorkers(),
+	)
+}
+
+// loadCalculatorConfig applies the -config file, if any, on top of base
+func loadCalculatorConfig(base CalculatorConfig, path string) (CalculatorConfig, error) {
+	if path == "" {
+		return base, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CalculatorConfig{}, err
+	}
+	config, err := base.withOverrides(data)
+	if err != nil {
+		return CalculatorConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// reloadCalculators re-reads the config file and swaps in calculators built
+// from it. The new set is built, and warmed up, while the old one keeps
+// serving; on any error the old set stays in place.
+func reloadCalculators(base CalculatorConfig, path string, warmUp bool) error {
+	config, err := loadCalculatorConfig(base, path)
+	if err != nil {
+		return err
+	}
+	set, err := newCalculatorSet(config, warmUp)
+	if err != nil {
+		return err
+	}
+	// Requests in flight may still hold the old set; close it once the longest
+	// deadline has passed
+	old := calculators.Swap(set)
+	applyFeatures(config.Features)
+	if old != nil {
+		time.AfterFunc(old.config.Deadlines.longest(), func() {
+			if err := old.close(); err != nil {
+				logger.Error("Error closing replaced calculators", "error", err)
+			}
+		})
+	}
+	return nil
+}
+
+// featureNames lists the known feature flags for the -features help text
+func featureNames() []string {
+	var names []string
+	for _, f := range features.Flags() {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+// applyFeatures puts the process-wide feature flags into effect, warning
+// about enabled ones this binary cannot honor
+func applyFeatures(set features.Set) {
+	set.Apply()
+	for _, f := range set.Unavailable() {
+		logger.Warn("Feature enabled but not available in this build; ignoring it", "feature", string(f))
+	}
+}
+
+// describeMetric describes how the current calculators configure metric, for
+// the config fingerprint
+func describeMetric(metric string) string {
+	description := metricSettings[metric]
+	var config CalculatorConfig
+	if set := currentCalculators(); set != nil {
+		config = set.config
+	}
+	if metric == MetricEfficient && config.Features.Enabled(features.ParallelChunks) {
+		description += ";parallel"
+	}
+	if ratio := config.metric(metric).MaxDiffRatio; ratio != 0 {
+		description += ";max_diff_ratio=" + strconv.FormatFloat(ratio, 'g', -1, 64)
+	}
+	return description
+}
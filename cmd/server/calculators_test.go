@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/features"
+	"github.com/baditaflorin/l"
+)
+
+func TestCalculatorConfigWithOverrides(t *testing.T) {
+	base := CalculatorConfig{ReportResources: true, Length: MetricConfig{Threshold: 0.6}}
+
+	got, err := base.withOverrides([]byte(`{"length": {"max_diff_ratio": 0.5}, "character": {"threshold": 0.9}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CalculatorConfig{
+		ReportResources: true,
+		Length:          MetricConfig{Threshold: 0.6, MaxDiffRatio: 0.5},
+		Character:       MetricConfig{Threshold: 0.9},
+	}
+	if got != want {
+		t.Errorf("withOverrides = %+v, want %+v", got, want)
+	}
+	if base.Length.MaxDiffRatio != 0 {
+		t.Error("withOverrides modified the base config")
+	}
+
+	got, err = base.withOverrides([]byte(`{"features": "-parallel_chunks"}`))
+	if err != nil || got.Features.Enabled(features.ParallelChunks) {
+		t.Errorf("features override = %q, %v", got.Features, err)
+	}
+	if _, err := base.withOverrides([]byte(`{"features": "warp_drive"}`)); err == nil {
+		t.Error("expected an error for an unknown feature")
+	}
+
+	if _, err := base.withOverrides([]byte(`{"lenght": {}}`)); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestReloadCalculators(t *testing.T) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = lg
+	t.Cleanup(func() { logger = nil; calculators.Store(nil) })
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(config string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"length": {"threshold": 0.6}}`)
+	if err := reloadCalculators(CalculatorConfig{}, path, false); err != nil {
+		t.Fatal(err)
+	}
+	first := currentCalculators()
+	fingerprint := configFingerprint(MetricLength, 0.6)
+	if got := first.length.Compute(context.Background(), "a b c d", "a b c").Threshold; got != 0.6 {
+		t.Errorf("threshold after first load = %v, want 0.6", got)
+	}
+
+	// A bad config leaves the current calculators in place
+	write(`{"length": {"threshold": 2}}`)
+	if err := reloadCalculators(CalculatorConfig{}, path, false); err == nil {
+		t.Fatal("expected an error for an invalid threshold")
+	}
+	if currentCalculators() != first {
+		t.Fatal("a failed reload replaced the calculators")
+	}
+
+	write(`{"length": {"threshold": 0.6, "max_diff_ratio": 0.5}}`)
+	if err := reloadCalculators(CalculatorConfig{}, path, false); err != nil {
+		t.Fatal(err)
+	}
+	if currentCalculators() == first {
+		t.Fatal("reload did not swap the calculators")
+	}
+	if configFingerprint(MetricLength, 0.6) == fingerprint {
+		t.Error("config fingerprint ignores max_diff_ratio")
+	}
+}
+
+func TestParallelChunksFeature(t *testing.T) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = lg
+	t.Cleanup(func() { logger = nil; calculators.Store(nil) })
+
+	fingerprint := func(list string) string {
+		t.Helper()
+		set, err := features.Parse(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calcs, err := newCalculatorSet(CalculatorConfig{Features: set}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calculators.Store(calcs)
+		return configFingerprint(MetricEfficient, 0.7)
+	}
+
+	parallel := fingerprint("")
+	if got := describeMetric(MetricEfficient); got != "efficient;parallel" {
+		t.Errorf("describeMetric = %q, want efficient;parallel", got)
+	}
+	if fingerprint("-parallel_chunks") == parallel {
+		t.Error("turning parallel_chunks off kept the efficient fingerprint")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/valyala/fasthttp"
+)
+
+// Metric names accepted by the batch and async endpoints
+const (
+	MetricLength    = "length"
+	MetricCharacter = "character"
+	MetricStreaming = "streaming"
+	MetricEfficient = "efficient"
+)
+
+// metricDeadline returns the current calculators' deadline for a metric, or
+// false if the metric is unknown
+func metricDeadline(metric string) (time.Duration, bool) {
+	return currentCalculators().config.Deadlines.forMetric(metric)
+}
+
+// errUnknownMetric is returned when a batch or job names an unsupported metric
+func errUnknownMetric(metric string) *APIError {
+	return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField,
+		"unknown metric "+metric+"; use length, character, streaming or efficient", "metric")
+}
+
+// progressTracker counts the bytes a comparison has consumed from each input
+type progressTracker struct {
+	originalSize, augmentedSize int
+	originalRead, augmentedRead atomic.Int64
+}
+
+// newProgressTracker creates a tracker for a request's inputs
+func newProgressTracker(req Request) *progressTracker {
+	return &progressTracker{originalSize: len(req.Original), augmentedSize: len(req.Augmented)}
+}
+
+// snapshot returns the current progress
+func (t *progressTracker) snapshot() *JobProgress {
+	return &JobProgress{
+		OriginalBytes:      t.originalSize,
+		AugmentedBytes:     t.augmentedSize,
+		OriginalBytesRead:  t.originalRead.Load(),
+		AugmentedBytesRead: t.augmentedRead.Load(),
+	}
+}
+
+// countingReader adds every byte read to n
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+// Read implements io.Reader
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(int64(n))
+	return n, err
+}
+
+// computeMetric runs a validated request through the named metric's calculator,
+// reporting consumed input to tracker when it is not nil
+func computeMetric(c context.Context, metric string, req Request, tracker *progressTracker) Response {
+	var original, augmented io.Reader = strings.NewReader(req.Original), strings.NewReader(req.Augmented)
+	if tracker != nil {
+		original = countingReader{r: original, n: &tracker.originalRead}
+		augmented = countingReader{r: augmented, n: &tracker.augmentedRead}
+	}
+
+	calcs := currentCalculators()
+	var response Response
+	switch metric {
+	case MetricLength:
+		response = responseFromResult(calcs.length.Compute(c, req.Original, req.Augmented))
+		markConsumed(c, tracker)
+	case MetricCharacter:
+		response = responseFromResult(calcs.character.Compute(c, req.Original, req.Augmented))
+		markConsumed(c, tracker)
+	case MetricStreaming:
+		response = responseFromStream(calcs.streaming.ComputeFromReaders(c, original, augmented))
+	case MetricEfficient:
+		response = responseFromStream(calcs.efficient.ComputeFromReaders(c, original, augmented))
+	}
+
+	applyThreshold(&response, req.Threshold)
+	return response
+}
+
+// computeError reports a comparison that produced no score: its deadline ran
+// out, or the calculator put an error in the details
+func computeError(c context.Context, response Response) *APIError {
+	if err := c.Err(); err != nil {
+		return newAPIError(fasthttp.StatusGatewayTimeout, ErrCodeDeadline, "comparison did not finish: "+err.Error(), "")
+	}
+	if msg, ok := response.Details["error"]; ok {
+		return newAPIError(fasthttp.StatusUnprocessableEntity, ErrCodeComputeFailed, fmt.Sprint(msg), "")
+	}
+	return nil
+}
+
+// markConsumed records an in-memory comparison that ran to completion as fully read
+func markConsumed(c context.Context, tracker *progressTracker) {
+	if tracker == nil || c.Err() != nil {
+		return
+	}
+	tracker.originalRead.Store(int64(tracker.originalSize))
+	tracker.augmentedRead.Store(int64(tracker.augmentedSize))
+}
+
+// responseFromResult converts an in-memory result to the API response
+func responseFromResult(result domain.Result) Response {
+	return Response{
+		Engine:          result.Engine,
+		Mode:            result.Mode,
+		Score:           result.Score,
+		Passed:          result.Passed,
+		Inconclusive:    result.Inconclusive,
+		OriginalLength:  result.OriginalLength,
+		AugmentedLength: result.AugmentedLength,
+		LengthRatio:     result.LengthRatio,
+		Threshold:       result.Threshold,
+		Details:         result.Details,
+		Resources:       resourceReport(result.Resources),
+		Uncertainty:     uncertaintyReport(result.Uncertainty),
+	}
+}
+
+// responseFromStream converts a streaming result to the API response
+func responseFromStream(result streaming.StreamResult) Response {
+	return Response{
+		Engine:          result.Engine,
+		Mode:            result.Mode,
+		Score:           result.Score,
+		Passed:          result.Passed,
+		OriginalLength:  result.OriginalLength,
+		AugmentedLength: result.AugmentedLength,
+		LengthRatio:     result.LengthRatio,
+		Threshold:       result.Threshold,
+		ProcessingTime:  result.ProcessingTime,
+		BytesProcessed:  result.BytesProcessed,
+		Details:         result.Details,
+		Resources:       resourceReport(result.Resources),
+		Uncertainty:     uncertaintyReport(result.Uncertainty),
+	}
+}
+
+// resourceReport converts a library resource report to the API form
+func resourceReport(r *domain.Resources) *ResourceReport {
+	if r == nil {
+		return nil
+	}
+	return &ResourceReport{
+		AllocatedBytes:  r.AllocatedBytes,
+		Allocations:     r.Allocations,
+		PeakBufferBytes: r.PeakBufferBytes,
+		Workers:         r.Workers,
+	}
+}
+
+// uncertaintyReport converts a library uncertainty to the API form
+func uncertaintyReport(u *domain.Uncertainty) *UncertaintyReport {
+	if u == nil {
+		return nil
+	}
+	return &UncertaintyReport{
+		Margin:        u.Margin,
+		Low:           u.Low,
+		High:          u.High,
+		NearThreshold: u.NearThreshold,
+	}
+}
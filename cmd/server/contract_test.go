@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/store"
+	"github.com/baditaflorin/l"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// contract is the wire format clients rely on, kept in testdata/contract.json:
+// the JSON field names of each API type, and the status, fields or error code
+// of representative requests. A client can test against the same file. A
+// change that fails these tests breaks clients; update the file only for a
+// deliberate, versioned API change.
+type contract struct {
+	Types map[string][]string `json:"types"`
+	Cases []contractCase      `json:"cases"`
+}
+
+type contractCase struct {
+	Name    string          `json:"name"`
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Body    json.RawMessage `json:"body"`
+	Raw     string          `json:"raw"`
+	RawSize int             `json:"raw_size"`
+	Status  int             `json:"status"`
+	Fields  []string        `json:"fields"`
+	Error   string          `json:"error"`
+	Field   string          `json:"field"`
+}
+
+// contractTypes maps the type names in the contract to the server's types
+var contractTypes = map[string]interface{}{
+	"Request":               Request{},
+	"StreamingRequest":      StreamingRequest{},
+	"Response":              Response{},
+	"ResourceReport":        ResourceReport{},
+	"UncertaintyReport":     UncertaintyReport{},
+	"APIError":              APIError{},
+	"ErrorResponse":         ErrorResponse{},
+	"BatchRequest":          BatchRequest{},
+	"BatchItem":             BatchItem{},
+	"BatchItemResult":       BatchItemResult{},
+	"BatchResponse":         BatchResponse{},
+	"JobRequest":            JobRequest{},
+	"Job":                   Job{},
+	"JobProgress":           JobProgress{},
+	"ProfileRequest":        ProfileRequest{},
+	"Profile":               Profile{},
+	"ProfileList":           ProfileList{},
+	"ProfileCompareRequest": ProfileCompareRequest{},
+}
+
+func loadContract(t *testing.T) contract {
+	t.Helper()
+	data, err := os.ReadFile("testdata/contract.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c contract
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// wireFields returns the JSON names of a struct's fields, including those of
+// embedded structs, in declaration order
+func wireFields(t reflect.Type) []string {
+	var names []string
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestContractTypes(t *testing.T) {
+	c := loadContract(t)
+	for name := range contractTypes {
+		if _, ok := c.Types[name]; !ok {
+			t.Errorf("%s is not in the contract", name)
+		}
+	}
+	for name, want := range c.Types {
+		v, ok := contractTypes[name]
+		if !ok {
+			t.Errorf("contract type %s has no server type", name)
+			continue
+		}
+		got := wireFields(reflect.TypeOf(v))
+		if !slices.Equal(got, want) {
+			t.Errorf("%s fields = %v, contract has %v", name, got, want)
+		}
+	}
+}
+
+// startContractServer serves the API over an in-memory listener with a 64KB body limit
+func startContractServer(t *testing.T) *fasthttp.Client {
+	t.Helper()
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Jobs keep running after the server stops, so they share the package
+	// store rather than one that is swapped back under them
+	savedProfiles := profiles
+	logger = lg
+	profiles = store.NewProfiles(time.Minute, 10, fingerprintHasher)
+	set, err := newCalculatorSet(CalculatorConfig{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+
+	ln := fasthttputil.NewInmemoryListener()
+	server := newHTTPServer(DefaultReadTimeout, DefaultWriteTimeout, 64*1024, DefaultConcurrency)
+	go server.Serve(ln)
+	t.Cleanup(func() {
+		if err := server.Shutdown(); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+		calculators.Store(nil)
+		profiles = savedProfiles
+		logger = nil
+	})
+	return &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+}
+
+func TestContractCases(t *testing.T) {
+	c := loadContract(t)
+	client := startContractServer(t)
+
+	// Cases run in order, so later ones can use what earlier ones created
+	for _, tc := range c.Cases {
+		req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		req.SetRequestURI("http://contract" + tc.Path)
+		req.Header.SetMethod(tc.Method)
+		switch {
+		case tc.RawSize > 0:
+			req.SetBody(bytes.Repeat([]byte("x"), tc.RawSize))
+		case tc.Raw != "":
+			req.SetBodyString(tc.Raw)
+		case tc.Body != nil:
+			req.SetBody(tc.Body)
+		}
+		if err := client.Do(req, resp); err != nil {
+			t.Fatalf("%s: %v", tc.Name, err)
+		}
+		checkContractCase(t, tc, resp.StatusCode(), resp.Body())
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}
+}
+
+func checkContractCase(t *testing.T, tc contractCase, status int, body []byte) {
+	t.Helper()
+	if status != tc.Status {
+		t.Errorf("%s: status = %d, contract has %d: %s", tc.Name, status, tc.Status, body)
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Errorf("%s: body is not a JSON object: %s", tc.Name, body)
+		return
+	}
+	for _, field := range tc.Fields {
+		if _, ok := got[field]; !ok {
+			t.Errorf("%s: response lacks %q: %s", tc.Name, field, body)
+		}
+	}
+	if tc.Error == "" {
+		return
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error == nil {
+		t.Errorf("%s: not an error response: %s", tc.Name, body)
+		return
+	}
+	if string(errResp.Error.Code) != tc.Error || errResp.Error.Field != tc.Field {
+		t.Errorf("%s: error %s on %q, contract has %s on %q", tc.Name, errResp.Error.Code, errResp.Error.Field, tc.Error, tc.Field)
+	}
+}
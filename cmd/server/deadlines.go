@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/valyala/fasthttp"
+)
+
+// Default per-metric request deadlines
+const (
+	DefaultLengthDeadline    = 30 * time.Second
+	DefaultCharacterDeadline = 30 * time.Second
+	DefaultStreamingDeadline = 60 * time.Second
+	DefaultEfficientDeadline = 60 * time.Second
+)
+
+// MetricDeadlines holds the total time budget allowed for each metric's
+// requests. It is part of CalculatorConfig, so requests use the deadlines of
+// the calculator set they loaded; zero values in a CalculatorConfig keep the
+// defaults, while the flags must be positive.
+type MetricDeadlines struct {
+	Length    time.Duration
+	Character time.Duration
+	Streaming time.Duration
+	Efficient time.Duration
+}
+
+// longest returns the largest of the metric deadlines
+func (d MetricDeadlines) longest() time.Duration {
+	return max(d.Length, d.Character, d.Streaming, d.Efficient)
+}
+
+// validate rejects deadlines that would fail every request on their metric
+// at once, naming the flag that sets them
+func (d MetricDeadlines) validate() error {
+	for _, deadline := range []struct {
+		flag  string
+		value time.Duration
+	}{
+		{"length-deadline", d.Length},
+		{"character-deadline", d.Character},
+		{"streaming-deadline", d.Streaming},
+		{"efficient-deadline", d.Efficient},
+	} {
+		if deadline.value <= 0 {
+			return domain.NewConfigError(deadline.flag, deadline.value, "must be greater than 0")
+		}
+	}
+	return nil
+}
+
+// withDefaults returns d with the default deadline for every metric left at zero
+func (d MetricDeadlines) withDefaults() MetricDeadlines {
+	if d.Length == 0 {
+		d.Length = DefaultLengthDeadline
+	}
+	if d.Character == 0 {
+		d.Character = DefaultCharacterDeadline
+	}
+	if d.Streaming == 0 {
+		d.Streaming = DefaultStreamingDeadline
+	}
+	if d.Efficient == 0 {
+		d.Efficient = DefaultEfficientDeadline
+	}
+	return d
+}
+
+// forMetric returns the deadline for a metric, or false if the metric is unknown
+func (d MetricDeadlines) forMetric(metric string) (time.Duration, bool) {
+	switch metric {
+	case MetricLength:
+		return d.Length, true
+	case MetricCharacter:
+		return d.Character, true
+	case MetricStreaming:
+		return d.Streaming, true
+	case MetricEfficient:
+		return d.Efficient, true
+	default:
+		return 0, false
+	}
+}
+
+// requestContext returns a context whose deadline is the request's arrival time
+// plus budget, so time already spent reading and decoding the body counts
+// against it. The processors check this context before every chunk they read.
+func requestContext(ctx *fasthttp.RequestCtx, budget time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(context.Background(), ctx.Time().Add(budget))
+}
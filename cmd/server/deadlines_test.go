@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/bulk"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+	"github.com/baditaflorin/l"
+)
+
+func TestMetricDeadlinesDefaults(t *testing.T) {
+	d := MetricDeadlines{Streaming: time.Second}.withDefaults()
+	want := MetricDeadlines{
+		Length:    DefaultLengthDeadline,
+		Character: DefaultCharacterDeadline,
+		Streaming: time.Second,
+		Efficient: DefaultEfficientDeadline,
+	}
+	if d != want {
+		t.Errorf("withDefaults = %+v, want %+v", d, want)
+	}
+	if _, ok := d.forMetric("bleu"); ok {
+		t.Error("forMetric accepted an unknown metric")
+	}
+}
+
+func TestMetricDeadlinesValidate(t *testing.T) {
+	valid := MetricDeadlines{}.withDefaults()
+	if err := valid.validate(); err != nil {
+		t.Fatalf("default deadlines: %v", err)
+	}
+	for _, tc := range []struct {
+		flag  string
+		apply func(*MetricDeadlines)
+	}{
+		{"length-deadline", func(d *MetricDeadlines) { d.Length = 0 }},
+		{"character-deadline", func(d *MetricDeadlines) { d.Character = -time.Second }},
+		{"streaming-deadline", func(d *MetricDeadlines) { d.Streaming = 0 }},
+		{"efficient-deadline", func(d *MetricDeadlines) { d.Efficient = -1 }},
+	} {
+		d := valid
+		tc.apply(&d)
+		var configErr *domain.ConfigError
+		if err := d.validate(); !errors.As(err, &configErr) || configErr.Field != tc.flag {
+			t.Errorf("%+v: error %v, want a ConfigError naming %s", d, err, tc.flag)
+		}
+	}
+
+	// A reload with a negative deadline fails and keeps the current calculators
+	useBulkCalculators(t)
+	before := currentCalculators()
+	if err := reloadCalculators(CalculatorConfig{Deadlines: MetricDeadlines{Length: -time.Second}}, "", false); err == nil {
+		t.Error("reload accepted a negative deadline")
+	}
+	if currentCalculators() != before {
+		t.Error("a rejected reload replaced the calculators")
+	}
+}
+
+func TestMetricDeadlineCutsOffOnlyItsMetric(t *testing.T) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = lg
+	set, err := newCalculatorSet(CalculatorConfig{Deadlines: MetricDeadlines{Efficient: time.Nanosecond}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+	t.Cleanup(func() { logger = nil; calculators.Store(nil) })
+
+	long := strings.Repeat("a line of text to compare\n", 10000)
+	stream := encodeBulk(t,
+		bulk.Pair{ID: "slow", Metric: MetricEfficient, Original: long, Augmented: long},
+		bulk.Pair{ID: "length", Metric: MetricLength, Original: long, Augmented: long},
+		bulk.Pair{ID: "streaming", Metric: MetricStreaming, Original: long, Augmented: long},
+	)
+
+	var out bytes.Buffer
+	summary := serveBulk(context.Background(), bytes.NewReader(stream), sink.NewJSONLSink(&out))
+	if summary.Records != 3 || summary.Errors != 1 {
+		t.Fatalf("summary = %+v", summary)
+	}
+
+	records := collectRecords(t, out.Bytes())
+	if rec := records["slow"]; rec.Passed || rec.Details["error"] == nil || rec.Details["code"] != string(ErrCodeDeadline) {
+		t.Errorf("efficient pair past its deadline = %+v, want a %s error", rec, ErrCodeDeadline)
+	}
+	for _, id := range []string{"length", "streaming"} {
+		if rec := records[id]; rec.Score != 1 || !rec.Passed {
+			t.Errorf("%s = %+v, want it to finish within its own deadline", id, rec)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/baditaflorin/go_length_similarity/internal/warmup"
+	"github.com/valyala/fasthttp"
+)
+
+// DebugStats is the body of /debug/stats
+type DebugStats struct {
+	// Warmup reports the warm-up of the current calculators by metric; a
+	// metric is absent when its calculator was not warmed up
+	Warmup map[string]WarmupStats `json:"warmup"`
+}
+
+// WarmupStats describes one calculator's warm-up run
+type WarmupStats struct {
+	Iterations   int64                  `json:"iterations"`
+	Duration     string                 `json:"duration"`
+	Components   []WarmupComponentStats `json:"components"`
+	AllocsBefore uint64                 `json:"allocs_before"`
+	AllocsAfter  uint64                 `json:"allocs_after"`
+	GCCycles     uint32                 `json:"gc_cycles"`
+	Cancelled    bool                   `json:"cancelled"`
+}
+
+// WarmupComponentStats describes the warm-up of one kind of component
+type WarmupComponentStats struct {
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+	Iterations int64  `json:"iterations"`
+	Duration   string `json:"duration"`
+}
+
+// warmupStatsFromReport converts a warm-up report to its wire form
+func warmupStatsFromReport(r warmup.WarmupReport) WarmupStats {
+	stats := WarmupStats{
+		Iterations:   r.Iterations,
+		Duration:     r.Duration.String(),
+		Components:   make([]WarmupComponentStats, len(r.Components)),
+		AllocsBefore: r.AllocsBefore,
+		AllocsAfter:  r.AllocsAfter,
+		GCCycles:     r.GCCycles,
+		Cancelled:    r.Cancelled,
+	}
+	for i, c := range r.Components {
+		stats.Components[i] = WarmupComponentStats{
+			Name:       c.Name,
+			Count:      c.Count,
+			Iterations: c.Iterations,
+			Duration:   c.Duration.String(),
+		}
+	}
+	return stats
+}
+
+// handleDebugStats reports internal statistics of the current calculators
+func handleDebugStats(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	set := currentCalculators()
+	stats := DebugStats{Warmup: map[string]WarmupStats{}}
+	if r, ok := set.length.WarmupReport(); ok {
+		stats.Warmup[MetricLength] = warmupStatsFromReport(r)
+	}
+	if r, ok := set.character.WarmupReport(); ok {
+		stats.Warmup[MetricCharacter] = warmupStatsFromReport(r)
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	writeJSONResponse(ctx, stats)
+}
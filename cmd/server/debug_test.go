@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/warmup"
+	"github.com/baditaflorin/l"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandleDebugStats(t *testing.T) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = lg
+	t.Cleanup(func() { logger = nil; calculators.Store(nil) })
+
+	set, err := newCalculatorSet(CalculatorConfig{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+
+	get := func() DebugStats {
+		t.Helper()
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+		ctx.Request.SetRequestURI("/debug/stats")
+		handleDebugStats(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("status = %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		var stats DebugStats
+		if err := json.Unmarshal(ctx.Response.Body(), &stats); err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+
+	if stats := get(); len(stats.Warmup) != 0 {
+		t.Errorf("cold calculators: %+v, want no warm-up", stats)
+	}
+
+	set.length.WarmUp(context.Background(), warmup.WarmupConfig{Concurrency: 2, Iterations: 10, SampleTextSize: 200, Duration: time.Minute})
+	stats := get()
+	w, ok := stats.Warmup[MetricLength]
+	if !ok || len(stats.Warmup) != 1 {
+		t.Fatalf("warmup = %+v, want only length", stats.Warmup)
+	}
+	// Normalizers and calculators, 10 iterations on each of 2 routines
+	if w.Iterations != 40 || len(w.Components) != 2 || w.Components[1].Name != "calculators" || w.Components[1].Iterations != 20 {
+		t.Errorf("length warm-up = %+v", w)
+	}
+	if w.AllocsAfter <= w.AllocsBefore || w.Cancelled {
+		t.Errorf("length warm-up = %+v, want allocations and no cancellation", w)
+	}
+
+	post := newPostCtx("/debug/stats", "", "")
+	handleDebugStats(post)
+	if post.Response.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", post.Response.StatusCode())
+	}
+}
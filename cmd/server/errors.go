@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrorCode identifies a class of API error so clients can branch without parsing messages
+type ErrorCode string
+
+// API error codes
+const (
+	ErrCodeInvalidJSON        ErrorCode = "INVALID_JSON"
+	ErrCodeMissingField       ErrorCode = "MISSING_FIELD"
+	ErrCodeInvalidField       ErrorCode = "INVALID_FIELD"
+	ErrCodeInvalidThreshold   ErrorCode = "INVALID_THRESHOLD"
+	ErrCodePayloadTooLarge    ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrCodeMethodNotAllowed   ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrCodeBadRequest         ErrorCode = "BAD_REQUEST"
+	ErrCodeBatchTooLarge      ErrorCode = "BATCH_TOO_LARGE"
+	ErrCodeDeadline           ErrorCode = "DEADLINE_EXCEEDED"
+	ErrCodeComputeFailed      ErrorCode = "COMPUTE_FAILED"
+	ErrCodeJobFinished        ErrorCode = "JOB_ALREADY_FINISHED"
+	ErrCodeIdempotencyReuse   ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrCodeIdempotencyBusy    ErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	ErrCodeTooManyProfiles    ErrorCode = "TOO_MANY_PROFILES"
+	ErrCodeTooManyJobs        ErrorCode = "TOO_MANY_JOBS"
+	ErrCodePreconditionFailed ErrorCode = "PRECONDITION_FAILED"
+	ErrCodeInternal           ErrorCode = "INTERNAL_ERROR"
+)
+
+// APIError is the structured error returned to clients
+type APIError struct {
+	Status  int       `json:"-"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Field   string    `json:"field,omitempty"`
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+// newAPIError creates an API error
+func newAPIError(status int, code ErrorCode, message, field string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message, Field: field}
+}
+
+// errMethodNotAllowed is returned for requests with an unsupported method
+func errMethodNotAllowed() *APIError {
+	return newAPIError(fasthttp.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed", "")
+}
+
+// errNotFound is returned for unknown paths
+func errNotFound() *APIError {
+	return newAPIError(fasthttp.StatusNotFound, ErrCodeNotFound, "Not found", "")
+}
+
+// errInvalidJSON wraps a request body decoding error
+func errInvalidJSON(err error) *APIError {
+	return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidJSON, "Invalid request: "+err.Error(), "")
+}
+
+// errMissingField is returned when a required field is absent or empty
+func errMissingField(field string) *APIError {
+	return newAPIError(fasthttp.StatusBadRequest, ErrCodeMissingField, "Both original and augmented texts are required", field)
+}
+
+// validateTexts checks that both texts were provided
+func validateTexts(originalEmpty, augmentedEmpty bool) *APIError {
+	if originalEmpty {
+		return errMissingField("original")
+	}
+	if augmentedEmpty {
+		return errMissingField("augmented")
+	}
+	return nil
+}
+
+// validateThreshold checks an optional per-request threshold; 0 means unset
+func validateThreshold(th float64) *APIError {
+	if math.IsNaN(th) || th < 0 || th > 1 {
+		return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidThreshold, "threshold must be between 0 and 1", "threshold")
+	}
+	return nil
+}
+
+// validate checks the fields common to all comparison requests
+func (r Request) validate() *APIError {
+	if apiErr := validateTexts(r.Original == "", r.Augmented == ""); apiErr != nil {
+		return apiErr
+	}
+	return validateThreshold(r.Threshold)
+}
+
+// applyThreshold re-evaluates a response against a per-request threshold.
+// Scores do not depend on the threshold, so only Passed and Threshold change.
+func applyThreshold(resp *Response, th float64) {
+	if th == 0 {
+		return
+	}
+	resp.Threshold = th
+	resp.Passed = resp.Score >= th
+	if u := resp.Uncertainty; u != nil {
+		u.NearThreshold = u.Low < th && th <= u.High
+	}
+}
+
+// writeAPIError writes a structured error response
+func writeAPIError(ctx *fasthttp.RequestCtx, apiErr *APIError) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+	ctx.SetStatusCode(apiErr.Status)
+
+	response, err := json.Marshal(ErrorResponse{Error: apiErr})
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		logger.Error("Error marshaling JSON error response", "error", err)
+		ctx.SetBodyString(`{"error":{"code":"INTERNAL_ERROR","message":"Internal server error"}}`)
+		return
+	}
+
+	ctx.SetBody(response)
+}
+
+// handleServerError reports errors fasthttp hits before a handler runs,
+// such as oversized bodies, in the same structured format
+func handleServerError(ctx *fasthttp.RequestCtx, err error) {
+	if errors.Is(err, fasthttp.ErrBodyTooLarge) {
+		writeAPIError(ctx, newAPIError(fasthttp.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "Request body exceeds the maximum allowed size", ""))
+		return
+	}
+	writeAPIError(ctx, newAPIError(fasthttp.StatusBadRequest, ErrCodeBadRequest, err.Error(), ""))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRequestValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		req   Request
+		code  ErrorCode
+		field string
+	}{
+		{"valid", Request{Original: "a", Augmented: "b"}, "", ""},
+		{"missing original", Request{Augmented: "b"}, ErrCodeMissingField, "original"},
+		{"missing augmented", Request{Original: "a"}, ErrCodeMissingField, "augmented"},
+		{"threshold too high", Request{Original: "a", Augmented: "b", Threshold: 1.5}, ErrCodeInvalidThreshold, "threshold"},
+		{"threshold NaN", Request{Original: "a", Augmented: "b", Threshold: math.NaN()}, ErrCodeInvalidThreshold, "threshold"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := tc.req.validate()
+			if tc.code == "" {
+				if apiErr != nil {
+					t.Fatalf("validate() = %v, want nil", apiErr)
+				}
+				return
+			}
+			if apiErr == nil || apiErr.Code != tc.code || apiErr.Field != tc.field {
+				t.Fatalf("validate() = %+v, want code %s field %s", apiErr, tc.code, tc.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Score headers let proxies and lightweight clients act on a result without parsing the body
+const (
+	HeaderScore             = "X-Similarity-Score"
+	HeaderPassed            = "X-Similarity-Passed"
+	HeaderConfigFingerprint = "X-Config-Fingerprint"
+)
+
+// scoreHeaders is configured from flags in main
+var scoreHeaders bool
+
+// metricSettings describes the fixed options newCalculatorSet configures each
+// metric with; describeMetric adds the configurable ones. It feeds the config
+// fingerprint, so keep it in step with the options used there: a change to
+// either must change the fingerprint.
+var metricSettings = map[string]string{
+	MetricLength:    "word;normalizer=fast",
+	MetricCharacter: "character;normalizer=optimized",
+	MetricStreaming: "streaming;normalizer=optimized",
+	MetricEfficient: "efficient",
+}
+
+// configFingerprint identifies the scoring configuration behind a response:
+// the metric, how its calculator is set up and the effective threshold. Two
+// responses with the same fingerprint are directly comparable.
+func configFingerprint(metric string, threshold float64) string {
+	config := metric + "|" + describeMetric(metric) + "|threshold=" + strconv.FormatFloat(threshold, 'g', -1, 64)
+	return hex.EncodeToString(fingerprintHasher.SumString(config))
+}
+
+// setScoreHeaders copies the score, pass decision and config fingerprint of a
+// single-comparison response into headers when -score-headers is set
+func setScoreHeaders(ctx *fasthttp.RequestCtx, metric string, response Response) {
+	if !scoreHeaders {
+		return
+	}
+	ctx.Response.Header.Set(HeaderScore, strconv.FormatFloat(response.Score, 'f', -1, 64))
+	ctx.Response.Header.Set(HeaderPassed, strconv.FormatBool(response.Passed))
+	ctx.Response.Header.Set(HeaderConfigFingerprint, configFingerprint(metric, response.Threshold))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSetScoreHeaders(t *testing.T) {
+	response := Response{Score: 0.75, Passed: true, Threshold: 0.7}
+
+	ctx := &fasthttp.RequestCtx{}
+	setScoreHeaders(ctx, MetricLength, response)
+	if len(ctx.Response.Header.Peek(HeaderScore)) != 0 {
+		t.Fatal("score headers set while disabled")
+	}
+
+	scoreHeaders = true
+	t.Cleanup(func() { scoreHeaders = false })
+
+	setScoreHeaders(ctx, MetricLength, response)
+	if got := string(ctx.Response.Header.Peek(HeaderScore)); got != "0.75" {
+		t.Errorf("%s = %q, want 0.75", HeaderScore, got)
+	}
+	if got := string(ctx.Response.Header.Peek(HeaderPassed)); got != "true" {
+		t.Errorf("%s = %q, want true", HeaderPassed, got)
+	}
+	fingerprint := string(ctx.Response.Header.Peek(HeaderConfigFingerprint))
+	if fingerprint == "" {
+		t.Fatalf("%s missing", HeaderConfigFingerprint)
+	}
+
+	if configFingerprint(MetricLength, 0.7) != fingerprint {
+		t.Error("fingerprint not stable for the same config")
+	}
+	if configFingerprint(MetricLength, 0.8) == fingerprint || configFingerprint(MetricCharacter, 0.7) == fingerprint {
+		t.Error("fingerprint ignores the threshold or metric")
+	}
+}
@@ -0,0 +1,471 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/store"
+	"github.com/baditaflorin/l"
+)
+
+// The integration tests run the real fasthttp server on a loopback port and
+// talk to it over HTTP, as a client in production would:
+//
+//	go test -tags integration ./cmd/server
+
+// testServer is a running server and the base URL it listens on
+type testServer struct {
+	url string
+}
+
+// startServer starts a server with the given body limit. setup runs before
+// the server starts, so it can adjust the package settings and the calculator
+// config the handlers read; the settings are restored when the test ends.
+func startServer(t *testing.T, maxRequestSize int, setup func(*CalculatorConfig)) testServer {
+	t.Helper()
+
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Jobs keep running after the server stops, so they share the package
+	// store rather than one that is swapped back under them
+	savedProfiles := profiles
+	logger = lg
+	profiles = store.NewProfiles(time.Minute, 10, fingerprintHasher)
+	var config CalculatorConfig
+	if setup != nil {
+		setup(&config)
+	}
+	set, err := newCalculatorSet(config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newHTTPServer(DefaultReadTimeout, DefaultWriteTimeout, maxRequestSize, DefaultConcurrency)
+	go server.Serve(ln)
+
+	t.Cleanup(func() {
+		if err := server.Shutdown(); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+		closeCalculators()
+		calculators.Store(nil)
+		profiles = savedProfiles
+		logger = nil
+	})
+	return testServer{url: "http://" + ln.Addr().String()}
+}
+
+// do sends a request and returns the response with its body read
+func (s testServer) do(t *testing.T, method, path string, body []byte, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.url+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+// post sends v as a JSON body
+func (s testServer) post(t *testing.T, path string, v interface{}) (*http.Response, []byte) {
+	t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.do(t, http.MethodPost, path, body, nil)
+}
+
+// schema maps required fields to their JSON kind: string, number, boolean,
+// object or array
+type schema map[string]string
+
+// Response schemas shared by several endpoints
+var (
+	comparisonSchema = schema{
+		"score":            "number",
+		"passed":           "boolean",
+		"original_length":  "number",
+		"augmented_length": "number",
+		"length_ratio":     "number",
+		"threshold":        "number",
+	}
+	errorSchema = schema{"code": "string", "message": "string"}
+	jobSchema   = schema{
+		"id":         "string",
+		"metric":     "string",
+		"status":     "string",
+		"created_at": "string",
+		"updated_at": "string",
+	}
+	profileSchema = schema{
+		"id":             "string",
+		"fingerprint":    "string",
+		"original_bytes": "number",
+		"created_at":     "string",
+		"last_used_at":   "string",
+		"ttl":            "string",
+		"expires_at":     "string",
+	}
+)
+
+// jsonKind names the JSON kind of a decoded value
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
+
+// checkObject reports each field of want that obj lacks or holds with the wrong kind
+func checkObject(t *testing.T, where string, obj map[string]interface{}, want schema) {
+	t.Helper()
+	for field, kind := range want {
+		v, ok := obj[field]
+		if !ok {
+			t.Errorf("%s: missing field %q", where, field)
+		} else if got := jsonKind(v); got != kind {
+			t.Errorf("%s: field %q is a %s, want a %s", where, field, got, kind)
+		}
+	}
+}
+
+// assertSchema checks that a JSON object body matches want and returns it decoded
+func assertSchema(t *testing.T, body []byte, want schema) map[string]interface{} {
+	t.Helper()
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		t.Fatalf("response is not a JSON object: %v: %s", err, body)
+	}
+	checkObject(t, "response", obj, want)
+	return obj
+}
+
+// assertError checks an error response's status, JSON content type and code
+func assertError(t *testing.T, resp *http.Response, body []byte, status int, code ErrorCode) {
+	t.Helper()
+	if resp.StatusCode != status {
+		t.Errorf("status = %d, want %d: %s", resp.StatusCode, status, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	obj := assertSchema(t, body, schema{"error": "object"})
+	if errObj, ok := obj["error"].(map[string]interface{}); ok {
+		checkObject(t, "error", errObj, errorSchema)
+		if errObj["code"] != string(code) {
+			t.Errorf("error code = %v, want %s", errObj["code"], code)
+		}
+	}
+}
+
+// waitForJob polls a job until its status is no longer pending or running
+func (s testServer) waitForJob(t *testing.T, id string) map[string]interface{} {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, body := s.do(t, http.MethodGet, "/jobs/"+id, nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /jobs/%s = %d: %s", id, resp.StatusCode, body)
+		}
+		job := assertSchema(t, body, jobSchema)
+		if status := job["status"]; status != string(JobPending) && status != string(JobRunning) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %v", id, job["status"])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIntegrationHealthAndUI(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+
+	resp, body := s.do(t, http.MethodGet, "/health", nil, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Server") != "SimilarityServer" {
+		t.Errorf("GET /health = %d, Server %q", resp.StatusCode, resp.Header.Get("Server"))
+	}
+	if health := assertSchema(t, body, schema{"status": "string", "time": "string"}); health["status"] != "ok" {
+		t.Errorf("health status = %v", health["status"])
+	}
+
+	resp, body = s.do(t, http.MethodGet, "/ui", nil, nil)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || len(body) == 0 {
+		t.Errorf("GET /ui = %d, Content-Type %q, %d bytes", resp.StatusCode, resp.Header.Get("Content-Type"), len(body))
+	}
+}
+
+func TestIntegrationComparisons(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+	req := Request{Original: "the quick brown fox jumps over the lazy dog", Augmented: "the quick brown fox jumps over the dog"}
+
+	for _, path := range []string{"/length", "/character", "/streaming", "/efficient"} {
+		t.Run(path, func(t *testing.T) {
+			resp, body := s.post(t, path, req)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("POST %s = %d: %s", path, resp.StatusCode, body)
+			}
+			result := assertSchema(t, body, comparisonSchema)
+			if score := result["score"].(float64); !(score > 0 && score < 1) {
+				t.Errorf("score = %v, want between 0 and 1", score)
+			}
+			if engine := resp.Header.Get("X-Similarity-Engine"); engine != "" {
+				t.Errorf("small request used the %s engine", engine)
+			}
+
+			strict := req
+			strict.Threshold = 0.999
+			_, body = s.post(t, path, strict)
+			if result := assertSchema(t, body, comparisonSchema); result["passed"] != false || result["threshold"] != 0.999 {
+				t.Errorf("threshold 0.999: passed %v, threshold %v", result["passed"], result["threshold"])
+			}
+		})
+	}
+}
+
+func TestIntegrationLargeStreamedPayloads(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+	const words = 400000 // about 2.4MB per text, above the streaming threshold
+	req := Request{
+		Original:  strings.Repeat("lorem ", words),
+		Augmented: strings.Repeat("lorem ", words/2),
+	}
+
+	for _, path := range []string{"/length", "/character", "/streaming", "/efficient"} {
+		t.Run(path, func(t *testing.T) {
+			resp, body := s.post(t, path, req)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("POST %s = %d: %s", path, resp.StatusCode, body)
+			}
+			result := assertSchema(t, body, comparisonSchema)
+			if path == "/length" || path == "/character" {
+				if engine := resp.Header.Get("X-Similarity-Engine"); engine != "streaming" {
+					t.Errorf("X-Similarity-Engine = %q, want streaming", engine)
+				}
+			}
+			original, augmented := result["original_length"].(float64), result["augmented_length"].(float64)
+			if original == 0 || augmented == 0 || original < 1.9*augmented || original > 2.1*augmented {
+				t.Errorf("lengths = %v and %v, want about 2:1", original, augmented)
+			}
+		})
+	}
+}
+
+func TestIntegrationErrors(t *testing.T) {
+	s := startServer(t, 64*1024, nil)
+
+	resp, body := s.do(t, http.MethodPost, "/length", []byte(`{"original": `), nil)
+	assertError(t, resp, body, http.StatusBadRequest, ErrCodeInvalidJSON)
+
+	resp, body = s.post(t, "/character", Request{Original: "text"})
+	assertError(t, resp, body, http.StatusBadRequest, ErrCodeMissingField)
+
+	resp, body = s.post(t, "/streaming", Request{Original: "a", Augmented: "b", Threshold: 2})
+	assertError(t, resp, body, http.StatusBadRequest, ErrCodeInvalidThreshold)
+
+	resp, body = s.do(t, http.MethodGet, "/efficient", nil, nil)
+	assertError(t, resp, body, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed)
+
+	resp, body = s.do(t, http.MethodGet, "/nowhere", nil, nil)
+	assertError(t, resp, body, http.StatusNotFound, ErrCodeNotFound)
+
+	resp, body = s.post(t, "/batch", BatchRequest{Metric: "nonsense", Items: []BatchItem{{Request: Request{Original: "a", Augmented: "b"}}}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown batch metric = %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = s.post(t, "/length", Request{Original: strings.Repeat("x", 128*1024), Augmented: "y"})
+	assertError(t, resp, body, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge)
+}
+
+func TestIntegrationBatch(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+	resp, body := s.post(t, "/batch", BatchRequest{
+		Metric: MetricCharacter,
+		Items: []BatchItem{
+			{ID: "same", Request: Request{Original: "abc", Augmented: "abc"}},
+			{ID: "missing", Request: Request{Original: "abc"}},
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /batch = %d: %s", resp.StatusCode, body)
+	}
+	batch := assertSchema(t, body, schema{"metric": "string", "results": "array"})
+	results := batch["results"].([]interface{})
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	first, second := results[0].(map[string]interface{}), results[1].(map[string]interface{})
+	checkObject(t, "results[0]", first, schema{"id": "string", "result": "object"})
+	checkObject(t, "results[0].result", first["result"].(map[string]interface{}), comparisonSchema)
+	checkObject(t, "results[1]", second, schema{"id": "string", "error": "object"})
+}
+
+func TestIntegrationJobs(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+	job := JobRequest{Request: Request{Original: "one two three four", Augmented: "one two"}, Metric: MetricStreaming}
+	body, err := json.Marshal(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := map[string]string{"Idempotency-Key": "integration-job"}
+	resp, data := s.do(t, http.MethodPost, "/jobs", body, header)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /jobs = %d: %s", resp.StatusCode, data)
+	}
+	accepted := assertSchema(t, data, jobSchema)
+	id := accepted["id"].(string)
+	if resp.Header.Get("Location") != "/jobs/"+id {
+		t.Errorf("Location = %q, want /jobs/%s", resp.Header.Get("Location"), id)
+	}
+
+	// A retry with the same key replays the first response
+	if _, replay := s.do(t, http.MethodPost, "/jobs", body, header); !bytes.Equal(replay, data) {
+		t.Errorf("retry returned %s, want the replayed %s", replay, data)
+	}
+
+	done := s.waitForJob(t, id)
+	if done["status"] != string(JobSucceeded) {
+		t.Fatalf("job finished %v: %v", done["status"], done["error"])
+	}
+	checkObject(t, "job", done, schema{"result": "object", "progress": "object"})
+	checkObject(t, "job.result", done["result"].(map[string]interface{}), comparisonSchema)
+
+	resp, data = s.do(t, http.MethodDelete, "/jobs/"+id, nil, nil)
+	assertError(t, resp, data, http.StatusConflict, ErrCodeJobFinished)
+}
+
+func TestIntegrationJobCancellation(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+	big := JobRequest{
+		Request: Request{Original: strings.Repeat("cancel me ", 500000), Augmented: strings.Repeat("cancel ", 500000)},
+		Metric:  MetricStreaming,
+	}
+
+	resp, body := s.post(t, "/jobs", big)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /jobs = %d: %s", resp.StatusCode, body)
+	}
+	id := assertSchema(t, body, jobSchema)["id"].(string)
+
+	resp, body = s.do(t, http.MethodDelete, "/jobs/"+id, nil, nil)
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		if job := s.waitForJob(t, id); job["status"] != string(JobCancelled) {
+			t.Errorf("cancelled job finished %v", job["status"])
+		}
+	case http.StatusConflict:
+		// The job beat the cancellation; it must then have succeeded
+		assertError(t, resp, body, http.StatusConflict, ErrCodeJobFinished)
+	default:
+		t.Errorf("DELETE /jobs/%s = %d: %s", id, resp.StatusCode, body)
+	}
+}
+
+func TestIntegrationJobDeadline(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, func(c *CalculatorConfig) { c.Deadlines.Efficient = time.Nanosecond })
+
+	resp, body := s.post(t, "/jobs", JobRequest{Request: Request{Original: "abc def", Augmented: "abc"}, Metric: MetricEfficient})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /jobs = %d: %s", resp.StatusCode, body)
+	}
+	job := s.waitForJob(t, assertSchema(t, body, jobSchema)["id"].(string))
+	if job["status"] != string(JobFailed) {
+		t.Fatalf("job past its deadline finished %v", job["status"])
+	}
+	errObj, _ := job["error"].(map[string]interface{})
+	checkObject(t, "job.error", errObj, errorSchema)
+	if errObj["code"] != string(ErrCodeDeadline) {
+		t.Errorf("error code = %v, want %s", errObj["code"], ErrCodeDeadline)
+	}
+}
+
+func TestIntegrationProfiles(t *testing.T) {
+	s := startServer(t, DefaultMaxRequestSize, nil)
+
+	resp, body := s.post(t, "/profiles", ProfileRequest{ID: "doc-1", Original: "a stored original text"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /profiles = %d: %s", resp.StatusCode, body)
+	}
+	created := assertSchema(t, body, profileSchema)
+	etag := resp.Header.Get("ETag")
+	if etag != `"`+created["fingerprint"].(string)+`"` {
+		t.Errorf("ETag = %s, want the quoted fingerprint", etag)
+	}
+
+	resp, body = s.do(t, http.MethodGet, "/profiles", nil, nil)
+	list := assertSchema(t, body, schema{"count": "number", "profiles": "array"})
+	if resp.StatusCode != http.StatusOK || list["count"] != 1.0 {
+		t.Errorf("GET /profiles = %d, count %v", resp.StatusCode, list["count"])
+	}
+
+	resp, body = s.do(t, http.MethodGet, "/profiles/doc-1", nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /profiles/doc-1 = %d", resp.StatusCode)
+	}
+	assertSchema(t, body, profileSchema)
+
+	compare := ProfileCompareRequest{Augmented: "a stored text", Metric: MetricCharacter}
+	data, err := json.Marshal(compare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, body = s.do(t, http.MethodPost, "/compare/profile/doc-1", data, map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("compare = %d: %s", resp.StatusCode, body)
+	}
+	assertSchema(t, body, comparisonSchema)
+
+	// Replacing the original invalidates the old ETag
+	resp, body = s.do(t, http.MethodPut, "/profiles/doc-1", []byte("a new original"), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /profiles/doc-1 = %d: %s", resp.StatusCode, body)
+	}
+	resp, body = s.do(t, http.MethodPost, "/compare/profile/doc-1", data, map[string]string{"If-Match": etag})
+	assertError(t, resp, body, http.StatusPreconditionFailed, ErrCodePreconditionFailed)
+
+	if resp, _ = s.do(t, http.MethodDelete, "/profiles/doc-1", nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE /profiles/doc-1 = %d", resp.StatusCode)
+	}
+	resp, body = s.do(t, http.MethodGet, "/profiles/doc-1", nil, nil)
+	assertError(t, resp, body, http.StatusNotFound, ErrCodeNotFound)
+}
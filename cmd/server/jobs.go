@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// DefaultJobTTL is how long finished jobs and idempotency keys are retained
+const DefaultJobTTL = 24 * time.Hour
+
+// DefaultMaxRunningJobs caps the async jobs computing at once
+const DefaultMaxRunningJobs = 64
+
+// JobStatus is the lifecycle state of an async job
+type JobStatus string
+
+// Job statuses
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// JobProgress reports how much of each input a job had read. For the
+// in-memory metrics the texts are consumed in one step, so progress jumps
+// from zero to complete.
+type JobProgress struct {
+	OriginalBytes      int   `json:"original_bytes"`
+	AugmentedBytes     int   `json:"augmented_bytes"`
+	OriginalBytesRead  int64 `json:"original_bytes_read"`
+	AugmentedBytesRead int64 `json:"augmented_bytes_read"`
+}
+
+// Job is an async comparison and its outcome
+type Job struct {
+	ID        string       `json:"id"`
+	Metric    string       `json:"metric"`
+	Status    JobStatus    `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Result    *Response    `json:"result,omitempty"`
+	Error     *APIError    `json:"error,omitempty"`
+	Progress  *JobProgress `json:"progress,omitempty"`
+
+	cancel  context.CancelFunc
+	tracker *progressTracker
+	expires time.Time
+}
+
+// idempotencyEntry records the response to replay for a key
+type idempotencyEntry struct {
+	fingerprint string
+	done        bool
+	status      int
+	body        []byte
+	expires     time.Time
+}
+
+// jobStore keeps async jobs and idempotency keys in memory with a TTL
+type jobStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	jobs map[string]*Job
+	keys map[string]*idempotencyEntry
+	// slots holds one token per job currently computing
+	slots chan struct{}
+	// running counts the jobs holding a slot, so shutdown can wait for them
+	running sync.WaitGroup
+}
+
+// jobs is the process-wide job store, configured in main
+var jobs = newJobStore(DefaultJobTTL, DefaultMaxRunningJobs)
+
+// newJobStore creates an empty job store that runs at most maxRunning jobs at once
+func newJobStore(ttl time.Duration, maxRunning int) *jobStore {
+	return &jobStore{
+		ttl:   ttl,
+		jobs:  make(map[string]*Job),
+		keys:  make(map[string]*idempotencyEntry),
+		slots: make(chan struct{}, max(maxRunning, 1)),
+	}
+}
+
+// acquireSlot reserves room for one more running job without blocking. It
+// reports false when the cap is reached.
+func (s *jobStore) acquireSlot() bool {
+	select {
+	case s.slots <- struct{}{}:
+		s.running.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseSlot frees the room taken by acquireSlot
+func (s *jobStore) releaseSlot() {
+	<-s.slots
+	s.running.Done()
+}
+
+// wait blocks until every job holding a slot has released it. Jobs end by
+// their metric deadline at the latest. No slots may be acquired once it is called.
+func (s *jobStore) wait() {
+	s.running.Wait()
+}
+
+// newJobID returns a random job identifier
+func newJobID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// create registers a pending job and returns a snapshot of it
+func (s *jobStore) create(metric string, cancel context.CancelFunc, tracker *progressTracker) Job {
+	now := time.Now()
+	job := &Job{
+		ID:        newJobID(),
+		Metric:    metric,
+		Status:    JobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		cancel:    cancel,
+		tracker:   tracker,
+		expires:   now.Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	return *job
+}
+
+// get returns a snapshot of a job
+func (s *jobStore) get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	snapshot := *job
+	if snapshot.Progress == nil && snapshot.tracker != nil {
+		snapshot.Progress = snapshot.tracker.snapshot()
+	}
+	return snapshot, true
+}
+
+// update applies fn to a job under the store lock
+func (s *jobStore) update(id string, fn func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+		job.UpdatedAt = time.Now()
+		job.expires = job.UpdatedAt.Add(s.ttl)
+	}
+}
+
+// cancel cancels a pending or running job. It reports false if the job does
+// not exist and returns the job unchanged if it had already finished.
+func (s *jobStore) cancel(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	if job.Status == JobPending || job.Status == JobRunning {
+		job.Status = JobCancelled
+		job.UpdatedAt = time.Now()
+		job.expires = job.UpdatedAt.Add(s.ttl)
+		job.cancel()
+	}
+	return *job, true
+}
+
+// reserveKey claims an idempotency key for a request fingerprint. It returns
+// the stored entry when the key was already used, or nil when the caller now
+// owns the key and must complete or release it.
+func (s *jobStore) reserveKey(key, fingerprint string) *idempotencyEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.keys[key]; ok && time.Now().Before(entry.expires) {
+		replay := *entry
+		return &replay
+	}
+
+	s.keys[key] = &idempotencyEntry{fingerprint: fingerprint, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+// completeKey stores the response to replay for a reserved key
+func (s *jobStore) completeKey(key string, status int, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.keys[key]; ok {
+		entry.done = true
+		entry.status = status
+		entry.body = append([]byte(nil), body...)
+		entry.expires = time.Now().Add(s.ttl)
+	}
+}
+
+// releaseKey forgets a reserved key so a failed request can be retried
+func (s *jobStore) releaseKey(key string) {
+	s.mu.Lock()
+	delete(s.keys, key)
+	s.mu.Unlock()
+}
+
+// purgeExpired drops finished jobs and idempotency keys past their TTL
+func (s *jobStore) purgeExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, job := range s.jobs {
+		finished := job.Status != JobPending && job.Status != JobRunning
+		if finished && now.After(job.expires) {
+			delete(s.jobs, id)
+		}
+	}
+	for key, entry := range s.keys {
+		if now.After(entry.expires) {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// runJanitor purges expired entries every interval until ctx is done
+func (s *jobStore) runJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.purgeExpired(now)
+		}
+	}
+}
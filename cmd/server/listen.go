@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultSocketMode lets the server's group, such as a sidecar sharing the
+// socket volume, connect to a Unix socket
+const DefaultSocketMode = 0o660
+
+// listenAddress is where the server listens, parsed from -listen
+type listenAddress struct {
+	network string
+	address string
+}
+
+// String formats the address the way -listen accepts it
+func (a listenAddress) String() string {
+	if a.network == "unix" {
+		return "unix://" + a.address
+	}
+	return a.address
+}
+
+// parseListenAddress parses -listen: unix:///path/to.sock for a Unix socket,
+// tcp://host:port or host:port for TCP. Empty listens on all interfaces at port.
+func parseListenAddress(listen string, port int) (listenAddress, error) {
+	switch {
+	case listen == "":
+		return listenAddress{network: "tcp", address: ":" + strconv.Itoa(port)}, nil
+	case strings.HasPrefix(listen, "unix://"):
+		path := strings.TrimPrefix(listen, "unix://")
+		if path == "" {
+			return listenAddress{}, errors.New("-listen unix:// needs a socket path, such as unix:///var/run/similarity.sock")
+		}
+		return listenAddress{network: "unix", address: path}, nil
+	case strings.Contains(listen, "://") && !strings.HasPrefix(listen, "tcp://"):
+		return listenAddress{}, fmt.Errorf("-listen %q: scheme must be unix:// or tcp://", listen)
+	default:
+		address := strings.TrimPrefix(listen, "tcp://")
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return listenAddress{}, fmt.Errorf("-listen %q: %w", listen, err)
+		}
+		return listenAddress{network: "tcp", address: address}, nil
+	}
+}
+
+// listen opens the listener for addr. A Unix socket file left behind by a
+// server that did not shut down cleanly is replaced, but one that still
+// accepts connections belongs to a running server and is an error. The socket
+// is created with mode.
+func listen(addr listenAddress, mode os.FileMode) (net.Listener, error) {
+	if addr.network != "unix" {
+		return net.Listen(addr.network, addr.address)
+	}
+
+	if conn, err := net.Dial("unix", addr.address); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another server is listening on %s", addr)
+	}
+	if err := os.Remove(addr.address); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", addr.address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(addr.address, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	for listen, want := range map[string]listenAddress{
+		"":                                {network: "tcp", address: ":8080"},
+		"127.0.0.1:9000":                  {network: "tcp", address: "127.0.0.1:9000"},
+		"tcp://:9000":                     {network: "tcp", address: ":9000"},
+		"unix:///var/run/similarity.sock": {network: "unix", address: "/var/run/similarity.sock"},
+	} {
+		got, err := parseListenAddress(listen, 8080)
+		if err != nil {
+			t.Errorf("%q: %v", listen, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q = %+v, want %+v", listen, got, want)
+		}
+	}
+
+	for _, listen := range []string{"unix://", "http://:80", "no-port"} {
+		if _, err := parseListenAddress(listen, 8080); err == nil {
+			t.Errorf("%q: expected an error", listen)
+		}
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := listenAddress{network: "unix", address: filepath.Join(dir, "s.sock")}
+
+	// A stale socket file is replaced
+	if err := os.WriteFile(addr.address, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := listen(addr, DefaultSocketMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(addr.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != DefaultSocketMode {
+		t.Errorf("socket mode = %v, want %v", info.Mode().Perm(), os.FileMode(DefaultSocketMode))
+	}
+
+	server := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) { ctx.SetBodyString("ok") }}
+	go server.Serve(ln)
+	defer server.Shutdown()
+
+	// A live socket is not taken over
+	if _, err := listen(addr, DefaultSocketMode); err == nil || !strings.Contains(err.Error(), "another server") {
+		t.Errorf("err = %v, want another server is listening", err)
+	}
+
+	client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return net.Dial("unix", addr.address) }}
+	status, body, err := client.Get(nil, "http://similarity/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != fasthttp.StatusOK || string(body) != "ok" {
+		t.Errorf("GET over the socket = %d %s", status, body)
+	}
+}
@@ -6,16 +6,22 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/signal"
-	"runtime"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/internal/store"
+	"github.com/baditaflorin/go_length_similarity/pkg/config"
+	"github.com/baditaflorin/go_length_similarity/pkg/features"
+	"github.com/baditaflorin/go_length_similarity/pkg/metrics"
+	"github.com/baditaflorin/go_length_similarity/pkg/similarity"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
 	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
-	"github.com/baditaflorin/go_length_similarity/pkg/word"
 	"github.com/baditaflorin/l"
 	"github.com/valyala/fasthttp"
 )
@@ -29,23 +35,8 @@ const (
 	DefaultConcurrency    = 0                // 0 means use GOMAXPROCS
 )
 
-// Performance-tuned similarity calculators
-var (
-	// Length similarity calculator
-	lengthSimilarity *word.LengthSimilarity
-
-	// Character similarity calculator
-	charSimilarity *character.CharacterSimilarity
-
-	// Streaming similarity calculator
-	streamingSimilarity *streaming.StreamingSimilarity
-
-	// Allocation-efficient streaming calculator
-	efficientStreamingSimilarity *streaming.AllocationEfficientStreamingSimilarity
-
-	// Logger instance
-	logger l.Logger
-)
+// Logger instance
+var logger l.Logger
 
 // Request represents a similarity computation request
 type Request struct {
@@ -63,8 +54,11 @@ type StreamingRequest struct {
 
 // Response represents a similarity computation response
 type Response struct {
+	Engine          string                 `json:"engine"`
+	Mode            string                 `json:"mode"`
 	Score           float64                `json:"score"`
 	Passed          bool                   `json:"passed"`
+	Inconclusive    bool                   `json:"inconclusive,omitempty"`
 	OriginalLength  int                    `json:"original_length"`
 	AugmentedLength int                    `json:"augmented_length"`
 	LengthRatio     float64                `json:"length_ratio"`
@@ -72,61 +66,243 @@ type Response struct {
 	ProcessingTime  string                 `json:"processing_time,omitempty"`
 	BytesProcessed  int64                  `json:"bytes_processed,omitempty"`
 	Details         map[string]interface{} `json:"details,omitempty"`
+	Resources       *ResourceReport        `json:"resources,omitempty"`
+	Uncertainty     *UncertaintyReport     `json:"uncertainty,omitempty"`
+}
+
+// ResourceReport is what one comparison cost; present when -report-resources is set
+type ResourceReport struct {
+	AllocatedBytes  int64 `json:"allocated_bytes"`
+	Allocations     int64 `json:"allocations"`
+	PeakBufferBytes int64 `json:"peak_buffer_bytes"`
+	Workers         int   `json:"workers"`
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error string `json:"error"`
+// UncertaintyReport bounds the score by one unit of length difference; present when -report-uncertainty is set
+type UncertaintyReport struct {
+	Margin        float64 `json:"margin"`
+	Low           float64 `json:"low"`
+	High          float64 `json:"high"`
+	NearThreshold bool    `json:"near_threshold"`
 }
 
 func main() {
 	// Parse command-line flags
 	port := flag.Int("port", DefaultPort, "HTTP server port")
+	listenFlag := flag.String("listen", "", "Listen address: unix:///path/to.sock, tcp://host:port or host:port (default :<port>)")
+	socketMode := flag.Uint("socket-mode", DefaultSocketMode, "Permissions of the -listen unix:// socket file")
 	readTimeout := flag.Duration("read-timeout", DefaultReadTimeout, "HTTP read timeout")
 	writeTimeout := flag.Duration("write-timeout", DefaultWriteTimeout, "HTTP write timeout")
 	maxRequestSize := flag.Int("max-request-size", DefaultMaxRequestSize, "Maximum request size in bytes")
 	concurrency := flag.Int("concurrency", DefaultConcurrency, "Maximum number of concurrent requests (0 = GOMAXPROCS)")
 	warmUp := flag.Bool("warm-up", true, "Perform system warm-up on startup")
+	reportResources := flag.Bool("report-resources", false, "Include estimated allocations, peak buffer size and workers in each response")
+	reportUncertainty := flag.Bool("report-uncertainty", false, "Include the score's uncertainty from input sizes in each response")
+	var featureSet features.Set
+	flag.Var(&featureSet, "features", "Experimental features to turn on, or off with a leading '-': "+strings.Join(featureNames(), ", "))
 	logFile := flag.String("log-file", "", "Log file path (empty = stdout)")
+	flag.DurationVar(&logFlushTimeout, "log-flush-timeout", DefaultLogFlushTimeout, "How long shutdown waits for buffered log entries to be written (0 = until all are)")
+	configFile := flag.String("config", "", "JSON file of calculator settings, re-read on SIGHUP (see README)")
+	var deadlines MetricDeadlines
+	flag.DurationVar(&deadlines.Length, "length-deadline", DefaultLengthDeadline, "Deadline for /length requests")
+	flag.DurationVar(&deadlines.Character, "character-deadline", DefaultCharacterDeadline, "Deadline for /character requests")
+	flag.DurationVar(&deadlines.Streaming, "streaming-deadline", DefaultStreamingDeadline, "Deadline for /streaming requests")
+	flag.DurationVar(&deadlines.Efficient, "efficient-deadline", DefaultEfficientDeadline, "Deadline for /efficient requests")
+	flag.IntVar(&streamThreshold, "stream-threshold", DefaultStreamThreshold, "Body size in bytes above which /length and /character stream their inputs (0 = never)")
+	jobTTL := flag.Duration("job-ttl", DefaultJobTTL, "How long finished jobs and idempotency keys are kept")
+	maxRunningJobs := flag.Int("max-running-jobs", DefaultMaxRunningJobs, "Maximum number of async jobs computing at once; further submissions get 429")
+	profileTTL := flag.Duration("profile-ttl", DefaultProfileTTL, "How long an unused stored profile is kept, unless it was stored with its own ttl")
+	maxProfiles := flag.Int("max-profiles", DefaultMaxProfiles, "Maximum number of stored profiles")
+	flag.IntVar(&maxBatchItems, "max-batch-items", DefaultMaxBatchItems, "Maximum number of items in a /batch request")
+	webhookSecret := flag.String("webhook-secret", os.Getenv("SIMILARITY_WEBHOOK_SECRET"), "HMAC secret for signing job webhooks (default $SIMILARITY_WEBHOOK_SECRET)")
+	flag.IntVar(&webhooks.maxAttempts, "webhook-max-attempts", DefaultWebhookMaxAttempts, "Delivery attempts per webhook before it is dead-lettered")
+	flag.DurationVar(&webhooks.backoff, "webhook-backoff", DefaultWebhookBackoff, "Delay before the first webhook retry; doubles on each retry")
+	flag.StringVar(&webhooks.deadLetterPath, "webhook-dead-letter", "", "JSONL file for undeliverable webhooks (empty = log only)")
+	webhookAllow := flag.String("webhook-allow-cidrs", "", "Comma-separated CIDRs webhooks may reach even though they are loopback, private or link-local")
+	autoGOMAXPROCS := flag.Bool("auto-gomaxprocs", true, "Lower GOMAXPROCS to the container CPU quota (ignored when $GOMAXPROCS is set)")
+	maxParallelism := flag.Int("max-parallelism", 0, "Cap on worker goroutines shared by parallel processors and /batch (0 = unlimited)")
+	yieldInterval := flag.Int("yield-interval", 0, "Bytes a streaming comparison reads between yields to other goroutines (0 = never yield)")
+	flag.BoolVar(&scoreHeaders, "score-headers", false, "Emit X-Similarity-Score, X-Similarity-Passed and X-Config-Fingerprint headers on comparison responses")
+	bulkListen := flag.String("bulk-listen", "", "Also accept length-prefixed bulk streams at this address: unix:///path/to.sock, tcp://host:port or host:port")
+	bulkInput := flag.String("bulk-input", "", "Compute the bulk stream in this file (- = stdin) into -bulk-output, then exit without serving HTTP")
+	bulkOutput := flag.String("bulk-output", "", "JSONL file bulk results are appended to (- = stdout); a .parquet name writes a Parquet file instead")
+	statsdAddr := flag.String("statsd-addr", "", "Also send the calculators' metrics to this StatsD server (host:port, UDP)")
+	statsdPrefix := flag.String("statsd-prefix", "", "Prefix for the metric names sent to StatsD")
+	hashName := flag.String("hash", hasher.XXHashType.String(), "Hash for Idempotency-Key fingerprints: xxhash or sha256")
 	flag.Parse()
+	if err := config.SetFlagsFromEnv(flag.CommandLine, "SIMILARITY"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	webhooks.secret = []byte(*webhookSecret)
+	if err := deadlines.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	hashType, err := hasher.ParseType(*hashName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if webhooks.allowed, err = parseWebhookAllowlist(*webhookAllow); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	addr, err := parseListenAddress(*listenFlag, *port)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	var bulkAddr listenAddress
+	if *bulkListen != "" {
+		if bulkAddr, err = parseListenAddress(*bulkListen, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -bulk-listen: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if (*bulkListen != "" || *bulkInput != "") && *bulkOutput == "" {
+		fmt.Fprintln(os.Stderr, "Error: -bulk-listen and -bulk-input need -bulk-output")
+		os.Exit(2)
+	}
+	fingerprintHasher = hasher.New(hashType)
+	bulkMaxFieldSize = *maxRequestSize
+	similarity.SetMaxParallelism(*maxParallelism)
+	similarity.SetYieldInterval(*yieldInterval)
 
 	// Set up logger
-	var err error
 	logger, err = createLogger(*logFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating logger: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Close()
+	defer func() {
+		if shutdownLogger() != nil {
+			os.Exit(1)
+		}
+	}()
+	if len(webhooks.secret) == 0 {
+		logger.Warn("No webhook secret configured; job webhooks will be sent unsigned. Set -webhook-secret or $SIMILARITY_WEBHOOK_SECRET")
+	}
+
+	if *autoGOMAXPROCS {
+		if before, after := parallel.TuneGOMAXPROCS(); after != before {
+			logger.Info("Adjusted GOMAXPROCS to the container CPU quota",
+				"before", before,
+				"after", after,
+				"cpu_quota", parallel.CPUQuota(),
+			)
+		}
+	}
 
 	logger.Info("Starting similarity HTTP server",
-		"port", *port,
+		"address", addr.String(),
 		"read_timeout", *readTimeout,
 		"write_timeout", *writeTimeout,
 		"max_request_size", *maxRequestSize,
 		"concurrency", *concurrency,
+		"max_parallelism", *maxParallelism,
+		"deadlines", deadlines,
 	)
 
-	// Initialize similarity calculators
-	initSimilarityCalculators(*warmUp)
+	if *statsdAddr != "" {
+		statsd, err := metrics.NewStatsD(*statsdAddr, *statsdPrefix)
+		if err != nil {
+			logger.Error("Failed to set up StatsD", "address", *statsdAddr, "error", err)
+			exit(1)
+		}
+		defer statsd.Close()
+		serverMetrics = metrics.Multi(prometheusMetrics, statsd)
+	}
 
-	// Create HTTP server with fasthttp
-	server := &fasthttp.Server{
-		Handler:               requestHandler,
-		ReadTimeout:           *readTimeout,
-		WriteTimeout:          *writeTimeout,
-		MaxRequestBodySize:    *maxRequestSize,
-		Concurrency:           *concurrency,
-		DisableKeepalive:      false,
-		TCPKeepalive:          true,
-		TCPKeepalivePeriod:    3 * time.Minute,
-		MaxConnsPerIP:         0, // unlimited
-		MaxRequestsPerConn:    0, // unlimited
-		MaxIdleWorkerDuration: 10 * time.Second,
-		Logger:                nil, // we'll handle logging ourselves
+	// Initialize similarity calculators from flags and the config file
+	baseConfig := CalculatorConfig{ReportResources: *reportResources, ReportUncertainty: *reportUncertainty, Features: featureSet, Deadlines: deadlines}
+	calcConfig, err := loadCalculatorConfig(baseConfig, *configFile)
+	if err != nil {
+		logger.Error("Failed to read config file", "error", err)
+		exit(1)
+	}
+	initSimilarityCalculators(calcConfig, *warmUp)
+
+	var bulkSink sink.ResultSink
+	if *bulkOutput != "" {
+		if bulkSink, err = openBulkSink(*bulkOutput); err != nil {
+			logger.Error("Failed to open bulk output", "path", *bulkOutput, "error", err)
+			exit(1)
+		}
 	}
 
-	// Set up graceful shutdown
+	// -bulk-input computes one stream and exits without serving HTTP
+	if *bulkInput != "" {
+		summary := runBulkFile(context.Background(), *bulkInput, bulkSink)
+		if err := bulkSink.Close(); err != nil && summary.Error == "" {
+			summary.Error = "writing results: " + err.Error()
+		}
+		closeCalculators()
+		logger.Info("Bulk input finished", "path", *bulkInput, "records", summary.Records, "errors", summary.Errors)
+		if summary.Error != "" {
+			logger.Error("Bulk input failed", "path", *bulkInput, "error", summary.Error)
+			exit(1)
+		}
+		return
+	}
+	if bulkSink != nil {
+		defer func() {
+			if err := bulkSink.Close(); err != nil {
+				logger.Error("Error closing bulk output", "path", *bulkOutput, "error", err)
+			}
+		}()
+	}
+
+	// Rebuild the calculators from the config file on SIGHUP
+	if *configFile != "" {
+		go func() {
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			for range hup {
+				if err := reloadCalculators(baseConfig, *configFile, *warmUp); err != nil {
+					logger.Error("Config reload failed; keeping the current calculators", "path", *configFile, "error", err)
+					continue
+				}
+				logger.Info("Config reloaded", "path", *configFile)
+			}
+		}()
+	}
+
+	// Expire old jobs, idempotency keys and profiles in the background
+	jobs = newJobStore(*jobTTL, *maxRunningJobs)
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go jobs.runJanitor(janitorCtx, time.Minute)
+	profiles = store.NewProfiles(*profileTTL, *maxProfiles, fingerprintHasher)
+	go profiles.RunJanitor(janitorCtx, time.Minute)
+
+	server := newHTTPServer(*readTimeout, *writeTimeout, *maxRequestSize, *concurrency)
+
+	// Accept bulk streams alongside HTTP until shutdown
+	bulkCtx, stopBulk := context.WithCancel(context.Background())
+	defer stopBulk()
+	var bulkLn net.Listener
+	bulkDone := make(chan struct{})
+	if *bulkListen != "" {
+		if bulkLn, err = listen(bulkAddr, os.FileMode(*socketMode)); err != nil {
+			logger.Error("Failed to listen for bulk streams", "address", bulkAddr.String(), "error", err)
+			exit(1)
+		}
+		logger.Info("Bulk listener started", "address", bulkAddr.String())
+		go func() {
+			defer close(bulkDone)
+			if err := serveBulkListener(bulkCtx, bulkLn, bulkSink); err != nil {
+				logger.Error("Bulk listener error", "error", err)
+			}
+		}()
+	} else {
+		close(bulkDone)
+	}
+
+	// Set up graceful shutdown. Bulk streams are cancelled, while running jobs
+	// finish, within their deadline, so their webhooks are delivered or
+	// dead-lettered before the notifier and the calculators shut down.
 	idleConnsClosed := make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
@@ -137,12 +313,25 @@ func main() {
 		if err := server.Shutdown(); err != nil {
 			logger.Error("Error during server shutdown", "error", err)
 		}
+		if bulkLn != nil {
+			bulkLn.Close()
+		}
+		stopBulk()
+		<-bulkDone
+		jobs.wait()
+		webhooks.shutdown()
+		closeCalculators()
 		close(idleConnsClosed)
 	}()
 
 	// Start server
-	logger.Info("Server listening", "address", fmt.Sprintf(":%d", *port))
-	if err := server.ListenAndServe(fmt.Sprintf(":%d", *port)); err != nil {
+	ln, err := listen(addr, os.FileMode(*socketMode))
+	if err != nil {
+		logger.Error("Failed to listen", "address", addr.String(), "error", err)
+		exit(1)
+	}
+	logger.Info("Server listening", "address", addr.String())
+	if err := server.Serve(ln); err != nil {
 		logger.Error("Server error", "error", err)
 	}
 
@@ -150,65 +339,23 @@ func main() {
 	logger.Info("Server stopped")
 }
 
-// initSimilarityCalculators initializes the similarity calculators with performance optimizations
-func initSimilarityCalculators(warmUp bool) {
-	// Create length similarity calculator with fast normalizer
-	var err error
-	opts := []word.LengthSimilarityOption{
-		word.WithFastNormalizer(),
-	}
-
-	if warmUp {
-		opts = append(opts, word.WithWarmUp(true))
-	}
-
-	lengthSimilarity, err = word.New(opts...)
-	if err != nil {
-		logger.Error("Failed to initialize length similarity", "error", err)
-		os.Exit(1)
-	}
-
-	// Create character similarity calculator with optimized normalizer
-	charOpts := []character.CharacterSimilarityOption{
-		character.WithOptimizedNormalizer(),
-	}
-
-	if warmUp {
-		charOpts = append(charOpts, character.WithWarmUp(true))
-	}
-
-	charSimilarity, err = character.NewCharacterSimilarity(charOpts...)
-	if err != nil {
-		logger.Error("Failed to initialize character similarity", "error", err)
-		os.Exit(1)
-	}
-
-	// Create streaming similarity calculator
-	streamOpts := []streaming.StreamingOption{
-		streaming.WithOptimizedNormalizer(),
-		streaming.WithStreamingLogger(logger),
-	}
-
-	streamingSimilarity, err = streaming.NewStreamingSimilarity(streamOpts...)
-	if err != nil {
-		logger.Error("Failed to initialize streaming similarity", "error", err)
-		os.Exit(1)
-	}
-
-	// Create allocation-efficient streaming similarity calculator
-	efficientStreamingSimilarity, err = streaming.NewAllocationEfficientStreamingSimilarity(
-		logger,
-		streaming.WithEfficientParallel(true),
-	)
-	if err != nil {
-		logger.Error("Failed to initialize efficient streaming similarity", "error", err)
-		os.Exit(1)
+// newHTTPServer creates the fasthttp server that serves requestHandler
+func newHTTPServer(readTimeout, writeTimeout time.Duration, maxRequestSize, concurrency int) *fasthttp.Server {
+	return &fasthttp.Server{
+		Handler:               requestHandler,
+		ErrorHandler:          handleServerError,
+		ReadTimeout:           readTimeout,
+		WriteTimeout:          writeTimeout,
+		MaxRequestBodySize:    maxRequestSize,
+		Concurrency:           concurrency,
+		DisableKeepalive:      false,
+		TCPKeepalive:          true,
+		TCPKeepalivePeriod:    3 * time.Minute,
+		MaxConnsPerIP:         0, // unlimited
+		MaxRequestsPerConn:    0, // unlimited
+		MaxIdleWorkerDuration: 10 * time.Second,
+		Logger:                nil, // we'll handle logging ourselves
 	}
-
-	logger.Info("Similarity calculators initialized successfully",
-		"warm_up", warmUp,
-		"cpus", runtime.NumCPU(),
-	)
 }
 
 // requestHandler is the main fasthttp request handler
@@ -231,9 +378,29 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 		handleStreamingSimilarity(ctx)
 	case "/efficient":
 		handleEfficientStreamingSimilarity(ctx)
+	case "/batch":
+		handleBatch(ctx)
+	case "/jobs":
+		handleJobs(ctx)
+	case "/profiles":
+		handleProfiles(ctx)
+	case "/ui", "/ui/":
+		handleUI(ctx)
+	case "/metrics":
+		handleMetrics(ctx)
+	case "/debug/stats":
+		handleDebugStats(ctx)
 	default:
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
-		writeJSONError(ctx, "Not found")
+		switch path := string(ctx.Path()); {
+		case strings.HasPrefix(path, "/jobs/"):
+			handleJob(ctx)
+		case strings.HasPrefix(path, "/profiles/"):
+			handleProfile(ctx)
+		case strings.HasPrefix(path, "/compare/profile/"):
+			handleProfileCompare(ctx)
+		default:
+			writeAPIError(ctx, errNotFound())
+		}
 	}
 
 	// Log request
@@ -261,43 +428,43 @@ func handleHealthCheck(ctx *fasthttp.RequestCtx) {
 func handleLengthSimilarity(ctx *fasthttp.RequestCtx) {
 	// Only accept POST requests
 	if !ctx.IsPost() {
-		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
-		writeJSONError(ctx, "Method not allowed")
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	// Count very large payloads as streams instead of decoding giant strings
+	if shouldStream(ctx, true) {
+		calcs := currentCalculators()
+		handleStreamedRequest(ctx, MetricLength, calcs.config.Deadlines.Length, calcs.length.ComputeFromReaders)
 		return
 	}
 
 	// Parse request
 	var req Request
 	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Invalid request: "+err.Error())
+		writeAPIError(ctx, errInvalidJSON(err))
 		return
 	}
 
 	// Validate request
-	if req.Original == "" || req.Augmented == "" {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Both original and augmented texts are required")
+	if apiErr := req.validate(); apiErr != nil {
+		writeAPIError(ctx, apiErr)
 		return
 	}
 
-	// Create context with timeout
-	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Create context bounded by the metric's remaining deadline budget
+	calcs := currentCalculators()
+	c, cancel := requestContext(ctx, calcs.config.Deadlines.Length)
 	defer cancel()
 
 	// Compute similarity
-	result := lengthSimilarity.Compute(c, req.Original, req.Augmented)
+	result := calcs.length.Compute(c, req.Original, req.Augmented)
 
 	// Create response
-	response := Response{
-		Score:           result.Score,
-		Passed:          result.Passed,
-		OriginalLength:  result.OriginalLength,
-		AugmentedLength: result.AugmentedLength,
-		LengthRatio:     result.LengthRatio,
-		Threshold:       result.Threshold,
-		Details:         result.Details,
-	}
+	response := responseFromResult(result)
+
+	applyThreshold(&response, req.Threshold)
+	setScoreHeaders(ctx, MetricLength, response)
 
 	// Write response
 	ctx.SetStatusCode(fasthttp.StatusOK)
@@ -308,43 +475,43 @@ func handleLengthSimilarity(ctx *fasthttp.RequestCtx) {
 func handleCharacterSimilarity(ctx *fasthttp.RequestCtx) {
 	// Only accept POST requests
 	if !ctx.IsPost() {
-		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
-		writeJSONError(ctx, "Method not allowed")
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	// Count very large payloads as streams instead of decoding giant strings
+	if shouldStream(ctx, false) {
+		calcs := currentCalculators()
+		handleStreamedRequest(ctx, MetricCharacter, calcs.config.Deadlines.Character, calcs.character.ComputeFromReaders)
 		return
 	}
 
 	// Parse request
 	var req Request
 	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Invalid request: "+err.Error())
+		writeAPIError(ctx, errInvalidJSON(err))
 		return
 	}
 
 	// Validate request
-	if req.Original == "" || req.Augmented == "" {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Both original and augmented texts are required")
+	if apiErr := req.validate(); apiErr != nil {
+		writeAPIError(ctx, apiErr)
 		return
 	}
 
-	// Create context with timeout
-	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Create context bounded by the metric's remaining deadline budget
+	calcs := currentCalculators()
+	c, cancel := requestContext(ctx, calcs.config.Deadlines.Character)
 	defer cancel()
 
 	// Compute similarity
-	result := charSimilarity.Compute(c, req.Original, req.Augmented)
+	result := calcs.character.Compute(c, req.Original, req.Augmented)
 
 	// Create response
-	response := Response{
-		Score:           result.Score,
-		Passed:          result.Passed,
-		OriginalLength:  result.OriginalLength,
-		AugmentedLength: result.AugmentedLength,
-		LengthRatio:     result.LengthRatio,
-		Threshold:       result.Threshold,
-		Details:         result.Details,
-	}
+	response := responseFromResult(result)
+
+	applyThreshold(&response, req.Threshold)
+	setScoreHeaders(ctx, MetricCharacter, response)
 
 	// Write response
 	ctx.SetStatusCode(fasthttp.StatusOK)
@@ -355,47 +522,38 @@ func handleCharacterSimilarity(ctx *fasthttp.RequestCtx) {
 func handleStreamingSimilarity(ctx *fasthttp.RequestCtx) {
 	// Only accept POST requests
 	if !ctx.IsPost() {
-		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
-		writeJSONError(ctx, "Method not allowed")
+		writeAPIError(ctx, errMethodNotAllowed())
 		return
 	}
 
 	// Parse request
 	var req StreamingRequest
 	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Invalid request: "+err.Error())
+		writeAPIError(ctx, errInvalidJSON(err))
 		return
 	}
 
 	// Validate request
-	if req.Original == "" || req.Augmented == "" {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Both original and augmented texts are required")
+	if apiErr := req.validate(); apiErr != nil {
+		writeAPIError(ctx, apiErr)
 		return
 	}
 
-	// Create context with timeout
-	c, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	// Create context bounded by the metric's remaining deadline budget
+	calcs := currentCalculators()
+	c, cancel := requestContext(ctx, calcs.config.Deadlines.Streaming)
 	defer cancel()
 
 	// Compute similarity
 	originalReader := strings.NewReader(req.Original)
 	augmentedReader := strings.NewReader(req.Augmented)
-	result := streamingSimilarity.ComputeFromReaders(c, originalReader, augmentedReader)
+	result := calcs.streaming.ComputeFromReaders(c, originalReader, augmentedReader)
 
 	// Create response
-	response := Response{
-		Score:           result.Score,
-		Passed:          result.Passed,
-		OriginalLength:  result.OriginalLength,
-		AugmentedLength: result.AugmentedLength,
-		LengthRatio:     result.LengthRatio,
-		Threshold:       result.Threshold,
-		ProcessingTime:  result.ProcessingTime,
-		BytesProcessed:  result.BytesProcessed,
-		Details:         result.Details,
-	}
+	response := responseFromStream(result)
+
+	applyThreshold(&response, req.Threshold)
+	setScoreHeaders(ctx, MetricStreaming, response)
 
 	// Write response
 	ctx.SetStatusCode(fasthttp.StatusOK)
@@ -406,45 +564,36 @@ func handleStreamingSimilarity(ctx *fasthttp.RequestCtx) {
 func handleEfficientStreamingSimilarity(ctx *fasthttp.RequestCtx) {
 	// Only accept POST requests
 	if !ctx.IsPost() {
-		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
-		writeJSONError(ctx, "Method not allowed")
+		writeAPIError(ctx, errMethodNotAllowed())
 		return
 	}
 
 	// Parse request
 	var req StreamingRequest
 	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Invalid request: "+err.Error())
+		writeAPIError(ctx, errInvalidJSON(err))
 		return
 	}
 
 	// Validate request
-	if req.Original == "" || req.Augmented == "" {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		writeJSONError(ctx, "Both original and augmented texts are required")
+	if apiErr := req.validate(); apiErr != nil {
+		writeAPIError(ctx, apiErr)
 		return
 	}
 
-	// Create context with timeout
-	c, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	// Create context bounded by the metric's remaining deadline budget
+	calcs := currentCalculators()
+	c, cancel := requestContext(ctx, calcs.config.Deadlines.Efficient)
 	defer cancel()
 
 	// Compute similarity using the allocation-efficient implementation
-	result := efficientStreamingSimilarity.ComputeFromStrings(c, req.Original, req.Augmented)
+	result := calcs.efficient.ComputeFromStrings(c, req.Original, req.Augmented)
 
 	// Create response
-	response := Response{
-		Score:           result.Score,
-		Passed:          result.Passed,
-		OriginalLength:  result.OriginalLength,
-		AugmentedLength: result.AugmentedLength,
-		LengthRatio:     result.LengthRatio,
-		Threshold:       result.Threshold,
-		ProcessingTime:  result.ProcessingTime,
-		BytesProcessed:  result.BytesProcessed,
-		Details:         result.Details,
-	}
+	response := responseFromStream(result)
+
+	applyThreshold(&response, req.Threshold)
+	setScoreHeaders(ctx, MetricEfficient, response)
 
 	// Write response
 	ctx.SetStatusCode(fasthttp.StatusOK)
@@ -457,26 +606,8 @@ func handleEfficientStreamingSimilarity(ctx *fasthttp.RequestCtx) {
 func writeJSONResponse(ctx *fasthttp.RequestCtx, data interface{}) {
 	response, err := json.Marshal(data)
 	if err != nil {
-		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
 		logger.Error("Error marshaling JSON response", "error", err)
-		writeJSONError(ctx, "Internal server error")
-		return
-	}
-
-	ctx.SetBody(response)
-}
-
-// writeJSONError writes a JSON error response to the context
-func writeJSONError(ctx *fasthttp.RequestCtx, message string) {
-	errResponse := ErrorResponse{
-		Error: message,
-	}
-
-	response, err := json.Marshal(errResponse)
-	if err != nil {
-		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-		logger.Error("Error marshaling JSON error response", "error", err)
-		ctx.SetBodyString(`{"error":"Internal server error"}`)
+		writeAPIError(ctx, newAPIError(fasthttp.StatusInternalServerError, ErrCodeInternal, "Internal server error", ""))
 		return
 	}
 
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/pkg/metrics"
+	"github.com/valyala/fasthttp"
+)
+
+// prometheusMetrics is the registry /metrics serves
+var prometheusMetrics = metrics.NewPrometheus()
+
+// serverMetrics is what the calculators record to: the /metrics registry,
+// and a StatsD server as well when -statsd-addr is set
+var serverMetrics ports.Metrics = prometheusMetrics
+
+// handleMetrics serves the calculators' measurements in the Prometheus text format
+func handleMetrics(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetContentType("text/plain; version=0.0.4; charset=utf-8")
+	if _, err := prometheusMetrics.WriteTo(ctx); err != nil {
+		logger.Error("Failed to write metrics", "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/l"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = lg
+	t.Cleanup(func() { logger = nil; calculators.Store(nil) })
+
+	set, err := newCalculatorSet(CalculatorConfig{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculators.Store(set)
+	set.length.Compute(context.Background(), "the quick brown fox jumps", "the quick brown fox jumps")
+	set.streaming.ComputeFromStrings(context.Background(), "one two three\n", "one two three\n")
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/metrics")
+	handleMetrics(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("status = %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if ct := string(ctx.Response.Header.ContentType()); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %s, want text/plain", ct)
+	}
+	body := string(ctx.Response.Body())
+	for _, want := range []string{
+		`similarity_comparisons_total{engine="word",mode="text",outcome="passed"}`,
+		`similarity_comparisons_total{engine="streaming",mode="line",outcome="passed"}`,
+		"# TYPE similarity_compute_seconds histogram",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s in:\n%s", want, body)
+		}
+	}
+
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	handleMetrics(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d, want 405", ctx.Response.StatusCode())
+	}
+}
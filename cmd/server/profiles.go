@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/store"
+	"github.com/valyala/fasthttp"
+)
+
+// Profile defaults
+const (
+	DefaultProfileTTL  = 24 * time.Hour
+	DefaultMaxProfiles = 10000
+	maxProfileIDLength = 128
+)
+
+// ProfileRequest stores an original text under an ID. An empty ID is
+// generated; registering an existing ID replaces its original.
+type ProfileRequest struct {
+	ID       string `json:"id,omitempty"`
+	Original string `json:"original"`
+	// TTL is a Go duration such as "1h"; empty uses -profile-ttl
+	TTL string `json:"ttl,omitempty"`
+}
+
+// Profile describes a stored original; the text itself is never returned
+type Profile struct {
+	ID string `json:"id"`
+	// Fingerprint changes whenever the original does; it is also sent as the ETag
+	Fingerprint   string    `json:"fingerprint"`
+	OriginalBytes int       `json:"original_bytes"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+	TTL           string    `json:"ttl"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ProfileList is the response to GET /profiles
+type ProfileList struct {
+	Count    int       `json:"count"`
+	Profiles []Profile `json:"profiles"`
+}
+
+// ProfileCompareRequest compares an augmented text against a stored profile
+type ProfileCompareRequest struct {
+	Augmented string  `json:"augmented"`
+	Threshold float64 `json:"threshold,omitempty"`
+	// Metric defaults to length
+	Metric string `json:"metric,omitempty"`
+}
+
+// profiles is the process-wide profile store, configured in main
+var profiles = store.NewProfiles(DefaultProfileTTL, DefaultMaxProfiles, fingerprintHasher)
+
+// profileFromStore converts a stored profile to the API form
+func profileFromStore(p store.Profile) Profile {
+	return Profile{
+		ID:            p.ID,
+		Fingerprint:   p.Fingerprint,
+		OriginalBytes: p.OriginalBytes,
+		CreatedAt:     p.CreatedAt,
+		LastUsedAt:    p.LastUsedAt,
+		TTL:           p.TTL.String(),
+		ExpiresAt:     p.ExpiresAt,
+	}
+}
+
+// parseProfileTTL parses an optional profile TTL; empty means the server default
+func parseProfileTTL(s string) (time.Duration, *APIError) {
+	if s == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		return 0, newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField, "ttl must be a positive duration such as 30m or 24h", "ttl")
+	}
+	return ttl, nil
+}
+
+// validateProfileID checks a client-chosen profile ID
+func validateProfileID(id string) *APIError {
+	if len(id) > maxProfileIDLength {
+		return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField,
+			"id must be at most "+strconv.Itoa(maxProfileIDLength)+" characters", "id")
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField,
+				"id may only contain letters, digits, '-', '_' and '.'", "id")
+		}
+	}
+	return nil
+}
+
+// setProfileETag sends the profile fingerprint as a strong ETag
+func setProfileETag(ctx *fasthttp.RequestCtx, profile store.Profile) {
+	ctx.Response.Header.Set("ETag", `"`+profile.Fingerprint+`"`)
+}
+
+// storeProfile saves original under id and writes the profile, or the error
+// when the request is invalid, the store is full or the profile does not match
+// the request's If-Match
+func storeProfile(ctx *fasthttp.RequestCtx, id, original, ttlParam string) {
+	if original == "" {
+		writeAPIError(ctx, newAPIError(fasthttp.StatusBadRequest, ErrCodeMissingField, "original text is required", "original"))
+		return
+	}
+	if apiErr := validateProfileID(id); apiErr != nil {
+		writeAPIError(ctx, apiErr)
+		return
+	}
+	ttl, apiErr := parseProfileTTL(ttlParam)
+	if apiErr != nil {
+		writeAPIError(ctx, apiErr)
+		return
+	}
+	if id == "" {
+		id = newJobID()
+	}
+
+	// The store checks If-Match and reports a replacement under its lock, so
+	// concurrent writes cannot both pass the check or both report a creation
+	expected := strings.Trim(string(ctx.Request.Header.Peek("If-Match")), `"`)
+	profile, replaced, err := profiles.PutIf(id, original, ttl, expected)
+	switch {
+	case errors.Is(err, store.ErrMismatch):
+		writeAPIError(ctx, newAPIError(fasthttp.StatusPreconditionFailed, ErrCodePreconditionFailed,
+			"profile has changed; its fingerprint no longer matches If-Match", "If-Match"))
+		return
+	case err != nil:
+		writeAPIError(ctx, newAPIError(fasthttp.StatusInsufficientStorage, ErrCodeTooManyProfiles,
+			"profile limit of "+strconv.Itoa(profiles.Max())+" reached; delete unused profiles first", ""))
+		return
+	}
+
+	ctx.Response.Header.Set("Location", "/profiles/"+profile.ID)
+	setProfileETag(ctx, profile)
+	if replaced {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	} else {
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+	}
+	writeJSONResponse(ctx, profileFromStore(profile))
+}
+
+// handleProfiles lists profiles or stores a new one from a JSON body
+func handleProfiles(ctx *fasthttp.RequestCtx) {
+	switch {
+	case ctx.IsGet():
+		list := profiles.List()
+		response := ProfileList{Count: len(list), Profiles: make([]Profile, len(list))}
+		for i, profile := range list {
+			response.Profiles[i] = profileFromStore(profile)
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		writeJSONResponse(ctx, response)
+	case ctx.IsPost():
+		var req ProfileRequest
+		if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+			writeAPIError(ctx, errInvalidJSON(err))
+			return
+		}
+		storeProfile(ctx, req.ID, req.Original, req.TTL)
+	default:
+		writeAPIError(ctx, errMethodNotAllowed())
+	}
+}
+
+// handleProfile returns, uploads or deletes one profile. PUT takes the raw
+// request body as the original, so files can be uploaded without JSON encoding.
+func handleProfile(ctx *fasthttp.RequestCtx) {
+	id := strings.TrimPrefix(string(ctx.Path()), "/profiles/")
+
+	switch {
+	case ctx.IsGet():
+		profile, ok := profiles.Get(id)
+		if !ok {
+			writeAPIError(ctx, errNotFound())
+			return
+		}
+		setProfileETag(ctx, profile)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		writeJSONResponse(ctx, profileFromStore(profile))
+	case ctx.IsPut():
+		if id == "" {
+			writeAPIError(ctx, newAPIError(fasthttp.StatusBadRequest, ErrCodeMissingField, "profile id is required in the path", "id"))
+			return
+		}
+		storeProfile(ctx, id, string(ctx.PostBody()), string(ctx.QueryArgs().Peek("ttl")))
+	case ctx.IsDelete():
+		if !profiles.Delete(id) {
+			writeAPIError(ctx, errNotFound())
+			return
+		}
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+	default:
+		writeAPIError(ctx, errMethodNotAllowed())
+	}
+}
+
+// handleProfileCompare compares the augmented text in the request against a
+// stored profile's original. With If-Match, the comparison only runs if the
+// profile still has that fingerprint, so a replaced original is not scored
+// against silently.
+func handleProfileCompare(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	var req ProfileCompareRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeAPIError(ctx, errInvalidJSON(err))
+		return
+	}
+	if req.Metric == "" {
+		req.Metric = MetricLength
+	}
+	budget, ok := metricDeadline(req.Metric)
+	if !ok {
+		writeAPIError(ctx, errUnknownMetric(req.Metric))
+		return
+	}
+	if req.Augmented == "" {
+		writeAPIError(ctx, errMissingField("augmented"))
+		return
+	}
+	if apiErr := validateThreshold(req.Threshold); apiErr != nil {
+		writeAPIError(ctx, apiErr)
+		return
+	}
+
+	profile, ok := profiles.Use(strings.TrimPrefix(string(ctx.Path()), "/compare/profile/"))
+	if !ok {
+		writeAPIError(ctx, errNotFound())
+		return
+	}
+	setProfileETag(ctx, profile)
+	if match := string(ctx.Request.Header.Peek("If-Match")); match != "" && match != "*" &&
+		strings.Trim(match, `"`) != profile.Fingerprint {
+		writeAPIError(ctx, newAPIError(fasthttp.StatusPreconditionFailed, ErrCodePreconditionFailed,
+			"profile has changed; its fingerprint no longer matches If-Match", "If-Match"))
+		return
+	}
+
+	c, cancel := requestContext(ctx, budget)
+	defer cancel()
+
+	response := computeMetric(c, req.Metric, Request{
+		Original:  profile.Original,
+		Augmented: req.Augmented,
+		Threshold: req.Threshold,
+	}, nil)
+	setScoreHeaders(ctx, req.Metric, response)
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	writeJSONResponse(ctx, response)
+}
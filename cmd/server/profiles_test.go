@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/store"
+	"github.com/valyala/fasthttp"
+)
+
+func TestProfileUploadAndList(t *testing.T) {
+	profiles = store.NewProfiles(DefaultProfileTTL, DefaultMaxProfiles, nil)
+
+	put := &fasthttp.RequestCtx{}
+	put.Request.Header.SetMethod(fasthttp.MethodPut)
+	put.Request.SetRequestURI("/profiles/doc-1?ttl=1h")
+	put.Request.SetBodyString("raw original text")
+	handleProfile(put)
+	if put.Response.StatusCode() != fasthttp.StatusCreated {
+		t.Fatalf("PUT status = %d: %s", put.Response.StatusCode(), put.Response.Body())
+	}
+	if got, ok := profiles.Get("doc-1"); !ok || got.Original != "raw original text" || got.TTL != time.Hour {
+		t.Errorf("stored profile = %+v, %v", got, ok)
+	}
+
+	post := newPostCtx("/profiles", "", `{"original":"json original","ttl":"forever"}`)
+	handleProfiles(post)
+	if post.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("invalid ttl status = %d, want 400", post.Response.StatusCode())
+	}
+
+	list := &fasthttp.RequestCtx{}
+	list.Request.Header.SetMethod(fasthttp.MethodGet)
+	list.Request.SetRequestURI("/profiles")
+	handleProfiles(list)
+	var got ProfileList
+	if err := json.Unmarshal(list.Response.Body(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Count != 1 || got.Profiles[0].ID != "doc-1" || got.Profiles[0].TTL != "1h0m0s" {
+		t.Errorf("list = %+v", got)
+	}
+	if strings.Contains(string(list.Response.Body()), "raw original text") {
+		t.Error("list exposes the original text")
+	}
+}
+
+func TestHandleProfileCompareChecks(t *testing.T) {
+	useBulkCalculators(t)
+	profiles = store.NewProfiles(DefaultProfileTTL, DefaultMaxProfiles, nil)
+	if _, err := profiles.Put("src", "original text", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := newPostCtx("/compare/profile/nope", "", `{"augmented":"text"}`)
+	handleProfileCompare(missing)
+	if missing.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Errorf("unknown profile status = %d, want 404", missing.Response.StatusCode())
+	}
+
+	stale := newPostCtx("/compare/profile/src", "", `{"augmented":"text"}`)
+	stale.Request.Header.Set("If-Match", `"0000"`)
+	handleProfileCompare(stale)
+	if stale.Response.StatusCode() != fasthttp.StatusPreconditionFailed {
+		t.Errorf("stale If-Match status = %d, want 412", stale.Response.StatusCode())
+	}
+
+	badMetric := newPostCtx("/compare/profile/src", "", `{"augmented":"text","metric":"nope"}`)
+	handleProfileCompare(badMetric)
+	if badMetric.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("unknown metric status = %d, want 400", badMetric.Response.StatusCode())
+	}
+}
+
+func TestValidateProfileID(t *testing.T) {
+	if validateProfileID("doc-42_v1.txt") != nil {
+		t.Error("valid ID rejected")
+	}
+	if validateProfileID("../etc") == nil || validateProfileID("a b") == nil {
+		t.Error("invalid ID accepted")
+	}
+}
+
+func TestProfilePutIfMatch(t *testing.T) {
+	profiles = store.NewProfiles(DefaultProfileTTL, DefaultMaxProfiles, nil)
+	put := func(body, ifMatch string) *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(fasthttp.MethodPut)
+		ctx.Request.SetRequestURI("/profiles/doc-1")
+		if ifMatch != "" {
+			ctx.Request.Header.Set("If-Match", ifMatch)
+		}
+		ctx.Request.SetBodyString(body)
+		handleProfile(ctx)
+		return ctx
+	}
+
+	first := put("first text", "")
+	if first.Response.StatusCode() != fasthttp.StatusCreated {
+		t.Fatalf("first PUT status = %d", first.Response.StatusCode())
+	}
+	etag := string(first.Response.Header.Peek("ETag"))
+
+	if stale := put("other text", `"0000"`); stale.Response.StatusCode() != fasthttp.StatusPreconditionFailed {
+		t.Errorf("PUT with a stale If-Match = %d, want 412", stale.Response.StatusCode())
+	}
+	if got, _ := profiles.Get("doc-1"); got.Original != "first text" {
+		t.Errorf("failed precondition replaced the original with %q", got.Original)
+	}
+	if ok := put("second text", etag); ok.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("PUT with the current ETag = %d, want 200", ok.Response.StatusCode())
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultStreamThreshold is the request body size above which /length and
+// /character count their inputs as streams instead of decoding them into strings
+const DefaultStreamThreshold = 1024 * 1024 // 1MB
+
+// streamThreshold is configured from flags in main; 0 disables routing
+var streamThreshold = DefaultStreamThreshold
+
+// rawRequest mirrors Request but keeps the texts as undecoded JSON strings
+type rawRequest struct {
+	Original  json.RawMessage `json:"original"`
+	Augmented json.RawMessage `json:"augmented"`
+	Threshold float64         `json:"threshold,omitempty"`
+}
+
+// shouldStream reports whether the request body is large enough to be counted
+// as a stream. Word counting strips markup from the whole text, which cannot be
+// done chunk by chunk, so markup-sensitive metrics keep the in-memory path when
+// the body contains any tag opener.
+func shouldStream(ctx *fasthttp.RequestCtx, markupSensitive bool) bool {
+	body := ctx.PostBody()
+	if streamThreshold <= 0 || len(body) < streamThreshold {
+		return false
+	}
+	if markupSensitive && (bytes.IndexByte(body, '<') >= 0 ||
+		bytes.Contains(body, []byte(`\u003c`)) || bytes.Contains(body, []byte(`\u003C`))) {
+		return false
+	}
+	return true
+}
+
+// handleStreamedRequest decodes a large request without materializing its texts
+// and scores it with compute, which must count exactly like the in-memory engine
+func handleStreamedRequest(
+	ctx *fasthttp.RequestCtx,
+	metric string,
+	budget time.Duration,
+	compute func(c context.Context, original, augmented io.Reader) domain.Result,
+) {
+	var req rawRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeAPIError(ctx, errInvalidJSON(err))
+		return
+	}
+
+	if apiErr := validateTexts(absentText(req.Original), absentText(req.Augmented)); apiErr != nil {
+		writeAPIError(ctx, apiErr)
+		return
+	}
+	original, err := newJSONStringReader(req.Original)
+	if err != nil {
+		writeAPIError(ctx, newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField, "original "+err.Error(), "original"))
+		return
+	}
+	augmented, err := newJSONStringReader(req.Augmented)
+	if err != nil {
+		writeAPIError(ctx, newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField, "augmented "+err.Error(), "augmented"))
+		return
+	}
+	if apiErr := validateTexts(original.empty(), augmented.empty()); apiErr != nil {
+		writeAPIError(ctx, apiErr)
+		return
+	}
+	if apiErr := validateThreshold(req.Threshold); apiErr != nil {
+		writeAPIError(ctx, apiErr)
+		return
+	}
+
+	c, cancel := requestContext(ctx, budget)
+	defer cancel()
+
+	result := compute(c, original, augmented)
+	response := responseFromResult(result)
+	applyThreshold(&response, req.Threshold)
+	setScoreHeaders(ctx, metric, response)
+
+	ctx.Response.Header.Set("X-Similarity-Engine", "streaming")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	writeJSONResponse(ctx, response)
+}
+
+// absentText reports whether a text field was omitted or null, matching how
+// the buffered path decodes both into an empty string
+func absentText(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+// errNotJSONString is returned when a text field is not a JSON string
+var errNotJSONString = errors.New("must be a JSON string")
+
+// jsonStringReader unescapes a raw JSON string literal incrementally
+type jsonStringReader struct {
+	src     []byte
+	pending []byte
+	buf     [utf8.UTFMax]byte
+}
+
+// newJSONStringReader returns a reader over the decoded contents of raw, which
+// must be a JSON string literal already validated by encoding/json
+func newJSONStringReader(raw json.RawMessage) (*jsonStringReader, error) {
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return nil, errNotJSONString
+	}
+	return &jsonStringReader{src: raw[1 : len(raw)-1]}, nil
+}
+
+// empty reports whether the decoded string is empty
+func (r *jsonStringReader) empty() bool {
+	return len(r.src) == 0 && len(r.pending) == 0
+}
+
+// Read implements io.Reader
+func (r *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) > 0 {
+			c := copy(p[n:], r.pending)
+			r.pending = r.pending[c:]
+			n += c
+			continue
+		}
+		if len(r.src) == 0 {
+			break
+		}
+
+		// Copy plain bytes up to the next escape in one go
+		i := bytes.IndexByte(r.src, '\\')
+		if i != 0 {
+			if i < 0 {
+				i = len(r.src)
+			}
+			c := copy(p[n:], r.src[:i])
+			r.src = r.src[c:]
+			n += c
+			continue
+		}
+
+		r.pending = r.decodeEscape()
+	}
+
+	if n == 0 && r.empty() {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// decodeEscape consumes the escape sequence at the start of src and returns its UTF-8 bytes
+func (r *jsonStringReader) decodeEscape() []byte {
+	c := r.src[1]
+	r.src = r.src[2:]
+
+	switch c {
+	case 'b':
+		return append(r.buf[:0], '\b')
+	case 'f':
+		return append(r.buf[:0], '\f')
+	case 'n':
+		return append(r.buf[:0], '\n')
+	case 'r':
+		return append(r.buf[:0], '\r')
+	case 't':
+		return append(r.buf[:0], '\t')
+	case 'u':
+		ru := r.hex4()
+		if utf16.IsSurrogate(ru) {
+			// Combine a surrogate pair; a lone surrogate decodes to U+FFFD like encoding/json
+			low := utf8.RuneError
+			if len(r.src) >= 6 && r.src[0] == '\\' && r.src[1] == 'u' {
+				saved := r.src
+				r.src = r.src[2:]
+				if low = r.hex4(); utf16.DecodeRune(ru, low) == utf8.RuneError {
+					r.src = saved
+				}
+			}
+			ru = utf16.DecodeRune(ru, low)
+		}
+		return r.buf[:utf8.EncodeRune(r.buf[:], ru)]
+	default: // '"', '\\' and '/'
+		return append(r.buf[:0], c)
+	}
+}
+
+// hex4 consumes four hex digits from src
+func (r *jsonStringReader) hex4() rune {
+	var v rune
+	for _, h := range r.src[:4] {
+		v <<= 4
+		switch {
+		case h >= '0' && h <= '9':
+			v |= rune(h - '0')
+		case h >= 'a' && h <= 'f':
+			v |= rune(h - 'a' + 10)
+		case h >= 'A' && h <= 'F':
+			v |= rune(h - 'A' + 10)
+		}
+	}
+	r.src = r.src[4:]
+	return v
+}
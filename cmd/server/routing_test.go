@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestJSONStringReaderMatchesUnmarshal(t *testing.T) {
+	for _, want := range []string{
+		"",
+		"plain ascii text",
+		"quotes \" and \\ backslashes / slashes",
+		"controls \b\f\n\r\t and \x01",
+		"unicode é 日本語 and emoji 😀",
+		"<html> & 'apostrophes'",
+	} {
+		raw, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := newJSONStringReader(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(iotest.OneByteReader(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("decoded %s = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestJSONStringReaderSurrogates(t *testing.T) {
+	for raw, want := range map[string]string{
+		`"\ud83d\ude00"`: "😀",
+		`"\ud83d\u0041"`: "�A",
+		`"\ud83dx"`:      "�x",
+		`"\ude00A"`:      "�A",
+		`"\ud83dA"`:      "�A",
+	} {
+		r, err := newJSONStringReader(json.RawMessage(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(r)
+
+		var ref string
+		if err := json.Unmarshal([]byte(raw), &ref); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want || ref != want {
+			t.Errorf("decoded %s = %q (encoding/json %q), want %q", raw, got, ref, want)
+		}
+	}
+}
+
+func TestMissingTextCodeIsEngineIndependent(t *testing.T) {
+	useBulkCalculators(t)
+	saved := streamThreshold
+	t.Cleanup(func() { streamThreshold = saved })
+
+	cases := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"omitted original", `{"augmented":"some text"}`, "original"},
+		{"null original", `{"original":null,"augmented":"some text"}`, "original"},
+		{"empty original", `{"original":"","augmented":"some text"}`, "original"},
+		{"null augmented", `{"original":"some text","augmented":null}`, "augmented"},
+	}
+
+	for _, engine := range []struct {
+		name      string
+		threshold int
+	}{{"buffered", 0}, {"streamed", 1}} {
+		streamThreshold = engine.threshold
+		for _, tc := range cases {
+			for path, handler := range map[string]fasthttp.RequestHandler{
+				"/similarity/length":    handleLengthSimilarity,
+				"/similarity/character": handleCharacterSimilarity,
+			} {
+				ctx := newPostCtx(path, "", tc.body)
+				handler(ctx)
+
+				var response ErrorResponse
+				if err := json.Unmarshal(ctx.Response.Body(), &response); err != nil {
+					t.Fatalf("%s %s %s: %v", engine.name, path, tc.name, err)
+				}
+				if ctx.Response.StatusCode() != fasthttp.StatusBadRequest || response.Error == nil ||
+					response.Error.Code != ErrCodeMissingField || response.Error.Field != tc.field {
+					t.Errorf("%s %s %s: status %d, error %+v, want %s on %s",
+						engine.name, path, tc.name, ctx.Response.StatusCode(), response.Error, ErrCodeMissingField, tc.field)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/jsonschema"
+	"github.com/valyala/fasthttp"
+)
+
+var update = flag.Bool("update", false, "rewrite the published schemas from the Go structs")
+
+// publishedSchemas are the response payloads documented in the schema directory
+var publishedSchemas = []struct {
+	file  string
+	title string
+	value interface{}
+}{
+	{"response.schema.json", "Similarity response", Response{}},
+	{"error.schema.json", "Error response", ErrorResponse{}},
+	{"batch-response.schema.json", "Batch response", BatchResponse{}},
+	{"job.schema.json", "Async job", Job{}},
+	{"profile.schema.json", "Stored profile", Profile{}},
+}
+
+func publishedSchema(t *testing.T, file string) *jsonschema.Schema {
+	t.Helper()
+	for _, p := range publishedSchemas {
+		if p.file == file {
+			return jsonschema.Generate(p.value, jsonschema.BaseID+p.file, p.title)
+		}
+	}
+	t.Fatalf("no published schema %s", file)
+	return nil
+}
+
+func TestPublishedSchemasAreCurrent(t *testing.T) {
+	for _, p := range publishedSchemas {
+		if err := jsonschema.CheckFile("../../schema/"+p.file, publishedSchema(t, p.file), *update); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestResponsesMatchSchemas(t *testing.T) {
+	client := startContractServer(t)
+	tests := []struct {
+		method, path, body string
+		schema             string
+	}{
+		{"POST", "/length", `{"original": "one two three", "augmented": "one two"}`, "response.schema.json"},
+		{"POST", "/character", `{"original": "abcdef", "augmented": "abcde", "threshold": 0.5}`, "response.schema.json"},
+		{"POST", "/streaming", `{"original": "one two three", "augmented": "one two"}`, "response.schema.json"},
+		{"POST", "/efficient", `{"original": "one two three", "augmented": "one two"}`, "response.schema.json"},
+		{"POST", "/batch", `{"metric": "length", "items": [{"id": "a", "original": "x y", "augmented": "x"}, {"id": "b"}]}`, "batch-response.schema.json"},
+		{"POST", "/jobs", `{"metric": "streaming", "original": "abc", "augmented": "ab"}`, "job.schema.json"},
+		{"POST", "/profiles", `{"id": "schema", "original": "stored"}`, "profile.schema.json"},
+		{"POST", "/length", `{"original": "text"}`, "error.schema.json"},
+		{"GET", "/missing", ``, "error.schema.json"},
+	}
+	for _, tt := range tests {
+		req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		req.SetRequestURI("http://schema" + tt.path)
+		req.Header.SetMethod(tt.method)
+		req.SetBodyString(tt.body)
+		if err := client.Do(req, resp); err != nil {
+			t.Fatal(err)
+		}
+		if err := jsonschema.Validate(publishedSchema(t, tt.schema), resp.Body()); err != nil {
+			t.Errorf("%s %s: %v\n%s", tt.method, tt.path, err, resp.Body())
+		}
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultLogFlushTimeout bounds how long shutdown waits for buffered log entries
+const DefaultLogFlushTimeout = 5 * time.Second
+
+// logFlushTimeout is set from -log-flush-timeout; zero waits until every
+// buffered entry is written
+var logFlushTimeout = DefaultLogFlushTimeout
+
+// errLogFlushTimeout is returned when the logger did not close in time
+var errLogFlushTimeout = errors.New("timed out writing buffered log entries")
+
+// closeLogger flushes and closes an asynchronous logger, giving up after
+// timeout; a timeout of zero waits for as long as it takes
+func closeLogger(c io.Closer, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- c.Close() }()
+	if timeout <= 0 {
+		return <-done
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errLogFlushTimeout
+	}
+}
+
+// shutdownLogger closes the server logger. Failures go to standard error,
+// since the logger can no longer record them.
+func shutdownLogger() error {
+	if logger == nil {
+		return nil
+	}
+	err := closeLogger(logger, logFlushTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error closing logger, buffered log entries may be lost: %v\n", err)
+	}
+	return err
+}
+
+// exit closes the logger before exiting, so the entries logged just before a
+// fatal error are written rather than dropped with the buffer
+func exit(code int) {
+	if shutdownLogger() != nil && code == 0 {
+		code = 1
+	}
+	os.Exit(code)
+}
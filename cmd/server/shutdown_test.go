@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// closerFunc adapts a function to io.Closer
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestCloseLogger(t *testing.T) {
+	failed := errors.New("disk full")
+	if err := closeLogger(closerFunc(func() error { return failed }), time.Second); !errors.Is(err, failed) {
+		t.Errorf("expected the close error to be returned, got %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := closerFunc(func() error { <-release; return nil })
+	if err := closeLogger(slow, 10*time.Millisecond); !errors.Is(err, errLogFlushTimeout) {
+		t.Errorf("expected errLogFlushTimeout, got %v", err)
+	}
+
+	closed := false
+	wait := closerFunc(func() error { time.Sleep(20 * time.Millisecond); closed = true; return nil })
+	if err := closeLogger(wait, 0); err != nil || !closed {
+		t.Errorf("expected a zero timeout to wait for the close, got %v, closed %v", err, closed)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	_ "embed"
+
+	"github.com/valyala/fasthttp"
+)
+
+// uiPage is a single-page form for manual comparisons against the JSON API
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+// handleUI serves the embedded comparison page
+func handleUI(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() && !ctx.IsHead() {
+		writeAPIError(ctx, errMethodNotAllowed())
+		return
+	}
+
+	ctx.Response.Header.Set("Content-Type", "text/html; charset=utf-8")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBody(uiPage)
+}
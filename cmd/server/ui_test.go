@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandleUI(t *testing.T) {
+	useBulkCalculators(t)
+
+	for _, path := range []string{"/ui", "/ui/"} {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+		ctx.Request.SetRequestURI(path)
+		requestHandler(ctx)
+
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, ctx.Response.StatusCode())
+		}
+		if ct := string(ctx.Response.Header.ContentType()); ct != "text/html; charset=utf-8" {
+			t.Errorf("GET %s Content-Type = %q, want text/html; charset=utf-8", path, ct)
+		}
+		if !bytes.Contains(ctx.Response.Body(), []byte("<title>Similarity check</title>")) {
+			t.Errorf("GET %s served no comparison page", path)
+		}
+	}
+
+	post := newPostCtx("/ui", "", "")
+	handleUI(post)
+	if post.Response.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Errorf("POST /ui = %d, want 405", post.Response.StatusCode())
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Webhook defaults
+const (
+	DefaultWebhookMaxAttempts = 5
+	DefaultWebhookBackoff     = time.Second
+	DefaultWebhookTimeout     = 10 * time.Second
+)
+
+// Webhook request headers
+const (
+	webhookSignatureHeader = "X-Similarity-Signature"
+	webhookTimestampHeader = "X-Similarity-Timestamp"
+	webhookEventHeader     = "X-Similarity-Event"
+)
+
+// errWebhookShutdown is the last error of deliveries still pending at shutdown
+var errWebhookShutdown = errors.New("server shut down before the webhook was delivered")
+
+// webhookNotifier delivers finished jobs to their webhook URLs. Each delivery
+// is signed with HMAC-SHA256 over "<timestamp>.<body>", retried with
+// exponential backoff, and appended to a dead-letter file once all attempts
+// fail or the server shuts down. Targets that resolve to loopback, private or
+// link-local addresses are refused unless allowed is a prefix containing them.
+type webhookNotifier struct {
+	client      *fasthttp.Client
+	secret      []byte
+	maxAttempts int
+	backoff     time.Duration
+	timeout     time.Duration
+	allowed     []netip.Prefix
+
+	deadLetterMu   sync.Mutex
+	deadLetterPath string
+
+	// pending tracks deliveries in flight; done is closed at shutdown
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}
+	pending sync.WaitGroup
+}
+
+// webhooks is configured from flags in main
+var webhooks = newWebhookNotifier()
+
+// newWebhookNotifier creates a notifier with the default settings
+func newWebhookNotifier() *webhookNotifier {
+	w := &webhookNotifier{
+		maxAttempts: DefaultWebhookMaxAttempts,
+		backoff:     DefaultWebhookBackoff,
+		timeout:     DefaultWebhookTimeout,
+		done:        make(chan struct{}),
+	}
+	// Check every address the client connects to, so a name that resolves
+	// differently after validation cannot reach an internal host
+	w.client = &fasthttp.Client{Dial: w.dial}
+	return w
+}
+
+// deadLetter is one undeliverable webhook event
+type deadLetter struct {
+	Time      time.Time       `json:"time"`
+	URL       string          `json:"url"`
+	JobID     string          `json:"job_id"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// parseWebhookAllowlist parses the comma-separated CIDRs of -webhook-allow-cidrs
+func parseWebhookAllowlist(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook allowlist entry %q: %w", field, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// validateURL checks that a job's webhook URL is an absolute http(s) URL
+// whose host resolves only to addresses the notifier may reach
+func (w *webhookNotifier) validateURL(raw string) *APIError {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField, "webhook_url must be an absolute http or https URL", "webhook_url")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	if _, err := w.resolve(ctx, u.Hostname()); err != nil {
+		return newAPIError(fasthttp.StatusBadRequest, ErrCodeInvalidField, "webhook_url: "+err.Error(), "webhook_url")
+	}
+	return nil
+}
+
+// resolve looks up host and fails if any of its addresses is internal and
+// not allowlisted
+func (w *webhookNotifier) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve %s: %w", host, err)
+		}
+		addrs = ips
+	}
+
+	for _, addr := range addrs {
+		if !w.reachable(addr.Unmap()) {
+			return nil, fmt.Errorf("%s resolves to %s, an internal address", host, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// reachable reports whether deliveries may go to addr
+func (w *webhookNotifier) reachable(addr netip.Addr) bool {
+	for _, p := range w.allowed {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	internal := addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified()
+	return !internal
+}
+
+// dial connects to a checked address of addr for the HTTP client
+func (w *webhookNotifier) dial(addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	addrs, err := w.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	for _, ip := range addrs {
+		var conn net.Conn
+		if conn, err = d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// sign returns the signature header value for a payload sent at timestamp
+func (w *webhookNotifier) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify delivers a finished job in the background. After shutdown the
+// event goes straight to the dead letters.
+func (w *webhookNotifier) notify(target string, job Job) {
+	if target == "" {
+		return
+	}
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		logger.Error("Error marshaling webhook payload", "job_id", job.ID, "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		w.writeDeadLetter(deadLetter{Time: time.Now(), URL: target, JobID: job.ID, LastError: errWebhookShutdown.Error(), Payload: body})
+		return
+	}
+	w.pending.Add(1)
+	w.mu.Unlock()
+
+	go func() {
+		defer w.pending.Done()
+		w.deliver(target, job.ID, body)
+	}()
+}
+
+// shutdown stops waiting retries, which dead-letter their events, and waits
+// for every pending delivery to finish
+func (w *webhookNotifier) shutdown() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.done)
+	}
+	w.mu.Unlock()
+	w.pending.Wait()
+}
+
+// deliver posts body to target until it succeeds, attempts run out or the
+// notifier shuts down
+func (w *webhookNotifier) deliver(target, jobID string, body []byte) {
+	var lastErr error
+	delay := w.backoff
+	attempts := max(w.maxAttempts, 1)
+
+	attempt := 1
+	for ; attempt <= attempts; attempt++ {
+		if lastErr = w.post(target, body); lastErr == nil {
+			logger.Debug("Webhook delivered", "job_id", jobID, "url", target, "attempt", attempt)
+			return
+		}
+
+		logger.Warn("Webhook delivery failed", "job_id", jobID, "url", target, "attempt", attempt, "error", lastErr)
+		if attempt == attempts {
+			break
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.done:
+			timer.Stop()
+			lastErr = fmt.Errorf("%w (last error: %v)", errWebhookShutdown, lastErr)
+		}
+		if errors.Is(lastErr, errWebhookShutdown) {
+			break
+		}
+		delay *= 2
+	}
+
+	w.writeDeadLetter(deadLetter{
+		Time:      time.Now(),
+		URL:       target,
+		JobID:     jobID,
+		Attempts:  min(attempt, attempts),
+		LastError: lastErr.Error(),
+		Payload:   body,
+	})
+}
+
+// post sends one signed delivery attempt; any non-2xx status is an error
+func (w *webhookNotifier) post(target string, body []byte) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.SetRequestURI(target)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	req.Header.Set(webhookEventHeader, "job.finished")
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	if len(w.secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, w.sign(timestamp, body))
+	}
+	req.SetBody(body)
+
+	if err := w.client.DoTimeout(req, resp, w.timeout); err != nil {
+		return err
+	}
+	if status := resp.StatusCode(); status < 200 || status >= 300 {
+		return fmt.Errorf("webhook responded with status %d", status)
+	}
+	return nil
+}
+
+// writeDeadLetter records an undeliverable event, falling back to the log
+// when no dead-letter file is configured or it cannot be written
+func (w *webhookNotifier) writeDeadLetter(entry deadLetter) {
+	logger.Error("Webhook moved to dead letter", "job_id", entry.JobID, "url", entry.URL, "attempts", entry.Attempts, "error", entry.LastError)
+	if w.deadLetterPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logger.Error("Error marshaling dead letter", "job_id", entry.JobID, "error", err)
+		return
+	}
+
+	w.deadLetterMu.Lock()
+	defer w.deadLetterMu.Unlock()
+
+	f, err := os.OpenFile(w.deadLetterPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		logger.Error("Error opening dead-letter file", "path", w.deadLetterPath, "error", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logger.Error("Error writing dead letter", "path", w.deadLetterPath, "error", err)
+	}
+}
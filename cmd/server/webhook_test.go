@@ -0,0 +1,176 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/l"
+)
+
+func newTestNotifier(t *testing.T) *webhookNotifier {
+	t.Helper()
+	var err error
+	if logger, err = l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard}); err != nil {
+		t.Fatal(err)
+	}
+	w := newWebhookNotifier()
+	w.secret = []byte("s3cret")
+	w.maxAttempts = 3
+	w.backoff = time.Millisecond
+	w.timeout = time.Second
+	// httptest servers listen on loopback
+	w.allowed = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	return w
+}
+
+func TestWebhookRetriesAndSigns(t *testing.T) {
+	w := newTestNotifier(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := w.sign(r.Header.Get(webhookTimestampHeader), body)
+		if got := r.Header.Get(webhookSignatureHeader); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if calls.Add(1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	w.deliver(srv.URL, "job-1", []byte(`{"id":"job-1"}`))
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("delivery attempts = %d, want 3", got)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	w := newTestNotifier(t)
+	w.deadLetterPath = filepath.Join(t.TempDir(), "dead.jsonl")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	w.deliver(srv.URL, "job-2", []byte(`{"id":"job-2"}`))
+
+	data, err := os.ReadFile(w.deadLetterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"job_id":"job-2"`) || !strings.Contains(string(data), `"attempts":3`) {
+		t.Errorf("dead letter = %s, want job-2 after 3 attempts", data)
+	}
+}
+
+func TestWebhookRefusesInternalTargets(t *testing.T) {
+	w := newTestNotifier(t)
+	w.allowed = nil
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if apiErr := w.validateURL(target); apiErr == nil {
+			t.Errorf("%s: accepted, want an internal address error", target)
+		}
+	}
+	if apiErr := w.validateURL("https://93.184.216.34/hook"); apiErr != nil {
+		t.Errorf("public address rejected: %v", apiErr)
+	}
+
+	// The allowlist opens exactly the listed ranges
+	if w.allowed, _ = parseWebhookAllowlist("10.0.0.0/8, 127.0.0.1/32"); len(w.allowed) != 2 {
+		t.Fatalf("allowlist = %v, want 2 prefixes", w.allowed)
+	}
+	if apiErr := w.validateURL("http://10.1.2.3/hook"); apiErr != nil {
+		t.Errorf("allowlisted address rejected: %v", apiErr)
+	}
+	if apiErr := w.validateURL("http://169.254.169.254/"); apiErr == nil {
+		t.Error("link-local address accepted with an allowlist that does not cover it")
+	}
+	if _, err := parseWebhookAllowlist("10.0.0.0/33"); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+func TestWebhookDialChecksAddresses(t *testing.T) {
+	w := newTestNotifier(t)
+	w.allowed = nil
+	w.maxAttempts = 1
+	w.deadLetterPath = filepath.Join(t.TempDir(), "dead.jsonl")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	// A target that got past validation still cannot reach loopback
+	w.deliver(srv.URL, "job-3", []byte(`{"id":"job-3"}`))
+	if calls.Load() != 0 {
+		t.Error("delivery reached a loopback server")
+	}
+	data, err := os.ReadFile(w.deadLetterPath)
+	if err != nil || !strings.Contains(string(data), "internal address") {
+		t.Errorf("dead letter = %s, %v; want an internal address error", data, err)
+	}
+}
+
+func TestWebhookShutdownDeadLettersPendingDeliveries(t *testing.T) {
+	w := newTestNotifier(t)
+	w.backoff = time.Hour
+	w.deadLetterPath = filepath.Join(t.TempDir(), "dead.jsonl")
+
+	failed := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	w.notify(srv.URL, Job{ID: "job-4"})
+	<-failed
+
+	// The retry waits an hour; shutdown must not
+	stopped := make(chan struct{})
+	go func() { w.shutdown(); close(stopped) }()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown waited for the retry backoff")
+	}
+
+	// Events finishing after shutdown go straight to the dead letters
+	w.notify(srv.URL, Job{ID: "job-5"})
+
+	data, err := os.ReadFile(w.deadLetterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"job-4", "job-5"} {
+		if !strings.Contains(string(data), `"job_id":"`+id+`"`) {
+			t.Errorf("dead letters = %s, want %s", data, id)
+		}
+	}
+	if !strings.Contains(string(data), "shut down") {
+		t.Errorf("dead letters = %s, want the shutdown as the reason", data)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// runCompare implements "similarity compare ORIGINAL AUGMENTED". Either file
+// may be - for stdin. When a daemon is listening on the socket, it does the
+// work with its warmed calculators; otherwise compare computes in-process.
+func runCompare(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	metric := flags.String("metric", metricLength, "Metric to compare with: length or character")
+	threshold := flags.Float64("threshold", 0, "Pass threshold (default: the metric's own)")
+	socket := flags.String("socket", defaultSocketPath(), "Unix socket of a running daemon")
+	noDaemon := flags.Bool("no-daemon", false, "Always compute in-process")
+	timeout := flags.Duration("timeout", DefaultDaemonTimeout, "Maximum time for the comparison")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("compare: want two files, ORIGINAL and AUGMENTED")
+	}
+	if flags.Arg(0) == "-" && flags.Arg(1) == "-" {
+		return errors.New("compare: only one input can be read from stdin")
+	}
+
+	original, err := readQuery(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	augmented, err := readQuery(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	req := daemonRequest{Metric: *metric, Original: original, Augmented: augmented, Threshold: *threshold}
+
+	result, err := compareVia(*socket, *noDaemon, *timeout, req)
+	if err != nil {
+		return fmt.Errorf("compare: %w", err)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// compareVia runs req on the daemon when one answers on socket, falling back
+// to cold in-process calculators
+func compareVia(socket string, noDaemon bool, timeout time.Duration, req daemonRequest) (compareResult, error) {
+	if !noDaemon {
+		if client, err := dialDaemon(socket); err == nil {
+			defer client.Close()
+			client.conn.SetDeadline(time.Now().Add(timeout))
+			return client.compare(req)
+		}
+	}
+
+	calcs, err := newCalculators(false)
+	if err != nil {
+		return compareResult{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return calcs.compare(ctx, req)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/warmup"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+)
+
+// Daemon defaults
+const (
+	DefaultDaemonTimeout = 30 * time.Second
+	// maxDaemonMessage bounds one request line, texts included
+	maxDaemonMessage = 64 << 20
+)
+
+// Metrics the daemon and compare understand
+const (
+	metricLength    = "length"
+	metricCharacter = "character"
+)
+
+// daemonRequest is one comparison sent to the daemon as a JSON line
+type daemonRequest struct {
+	Metric    string  `json:"metric"`
+	Original  string  `json:"original"`
+	Augmented string  `json:"augmented"`
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// daemonResponse answers one daemonRequest
+type daemonResponse struct {
+	Result *compareResult `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// compareResult is what compare prints, whether computed locally or by the daemon
+type compareResult struct {
+	Name            string  `json:"name"`
+	Engine          string  `json:"engine"`
+	Mode            string  `json:"mode"`
+	Score           float64 `json:"score"`
+	Passed          bool    `json:"passed"`
+	OriginalLength  int     `json:"original_length"`
+	AugmentedLength int     `json:"augmented_length"`
+	LengthRatio     float64 `json:"length_ratio"`
+	Threshold       float64 `json:"threshold"`
+}
+
+// calculators are the comparison engines behind compare. They are safe for
+// concurrent use, so the daemon shares one set between all connections.
+type calculators struct {
+	words *word.LengthSimilarity
+	chars *character.CharacterSimilarity
+}
+
+// newCalculators builds the calculators, warming them up when warm is set.
+// Warm-up costs seconds, which only pays off in a long-lived daemon.
+func newCalculators(warm bool) (*calculators, error) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		return nil, err
+	}
+	words, err := word.New(word.WithLogger(lg), word.WithFastNormalizer(), word.WithWarmUp(warm))
+	if err != nil {
+		return nil, err
+	}
+	chars, err := character.NewCharacterSimilarity(character.WithLogger(lg), character.WithOptimizedNormalizer(), character.WithWarmUp(warm))
+	if err != nil {
+		return nil, err
+	}
+	return &calculators{words: words, chars: chars}, nil
+}
+
+// compare runs one request. A non-zero threshold re-evaluates Passed; scores
+// do not depend on the threshold.
+func (c *calculators) compare(ctx context.Context, req daemonRequest) (compareResult, error) {
+	if req.Threshold < 0 || req.Threshold > 1 {
+		return compareResult{}, errors.New("threshold must be between 0 and 1")
+	}
+
+	var r domain.Result
+	switch req.Metric {
+	case metricLength, "":
+		r = c.words.Compute(ctx, req.Original, req.Augmented)
+	case metricCharacter:
+		r = c.chars.Compute(ctx, req.Original, req.Augmented)
+	default:
+		return compareResult{}, fmt.Errorf("unknown metric %q", req.Metric)
+	}
+	if msg, ok := r.Details["error"]; ok {
+		return compareResult{}, fmt.Errorf("%v", msg)
+	}
+
+	result := compareResult{
+		Name:            r.Name,
+		Engine:          r.Engine,
+		Mode:            r.Mode,
+		Score:           r.Score,
+		Passed:          r.Passed,
+		OriginalLength:  r.OriginalLength,
+		AugmentedLength: r.AugmentedLength,
+		LengthRatio:     r.LengthRatio,
+		Threshold:       r.Threshold,
+	}
+	if req.Threshold != 0 {
+		result.Threshold = req.Threshold
+		result.Passed = result.Score >= req.Threshold
+	}
+	return result, nil
+}
+
+// defaultSocketPath is the daemon socket used when --socket is not given:
+// under XDG_RUNTIME_DIR when set, otherwise a per-user file in the temp dir
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "similarity.sock")
+	}
+	return filepath.Join(os.TempDir(), "similarity-"+strconv.Itoa(os.Getuid())+".sock")
+}
+
+// runDaemon implements "similarity daemon"
+func runDaemon(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	socket := flags.String("socket", defaultSocketPath(), "Unix socket to serve on")
+	timeout := flags.Duration("timeout", DefaultDaemonTimeout, "Maximum time for one comparison")
+	verbose := flags.Bool("verbose", false, "Print what the warm-up did")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	calcs, err := newCalculators(true)
+	if err != nil {
+		return err
+	}
+
+	ln, err := listenSocket(*socket)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Warmed up in %v; serving on %s\n", time.Since(start).Round(time.Millisecond), *socket)
+	if *verbose {
+		if r, ok := calcs.words.WarmupReport(); ok {
+			writeWarmupReport(stdout, metricLength, r)
+		}
+		if r, ok := calcs.chars.WarmupReport(); ok {
+			writeWarmupReport(stdout, metricCharacter, r)
+		}
+	}
+
+	return serveDaemon(ctx, ln, calcs, *timeout)
+}
+
+// writeWarmupReport prints one calculator's warm-up report
+func writeWarmupReport(w io.Writer, metric string, r warmup.WarmupReport) {
+	fmt.Fprintf(w, "%s warm-up: %d iterations in %v, %d allocations, %d GC cycles",
+		metric, r.Iterations, r.Duration.Round(time.Millisecond), r.AllocsAfter-r.AllocsBefore, r.GCCycles)
+	if r.Cancelled {
+		fmt.Fprint(w, ", stopped early")
+	}
+	fmt.Fprintln(w)
+	for _, c := range r.Components {
+		fmt.Fprintf(w, "  %-18s %d registered, %d iterations in %v\n", c.Name, c.Count, c.Iterations, c.Duration.Round(time.Millisecond))
+	}
+}
+
+// listenSocket listens on a Unix socket readable only by the current user.
+// A socket file left by a daemon that did not shut down cleanly is replaced;
+// one that still answers belongs to a running daemon and is an error.
+func listenSocket(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("daemon already running on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var ln net.Listener
+	err := withPrivateUmask(func() (err error) {
+		ln, err = net.Listen("unix", path)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// serveDaemon answers requests on ln until ctx is done, then stops accepting,
+// closes idle connections, waits for in-flight requests and removes the socket
+func serveDaemon(ctx context.Context, ln net.Listener, calcs *calculators, timeout time.Duration) error {
+	var wg sync.WaitGroup
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(ctx, conn, calcs, timeout)
+		}()
+	}
+}
+
+// serveConn answers JSON-line requests on one connection until the client
+// closes it or ctx is done. Responses are written in request order.
+func serveConn(ctx context.Context, conn net.Conn, calcs *calculators, timeout time.Duration) {
+	defer conn.Close()
+
+	// On shutdown, unblock a read waiting for the next request. A request
+	// already read still gets its response.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxDaemonMessage)
+	enc := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var resp daemonResponse
+		var req daemonRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = "invalid request: " + err.Error()
+		} else {
+			c, cancel := context.WithTimeout(ctx, timeout)
+			result, err := calcs.compare(c, req)
+			cancel()
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Result = &result
+			}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// daemonClient sends comparisons to a running daemon over one connection
+type daemonClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	enc     *json.Encoder
+}
+
+// dialDaemon connects to the daemon on socket
+func dialDaemon(socket string) (*daemonClient, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxDaemonMessage)
+	return &daemonClient{conn: conn, scanner: scanner, enc: json.NewEncoder(conn)}, nil
+}
+
+// compare sends one request and waits for its response
+func (c *daemonClient) compare(req daemonRequest) (compareResult, error) {
+	if err := c.enc.Encode(req); err != nil {
+		return compareResult{}, err
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return compareResult{}, err
+		}
+		return compareResult{}, io.ErrUnexpectedEOF
+	}
+	var resp daemonResponse
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return compareResult{}, err
+	}
+	if resp.Error != "" {
+		return compareResult{}, errors.New(resp.Error)
+	}
+	if resp.Result == nil {
+		return compareResult{}, errors.New("daemon returned no result")
+	}
+	return *resp.Result, nil
+}
+
+// Close closes the connection
+func (c *daemonClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/warmup"
+)
+
+// socketPath returns a socket path short enough for the Unix socket limit
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "d.sock")
+}
+
+func startDaemon(t *testing.T, socket string) (*calculators, func()) {
+	t.Helper()
+	calcs, err := newCalculators(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := listenSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveDaemon(ctx, ln, calcs, time.Second) }()
+	return calcs, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serveDaemon: %v", err)
+		}
+	}
+}
+
+func TestDaemonMatchesInProcess(t *testing.T) {
+	socket := socketPath(t)
+	calcs, stop := startDaemon(t, socket)
+	defer stop()
+
+	requests := []daemonRequest{
+		{Metric: metricLength, Original: "one two three four", Augmented: "one two three"},
+		{Metric: metricCharacter, Original: "abcdefghij", Augmented: "abcdefgh", Threshold: 0.9},
+	}
+	client, err := dialDaemon(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, req := range requests {
+		got, err := client.compare(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := calcs.compare(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: daemon returned %+v, in-process %+v", req.Metric, got, want)
+		}
+	}
+
+	if _, err := client.compare(daemonRequest{Metric: "nope", Original: "a", Augmented: "b"}); err == nil ||
+		!strings.Contains(err.Error(), "unknown metric") {
+		t.Errorf("err = %v, want the daemon's unknown metric error", err)
+	}
+	// The connection stays usable after an error
+	if _, err := client.compare(requests[0]); err != nil {
+		t.Errorf("request after an error: %v", err)
+	}
+}
+
+func TestListenSocketRefusesRunningDaemon(t *testing.T) {
+	socket := socketPath(t)
+	_, stop := startDaemon(t, socket)
+
+	if _, err := listenSocket(socket); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("err = %v, want already running", err)
+	}
+	stop()
+
+	// A stale socket file does not block a new daemon
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := listenSocket(socket)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	ln.Close()
+}
+
+func TestCompareFallsBackWithoutDaemon(t *testing.T) {
+	req := daemonRequest{Metric: metricLength, Original: "one two three four", Augmented: "one two three"}
+	result, err := compareVia(socketPath(t), false, time.Second, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OriginalLength != 4 || result.AugmentedLength != 3 {
+		t.Errorf("result = %+v, want word counts 4 and 3", result)
+	}
+}
+
+func TestWriteWarmupReport(t *testing.T) {
+	var out strings.Builder
+	writeWarmupReport(&out, metricLength, warmup.WarmupReport{
+		Iterations:   30,
+		Duration:     1500 * time.Millisecond,
+		Components:   []warmup.ComponentReport{{Name: "calculators", Count: 1, Iterations: 30, Duration: time.Second}},
+		AllocsBefore: 100,
+		AllocsAfter:  350,
+		GCCycles:     2,
+		Cancelled:    true,
+	})
+	want := "length warm-up: 30 iterations in 1.5s, 250 allocations, 2 GC cycles, stopped early\n" +
+		"  calculators        1 registered, 30 iterations in 1s\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestDaemonShutdownClosesIdleConnections(t *testing.T) {
+	socket := socketPath(t)
+	calcs, err := newCalculators(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := listenSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveDaemon(ctx, ln, calcs, time.Second) }()
+
+	// An idle client must not keep the daemon alive
+	client, err := dialDaemon(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.compare(daemonRequest{Original: "one two three four", Augmented: "one two three"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveDaemon: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveDaemon did not return with an idle client connected")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/resultdiff"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+// DefaultDiffTop is how many changes diff lists when --top is not given
+const DefaultDiffTop = 20
+
+// errFlips is returned by diff --fail-on-flip when a verdict changed
+var errFlips = errors.New("diff: pass/fail verdicts changed")
+
+// runDiff implements "similarity diff BASE CANDIDATE": it compares two JSONL
+// result files written for the same inputs and summarizes how the candidate
+// moved the scores
+func runDiff(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	epsilon := flags.Float64("epsilon", resultdiff.DefaultEpsilon, "Score movement below which a pair counts as unchanged")
+	top := flags.Int("top", DefaultDiffTop, "Number of changes to list; -1 lists all")
+	asJSON := flags.Bool("json", false, "Print the full report as JSON")
+	failOnFlip := flags.Bool("fail-on-flip", false, "Exit with an error when any pair flips between pass and fail")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("diff: want two result files, BASE and CANDIDATE")
+	}
+
+	base, err := sink.ReadJSONLFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+	candidate, err := sink.ReadJSONLFile(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+	report, err := resultdiff.Compare(base, candidate, *epsilon)
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(stdout, *top)
+	}
+	if err != nil {
+		return err
+	}
+	if *failOnFlip && report.Overall.Flips() > 0 {
+		return errFlips
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/resultdiff"
+)
+
+func TestDiff(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{
+		"base.jsonl": `{"id":"a","metric":"length_similarity","score":0.8,"passed":true}
+{"id":"b","metric":"length_similarity","score":0.72,"passed":true}
+`,
+		"candidate.jsonl": `{"id":"a","metric":"length_similarity","score":0.8,"passed":true}
+{"id":"b","metric":"length_similarity","score":0.6,"passed":false}
+`,
+	})
+	base, candidate := filepath.Join(dir, "base.jsonl"), filepath.Join(dir, "candidate.jsonl")
+
+	var out bytes.Buffer
+	if err := runDiff([]string{base, candidate}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "compared 2 pairs: 1 changed, 1 flipped") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runDiff([]string{"--json", base, candidate}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var report resultdiff.Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Overall.NewlyFailing != 1 || len(report.Changes) != 1 || report.Changes[0].ID != "b" {
+		t.Errorf("report = %+v, want b newly failing", report)
+	}
+
+	if err := runDiff([]string{"--fail-on-flip", base, candidate}, &out); !errors.Is(err, errFlips) {
+		t.Errorf("--fail-on-flip: error %v, want errFlips", err)
+	}
+	if err := runDiff([]string{"--fail-on-flip", base, base}, &out); err != nil {
+		t.Errorf("--fail-on-flip without flips: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Command similarity is the command-line companion to the library. It groups
+// offline tasks as subcommands:
+//
+//	similarity profile build --dir corpus/ --out profiles.db
+//	similarity profile nearest --db profiles.db --query draft.txt --k 5
+//	similarity daemon &
+//	similarity compare original.txt augmented.txt
+//	similarity diff before.jsonl after.jsonl
+//	similarity query --db results.db --preset daily
+//	similarity selftest
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command runs a subcommand with its arguments, writing progress to stdout
+type command func(args []string, stdout io.Writer) error
+
+// commands maps a command, or "group action", to its implementation
+var commands = map[string]command{
+	"compare":         runCompare,
+	"daemon":          runDaemon,
+	"diff":            runDiff,
+	"profile build":   runProfileBuild,
+	"profile nearest": runProfileNearest,
+	"query":           runQuery,
+	"selftest":        runSelftest,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	cmd, args, ok := lookupCommand(os.Args[1:])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "similarity: unknown command %q\n", strings.Join(os.Args[1:min(len(os.Args), 3)], " "))
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	if err := cmd(args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "similarity:", err)
+		os.Exit(1)
+	}
+}
+
+// lookupCommand finds the command named by the first one or two arguments and
+// returns it with the remaining arguments
+func lookupCommand(args []string) (command, []string, bool) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd, args[2:], true
+		}
+	}
+	cmd, ok := commands[args[0]]
+	return cmd, args[1:], ok
+}
+
+// usage lists the available subcommands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: similarity <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	fmt.Fprintln(w, "  compare           compare two texts, through the daemon when one is running")
+	fmt.Fprintln(w, "  daemon            keep warmed calculators resident for compare")
+	fmt.Fprintln(w, "  diff              compare two result files and report score changes")
+	fmt.Fprintln(w, "  profile build     precompute profiles for every file in a corpus")
+	fmt.Fprintln(w, "  profile nearest   list the stored profiles most similar to a query text")
+	fmt.Fprintln(w, "  query             run read-only SQL over stored results")
+	fmt.Fprintln(w, "  selftest          score known pairs to verify this installation")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/core/distance"
+	"github.com/baditaflorin/go_length_similarity/pkg/profile"
+)
+
+// DefaultNearestK is how many matches profile nearest prints by default
+const DefaultNearestK = 10
+
+// runProfileNearest implements "similarity profile nearest"
+func runProfileNearest(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("profile nearest", flag.ContinueOnError)
+	db := flags.String("db", DefaultProfileOut, "Profile file written by profile build")
+	index := flags.String("index", "", "Search index written by profile build --index; used instead of --db")
+	query := flags.String("query", "", "File holding the query text, or - for stdin (required)")
+	k := flags.Int("k", DefaultNearestK, "Number of matches to print")
+	minScore := flags.Float64("min-score", 0, "Drop candidates whose length score is below this")
+	rescore := flags.String("rescore", "", "Re-rank the shortlist by content: edit or ngram (needs --dir)")
+	shortlist := flags.Int("shortlist", 0, "Candidates to rescore (default 4 * k)")
+	dir := flags.String("dir", "", "Corpus directory the profiles were built from, for --rescore")
+	norm := flags.String("normalizer", "default", "Normalizer the profiles were built with: default, fast, optimized, ocr or logs")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *query == "" {
+		return errors.New("profile nearest: --query is required")
+	}
+	buildOpts, err := normalizerOptions(*norm)
+	if err != nil {
+		return fmt.Errorf("profile nearest: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	text, err := readQuery(*query)
+	if err != nil {
+		return err
+	}
+	q, err := profile.BuildString(ctx, *query, text, buildOpts...)
+	if err != nil {
+		return err
+	}
+
+	opts := []profile.NearestOption{profile.WithMinScore(*minScore), profile.WithShortlist(*shortlist)}
+	if *rescore != "" {
+		if *dir == "" {
+			return errors.New("profile nearest: --rescore needs --dir")
+		}
+		rescorer, err := contentRescorer(*rescore, *dir, text)
+		if err != nil {
+			return fmt.Errorf("profile nearest: %w", err)
+		}
+		opts = append(opts, profile.WithRescorer(rescorer))
+	}
+
+	var matches []profile.Match
+	if *index != "" {
+		idx, err := readIndex(*index)
+		if err != nil {
+			return err
+		}
+		matches, err = idx.Nearest(ctx, q, *k, opts...)
+		if err != nil {
+			return err
+		}
+	} else {
+		candidates, err := readProfiles(*db)
+		if err != nil {
+			return err
+		}
+		matches, err = profile.Nearest(ctx, q, candidates, *k, opts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tSCORE\tWORDS\tRUNES\tID")
+	for i, m := range matches {
+		fmt.Fprintf(tw, "%d\t%.4f\t%d\t%d\t%s\n", i+1, m.Score, m.Profile.Words, m.Profile.Runes, m.Profile.ID)
+	}
+	return tw.Flush()
+}
+
+// readProfiles loads a profile file
+func readProfiles(path string) ([]profile.Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return profile.Read(f)
+}
+
+// readIndex loads a search index
+func readIndex(path string) (*profile.Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return profile.LoadIndex(bufio.NewReader(f))
+}
+
+// readQuery reads the query text from a file, or stdin for "-"
+func readQuery(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// contentRescorer compares the query text against each shortlisted corpus
+// file with a content metric
+func contentRescorer(metric, dir, query string) (profile.Rescorer, error) {
+	var compute func(ctx context.Context, original, augmented string) float64
+	switch metric {
+	case "edit":
+		c, err := distance.NewEditCalculator(distance.DefaultConfig(), normalizer.NewDefaultNormalizer())
+		if err != nil {
+			return nil, err
+		}
+		compute = func(ctx context.Context, original, augmented string) float64 {
+			return c.Compute(ctx, original, augmented).Score
+		}
+	case "ngram":
+		c, err := distance.NewNGramCalculator(distance.DefaultConfig(), normalizer.NewDefaultNormalizer())
+		if err != nil {
+			return nil, err
+		}
+		compute = func(ctx context.Context, original, augmented string) float64 {
+			return c.Compute(ctx, original, augmented).Score
+		}
+	default:
+		return nil, fmt.Errorf("unknown rescore metric %q", metric)
+	}
+
+	return func(ctx context.Context, candidate profile.Profile) (float64, error) {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(candidate.ID)))
+		if err != nil {
+			return 0, err
+		}
+		score := compute(ctx, query, string(data))
+		return score, ctx.Err()
+	}, nil
+}
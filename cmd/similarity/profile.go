@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/pkg/content"
+	"github.com/baditaflorin/go_length_similarity/pkg/profile"
+)
+
+// DefaultProfileOut is where profile build writes when --out is not given
+const DefaultProfileOut = "profiles.db"
+
+// runProfileBuild implements "similarity profile build"
+func runProfileBuild(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("profile build", flag.ContinueOnError)
+	dir := flags.String("dir", "", "Corpus directory to profile (required)")
+	out := flags.String("out", DefaultProfileOut, "File to write the profiles to")
+	workers := flags.Int("workers", parallel.DefaultWorkers(), "Number of files profiled in parallel")
+	ext := flags.String("ext", "", "Only profile files with these comma-separated extensions, such as .txt,.md")
+	norm := flags.String("normalizer", "default", "Normalizer to count with: default, fast, optimized, ocr or logs")
+	index := flags.String("index", "", "Also write a search index for profile nearest --index to this file")
+	contentDir := flags.String("content-dir", "", "Also store each file in a content store in this directory, recording its key in the profile")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return errors.New("profile build: --dir is required")
+	}
+	if *workers < 1 {
+		return errors.New("profile build: --workers must be at least 1")
+	}
+
+	opts, err := normalizerOptions(*norm)
+	if err != nil {
+		return fmt.Errorf("profile build: %w", err)
+	}
+	if *contentDir != "" {
+		store, err := content.NewFileStore(*contentDir)
+		if err != nil {
+			return fmt.Errorf("profile build: %w", err)
+		}
+		opts = append(opts, profile.WithContentStore(store))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	profiles, err := buildCorpus(ctx, *dir, splitExtensions(*ext), *workers, opts...)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(*out, func(w io.Writer) error { return profile.Write(w, profiles) }); err != nil {
+		return err
+	}
+	if *index != "" {
+		if err := writeAtomic(*index, profile.NewIndex(profiles).Save); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "Profiled %d files from %s into %s in %v\n",
+		len(profiles), *dir, *out, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// normalizerOptions maps a --normalizer value to profile build options
+func normalizerOptions(name string) ([]profile.Option, error) {
+	switch name {
+	case "default":
+		return nil, nil
+	case "fast":
+		return []profile.Option{profile.WithFastNormalizer()}, nil
+	case "optimized":
+		return []profile.Option{profile.WithOptimizedNormalizer()}, nil
+	case "ocr":
+		return []profile.Option{profile.WithOCRNormalizer()}, nil
+	case "logs":
+		return []profile.Option{profile.WithLogNormalizer()}, nil
+	default:
+		return nil, fmt.Errorf("unknown normalizer %q", name)
+	}
+}
+
+// splitExtensions parses the --ext list, adding leading dots where missing
+func splitExtensions(list string) []string {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// buildCorpus profiles every regular file under dir whose extension is in
+// exts (all files when exts is empty) using workers goroutines. Profile IDs
+// are slash-separated paths relative to dir, and the result is sorted by ID so
+// repeated builds of the same corpus produce the same file.
+func buildCorpus(ctx context.Context, dir string, exts []string, workers int, opts ...profile.Option) ([]profile.Profile, error) {
+	paths, err := corpusFiles(dir, exts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	profiles := make([]profile.Profile, len(paths))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for range min(workers, max(len(paths), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p, err := buildFile(ctx, dir, paths[i], opts...)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				profiles[i] = p
+			}
+		}()
+	}
+
+dispatch:
+	for i := range paths {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// corpusFiles lists the files to profile, relative to dir and sorted
+func corpusFiles(dir string, exts []string) ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(os.DirFS(dir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(exts) > 0 && !hasExtension(path, exts) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// hasExtension reports whether path ends in one of exts, ignoring case
+func hasExtension(path string, exts []string) bool {
+	ext := filepath.Ext(path)
+	for _, want := range exts {
+		if strings.EqualFold(ext, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFile profiles one corpus file
+func buildFile(ctx context.Context, dir, path string, opts ...profile.Option) (profile.Profile, error) {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(path)))
+	if err != nil {
+		return profile.Profile{}, err
+	}
+	defer f.Close()
+
+	p, err := profile.Build(ctx, path, f, opts...)
+	if err != nil {
+		return profile.Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// writeAtomic writes a file through a temporary file renamed over out, so
+// readers never see a partially written profile file or index
+func writeAtomic(out string, write func(io.Writer) error) error {
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, out)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/profile"
+)
+
+func writeCorpus(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestProfileBuild(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{
+		"b.txt":        "two words",
+		"a.txt":        "one",
+		"nested/c.txt": "three little words",
+		"notes.md":     "skipped by --ext",
+	})
+	out := filepath.Join(t.TempDir(), "profiles.db")
+
+	if err := runProfileBuild([]string{"--dir", dir, "--out", out, "--ext", "txt", "--workers", "2"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	profiles, err := profile.Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantIDs := []string{"a.txt", "b.txt", "nested/c.txt"}
+	wantWords := []int{1, 2, 3}
+	if len(profiles) != len(wantIDs) {
+		t.Fatalf("got %d profiles, want %d", len(profiles), len(wantIDs))
+	}
+	for i, p := range profiles {
+		if p.ID != wantIDs[i] || p.Words != wantWords[i] {
+			t.Errorf("profile %d = %s with %d words, want %s with %d", i, p.ID, p.Words, wantIDs[i], wantWords[i])
+		}
+	}
+	if _, err := os.Stat(out + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary output file was left behind")
+	}
+}
+
+func TestBuildCorpusCancelled(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{"a.txt": "one", "b.txt": "two"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := buildCorpus(ctx, dir, nil, 1); err == nil {
+		t.Fatal("expected an error from a cancelled build")
+	}
+}
+
+func TestProfileBuildRequiresDir(t *testing.T) {
+	if err := runProfileBuild(nil, io.Discard); err == nil {
+		t.Fatal("expected an error without --dir")
+	}
+}
+
+func TestProfileNearest(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{
+		"cat.txt":   "the cat sat on the mat today",
+		"dog.txt":   "the dog sat on the log today",
+		"short.txt": "hi",
+		"long.txt":  "a much longer document that has many more words than the query text does at all",
+	})
+	out := filepath.Join(t.TempDir(), "profiles.db")
+	index := filepath.Join(t.TempDir(), "profiles.idx")
+	if err := runProfileBuild([]string{"--dir", dir, "--out", out, "--index", index}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	query := filepath.Join(t.TempDir(), "query.txt")
+	if err := os.WriteFile(query, []byte("the cat sat on the mat today"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, source := range [][]string{{"--db", out}, {"--index", index}} {
+		var stdout strings.Builder
+		args := append(source, "--query", query, "--k", "2", "--rescore", "ngram", "--dir", dir)
+		if err := runProfileNearest(args, &stdout); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("%s: got %d lines, want a header and 2 matches:\n%s", source[0], len(lines), stdout.String())
+		}
+		if !strings.HasSuffix(lines[1], "cat.txt") || !strings.HasSuffix(lines[2], "dog.txt") {
+			t.Errorf("%s: unexpected ranking:\n%s", source[0], stdout.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/resultquery"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+// DefaultQueryDriver is the database/sql driver query opens --db with
+const DefaultQueryDriver = "sqlite"
+
+// runQuery implements "similarity query SQL": it runs a read-only query, or a
+// built-in --preset, over the results an SQL sink stored and prints the rows
+func runQuery(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	driverName := flags.String("driver", DefaultQueryDriver, "database/sql driver to open --db with")
+	dsn := flags.String("db", "", "Data source name of the result store, e.g. results.db")
+	table := flags.String("table", sink.DefaultTable, "Results table the presets read")
+	preset := flags.String("preset", "", "Run a built-in query instead of SQL: "+strings.Join(resultquery.Presets(), ", "))
+	format := flags.String("format", "text", "Output format: text, csv or json")
+	limit := flags.Int("limit", resultquery.DefaultLimit, "Maximum rows to print; 0 prints all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var query string
+	switch {
+	case *preset != "" && flags.NArg() == 0:
+		var ok bool
+		if query, ok = resultquery.Preset(*preset, *table); !ok {
+			return fmt.Errorf("query: unknown preset %q (have %s)", *preset, strings.Join(resultquery.Presets(), ", "))
+		}
+	case *preset == "" && flags.NArg() == 1:
+		query = flags.Arg(0)
+	default:
+		return errors.New("query: want one SQL statement or --preset")
+	}
+	if *dsn == "" {
+		return errors.New("query: --db is required")
+	}
+
+	var write func(*resultquery.Table, io.Writer) error
+	switch *format {
+	case "text":
+		write = (*resultquery.Table).WriteText
+	case "csv":
+		write = (*resultquery.Table).WriteCSV
+	case "json":
+		write = (*resultquery.Table).WriteJSON
+	default:
+		return fmt.Errorf("query: unknown format %q", *format)
+	}
+
+	// Only SQLite is linked in; see "Querying Stored Results" in the README
+	// for adding another driver
+	if !slices.Contains(sql.Drivers(), *driverName) {
+		return fmt.Errorf("query: database driver %q is not linked into this binary (have %v)", *driverName, sql.Drivers())
+	}
+	// The statement check is lexical, so SQLite stores are also opened read-only
+	if *driverName == DefaultQueryDriver {
+		*dsn = readOnlySQLiteDSN(*dsn)
+	}
+	db, err := sql.Open(*driverName, *dsn)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	defer db.Close()
+
+	result, err := resultquery.Query(context.Background(), db, *limit, query)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	return write(result, stdout)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+func TestQueryArguments(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--db", "results.db"}, want: "want one SQL statement or --preset"},
+		{args: []string{"--db", "results.db", "--preset", "daily", "SELECT 1"}, want: "want one SQL statement or --preset"},
+		{args: []string{"--db", "results.db", "--preset", "weekly"}, want: `unknown preset "weekly"`},
+		{args: []string{"SELECT 1"}, want: "--db is required"},
+		{args: []string{"--db", "results.db", "--format", "xml", "SELECT 1"}, want: `unknown format "xml"`},
+		{args: []string{"--db", "results.db", "--driver", "nosuchdb", "SELECT 1"}, want: `driver "nosuchdb" is not linked`},
+	} {
+		err := runQuery(tc.args, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: error %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestQueryReadsSQLSinkStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	db, err := sql.Open(DefaultQueryDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s, err := sink.NewSQLSink(ctx, db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, rec := range []sink.Record{
+		{ID: "a", Metric: "length", Score: 1, Passed: true, Timestamp: now},
+		{ID: "b", Metric: "length", Score: 0.5, Timestamp: now},
+	} {
+		if err := s.Write(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runQuery([]string{"--db", path, "--format", "json", "SELECT id, score FROM similarity_results ORDER BY id"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var table struct {
+		Rows [][]interface{} `json:"rows"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &table); err != nil {
+		t.Fatalf("%v in %s", err, out.String())
+	}
+	if rows := table.Rows; len(rows) != 2 || rows[0][0] != "a" || rows[1][1] != 0.5 {
+		t.Errorf("rows = %v, want a and b with their scores", rows)
+	}
+
+	out.Reset()
+	if err := runQuery([]string{"--db", path, "--preset", "pass-rate"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "length") {
+		t.Errorf("pass-rate preset printed %q, want a row for the length metric", out.String())
+	}
+}
+
+func TestQueryCannotWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	db, err := sql.Open(DefaultQueryDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s, err := sink.NewSQLSink(ctx, db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(ctx, sink.Record{ID: "a", Metric: "length", Score: 1, Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// WITH passes the lexical check; the read-only connection must stop it
+	if err := runQuery([]string{"--db", path, "WITH t AS (SELECT 1) DELETE FROM similarity_results"}, &bytes.Buffer{}); err == nil {
+		t.Error("a WITH-prefixed DELETE ran")
+	} else if !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("WITH-prefixed DELETE failed with %v, want a read-only error", err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM similarity_results").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("%d rows left, want 1", n)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	if err := runQuery([]string{"--db", missing, "SELECT 1"}, &bytes.Buffer{}); err == nil {
+		t.Error("query on a missing store succeeded")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// DefaultSelftestTimeout bounds a whole selftest run
+const DefaultSelftestTimeout = time.Minute
+
+// errSelftest is returned by selftest when a check scored outside its range
+var errSelftest = errors.New("selftest: checks failed")
+
+// selftestCase is a known pair and the range its score must fall in
+type selftestCase struct {
+	Name   string
+	Metric string
+	// Stream feeds the texts through the streaming readers instead of as strings
+	Stream    bool
+	Original  string
+	Augmented string
+	Min, Max  float64
+}
+
+// selftestCheck is the outcome of one case
+type selftestCheck struct {
+	Name   string  `json:"name"`
+	Metric string  `json:"metric"`
+	Mode   string  `json:"mode"`
+	Score  float64 `json:"score"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// selftestCases returns the built-in suite. The ranges are the scores a correct
+// build produces, with room for rounding; a score outside one means the
+// binary, its dependencies or the host (locale, Unicode tables) misbehave.
+func selftestCases() []selftestCase {
+	// A single line of about 1MB, longer than any read buffer
+	long := strings.Repeat("lorem ipsum dolor ", 60000)
+	shorter := strings.Repeat("lorem ipsum dolor ", 45000)
+
+	return []selftestCase{
+		{Name: "identical", Metric: metricLength, Original: "the quick brown fox jumps over the lazy dog", Augmented: "the quick brown fox jumps over the lazy dog", Min: 1, Max: 1},
+		{Name: "much shorter", Metric: metricLength, Original: "the quick brown fox jumps over the lazy dog", Augmented: "the quick brown fox jumps", Min: 0, Max: 0.01},
+		{Name: "punctuation", Metric: metricLength, Original: "Hello, world! How are you?", Augmented: "hello world how are you", Min: 1, Max: 1},
+		{Name: "accents count as runes", Metric: metricCharacter, Original: "héllo wörld", Augmented: "hello world", Min: 1, Max: 1},
+		{Name: "unicode case folding", Metric: metricCharacter, Original: "ÜNÏCÖDÉ FAÇADE", Augmented: "ünïcödé façade", Min: 1, Max: 1},
+		{Name: "cjk", Metric: metricCharacter, Original: "日本語のテキストです", Augmented: "日本語のテキスト", Min: 0.32, Max: 0.34},
+		{Name: "crlf line endings", Metric: metricLength, Original: "line one\r\nline two\r\n", Augmented: "line one\nline two\n", Min: 1, Max: 1},
+		{Name: "crlf line endings", Metric: metricCharacter, Original: "line one\r\nline two\r\n", Augmented: "line one\nline two\n", Min: 1, Max: 1},
+		{Name: "long line", Metric: metricLength, Original: long, Augmented: shorter, Min: 0.16, Max: 0.17},
+		{Name: "long line", Metric: metricLength, Stream: true, Original: long, Augmented: shorter, Min: 0.16, Max: 0.17},
+		{Name: "long line", Metric: metricCharacter, Stream: true, Original: long, Augmented: shorter, Min: 0.16, Max: 0.18},
+	}
+}
+
+// runSelftest implements "similarity selftest": it scores the built-in
+// suite with the calculators compare uses and fails when any score falls
+// outside its expected range
+func runSelftest(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	warm := flags.Bool("warm", false, "Warm the calculators up first, as the daemon does")
+	asJSON := flags.Bool("json", false, "Print the checks as JSON")
+	timeout := flags.Duration("timeout", DefaultSelftestTimeout, "Maximum time for the whole suite")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("selftest: takes no arguments")
+	}
+
+	calcs, err := newCalculators(*warm)
+	if err != nil {
+		return fmt.Errorf("selftest: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	cases := selftestCases()
+	checks := make([]selftestCheck, len(cases))
+	failed := 0
+	for i, tc := range cases {
+		checks[i] = calcs.selftest(ctx, tc)
+		if !checks[i].OK {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(checks)
+	} else {
+		err = writeSelftest(stdout, checks, failed)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errSelftest
+	}
+	return nil
+}
+
+// selftest scores one case
+func (c *calculators) selftest(ctx context.Context, tc selftestCase) selftestCheck {
+	check := selftestCheck{Name: tc.Name, Metric: tc.Metric, Min: tc.Min, Max: tc.Max}
+
+	var r domain.Result
+	switch {
+	case tc.Metric == metricLength && tc.Stream:
+		r = c.words.ComputeFromReaders(ctx, strings.NewReader(tc.Original), strings.NewReader(tc.Augmented))
+	case tc.Metric == metricLength:
+		r = c.words.Compute(ctx, tc.Original, tc.Augmented)
+	case tc.Stream:
+		r = c.chars.ComputeFromReaders(ctx, strings.NewReader(tc.Original), strings.NewReader(tc.Augmented))
+	default:
+		r = c.chars.Compute(ctx, tc.Original, tc.Augmented)
+	}
+
+	check.Mode, check.Score = r.Mode, r.Score
+	if msg, ok := r.Details["error"]; ok {
+		check.Error = fmt.Sprint(msg)
+		return check
+	}
+	check.OK = r.Score >= tc.Min && r.Score <= tc.Max
+	return check
+}
+
+// writeSelftest prints one line per check and a summary
+func writeSelftest(w io.Writer, checks []selftestCheck, failed int) error {
+	for _, c := range checks {
+		status := "ok  "
+		if !c.OK {
+			status = "FAIL"
+		}
+		line := fmt.Sprintf("%s %-24s %-9s %-6s score %.3f, want %.3f to %.3f", status, c.Name, c.Metric, c.Mode, c.Score, c.Min, c.Max)
+		if c.Error != "" {
+			line += ": " + c.Error
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d of %d checks passed\n", len(checks)-failed, len(checks))
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSelftest(t *testing.T) {
+	var out bytes.Buffer
+	if err := runSelftest(nil, &out); err != nil {
+		t.Fatalf("selftest: %v\n%s", err, out.String())
+	}
+	if want := "11 of 11 checks passed"; !strings.Contains(out.String(), want) {
+		t.Errorf("output lacks %q:\n%s", want, out.String())
+	}
+
+	out.Reset()
+	if err := runSelftest([]string{"--json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var checks []selftestCheck
+	if err := json.Unmarshal(out.Bytes(), &checks); err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != len(selftestCases()) || checks[9].Mode != "stream" {
+		t.Errorf("checks = %+v, want every case with the streamed ones in stream mode", checks)
+	}
+}
+
+func TestSelftestReportsOutOfRangeScores(t *testing.T) {
+	calcs, err := newCalculators(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := calcs.selftest(context.Background(), selftestCase{
+		Name: "wrong", Metric: metricLength, Original: "one two three", Augmented: "one", Min: 0.9, Max: 1,
+	})
+	if check.OK {
+		t.Errorf("check = %+v, want a failure", check)
+	}
+
+	var out bytes.Buffer
+	if err := writeSelftest(&out, []selftestCheck{check}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "FAIL wrong") || !strings.Contains(out.String(), "0 of 1 checks passed") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+
+	// The pure-Go SQLite driver, registered as "sqlite", lets query read the
+	// stores SQLSink writes without cgo
+	_ "modernc.org/sqlite"
+)
+
+// readOnlySQLiteDSN makes SQLite refuse every write through dsn, however the
+// statement is phrased, and report a missing file instead of creating it
+func readOnlySQLiteDSN(dsn string) string {
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "mode=ro&_pragma=query_only(1)"
+}
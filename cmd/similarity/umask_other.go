@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// withPrivateUmask runs fn; there is no umask outside Unix
+func withPrivateUmask(fn func() error) error {
+	return fn()
+}
@@ -3,22 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
-	"math"
 	"time"
 
 	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/evaluate"
 	"github.com/baditaflorin/go_length_similarity/pkg/word"
 )
 
-// CombinedResult represents a combined similarity result
-type CombinedResult struct {
-	LengthScore    float64
-	CharacterScore float64
-	CombinedScore  float64
-	Passed         bool
-	Details        map[string]interface{}
-}
-
 func main() {
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -64,6 +55,18 @@ func main() {
 		panic(err)
 	}
 
+	// Equal weights, the same as a simple average
+	combiner, err := evaluate.NewCombiner(
+		evaluate.WithCombinedThreshold(0.7),
+		evaluate.WithWeightedMetrics(
+			evaluate.Weighted{Name: "word", Metric: ls, Weight: 1},
+			evaluate.Weighted{Name: "character", Metric: cs, Weight: 1},
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+
 	fmt.Println("=== Combined Metrics Example ===")
 
 	// Process each example
@@ -73,13 +76,8 @@ func main() {
 		fmt.Printf("  Augmented: %s\n", example.Augmented)
 
 		// Calculate combined metrics
-		result := CalculateCombinedMetrics(ctx, ls, cs, example.Original, example.Augmented, 0.7)
-
-		// Display results
-		fmt.Printf("  Length Score:    %.2f\n", result.LengthScore)
-		fmt.Printf("  Character Score: %.2f\n", result.CharacterScore)
-		fmt.Printf("  Combined Score:  %.2f\n", result.CombinedScore)
-		fmt.Printf("  Passed:          %v\n", result.Passed)
+		result := combiner.Combine(ctx, example.Original, example.Augmented)
+		printCombined(result)
 	}
 
 	// Advanced combined metrics with weighted combination
@@ -89,100 +87,39 @@ func main() {
 	augmented := "This document explains the methodology for computing similarity between textual content."
 
 	// Calculate with different weightings
-	result1 := CalculateWeightedMetrics(ctx, ls, cs, original, augmented, 0.3, 0.7, 0.7)
-	result2 := CalculateWeightedMetrics(ctx, ls, cs, original, augmented, 0.7, 0.3, 0.7)
+	result1 := weighted(ls, cs, 0.3, 0.7).Combine(ctx, original, augmented)
+	result2 := weighted(ls, cs, 0.7, 0.3).Combine(ctx, original, augmented)
 
 	fmt.Printf("\nOriginal:  %s\n", original)
 	fmt.Printf("Augmented: %s\n", augmented)
 	fmt.Printf("\nLength-weighted (30%%/70%%):\n")
-	fmt.Printf("  Length Score:    %.2f\n", result1.LengthScore)
-	fmt.Printf("  Character Score: %.2f\n", result1.CharacterScore)
-	fmt.Printf("  Combined Score:  %.2f\n", result1.CombinedScore)
-	fmt.Printf("  Passed:          %v\n", result1.Passed)
+	printCombined(result1)
 
 	fmt.Printf("\nCharacter-weighted (70%%/30%%):\n")
-	fmt.Printf("  Length Score:    %.2f\n", result2.LengthScore)
-	fmt.Printf("  Character Score: %.2f\n", result2.CharacterScore)
-	fmt.Printf("  Combined Score:  %.2f\n", result2.CombinedScore)
-	fmt.Printf("  Passed:          %v\n", result2.Passed)
+	printCombined(result2)
 }
 
-// CalculateCombinedMetrics calculates both length and character similarity
-// and combines them into a single score
-func CalculateCombinedMetrics(
-	ctx context.Context,
-	ls *word.LengthSimilarity,
-	cs *character.CharacterSimilarity,
-	original, augmented string,
-	threshold float64,
-) CombinedResult {
-	// Calculate individual metrics
-	lengthResult := ls.Compute(ctx, original, augmented)
-	charResult := cs.Compute(ctx, original, augmented)
-
-	// Combine scores (simple average)
-	combinedScore := (lengthResult.Score + charResult.Score) / 2
-
-	// Determine pass/fail based on combined threshold
-	passed := combinedScore >= threshold
-
-	// Create details map
-	details := map[string]interface{}{
-		"length_result":    lengthResult,
-		"character_result": charResult,
-		"threshold":        threshold,
-	}
-
-	return CombinedResult{
-		LengthScore:    lengthResult.Score,
-		CharacterScore: charResult.Score,
-		CombinedScore:  combinedScore,
-		Passed:         passed,
-		Details:        details,
+// weighted combines the two calculators with the given weights
+func weighted(ls *word.LengthSimilarity, cs *character.CharacterSimilarity, lengthWeight, characterWeight float64) *evaluate.Combiner {
+	combiner, err := evaluate.NewCombiner(
+		evaluate.WithCombinedThreshold(0.7),
+		evaluate.WithWeightedMetrics(
+			evaluate.Weighted{Name: "word", Metric: ls, Weight: lengthWeight},
+			evaluate.Weighted{Name: "character", Metric: cs, Weight: characterWeight},
+		),
+	)
+	if err != nil {
+		panic(err)
 	}
+	return combiner
 }
 
-// CalculateWeightedMetrics calculates both metrics and combines them with custom weights
-func CalculateWeightedMetrics(
-	ctx context.Context,
-	ls *word.LengthSimilarity,
-	cs *character.CharacterSimilarity,
-	original, augmented string,
-	lengthWeight, characterWeight, threshold float64,
-) CombinedResult {
-	// Ensure weights sum to 1.0
-	sum := lengthWeight + characterWeight
-	if math.Abs(sum-1.0) > 0.001 {
-		lengthWeight = lengthWeight / sum
-		characterWeight = characterWeight / sum
-	}
-
-	// Calculate individual metrics
-	lengthResult := ls.Compute(ctx, original, augmented)
-	charResult := cs.Compute(ctx, original, augmented)
-
-	// Apply weighted combination
-	combinedScore := (lengthResult.Score * lengthWeight) + (charResult.Score * characterWeight)
-
-	// Determine pass/fail based on threshold
-	passed := combinedScore >= threshold
-
-	// Create details map
-	details := map[string]interface{}{
-		"length_result":    lengthResult,
-		"character_result": charResult,
-		"length_weight":    lengthWeight,
-		"character_weight": characterWeight,
-		"threshold":        threshold,
-	}
-
-	return CombinedResult{
-		LengthScore:    lengthResult.Score,
-		CharacterScore: charResult.Score,
-		CombinedScore:  combinedScore,
-		Passed:         passed,
-		Details:        details,
-	}
+// printCombined prints the per-metric and combined scores
+func printCombined(result evaluate.Combined) {
+	fmt.Printf("  Length Score:    %.2f\n", result.Results[0].Score)
+	fmt.Printf("  Character Score: %.2f\n", result.Results[1].Score)
+	fmt.Printf("  Combined Score:  %.2f\n", result.Score)
+	fmt.Printf("  Passed:          %v\n", result.Passed)
 }
 
 /*
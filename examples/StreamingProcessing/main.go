@@ -133,7 +133,7 @@ func highPerformanceStreamingExample(ctx context.Context) {
 
 	// Initialize the allocation-efficient streaming similarity
 	ss, err := streaming.NewAllocationEfficientStreamingSimilarity(
-		logger,
+		streaming.WithEfficientLogger(logger),
 		streaming.WithEfficientMode(streaming.LineByLine),
 		streaming.WithEfficientChunkSize(4096), // 4KB chunks
 		streaming.WithEfficientParallel(true),  // Enable parallel processing
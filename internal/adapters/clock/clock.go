@@ -0,0 +1,29 @@
+// Package clock provides the wall clock calculators use unless given another
+package clock
+
+import (
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// systemClock reads the wall clock
+type systemClock struct{}
+
+// Now returns time.Now()
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System returns the wall clock
+func System() ports.Clock {
+	return systemClock{}
+}
+
+// OrSystem returns c, or the wall clock when c is nil
+func OrSystem(c ports.Clock) ports.Clock {
+	if c == nil {
+		return systemClock{}
+	}
+	return c
+}
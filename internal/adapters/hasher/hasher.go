@@ -0,0 +1,84 @@
+// Package hasher provides the ports.Hasher implementations.
+package hasher
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/cespare/xxhash/v2"
+)
+
+// HasherType identifies a hash algorithm
+type HasherType int
+
+const (
+	// XXHashType is a fast non-cryptographic 64-bit hash (default)
+	XXHashType HasherType = iota
+	// SHA256Type is collision resistant, for keys an attacker may choose
+	SHA256Type
+)
+
+// String returns the name accepted by ParseType
+func (t HasherType) String() string {
+	switch t {
+	case SHA256Type:
+		return "sha256"
+	default:
+		return "xxhash"
+	}
+}
+
+// ParseType maps a name ("xxhash" or "sha256") to a HasherType
+func ParseType(name string) (HasherType, error) {
+	switch name {
+	case "", "xxhash":
+		return XXHashType, nil
+	case "sha256":
+		return SHA256Type, nil
+	default:
+		return 0, fmt.Errorf("unknown hash %q (want xxhash or sha256)", name)
+	}
+}
+
+// New creates a hasher of the given type
+func New(t HasherType) ports.Hasher {
+	if t == SHA256Type {
+		return SHA256Hasher{}
+	}
+	return XXHasher{}
+}
+
+// Default returns the default hasher (xxhash)
+func Default() ports.Hasher {
+	return XXHasher{}
+}
+
+// XXHasher hashes with 64-bit xxHash
+type XXHasher struct{}
+
+// Sum returns the 8-byte big-endian xxHash of data
+func (XXHasher) Sum(data []byte) []byte {
+	return binary.BigEndian.AppendUint64(nil, xxhash.Sum64(data))
+}
+
+// SumString returns the 8-byte big-endian xxHash of s
+func (XXHasher) SumString(s string) []byte {
+	return binary.BigEndian.AppendUint64(nil, xxhash.Sum64String(s))
+}
+
+// SHA256Hasher hashes with SHA-256
+type SHA256Hasher struct{}
+
+// Sum returns the SHA-256 digest of data
+func (SHA256Hasher) Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// SumString returns the SHA-256 digest of s
+func (SHA256Hasher) SumString(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
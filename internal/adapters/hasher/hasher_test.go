@@ -0,0 +1,35 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashersAgreeOnStringAndBytes(t *testing.T) {
+	for _, typ := range []HasherType{XXHashType, SHA256Type} {
+		h := New(typ)
+		if !bytes.Equal(h.Sum([]byte("hello world")), h.SumString("hello world")) {
+			t.Errorf("%s: Sum and SumString differ", typ)
+		}
+		if bytes.Equal(h.SumString("a"), h.SumString("b")) {
+			t.Errorf("%s: distinct inputs share a digest", typ)
+		}
+	}
+
+	want := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if got := hex.EncodeToString(New(SHA256Type).SumString("hello world")); got != want {
+		t.Errorf("sha256 = %s, want %s", got, want)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, typ := range []HasherType{XXHashType, SHA256Type} {
+		if got, err := ParseType(typ.String()); err != nil || got != typ {
+			t.Errorf("ParseType(%q) = %v, %v", typ.String(), got, err)
+		}
+	}
+	if _, err := ParseType("md5"); err == nil {
+		t.Error("expected an error for an unknown hash")
+	}
+}
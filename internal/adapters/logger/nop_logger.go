@@ -0,0 +1,28 @@
+package logger
+
+import "github.com/baditaflorin/go_length_similarity/internal/ports"
+
+// NopLogger discards all log messages.
+type NopLogger struct{}
+
+// NewNopLogger creates a logger that discards everything.
+func NewNopLogger() ports.Logger {
+	return NopLogger{}
+}
+
+// Debug discards a debug message.
+func (NopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// Info discards an info message.
+func (NopLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+// Warn discards a warning message.
+func (NopLogger) Warn(msg string, keysAndValues ...interface{}) {}
+
+// Error discards an error message.
+func (NopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+// Close is a no-op.
+func (NopLogger) Close() error {
+	return nil
+}
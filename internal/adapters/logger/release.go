@@ -0,0 +1,24 @@
+package logger
+
+import "github.com/baditaflorin/go_length_similarity/internal/ports"
+
+// flusher is implemented by loggers that buffer entries, such as StdLogger
+type flusher interface {
+	Flush() error
+}
+
+// Release is how a component gives up a logger when it shuts down: a logger
+// it created itself is closed, while one the caller passed in is only flushed,
+// because the caller may still be using it.
+func Release(l ports.Logger, owned bool) error {
+	if l == nil {
+		return nil
+	}
+	if owned {
+		return l.Close()
+	}
+	if f, ok := l.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -12,9 +12,10 @@ type StdLogger struct {
 	logger l.Logger
 }
 
-// NewStdLogger creates a new standard logger adapter with default configuration.
-func NewStdLogger() (ports.Logger, error) {
-	logger, err := l.NewStandardFactory().CreateLogger(l.Config{
+// DefaultConfig returns the configuration of the default logger: asynchronous
+// text output to stdout.
+func DefaultConfig() l.Config {
+	return l.Config{
 		Output:      os.Stdout,
 		JsonFormat:  false,
 		AsyncWrite:  true,
@@ -23,8 +24,12 @@ func NewStdLogger() (ports.Logger, error) {
 		MaxBackups:  5,
 		AddSource:   true,
 		Metrics:     true,
-	})
+	}
+}
 
+// NewStdLogger creates a new standard logger adapter with default configuration.
+func NewStdLogger() (ports.Logger, error) {
+	logger, err := l.NewStandardFactory().CreateLogger(DefaultConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +72,11 @@ func (l *StdLogger) Close() error {
 	return l.logger.Close()
 }
 
+// Flush writes out any buffered log entries without closing the logger.
+func (l *StdLogger) Flush() error {
+	return l.logger.Flush()
+}
+
 // FromExisting creates a new StdLogger from an existing l.Logger.
 func FromExisting(logger l.Logger) ports.Logger {
 	return &StdLogger{logger: logger}
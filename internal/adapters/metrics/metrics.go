@@ -0,0 +1,96 @@
+// Package metrics provides the ports.Metrics implementations calculators
+// record to: a no-op, a Prometheus registry, a StatsD client and a fan-out
+package metrics
+
+import (
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// Names of the metrics the calculators record
+const (
+	// Comparisons counts comparisons by engine, mode and outcome
+	Comparisons = "similarity_comparisons_total"
+	// ComputeSeconds is a histogram of comparison latency by engine and mode
+	ComputeSeconds = "similarity_compute_seconds"
+	// BytesProcessed counts the input bytes the streaming engines read
+	BytesProcessed = "similarity_bytes_processed_total"
+	// WarmupSeconds is how long a calculator's warm-up took, by engine
+	WarmupSeconds = "similarity_warmup_seconds"
+)
+
+// Outcomes of a comparison, the values of the "outcome" label
+const (
+	OutcomePassed       = "passed"
+	OutcomeFailed       = "failed"
+	OutcomeInconclusive = "inconclusive"
+	OutcomeError        = "error"
+)
+
+// Outcome classifies a comparison for the "outcome" label
+func Outcome(passed, inconclusive, errored bool) string {
+	switch {
+	case errored:
+		return OutcomeError
+	case inconclusive:
+		return OutcomeInconclusive
+	case passed:
+		return OutcomePassed
+	default:
+		return OutcomeFailed
+	}
+}
+
+// RecordComparison records one comparison's outcome and latency
+func RecordComparison(m ports.Metrics, engine, mode, outcome string, elapsed time.Duration) {
+	m.Count(Comparisons, 1, "engine", engine, "mode", mode, "outcome", outcome)
+	m.Observe(ComputeSeconds, elapsed.Seconds(), "engine", engine, "mode", mode)
+}
+
+// nop discards every measurement
+type nop struct{}
+
+func (nop) Count(string, float64, ...string)   {}
+func (nop) Gauge(string, float64, ...string)   {}
+func (nop) Observe(string, float64, ...string) {}
+
+// Nop returns metrics that discard every measurement
+func Nop() ports.Metrics {
+	return nop{}
+}
+
+// multi records every measurement to each of its metrics
+type multi []ports.Metrics
+
+func (m multi) Count(name string, delta float64, labels ...string) {
+	for _, each := range m {
+		each.Count(name, delta, labels...)
+	}
+}
+
+func (m multi) Gauge(name string, value float64, labels ...string) {
+	for _, each := range m {
+		each.Gauge(name, value, labels...)
+	}
+}
+
+func (m multi) Observe(name string, value float64, labels ...string) {
+	for _, each := range m {
+		each.Observe(name, value, labels...)
+	}
+}
+
+// Multi returns metrics that record to each of ms, skipping nil ones
+func Multi(ms ...ports.Metrics) ports.Metrics {
+	var all multi
+	for _, m := range ms {
+		if m != nil {
+			all = append(all, m)
+		}
+	}
+	if len(all) == 1 {
+		return all[0]
+	}
+	return all
+}
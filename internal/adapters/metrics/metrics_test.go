@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrometheusExposition(t *testing.T) {
+	p := NewPrometheus(0.1, 1)
+	p.Count(Comparisons, 1, "engine", "word", "outcome", "passed")
+	p.Count(Comparisons, 2, "engine", "word", "outcome", "passed")
+	p.Count(Comparisons, 1, "engine", "char", "outcome", `say "hi"`)
+	p.Gauge(WarmupSeconds, 0.25, "engine", "word")
+	p.Observe(ComputeSeconds, 0.05)
+	p.Observe(ComputeSeconds, 0.5)
+	p.Observe(ComputeSeconds, 3)
+	// A name keeps its first kind
+	p.Gauge(Comparisons, 100)
+
+	var buf bytes.Buffer
+	n, err := p.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, wrote %d", n, buf.Len())
+	}
+
+	want := `# TYPE similarity_comparisons_total counter
+similarity_comparisons_total{engine="char",outcome="say \"hi\""} 1
+similarity_comparisons_total{engine="word",outcome="passed"} 3
+# TYPE similarity_compute_seconds histogram
+similarity_compute_seconds_bucket{le="0.1"} 1
+similarity_compute_seconds_bucket{le="1"} 2
+similarity_compute_seconds_bucket{le="+Inf"} 3
+similarity_compute_seconds_sum 3.55
+similarity_compute_seconds_count 3
+# TYPE similarity_warmup_seconds gauge
+similarity_warmup_seconds{engine="word"} 0.25
+`
+	if buf.String() != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrometheusIsSafeForConcurrentUse(t *testing.T) {
+	p := NewPrometheus()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				RecordComparison(p, "word", "text", OutcomePassed, time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `similarity_comparisons_total{engine="word",mode="text",outcome="passed"} 800`) {
+		t.Errorf("expected 800 comparisons:\n%s", buf.String())
+	}
+}
+
+func TestStatsDLineFormat(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	defer pc.Close()
+
+	s, err := NewStatsD(pc.LocalAddr().String(), "app.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Count("hits", 2, "engine", "word", "mode", "a|b")
+	s.Gauge("level", 1.5)
+	s.Observe("latency", 0.25, "engine", "word")
+
+	want := []string{"app.hits:2|c|#engine:word,mode:a_b", "app.level:1.5|g", "app.latency:0.25|h|#engine:word"}
+	buf := make([]byte, 512)
+	for _, line := range want {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != line {
+			t.Errorf("packet %q, want %q", got, line)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	for _, tc := range []struct {
+		passed, inconclusive, errored bool
+		want                          string
+	}{
+		{passed: true, want: OutcomePassed},
+		{want: OutcomeFailed},
+		{passed: true, inconclusive: true, want: OutcomeInconclusive},
+		{inconclusive: true, errored: true, want: OutcomeError},
+	} {
+		if got := Outcome(tc.passed, tc.inconclusive, tc.errored); got != tc.want {
+			t.Errorf("Outcome(%v, %v, %v) = %s, want %s", tc.passed, tc.inconclusive, tc.errored, got, tc.want)
+		}
+	}
+}
+
+func TestMulti(t *testing.T) {
+	a, b := NewPrometheus(), NewPrometheus()
+	Multi(a, nil, b).Count("n", 1)
+	for _, p := range []*Prometheus{a, b} {
+		var buf bytes.Buffer
+		p.WriteTo(&buf)
+		if !strings.Contains(buf.String(), "n 1\n") {
+			t.Errorf("expected the count in every registry:\n%s", buf.String())
+		}
+	}
+	if _, ok := Multi(a).(*Prometheus); !ok {
+		t.Error("expected a single metrics to be returned as is")
+	}
+}
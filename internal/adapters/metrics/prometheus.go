@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds, in seconds, sized for
+// comparisons that take from tens of microseconds to seconds
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Prometheus keeps measurements in memory and renders them in the Prometheus
+// text exposition format for a scrape endpoint. A name keeps the kind it was
+// first recorded as; measurements of another kind under it are dropped.
+type Prometheus struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*family
+}
+
+// family is every series of one metric name
+type family struct {
+	kind   string
+	series map[string]*series
+}
+
+// series is one metric name with one set of label values
+type series struct {
+	labels string
+	value  float64
+	// Histograms only: counts[i] observations were at most buckets[i]
+	counts []uint64
+	count  uint64
+}
+
+// NewPrometheus creates an empty registry; buckets override DefaultBuckets
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Prometheus{buckets: buckets, families: make(map[string]*family)}
+}
+
+// Count adds delta to a counter
+func (p *Prometheus) Count(name string, delta float64, labels ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s := p.series("counter", name, labels); s != nil {
+		s.value += delta
+	}
+}
+
+// Gauge sets a gauge to value
+func (p *Prometheus) Gauge(name string, value float64, labels ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s := p.series("gauge", name, labels); s != nil {
+		s.value = value
+	}
+}
+
+// Observe records value in a histogram
+func (p *Prometheus) Observe(name string, value float64, labels ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.series("histogram", name, labels)
+	if s == nil {
+		return
+	}
+	if s.counts == nil {
+		s.counts = make([]uint64, len(p.buckets))
+	}
+	for i, bound := range p.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.value += value
+	s.count++
+}
+
+// series finds or creates the series of name with labels, or returns nil
+// when name was recorded as another kind
+func (p *Prometheus) series(kind, name string, labels []string) *series {
+	f := p.families[name]
+	if f == nil {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		p.families[name] = f
+	}
+	if f.kind != kind {
+		return nil
+	}
+
+	key := formatLabels(labels)
+	s := f.series[key]
+	if s == nil {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WriteTo renders every metric, sorted by name and labels
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	names := make([]string, 0, len(p.families))
+	for name := range p.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := p.families[name]
+		fmt.Fprintf(cw, "# TYPE %s %s\n", name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != "histogram" {
+				fmt.Fprintf(cw, "%s%s %s\n", name, braces(s.labels), formatFloat(s.value))
+				continue
+			}
+			for i, bound := range p.buckets {
+				fmt.Fprintf(cw, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, `le="`+formatFloat(bound)+`"`)), s.counts[i])
+			}
+			fmt.Fprintf(cw, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, `le="+Inf"`)), s.count)
+			fmt.Fprintf(cw, "%s_sum%s %s\n", name, braces(s.labels), formatFloat(s.value))
+			fmt.Fprintf(cw, "%s_count%s %d\n", name, braces(s.labels), s.count)
+		}
+	}
+
+	if err := cw.w.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// formatLabels renders name and value pairs as name="value",...; a trailing
+// name without a value is dropped
+func formatLabels(labels []string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(labels); i += 2 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter counts bytes written and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
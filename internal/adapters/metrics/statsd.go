@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// StatsD sends each measurement as one UDP packet in the StatsD line format,
+// with labels as DogStatsD tags (|#name:value), which Datadog, Telegraf and
+// the statsd_exporter accept. Counters are sent as |c, gauges as |g and
+// histogram observations as |h. Like any StatsD client it is fire and
+// forget: packets that cannot be sent are dropped.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsD creates a client sending to addr (host:port), prefixing every
+// name with prefix
+func NewStatsD(addr, prefix string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsD{conn: conn, prefix: prefix}, nil
+}
+
+// Count sends a counter increment
+func (s *StatsD) Count(name string, delta float64, labels ...string) {
+	s.send(name, delta, "c", labels)
+}
+
+// Gauge sends a gauge value
+func (s *StatsD) Gauge(name string, value float64, labels ...string) {
+	s.send(name, value, "g", labels)
+}
+
+// Observe sends a histogram sample
+func (s *StatsD) Observe(name string, value float64, labels ...string) {
+	s.send(name, value, "h", labels)
+}
+
+// Close closes the UDP socket
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// send writes one line, name:value|kind|#tags
+func (s *StatsD) send(name string, value float64, kind string, labels []string) {
+	buf := make([]byte, 0, 128)
+	buf = append(buf, s.prefix...)
+	buf = append(buf, name...)
+	buf = append(buf, ':')
+	buf = strconv.AppendFloat(buf, value, 'g', -1, 64)
+	buf = append(buf, '|')
+	buf = append(buf, kind...)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			buf = append(buf, "|#"...)
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, labels[i]...)
+		buf = append(buf, ':')
+		buf = append(buf, tagEscaper.Replace(labels[i+1])...)
+	}
+	_, _ = s.conn.Write(buf)
+}
+
+// tagEscaper replaces the characters that delimit StatsD lines and tags
+var tagEscaper = strings.NewReplacer("|", "_", ",", "_", "\n", "_", "#", "_")
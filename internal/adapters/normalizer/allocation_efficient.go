@@ -4,7 +4,9 @@ package normalizer
 import (
 	"sync"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
 )
 
@@ -12,6 +14,11 @@ import (
 type ByteNormalizer interface {
 	ports.Normalizer
 	NormalizeBytes([]byte, []byte) []byte
+	// NormalizeBatch returns the normalized length in runes of each line
+	NormalizeBatch(lines [][]byte) []int
+	// CountNormalized counts the runes and words of the normalized src
+	// without producing it
+	CountNormalized(src []byte) (runes int, words int)
 }
 
 // AllocationEfficientNormalizer implements an optimized normalizer with minimal allocations
@@ -75,38 +82,15 @@ func (n *AllocationEfficientNormalizer) Normalize(text string) string {
 		return ""
 	}
 
-	// Get a buffer from the pool
+	// Get a buffer from the pool; normalizing sizes it for this text
 	buffer := n.bufferPool.Get().(*[]byte)
-
-	// Ensure the buffer has adequate capacity
-	if cap(*buffer) < len(text)*2 {
-		*buffer = make([]byte, 0, len(text)*2)
-	}
-	*buffer = (*buffer)[:0] // Reset length while keeping capacity
-
-	// Fast path for ASCII-only strings
-	asciiOnly := true
-	for i := 0; i < len(text); i++ {
-		if text[i] >= 128 {
-			asciiOnly = false
-			break
-		}
-	}
-
-	var result []byte
-	if asciiOnly {
-		// Fast path for ASCII
-		result = n.normalizeASCII([]byte(text), *buffer)
-	} else {
-		// Fallback for non-ASCII text
-		result = n.normalizeUnicode([]byte(text), *buffer)
-	}
+	result := n.NormalizeBytes(bytesconv.Bytes(text), *buffer)
 
 	// Create a string from the result
 	s := string(result)
 
-	// Reset and return the buffer to the pool
-	*buffer = (*buffer)[:0]
+	// Keep the buffer at its new size and return it to the pool
+	*buffer = result[:0]
 	n.bufferPool.Put(buffer)
 
 	return s
@@ -136,16 +120,139 @@ func (n *AllocationEfficientNormalizer) NormalizeBytes(src []byte, dest []byte)
 	return n.normalizeUnicode(src, dest)
 }
 
-// normalizeASCII performs fast normalization of ASCII-only text
-func (n *AllocationEfficientNormalizer) normalizeASCII(src []byte, dest []byte) []byte {
-	dest = dest[:0]
+// NormalizeBatch returns the normalized length in runes of each line. Only
+// the counts are needed, so no normalized bytes are produced and the batch
+// costs one allocation for the counts however many lines it holds.
+func (n *AllocationEfficientNormalizer) NormalizeBatch(lines [][]byte) []int {
+	counts := make([]int, len(lines))
+	for i, line := range lines {
+		counts[i], _ = n.CountNormalized(line)
+	}
+	return counts
+}
+
+// CountNormalized returns the rune and word counts of the normalized src
+// without writing it anywhere. runes equals utf8.RuneCount and words equals
+// len(strings.Fields(...)) of what NormalizeBytes produces for src.
+func (n *AllocationEfficientNormalizer) CountNormalized(src []byte) (runes int, words int) {
+	lastWasSpace := false // punctuation collapse, as when normalizing
+	inWord := false
 
-	// Reserve capacity if needed
-	if cap(dest) < len(src) {
-		newDest := make([]byte, 0, len(src))
-		dest = newDest
+	for i := 0; i < len(src); {
+		if b := src[i]; b < 128 {
+			if entry := n.asciiTable[b]; entry.replace && entry.char == ' ' {
+				if !lastWasSpace {
+					runes++
+					lastWasSpace = true
+				}
+				inWord = false
+			} else {
+				runes++
+				lastWasSpace = false
+				if isASCIISpace(b) {
+					inWord = false
+				} else if !inWord {
+					words++
+					inWord = true
+				}
+			}
+			i++
+			continue
+		}
+
+		r, size := decodeRune(src[i:])
+		if unicode.IsPunct(r) || unicode.IsSpace(r) {
+			if !lastWasSpace {
+				runes++
+				lastWasSpace = true
+			}
+			inWord = false
+		} else {
+			if unicode.IsUpper(r) {
+				runes++
+			} else {
+				// Kept as is; invalid sequences count one rune per byte
+				runes += utf8.RuneCount(src[i : i+size])
+			}
+			lastWasSpace = false
+			if !inWord {
+				words++
+				inWord = true
+			}
+		}
+		i += size
 	}
 
+	return runes, words
+}
+
+// isASCIISpace reports whether b is one of the ASCII bytes unicode.IsSpace accepts
+func isASCIISpace(b byte) bool {
+	return b == ' ' || '\t' <= b && b <= '\r'
+}
+
+// PredictLength returns the exact length in bytes of the normalized src,
+// accounting for collapsed punctuation and runes whose lower case encodes to
+// a different length. It is a cheap pre-scan that lets callers size a buffer
+// once instead of growing it while normalizing.
+func (n *AllocationEfficientNormalizer) PredictLength(src []byte) int {
+	size := 0
+	lastWasSpace := false
+
+	for i := 0; i < len(src); {
+		if src[i] < 128 {
+			if entry := n.asciiTable[src[i]]; entry.replace && entry.char == ' ' {
+				if !lastWasSpace {
+					size++
+					lastWasSpace = true
+				}
+			} else {
+				size++
+				lastWasSpace = false
+			}
+			i++
+			continue
+		}
+
+		r, width := decodeRune(src[i:])
+		switch {
+		case unicode.IsPunct(r) || unicode.IsSpace(r):
+			if !lastWasSpace {
+				size++
+				lastWasSpace = true
+			}
+		case unicode.IsUpper(r):
+			size += encodedLen(unicode.ToLower(r))
+			lastWasSpace = false
+		default:
+			size += width
+			lastWasSpace = false
+		}
+		i += width
+	}
+
+	return size
+}
+
+// reserve returns dest emptied and able to hold the normalized src. A dest
+// that already fits worstCase bytes is used without scanning; otherwise the
+// exact length is predicted, so a new buffer is allocated once at its final
+// size rather than over-allocated or grown by append.
+func (n *AllocationEfficientNormalizer) reserve(src, dest []byte, worstCase int) []byte {
+	if cap(dest) >= worstCase {
+		return dest[:0]
+	}
+	if size := n.PredictLength(src); cap(dest) < size {
+		return make([]byte, 0, size)
+	}
+	return dest[:0]
+}
+
+// normalizeASCII performs fast normalization of ASCII-only text
+func (n *AllocationEfficientNormalizer) normalizeASCII(src []byte, dest []byte) []byte {
+	// ASCII output is never longer than its input
+	dest = n.reserve(src, dest, len(src))
+
 	var lastWasSpace bool
 
 	for i := 0; i < len(src); i++ {
@@ -181,13 +288,8 @@ func (n *AllocationEfficientNormalizer) normalizeASCII(src []byte, dest []byte)
 
 // normalizeUnicode normalizes text that may contain unicode characters
 func (n *AllocationEfficientNormalizer) normalizeUnicode(src []byte, dest []byte) []byte {
-	dest = dest[:0]
-
-	// Reserve capacity if needed - unicode may expand
-	if cap(dest) < len(src)*2 {
-		newDest := make([]byte, 0, len(src)*2)
-		dest = newDest
-	}
+	// Lower-casing can turn a 2-byte rune into a 3-byte one
+	dest = n.reserve(src, dest, len(src)+len(src)/2)
 
 	var lastWasSpace bool
 
@@ -258,6 +360,20 @@ func (n *AllocationEfficientNormalizer) normalizeUnicode(src []byte, dest []byte
 	return dest
 }
 
+// encodedLen returns how many bytes normalizeUnicode writes for r
+func encodedLen(r rune) int {
+	switch {
+	case r < 128:
+		return 1
+	case r < 2048:
+		return 2
+	case r < 65536:
+		return 3
+	default:
+		return 4
+	}
+}
+
 // decodeRune decodes a UTF-8 sequence to a rune
 func decodeRune(b []byte) (rune, int) {
 	if len(b) == 0 {
@@ -0,0 +1,95 @@
+package normalizer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPredictLengthIsExact(t *testing.T) {
+	n := NewAllocationEfficientNormalizer().(*AllocationEfficientNormalizer)
+	// Pieces cover collapsed punctuation runs, Unicode spaces and punctuation,
+	// lower-casing that grows (Ⱥ→ⱥ) or shrinks (K→k) and invalid UTF-8
+	pieces := []string{"a", "Z", " ", ",", "...", "!?", "é", "É", "Ⱥ", "K", " ", "—", "«", "日本", "😀", "\xff", "\xe2\x82", "\n"}
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		var b strings.Builder
+		for j := rng.Intn(40); j > 0; j-- {
+			b.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		src := []byte(b.String())
+		got := len(n.NormalizeBytes(src, nil))
+		if predicted := n.PredictLength(src); predicted != got {
+			t.Fatalf("PredictLength(%q) = %d, normalized length %d", src, predicted, got)
+		}
+	}
+}
+
+func TestNormalizeBytesAllocatesOnce(t *testing.T) {
+	n := NewAllocationEfficientNormalizer().(*AllocationEfficientNormalizer)
+	// Mostly collapsed punctuation and growing runes: the old 2x reservation
+	// over-allocated the first and a len(src) one would grow for the second
+	for _, src := range [][]byte{
+		[]byte(strings.Repeat("Ⱥ", 1000)),
+		[]byte(strings.Repeat("word,,,,,, ", 1000)),
+	} {
+		var out []byte
+		allocs := testing.AllocsPerRun(20, func() {
+			out = n.NormalizeBytes(src, nil)
+		})
+		if allocs != 1 {
+			t.Errorf("%.10q...: %v allocations, want 1", src, allocs)
+		}
+		if cap(out) != len(out) {
+			t.Errorf("%.10q...: capacity %d for %d bytes", src, cap(out), len(out))
+		}
+	}
+}
+
+func TestNormalizeBatchMatchesNormalize(t *testing.T) {
+	n := NewAllocationEfficientNormalizer()
+	lines := [][]byte{[]byte("Hello, World"), nil, []byte("Ünïcode — TEXT!!"), []byte(strings.Repeat("Ⱥb ", 500))}
+
+	counts := n.NormalizeBatch(lines)
+	if len(counts) != len(lines) {
+		t.Fatalf("got %d counts for %d lines", len(counts), len(lines))
+	}
+	for i, line := range lines {
+		if want := len([]rune(n.Normalize(string(line)))); counts[i] != want {
+			t.Errorf("line %d: count %d, want %d", i, counts[i], want)
+		}
+	}
+}
+
+func TestCountNormalizedMatchesOutput(t *testing.T) {
+	n := NewAllocationEfficientNormalizer()
+	pieces := []string{"a", "Z", " ", "\t", "\n", ",", "...", "é", "É", "Ⱥ", "\u00a0", "\u2003", "—", "日本", "😀", "\xff", "\xe2\x82", "\xc0\x80"}
+	rng := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 2000; i++ {
+		var b strings.Builder
+		for j := rng.Intn(40); j > 0; j-- {
+			b.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		src := []byte(b.String())
+		out := n.NormalizeBytes(src, nil)
+
+		runes, words := n.CountNormalized(src)
+		if want := utf8.RuneCount(out); runes != want {
+			t.Fatalf("CountNormalized(%q) runes = %d, want %d", src, runes, want)
+		}
+		if want := len(strings.Fields(string(out))); words != want {
+			t.Fatalf("CountNormalized(%q) words = %d, want %d", src, words, want)
+		}
+	}
+}
+
+func TestCountNormalizedDoesNotAllocate(t *testing.T) {
+	n := NewAllocationEfficientNormalizer()
+	src := []byte(strings.Repeat("Mixed Ünïcode, TEXT. ", 500))
+	if allocs := testing.AllocsPerRun(20, func() { n.CountNormalized(src) }); allocs != 0 {
+		t.Errorf("CountNormalized allocated %v times", allocs)
+	}
+}
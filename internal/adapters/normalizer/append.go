@@ -0,0 +1,37 @@
+package normalizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AppendNormalized appends the lower-cased text with punctuation replaced by spaces to dst
+func (n *DefaultNormalizer) AppendNormalized(dst []byte, text string) []byte {
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsPunct(r) {
+			dst = append(dst, ' ')
+		} else {
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+	return dst
+}
+
+// AppendNormalized appends the normalized form of text to dst, with the same output as Normalize
+func (n *FastNormalizer) AppendNormalized(dst []byte, text string) []byte {
+	for _, r := range text {
+		if r < 128 {
+			entry := n.asciiTable[r]
+			if entry.replace {
+				r = entry.char
+			}
+		} else if unicode.IsPunct(r) {
+			r = ' '
+		} else {
+			r = unicode.ToLower(r)
+		}
+		dst = utf8.AppendRune(dst, r)
+	}
+	return dst
+}
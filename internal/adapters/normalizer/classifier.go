@@ -0,0 +1,52 @@
+package normalizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ClassifierNormalizer lower-cases the runes a word classifier accepts and
+// replaces every other rune with a space, so strings.Fields splits its output
+// into exactly the classifier's words: maximal runs of accepted runes.
+// Whitespace always separates words, even if the classifier accepts it.
+type ClassifierNormalizer struct {
+	isWord func(r rune) bool
+}
+
+// NewClassifierNormalizer creates a normalizer whose words are the runs of runes isWord accepts
+func NewClassifierNormalizer(isWord func(r rune) bool) *ClassifierNormalizer {
+	return &ClassifierNormalizer{isWord: isWord}
+}
+
+// Normalize keeps word runes, lower-cased, and turns the rest into spaces
+func (n *ClassifierNormalizer) Normalize(text string) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for _, r := range text {
+		if n.IsSeparator(r) {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// AppendNormalized appends the normalized form of text to dst, with the same output as Normalize
+func (n *ClassifierNormalizer) AppendNormalized(dst []byte, text string) []byte {
+	for _, r := range text {
+		if n.IsSeparator(r) {
+			dst = append(dst, ' ')
+		} else {
+			dst = utf8.AppendRune(dst, unicode.ToLower(r))
+		}
+	}
+	return dst
+}
+
+// IsSeparator reports whether r ends a word, so streaming counters can cut
+// their input on the same boundaries
+func (n *ClassifierNormalizer) IsSeparator(r rune) bool {
+	return unicode.IsSpace(r) || !n.isWord(r)
+}
@@ -0,0 +1,124 @@
+package normalizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// Placeholders LogNormalizer puts in place of variable fields. They are plain
+// words, so every normalizer keeps each one as a single word.
+const (
+	LogTimestamp = "TIMESTAMP"
+	LogUUID      = "UUID"
+	LogIP        = "IP"
+	LogHex       = "HEX"
+	LogNumber    = "NUM"
+)
+
+// LogNormalizer collapses the variable fields of log lines into placeholders
+// before delegating to another normalizer, so two log captures compare on
+// their structure rather than on the timestamps, IDs and addresses that differ
+// between any two runs. Each whitespace-delimited field, stripped of
+// surrounding brackets, quotes and trailing punctuation, is replaced when it
+// is a timestamp or time of day, a UUID, an IPv4 or IPv6 address (with an
+// optional port), a number (with an optional unit) or a hex ID of at least 8
+// digits. In key=value fields only the value is replaced. Whitespace is kept,
+// and fields never span it, so streamed counts equal those of the whole text.
+type LogNormalizer struct {
+	next ports.Normalizer
+}
+
+// NewLogNormalizer creates a log normalizer over the default normalizer
+func NewLogNormalizer() ports.Normalizer {
+	return NewLogNormalizerWith(NewDefaultNormalizer())
+}
+
+// NewLogNormalizerWith creates a log normalizer that collapses fields before next
+func NewLogNormalizerWith(next ports.Normalizer) *LogNormalizer {
+	return &LogNormalizer{next: next}
+}
+
+// Normalize collapses variable fields and normalizes the result with the next normalizer
+func (n *LogNormalizer) Normalize(text string) string {
+	return n.next.Normalize(CollapseLogFields(text))
+}
+
+// IsSeparator reports whether r ends a field, so streaming counters only cut
+// their input between fields
+func (n *LogNormalizer) IsSeparator(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+var (
+	logTimestampRE = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([T_]\d{2}:\d{2}(:\d{2}([.,]\d+)?)?)?|\d{1,2}:\d{2}(:\d{2}([.,]\d+)?)?)(Z|[+-]\d{2}:?\d{2})?$`)
+	logUUIDRE      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	logIPv4RE      = regexp.MustCompile(`^\d{1,3}(\.\d{1,3}){3}(:\d+)?$`)
+	logIPv6RE      = regexp.MustCompile(`^\[?[0-9a-fA-F]{0,4}(:[0-9a-fA-F]{0,4}){2,7}(%\w+)?\]?(:\d+)?$`)
+	logNumberRE    = regexp.MustCompile(`^[-+]?\d+([.,]\d+)*(?i:ns|us|µs|ms|s|m|h|b|kb|mb|gb|%)?$`)
+	logHexRE       = regexp.MustCompile(`^(0[xX])?[0-9a-fA-F]*\d[0-9a-fA-F]*$`)
+)
+
+// CollapseLogFields replaces the variable fields of text with placeholders, as
+// LogNormalizer describes, keeping everything else as it is
+func CollapseLogFields(text string) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				sb.WriteString(collapseLogField(text[start:i]))
+				start = -1
+			}
+			sb.WriteRune(r)
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		sb.WriteString(collapseLogField(text[start:]))
+	}
+	return sb.String()
+}
+
+// collapseLogField replaces one field, keeping its surrounding punctuation
+func collapseLogField(field string) string {
+	core := strings.TrimLeft(field, `[("'<{`)
+	prefix := field[:len(field)-len(core)]
+	core = strings.TrimRight(core, `])"'>},;.:`)
+	suffix := field[len(prefix)+len(core):]
+
+	if key, value, ok := strings.Cut(core, "="); ok && key != "" {
+		return prefix + key + "=" + collapseLogField(value) + suffix
+	}
+	if placeholder := logPlaceholder(core); placeholder != "" {
+		if strings.Contains(core, "]") {
+			// A bracketed IPv6 address with a port, [::1]:443
+			prefix = strings.TrimSuffix(prefix, "[")
+		}
+		return prefix + placeholder + suffix
+	}
+	return field
+}
+
+// logPlaceholder returns the placeholder for a variable field, or "" to keep it
+func logPlaceholder(s string) string {
+	switch {
+	case s == "":
+		return ""
+	case logTimestampRE.MatchString(s):
+		return LogTimestamp
+	case logUUIDRE.MatchString(s):
+		return LogUUID
+	case logIPv4RE.MatchString(s), strings.Count(s, ":") >= 2 && logIPv6RE.MatchString(s):
+		return LogIP
+	case logNumberRE.MatchString(s):
+		return LogNumber
+	case len(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")) >= 8 && logHexRE.MatchString(s):
+		return LogHex
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package normalizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollapseLogFields(t *testing.T) {
+	cases := map[string]string{
+		"2024-03-01T12:00:05.123Z INFO request done":         "TIMESTAMP INFO request done",
+		"[12:00:05] GET /users/42 took 15ms":                 "[TIMESTAMP] GET /users/42 took NUM",
+		"from 10.0.0.12:51234 to [2001:db8::1]:443":          "from IP to IP",
+		"trace=3f2b9c1e-8d4a-4b2e-9f1c-0a1b2c3d4e5f user=17": "trace=UUID user=NUM",
+		"commit deadbeef42 ok, retries=3.":                   "commit HEX ok, retries=NUM.",
+		"connection refused (errno 111)":                     "connection refused (errno NUM)",
+		"status: ready\n\tworker pool idle":                  "status: ready\n\tworker pool idle",
+	}
+	for in, want := range cases {
+		if got := CollapseLogFields(in); got != want {
+			t.Errorf("CollapseLogFields(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLogNormalizerComparesStructure(t *testing.T) {
+	first := "2024-03-01 12:00:05,120 INFO conn=7f3a9b2c1d from 10.0.0.12 served /api in 12ms\n"
+	second := "2024-03-02 08:41:59,003 INFO conn=00c0ffee99 from 192.168.1.200 served /api in 1.2s\n"
+
+	n := NewLogNormalizer()
+	if a, b := n.Normalize(first), n.Normalize(second); a != b {
+		t.Errorf("expected equal templates, got %q and %q", a, b)
+	}
+	if plain := NewDefaultNormalizer(); len(strings.Fields(plain.Normalize(first))) == len(strings.Fields(plain.Normalize(second))) {
+		t.Error("expected the default normalizer to split the variable fields differently")
+	}
+}
@@ -0,0 +1,105 @@
+package normalizer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// OCRNormalizer folds the artifacts OCR engines leave in scanned text before
+// delegating to another normalizer, so comparisons of scanned documents count
+// the content rather than the recognition errors:
+//
+//   - soft hyphens and zero-width characters are removed (transform
+//     JoinHyphenation joins words a soft hyphen splits across lines)
+//   - typographic ligatures (ﬁ, ﬂ, ﬀ, ﬃ, ﬄ, ﬅ, ﬆ, ĳ) are spelled out
+//   - common confusions are folded: "rn" to "m", "vv" to "w", and 0, 1 and |
+//     next to a letter to o, l and l
+//
+// The folds are applied to both texts alike, so they only change counts where
+// the two sides were recognized differently. All folds stay within a word, so
+// streamed counts equal those of the whole text.
+type OCRNormalizer struct {
+	next ports.Normalizer
+}
+
+// NewOCRNormalizer creates an OCR normalizer over the default normalizer
+func NewOCRNormalizer() ports.Normalizer {
+	return NewOCRNormalizerWith(NewDefaultNormalizer())
+}
+
+// NewOCRNormalizerWith creates an OCR normalizer that folds text before next
+func NewOCRNormalizerWith(next ports.Normalizer) *OCRNormalizer {
+	return &OCRNormalizer{next: next}
+}
+
+// Normalize folds OCR artifacts and normalizes the result with the next normalizer
+func (n *OCRNormalizer) Normalize(text string) string {
+	return n.next.Normalize(FoldOCR(text))
+}
+
+// ocrLigatures spells out the ligature runes OCR engines emit
+var ocrLigatures = map[rune]string{
+	'ﬀ': "ff",
+	'ﬁ': "fi",
+	'ﬂ': "fl",
+	'ﬃ': "ffi",
+	'ﬄ': "ffl",
+	'ﬅ': "st",
+	'ﬆ': "st",
+	'Ĳ': "ij",
+	'ĳ': "ij",
+}
+
+// isInvisible reports whether r is a zero-width character OCR leaves between letters
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// FoldOCR lower-cases text and folds the OCR artifacts OCRNormalizer describes
+func FoldOCR(text string) string {
+	in := []rune(strings.ToLower(text))
+	spelled := make([]rune, 0, len(in))
+	for i := 0; i < len(in); i++ {
+		r := in[i]
+		switch {
+		case r == '\u00ad' || isInvisible(r):
+		case ocrLigatures[r] != "":
+			spelled = append(spelled, []rune(ocrLigatures[r])...)
+		default:
+			spelled = append(spelled, r)
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for i := 0; i < len(spelled); i++ {
+		r := spelled[i]
+		next := rune(0)
+		if i+1 < len(spelled) {
+			next = spelled[i+1]
+		}
+		switch {
+		case r == 'r' && next == 'n':
+			sb.WriteRune('m')
+			i++
+		case r == 'v' && next == 'v':
+			sb.WriteRune('w')
+			i++
+		case (r == '0' || r == '1' || r == '|') && (i > 0 && unicode.IsLetter(spelled[i-1]) || unicode.IsLetter(next)):
+			if r == '0' {
+				sb.WriteRune('o')
+			} else {
+				sb.WriteRune('l')
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,43 @@
+package normalizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFoldOCR(t *testing.T) {
+	cases := map[string]string{
+		"The ﬁrst ofﬁce":         "the first office",
+		"rnodern vvork":          "modem work",
+		"modern work":            "modem work",
+		"w0rd 1ist, he|lo":       "word list, hello",
+		"room 101 in 2024":       "room 101 in 2024",
+		"soft\u00adhyphen":       "softhyphen",
+		"zero\u200bwidth\ufeff!": "zerowidth!",
+	}
+	for in, want := range cases {
+		if got := FoldOCR(in); got != want {
+			t.Errorf("FoldOCR(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOCRNormalizerEvensOutRecognitionErrors(t *testing.T) {
+	clean := "The first modern office building, with its tall windows."
+	scanned := "The ﬁrst rnodern ofﬁce bui1ding, with its ta\u00adll vvindows."
+
+	n := NewOCRNormalizer()
+	a, b := n.Normalize(clean), n.Normalize(scanned)
+	if utf8.RuneCountInString(a) != utf8.RuneCountInString(b) {
+		t.Errorf("rune counts differ: %q and %q", a, b)
+	}
+	if len(strings.Fields(a)) != len(strings.Fields(b)) {
+		t.Errorf("word counts differ: %q and %q", a, b)
+	}
+
+	plain := NewDefaultNormalizer()
+	if utf8.RuneCountInString(plain.Normalize(clean)) == utf8.RuneCountInString(plain.Normalize(scanned)) {
+		t.Error("expected the default normalizer to count the artifacts")
+	}
+}
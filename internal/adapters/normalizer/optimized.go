@@ -2,6 +2,7 @@ package normalizer
 
 import (
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/baditaflorin/go_length_similarity/internal/pool"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
@@ -50,15 +51,6 @@ func (n *OptimizedNormalizer) Normalize(text string) string {
 		return ""
 	}
 
-	// Check for ASCII-only string first (optimization)
-	asciiOnly := true
-	for i := 0; i < len(text); i++ {
-		if text[i] >= 128 {
-			asciiOnly = false
-			break
-		}
-	}
-
 	// Get a reusable buffer from the pool
 	buffer := n.bytePool.Get()
 	defer n.bytePool.Put(buffer)
@@ -67,7 +59,21 @@ func (n *OptimizedNormalizer) Normalize(text string) string {
 	if cap(*buffer) < len(text) {
 		*buffer = make([]byte, 0, len(text))
 	}
-	*buffer = (*buffer)[:0] // Reset length while keeping capacity
+	*buffer = n.AppendNormalized((*buffer)[:0], text)
+
+	return string(*buffer)
+}
+
+// AppendNormalized appends the normalized form of text to dst, with the same output as Normalize
+func (n *OptimizedNormalizer) AppendNormalized(dst []byte, text string) []byte {
+	// Check for ASCII-only string first (optimization)
+	asciiOnly := true
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 128 {
+			asciiOnly = false
+			break
+		}
+	}
 
 	if asciiOnly {
 		// Fast path for ASCII-only strings
@@ -76,21 +82,21 @@ func (n *OptimizedNormalizer) Normalize(text string) string {
 			b := text[i]
 			switch n.asciiTable[b] {
 			case 0: // Keep as is
-				*buffer = append(*buffer, b)
+				dst = append(dst, b)
 				lastWasSpace = false
 			case 1: // Replace with space
 				// Avoid consecutive spaces
 				if !lastWasSpace {
-					*buffer = append(*buffer, ' ')
+					dst = append(dst, ' ')
 					lastWasSpace = true
 				}
 			case 2: // Convert to lowercase (ASCII)
-				*buffer = append(*buffer, b+('a'-'A'))
+				dst = append(dst, b+('a'-'A'))
 				lastWasSpace = false
 			}
 		}
 
-		return string(*buffer)
+		return dst
 	}
 
 	// Slower path for mixed ASCII/Unicode strings
@@ -100,16 +106,16 @@ func (n *OptimizedNormalizer) Normalize(text string) string {
 			// ASCII character - use lookup table
 			switch n.asciiTable[r] {
 			case 0: // Keep as is
-				*buffer = append(*buffer, byte(r))
+				dst = append(dst, byte(r))
 				lastWasSpace = false
 			case 1: // Replace with space
 				// Avoid consecutive spaces
 				if !lastWasSpace {
-					*buffer = append(*buffer, ' ')
+					dst = append(dst, ' ')
 					lastWasSpace = true
 				}
 			case 2: // Convert to lowercase (ASCII)
-				*buffer = append(*buffer, byte(r)+('a'-'A'))
+				dst = append(dst, byte(r)+('a'-'A'))
 				lastWasSpace = false
 			}
 		} else {
@@ -117,20 +123,18 @@ func (n *OptimizedNormalizer) Normalize(text string) string {
 			if unicode.IsPunct(r) || unicode.IsSpace(r) {
 				// Replace punctuation with space
 				if !lastWasSpace {
-					*buffer = append(*buffer, ' ')
+					dst = append(dst, ' ')
 					lastWasSpace = true
 				}
 			} else {
 				// Convert to lowercase and append the UTF-8 bytes
-				lower := unicode.ToLower(r)
-				runeBytes := []byte(string(lower))
-				*buffer = append(*buffer, runeBytes...)
+				dst = utf8.AppendRune(dst, unicode.ToLower(r))
 				lastWasSpace = false
 			}
 		}
 	}
 
-	return string(*buffer)
+	return dst
 }
 
 // FastNormalizer offers an even faster normalization with pre-cached decisions
@@ -259,6 +263,10 @@ const (
 	OptimizedNormalizerType
 	// FastNormalizerType uses precomputed tables and is optimized for ASCII
 	FastNormalizerType
+	// OCRNormalizerType folds OCR artifacts before the default normalization
+	OCRNormalizerType
+	// LogNormalizerType collapses variable log fields before the default normalization
+	LogNormalizerType
 )
 
 // CreateNormalizer creates a normalizer of the specified type
@@ -268,6 +276,10 @@ func (f *NormalizerFactory) CreateNormalizer(normalizerType NormalizerType) port
 		return NewOptimizedNormalizer()
 	case FastNormalizerType:
 		return NewFastNormalizer()
+	case OCRNormalizerType:
+		return NewOCRNormalizer()
+	case LogNormalizerType:
+		return NewLogNormalizer()
 	default:
 		return NewDefaultNormalizer()
 	}
@@ -0,0 +1,23 @@
+package stream
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// OpenPair opens the original and augmented files from fsys. On error nothing
+// is left open; otherwise the caller closes both files.
+func OpenPair(fsys fs.FS, original, augmented string) (fs.File, fs.File, error) {
+	orig, err := fsys.Open(original)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open original: %w", err)
+	}
+
+	aug, err := fsys.Open(augmented)
+	if err != nil {
+		orig.Close()
+		return nil, nil, fmt.Errorf("open augmented: %w", err)
+	}
+
+	return orig, aug, nil
+}
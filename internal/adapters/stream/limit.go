@@ -0,0 +1,131 @@
+package stream
+
+import (
+	"io"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// maxBytesReader passes through at most limit bytes and then fails with a
+// *domain.MaxBytesError. Unlike io.LimitReader it reports the overflow
+// instead of silently truncating, which matters for unknown-length inputs
+// such as chunked HTTP bodies and pipes.
+type maxBytesReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	err       error
+}
+
+// LimitReader wraps r so reading more than limit bytes fails with a
+// *domain.MaxBytesError. A limit of 0 or less returns r unchanged.
+func LimitReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &maxBytesReader{r: r, limit: limit, remaining: limit}
+}
+
+// Read implements io.Reader
+func (l *maxBytesReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Ask for one byte past the limit so an overflow is detected without
+	// waiting for a further read
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.r.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		l.err = err
+		return n, err
+	}
+
+	n = int(l.remaining)
+	l.remaining = 0
+	l.err = &domain.MaxBytesError{Limit: l.limit}
+	return n, l.err
+}
+
+// limitHit reports whether r is an io.LimitedReader that was read up to its
+// cap. The underlying reader may or may not have had more data; peeking
+// would consume it, so the input can only be called potentially truncated.
+func limitHit(r io.Reader) bool {
+	lr, ok := r.(*io.LimitedReader)
+	return ok && lr.N <= 0
+}
+
+// LimitReached reports which of the caller's readers stopped at an
+// io.LimitReader cap: "original", "augmented", "both", or "" for neither
+func LimitReached(original, augmented io.Reader) string {
+	origHit, augHit := limitHit(original), limitHit(augmented)
+	switch {
+	case origHit && augHit:
+		return "both"
+	case origHit:
+		return "original"
+	case augHit:
+		return "augmented"
+	}
+	return ""
+}
+
+// MarkLimitReached flags a completed result as potentially truncated when
+// original or augmented, the readers the caller passed in, stopped at an
+// io.LimitReader cap. The score is kept; the flag tells the caller not to
+// trust it blindly.
+func MarkLimitReached(result ports.StreamResult, original, augmented io.Reader) ports.StreamResult {
+	if result.Err != nil {
+		return result
+	}
+	if which := LimitReached(original, augmented); which != "" {
+		result.PotentiallyTruncated = true
+		if result.Details == nil {
+			result.Details = make(map[string]interface{})
+		}
+		result.Details["limit_reached"] = which
+	}
+	return result
+}
+
+// fillReader keeps reading until the caller's buffer is full or the stream
+// ends. Pipes and chunked HTTP bodies return whatever has arrived, and the
+// processors normalize each read separately, so without this the counts
+// would depend on how the producer happened to split its writes.
+type fillReader struct {
+	r   io.Reader
+	err error
+}
+
+// FillReader wraps r so every Read fills the buffer unless the stream ends,
+// making results independent of how r splits its data
+func FillReader(r io.Reader) io.Reader {
+	if _, ok := r.(*fillReader); ok {
+		return r
+	}
+	return &fillReader{r: r}
+}
+
+// Read implements io.Reader
+func (f *fillReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && f.err == nil {
+		var m int
+		m, f.err = f.r.Read(p[n:])
+		n += m
+	}
+
+	// Report the end of the stream on the next call, like a bytes.Reader
+	if n > 0 {
+		return n, nil
+	}
+	return 0, f.err
+}
@@ -0,0 +1,80 @@
+package lineprocessor
+
+// Adaptive batching for the parallel line processor. BatchSize counts lines,
+// but a line's cost follows its length, so a fixed count gives jobs of wildly
+// different sizes on corpora mixing tiny and huge lines. The sizer turns
+// BatchSize into a byte budget and divides it by the recent average line
+// length, so every job carries about the same amount of text.
+const (
+	// batchLineBytes is the line length BatchSize is calibrated for
+	batchLineBytes = 64
+
+	// batchSmoothing is the weight of the newest line in the running average
+	batchSmoothing = 1.0 / 16
+
+	// maxBatchGrowth caps how many times BatchSize lines a batch of short lines may hold
+	maxBatchGrowth = 16
+)
+
+// batchSizer tracks the recent average line length and the batch size it implies
+type batchSizer struct {
+	targetBytes float64
+	maxLines    int
+	avgLine     float64
+}
+
+// newBatchSizer creates a sizer whose jobs carry as much text as batchSize
+// lines of batchLineBytes each
+func newBatchSizer(batchSize int) *batchSizer {
+	return &batchSizer{
+		targetBytes: float64(batchSize * batchLineBytes),
+		maxLines:    batchSize * maxBatchGrowth,
+		avgLine:     batchLineBytes,
+	}
+}
+
+// observe adds a line's length to the running average
+func (s *batchSizer) observe(lineLen int) {
+	s.avgLine += (float64(lineLen) - s.avgLine) * batchSmoothing
+}
+
+// size returns how many lines the current batch should hold
+func (s *batchSizer) size() int {
+	lines := int(s.targetBytes / max(s.avgLine, 1))
+	return min(max(lines, 1), s.maxLines)
+}
+
+// lineBatch is a run of lines handed to a parallel worker. The lines share
+// one backing array, so a batch costs two allocations however many lines it holds.
+type lineBatch struct {
+	seq  int
+	data []byte
+	ends []int
+}
+
+// newLineBatch creates an empty batch with room for about capBytes of text
+func newLineBatch(seq, capBytes int) *lineBatch {
+	return &lineBatch{seq: seq, data: make([]byte, 0, capBytes)}
+}
+
+// appendLine adds one line made of parts, which are copied
+func (b *lineBatch) appendLine(parts ...[]byte) {
+	for _, part := range parts {
+		b.data = append(b.data, part...)
+	}
+	b.ends = append(b.ends, len(b.data))
+}
+
+// lines returns the number of lines in the batch
+func (b *lineBatch) lines() int {
+	return len(b.ends)
+}
+
+// line returns the i-th line
+func (b *lineBatch) line(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = b.ends[i-1]
+	}
+	return b.data[start:b.ends[i]]
+}
@@ -0,0 +1,66 @@
+package lineprocessor
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+)
+
+func TestBatchSizerAdaptsToLineLength(t *testing.T) {
+	s := newBatchSizer(100)
+	if got := s.size(); got != 100 {
+		t.Fatalf("initial size = %d, want BatchSize", got)
+	}
+
+	for i := 0; i < 200; i++ {
+		s.observe(8)
+	}
+	short := s.size()
+	if short <= 100 || short > 100*maxBatchGrowth {
+		t.Errorf("size after short lines = %d, want more than 100 and at most %d", short, 100*maxBatchGrowth)
+	}
+
+	for i := 0; i < 200; i++ {
+		s.observe(64 * 1024)
+	}
+	if got := s.size(); got != 1 {
+		t.Errorf("size after 64KB lines = %d, want 1", got)
+	}
+}
+
+func TestParallelBatchesMatchSequential(t *testing.T) {
+	norm := normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OptimizedNormalizerType)
+	var sb strings.Builder
+	for i := 0; i < 3000; i++ {
+		if i%50 == 0 {
+			sb.WriteString(strings.Repeat("A Much Longer Line, ", 500))
+		} else {
+			sb.WriteString("Short line.")
+		}
+		sb.WriteString("\n")
+	}
+	text := sb.String()
+
+	run := func(parallel bool) (int, string) {
+		p := NewProcessor(logger.NewNopLogger(), norm, ProcessingConfig{ChunkSize: 4096, BatchSize: 10, UseParallel: parallel})
+		var out bytes.Buffer
+		count, _, err := p.ProcessLines(context.Background(), strings.NewReader(text), &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count, out.String()
+	}
+
+	wantCount, wantOut := run(false)
+	gotCount, gotOut := run(true)
+	if gotCount != wantCount {
+		t.Errorf("parallel counted %d, sequential %d", gotCount, wantCount)
+	}
+	if gotOut != wantOut {
+		t.Error("parallel output differs from sequential output")
+	}
+}
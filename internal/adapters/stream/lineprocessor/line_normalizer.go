@@ -0,0 +1,93 @@
+package lineprocessor
+
+import (
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// byteNormalizer is implemented by normalizers that work on byte slices, such
+// as the allocation-efficient normalizer
+type byteNormalizer interface {
+	NormalizeBytes(src, dst []byte) []byte
+}
+
+// countingNormalizer is implemented by normalizers that count their output
+// without producing it
+type countingNormalizer interface {
+	CountNormalized(src []byte) (runes int, words int)
+}
+
+// batchNormalizer is implemented by normalizers that count many lines in one call
+type batchNormalizer interface {
+	NormalizeBatch(lines [][]byte) []int
+}
+
+// lineNormalizer normalizes lines held as bytes into a buffer it reuses. When
+// the normalizer works on bytes, a line is never copied into a string, so a
+// multi-megabyte line costs one reusable buffer instead of several strings the
+// size of the line. It is not safe for concurrent use.
+type lineNormalizer struct {
+	normalizer ports.Normalizer
+	bytes      byteNormalizer
+	batch      batchNormalizer
+	counter    countingNormalizer
+	appender   ports.AppendNormalizer
+	buf        []byte
+}
+
+// newLineNormalizer wraps n, using its byte or append form when it has one
+func newLineNormalizer(n ports.Normalizer) *lineNormalizer {
+	ln := &lineNormalizer{normalizer: n}
+	ln.bytes, _ = n.(byteNormalizer)
+	ln.batch, _ = n.(batchNormalizer)
+	ln.counter, _ = n.(countingNormalizer)
+	ln.appender, _ = n.(ports.AppendNormalizer)
+	return ln
+}
+
+// normalize returns the normalized line and its length in runes. The result
+// is only valid until the next call.
+func (ln *lineNormalizer) normalize(line []byte) ([]byte, int) {
+	switch {
+	case ln.bytes != nil:
+		ln.buf = ln.bytes.NormalizeBytes(line, ln.buf)
+	case ln.appender != nil:
+		ln.buf = ln.appender.AppendNormalized(ln.buf[:0], bytesconv.String(line))
+	default:
+		ln.buf = append(ln.buf[:0], ln.normalizer.Normalize(bytesconv.String(line))...)
+	}
+	return ln.buf, utf8.RuneCount(ln.buf)
+}
+
+// count returns the normalized length in runes of each line, in a single
+// call when the normalizer takes batches
+func (ln *lineNormalizer) count(lines [][]byte) []int {
+	if ln.batch != nil {
+		return ln.batch.NormalizeBatch(lines)
+	}
+	counts := make([]int, len(lines))
+	for i, line := range lines {
+		counts[i] = ln.runes(line)
+	}
+	return counts
+}
+
+// runes returns the normalized length of line in runes, without producing
+// the normalized bytes when the normalizer can count directly
+func (ln *lineNormalizer) runes(line []byte) int {
+	if ln.counter != nil {
+		runes, _ := ln.counter.CountNormalized(line)
+		return runes
+	}
+	_, runes := ln.normalize(line)
+	return runes
+}
+
+// withSuffix appends suffix to the last normalized line, for writing the line
+// and its delimiter in one call
+func (ln *lineNormalizer) withSuffix(suffix []byte) []byte {
+	ln.buf = append(ln.buf, suffix...)
+	return ln.buf
+}
@@ -2,8 +2,12 @@
 package lineprocessor
 
 import (
+	"context"
 	"strings"
 	"sync"
+
+	"github.com/baditaflorin/go_length_similarity/internal/pool"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 )
 
 // LineRanges represents a collection of line boundaries without storing line content
@@ -131,73 +135,132 @@ func (sb *StringBuilder) Reset() {
 type ChunkBuffer struct {
 	// Buffer to store chunk bytes
 	Bytes []byte
+
+	// owner is who GetOwned took the buffer for; Put returns it to their shard
+	owner int
+	owned bool
 }
 
-// ChunkBufferPool implements a pool of chunk buffers
+// ChunkBufferPool implements a sharded pool of chunk buffers
 type ChunkBufferPool struct {
-	pool      sync.Pool
+	pool      *pool.Sharded[*ChunkBuffer]
 	chunkSize int
 }
 
 // NewChunkBufferPool creates a new chunk buffer pool
 func NewChunkBufferPool(chunkSize int) *ChunkBufferPool {
 	return &ChunkBufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				buf := make([]byte, chunkSize)
-				return &ChunkBuffer{Bytes: buf}
-			},
-		},
+		pool: pool.NewSharded(func() *ChunkBuffer {
+			buf := make([]byte, chunkSize)
+			return &ChunkBuffer{Bytes: buf}
+		}, pool.ChunkShardCapacity),
 		chunkSize: chunkSize,
 	}
 }
 
-// Get retrieves a chunk buffer from the pool
-func (cbp *ChunkBufferPool) Get() *ChunkBuffer {
-	buffer := cbp.pool.Get().(*ChunkBuffer)
+// Get retrieves a chunk buffer from the pool, recording a hit or miss in
+// ctx's probe
+func (cbp *ChunkBufferPool) Get(ctx context.Context) *ChunkBuffer {
+	buffer, pooled := cbp.pool.TryGet()
+	buffer = cbp.sized(ctx, buffer, pooled)
+	buffer.owned = false
+	return buffer
+}
+
+// GetOwned retrieves a chunk buffer from owner's shard. Put returns it there,
+// whichever worker finishes with it.
+func (cbp *ChunkBufferPool) GetOwned(ctx context.Context, owner int) *ChunkBuffer {
+	buffer, pooled := cbp.pool.TryGetHome(owner)
+	buffer = cbp.sized(ctx, buffer, pooled)
+	buffer.owner, buffer.owned = owner, true
+	return buffer
+}
 
+// sized resets a pooled buffer to the chunk size. A pooled buffer too small
+// for the chunk size is reallocated and counts as a miss.
+func (cbp *ChunkBufferPool) sized(ctx context.Context, buffer *ChunkBuffer, pooled bool) *ChunkBuffer {
 	// Ensure buffer has correct size (in case chunkSize changed)
 	if cap(buffer.Bytes) < cbp.chunkSize {
 		buffer.Bytes = make([]byte, cbp.chunkSize)
+		pooled = false
 	} else {
 		buffer.Bytes = buffer.Bytes[:cbp.chunkSize]
 	}
+	probe.FromContext(ctx).RecordPoolGet(pooled)
 
 	return buffer
 }
 
-// Put returns a chunk buffer to the pool
+// Put returns a chunk buffer to the pool, to its owner's shard if it has one
 func (cbp *ChunkBufferPool) Put(cb *ChunkBuffer) {
+	if cb.owned {
+		cbp.pool.PutHome(cb.owner, cb)
+		return
+	}
 	cbp.pool.Put(cb)
 }
 
+// maxPooledOutput is the largest output buffer kept for reuse
+const maxPooledOutput = 4 * DefaultChunkSize
+
+// outputBufferPool keeps parallel workers' normalized output buffers. The
+// collector returns each buffer to the shard of the worker that filled it,
+// so on a multi-socket machine a worker keeps writing into memory its own
+// core touched last.
+type outputBufferPool struct {
+	pool *pool.Sharded[[]byte]
+}
+
+// newOutputBufferPool creates an empty output buffer pool
+func newOutputBufferPool() *outputBufferPool {
+	return &outputBufferPool{pool: pool.NewSharded(func() []byte { return nil }, 0)}
+}
+
+// get returns an empty buffer for worker, recording a hit or miss in ctx's probe
+func (obp *outputBufferPool) get(ctx context.Context, worker int) []byte {
+	buf, pooled := obp.pool.TryGetHome(worker)
+	probe.FromContext(ctx).RecordPoolGet(pooled)
+	return buf[:0]
+}
+
+// put returns worker's buffer once its output is written
+func (obp *outputBufferPool) put(worker int, buf []byte) {
+	if cap(buf) == 0 || cap(buf) > maxPooledOutput {
+		return
+	}
+	obp.pool.PutHome(worker, buf)
+}
+
+// Drain drops all pooled chunk buffers
+func (cbp *ChunkBufferPool) Drain() {
+	cbp.pool.Drain()
+}
+
 // LineBuffer represents a reusable buffer for storing a line of text
 type LineBuffer struct {
 	// Buffer to store line bytes
 	Bytes []byte
 }
 
-// LineBufferPool implements a pool of line buffers for efficient reuse
+// LineBufferPool implements a sharded pool of line buffers for efficient reuse
 type LineBufferPool struct {
-	pool sync.Pool
+	pool *pool.Sharded[*LineBuffer]
 }
 
 // NewLineBufferPool creates a new line buffer pool
 func NewLineBufferPool() *LineBufferPool {
 	return &LineBufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				// Most lines are under 256 bytes
-				buf := make([]byte, 0, 256)
-				return &LineBuffer{Bytes: buf}
-			},
-		},
+		pool: pool.NewSharded(func() *LineBuffer {
+			// Most lines are under 256 bytes
+			buf := make([]byte, 0, 256)
+			return &LineBuffer{Bytes: buf}
+		}, 0),
 	}
 }
 
 // Get retrieves a line buffer from the pool
 func (lbp *LineBufferPool) Get() *LineBuffer {
-	return lbp.pool.Get().(*LineBuffer)
+	return lbp.pool.Get()
 }
 
 // Put returns a line buffer to the pool
@@ -206,3 +269,8 @@ func (lbp *LineBufferPool) Put(lb *LineBuffer) {
 	lb.Bytes = lb.Bytes[:0]
 	lbp.pool.Put(lb)
 }
+
+// Drain drops all pooled line buffers
+func (lbp *LineBufferPool) Drain() {
+	lbp.pool.Drain()
+}
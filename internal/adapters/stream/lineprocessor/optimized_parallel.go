@@ -2,16 +2,20 @@
 package lineprocessor
 
 import (
+	"bufio"
 	"context"
 	"io"
-	"runtime"
 	"sync"
 	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+	"github.com/baditaflorin/go_length_similarity/internal/sketch"
 )
 
 // Constants for parallel processing
 const (
-	// Default number of workers - use 0 to automatically use runtime.NumCPU()
+	// Default number of workers - use 0 to automatically use parallel.DefaultWorkers()
 	DefaultWorkers = 0
 
 	// Maximum job queue size
@@ -34,9 +38,15 @@ type LineJobResult struct {
 	CharCount int
 	ChunkID   int
 	Error     error
+	// Output is the batch's normalized text when a writer is attached
+	Output []byte
+	// Worker produced Output, and its buffer goes back to that worker's pool
+	Worker int
 }
 
-// processLinesParallel implements parallel line processing with reduced allocations
+// processLinesParallel implements parallel line processing with reduced allocations.
+// Workers buffer their normalized output; the collector writes it in chunk
+// order and stops everything on the first write error.
 func (p *OptimizedProcessor) processLinesParallel(
 	ctx context.Context,
 	reader io.Reader,
@@ -44,15 +54,18 @@ func (p *OptimizedProcessor) processLinesParallel(
 ) (int, int64, error) {
 	startTime := time.Now()
 
-	// Determine number of workers
-	workers := runtime.NumCPU()
-	if workers > 8 {
-		// Limit to 8 workers to avoid excessive overhead
-		workers = 8
-	}
+	// Cancelled on the first failure, so the reader and workers stop early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Determine number of workers within the shared parallelism budget.
+	// Limit to 8 workers to avoid excessive overhead.
+	workers, release := parallel.Acquire(min(parallel.DefaultWorkers(), 8))
+	defer release()
+	probe.FromContext(ctx).RecordWorkers(workers)
 
-	// Create channels for job distribution and result collection
-	jobs := make(chan LineJob, MaxJobQueueSize)
+	// Jobs go through per-worker deques; results come back on one channel
+	jobs := newJobScheduler(workers, MaxJobQueueSize)
 	results := make(chan LineJobResult, workers)
 
 	// Create a wait group to track worker completion
@@ -61,7 +74,7 @@ func (p *OptimizedProcessor) processLinesParallel(
 	// Start worker goroutines
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go p.lineWorker(ctx, i, jobs, results, &wg, writer)
+		go p.lineWorker(ctx, i, jobs, results, &wg, writer != nil)
 	}
 
 	// Create a goroutine to close the results channel when all workers are done
@@ -79,14 +92,21 @@ func (p *OptimizedProcessor) processLinesParallel(
 		var bytesProcessed int64
 		var partialLine []byte
 
+		// Always release the workers and report progress, however reading ends
+		defer func() { bytesProcessedChan <- bytesProcessed }()
+		defer jobs.close()
+
 		// Use a pool of chunk buffers for reading
 		chunkBuffers := make([]*ChunkBuffer, MaxJobQueueSize)
 		lineRangesPool := make([]*LineRanges, MaxJobQueueSize)
 
+		// The reader owns the chunk buffers it fills; workers return them to its pool shard
+		readerOwner := workers
 		for i := 0; i < MaxJobQueueSize; i++ {
-			chunkBuffers[i] = p.chunkBufferPool.Get()
+			chunkBuffers[i] = p.chunkBufferPool.GetOwned(ctx, readerOwner)
 			lineRangesPool[i] = p.lineRangePool.Get()
 		}
+		probe.FromContext(ctx).RecordBuffer(len(chunkBuffers[0].Bytes) * MaxJobQueueSize)
 
 		// Function to clean up resources
 		defer func() {
@@ -100,6 +120,7 @@ func (p *OptimizedProcessor) processLinesParallel(
 			}
 		}()
 
+		yield := parallel.NewYielder()
 		for {
 			// Check for context cancellation
 			select {
@@ -123,6 +144,7 @@ func (p *OptimizedProcessor) processLinesParallel(
 
 			if n > 0 {
 				bytesProcessed += int64(n)
+				yield.Add(n)
 				chunk := chunkBuffer.Bytes[:n]
 
 				// Process chunk to find line boundaries
@@ -140,10 +162,8 @@ func (p *OptimizedProcessor) processLinesParallel(
 					}
 
 					if newlineIdx >= 0 {
-						// Complete the partial line
-						completeLine := make([]byte, len(partialLine)+newlineIdx)
-						copy(completeLine, partialLine)
-						copy(completeLine[len(partialLine):], chunk[:newlineIdx])
+						// Complete the partial line; the job takes ownership of it
+						completeLine := append(partialLine, chunk[:newlineIdx+1]...)
 
 						// Send this as a special single-line job
 						singleLineJob := LineJob{
@@ -156,24 +176,20 @@ func (p *OptimizedProcessor) processLinesParallel(
 							IsFinal: false,
 						}
 
-						select {
-						case jobs <- singleLineJob:
-							// Job sent successfully
-							chunkID++
-						case <-ctx.Done():
+						if !jobs.push(ctx, singleLineJob) {
 							errChan <- ctx.Err()
 							return
 						}
+						chunkID++
 
 						// Process the rest of the chunk for line boundaries
 						lineCount = p.findLineRanges(chunk[newlineIdx+1:], lineRanges, newlineIdx+1)
 						partialLine = nil
 					} else {
-						// No newline found - the entire chunk is part of the partial line
-						newPartial := make([]byte, len(partialLine)+n)
-						copy(newPartial, partialLine)
-						copy(newPartial[len(partialLine):], chunk)
-						partialLine = newPartial
+						// No newline found - the entire chunk is part of the partial
+						// line. Appending grows it geometrically, so a long line is
+						// not recopied in full for every chunk.
+						partialLine = append(partialLine, chunk...)
 
 						// Skip sending a job for this chunk
 						continue
@@ -209,19 +225,16 @@ func (p *OptimizedProcessor) processLinesParallel(
 						IsFinal:     false,
 					}
 
-					select {
-					case jobs <- job:
-						// Job sent successfully
-
-						// Allocate a new buffer and line ranges for the next chunk
-						// since we've sent the current ones to a worker
-						chunkBuffers[bufferIndex] = p.chunkBufferPool.Get()
-						lineRangesPool[bufferIndex] = p.lineRangePool.Get()
-					case <-ctx.Done():
+					if !jobs.push(ctx, job) {
 						errChan <- ctx.Err()
 						return
 					}
 
+					// Allocate a new buffer and line ranges for the next chunk
+					// since we've sent the current ones to a worker
+					chunkBuffers[bufferIndex] = p.chunkBufferPool.GetOwned(ctx, readerOwner)
+					lineRangesPool[bufferIndex] = p.lineRangePool.Get()
+
 					chunkID++
 				}
 			}
@@ -240,37 +253,36 @@ func (p *OptimizedProcessor) processLinesParallel(
 						IsFinal: true,
 					}
 
-					select {
-					case jobs <- finalLineJob:
-						// Final job sent successfully
-					case <-ctx.Done():
+					if !jobs.push(ctx, finalLineJob) {
 						errChan <- ctx.Err()
 						return
 					}
 				}
 
-				// Close the jobs channel to signal no more jobs
-				close(jobs)
-
 				if err != io.EOF {
 					errChan <- err
 				} else {
 					errChan <- nil // Normal EOF
 				}
-
-				bytesProcessedChan <- bytesProcessed
 				return
 			}
 		}
 	}()
 
-	// Collect and process results
+	// Collect results in chunk order, merging output through one buffered writer
+	var out *bufio.Writer
+	if writer != nil {
+		out = bufio.NewWriter(writer)
+	}
 	charCount := 0
 	resultMap := make(map[int]LineJobResult)
 	nextChunkID := 0
+	var firstErr error
 
-	// Wait for all results and order them by chunk ID
 	for result := range results {
+		if firstErr != nil {
+			continue // Draining after a failure
+		}
 		resultMap[result.ChunkID] = result
 
 		// Process results in order
@@ -279,22 +291,39 @@ func (p *OptimizedProcessor) processLinesParallel(
 			if !exists {
 				break
 			}
+			delete(resultMap, nextChunkID)
+			nextChunkID++
 
 			// Check for errors
 			if result.Error != nil {
-				return charCount, <-bytesProcessedChan, result.Error
+				firstErr = result.Error
+				cancel()
+				break
 			}
 
-			// Add to character count
+			// Add to character count and write the batch's output
 			charCount += result.CharCount
-
-			// Remove processed result and move to next
-			delete(resultMap, nextChunkID)
-			nextChunkID++
+			if out != nil {
+				if _, err := out.Write(result.Output); err != nil {
+					p.logger.Error("Error writing normalized output", "error", err)
+					firstErr = err
+					cancel()
+					break
+				}
+				p.outputBuffers.put(result.Worker, result.Output)
+			}
 		}
 	}
 
-	// Get the final error (if any) and bytes processed
+	bytesProcessed := <-bytesProcessedChan
+	if firstErr == nil && out != nil {
+		firstErr = out.Flush()
+	}
+	if firstErr != nil {
+		return charCount, bytesProcessed, firstErr
+	}
+
+	// Get the final error (if any)
 	var err error
 	select {
 	case err = <-errChan:
@@ -303,8 +332,6 @@ func (p *OptimizedProcessor) processLinesParallel(
 		// No error
 	}
 
-	bytesProcessed := <-bytesProcessedChan
-
 	// Log completion
 	p.logger.Debug("Parallel line processing completed",
 		"char_count", charCount,
@@ -320,19 +347,27 @@ func (p *OptimizedProcessor) processLinesParallel(
 func (p *OptimizedProcessor) lineWorker(
 	ctx context.Context,
 	id int,
-	jobs <-chan LineJob,
+	jobs *jobScheduler,
 	results chan<- LineJobResult,
 	wg *sync.WaitGroup,
-	writer io.Writer,
+	buffered bool,
 ) {
 	defer wg.Done()
 
-	// Get a string builder for normalization
-	sb := p.stringBuilderPool.Get()
-	defer p.stringBuilderPool.Put(sb)
+	// Per-worker line normalizer, so lines are normalized as bytes
+	ln := newLineNormalizer(p.normalizer)
+	var batch [][]byte
+
+	// Line length distribution, when the caller asked for one
+	lengths := sketch.FromContext(ctx)
+
+	// Process jobs until the scheduler is closed and drained
+	for {
+		job, ok := jobs.take(id)
+		if !ok {
+			return
+		}
 
-	// Process jobs until the channel is closed
-	for job := range jobs {
 		// Check context for cancellation
 		select {
 		case <-ctx.Done():
@@ -345,37 +380,50 @@ func (p *OptimizedProcessor) lineWorker(
 			// Continue processing
 		}
 
-		// Process the lines in this job
+		// Process the lines in this job, into an output buffer from this
+		// worker's pool that the collector hands back once written
 		charCount := 0
+		var out []byte
+		if buffered {
+			out = p.outputBuffers.get(ctx, id)
+		}
 
 		// Get chunk data and line ranges
 		chunk := job.ChunkBuffer.Bytes
 		lineRanges := job.Ranges
 
 		// Process each line in the batch
+		batch = batch[:0]
 		for i := 0; i < lineRanges.Count; i++ {
 			lr := lineRanges.Get(i)
 
-			// Get the line text
-			line := string(chunk[lr.Start:lr.End])
+			// Lines that are only counted are normalized together below
+			if !buffered {
+				batch = append(batch, chunk[lr.Start:lr.End])
+				continue
+			}
 
-			// Normalize the line
-			normalized := p.normalizer.Normalize(line)
-			charCount += len([]rune(normalized))
+			// Normalize the line in place and buffer it for the writer
+			normalized, lineLen := ln.normalize(chunk[lr.Start:lr.End])
+			charCount += lineLen
+			lengths.Add(float64(lineLen))
+			out = append(out, normalized...)
+			out = append(out, p.output.delimiter...)
+		}
+		charCount += countBatch(ln, batch, lengths)
 
-			// Write normalized output if writer is provided
-			if writer != nil {
-				// For parallel writer support, we would need synchronization
-				// This is simplified and would need additional sync mechanisms
-				writer.Write([]byte(normalized + "\n"))
-			}
+		// The chunk is done with; pooled ones go back to the reader's shard
+		if job.ChunkBuffer.owned {
+			p.chunkBufferPool.Put(job.ChunkBuffer)
+			p.lineRangePool.Put(job.Ranges)
 		}
 
 		// Send the result
 		results <- LineJobResult{
 			CharCount: charCount,
 			ChunkID:   job.ChunkID,
-			Error:     nil,
+			Output:    out,
+			Worker:    id,
 		}
 	}
 }
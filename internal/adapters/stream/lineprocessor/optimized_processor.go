@@ -2,11 +2,15 @@
 package lineprocessor
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"time"
 
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+	"github.com/baditaflorin/go_length_similarity/internal/sketch"
 )
 
 // LineRange represents a line's location in a buffer without copying the line
@@ -23,9 +27,6 @@ const (
 	// DefaultBatchSize defines how many lines to process in one batch
 	DefaultBatchSize = 100
 
-	// ContextCheckFrequency defines how often to check for context cancellation
-	ContextCheckFrequency = 500 // lines
-
 	// Common newline characters
 	CR = '\r'
 	LF = '\n'
@@ -41,18 +42,27 @@ type OptimizedProcessor struct {
 	chunkBufferPool   *ChunkBufferPool
 	lineRangePool     *LineRangePool
 	stringBuilderPool *StringBuilderPool
+	outputBuffers     *outputBufferPool
 
 	// Configuration
 	chunkSize   int
 	batchSize   int
 	useParallel bool
+	output      separators
 }
 
 // ProcessingConfig defines configuration for line processing
 type ProcessingConfig struct {
-	ChunkSize   int
+	ChunkSize int
+	// BatchSize is how many lines of typical length a parallel job carries;
+	// jobs of longer or shorter lines hold proportionally fewer or more
 	BatchSize   int
 	UseParallel bool
+	// OutputDelimiter is written after each normalized line ("" = DefaultOutputDelimiter)
+	OutputDelimiter string
+	// PreserveSeparators writes each line's original terminator (CRLF, LF, CR or
+	// none for an unterminated last line) instead of OutputDelimiter
+	PreserveSeparators bool
 }
 
 // NewOptimizedProcessor creates a new optimized line processor
@@ -76,19 +86,31 @@ func NewOptimizedProcessor(
 		chunkBufferPool:   NewChunkBufferPool(config.ChunkSize),
 		lineRangePool:     NewLineRangePool(config.BatchSize * 2), // Double capacity to avoid reallocations
 		stringBuilderPool: NewStringBuilderPool(),
+		outputBuffers:     newOutputBufferPool(),
 		chunkSize:         config.ChunkSize,
 		batchSize:         config.BatchSize,
 		useParallel:       config.UseParallel,
+		output:            newSeparators(config),
 	}
 }
 
+// Release drops the processor's pooled buffers. The processor stays usable
+// but allocates fresh buffers until the pools refill.
+func (p *OptimizedProcessor) Release() {
+	p.lineBufferPool.Drain()
+	p.chunkBufferPool.Drain()
+	p.outputBuffers.pool.Drain()
+}
+
 // ProcessLines processes a reader line by line and returns the character count
 func (p *OptimizedProcessor) ProcessLines(
 	ctx context.Context,
 	reader io.Reader,
 	writer io.Writer,
 ) (int, int64, error) {
-	if p.useParallel {
+	// Parallel workers write in completion order, so preserving each line's
+	// terminator needs the sequential path
+	if p.useParallel && !(writer != nil && p.output.preserve) {
 		return p.processLinesParallel(ctx, reader, writer)
 	}
 	return p.processLinesOptimized(ctx, reader, writer)
@@ -103,44 +125,43 @@ func (p *OptimizedProcessor) processLinesOptimized(
 	startTime := time.Now()
 
 	// Get a chunk buffer from the pool
-	chunkBuffer := p.chunkBufferPool.Get()
+	chunkBuffer := p.chunkBufferPool.Get(ctx)
 	defer p.chunkBufferPool.Put(chunkBuffer)
+	probe.FromContext(ctx).RecordBuffer(len(chunkBuffer.Bytes))
 
 	// Get a line ranges slice from the pool
 	lineRanges := p.lineRangePool.Get()
 	defer p.lineRangePool.Put(lineRanges)
 
-	// Get a string builder for normalization
-	sb := p.stringBuilderPool.Get()
-	defer p.stringBuilderPool.Put(sb)
+	// Normalize lines as bytes into a buffer reused across lines
+	ln := newLineNormalizer(p.normalizer)
+	var batch [][]byte
 
-	// Count characters (runes) and bytes
+	// Count characters (runes) and bytes, recording line lengths if requested
+	lengths := sketch.FromContext(ctx)
 	charCount := 0
 	var bytesProcessed int64 = 0
 
-	// Track the previous chunk's unused data
+	// Bytes of a line that started in an earlier chunk. The buffer is reused
+	// and grows by appending, so a long line is copied once per chunk rather
+	// than once per chunk for everything read so far.
 	var partialLine []byte
-	contextCheckCounter := 0
+	yield := parallel.NewYielder()
 
 	// Loop until we're done or encounter an error
 	for {
-		// Periodically check context for cancellation
-		contextCheckCounter++
-		if contextCheckCounter >= ContextCheckFrequency {
-			select {
-			case <-ctx.Done():
-				p.logger.Warn("Processing cancelled by context", "error", ctx.Err())
-				return charCount, bytesProcessed, ctx.Err()
-			default:
-				// Continue processing
-			}
-			contextCheckCounter = 0
+		// Check the context before every read so the caller's remaining deadline
+		// budget is honored within one chunk rather than thousands of them
+		if err := ctx.Err(); err != nil {
+			p.logger.Warn("Processing cancelled by context", "error", err)
+			return charCount, bytesProcessed, err
 		}
 
 		// Read a chunk
 		n, err := reader.Read(chunkBuffer.Bytes)
 		if n > 0 {
 			bytesProcessed += int64(n)
+			yield.Add(n)
 			chunk := chunkBuffer.Bytes[:n]
 
 			// Process the chunk to find line boundaries (without copying each line)
@@ -149,46 +170,30 @@ func (p *OptimizedProcessor) processLinesOptimized(
 
 			// If we have a partial line from the previous chunk, handle it
 			if len(partialLine) > 0 {
-				// Find the first newline in the current chunk
-				newlineIndex := -1
-				for i := 0; i < n; i++ {
-					if chunk[i] == LF {
-						newlineIndex = i
-						break
-					}
-				}
-
-				if newlineIndex >= 0 {
+				if newlineIndex := bytes.IndexByte(chunk, LF); newlineIndex >= 0 {
 					// We found a newline - complete the partial line
-					completeLine := make([]byte, len(partialLine)+newlineIndex)
-					copy(completeLine, partialLine)
-					copy(completeLine[len(partialLine):], chunk[:newlineIndex])
-
-					// Process this complete line
-					line := string(completeLine)
-					normalized := p.normalizer.Normalize(line)
-					charCount += len([]rune(normalized))
-
-					if writer != nil {
-						writer.Write([]byte(normalized + "\n"))
+					partialLine = append(partialLine, chunk[:newlineIndex+1]...)
+					lineLen, werr := p.countLine(ln, partialLine, writer, lengths)
+					charCount += lineLen
+					if werr != nil {
+						return charCount, bytesProcessed, werr
 					}
 
 					// Start processing the rest of the chunk
 					lineCount = p.findLineRanges(chunk[newlineIndex+1:], lineRanges, newlineIndex+1)
-					partialLine = nil
+					partialLine = partialLine[:0]
 				} else {
 					// No newline - the entire chunk is part of the partial line
-					newPartialLine := make([]byte, len(partialLine)+n)
-					copy(newPartialLine, partialLine)
-					copy(newPartialLine[len(partialLine):], chunk)
-					partialLine = newPartialLine
+					partialLine = append(partialLine, chunk...)
 				}
 			} else {
 				// No partial line, process the whole chunk
 				lineCount = p.findLineRanges(chunk, lineRanges, 0)
 			}
 
-			// Process the lines in the current chunk
+			// Process the lines in the current chunk. Without a writer the
+			// complete lines are only counted, so they are normalized as one batch.
+			batch = batch[:0]
 			for i := 0; i < lineCount; i++ {
 				lr := lineRanges.Get(i)
 
@@ -198,20 +203,22 @@ func (p *OptimizedProcessor) processLinesOptimized(
 
 				if isPartialLine && err == nil {
 					// This is a partial line - save it for the next chunk
-					partialLine = make([]byte, lr.End-lr.Start)
-					copy(partialLine, chunk[lr.Start:lr.End])
+					partialLine = append(partialLine[:0], chunk[lr.Start:lr.End]...)
 					continue
 				}
 
-				// Process a complete line
-				line := string(chunk[lr.Start:lr.End])
-				normalized := p.normalizer.Normalize(line)
-				charCount += len([]rune(normalized))
-
-				if writer != nil {
-					writer.Write([]byte(normalized + "\n"))
+				// Process a complete line in place
+				if writer == nil {
+					batch = append(batch, chunk[lr.Start:lr.End])
+					continue
+				}
+				lineLen, werr := p.countLine(ln, chunk[lr.Start:lr.End], writer, lengths)
+				charCount += lineLen
+				if werr != nil {
+					return charCount, bytesProcessed, werr
 				}
 			}
+			charCount += countBatch(ln, batch, lengths)
 		}
 
 		// Handle errors or EOF
@@ -223,12 +230,10 @@ func (p *OptimizedProcessor) processLinesOptimized(
 
 			// Handle final line if there's a partial line
 			if len(partialLine) > 0 {
-				line := string(partialLine)
-				normalized := p.normalizer.Normalize(line)
-				charCount += len([]rune(normalized))
-
-				if writer != nil {
-					writer.Write([]byte(normalized + "\n"))
+				lineLen, werr := p.countLine(ln, partialLine, writer, lengths)
+				charCount += lineLen
+				if werr != nil {
+					return charCount, bytesProcessed, werr
 				}
 			}
 
@@ -246,6 +251,38 @@ func (p *OptimizedProcessor) processLinesOptimized(
 	return charCount, bytesProcessed, nil
 }
 
+// countLine normalizes one raw line, records its length in runes and writes
+// the normalized line to writer, if set, followed by its delimiter
+func (p *OptimizedProcessor) countLine(ln *lineNormalizer, line []byte, writer io.Writer, lengths *sketch.TDigest) (int, error) {
+	if writer == nil {
+		lineLen := ln.runes(line)
+		lengths.Add(float64(lineLen))
+		return lineLen, nil
+	}
+
+	_, lineLen := ln.normalize(line)
+	lengths.Add(float64(lineLen))
+	if _, err := writer.Write(ln.withSuffix(p.output.after(terminator(line)))); err != nil {
+		p.logger.Error("Error writing normalized output", "error", err)
+		return lineLen, err
+	}
+	return lineLen, nil
+}
+
+// countBatch counts a batch of raw lines, recording each length, and returns
+// their total length in runes
+func countBatch(ln *lineNormalizer, lines [][]byte, lengths *sketch.TDigest) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	total := 0
+	for _, lineLen := range ln.count(lines) {
+		total += lineLen
+		lengths.Add(float64(lineLen))
+	}
+	return total
+}
+
 // findLineRanges locates line boundaries in a byte slice without copying each line
 // Returns the number of lines found
 func (p *OptimizedProcessor) findLineRanges(data []byte, ranges *LineRanges, offset int) int {
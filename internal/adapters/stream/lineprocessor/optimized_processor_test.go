@@ -0,0 +1,86 @@
+package lineprocessor
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+)
+
+func TestLongLineCountMatchesNormalizer(t *testing.T) {
+	norm := normalizer.NewAllocationEfficientNormalizer()
+	text := "Héllo, World\r\n" + strings.Repeat("Ünïcode and ASCII, MIXED; ", 20000) + "\nTail"
+	want := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		want += utf8.RuneCountInString(norm.Normalize(line))
+	}
+
+	for _, parallel := range []bool{false, true} {
+		p := NewOptimizedProcessor(logger.NewNopLogger(), norm, ProcessingConfig{ChunkSize: 4096, UseParallel: parallel})
+		var out bytes.Buffer
+		got, _, err := p.ProcessLines(context.Background(), strings.NewReader(text), &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("parallel=%v: counted %d runes, want %d", parallel, got, want)
+		}
+		// Without a writer, lines are counted in batches
+		if got, _, err := p.ProcessLines(context.Background(), strings.NewReader(text), nil); err != nil || got != want {
+			t.Errorf("parallel=%v, batched: counted %d runes (%v), want %d", parallel, got, err, want)
+		}
+		if first := norm.Normalize("Héllo, World\r\n") + "\n"; !strings.HasPrefix(out.String(), first) {
+			t.Errorf("parallel=%v: output starts %q, want %q", parallel, out.String()[:len(first)], first)
+		}
+	}
+}
+
+func TestLongLineAllocatesLinearly(t *testing.T) {
+	const size = 4 << 20
+	text := strings.Repeat("abcdefgh ", size/9)
+	p := NewOptimizedProcessor(logger.NewNopLogger(), normalizer.NewAllocationEfficientNormalizer(), ProcessingConfig{})
+	// Warm the pools so only per-line costs are measured
+	if _, _, err := p.ProcessLines(context.Background(), strings.NewReader("warm up\n"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, _, err := p.ProcessLines(context.Background(), strings.NewReader(text), nil); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	// Accumulating the line and normalizing it each cost about its size;
+	// recopying it per chunk or converting it to strings and runes costs far more
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 6*size {
+		t.Errorf("a %d byte line allocated %d bytes", size, allocated)
+	}
+}
+
+func TestParallelBuffersReturnToTheirPools(t *testing.T) {
+	norm := normalizer.NewAllocationEfficientNormalizer()
+	text := strings.Repeat("Some line of text, repeated.\n", 20000)
+	p := NewOptimizedProcessor(logger.NewNopLogger(), norm, ProcessingConfig{ChunkSize: 4096, UseParallel: true})
+
+	var out bytes.Buffer
+	if _, _, err := p.ProcessLines(context.Background(), strings.NewReader(text), &out); err != nil {
+		t.Fatal(err)
+	}
+	if p.outputBuffers.pool.Len() == 0 {
+		t.Error("no output buffers came back from the collector")
+	}
+	if p.chunkBufferPool.pool.Len() == 0 {
+		t.Error("no chunk buffers came back from the workers")
+	}
+
+	p.Release()
+	if n := p.outputBuffers.pool.Len() + p.chunkBufferPool.pool.Len(); n != 0 {
+		t.Errorf("%d buffers left after Release", n)
+	}
+}
@@ -0,0 +1,39 @@
+package lineprocessor
+
+// DefaultOutputDelimiter is written after each normalized line on the writer path
+const DefaultOutputDelimiter = "\n"
+
+// separators decides what follows each normalized line on the writer path
+type separators struct {
+	delimiter []byte
+	preserve  bool
+}
+
+func newSeparators(config ProcessingConfig) separators {
+	delimiter := config.OutputDelimiter
+	if delimiter == "" {
+		delimiter = DefaultOutputDelimiter
+	}
+	return separators{delimiter: []byte(delimiter), preserve: config.PreserveSeparators}
+}
+
+// after returns the bytes to write after a line whose original terminator is
+// terminator (empty for a final line without one)
+func (s separators) after(terminator []byte) []byte {
+	if s.preserve {
+		return terminator
+	}
+	return s.delimiter
+}
+
+// terminator returns the line break that ends raw: CRLF, LF, CR or nothing
+func terminator(raw []byte) []byte {
+	n := len(raw)
+	switch {
+	case n >= 2 && raw[n-2] == CR && raw[n-1] == LF:
+		return raw[n-2:]
+	case n >= 1 && (raw[n-1] == LF || raw[n-1] == CR):
+		return raw[n-1:]
+	}
+	return nil
+}
@@ -1,9 +1,15 @@
 package lineprocessor
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/segments"
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+	"github.com/baditaflorin/go_length_similarity/internal/sketch"
 	"io"
 	"sync"
 	"time"
@@ -47,11 +53,13 @@ type Processor struct {
 	lineBufferPool  *LineBufferPool
 	chunkBufferPool *ChunkBufferPool
 	batchBufferPool *LineBatchBufferPool
+	outputBuffers   *outputBufferPool
 
 	// Configuration
 	chunkSize   int
 	batchSize   int
 	useParallel bool
+	output      separators
 }
 
 // // ProcessingConfig defines configuration for line processing
@@ -82,25 +90,48 @@ func NewProcessor(
 		lineBufferPool:  NewLineBufferPool(),
 		chunkBufferPool: NewChunkBufferPool(config.ChunkSize),
 		batchBufferPool: NewLineBatchBufferPool(config.BatchSize),
+		outputBuffers:   newOutputBufferPool(),
 		chunkSize:       config.ChunkSize,
 		batchSize:       config.BatchSize,
 		useParallel:     config.UseParallel,
+		output:          newSeparators(config),
 	}
 }
 
+// Release drops the processor's pooled buffers. The processor stays usable
+// but allocates fresh buffers until the pools refill.
+func (p *Processor) Release() {
+	p.lineBufferPool.Drain()
+	p.chunkBufferPool.Drain()
+	p.outputBuffers.pool.Drain()
+}
+
 // ProcessLines processes a reader line by line and returns the character count
 func (p *Processor) ProcessLines(
 	ctx context.Context,
 	reader io.Reader,
 	writer io.Writer,
 ) (int, int64, error) {
-	if p.useParallel {
+	// Parallel workers write in completion order, so preserving each line's
+	// terminator or recording each line's counts needs the sequential path
+	if p.useParallel && !(writer != nil && p.output.preserve) && segments.FromContext(ctx) == nil {
 		return p.processLinesParallel(ctx, reader, writer)
 	}
 	return p.processLinesOptimized(ctx, reader, writer)
 }
 
-// processLinesParallel implements a parallel line processing algorithm
+// lineResult is a worker's count for one batch and, when writing, its normalized output
+type lineResult struct {
+	seq    int
+	count  int
+	output []byte
+	// worker produced output, and its buffer goes back to that worker's pool
+	worker int
+}
+
+// processLinesParallel implements a parallel line processing algorithm.
+// Workers normalize into their own buffers; the collector merges the output
+// in input order and stops everything on the first write error.
 func (p *Processor) processLinesParallel(
 	ctx context.Context,
 	reader io.Reader,
@@ -108,76 +139,114 @@ func (p *Processor) processLinesParallel(
 ) (int, int64, error) {
 	startTime := time.Now()
 
-	// Define the number of workers for parallel processing
-	numWorkers := 4 // This could be made configurable or based on runtime.NumCPU()
+	// Cancelled on a write error, so the reader and workers stop early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Define the number of workers for parallel processing within the shared budget
+	numWorkers, release := parallel.Acquire(min(parallel.DefaultWorkers(), 4))
+	defer release()
+	probe.FromContext(ctx).RecordWorkers(numWorkers)
 
 	// Create channels for communication between workers
-	jobs := make(chan []byte, p.batchSize)
-	results := make(chan int, numWorkers)
+	jobs := make(chan *lineBatch, numWorkers*2)
+	results := make(chan lineResult, numWorkers)
 	errChan := make(chan error, 1)
-	doneChan := make(chan struct{})
 
-	// Variable to track total bytes processed
+	// Variable to track total bytes processed; read after the reader finishes
 	var bytesProcessed int64
+	readerDone := make(chan struct{})
 
 	// Start workers
+	lengths := sketch.FromContext(ctx)
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go func() {
+		go func(worker int) {
 			defer wg.Done()
 
-			for line := range jobs {
-				// Check for context cancellation
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					// Process the line
-					normalized := p.normalizer.Normalize(string(line))
-					charCount := len([]rune(normalized))
+			for batch := range jobs {
+				// Skip remaining batches once cancelled
+				if ctx.Err() != nil {
+					continue
+				}
 
-					// Send result back
-					results <- charCount
+				// Process the batch's lines, into an output buffer from this
+				// worker's pool that the collector hands back once written
+				result := lineResult{seq: batch.seq, worker: worker}
+				if writer != nil {
+					result.output = p.outputBuffers.get(ctx, worker)
+				}
+				for i := 0; i < batch.lines(); i++ {
+					normalized := p.normalizer.Normalize(bytesconv.String(batch.line(i)))
+					charCount := len([]rune(normalized))
+					lengths.Add(float64(charCount))
+					result.count += charCount
 
-					// Write normalized output if writer is provided
+					// Buffer normalized output for the collector to write in order
 					if writer != nil {
-						writer.Write([]byte(normalized + "\n"))
+						result.output = append(result.output, normalized...)
+						result.output = append(result.output, p.output.delimiter...)
 					}
 				}
+				results <- result
 			}
-		}()
+		}(i)
 	}
 
 	// Close the results channel when all workers are done
 	go func() {
 		wg.Wait()
 		close(results)
-		close(doneChan)
 	}()
 
 	// Start a goroutine to read lines and send them to workers
 	go func() {
-		chunkBuffer := p.chunkBufferPool.Get()
+		defer close(readerDone)
+		defer close(jobs)
+
+		chunkBuffer := p.chunkBufferPool.Get(ctx)
 		defer p.chunkBufferPool.Put(chunkBuffer)
+		probe.FromContext(ctx).RecordBuffer(len(chunkBuffer.Bytes))
 
+		// Lines are gathered into batches sized to the recent line length
 		var partialLine []byte
-
-		for {
-			// Check for context cancellation
+		sizer := newBatchSizer(p.batchSize)
+		seq := 0
+		batch := newLineBatch(seq, int(sizer.targetBytes))
+		flush := func() bool {
+			if batch.lines() == 0 {
+				return true
+			}
 			select {
+			case jobs <- batch:
+				seq++
+				batch = newLineBatch(seq, int(sizer.targetBytes))
+				return true
 			case <-ctx.Done():
-				close(jobs)
 				errChan <- ctx.Err()
+				return false
+			}
+		}
+		add := func(parts ...[]byte) bool {
+			batch.appendLine(parts...)
+			sizer.observe(len(batch.line(batch.lines() - 1)))
+			return batch.lines() < sizer.size() || flush()
+		}
+		yield := parallel.NewYielder()
+
+		for {
+			// Check for context cancellation
+			if err := ctx.Err(); err != nil {
+				errChan <- err
 				return
-			default:
-				// Continue reading
 			}
 
 			// Read a chunk
 			n, err := reader.Read(chunkBuffer.Bytes)
 			if n > 0 {
 				bytesProcessed += int64(n)
+				yield.Add(n)
 				chunk := chunkBuffer.Bytes[:n]
 
 				// Process the chunk to find lines
@@ -188,18 +257,8 @@ func (p *Processor) processLinesParallel(
 					// Find the first newline in this chunk
 					newlineIdx := bytes.IndexByte(chunk, LF)
 					if newlineIdx >= 0 {
-						// Complete the partial line
-						completeLine := make([]byte, len(partialLine)+newlineIdx)
-						copy(completeLine, partialLine)
-						copy(completeLine[len(partialLine):], chunk[:newlineIdx])
-
-						// Send this line to be processed
-						select {
-						case jobs <- completeLine:
-							// Continue
-						case <-ctx.Done():
-							close(jobs)
-							errChan <- ctx.Err()
+						// Complete the partial line and batch it
+						if !add(partialLine, chunk[:newlineIdx]) {
 							return
 						}
 
@@ -208,10 +267,7 @@ func (p *Processor) processLinesParallel(
 						partialLine = nil
 					} else {
 						// No newline found - the entire chunk is part of the partial line
-						newPartial := make([]byte, len(partialLine)+n)
-						copy(newPartial, partialLine)
-						copy(newPartial[len(partialLine):], chunk)
-						partialLine = newPartial
+						partialLine = append(partialLine, chunk...)
 						continue
 					}
 				} else {
@@ -219,84 +275,89 @@ func (p *Processor) processLinesParallel(
 					lines = bytes.Split(chunk, []byte{LF})
 				}
 
-				// Process complete lines
-				if len(lines) > 0 {
-					// Check if the last line is complete (ends with newline)
-					lastLine := lines[len(lines)-1]
-					if n > 0 && chunk[n-1] != LF {
-						// Last line is incomplete, save it for the next chunk
-						partialLine = lastLine
-						lines = lines[:len(lines)-1]
-					}
+				// Check if the last line is complete (ends with newline)
+				if len(lines) > 0 && chunk[n-1] != LF {
+					// Last line is incomplete, save it for the next chunk
+					partialLine = append([]byte(nil), lines[len(lines)-1]...)
+					lines = lines[:len(lines)-1]
+				}
 
-					// Send complete lines to workers
-					for _, line := range lines {
-						if len(line) > 0 {
-							select {
-							case jobs <- line:
-								// Continue
-							case <-ctx.Done():
-								close(jobs)
-								errChan <- ctx.Err()
-								return
-							}
-						}
+				// Batch complete lines; the batch copies them out of the reused chunk buffer
+				for _, line := range lines {
+					if len(line) > 0 && !add(line) {
+						return
 					}
 				}
 			}
 
 			// Handle errors or EOF
 			if err != nil {
-				// Process any remaining partial line
-				if len(partialLine) > 0 {
-					select {
-					case jobs <- partialLine:
-						// Sent successfully
-					case <-ctx.Done():
-						close(jobs)
-						errChan <- ctx.Err()
-						return
-					}
+				// Process any remaining partial line and the last batch
+				if len(partialLine) > 0 && !add(partialLine) {
+					return
+				}
+				if !flush() {
+					return
 				}
-
-				// Close the jobs channel to signal no more jobs
-				close(jobs)
 
 				if err != io.EOF {
 					errChan <- err
-				} else {
-					errChan <- nil // Normal EOF
 				}
 				return
 			}
 		}
 	}()
 
-	// Collect results from workers
+	// Collect results, writing output in input order through one buffered writer
+	var out *bufio.Writer
+	if writer != nil {
+		out = bufio.NewWriter(writer)
+	}
+	pending := make(map[int]lineResult)
+	nextSeq := 0
 	charCount := 0
-	var processingErr error
+	var writeErr error
+
+	for result := range results {
+		if writeErr != nil {
+			continue // Draining after a failed write
+		}
+		charCount += result.count
+		if out == nil {
+			continue
+		}
 
-	// Process results
-	resultsDone := false
-	for !resultsDone {
-		select {
-		case count, ok := <-results:
+		pending[result.seq] = result
+		for {
+			next, ok := pending[nextSeq]
 			if !ok {
-				resultsDone = true
-			} else {
-				charCount += count
+				break
 			}
-		case err := <-errChan:
-			if err != nil && err != io.EOF {
-				processingErr = err
+			delete(pending, nextSeq)
+			nextSeq++
+			if _, err := out.Write(next.output); err != nil {
+				writeErr = err
+				cancel()
+				break
 			}
-		case <-ctx.Done():
-			return charCount, bytesProcessed, ctx.Err()
+			p.outputBuffers.put(next.worker, next.output)
 		}
 	}
+	<-readerDone
+
+	if writeErr == nil && out != nil {
+		writeErr = out.Flush()
+	}
+	if writeErr != nil {
+		p.logger.Error("Error writing normalized output", "error", writeErr)
+		return charCount, bytesProcessed, writeErr
+	}
 
-	// Wait for processing to complete
-	<-doneChan
+	var processingErr error
+	select {
+	case processingErr = <-errChan:
+	default:
+	}
 
 	// Log completion
 	p.logger.Debug("Parallel line processing completed",
@@ -317,44 +378,43 @@ func (p *Processor) processLinesOptimized(
 	startTime := time.Now()
 
 	// Get buffers from pools
-	chunkBuffer := p.chunkBufferPool.Get()
+	chunkBuffer := p.chunkBufferPool.Get(ctx)
 	defer p.chunkBufferPool.Put(chunkBuffer)
+	probe.FromContext(ctx).RecordBuffer(len(chunkBuffer.Bytes))
 
 	lineBuffer := p.lineBufferPool.Get()
 	defer p.lineBufferPool.Put(lineBuffer)
 
+	// Line length distribution and per-line counts, when the caller asked for them
+	lengths := sketch.FromContext(ctx)
+	counts := segments.FromContext(ctx)
+	// Set when the last chunk ended in CR, so an LF opening the next one
+	// completes that CRLF rather than a line of its own
+	splitCRLF := false
+	yield := parallel.NewYielder()
+
 	// Count characters (runes) and bytes
 	charCount := 0
 	var bytesProcessed int64 = 0
 
-	// Line tracking state
-	inLine := false
-	carryoverCR := false
-	contextCheckCounter := 0
-
 	// Loop until we're done or encounter an error
 	for {
-		// Periodically check context for cancellation
-		contextCheckCounter++
-		if contextCheckCounter >= ContextCheckFrequency {
-			select {
-			case <-ctx.Done():
-				p.logger.Warn("Processing cancelled by context", "error", ctx.Err())
-				return charCount, bytesProcessed, ctx.Err()
-			default:
-				// Continue processing
-			}
-			contextCheckCounter = 0
+		// Check the context before every read so the caller's remaining deadline
+		// budget is honored within one chunk rather than thousands of them
+		if err := ctx.Err(); err != nil {
+			p.logger.Warn("Processing cancelled by context", "error", err)
+			return charCount, bytesProcessed, err
 		}
 
 		// Read a chunk
 		n, err := reader.Read(chunkBuffer.Bytes)
 		if n > 0 {
 			bytesProcessed += int64(n)
+			yield.Add(n)
 			chunk := chunkBuffer.Bytes[:n]
 
 			// Process the chunk line by line
-			var lineStart int = 0
+			lineStart := 0
 
 			for i := 0; i < n; i++ {
 				b := chunk[i]
@@ -363,51 +423,41 @@ func (p *Processor) processLinesOptimized(
 				// LF: \n (Unix)
 				// CRLF: \r\n (Windows)
 				// CR: \r (Old Mac)
-				if b == LF || b == CR {
-					// We found a line break
-
-					// For Windows-style CRLF, we need to handle the sequence as one line break
-					// If we see a CR, we set a flag and continue to the next byte
-					if b == CR {
-						// Extract the line so far (excluding CR)
-						line := chunk[lineStart:i]
-
-						// Check for CRLF sequence
-						if i+1 < n && chunk[i+1] == LF {
-							// This is a CRLF sequence, skip the CR and let the LF handling process it
-							carryoverCR = true
-							continue
-						}
+				if b != LF && b != CR {
+					continue
+				}
 
-						// Process the line that ends with a CR
-						p.processLine(line, writer, &charCount)
-						lineStart = i + 1
-						carryoverCR = false
-					} else if b == LF {
-						// Handle LF (Unix style) or second part of CRLF (Windows style)
-						var line []byte
-
-						if carryoverCR {
-							// This LF is part of a CRLF sequence, the CR was already handled
-							carryoverCR = false
-							line = chunk[lineStart:i]
-						} else {
-							// This is a standalone LF
-							line = chunk[lineStart:i]
-						}
+				// A CRLF sequence is one line break. A CRLF split across chunks
+				// is seen as CR then an empty LF-terminated line, which adds no
+				// characters.
+				lineEnd := i
+				if b == CR && i+1 < n && chunk[i+1] == LF {
+					i++
+				}
 
-						// Process the line
-						p.processLine(line, writer, &charCount)
-						lineStart = i + 1
-					}
+				// Complete a line started in an earlier chunk
+				line := chunk[lineStart:lineEnd]
+				if len(lineBuffer.Bytes) > 0 {
+					lineBuffer.Bytes = append(lineBuffer.Bytes, line...)
+					line = lineBuffer.Bytes
+				}
+
+				lineCounts := counts
+				if i == 0 && b == LF && splitCRLF {
+					lineCounts = nil
 				}
+				if err := p.processLine(line, chunk[lineEnd:i+1], writer, &charCount, lengths, lineCounts); err != nil {
+					return charCount, bytesProcessed, err
+				}
+				lineBuffer.Bytes = lineBuffer.Bytes[:0]
+				lineStart = i + 1
 			}
 
-			// Handle any partial line at the end of the chunk
+			splitCRLF = chunk[n-1] == CR
+
+			// Keep a partial line at the end of the chunk for the next one
 			if lineStart < n {
-				// We have a partial line, store it for the next chunk
 				lineBuffer.Bytes = append(lineBuffer.Bytes, chunk[lineStart:]...)
-				inLine = true
 			}
 		}
 
@@ -419,8 +469,10 @@ func (p *Processor) processLinesOptimized(
 			}
 
 			// Handle final line if there's buffered data
-			if inLine && len(lineBuffer.Bytes) > 0 {
-				p.processLine(lineBuffer.Bytes, writer, &charCount)
+			if len(lineBuffer.Bytes) > 0 {
+				if err := p.processLine(lineBuffer.Bytes, nil, writer, &charCount, lengths, counts); err != nil {
+					return charCount, bytesProcessed, err
+				}
 				lineBuffer.Bytes = lineBuffer.Bytes[:0]
 			}
 
@@ -438,22 +490,40 @@ func (p *Processor) processLinesOptimized(
 	return charCount, bytesProcessed, nil
 }
 
-// processLine handles a single line of text
-func (p *Processor) processLine(line []byte, writer io.Writer, charCount *int) {
+// processLine handles a single line of text ended by terminator, recording
+// its length in lengths and counts if set. It returns the writers' error, if any.
+func (p *Processor) processLine(line, terminator []byte, writer io.Writer, charCount *int, lengths *sketch.TDigest, counts *segments.Writer) error {
 	if len(line) == 0 {
-		return
+		if err := counts.Record(0, 0); err != nil {
+			return err
+		}
+		// Blank lines add nothing, but keep their place when separators are preserved
+		if writer != nil && p.output.preserve && len(terminator) > 0 {
+			_, err := writer.Write(terminator)
+			return err
+		}
+		return nil
 	}
 
 	// Normalize the line
-	normalized := p.normalizer.Normalize(string(line))
+	normalized := p.normalizer.Normalize(bytesconv.String(line))
 
 	// Count characters (runes)
-	*charCount += len([]rune(normalized))
+	n := len([]rune(normalized))
+	*charCount += n
+	lengths.Add(float64(n))
+	if err := counts.Record(len(line), n); err != nil {
+		return err
+	}
 
 	// Write normalized output if writer is provided
 	if writer != nil {
-		writer.Write([]byte(normalized + "\n"))
+		if _, err := writer.Write(append(bytesconv.Bytes(normalized), p.output.after(terminator)...)); err != nil {
+			p.logger.Error("Error writing normalized output", "error", err)
+			return err
+		}
 	}
+	return nil
 }
 
 // findLines locates lines in a byte slice and adds them to the provided buffer
@@ -0,0 +1,119 @@
+package lineprocessor
+
+import (
+	"context"
+	"sync"
+)
+
+// jobDeque is one worker's queue of line jobs
+type jobDeque struct {
+	mu   sync.Mutex
+	jobs []LineJob
+}
+
+// pushBack adds a job at the back
+func (d *jobDeque) pushBack(job LineJob) {
+	d.mu.Lock()
+	d.jobs = append(d.jobs, job)
+	d.mu.Unlock()
+}
+
+// popFront removes the oldest job. Owners and thieves both take from the
+// front, because the collector writes results in job order and waits for
+// the oldest job first.
+func (d *jobDeque) popFront() (LineJob, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.jobs) == 0 {
+		return LineJob{}, false
+	}
+	job := d.jobs[0]
+	d.jobs[0] = LineJob{}
+	d.jobs = d.jobs[1:]
+	return job, true
+}
+
+// jobScheduler hands line jobs to workers through a deque per worker. The
+// reader deals jobs round-robin; a worker whose deque runs dry steals from
+// the others, so one slow job delays only the worker running it instead of
+// every job queued behind it. Workers rarely touch the same lock, unlike a
+// shared channel.
+type jobScheduler struct {
+	deques []jobDeque
+	next   int
+
+	// slots bounds the jobs queued across all deques, holding the reader back
+	slots chan struct{}
+	// wake rouses idle workers when a job arrives
+	wake chan struct{}
+	// done is closed when no more jobs will be pushed
+	done chan struct{}
+}
+
+// newJobScheduler creates a scheduler for workers that queues at most capacity jobs
+func newJobScheduler(workers, capacity int) *jobScheduler {
+	return &jobScheduler{
+		deques: make([]jobDeque, workers),
+		slots:  make(chan struct{}, capacity),
+		wake:   make(chan struct{}, workers),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues a job, waiting while the scheduler is full. It must only be
+// called from one goroutine and returns false once ctx is done.
+func (s *jobScheduler) push(ctx context.Context, job LineJob) bool {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	s.deques[s.next].pushBack(job)
+	s.next = (s.next + 1) % len(s.deques)
+
+	select {
+	case s.wake <- struct{}{}:
+	default: // Every worker already has a wake-up pending
+	}
+	return true
+}
+
+// close tells the workers that no more jobs will be pushed
+func (s *jobScheduler) close() {
+	close(s.done)
+}
+
+// take returns worker's next job, from its own deque or stolen from
+// another's, waiting while all are empty. It returns false once the
+// scheduler is closed and drained.
+func (s *jobScheduler) take(worker int) (LineJob, bool) {
+	for {
+		// Checked before the deques, so jobs pushed before close are seen
+		closed := false
+		select {
+		case <-s.done:
+			closed = true
+		default:
+		}
+
+		if job, ok := s.deques[worker].popFront(); ok {
+			<-s.slots
+			return job, true
+		}
+		for i := 1; i < len(s.deques); i++ {
+			if job, ok := s.deques[(worker+i)%len(s.deques)].popFront(); ok {
+				<-s.slots
+				return job, true
+			}
+		}
+
+		if closed {
+			return LineJob{}, false
+		}
+		select {
+		case <-s.wake:
+		case <-s.done:
+		}
+	}
+}
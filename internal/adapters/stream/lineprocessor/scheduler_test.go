@@ -0,0 +1,161 @@
+package lineprocessor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobSchedulerDeliversEveryJobOnce(t *testing.T) {
+	const workers, total = 4, 2000
+	s := newJobScheduler(workers, MaxJobQueueSize)
+
+	var mu sync.Mutex
+	seen := make(map[int]int)
+	perWorker := make([]int, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				job, ok := s.take(w)
+				if !ok {
+					return
+				}
+				// Worker 0 is slow, so the others must steal its jobs
+				if w == 0 {
+					time.Sleep(100 * time.Microsecond)
+				}
+				mu.Lock()
+				seen[job.ChunkID]++
+				perWorker[w]++
+				mu.Unlock()
+			}
+		}(w)
+	}
+
+	for i := 0; i < total; i++ {
+		if !s.push(context.Background(), LineJob{ChunkID: i}) {
+			t.Fatal("push failed")
+		}
+	}
+	s.close()
+	wg.Wait()
+
+	if len(seen) != total {
+		t.Fatalf("%d distinct jobs taken, want %d", len(seen), total)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("job %d taken %d times", id, n)
+		}
+	}
+	if perWorker[0] >= total/workers {
+		t.Errorf("slow worker ran %d of %d jobs; its deque was not stolen from", perWorker[0], total)
+	}
+}
+
+func TestJobSchedulerPushStopsOnCancel(t *testing.T) {
+	s := newJobScheduler(2, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	if !s.push(ctx, LineJob{}) {
+		t.Fatal("first push failed")
+	}
+	cancel()
+	// The scheduler is full and nobody takes, so only cancellation returns
+	if s.push(ctx, LineJob{}) {
+		t.Error("push succeeded on a full scheduler after cancellation")
+	}
+}
+
+// skewedWork spins for longer on every 16th job, like a chunk of huge lines
+func skewedWork(id int) int {
+	n := 2000
+	if id%16 == 0 {
+		n *= 64
+	}
+	sum := 0
+	for i := 0; i < n; i++ {
+		sum += i ^ id
+	}
+	return sum
+}
+
+// benchmarkDispatch runs skewed jobs through dispatch and reports the 99th
+// percentile wait between a job being queued and a worker starting it
+func benchmarkDispatch(b *testing.B, dispatch func(jobs int, queued []time.Time, run func(id int))) {
+	const jobs = 512
+	waits := make([]time.Duration, 0, jobs*b.N)
+	var mu sync.Mutex
+	for i := 0; i < b.N; i++ {
+		queued := make([]time.Time, jobs)
+		dispatch(jobs, queued, func(id int) {
+			wait := time.Since(queued[id])
+			skewedWork(id)
+			mu.Lock()
+			waits = append(waits, wait)
+			mu.Unlock()
+		})
+	}
+	b.StopTimer()
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	b.ReportMetric(float64(waits[len(waits)*99/100].Microseconds()), "p99-wait-µs")
+}
+
+// BenchmarkLineJobDispatch compares the work-stealing scheduler with the
+// shared channel the parallel processor used before
+func BenchmarkLineJobDispatch(b *testing.B) {
+	const workers = 8
+
+	b.Run("channel", func(b *testing.B) {
+		benchmarkDispatch(b, func(jobs int, queued []time.Time, run func(id int)) {
+			ch := make(chan LineJob, MaxJobQueueSize)
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for job := range ch {
+						run(job.ChunkID)
+					}
+				}()
+			}
+			for id := 0; id < jobs; id++ {
+				queued[id] = time.Now()
+				ch <- LineJob{ChunkID: id}
+			}
+			close(ch)
+			wg.Wait()
+		})
+	})
+
+	b.Run("work-stealing", func(b *testing.B) {
+		benchmarkDispatch(b, func(jobs int, queued []time.Time, run func(id int)) {
+			s := newJobScheduler(workers, MaxJobQueueSize)
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for {
+						job, ok := s.take(w)
+						if !ok {
+							return
+						}
+						run(job.ChunkID)
+					}
+				}(w)
+			}
+			ctx := context.Background()
+			for id := 0; id < jobs; id++ {
+				queued[id] = time.Now()
+				s.push(ctx, LineJob{ChunkID: id})
+			}
+			s.close()
+			wg.Wait()
+		})
+	})
+}
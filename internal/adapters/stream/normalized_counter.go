@@ -0,0 +1,119 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+)
+
+// NormalizedCounts holds the counts of a normalized stream
+type NormalizedCounts struct {
+	Words          int
+	Runes          int
+	BytesProcessed int64
+}
+
+// CountNormalized reads r in chunks of roughly chunkSize bytes, normalizes each
+// chunk and counts the words (as strings.Fields splits them) and runes of the
+// result. Chunks are only cut where a word rune is followed by a separator, so
+// for normalizers that map runes independently and at most collapse separator
+// runs (all built-in normalizers) the counts equal those of normalizing the
+// whole input at once. Normalizers with their own word boundaries report their
+// separators through an IsSeparator(rune) bool method.
+func CountNormalized(ctx context.Context, r io.Reader, norm ports.Normalizer, chunkSize int) (NormalizedCounts, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultModeChunkSize(ports.LineByLine)
+	}
+
+	var counts NormalizedCounts
+	pr := probe.FromContext(ctx)
+	buf := make([]byte, chunkSize)
+	carried := 0
+	yield := parallel.NewYielder()
+
+	separator := isSeparator
+	if s, ok := norm.(interface{ IsSeparator(r rune) bool }); ok {
+		separator = s.IsSeparator
+	}
+
+	// Normalizers that count their output directly skip producing it
+	counter, _ := norm.(interface {
+		CountNormalized(src []byte) (runes int, words int)
+	})
+	flush := func(segment []byte) {
+		if counter != nil {
+			runes, words := counter.CountNormalized(segment)
+			counts.Runes += runes
+			counts.Words += words
+			return
+		}
+		normalized := norm.Normalize(bytesconv.String(segment))
+		counts.Words += len(strings.Fields(normalized))
+		counts.Runes += utf8.RuneCountInString(normalized)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return counts, err
+		}
+
+		// Grow when a single unbroken word fills the whole buffer
+		if carried == len(buf) {
+			grown := make([]byte, len(buf)*2)
+			copy(grown, buf[:carried])
+			buf = grown
+		}
+		pr.RecordBuffer(len(buf))
+
+		n, err := r.Read(buf[carried:])
+		counts.BytesProcessed += int64(n)
+		yield.Add(n)
+		data := buf[:carried+n]
+
+		if err == io.EOF {
+			if len(data) > 0 {
+				flush(data)
+			}
+			return counts, nil
+		}
+		if err != nil {
+			return counts, err
+		}
+
+		cut := lastWordBoundary(data, separator)
+		if cut > 0 {
+			flush(data[:cut])
+		}
+		if cut < 0 {
+			cut = 0
+		}
+		carried = copy(buf, data[cut:])
+	}
+}
+
+// lastWordBoundary returns the last index in data where a word rune is
+// immediately followed by a separator rune, or -1 if there is none
+func lastWordBoundary(data []byte, isSeparator func(r rune) bool) int {
+	for i := len(data); i > 0; {
+		r, size := utf8.DecodeLastRune(data[:i])
+		if isSeparator(r) && i-size > 0 {
+			if prev, _ := utf8.DecodeLastRune(data[:i-size]); !isSeparator(prev) {
+				return i - size
+			}
+		}
+		i -= size
+	}
+	return -1
+}
+
+// isSeparator reports whether the built-in normalizers turn r into a space
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
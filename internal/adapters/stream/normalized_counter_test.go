@@ -0,0 +1,42 @@
+package stream
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+func TestCountNormalizedMatchesWholeText(t *testing.T) {
+	text := strings.Repeat("Hello,  world!! Ünïcödé — text...\n\tTabs; and  spaces ", 200) + "tail"
+	factory := normalizer.NewNormalizerFactory()
+
+	norms := []ports.Normalizer{
+		factory.CreateNormalizer(normalizer.DefaultNormalizerType),
+		factory.CreateNormalizer(normalizer.OptimizedNormalizerType),
+		factory.CreateNormalizer(normalizer.FastNormalizerType),
+		// Counts without normalizing
+		factory.CreateAllocationEfficientNormalizer(),
+	}
+
+	for nt, norm := range norms {
+		whole := norm.Normalize(text)
+		wantWords := len(strings.Fields(whole))
+		wantRunes := utf8.RuneCountInString(whole)
+
+		for _, chunkSize := range []int{3, 7, 64, 4096} {
+			got, err := CountNormalized(context.Background(), iotest.HalfReader(strings.NewReader(text)), norm, chunkSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Words != wantWords || got.Runes != wantRunes {
+				t.Errorf("normalizer %d chunk %d: got %d words / %d runes, want %d / %d",
+					nt, chunkSize, got.Words, got.Runes, wantWords, wantRunes)
+			}
+		}
+	}
+}
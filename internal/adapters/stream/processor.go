@@ -9,14 +9,22 @@ package stream
 
 import (
 	"context"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/clock"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/lineprocessor"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/segments"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/wordprocessor"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/transform"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
 	"io"
-	"math"
 	"time"
 
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/pool"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+	"github.com/baditaflorin/go_length_similarity/internal/sketch"
 )
 
 const (
@@ -28,7 +36,19 @@ const (
 	MaxScannerBufferSize = 1024 * 1024 // 1MB
 )
 
-// DefaultProcessor implements streaming processing for text input
+// DefaultModeChunkSize returns the number of bytes read per Read call for a mode
+// when no explicit chunk size is configured
+func DefaultModeChunkSize(mode ports.StreamingMode) int {
+	switch mode {
+	case ports.LineByLine, ports.WordByWord:
+		return DefaultChunkSize * 8 // Larger chunks amortize line and word scanning
+	default:
+		return DefaultChunkSize
+	}
+}
+
+// DefaultProcessor implements streaming processing for text input.
+// Chunk sizes are always the number of bytes requested per Read call, in every mode.
 type DefaultProcessor struct {
 	logger      ports.Logger
 	normalizer  ports.Normalizer
@@ -37,6 +57,18 @@ type DefaultProcessor struct {
 	builderPool *pool.StringBuilderPool
 	chunkSize   int
 
+	// Per-mode read sizes for the specialized processors
+	lineChunkSize int
+	wordChunkSize int
+	useParallel   bool
+
+	// Writer path output for line and word modes
+	outputDelimiter    string
+	preserveSeparators bool
+
+	// Decides which runes are part of a word in word mode (nil = default rules)
+	wordClassifier func(r rune) bool
+
 	// Specialized processors for different modes
 	wordProcessor *wordprocessor.Processor
 	lineProcessor *lineprocessor.Processor
@@ -44,55 +76,117 @@ type DefaultProcessor struct {
 
 // NewDefaultProcessor creates a new default stream processor
 func NewDefaultProcessor(logger ports.Logger, normalizer ports.Normalizer) *DefaultProcessor {
-	// Initialize the optimized word processor
-	wordProc := wordprocessor.NewProcessor(logger, normalizer, wordprocessor.ProcessingConfig{
-		ChunkSize:   DefaultChunkSize * 8, // Larger chunks for word processing
-		BatchSize:   1000,                 // Process words in batches of 1000
-		UseParallel: false,                // Disable parallel processing by default
-	})
-
-	// Initialize the optimized line processor
-	lineProc := lineprocessor.NewProcessor(logger, normalizer, lineprocessor.ProcessingConfig{
-		ChunkSize:   DefaultChunkSize * 8, // Larger chunks for line processing
-		BatchSize:   100,                  // Process lines in batches of 100
-		UseParallel: false,                // Disable parallel processing by default
-	})
-
-	return &DefaultProcessor{
+	p := &DefaultProcessor{
 		logger:        logger,
 		normalizer:    normalizer,
 		bufferPool:    pool.NewBufferPool(DefaultChunkSize),
 		runePool:      pool.NewRuneBufferPool(DefaultChunkSize),
 		builderPool:   pool.NewStringBuilderPool(),
-		chunkSize:     DefaultChunkSize,
-		wordProcessor: wordProc,
-		lineProcessor: lineProc,
+		chunkSize:     DefaultModeChunkSize(ports.ChunkByChunk),
+		lineChunkSize: DefaultModeChunkSize(ports.LineByLine),
+		wordChunkSize: DefaultModeChunkSize(ports.WordByWord),
 	}
+	p.rebuildProcessors()
+
+	return p
 }
 
-// WithChunkSize sets a custom chunk size for the processor
+// WithChunkSize sets the number of bytes read per Read call in every mode.
+// A size of 0 or less restores the per-mode defaults.
 func (p *DefaultProcessor) WithChunkSize(size int) *DefaultProcessor {
-	p.chunkSize = size
+	for _, mode := range []ports.StreamingMode{ports.ChunkByChunk, ports.LineByLine, ports.WordByWord} {
+		p.setModeChunkSize(mode, size)
+	}
+	p.rebuildProcessors()
+	return p
+}
+
+// WithModeChunkSize overrides the number of bytes read per Read call for a single mode.
+// A size of 0 or less restores the default for that mode.
+func (p *DefaultProcessor) WithModeChunkSize(mode ports.StreamingMode, size int) *DefaultProcessor {
+	p.setModeChunkSize(mode, size)
+	p.rebuildProcessors()
 	return p
 }
 
 // WithParallelProcessing enables parallel processing for specific modes
 func (p *DefaultProcessor) WithParallelProcessing(enable bool) *DefaultProcessor {
-	// Update word processor
+	p.useParallel = enable
+	p.rebuildProcessors()
+	return p
+}
+
+// WithOutputDelimiter sets what ProcessStreamWithWriter writes after each
+// normalized line or word. An empty delimiter restores the defaults ("\n" after
+// lines, " " after words). Chunk mode writes normalized chunks back to back.
+func (p *DefaultProcessor) WithOutputDelimiter(delimiter string) *DefaultProcessor {
+	p.outputDelimiter = delimiter
+	p.rebuildProcessors()
+	return p
+}
+
+// WithPreservedSeparators makes ProcessStreamWithWriter copy the original line
+// terminators (line mode) or the bytes between words (word mode) instead of
+// writing a delimiter. Parallel processing falls back to sequential while
+// writing, so the separators stay in order.
+func (p *DefaultProcessor) WithPreservedSeparators(enable bool) *DefaultProcessor {
+	p.preserveSeparators = enable
+	p.rebuildProcessors()
+	return p
+}
+
+// WithWordClassifier sets which runes word mode counts as part of a word;
+// nil restores the default letters, digits, underscores, hyphens and apostrophes
+func (p *DefaultProcessor) WithWordClassifier(isWord func(r rune) bool) *DefaultProcessor {
+	p.wordClassifier = isWord
+	p.rebuildProcessors()
+	return p
+}
+
+// setModeChunkSize stores the read size for a mode, falling back to its default
+func (p *DefaultProcessor) setModeChunkSize(mode ports.StreamingMode, size int) {
+	if size <= 0 {
+		size = DefaultModeChunkSize(mode)
+	}
+
+	switch mode {
+	case ports.ChunkByChunk:
+		p.chunkSize = size
+	case ports.LineByLine:
+		p.lineChunkSize = size
+	case ports.WordByWord:
+		p.wordChunkSize = size
+	}
+}
+
+// rebuildProcessors recreates the specialized processors with the current settings
+func (p *DefaultProcessor) rebuildProcessors() {
 	p.wordProcessor = wordprocessor.NewProcessor(p.logger, p.normalizer, wordprocessor.ProcessingConfig{
-		ChunkSize:   p.chunkSize * 8, // Larger chunks for word processing
-		BatchSize:   1000,            // Process words in batches of 1000
-		UseParallel: enable,          // Set parallel processing as requested
+		ChunkSize:   p.wordChunkSize,
+		BatchSize:   1000, // Process words in batches of 1000
+		UseParallel: p.useParallel,
+
+		OutputDelimiter:    p.outputDelimiter,
+		PreserveSeparators: p.preserveSeparators,
+
+		WordClassifier: p.wordClassifier,
 	})
 
-	// Update line processor
 	p.lineProcessor = lineprocessor.NewProcessor(p.logger, p.normalizer, lineprocessor.ProcessingConfig{
-		ChunkSize:   p.chunkSize * 8, // Larger chunks for line processing
-		BatchSize:   100,             // Process lines in batches of 100
-		UseParallel: enable,          // Set parallel processing as requested
+		ChunkSize:   p.lineChunkSize,
+		BatchSize:   100, // Process lines in batches of 100
+		UseParallel: p.useParallel,
+
+		OutputDelimiter:    p.outputDelimiter,
+		PreserveSeparators: p.preserveSeparators,
 	})
+}
 
-	return p
+// Release drops the pooled buffers of the processor and its line and word processors
+func (p *DefaultProcessor) Release() {
+	p.bufferPool.Drain()
+	p.wordProcessor.Release()
+	p.lineProcessor.Release()
 }
 
 func (p *DefaultProcessor) ProcessStream(ctx context.Context, reader io.Reader, mode ports.StreamingMode) (int, error) {
@@ -142,6 +236,15 @@ func (p *DefaultProcessor) ProcessStream(ctx context.Context, reader io.Reader,
 
 // ProcessStreamWithWriter processes an input stream, potentially transforms it, and writes to the output writer
 func (p *DefaultProcessor) ProcessStreamWithWriter(ctx context.Context, reader io.Reader, writer io.Writer, mode ports.StreamingMode) (int, error) {
+	return p.ProcessStreamWithCounts(ctx, reader, writer, nil, mode)
+}
+
+// ProcessStreamWithCounts is ProcessStreamWithWriter that also writes a
+// sidecar of JSON Lines to counts, one record per line, word or chunk (see
+// segments.Writer). Either writer may be nil, so counts can be emitted
+// instead of the normalized text or alongside it. Records are written in
+// input order, so parallel processing falls back to sequential.
+func (p *DefaultProcessor) ProcessStreamWithCounts(ctx context.Context, reader io.Reader, writer, counts io.Writer, mode ports.StreamingMode) (int, error) {
 	startTime := time.Now()
 
 	// Check if reader or writer is nil
@@ -149,11 +252,17 @@ func (p *DefaultProcessor) ProcessStreamWithWriter(ctx context.Context, reader i
 		p.logger.Error("Nil reader provided")
 		return 0, io.ErrUnexpectedEOF
 	}
-	if writer == nil {
+	if writer == nil && counts == nil {
 		p.logger.Error("Nil writer provided")
 		return 0, io.ErrUnexpectedEOF
 	}
 
+	var sidecar *segments.Writer
+	if counts != nil {
+		sidecar = segments.NewWriter(counts)
+		ctx = segments.NewContext(ctx, sidecar)
+	}
+
 	var count int
 	var bytesProcessed int64
 	var err error
@@ -173,6 +282,10 @@ func (p *DefaultProcessor) ProcessStreamWithWriter(ctx context.Context, reader i
 		p.logger.Error("Stream processing with writer error", "error", err, "mode", mode)
 		return count, err
 	}
+	if err := sidecar.Flush(); err != nil {
+		p.logger.Error("Error writing counts", "error", err)
+		return count, err
+	}
 
 	p.logger.Debug("Stream processing with writer completed",
 		"mode", mode,
@@ -195,6 +308,11 @@ func (p *DefaultProcessor) processChunks(ctx context.Context, reader io.Reader,
 	} else {
 		*buffer = (*buffer)[:p.chunkSize]
 	}
+	probe.FromContext(ctx).RecordBuffer(len(*buffer))
+
+	// Per-chunk counts, when the caller asked for them
+	counts := segments.FromContext(ctx)
+	yield := parallel.NewYielder()
 
 	count := 0
 	var totalBytes int64 = 0
@@ -216,15 +334,21 @@ func (p *DefaultProcessor) processChunks(ctx context.Context, reader io.Reader,
 		n, err := reader.Read(*buffer)
 		*buffer = (*buffer)[:n]
 		totalBytes += int64(n)
+		yield.Add(n)
 
 		if n > 0 {
 			// Process chunk
-			normalized := p.normalizer.Normalize(string(*buffer))
-			count += len([]rune(normalized))
+			normalized := p.normalizer.Normalize(bytesconv.String(*buffer))
+			length := len([]rune(normalized))
+			count += length
+			if err := counts.Record(n, length); err != nil {
+				p.logger.Error("Error writing counts", "error", err)
+				return count, totalBytes, err
+			}
 
 			// Write normalized output if writer is provided
 			if writer != nil {
-				_, werr := writer.Write([]byte(normalized))
+				_, werr := writer.Write(bytesconv.Bytes(normalized))
 				if werr != nil {
 					p.logger.Error("Error writing to output", "error", werr)
 					return count, totalBytes, werr
@@ -252,62 +376,148 @@ type StreamingCalculator struct {
 	logger     ports.Logger
 	normalizer ports.Normalizer
 	processor  *DefaultProcessor
+	clock      ports.Clock
 }
 
+// EmptyAugmentedPolicy controls how an empty augmented stream is scored when the original is not empty
+type EmptyAugmentedPolicy int
+
+const (
+	// EmptyAugmentedFail scores an empty augmented stream as 0 and never passes,
+	// mirroring the handling of an empty original stream
+	EmptyAugmentedFail EmptyAugmentedPolicy = iota
+	// EmptyAugmentedScore applies the regular formula, which can yield a nonzero
+	// score when MaxDiffRatio is 1 or greater
+	EmptyAugmentedScore
+)
+
 // StreamingConfig holds configuration for streaming similarity calculation
 type StreamingConfig struct {
 	Threshold    float64
 	MaxDiffRatio float64
-	ChunkSize    int
-	Mode         ports.StreamingMode
+	// ChunkSize is the number of bytes read per Read call in every mode (0 = per-mode default)
+	ChunkSize int
+	// ModeChunkSizes overrides ChunkSize for individual modes
+	ModeChunkSizes map[ports.StreamingMode]int
+	Mode           ports.StreamingMode
+	EmptyAugmented EmptyAugmentedPolicy
+	// MaxBytes aborts a comparison once either stream exceeds this many bytes (0 = no limit)
+	MaxBytes int64
+	// Transforms rewrite both streams before they are processed
+	Transforms []ports.Transform
+	// Clock times each comparison for ProcessingTime (nil = wall clock)
+	Clock ports.Clock
+	// WordClassifier decides which runes are part of a word in word mode (nil = default rules)
+	WordClassifier func(r rune) bool
+	// Scorer turns the two counts into a score (nil = scoring.Evaluate)
+	Scorer scoring.Scorer
+}
+
+// Validate checks if the configuration is valid.
+func (c StreamingConfig) Validate() error {
+	if err := domain.ValidateThreshold(c.Threshold); err != nil {
+		return err
+	}
+	if err := domain.ValidateMaxDiffRatio(c.MaxDiffRatio); err != nil {
+		return err
+	}
+	if c.ChunkSize < 0 {
+		return domain.NewConfigError("chunkSize", c.ChunkSize, "must not be negative")
+	}
+	for _, size := range c.ModeChunkSizes {
+		if size < 0 {
+			return domain.NewConfigError("modeChunkSizes", size, "must not be negative")
+		}
+	}
+	if c.MaxBytes < 0 {
+		return domain.NewConfigError("maxBytes", c.MaxBytes, "must not be negative")
+	}
+	if c.Mode < ports.ChunkByChunk || c.Mode > ports.WordByWord {
+		return domain.NewConfigError("mode", c.Mode, "is not a supported streaming mode")
+	}
+	if err := transform.Validate(c.Transforms); err != nil {
+		return err
+	}
+	return nil
+}
+
+// scoringConfig returns the shared scoring parameters; streaming scores are not rounded.
+func (c StreamingConfig) scoringConfig() scoring.Config {
+	return scoring.Config{
+		Threshold:    c.Threshold,
+		MaxDiffRatio: c.MaxDiffRatio,
+		Precision:    scoring.NoRounding,
+	}
 }
 
 // NewStreamingCalculator creates a new streaming calculator
 func NewStreamingCalculator(config StreamingConfig, logger ports.Logger, normalizer ports.Normalizer) (*StreamingCalculator, error) {
-	processor := NewDefaultProcessor(logger, normalizer).WithChunkSize(config.ChunkSize)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	processor := NewDefaultProcessor(logger, normalizer).WithChunkSize(config.ChunkSize).WithWordClassifier(config.WordClassifier)
+	for mode, size := range config.ModeChunkSizes {
+		processor.WithModeChunkSize(mode, size)
+	}
 
 	return &StreamingCalculator{
 		config:     config,
 		logger:     logger,
 		normalizer: normalizer,
 		processor:  processor,
+		clock:      clock.OrSystem(config.Clock),
 	}, nil
 }
 
-// ComputeStreaming calculates the similarity between two text streams
+// Release drops the calculator's pooled buffers
+func (sc *StreamingCalculator) Release() {
+	sc.processor.Release()
+}
+
+// ComputeStreaming calculates the similarity between two text streams.
+// Inputs wrapped in io.LimitReader that hit their cap are flagged as
+// potentially truncated.
 func (sc *StreamingCalculator) ComputeStreaming(ctx context.Context, original io.Reader, augmented io.Reader) ports.StreamResult {
-	startTime := time.Now()
+	return MarkLimitReached(sc.computeStreaming(ctx, original, augmented), original, augmented)
+}
+
+// computeStreaming runs ComputeStreaming without the io.LimitReader check
+func (sc *StreamingCalculator) computeStreaming(ctx context.Context, original io.Reader, augmented io.Reader) ports.StreamResult {
+	startTime := sc.clock.Now()
 
 	details := make(map[string]interface{})
 
+	// Unknown-length inputs such as pipes and chunked bodies are bounded and
+	// read in full buffers, so results do not depend on how the producer writes
+	original = FillReader(transform.Readers(LimitReader(original, sc.config.MaxBytes), sc.config.Transforms))
+	augmented = FillReader(transform.Readers(LimitReader(augmented, sc.config.MaxBytes), sc.config.Transforms))
+
+	// In line mode, sketch each side's line lengths so collapsed or split
+	// lines show up in the details even when the totals match
+	var origLines, augLines *sketch.TDigest
+	origCtx, augCtx := ctx, ctx
+	if sc.config.Mode == ports.LineByLine {
+		origLines, augLines = sketch.New(sketch.DefaultCompression), sketch.New(sketch.DefaultCompression)
+		origCtx, augCtx = sketch.NewContext(ctx, origLines), sketch.NewContext(ctx, augLines)
+	}
+
 	// Process original text stream
-	origCount, err := sc.processor.ProcessStream(ctx, original, sc.config.Mode)
+	origCount, err := sc.processor.ProcessStream(origCtx, original, sc.config.Mode)
 	if err != nil && err != io.EOF {
 		sc.logger.Error("Error processing original stream", "error", err)
-		details["error"] = "error processing original stream: " + err.Error()
-		return ports.StreamResult{
-			Name:           "streaming_similarity",
-			Score:          0,
-			Passed:         false,
-			Details:        details,
-			ProcessingTime: time.Since(startTime),
-		}
+		return ErrorResult("original", err, details, sc.clock.Now().Sub(startTime))
 	}
 
 	// Process augmented text stream
-	augCount, err := sc.processor.ProcessStream(ctx, augmented, sc.config.Mode)
+	augCount, err := sc.processor.ProcessStream(augCtx, augmented, sc.config.Mode)
 	if err != nil && err != io.EOF {
 		sc.logger.Error("Error processing augmented stream", "error", err)
-		details["error"] = "error processing augmented stream: " + err.Error()
-		return ports.StreamResult{
-			Name:           "streaming_similarity",
-			Score:          0,
-			Passed:         false,
-			Details:        details,
-			ProcessingTime: time.Since(startTime),
-		}
+		return ErrorResult("augmented", err, details, sc.clock.Now().Sub(startTime))
 	}
 
+	AddLineLengths(details, origLines, augLines)
+
 	// Special case: if both texts are empty, consider them identical
 	if origCount == 0 && augCount == 0 {
 		sc.logger.Debug("Both texts are empty, considering them identical")
@@ -321,7 +531,7 @@ func (sc *StreamingCalculator) ComputeStreaming(ctx context.Context, original io
 			LengthRatio:     1.0,
 			Threshold:       sc.config.Threshold,
 			Details:         details,
-			ProcessingTime:  time.Since(startTime),
+			ProcessingTime:  sc.clock.Now().Sub(startTime),
 		}
 	}
 
@@ -338,26 +548,32 @@ func (sc *StreamingCalculator) ComputeStreaming(ctx context.Context, original io
 			LengthRatio:     0.0,
 			Threshold:       sc.config.Threshold,
 			Details:         details,
-			ProcessingTime:  time.Since(startTime),
+			ProcessingTime:  sc.clock.Now().Sub(startTime),
 		}
 	}
 
-	// Calculate similarity using the same algorithm as the non-streaming version
-	var lengthRatio float64
-	if origCount > augCount {
-		lengthRatio = float64(augCount) / float64(origCount)
-	} else {
-		lengthRatio = float64(origCount) / float64(augCount)
-	}
-
-	diff := math.Abs(float64(origCount - augCount))
-	diffRatio := diff / (float64(origCount) * sc.config.MaxDiffRatio)
-	if diffRatio > 1.0 {
-		diffRatio = 1.0
+	// Handle case where augmented text is empty but original is not
+	if augCount == 0 && sc.config.EmptyAugmented == EmptyAugmentedFail {
+		sc.logger.Warn("Augmented text has zero length, considering maximum difference")
+		details["warning"] = "augmented text has zero length"
+		return ports.StreamResult{
+			Name:            "streaming_similarity",
+			Score:           0.0,
+			Passed:          false,
+			OriginalLength:  origCount,
+			AugmentedLength: 0,
+			LengthRatio:     0.0,
+			Threshold:       sc.config.Threshold,
+			Details:         details,
+			ProcessingTime:  sc.clock.Now().Sub(startTime),
+		}
 	}
 
-	scaledScore := 1.0 - diffRatio
-	passed := scaledScore >= sc.config.Threshold
+	// Calculate similarity using the same algorithm as the non-streaming version
+	outcome := scoring.Apply(sc.config.Scorer, origCount, augCount, sc.config.scoringConfig())
+	lengthRatio := outcome.LengthRatio
+	scaledScore := outcome.Score
+	passed := outcome.Passed
 
 	details["original_length"] = origCount
 	details["augmented_length"] = augCount
@@ -369,7 +585,7 @@ func (sc *StreamingCalculator) ComputeStreaming(ctx context.Context, original io
 		"score", scaledScore,
 		"passed", passed,
 		"details", details,
-		"duration", time.Since(startTime),
+		"duration", sc.clock.Now().Sub(startTime),
 	)
 
 	return ports.StreamResult{
@@ -381,6 +597,39 @@ func (sc *StreamingCalculator) ComputeStreaming(ctx context.Context, original io
 		LengthRatio:     lengthRatio,
 		Threshold:       sc.config.Threshold,
 		Details:         details,
-		ProcessingTime:  time.Since(startTime),
+		ProcessingTime:  sc.clock.Now().Sub(startTime),
+	}
+}
+
+// AddLineLengths records the line length quantiles of each side in details,
+// under "original_line_lengths" and "augmented_line_lengths"
+func AddLineLengths(details map[string]interface{}, original, augmented *sketch.TDigest) {
+	if summary := original.Summary(); summary != nil {
+		details["original_line_lengths"] = summary
+	}
+	if summary := augmented.Summary(); summary != nil {
+		details["augmented_line_lengths"] = summary
+	}
+}
+
+// ErrorResult reports a comparison aborted by an error on one side,
+// flagging inputs that were cut short so they are not mistaken for a low
+// score. elapsed is the time spent before the error.
+func ErrorResult(which string, err error, details map[string]interface{}, elapsed time.Duration) ports.StreamResult {
+	truncated := domain.IsTruncated(err)
+	if truncated {
+		details["error"] = which + " stream was truncated: " + err.Error()
+		details["truncated_input"] = which
+	} else {
+		details["error"] = "error processing " + which + " stream: " + err.Error()
+	}
+	return ports.StreamResult{
+		Name:           "streaming_similarity",
+		Score:          0,
+		Passed:         false,
+		Details:        details,
+		Err:            err,
+		TruncatedInput: truncated,
+		ProcessingTime: elapsed,
 	}
 }
@@ -62,9 +62,11 @@ func (f *ProcessorFactory) CreateProcessor(
 			f.logger,
 			norm,
 			lineprocessor.ProcessingConfig{
-				ChunkSize:   config.ChunkSize,
-				BatchSize:   config.BatchSize,
-				UseParallel: config.UseParallel,
+				ChunkSize:          config.ChunkSize,
+				BatchSize:          config.BatchSize,
+				UseParallel:        config.UseParallel,
+				OutputDelimiter:    config.OutputDelimiter,
+				PreserveSeparators: config.PreserveSeparators,
 			},
 		)
 
@@ -77,9 +79,11 @@ func (f *ProcessorFactory) CreateProcessor(
 			f.logger,
 			norm,
 			lineprocessor.ProcessingConfig{
-				ChunkSize:   config.ChunkSize,
-				BatchSize:   config.BatchSize,
-				UseParallel: config.UseParallel,
+				ChunkSize:          config.ChunkSize,
+				BatchSize:          config.BatchSize,
+				UseParallel:        config.UseParallel,
+				OutputDelimiter:    config.OutputDelimiter,
+				PreserveSeparators: config.PreserveSeparators,
 			},
 		)
 
@@ -95,6 +99,7 @@ func (f *ProcessorFactory) CreateProcessor(
 		if config.UseParallel {
 			processor.WithParallelProcessing(true)
 		}
+		processor.WithOutputDelimiter(config.OutputDelimiter).WithPreservedSeparators(config.PreserveSeparators)
 		return processor
 	}
 }
@@ -104,6 +109,9 @@ type ProcessorConfig struct {
 	ChunkSize   int
 	BatchSize   int
 	UseParallel bool
+	// OutputDelimiter and PreserveSeparators shape the ProcessStreamWithWriter output
+	OutputDelimiter    string
+	PreserveSeparators bool
 }
 
 // StreamProcessorWithLineProcessor adapts a line processor to the StreamProcessor interface
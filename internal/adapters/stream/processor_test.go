@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// readSizeRecorder remembers the largest buffer a Read call asked to fill
+type readSizeRecorder struct {
+	r   io.Reader
+	max int
+}
+
+func (rr *readSizeRecorder) Read(p []byte) (int, error) {
+	rr.max = max(rr.max, len(p))
+	return rr.r.Read(p)
+}
+
+// readSizes reports the largest read each mode makes through p
+func readSizes(t *testing.T, p *DefaultProcessor) map[ports.StreamingMode]int {
+	t.Helper()
+	input := strings.Repeat("alpha beta gamma\n", 20000)
+	sizes := make(map[ports.StreamingMode]int)
+	for _, mode := range []ports.StreamingMode{ports.ChunkByChunk, ports.LineByLine, ports.WordByWord} {
+		rr := &readSizeRecorder{r: strings.NewReader(input)}
+		if _, err := p.ProcessStream(context.Background(), rr, mode); err != nil {
+			t.Fatal(err)
+		}
+		sizes[mode] = rr.max
+	}
+	return sizes
+}
+
+func TestChunkSizesPerMode(t *testing.T) {
+	newProcessor := func() *DefaultProcessor {
+		return NewDefaultProcessor(logger.NewNopLogger(), lowerNormalizer{})
+	}
+	check := func(name string, got, want map[ports.StreamingMode]int) {
+		t.Helper()
+		for mode, size := range want {
+			if got[mode] != size {
+				t.Errorf("%s: mode %v reads %d bytes at a time, want %d", name, mode, got[mode], size)
+			}
+		}
+	}
+
+	defaults := map[ports.StreamingMode]int{
+		ports.ChunkByChunk: DefaultChunkSize,
+		ports.LineByLine:   8 * DefaultChunkSize,
+		ports.WordByWord:   8 * DefaultChunkSize,
+	}
+	for mode, size := range defaults {
+		if got := DefaultModeChunkSize(mode); got != size {
+			t.Errorf("DefaultModeChunkSize(%v) = %d, want %d", mode, got, size)
+		}
+	}
+	check("defaults", readSizes(t, newProcessor()), defaults)
+
+	// The size is the read size in every mode; line and word modes no longer
+	// multiply it by 8
+	check("WithChunkSize", readSizes(t, newProcessor().WithChunkSize(1000)), map[ports.StreamingMode]int{
+		ports.ChunkByChunk: 1000,
+		ports.LineByLine:   1000,
+		ports.WordByWord:   1000,
+	})
+
+	// A per-mode size overrides the shared one for that mode only
+	p := newProcessor().WithChunkSize(1000).WithModeChunkSize(ports.LineByLine, 500)
+	check("WithModeChunkSize", readSizes(t, p), map[ports.StreamingMode]int{
+		ports.ChunkByChunk: 1000,
+		ports.LineByLine:   500,
+		ports.WordByWord:   1000,
+	})
+
+	// Zero restores the mode's default
+	p.WithModeChunkSize(ports.LineByLine, 0)
+	check("reset", readSizes(t, p), map[ports.StreamingMode]int{ports.LineByLine: 8 * DefaultChunkSize})
+}
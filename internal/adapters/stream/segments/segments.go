@@ -0,0 +1,70 @@
+// Package segments writes the counts sidecar of the writer path: one JSON
+// object per line, word or chunk, so downstream tools can compute their own
+// metrics from the same pass that produced the normalized text.
+package segments
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+)
+
+// Writer writes one record per segment, numbered from 0:
+//
+//	{"segment":0,"bytes":14,"length":12}
+//
+// bytes is the size of the raw segment without its line terminator or the
+// separators around a word; length is the rune count of its normalized form.
+// A nil *Writer ignores Record so callers need not check for one. It is not
+// safe for concurrent use.
+type Writer struct {
+	w     *bufio.Writer
+	buf   []byte
+	index int
+}
+
+// NewWriter creates a Writer that buffers its records to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Record writes the next segment's record
+func (s *Writer) Record(bytes, length int) error {
+	if s == nil {
+		return nil
+	}
+	b := append(s.buf[:0], `{"segment":`...)
+	b = strconv.AppendInt(b, int64(s.index), 10)
+	b = append(b, `,"bytes":`...)
+	b = strconv.AppendInt(b, int64(bytes), 10)
+	b = append(b, `,"length":`...)
+	b = strconv.AppendInt(b, int64(length), 10)
+	b = append(b, "}\n"...)
+	s.buf = b
+	s.index++
+	_, err := s.w.Write(b)
+	return err
+}
+
+// Flush writes any buffered records
+func (s *Writer) Flush() error {
+	if s == nil {
+		return nil
+	}
+	return s.w.Flush()
+}
+
+type contextKey struct{}
+
+// NewContext attaches a Writer that processors record segments to. Processors
+// record in input order, so they use their sequential path while one is attached.
+func NewContext(ctx context.Context, s *Writer) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the Writer attached to ctx, or nil
+func FromContext(ctx context.Context) *Writer {
+	s, _ := ctx.Value(contextKey{}).(*Writer)
+	return s
+}
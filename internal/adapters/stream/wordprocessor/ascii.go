@@ -124,3 +124,40 @@ func RuneLen(r rune) int {
 	}
 	return 4
 }
+
+// Classifier decides which runes are part of a word. It caches the ASCII
+// answers in a lookup table so custom rules keep the fast ASCII path.
+type Classifier struct {
+	ascii  [ASCIITableSize]bool
+	isWord func(r rune) bool
+}
+
+// NewClassifier creates a classifier from isWord; nil uses IsWordChar
+func NewClassifier(isWord func(r rune) bool) *Classifier {
+	if isWord == nil {
+		isWord = IsWordChar
+	}
+	c := &Classifier{isWord: isWord}
+	for i := 0; i < ASCIITableSize; i++ {
+		c.ascii[i] = isWord(rune(i))
+	}
+	return c
+}
+
+// IsASCIIWordChar is IsASCIIWordChar under the classifier's rules
+func (c *Classifier) IsASCIIWordChar(b byte) bool {
+	if b < ASCIITableSize {
+		return c.ascii[b]
+	}
+	return false
+}
+
+// HandleUTF8 is HandleUTF8 under the classifier's rules
+func (c *Classifier) HandleUTF8(data []byte, pos int) (rune, int, bool) {
+	if data[pos] < ASCIITableSize {
+		return rune(data[pos]), 1, c.ascii[data[pos]]
+	}
+
+	r, size := DecodeRune(data[pos:])
+	return r, size, c.isWord(r)
+}
@@ -2,6 +2,8 @@ package wordprocessor
 
 import (
 	"sync"
+
+	"github.com/baditaflorin/go_length_similarity/internal/pool"
 )
 
 // WordBuffer represents a reusable buffer for word processing
@@ -10,27 +12,25 @@ type WordBuffer struct {
 	Bytes []byte
 }
 
-// WordBufferPool implements a pool of word buffers for efficient reuse
+// WordBufferPool implements a sharded pool of word buffers for efficient reuse
 type WordBufferPool struct {
-	pool sync.Pool
+	pool *pool.Sharded[*WordBuffer]
 }
 
 // NewWordBufferPool creates a new word buffer pool
 func NewWordBufferPool() *WordBufferPool {
 	return &WordBufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				// Most words are under 64 bytes
-				buf := make([]byte, 0, 64)
-				return &WordBuffer{Bytes: buf}
-			},
-		},
+		pool: pool.NewSharded(func() *WordBuffer {
+			// Most words are under 64 bytes
+			buf := make([]byte, 0, 64)
+			return &WordBuffer{Bytes: buf}
+		}, 0),
 	}
 }
 
 // Get retrieves a word buffer from the pool
 func (wbp *WordBufferPool) Get() *WordBuffer {
-	return wbp.pool.Get().(*WordBuffer)
+	return wbp.pool.Get()
 }
 
 // Put returns a word buffer to the pool
@@ -40,34 +40,37 @@ func (wbp *WordBufferPool) Put(wb *WordBuffer) {
 	wbp.pool.Put(wb)
 }
 
+// Drain drops all pooled word buffers
+func (wbp *WordBufferPool) Drain() {
+	wbp.pool.Drain()
+}
+
 // ChunkBuffer represents a larger buffer for processing chunks of text
 type ChunkBuffer struct {
 	// Buffer to store chunk bytes
 	Bytes []byte
 }
 
-// ChunkBufferPool implements a pool of chunk buffers
+// ChunkBufferPool implements a sharded pool of chunk buffers
 type ChunkBufferPool struct {
-	pool      sync.Pool
+	pool      *pool.Sharded[*ChunkBuffer]
 	chunkSize int
 }
 
 // NewChunkBufferPool creates a new chunk buffer pool
 func NewChunkBufferPool(chunkSize int) *ChunkBufferPool {
 	return &ChunkBufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				buf := make([]byte, chunkSize)
-				return &ChunkBuffer{Bytes: buf}
-			},
-		},
+		pool: pool.NewSharded(func() *ChunkBuffer {
+			buf := make([]byte, chunkSize)
+			return &ChunkBuffer{Bytes: buf}
+		}, pool.ChunkShardCapacity),
 		chunkSize: chunkSize,
 	}
 }
 
 // Get retrieves a chunk buffer from the pool
 func (cbp *ChunkBufferPool) Get() *ChunkBuffer {
-	buffer := cbp.pool.Get().(*ChunkBuffer)
+	buffer := cbp.pool.Get()
 
 	// Ensure buffer has correct size (in case chunkSize changed)
 	if cap(buffer.Bytes) < cbp.chunkSize {
@@ -84,6 +87,11 @@ func (cbp *ChunkBufferPool) Put(cb *ChunkBuffer) {
 	cbp.pool.Put(cb)
 }
 
+// Drain drops all pooled chunk buffers
+func (cbp *ChunkBufferPool) Drain() {
+	cbp.pool.Drain()
+}
+
 // WordBatchBuffer holds a batch of words for batch processing
 type WordBatchBuffer struct {
 	// Slice of word slices
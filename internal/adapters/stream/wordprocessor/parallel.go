@@ -1,17 +1,21 @@
 package wordprocessor
 
 import (
+	"bufio"
 	"context"
 	"io"
-	"runtime"
 	"sync"
 	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 )
 
 // Constants for parallel processing
 const (
 	// DefaultWorkers is the default number of worker goroutines
-	DefaultWorkers = 0 // 0 means use runtime.NumCPU()
+	DefaultWorkers = 0 // 0 means use parallel.DefaultWorkers()
 
 	// MaxJobQueueSize limits the number of pending jobs
 	MaxJobQueueSize = 32
@@ -28,11 +32,22 @@ type WordJob struct {
 type WordJobResult struct {
 	WordCount int
 	ChunkID   int
+	StartWord bool // Whether the chunk started inside a word from the previous chunk
 	EndWord   bool // Whether we ended in a word
 	Error     error
+
+	// Writer path only: Output holds the normalized words that start and end in
+	// the chunk. A word crossing chunk boundaries is stitched by the collector
+	// from Head (its end, when HeadEnded) and Tail (its unfinished start).
+	Output    []byte
+	Head      []byte
+	HeadEnded bool
+	Tail      []byte
 }
 
-// processWordsParallel implements parallel word processing using worker pools
+// processWordsParallel implements parallel word processing using worker pools.
+// Workers buffer their normalized output; the collector merges it in chunk
+// order, stitches words split across chunks and stops on the first write error.
 func (p *Processor) processWordsParallel(
 	ctx context.Context,
 	reader io.Reader,
@@ -40,8 +55,14 @@ func (p *Processor) processWordsParallel(
 ) (int, int64, error) {
 	startTime := time.Now()
 
-	// Determine number of workers
-	workers := runtime.NumCPU()
+	// Cancelled on the first failure, so the reader and workers stop early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Determine number of workers within the shared parallelism budget
+	workers, release := parallel.Acquire(parallel.DefaultWorkers())
+	defer release()
+	probe.FromContext(ctx).RecordWorkers(workers)
 
 	// Create channels for job distribution and result collection
 	jobs := make(chan WordJob, MaxJobQueueSize)
@@ -53,7 +74,7 @@ func (p *Processor) processWordsParallel(
 	// Start worker goroutines
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go p.wordWorker(ctx, i, jobs, results, &wg, writer)
+		go p.wordWorker(ctx, i, jobs, results, &wg, writer != nil)
 	}
 
 	// Create a goroutine to close the results channel when all workers are done
@@ -71,9 +92,16 @@ func (p *Processor) processWordsParallel(
 		var inWord bool
 		var bytesProcessed int64
 
+		// Always release the workers and report progress, however reading ends
+		defer func() { bytesProcessedChan <- bytesProcessed }()
+		defer close(jobs)
+
 		// Get a buffer for reading
 		chunkBuffer := p.chunkBufferPool.Get()
 		defer p.chunkBufferPool.Put(chunkBuffer)
+		// The read buffer plus up to MaxJobQueueSize queued chunk copies
+		probe.FromContext(ctx).RecordBuffer(len(chunkBuffer.Bytes) * (MaxJobQueueSize + 1))
+		yield := parallel.NewYielder()
 
 		for {
 			// Check for context cancellation
@@ -94,6 +122,7 @@ func (p *Processor) processWordsParallel(
 				chunk := make([]byte, n)
 				copy(chunk, chunkBuffer.Bytes[:n])
 				bytesProcessed += int64(n)
+				yield.Add(n)
 
 				// Send the chunk to the worker pool
 				select {
@@ -112,7 +141,7 @@ func (p *Processor) processWordsParallel(
 				// Check if we end in a word character
 				if n > 0 {
 					lastByte := chunk[n-1]
-					inWord = IsASCIIWordChar(lastByte)
+					inWord = p.classifier.IsASCIIWordChar(lastByte)
 				}
 
 				chunkID++
@@ -120,51 +149,106 @@ func (p *Processor) processWordsParallel(
 
 			// Handle end of stream or errors
 			if err != nil {
-				close(jobs) // No more jobs
-
 				if err != io.EOF {
 					errChan <- err
 				} else {
 					errChan <- nil // Normal EOF
 				}
-
-				bytesProcessedChan <- bytesProcessed
 				return
 			}
 		}
 	}()
 
-	// Collect and process results
+	// Collect and process results, merging output through one buffered writer
+	var out *bufio.Writer
+	if writer != nil {
+		out = bufio.NewWriter(writer)
+	}
 	wordCount := 0
 	resultMap := make(map[int]WordJobResult)
 	nextChunkID := 0
+	var firstErr error
+
+	// A word left unfinished by the previous chunk, and its raw bytes when writing
+	pendingWord := false
+	var carry []byte
+	flushCarry := func(head []byte) {
+		if out != nil && firstErr == nil {
+			word := append(carry, head...)
+			if _, err := out.Write(p.appendWord(nil, word)); err != nil {
+				firstErr = err
+				cancel()
+			}
+		}
+		carry = carry[:0]
+		pendingWord = false
+	}
 
 	// Wait for all results and order them by chunk ID
 	for result := range results {
+		if firstErr != nil {
+			continue // Draining after a failure
+		}
 		resultMap[result.ChunkID] = result
 
 		// Process results in order
-		for {
+		for firstErr == nil {
 			result, exists := resultMap[nextChunkID]
 			if !exists {
 				break
 			}
+			delete(resultMap, nextChunkID)
+			nextChunkID++
 
 			// Check for errors
 			if result.Error != nil {
-				return wordCount, <-bytesProcessedChan, result.Error
+				firstErr = result.Error
+				cancel()
+				break
+			}
+
+			// A word that ended exactly at the previous chunk boundary
+			if pendingWord && !result.StartWord {
+				wordCount++
+				flushCarry(nil)
+			}
+			// A word that ended inside this chunk; the worker counted it
+			if result.HeadEnded {
+				flushCarry(result.Head)
 			}
 
-			// Add to word count
+			// Add to word count and write the chunk's own words
 			wordCount += result.WordCount
+			if out != nil && firstErr == nil {
+				if _, err := out.Write(result.Output); err != nil {
+					firstErr = err
+					cancel()
+				}
+			}
 
-			// Remove processed result and move to next
-			delete(resultMap, nextChunkID)
-			nextChunkID++
+			if result.EndWord {
+				carry = append(carry, result.Tail...)
+				pendingWord = true
+			}
 		}
 	}
 
-	// Get the final error (if any) and bytes processed
+	bytesProcessed := <-bytesProcessedChan
+
+	// The last word of the stream has no separator after it
+	if firstErr == nil && pendingWord {
+		wordCount++
+		flushCarry(nil)
+	}
+	if firstErr == nil && out != nil {
+		firstErr = out.Flush()
+	}
+	if firstErr != nil {
+		p.logger.Error("Parallel word processing failed", "error", firstErr)
+		return wordCount, bytesProcessed, firstErr
+	}
+
+	// Get the final error (if any)
 	var err error
 	select {
 	case err = <-errChan:
@@ -173,8 +257,6 @@ func (p *Processor) processWordsParallel(
 		// No error
 	}
 
-	bytesProcessed := <-bytesProcessedChan
-
 	// Log completion
 	p.logger.Debug("Parallel word processing completed",
 		"word_count", wordCount,
@@ -186,6 +268,12 @@ func (p *Processor) processWordsParallel(
 	return wordCount, bytesProcessed, err
 }
 
+// appendWord appends the normalized word and the output delimiter to out
+func (p *Processor) appendWord(out, word []byte) []byte {
+	out = append(out, p.normalizer.Normalize(bytesconv.String(word))...)
+	return append(out, p.delimiter...)
+}
+
 // wordWorker is a worker goroutine that processes chunks in parallel
 func (p *Processor) wordWorker(
 	ctx context.Context,
@@ -193,13 +281,12 @@ func (p *Processor) wordWorker(
 	jobs <-chan WordJob,
 	results chan<- WordJobResult,
 	wg *sync.WaitGroup,
-	writer io.Writer,
+	buffered bool,
 ) {
 	defer wg.Done()
 
-	// Get a word buffer from the pool
-	wordBuffer := p.wordBufferPool.Get()
-	defer p.wordBufferPool.Put(wordBuffer)
+	// Per-worker output buffer; each chunk's output is copied out for the collector
+	var out []byte
 
 	// Process jobs until the channel is closed
 	for job := range jobs {
@@ -216,46 +303,43 @@ func (p *Processor) wordWorker(
 		}
 
 		// Process the chunk
-		var wordCount int
-		var endWord bool
+		result := WordJobResult{ChunkID: job.ChunkID, StartWord: job.StartWord}
+		out = out[:0]
 
-		// Determine if we can use the fast ASCII path
-		asciiOnly := IsASCIIOnly(job.Chunk)
-
-		// Variables for word tracking
+		// Variables for word tracking; a word continued from the previous
+		// chunk is the head until it ends
 		inWord := job.StartWord
+		inHead := job.StartWord
 		wordStart := 0
 
-		if asciiOnly {
+		// endWord handles a word ending at i
+		endWord := func(i int) {
+			result.WordCount++
+			if buffered {
+				if inHead {
+					result.Head = job.Chunk[:i]
+					result.HeadEnded = true
+				} else {
+					out = p.appendWord(out, job.Chunk[wordStart:i])
+				}
+			}
+			inHead = false
+			inWord = false
+		}
+
+		// Determine if we can use the fast ASCII path
+		if IsASCIIOnly(job.Chunk) {
 			// Fast ASCII path
 			for i := 0; i < len(job.Chunk); i++ {
-				b := job.Chunk[i]
-				isChar := IsASCIIWordChar(b)
-
-				if isChar {
+				if p.classifier.IsASCIIWordChar(job.Chunk[i]) {
 					// Start of a word
 					if !inWord {
 						wordStart = i
 						inWord = true
 					}
-				} else {
+				} else if inWord {
 					// End of a word
-					if inWord {
-						// Found a complete word
-						wordCount++
-
-						// Write the word if needed
-						if writer != nil {
-							wordBuffer.Bytes = append(wordBuffer.Bytes[:0], job.Chunk[wordStart:i]...)
-							normalized := p.normalizer.Normalize(string(wordBuffer.Bytes))
-
-							// For parallel writer support, we would need a write mutex here
-							// For now, this is a simplification and would need additional synchronization
-							writer.Write([]byte(normalized + " "))
-						}
-
-						inWord = false
-					}
+					endWord(i)
 				}
 			}
 		} else {
@@ -263,7 +347,7 @@ func (p *Processor) wordWorker(
 			i := 0
 			for i < len(job.Chunk) {
 				// Fix: Use blank identifier for unused variable
-				_, size, isChar := HandleUTF8(job.Chunk, i)
+				_, size, isChar := p.classifier.HandleUTF8(job.Chunk, i)
 
 				if isChar {
 					// Start of a word
@@ -271,21 +355,9 @@ func (p *Processor) wordWorker(
 						wordStart = i
 						inWord = true
 					}
-				} else {
+				} else if inWord {
 					// End of a word
-					if inWord {
-						// Found a complete word
-						wordCount++
-
-						// Write the word if needed
-						if writer != nil {
-							wordBuffer.Bytes = append(wordBuffer.Bytes[:0], job.Chunk[wordStart:i]...)
-							normalized := p.normalizer.Normalize(string(wordBuffer.Bytes))
-							writer.Write([]byte(normalized + " "))
-						}
-
-						inWord = false
-					}
+					endWord(i)
 				}
 
 				i += size
@@ -293,14 +365,15 @@ func (p *Processor) wordWorker(
 		}
 
 		// Are we ending in a word?
-		endWord = inWord
+		result.EndWord = inWord
+		if buffered {
+			if inWord {
+				result.Tail = job.Chunk[wordStart:]
+			}
+			result.Output = append([]byte(nil), out...)
+		}
 
 		// Send the result
-		results <- WordJobResult{
-			WordCount: wordCount,
-			ChunkID:   job.ChunkID,
-			EndWord:   endWord,
-			Error:     nil,
-		}
+		results <- result
 	}
 }
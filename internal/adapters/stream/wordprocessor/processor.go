@@ -4,8 +4,13 @@ import (
 	"context"
 	"io"
 	"time"
+	"unicode/utf8"
 
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/segments"
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 )
 
 // Constants for word processing
@@ -15,9 +20,6 @@ const (
 
 	// DefaultBatchSize defines how many words to process in one batch
 	DefaultBatchSize = 1000
-
-	// ContextCheckFrequency defines how often to check for context cancellation
-	ContextCheckFrequency = 5000 // words
 )
 
 // Processor implements optimized word processing
@@ -34,13 +36,30 @@ type Processor struct {
 	chunkSize   int
 	batchSize   int
 	useParallel bool
+
+	// Writer output
+	delimiter          []byte
+	preserveSeparators bool
+
+	// Decides which runes are part of a word
+	classifier *Classifier
 }
 
+// DefaultOutputDelimiter is written after each normalized word on the writer path
+const DefaultOutputDelimiter = " "
+
 // ProcessingConfig defines configuration for word processing
 type ProcessingConfig struct {
 	ChunkSize   int
 	BatchSize   int
 	UseParallel bool
+	// OutputDelimiter is written after each normalized word ("" = DefaultOutputDelimiter)
+	OutputDelimiter string
+	// PreserveSeparators copies the original bytes between words to the writer
+	// instead of writing OutputDelimiter
+	PreserveSeparators bool
+	// WordClassifier reports whether a rune is part of a word (nil = IsWordChar)
+	WordClassifier func(r rune) bool
 }
 
 // NewProcessor creates a new optimized word processor
@@ -56,6 +75,9 @@ func NewProcessor(
 	if config.BatchSize <= 0 {
 		config.BatchSize = DefaultBatchSize
 	}
+	if config.OutputDelimiter == "" {
+		config.OutputDelimiter = DefaultOutputDelimiter
+	}
 
 	return &Processor{
 		logger:          logger,
@@ -66,16 +88,30 @@ func NewProcessor(
 		chunkSize:       config.ChunkSize,
 		batchSize:       config.BatchSize,
 		useParallel:     config.UseParallel,
+
+		delimiter:          []byte(config.OutputDelimiter),
+		preserveSeparators: config.PreserveSeparators,
+
+		classifier: NewClassifier(config.WordClassifier),
 	}
 }
 
+// Release drops the processor's pooled buffers. The processor stays usable
+// but allocates fresh buffers until the pools refill.
+func (p *Processor) Release() {
+	p.wordBufferPool.Drain()
+	p.chunkBufferPool.Drain()
+}
+
 // ProcessWords processes a reader word by word and returns the word count
 func (p *Processor) ProcessWords(
 	ctx context.Context,
 	reader io.Reader,
 	writer io.Writer,
 ) (int, int64, error) {
-	if p.useParallel {
+	// Parallel workers write in completion order, so copying the original
+	// separators or recording each word's counts needs the sequential path
+	if p.useParallel && !(writer != nil && p.preserveSeparators) && segments.FromContext(ctx) == nil {
 		return p.processWordsParallel(ctx, reader, writer)
 	}
 	return p.processWordsOptimized(ctx, reader, writer)
@@ -92,6 +128,17 @@ func (p *Processor) processWordsOptimized(
 	// Get buffers from pools
 	chunkBuffer := p.chunkBufferPool.Get()
 	defer p.chunkBufferPool.Put(chunkBuffer)
+	probe.FromContext(ctx).RecordBuffer(len(chunkBuffer.Bytes))
+
+	// Holds the start of a word that spans chunks, for the writer path
+	carry := p.wordBufferPool.Get()
+	defer p.wordBufferPool.Put(carry)
+
+	// Per-word counts, when the caller asked for them; words are only
+	// assembled and normalized when something consumes them
+	counts := segments.FromContext(ctx)
+	emit := writer != nil || counts != nil
+	yield := parallel.NewYielder()
 
 	// Count words and bytes
 	wordCount := 0
@@ -101,29 +148,26 @@ func (p *Processor) processWordsOptimized(
 	inWord := false
 	wordStart := 0
 	lastWordChar := false
-	contextCheckCounter := 0
 
 	// Loop until we're done or encounter an error
 	for {
-		// Periodically check context for cancellation
-		contextCheckCounter++
-		if contextCheckCounter >= ContextCheckFrequency {
-			select {
-			case <-ctx.Done():
-				p.logger.Warn("Processing cancelled by context", "error", ctx.Err())
-				return wordCount, bytesProcessed, ctx.Err()
-			default:
-				// Continue processing
-			}
-			contextCheckCounter = 0
+		// Check the context before every read so the caller's remaining deadline
+		// budget is honored within one chunk rather than thousands of them
+		if err := ctx.Err(); err != nil {
+			p.logger.Warn("Processing cancelled by context", "error", err)
+			return wordCount, bytesProcessed, err
 		}
 
 		// Read a chunk
 		n, err := reader.Read(chunkBuffer.Bytes)
 		if n > 0 {
 			bytesProcessed += int64(n)
+			yield.Add(n)
 			chunk := chunkBuffer.Bytes[:n]
 
+			// Start of the separator run being copied when separators are preserved
+			sepStart := 0
+
 			// Determine if we can use the fast ASCII path
 			asciiOnly := IsASCIIOnly(chunk)
 
@@ -132,11 +176,14 @@ func (p *Processor) processWordsOptimized(
 				// Fast path for ASCII
 				for i := 0; i < n; i++ {
 					b := chunk[i]
-					isChar := IsASCIIWordChar(b)
+					isChar := p.classifier.IsASCIIWordChar(b)
 
 					if isChar {
 						// Start of a word
 						if !inWord {
+							if err := p.writeSeparator(writer, chunk[sepStart:i]); err != nil {
+								return wordCount, bytesProcessed, err
+							}
 							wordStart = i
 							inWord = true
 						}
@@ -147,31 +194,33 @@ func (p *Processor) processWordsOptimized(
 							wordCount++
 
 							// Write the word if needed
-							if writer != nil {
-								wb := p.wordBufferPool.Get()
-								wb.Bytes = append(wb.Bytes, chunk[wordStart:i]...)
-								normalized := p.normalizer.Normalize(string(wb.Bytes))
-								writer.Write([]byte(normalized + " "))
-								p.wordBufferPool.Put(wb)
+							if emit {
+								if err := p.writeWord(writer, counts, carry, chunk[wordStart:i]); err != nil {
+									return wordCount, bytesProcessed, err
+								}
 							}
 
+							sepStart = i
 							inWord = false
 						}
 					}
 				}
 
 				// Update for the next chunk
-				lastWordChar = n > 0 && IsASCIIWordChar(chunk[n-1])
+				lastWordChar = n > 0 && p.classifier.IsASCIIWordChar(chunk[n-1])
 			} else {
 				// Slower path for non-ASCII
 				i := 0
 				for i < n {
 					// Fix: Use blank identifier for unused variable
-					_, size, isChar := HandleUTF8(chunk, i)
+					_, size, isChar := p.classifier.HandleUTF8(chunk, i)
 
 					if isChar {
 						// Start of a word
 						if !inWord {
+							if err := p.writeSeparator(writer, chunk[sepStart:i]); err != nil {
+								return wordCount, bytesProcessed, err
+							}
 							wordStart = i
 							inWord = true
 						}
@@ -182,14 +231,13 @@ func (p *Processor) processWordsOptimized(
 							wordCount++
 
 							// Write the word if needed
-							if writer != nil {
-								wb := p.wordBufferPool.Get()
-								wb.Bytes = append(wb.Bytes, chunk[wordStart:i]...)
-								normalized := p.normalizer.Normalize(string(wb.Bytes))
-								writer.Write([]byte(normalized + " "))
-								p.wordBufferPool.Put(wb)
+							if emit {
+								if err := p.writeWord(writer, counts, carry, chunk[wordStart:i]); err != nil {
+									return wordCount, bytesProcessed, err
+								}
 							}
 
+							sepStart = i
 							inWord = false
 						}
 					}
@@ -200,7 +248,7 @@ func (p *Processor) processWordsOptimized(
 				// Update for the next chunk
 				if n > 0 {
 					// Fix: Use blank identifier for unused variables
-					_, _, isChar := HandleUTF8(chunk, n-1)
+					_, _, isChar := p.classifier.HandleUTF8(chunk, n-1)
 					lastWordChar = isChar
 				}
 			}
@@ -211,16 +259,25 @@ func (p *Processor) processWordsOptimized(
 				wordCount++
 
 				// Write the word if needed
-				if writer != nil {
-					wb := p.wordBufferPool.Get()
-					wb.Bytes = append(wb.Bytes, chunk[wordStart:n]...)
-					normalized := p.normalizer.Normalize(string(wb.Bytes))
-					writer.Write([]byte(normalized + " "))
-					p.wordBufferPool.Put(wb)
+				if emit {
+					if err := p.writeWord(writer, counts, carry, chunk[wordStart:n]); err != nil {
+						return wordCount, bytesProcessed, err
+					}
 				}
 
+				sepStart = n
 				inWord = false
 			}
+
+			// Carry an unfinished word into the next chunk, or flush the trailing separators
+			if inWord {
+				if emit {
+					carry.Bytes = append(carry.Bytes, chunk[wordStart:n]...)
+				}
+				wordStart = 0
+			} else if err := p.writeSeparator(writer, chunk[sepStart:n]); err != nil {
+				return wordCount, bytesProcessed, err
+			}
 		}
 
 		// Handle errors or EOF
@@ -233,6 +290,11 @@ func (p *Processor) processWordsOptimized(
 			// Handle final word if necessary
 			if inWord {
 				wordCount++
+				if emit {
+					if err := p.writeWord(writer, counts, carry, nil); err != nil {
+						return wordCount, bytesProcessed, err
+					}
+				}
 			}
 
 			break
@@ -249,6 +311,45 @@ func (p *Processor) processWordsOptimized(
 	return wordCount, bytesProcessed, nil
 }
 
+// writeWord normalizes a completed word, made of any carried bytes followed by
+// tail, and writes it with the output delimiter unless separators are preserved.
+// Its counts are recorded to counts, and writer may be nil when only they are
+// wanted. It empties carry.
+func (p *Processor) writeWord(writer io.Writer, counts *segments.Writer, carry *WordBuffer, tail []byte) error {
+	word := append(carry.Bytes, tail...)
+	carry.Bytes = word[:0]
+
+	normalized := p.normalizer.Normalize(bytesconv.String(word))
+	if err := counts.Record(len(word), utf8.RuneCountInString(normalized)); err != nil {
+		return err
+	}
+	if writer == nil {
+		return nil
+	}
+
+	out := bytesconv.Bytes(normalized)
+	if !p.preserveSeparators {
+		out = append(out, p.delimiter...)
+	}
+	if _, err := writer.Write(out); err != nil {
+		p.logger.Error("Error writing normalized output", "error", err)
+		return err
+	}
+	return nil
+}
+
+// writeSeparator copies the original bytes between words when separators are preserved
+func (p *Processor) writeSeparator(writer io.Writer, sep []byte) error {
+	if writer == nil || !p.preserveSeparators || len(sep) == 0 {
+		return nil
+	}
+	if _, err := writer.Write(sep); err != nil {
+		p.logger.Error("Error writing normalized output", "error", err)
+		return err
+	}
+	return nil
+}
+
 // ProcessResult holds the result of a parallel word processing operation
 type ProcessResult struct {
 	WordCount int
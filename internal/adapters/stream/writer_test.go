@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// lowerNormalizer keeps the expected output easy to read
+type lowerNormalizer struct{}
+
+func (lowerNormalizer) Normalize(text string) string {
+	return strings.ToLower(text)
+}
+
+func TestProcessStreamWithWriterSeparators(t *testing.T) {
+	cases := []struct {
+		name     string
+		mode     ports.StreamingMode
+		setup    func(*DefaultProcessor)
+		in, want string
+	}{
+		{"line default", ports.LineByLine, func(*DefaultProcessor) {}, "A\r\nB\rC\n\nD", "a\nb\nc\nd\n"},
+		{"line delimiter", ports.LineByLine, func(p *DefaultProcessor) { p.WithOutputDelimiter("|") }, "A\nB", "a|b|"},
+		{"line preserved", ports.LineByLine, func(p *DefaultProcessor) { p.WithPreservedSeparators(true) }, "A\r\nB\rC\n\nD", "a\r\nb\rc\n\nd"},
+		{"word default", ports.WordByWord, func(*DefaultProcessor) {}, "One,  Two\nThree", "one two three "},
+		{"word preserved", ports.WordByWord, func(p *DefaultProcessor) { p.WithPreservedSeparators(true) }, "One,  Two\nThree.", "one,  two\nthree."},
+	}
+
+	for _, c := range cases {
+		// Tiny chunks split lines, words and CRLF pairs across reads
+		p := NewDefaultProcessor(logger.NewNopLogger(), lowerNormalizer{}).WithChunkSize(3)
+		c.setup(p)
+
+		var out bytes.Buffer
+		if _, err := p.ProcessStreamWithWriter(context.Background(), iotest.OneByteReader(strings.NewReader(c.in)), &out, c.mode); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != c.want {
+			t.Errorf("%s: wrote %q, want %q", c.name, out.String(), c.want)
+		}
+	}
+}
+
+// identityNormalizer returns its input, so under the zerocopy tag the
+// normalized text shares memory with the processor's read buffer
+type identityNormalizer struct{}
+
+func (identityNormalizer) Normalize(text string) string {
+	return text
+}
+
+// TestOutputSurvivesBufferReuse checks that output written from one chunk is
+// not changed by reading the next chunk into the same buffer, which would
+// happen if a zero-copy string outlived its bytes
+func TestOutputSurvivesBufferReuse(t *testing.T) {
+	text := strings.Repeat("alpha beta\ngamma delta epsilon\n", 200)
+
+	for _, mode := range []ports.StreamingMode{ports.ChunkByChunk, ports.LineByLine, ports.WordByWord} {
+		for _, parallel := range []bool{false, true} {
+			p := NewDefaultProcessor(logger.NewNopLogger(), identityNormalizer{}).
+				WithChunkSize(7).WithParallelProcessing(parallel).WithPreservedSeparators(true)
+			var out bytes.Buffer
+			if _, err := p.ProcessStreamWithWriter(context.Background(), strings.NewReader(text), &out, mode); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != text {
+				t.Errorf("mode %d, parallel %v: output differs from input", mode, parallel)
+			}
+		}
+	}
+}
+
+func TestParallelWriterMatchesSequential(t *testing.T) {
+	text := strings.Repeat("Alpha beta, Gamma delta\nEpsilon zeta eta theta iota\n", 500) + "Kappa"
+
+	for _, mode := range []ports.StreamingMode{ports.LineByLine, ports.WordByWord} {
+		var want, got bytes.Buffer
+		sequential := NewDefaultProcessor(logger.NewNopLogger(), lowerNormalizer{}).WithChunkSize(61)
+		wantCount, err := sequential.ProcessStreamWithWriter(context.Background(), strings.NewReader(text), &want, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		concurrent := NewDefaultProcessor(logger.NewNopLogger(), lowerNormalizer{}).WithChunkSize(61).WithParallelProcessing(true)
+		gotCount, err := concurrent.ProcessStreamWithWriter(context.Background(), strings.NewReader(text), &got, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if gotCount != wantCount || got.String() != want.String() {
+			t.Errorf("mode %d: parallel wrote %d bytes (count %d), sequential %d bytes (count %d)",
+				mode, got.Len(), gotCount, want.Len(), wantCount)
+		}
+	}
+}
+
+// failingWriter accepts limit bytes and then fails every write
+type failingWriter struct {
+	limit int
+}
+
+var errWriteFailed = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errWriteFailed
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestWriterErrorsPropagate(t *testing.T) {
+	text := strings.Repeat("some words on a line\n", 20000)
+
+	for _, mode := range []ports.StreamingMode{ports.ChunkByChunk, ports.LineByLine, ports.WordByWord} {
+		for _, useParallel := range []bool{false, true} {
+			p := NewDefaultProcessor(logger.NewNopLogger(), lowerNormalizer{}).WithParallelProcessing(useParallel)
+			_, err := p.ProcessStreamWithWriter(context.Background(), strings.NewReader(text), &failingWriter{limit: 1000}, mode)
+			if !errors.Is(err, errWriteFailed) {
+				t.Errorf("mode %d, parallel %v: got %v, want the write error", mode, useParallel, err)
+			}
+		}
+	}
+}
+
+func TestProcessStreamWithCounts(t *testing.T) {
+	cases := []struct {
+		name string
+		mode ports.StreamingMode
+		in   string
+		want string
+	}{
+		// The CRLF is split across reads and the blank line keeps its record
+		{"line", ports.LineByLine, "A\r\nBé\n\nC", `{"segment":0,"bytes":1,"length":1}
+{"segment":1,"bytes":3,"length":2}
+{"segment":2,"bytes":0,"length":0}
+{"segment":3,"bytes":1,"length":1}
+`},
+		{"word", ports.WordByWord, "One,  Two.", `{"segment":0,"bytes":3,"length":3}
+{"segment":1,"bytes":3,"length":3}
+`},
+		{"chunk", ports.ChunkByChunk, "abcd", `{"segment":0,"bytes":1,"length":1}
+{"segment":1,"bytes":1,"length":1}
+{"segment":2,"bytes":1,"length":1}
+{"segment":3,"bytes":1,"length":1}
+`},
+	}
+
+	for _, c := range cases {
+		p := NewDefaultProcessor(logger.NewNopLogger(), lowerNormalizer{}).WithChunkSize(3).WithParallelProcessing(true)
+
+		// Counts alongside the text
+		var text, counts bytes.Buffer
+		n, err := p.ProcessStreamWithCounts(context.Background(), iotest.OneByteReader(strings.NewReader(c.in)), &text, &counts, c.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts.String() != c.want {
+			t.Errorf("%s: counts %q, want %q", c.name, counts.String(), c.want)
+		}
+		if text.Len() == 0 {
+			t.Errorf("%s: expected the normalized text alongside the counts", c.name)
+		}
+
+		// Counts instead of the text
+		counts.Reset()
+		m, err := p.ProcessStreamWithCounts(context.Background(), iotest.OneByteReader(strings.NewReader(c.in)), nil, &counts, c.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts.String() != c.want || m != n {
+			t.Errorf("%s: counts only wrote %q (count %d), want %q (count %d)", c.name, counts.String(), m, c.want, n)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package transform
+
+import "github.com/baditaflorin/go_length_similarity/internal/ports"
+
+// JoinHyphenation joins words that a hyphen split across lines, as OCR and
+// PDF extraction leave them: a letter, a hyphen, optional spaces or tabs, a
+// line break (LF, CRLF or CR), optional indentation and another letter become
+// the two letters side by side. "exam-\nple" becomes "example", so the split
+// no longer counts as two words on one side only. A soft hyphen (U+00AD) at
+// the end of a line joins the same way. Hyphens inside a line and hyphens
+// before a blank line or a non-letter are kept.
+func JoinHyphenation() ports.Transform {
+	return byteTransform{name: "join_hyphenation", newMachine: func() machine { return &joinHyphenation{} }}
+}
+
+// Phases of a possible line-end hyphenation
+const (
+	hyphenNone  = iota
+	hyphenSoft  // after the first byte of a possible soft hyphen
+	hyphenSeen  // after the hyphen, before the line break
+	hyphenCR    // after a CR, which an LF may complete
+	hyphenBreak // after the line break, in the next line's indentation
+)
+
+type joinHyphenation struct {
+	// afterLetter is set when the last byte written was part of a letter
+	afterLetter bool
+	phase       int
+	// held is the hyphen and whitespace dropped if a letter follows
+	held []byte
+}
+
+func (m *joinHyphenation) step(out []byte, b byte) []byte {
+	switch m.phase {
+	case hyphenNone:
+		if b == '-' && m.afterLetter {
+			m.phase = hyphenSeen
+			m.held = append(m.held[:0], b)
+			return out
+		}
+		if b == softHyphen[0] && m.afterLetter {
+			m.phase = hyphenSoft
+			m.held = append(m.held[:0], b)
+			return out
+		}
+		m.afterLetter = isLetterByte(b)
+		return append(out, b)
+	case hyphenSoft:
+		if b == softHyphen[1] {
+			m.phase = hyphenSeen
+			m.held = append(m.held, b)
+			return out
+		}
+	case hyphenSeen:
+		switch b {
+		case ' ', '\t':
+			m.held = append(m.held, b)
+			return out
+		case '\n':
+			m.phase = hyphenBreak
+			m.held = append(m.held, b)
+			return out
+		case '\r':
+			m.phase = hyphenCR
+			m.held = append(m.held, b)
+			return out
+		}
+	case hyphenCR, hyphenBreak:
+		switch {
+		case b == '\n' && m.phase == hyphenCR, b == ' ', b == '\t':
+			m.phase = hyphenBreak
+			m.held = append(m.held, b)
+			return out
+		case isLetterByte(b):
+			m.phase = hyphenNone
+			m.held = m.held[:0]
+			m.afterLetter = true
+			return append(out, b)
+		}
+	}
+
+	// Not a line-end hyphenation: write what was held back and start over
+	out = m.flush(out)
+	return m.step(out, b)
+}
+
+func (m *joinHyphenation) flush(out []byte) []byte {
+	out = append(out, m.held...)
+	m.held = m.held[:0]
+	m.phase = hyphenNone
+	m.afterLetter = false
+	return out
+}
+
+// softHyphen is the UTF-8 encoding of U+00AD
+var softHyphen = [2]byte{0xC2, 0xAD}
+
+// isLetterByte reports whether b is an ASCII letter or a byte of a multi-byte
+// rune, which in text is almost always a letter
+func isLetterByte(b byte) bool {
+	return b >= 0x80 || (b|0x20 >= 'a' && b|0x20 <= 'z')
+}
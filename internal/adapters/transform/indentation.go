@@ -0,0 +1,73 @@
+package transform
+
+import (
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// ExpandTabs replaces each tab with spaces up to the next multiple of width
+// columns, like expand(1). Columns count runes and restart after every line
+// break. A width of 0 removes tabs; a negative width fails Validate.
+func ExpandTabs(width int) ports.Transform {
+	t := byteTransform{name: "expand_tabs", newMachine: func() machine { return &expandTabs{width: width} }}
+	if width < 0 {
+		t.err = domain.NewConfigError("tabWidth", width, "must not be negative")
+	}
+	return t
+}
+
+type expandTabs struct {
+	width  int
+	column int
+}
+
+func (m *expandTabs) step(out []byte, b byte) []byte {
+	switch {
+	case b == '\t':
+		if m.width == 0 {
+			return out
+		}
+		spaces := m.width - m.column%m.width
+		m.column += spaces
+		for ; spaces > 0; spaces-- {
+			out = append(out, ' ')
+		}
+		return out
+	case b == '\n' || b == '\r':
+		m.column = 0
+	case b&0xC0 != 0x80:
+		// Continuation bytes belong to the rune already counted
+		m.column++
+	}
+	return append(out, b)
+}
+
+func (m *expandTabs) flush(out []byte) []byte {
+	return out
+}
+
+// StripIndentation drops the spaces and tabs that start each line, so
+// re-indented code or reflowed text compares on its content
+func StripIndentation() ports.Transform {
+	return byteTransform{name: "strip_indentation", newMachine: func() machine { return &stripIndentation{atLineStart: true} }}
+}
+
+type stripIndentation struct {
+	atLineStart bool
+}
+
+func (m *stripIndentation) step(out []byte, b byte) []byte {
+	switch {
+	case b == '\n' || b == '\r':
+		m.atLineStart = true
+	case m.atLineStart && (b == ' ' || b == '\t'):
+		return out
+	default:
+		m.atLineStart = false
+	}
+	return append(out, b)
+}
+
+func (m *stripIndentation) flush(out []byte) []byte {
+	return out
+}
@@ -0,0 +1,39 @@
+package transform
+
+import "github.com/baditaflorin/go_length_similarity/internal/ports"
+
+// LineEndings converts CRLF and lone CR line endings to LF, so files from
+// different platforms compare on content rather than on line terminators
+func LineEndings() ports.Transform {
+	return byteTransform{name: "line_endings", newMachine: func() machine { return &lineEndings{} }}
+}
+
+type lineEndings struct {
+	pendingCR bool
+}
+
+func (m *lineEndings) step(out []byte, b byte) []byte {
+	switch {
+	case b == '\r':
+		if m.pendingCR {
+			out = append(out, '\n')
+		}
+		m.pendingCR = true
+		return out
+	case b == '\n':
+		m.pendingCR = false
+		return append(out, '\n')
+	case m.pendingCR:
+		m.pendingCR = false
+		out = append(out, '\n')
+	}
+	return append(out, b)
+}
+
+func (m *lineEndings) flush(out []byte) []byte {
+	if m.pendingCR {
+		m.pendingCR = false
+		out = append(out, '\n')
+	}
+	return out
+}
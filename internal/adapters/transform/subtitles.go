@@ -0,0 +1,135 @@
+package transform
+
+import (
+	"bytes"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// maxSubtitleLine bounds how much of a line is held back while it may still
+// be a cue number or timing line; longer lines pass through unchanged
+const maxSubtitleLine = 1024
+
+// StripSubtitles removes the SRT and WebVTT structure around the spoken text,
+// so transcripts compare on their words: the WEBVTT header line, lines that
+// hold only a cue number, and timing lines such as
+// "00:00:01,000 --> 00:00:04,000 align:start" are dropped with their line
+// break. Every other line, including blank ones, is kept.
+func StripSubtitles() ports.Transform {
+	return byteTransform{name: "strip_subtitles", newMachine: func() machine { return &stripSubtitles{firstLine: true} }}
+}
+
+type stripSubtitles struct {
+	firstLine bool
+	// line holds the current line while it may still be dropped
+	line []byte
+	// passing is set once the current line is known to be kept
+	passing bool
+}
+
+func (m *stripSubtitles) step(out []byte, b byte) []byte {
+	if b == '\n' {
+		if m.passing || !isSubtitleLine(m.line, m.firstLine) {
+			out = append(append(out, m.line...), b)
+		}
+		m.line = m.line[:0]
+		m.passing = false
+		m.firstLine = false
+		return out
+	}
+	if m.passing {
+		return append(out, b)
+	}
+
+	m.line = append(m.line, b)
+	if len(m.line) > maxSubtitleLine || !mayBeSubtitleLine(m.line, m.firstLine) {
+		out = append(out, m.line...)
+		m.line = m.line[:0]
+		m.passing = true
+	}
+	return out
+}
+
+func (m *stripSubtitles) flush(out []byte) []byte {
+	if !m.passing && !isSubtitleLine(m.line, m.firstLine) {
+		out = append(out, m.line...)
+	}
+	m.line = m.line[:0]
+	return out
+}
+
+var (
+	utf8BOM     = []byte("\xEF\xBB\xBF")
+	vttHeader   = []byte("WEBVTT")
+	timingArrow = []byte("-->")
+)
+
+// mayBeSubtitleLine reports whether a line starting with prefix could still
+// turn out to be a header, cue number or timing line
+func mayBeSubtitleLine(prefix []byte, firstLine bool) bool {
+	if firstLine {
+		rest := bytes.TrimPrefix(prefix, utf8BOM)
+		if bytes.HasPrefix(rest, vttHeader) || bytes.HasPrefix(vttHeader, rest) || bytes.HasPrefix(utf8BOM, prefix) {
+			return true
+		}
+	}
+	if bytes.Contains(prefix, timingArrow) {
+		return true
+	}
+	for _, c := range prefix {
+		if !isTimingByte(c) && c != '-' && c != '>' && c != ' ' && c != '\t' && c != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// isSubtitleLine reports whether a whole line, without its LF, is a header,
+// cue number or timing line
+func isSubtitleLine(line []byte, firstLine bool) bool {
+	line = bytes.TrimSpace(line)
+	if firstLine {
+		line = bytes.TrimPrefix(line, utf8BOM)
+		if bytes.HasPrefix(line, vttHeader) {
+			return true
+		}
+	}
+	if len(line) == 0 {
+		return false
+	}
+
+	if i := bytes.Index(line, timingArrow); i >= 0 {
+		start := bytes.TrimSpace(line[:i])
+		end := bytes.TrimSpace(line[i+len(timingArrow):])
+		if j := bytes.IndexAny(end, " \t"); j >= 0 {
+			// Cue settings follow the end time
+			end = end[:j]
+		}
+		return isTimestamp(start) && isTimestamp(end)
+	}
+
+	for _, c := range line {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isTimestamp reports whether s looks like an SRT or WebVTT timestamp: digits
+// and at least one colon, with a comma or period before the milliseconds
+func isTimestamp(s []byte) bool {
+	if len(s) == 0 || bytes.IndexByte(s, ':') < 0 {
+		return false
+	}
+	for _, c := range s {
+		if !isTimingByte(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isTimingByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == ':' || c == '.' || c == ','
+}
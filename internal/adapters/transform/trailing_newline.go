@@ -0,0 +1,36 @@
+package transform
+
+import "github.com/baditaflorin/go_length_similarity/internal/ports"
+
+// TrimTrailingNewline drops one line break (LF, CRLF or CR) at the very end
+// of the text, so a file saved with a final newline compares equal to the
+// same file without one. Only the last break is dropped: two trailing breaks
+// still differ from none by one.
+func TrimTrailingNewline() ports.Transform {
+	return byteTransform{name: "trim_trailing_newline", newMachine: func() machine { return &trimTrailingNewline{} }}
+}
+
+type trimTrailingNewline struct {
+	// held is the line break that may end the text: "", "\r", "\n" or "\r\n"
+	held []byte
+}
+
+func (m *trimTrailingNewline) step(out []byte, b byte) []byte {
+	switch {
+	case b == '\n' && len(m.held) == 1 && m.held[0] == '\r':
+		m.held = append(m.held, b)
+		return out
+	case b == '\n' || b == '\r':
+		out = append(out, m.held...)
+		m.held = append(m.held[:0], b)
+		return out
+	}
+	out = append(out, m.held...)
+	m.held = m.held[:0]
+	return append(out, b)
+}
+
+func (m *trimTrailingNewline) flush(out []byte) []byte {
+	m.held = m.held[:0]
+	return out
+}
@@ -0,0 +1,148 @@
+// Package transform provides the ports.Transform implementations that rewrite
+// raw text before normalization. Each transform is a byte-level state machine,
+// so the string and stream forms agree no matter where a stream is split.
+package transform
+
+import (
+	"io"
+	"strings"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// machine is the per-text state of a transform
+type machine interface {
+	// step appends the output for b to out
+	step(out []byte, b byte) []byte
+	// flush appends any output held back at the end of the text
+	flush(out []byte) []byte
+}
+
+// byteTransform adapts a machine factory to ports.Transform
+type byteTransform struct {
+	name       string
+	newMachine func() machine
+	// err is reported by Validate when the transform was built with bad parameters
+	err error
+}
+
+// Name implements ports.Transform
+func (t byteTransform) Name() string {
+	return t.name
+}
+
+// Apply implements ports.Transform
+func (t byteTransform) Apply(text string) string {
+	m := t.newMachine()
+	out := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		out = m.step(out, text[i])
+	}
+	return string(m.flush(out))
+}
+
+// Reader implements ports.Transform
+func (t byteTransform) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, m: t.newMachine()}
+}
+
+// reader runs a machine over a stream
+type reader struct {
+	r       io.Reader
+	m       machine
+	in      []byte
+	pending []byte
+	err     error
+}
+
+// Read implements io.Reader
+func (tr *reader) Read(p []byte) (int, error) {
+	for len(tr.pending) == 0 {
+		if tr.err != nil {
+			return 0, tr.err
+		}
+
+		if tr.in == nil {
+			tr.in = make([]byte, max(len(p), 512))
+		}
+		n, err := tr.r.Read(tr.in)
+		out := tr.pending[:0]
+		for _, b := range tr.in[:n] {
+			out = tr.m.step(out, b)
+		}
+		if err == io.EOF {
+			out = tr.m.flush(out)
+		}
+		tr.pending = out
+		tr.err = err
+	}
+
+	n := copy(p, tr.pending)
+	tr.pending = tr.pending[n:]
+	return n, nil
+}
+
+// Validate reports the first transform built with invalid parameters
+func Validate(transforms []ports.Transform) error {
+	for _, t := range transforms {
+		if bt, ok := t.(byteTransform); ok && bt.err != nil {
+			return bt.err
+		}
+	}
+	return nil
+}
+
+// Apply runs every transform over text in order
+func Apply(text string, transforms []ports.Transform) string {
+	for _, t := range transforms {
+		text = t.Apply(text)
+	}
+	return text
+}
+
+// Readers wraps r with every transform in order
+func Readers(r io.Reader, transforms []ports.Transform) io.Reader {
+	for _, t := range transforms {
+		r = t.Reader(r)
+	}
+	return r
+}
+
+// normalizer applies transforms before delegating to another normalizer
+type normalizer struct {
+	transforms []ports.Transform
+	next       ports.Normalizer
+}
+
+// Normalizer returns a normalizer that runs the transforms before next.
+// With no transforms it returns next unchanged.
+func Normalizer(next ports.Normalizer, transforms []ports.Transform) ports.Normalizer {
+	if len(transforms) == 0 {
+		return next
+	}
+	return &normalizer{transforms: transforms, next: next}
+}
+
+// Normalize implements ports.Normalizer
+func (n *normalizer) Normalize(text string) string {
+	return n.next.Normalize(Apply(text, n.transforms))
+}
+
+// AppendNormalized implements ports.AppendNormalizer, appending without an
+// extra copy when next supports it
+func (n *normalizer) AppendNormalized(dst []byte, text string) []byte {
+	text = Apply(text, n.transforms)
+	if a, ok := n.next.(ports.AppendNormalizer); ok {
+		return a.AppendNormalized(dst, text)
+	}
+	return append(dst, n.next.Normalize(text)...)
+}
+
+// Names lists the transform names, for result details
+func Names(transforms []ports.Transform) string {
+	names := make([]string, len(transforms))
+	for i, t := range transforms {
+		names[i] = t.Name()
+	}
+	return strings.Join(names, ",")
+}
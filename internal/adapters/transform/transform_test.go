@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+func TestLineEndings(t *testing.T) {
+	cases := map[string]string{
+		"a\r\nb\r\n":   "a\nb\n",
+		"a\rb\r":       "a\nb\n",
+		"a\r\r\nb":     "a\n\nb",
+		"unix\nonly\n": "unix\nonly\n",
+	}
+	for in, want := range cases {
+		if got := LineEndings().Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReaderMatchesApplyAcrossSplits(t *testing.T) {
+	text := strings.Repeat("line one\r\nline two\rline three\n\r\n", 50)
+	want := LineEndings().Apply(text)
+
+	// One byte per read splits every CRLF pair
+	got, err := io.ReadAll(LineEndings().Reader(iotest.OneByteReader(strings.NewReader(text))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("stream and string forms differ")
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	cases := []struct {
+		width   int
+		in, out string
+	}{
+		{4, "\tx", "    x"},
+		{4, "ab\tx", "ab  x"},
+		{4, "é\tx", "é   x"},
+		{4, "abcd\tx\n\ty", "abcd    x\n    y"},
+		{0, "\ta\tb", "ab"},
+	}
+	for _, c := range cases {
+		if got := ExpandTabs(c.width).Apply(c.in); got != c.out {
+			t.Errorf("ExpandTabs(%d).Apply(%q) = %q, want %q", c.width, c.in, got, c.out)
+		}
+	}
+	if err := Validate([]ports.Transform{LineEndings(), ExpandTabs(-1)}); err == nil {
+		t.Error("negative tab width should fail validation")
+	}
+}
+
+func TestStripIndentation(t *testing.T) {
+	in := "  func f() {\n\t\treturn  1\n\t}\r\n   \nend"
+	want := "func f() {\nreturn  1\n}\r\n\nend"
+	if got := StripIndentation().Apply(in); got != want {
+		t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+	}
+}
+
+func TestJoinHyphenation(t *testing.T) {
+	cases := map[string]string{
+		"exam-\nple":                "example",
+		"exam-  \r\n    ple text":   "example text",
+		"exam-\rple":                "example",
+		"naïve-\nté":                "naïveté",
+		"well-known":                "well-known",
+		"list -\nitem":              "list -\nitem",
+		"page 12-\n13":              "page 12-\n13",
+		"end-\n\nnext":              "end-\n\nnext",
+		"dash--\nword":              "dash--\nword",
+		"trailing-\n":               "trailing-\n",
+		"re-\nflowed and con-\ncat": "reflowed and concat",
+		"soft\u00ad\nhyphen":        "softhyphen",
+		"soft\u00adhyphen":          "soft\u00adhyphen",
+		"non\u00a0breaking-\nspace": "non\u00a0breakingspace",
+	}
+	for in, want := range cases {
+		if got := JoinHyphenation().Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+		got, err := io.ReadAll(JoinHyphenation().Reader(iotest.OneByteReader(strings.NewReader(in))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Reader(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStripSubtitles(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:04,000\r\nHello there.\r\n\r\n2\r\n00:00:05,500 --> 00:00:07,250\r\nGeneral Kenobi!\r\n"
+	vtt := "\xEF\xBB\xBFWEBVTT - greetings\n\n00:01.000 --> 00:04.000 align:start position:10%\nHello there.\n\nintro\n00:05.500 --> 00:07.250\nGeneral Kenobi!"
+	cases := map[string]string{
+		srt:                           "Hello there.\r\n\r\nGeneral Kenobi!\r\n",
+		vtt:                           "\nHello there.\n\nintro\nGeneral Kenobi!",
+		"The year 1999 --> 2000\n":    "The year 1999 --> 2000\n",
+		"Room 101\n42\nis the answer": "Room 101\nis the answer",
+		"Intro\nWEBVTT in the middle": "Intro\nWEBVTT in the middle",
+	}
+	for in, want := range cases {
+		if got := StripSubtitles().Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+		got, err := io.ReadAll(StripSubtitles().Reader(iotest.OneByteReader(strings.NewReader(in))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Reader(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTrimTrailingNewline(t *testing.T) {
+	cases := map[string]string{
+		"a\nb\n":   "a\nb",
+		"a\nb\r\n": "a\nb",
+		"a\nb\r":   "a\nb",
+		"a\nb":     "a\nb",
+		"a\n\n":    "a\n",
+		"a\r\r":    "a\r",
+		"\n":       "",
+		"":         "",
+	}
+	for in, want := range cases {
+		if got := TrimTrailingNewline().Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+		got, err := io.ReadAll(TrimTrailingNewline().Reader(iotest.OneByteReader(strings.NewReader(in))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Reader(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Package bytesconv converts between byte slices and strings on the streaming
+// hot paths. By default every conversion copies, as Go's own conversions do.
+// Building with the zerocopy tag makes them share memory instead, which saves
+// an allocation and a copy per line, word or chunk:
+//
+//	go build -tags zerocopy ./...
+//
+// Under zerocopy, a string from String is only valid while its bytes are left
+// unchanged, and the bytes from Bytes must never be written. The processors
+// only convert a buffer after it is filled and finish with the result before
+// reading into it again, so the built-in normalizers are safe. A custom
+// normalizer must not keep its input string, in a cache for example, beyond
+// the call.
+//
+// A zerocopy build can still switch back to copying at run time with
+// SetEnabled(false), so sharing can be rolled out behind a feature flag.
+package bytesconv
@@ -0,0 +1,94 @@
+package bytesconv
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "a", "hello, world", "héllo wörld ✓", string([]byte{0xff, 0xfe})} {
+		if got := String([]byte(s)); got != s {
+			t.Errorf("String(%q) = %q", s, got)
+		}
+		b := Bytes(s)
+		if string(b) != s || len(b) != len(s) {
+			t.Errorf("Bytes(%q) = %q", s, b)
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if s := String(nil); s != "" {
+		t.Errorf("String(nil) = %q", s)
+	}
+	if s := String([]byte{}); s != "" {
+		t.Errorf("String([]byte{}) = %q", s)
+	}
+	if b := Bytes(""); len(b) != 0 {
+		t.Errorf("Bytes(\"\") = %q", b)
+	}
+}
+
+func TestSharing(t *testing.T) {
+	buf := []byte("mutable")
+	s := String(buf)
+	shared := unsafe.StringData(s) == unsafe.SliceData(buf)
+	if shared != ZeroCopy {
+		t.Fatalf("String shares memory = %v, want %v", shared, ZeroCopy)
+	}
+
+	// A copied string keeps its value when the buffer is reused; a shared one
+	// sees the change
+	buf[0] = 'M'
+	want := "mutable"
+	if ZeroCopy {
+		want = "Mutable"
+	}
+	if s != want {
+		t.Errorf("after reuse s = %q, want %q", s, want)
+	}
+}
+
+func TestBytesAppendCopies(t *testing.T) {
+	s := "immutable"
+	b := Bytes(s)
+	if ZeroCopy && cap(b) != len(b) {
+		t.Fatalf("cap = %d, want %d", cap(b), len(b))
+	}
+	b = append(b, '!')
+	b[0] = 'I'
+	if s != "immutable" {
+		t.Errorf("appending to Bytes changed the string to %q", s)
+	}
+}
+
+func TestConversionsDoNotAllocate(t *testing.T) {
+	if !ZeroCopy {
+		t.Skip("conversions copy without the zerocopy tag")
+	}
+	buf := []byte("a line of text that would otherwise be copied")
+	var sink string
+	allocs := testing.AllocsPerRun(100, func() {
+		sink = String(buf)
+		_ = Bytes(sink)
+	})
+	if allocs != 0 {
+		t.Errorf("conversions allocated %v times per run", allocs)
+	}
+}
+
+func TestSetEnabled(t *testing.T) {
+	defer SetEnabled(Enabled())
+	SetEnabled(false)
+	buf := []byte("mutable")
+	s := String(buf)
+	buf[0] = 'M'
+	if Enabled() || s != "mutable" {
+		t.Errorf("disabled: Enabled() = %v, s = %q; want a copy", Enabled(), s)
+	}
+
+	SetEnabled(true)
+	if Enabled() != ZeroCopy {
+		t.Errorf("enabled: Enabled() = %v, want %v", Enabled(), ZeroCopy)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !zerocopy
+
+package bytesconv
+
+// ZeroCopy reports whether conversions share memory instead of copying
+const ZeroCopy = false
+
+// SetEnabled has no effect: sharing needs the zerocopy tag
+func SetEnabled(enable bool) {}
+
+// Enabled reports whether conversions currently share memory, which they
+// never do without the zerocopy tag
+func Enabled() bool {
+	return false
+}
+
+// String returns b as a string
+func String(b []byte) string {
+	return string(b)
+}
+
+// Bytes returns s as a byte slice
+func Bytes(s string) []byte {
+	return []byte(s)
+}
@@ -0,0 +1,49 @@
+//go:build zerocopy
+
+package bytesconv
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// ZeroCopy reports whether conversions share memory instead of copying
+const ZeroCopy = true
+
+// disabled switches conversions back to copying at run time
+var disabled atomic.Bool
+
+// SetEnabled turns sharing on or off for conversions made after the call. It
+// is on by default.
+func SetEnabled(enable bool) {
+	disabled.Store(!enable)
+}
+
+// Enabled reports whether conversions currently share memory
+func Enabled() bool {
+	return !disabled.Load()
+}
+
+// String returns a string sharing b's memory. b must not change while the
+// string is in use.
+func String(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if disabled.Load() {
+		return string(b)
+	}
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// Bytes returns a byte slice sharing s's memory. Its capacity equals its
+// length, so appending always copies; the slice itself must never be written.
+func Bytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	if disabled.Load() {
+		return []byte(s)
+	}
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
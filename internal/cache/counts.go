@@ -0,0 +1,117 @@
+// Package cache provides bounded caches shared by the similarity calculators.
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// key identifies a text by digest and length; the length guards against most collisions
+type key struct {
+	digest string
+	size   int
+}
+
+type entry struct {
+	key   key
+	count int
+}
+
+// CountCache is a concurrency-safe LRU cache of normalized counts keyed by a hash
+// of the raw text. A nil *CountCache is valid and never caches anything.
+type CountCache struct {
+	mu       sync.Mutex
+	capacity int
+	hasher   ports.Hasher
+	order    *list.List
+	items    map[key]*list.Element
+}
+
+// NewCountCache creates a cache holding at most capacity texts, keyed with h.
+// A nil h uses the default hasher. A capacity below 1 returns nil, which disables caching.
+func NewCountCache(capacity int, h ports.Hasher) *CountCache {
+	if capacity < 1 {
+		return nil
+	}
+	if h == nil {
+		h = hasher.Default()
+	}
+	return &CountCache{
+		capacity: capacity,
+		hasher:   h,
+		order:    list.New(),
+		items:    make(map[key]*list.Element, capacity),
+	}
+}
+
+func (c *CountCache) keyFor(text string) key {
+	return key{digest: string(c.hasher.SumString(text)), size: len(text)}
+}
+
+// Get returns the cached count for text
+func (c *CountCache) Get(text string) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	k := c.keyFor(text)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[k]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*entry).count, true
+}
+
+// Put stores the count for text, evicting the least recently used entry when full
+func (c *CountCache) Put(text string, count int) {
+	if c == nil {
+		return
+	}
+
+	k := c.keyFor(text)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[k]; ok {
+		el.Value.(*entry).count = count
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[k] = c.order.PushFront(&entry{key: k, count: count})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
+
+// Len returns the number of cached texts
+func (c *CountCache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear drops every cached count
+func (c *CountCache) Clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.items)
+}
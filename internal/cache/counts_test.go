@@ -0,0 +1,42 @@
+package cache
+
+import "testing"
+
+func TestCountCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCountCache(2, nil)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a")
+	c.Put("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if n, ok := c.Get("a"); !ok || n != 1 {
+		t.Errorf("Get(a) = %d, %v", n, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestNilCountCacheIsDisabled(t *testing.T) {
+	var c *CountCache = NewCountCache(0, nil)
+	c.Put("a", 1)
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("disabled cache returned a value")
+	}
+}
+
+func TestCountCacheClear(t *testing.T) {
+	c := NewCountCache(4, nil)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Clear()
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("cache kept entries after Clear")
+	}
+
+	var disabled *CountCache
+	disabled.Clear()
+}
@@ -2,11 +2,13 @@ package character
 
 import (
 	"context"
-	"errors"
-	"math"
+	"unicode/utf8"
 
+	"github.com/baditaflorin/go_length_similarity/internal/cache"
 	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 )
 
 // SimilarityConfig holds configuration for the character similarity calculator.
@@ -14,33 +16,58 @@ type SimilarityConfig struct {
 	Threshold    float64
 	MaxDiffRatio float64
 	Precision    int
+	// MinOriginalLength marks results inconclusive instead of scoring them
+	// when the original has fewer normalized characters. 0 disables the guard.
+	MinOriginalLength int
+	// OriginalCacheSize caches the character counts of up to this many originals,
+	// so comparing one source against many candidates only processes the
+	// augmented side. 0 disables the cache.
+	OriginalCacheSize int
+	// Hasher keys the originals cache; nil uses the default (xxhash).
+	Hasher ports.Hasher
 }
 
 // DefaultConfig returns a default configuration.
 func DefaultConfig() SimilarityConfig {
 	return SimilarityConfig{
-		Threshold:    0.7,
-		MaxDiffRatio: 0.3,
+		Threshold:    scoring.DefaultThreshold,
+		MaxDiffRatio: scoring.DefaultMaxDiffRatio,
 		Precision:    2,
 	}
 }
 
 // Validate checks if the configuration is valid.
 func (c SimilarityConfig) Validate() error {
-	if c.Threshold < 0 || c.Threshold > 1 {
-		return errors.New("threshold must be between 0 and 1")
+	if err := domain.ValidateThreshold(c.Threshold); err != nil {
+		return err
 	}
-	if c.MaxDiffRatio <= 0 {
-		return errors.New("maxDiffRatio must be greater than 0")
+	if err := domain.ValidateMaxDiffRatio(c.MaxDiffRatio); err != nil {
+		return err
+	}
+	if c.MinOriginalLength < 0 {
+		return domain.NewConfigError("minOriginalLength", c.MinOriginalLength, "must not be negative")
+	}
+	if c.OriginalCacheSize < 0 {
+		return domain.NewConfigError("originalCacheSize", c.OriginalCacheSize, "must not be negative")
 	}
 	return nil
 }
 
+// scoringConfig returns the shared scoring parameters.
+func (c SimilarityConfig) scoringConfig() scoring.Config {
+	return scoring.Config{
+		Threshold:    c.Threshold,
+		MaxDiffRatio: c.MaxDiffRatio,
+		Precision:    c.Precision,
+	}
+}
+
 // Calculator implements the character-level similarity calculation.
 type Calculator struct {
 	config     SimilarityConfig
 	logger     ports.Logger
 	normalizer ports.Normalizer
+	originals  *cache.CountCache
 }
 
 // NewCalculator creates a new character similarity calculator.
@@ -53,9 +80,15 @@ func NewCalculator(config SimilarityConfig, logger ports.Logger, normalizer port
 		config:     config,
 		logger:     logger,
 		normalizer: normalizer,
+		originals:  cache.NewCountCache(config.OriginalCacheSize, config.Hasher),
 	}, nil
 }
 
+// Release empties the originals cache
+func (c *Calculator) Release() {
+	c.originals.Clear()
+}
+
 // Compute calculates the character-level similarity between two texts.
 func (c *Calculator) Compute(ctx context.Context, original, augmented string) domain.Result {
 	c.logger.Debug("Starting character similarity computation",
@@ -65,11 +98,11 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 
 	details := make(map[string]interface{})
 
-	normalizedOriginal := c.normalizer.Normalize(original)
+	origLen := c.originalLength(ctx, original)
 	normalizedAugmented := c.normalizer.Normalize(augmented)
+	probe.FromContext(ctx).RecordBuffer(len(normalizedAugmented))
 
-	c.logger.Debug("Normalized texts",
-		"normalizedOriginal", normalizedOriginal,
+	c.logger.Debug("Normalized augmented text",
 		"normalizedAugmented", normalizedAugmented,
 	)
 
@@ -88,18 +121,55 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 		// continue
 	}
 
-	origRunes := []rune(normalizedOriginal)
-	augRunes := []rune(normalizedAugmented)
-	origLen := len(origRunes)
-	augLen := len(augRunes)
+	augLen := utf8.RuneCountInString(normalizedAugmented)
 
 	c.logger.Debug("Computed character counts",
 		"original_length", origLen,
 		"augmented_length", augLen,
 	)
 
+	return c.ComputeCounts(origLen, augLen)
+}
+
+// originalLength returns the normalized character count of original, using the
+// originals cache when it is enabled.
+func (c *Calculator) originalLength(ctx context.Context, original string) int {
+	if n, ok := c.originals.Get(original); ok {
+		c.logger.Debug("Original character count served from cache", "original_length", n)
+		return n
+	}
+
+	normalizedOriginal := c.normalizer.Normalize(original)
+	probe.FromContext(ctx).RecordBuffer(len(normalizedOriginal))
+	c.logger.Debug("Normalized original text", "normalizedOriginal", normalizedOriginal)
+
+	n := utf8.RuneCountInString(normalizedOriginal)
+	c.originals.Put(original, n)
+	return n
+}
+
+// CountRunes returns the normalized character count of text as Compute counts
+// it, using buf as scratch space. It returns buf, possibly grown, so a caller
+// that keeps it avoids allocating on later calls.
+func (c *Calculator) CountRunes(text string, buf []byte) (int, []byte) {
+	if a, ok := c.normalizer.(ports.AppendNormalizer); ok {
+		buf = a.AppendNormalized(buf[:0], text)
+	} else {
+		buf = append(buf[:0], c.normalizer.Normalize(text)...)
+	}
+	return utf8.RuneCount(buf), buf
+}
+
+// ComputeCounts scores precomputed normalized character counts with the same rules as Compute.
+// It lets streaming callers count without materializing the texts.
+func (c *Calculator) ComputeCounts(origLen, augLen int) domain.Result {
+	details := make(map[string]interface{})
+
+	if origLen < c.config.MinOriginalLength {
+		return inconclusiveResult(origLen, augLen, c.config.MinOriginalLength, c.config.Threshold)
+	}
 	if origLen == 0 {
-		c.logger.Error("Original text has zero characters", "original", original)
+		c.logger.Error("Original text has zero characters")
 		details["error"] = "original text has zero characters"
 		return domain.Result{
 			Name:    "character_similarity",
@@ -109,26 +179,11 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 		}
 	}
 
-	var lengthRatio float64
-	if origLen > augLen {
-		lengthRatio = float64(augLen) / float64(origLen)
-	} else {
-		lengthRatio = float64(origLen) / float64(augLen)
-	}
-
-	diff := math.Abs(float64(origLen - augLen))
-	diffRatio := diff / (float64(origLen) * c.config.MaxDiffRatio)
-	if diffRatio > 1.0 {
-		diffRatio = 1.0
-	}
-
-	scaledScore := 1.0 - diffRatio
-	// Round the score to the configured precision.
-	factor := math.Pow(10, float64(c.config.Precision))
-	scaledScore = math.Round(scaledScore*factor) / factor
-	lengthRatio = math.Round(lengthRatio*factor) / factor
-
-	passed := scaledScore >= c.config.Threshold
+	// Score, round to the configured precision and apply the threshold.
+	outcome := scoring.Evaluate(origLen, augLen, c.config.scoringConfig())
+	lengthRatio := outcome.LengthRatio
+	scaledScore := outcome.Score
+	passed := outcome.Passed
 
 	details["original_length"] = origLen
 	details["augmented_length"] = augLen
@@ -152,3 +207,23 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 		Details:         details,
 	}
 }
+
+// inconclusiveResult reports an original below MinOriginalLength, which is too
+// short for a length ratio to mean anything
+func inconclusiveResult(origLen, augLen, minimum int, threshold float64) domain.Result {
+	return domain.Result{
+		Name:            "character_similarity",
+		Score:           0,
+		Passed:          false,
+		Inconclusive:    true,
+		OriginalLength:  origLen,
+		AugmentedLength: augLen,
+		Threshold:       threshold,
+		Details: map[string]interface{}{
+			"inconclusive":            "original shorter than the minimum length",
+			"minimum_original_length": minimum,
+			"original_length":         origLen,
+			"augmented_length":        augLen,
+		},
+	}
+}
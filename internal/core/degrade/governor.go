@@ -0,0 +1,134 @@
+// Package degrade decides when a calculator should trade accuracy for latency.
+// A Governor tracks a moving average of observed compute latencies against a
+// target and steps through progressively cheaper levels while the target is
+// missed, stepping back once latency recovers.
+package degrade
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// Level is how far a calculator has degraded
+type Level int
+
+const (
+	// None runs the configured pipeline unchanged
+	None Level = iota
+	// FastNormalizer swaps in the fast, table-driven normalizer
+	FastNormalizer
+	// EarlyExit also fails requests immediately when their deadline is
+	// shorter than the current average latency
+	EarlyExit
+)
+
+// String returns the name recorded in result details
+func (l Level) String() string {
+	switch l {
+	case FastNormalizer:
+		return "fast_normalizer"
+	case EarlyExit:
+		return "early_exit"
+	default:
+		return "none"
+	}
+}
+
+const (
+	// SustainedSamples is how many consecutive observations must miss (or
+	// comfortably meet) the target before the level changes
+	SustainedSamples = 20
+	// smoothing is the weight of the newest observation in the moving average
+	smoothing = 0.2
+)
+
+// Governor tracks latency pressure. It is safe for concurrent use.
+type Governor struct {
+	mu     sync.Mutex
+	clock  ports.Clock
+	target time.Duration
+	ewma   float64
+	level  Level
+	streak int // positive while over target, negative while well under it
+}
+
+// NewGovernor creates a governor for the given latency target that reads the time from clock
+func NewGovernor(target time.Duration, clock ports.Clock) *Governor {
+	return &Governor{clock: clock, target: target}
+}
+
+// Now reads the governor's clock, for timing the computations passed to Observe
+func (g *Governor) Now() time.Time {
+	return g.clock.Now()
+}
+
+// Observe records one compute latency and adjusts the level
+func (g *Governor) Observe(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.ewma == 0 {
+		g.ewma = float64(d)
+	} else {
+		g.ewma = smoothing*float64(d) + (1-smoothing)*g.ewma
+	}
+
+	// Recover only well below the target so the cheaper settings do not
+	// immediately switch themselves off again
+	switch {
+	case g.ewma > float64(g.target):
+		g.streak = max(g.streak, 0) + 1
+	case g.ewma < float64(g.target)/2:
+		g.streak = min(g.streak, 0) - 1
+	default:
+		g.streak = 0
+	}
+
+	if g.streak >= SustainedSamples && g.level < EarlyExit {
+		g.level++
+		g.streak = 0
+	} else if g.streak <= -SustainedSamples && g.level > None {
+		g.level--
+		g.streak = 0
+	}
+}
+
+// Level returns the current degradation level
+func (g *Governor) Level() Level {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.level
+}
+
+// Expected returns the current moving-average latency
+func (g *Governor) Expected() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return time.Duration(g.ewma)
+}
+
+// ShouldExit reports whether, at the given level, a request whose deadline is
+// shorter than the expected latency should fail fast instead of running
+func (g *Governor) ShouldExit(ctx context.Context, level Level) bool {
+	if level < EarlyExit {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && deadline.Sub(g.clock.Now()) < g.Expected()
+}
+
+// Annotate records the level in result details when the calculator degraded
+func Annotate(details map[string]interface{}, level Level) map[string]interface{} {
+	if level == None {
+		return details
+	}
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["degraded"] = true
+	details["degradation"] = level.String()
+	return details
+}
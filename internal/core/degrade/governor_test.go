@@ -0,0 +1,69 @@
+package degrade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/clock"
+	"github.com/baditaflorin/go_length_similarity/pkg/similaritytest/fakeclock"
+)
+
+func TestGovernorEscalatesAndRecovers(t *testing.T) {
+	g := NewGovernor(10*time.Millisecond, clock.System())
+
+	for i := 0; i < SustainedSamples; i++ {
+		g.Observe(50 * time.Millisecond)
+	}
+	if g.Level() != FastNormalizer {
+		t.Fatalf("level = %v, want fast_normalizer", g.Level())
+	}
+	for i := 0; i < SustainedSamples; i++ {
+		g.Observe(50 * time.Millisecond)
+	}
+	if g.Level() != EarlyExit {
+		t.Fatalf("level = %v, want early_exit", g.Level())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if !g.ShouldExit(ctx, g.Level()) {
+		t.Error("a deadline shorter than the expected latency should exit early")
+	}
+
+	// Latency between target/2 and target holds the level
+	for i := 0; i < 3*SustainedSamples; i++ {
+		g.Observe(8 * time.Millisecond)
+	}
+	if g.Level() != EarlyExit {
+		t.Fatalf("level = %v, want early_exit to hold", g.Level())
+	}
+
+	for i := 0; i < 3*SustainedSamples; i++ {
+		g.Observe(time.Millisecond)
+	}
+	if g.Level() != None {
+		t.Fatalf("level = %v, want none after recovery", g.Level())
+	}
+}
+
+func TestGovernorShouldExitReadsItsClock(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	g := NewGovernor(10*time.Millisecond, clk)
+	for i := 0; i < 2*SustainedSamples; i++ {
+		g.Observe(50 * time.Millisecond)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	clk.Set(deadline.Add(-time.Second))
+	if g.ShouldExit(ctx, g.Level()) {
+		t.Error("1s left on the clock is more than the 50ms expected")
+	}
+	clk.Set(deadline.Add(-5 * time.Millisecond))
+	if !g.ShouldExit(ctx, g.Level()) {
+		t.Error("5ms left on the clock is less than the 50ms expected")
+	}
+}
@@ -0,0 +1,197 @@
+// Package distance implements the content metrics that compare what two texts
+// say rather than how long they are. They cost O(n*m) or O(n) with large
+// constants, so callers usually run them only when the length metrics cannot decide.
+package distance
+
+import (
+	"context"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// DefaultNGramSize is the n-gram length used when none is configured
+const DefaultNGramSize = 3
+
+// Config holds the parameters shared by the content metrics
+type Config struct {
+	Threshold float64
+	// NGramSize is the number of runes per n-gram; only the n-gram metric uses it
+	NGramSize int
+}
+
+// DefaultConfig returns a default configuration
+func DefaultConfig() Config {
+	return Config{
+		Threshold: 0.7,
+		NGramSize: DefaultNGramSize,
+	}
+}
+
+// Validate checks if the configuration is valid
+func (c Config) Validate() error {
+	if err := domain.ValidateThreshold(c.Threshold); err != nil {
+		return err
+	}
+	if c.NGramSize < 1 {
+		return domain.NewConfigError("nGramSize", c.NGramSize, "must be at least 1")
+	}
+	return nil
+}
+
+// EditCalculator scores two texts by their Levenshtein distance over normalized
+// runes: 1 - distance / max(len).
+type EditCalculator struct {
+	config     Config
+	normalizer ports.Normalizer
+}
+
+// NewEditCalculator creates a new edit distance calculator
+func NewEditCalculator(config Config, normalizer ports.Normalizer) (*EditCalculator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &EditCalculator{config: config, normalizer: normalizer}, nil
+}
+
+// Compute calculates the edit distance similarity between two texts
+func (c *EditCalculator) Compute(ctx context.Context, original, augmented string) domain.Result {
+	a := []rune(c.normalizer.Normalize(original))
+	b := []rune(c.normalizer.Normalize(augmented))
+
+	d, ok := levenshtein(ctx, a, b)
+	if !ok {
+		return cancelled("edit_distance_similarity", domain.EngineEdit)
+	}
+
+	longest := max(len(a), len(b))
+	score := 1.0
+	if longest > 0 {
+		score = scoring.Clamp01(1 - float64(d)/float64(longest))
+	}
+
+	return build("edit_distance_similarity", domain.EngineEdit, score, len(a), len(b), c.config.Threshold, map[string]interface{}{"distance": d})
+}
+
+// levenshtein returns the edit distance between a and b using two rows of
+// memory. It checks ctx once per row and reports false when cancelled.
+func levenshtein(ctx context.Context, a, b []rune) (int, bool) {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		if ctx.Err() != nil {
+			return 0, false
+		}
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)], true
+}
+
+// NGramCalculator scores two texts by the Dice coefficient of their normalized
+// rune n-gram multisets.
+type NGramCalculator struct {
+	config     Config
+	normalizer ports.Normalizer
+}
+
+// NewNGramCalculator creates a new n-gram calculator
+func NewNGramCalculator(config Config, normalizer ports.Normalizer) (*NGramCalculator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &NGramCalculator{config: config, normalizer: normalizer}, nil
+}
+
+// Compute calculates the n-gram similarity between two texts
+func (c *NGramCalculator) Compute(ctx context.Context, original, augmented string) domain.Result {
+	origRunes := []rune(c.normalizer.Normalize(original))
+	augRunes := []rune(c.normalizer.Normalize(augmented))
+	a := ngrams(origRunes, c.config.NGramSize)
+	b := ngrams(augRunes, c.config.NGramSize)
+	if ctx.Err() != nil {
+		return cancelled("ngram_similarity", domain.EngineNGram)
+	}
+
+	total := 0
+	for _, n := range a {
+		total += n
+	}
+	shared := 0
+	for g, n := range b {
+		total += n
+		shared += min(n, a[g])
+	}
+
+	score := 1.0
+	if total > 0 {
+		score = float64(2*shared) / float64(total)
+	}
+
+	return build("ngram_similarity", domain.EngineNGram, score, len(origRunes), len(augRunes), c.config.Threshold, map[string]interface{}{"n": c.config.NGramSize, "shared_ngrams": shared})
+}
+
+// ngrams counts the n-rune windows of s. Texts shorter than n yield one gram
+// holding the whole text, so short inputs still compare.
+func ngrams(s []rune, n int) map[string]int {
+	counts := make(map[string]int)
+	if len(s) == 0 {
+		return counts
+	}
+	if len(s) < n {
+		counts[string(s)]++
+		return counts
+	}
+	for i := 0; i+n <= len(s); i++ {
+		counts[string(s[i:i+n])]++
+	}
+	return counts
+}
+
+// build fills the fields every content metric reports
+func build(name, engine string, score float64, origLen, augLen int, threshold float64, details map[string]interface{}) domain.Result {
+	lengthRatio := scoring.LengthRatio(origLen, augLen)
+	details["original_length"] = origLen
+	details["augmented_length"] = augLen
+	details["length_ratio"] = lengthRatio
+	details["threshold"] = threshold
+	return domain.Result{
+		Name:            name,
+		Engine:          engine,
+		Mode:            domain.ModeText,
+		Score:           score,
+		Passed:          score >= threshold,
+		OriginalLength:  origLen,
+		AugmentedLength: augLen,
+		LengthRatio:     lengthRatio,
+		Threshold:       threshold,
+		Details:         details,
+	}
+}
+
+// cancelled reports a computation aborted by its context
+func cancelled(name, engine string) domain.Result {
+	return domain.Result{
+		Name:    name,
+		Engine:  engine,
+		Mode:    domain.ModeText,
+		Score:   0,
+		Passed:  false,
+		Details: map[string]interface{}{"error": "computation cancelled"},
+	}
+}
@@ -0,0 +1,43 @@
+package distance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+)
+
+func TestEditCalculator(t *testing.T) {
+	c, err := NewEditCalculator(DefaultConfig(), normalizer.NewDefaultNormalizer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := c.Compute(context.Background(), "kitten", "sitting")
+	if r.Details["distance"] != 3 {
+		t.Errorf("distance = %v, want 3", r.Details["distance"])
+	}
+	if want := 1 - 3.0/7; r.Score != want {
+		t.Errorf("score = %v, want %v", r.Score, want)
+	}
+
+	if r := c.Compute(context.Background(), "", ""); r.Score != 1 {
+		t.Errorf("two empty texts scored %v, want 1", r.Score)
+	}
+}
+
+func TestNGramCalculator(t *testing.T) {
+	c, err := NewNGramCalculator(DefaultConfig(), normalizer.NewDefaultNormalizer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if r := c.Compute(ctx, "Hello World", "hello world"); r.Score != 1 || !r.Passed {
+		t.Errorf("normalized equal texts: %+v, want score 1", r)
+	}
+	// "abcd" has abc, bcd; "abce" has abc, bce: one shared of four
+	if r := c.Compute(ctx, "abcd", "abce"); r.Score != 0.5 {
+		t.Errorf("score = %v, want 0.5", r.Score)
+	}
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultEpsilon absorbs floating point noise from the scoring arithmetic
+// without hiding a change in the rounded scores.
+const DefaultEpsilon = 1e-9
+
+// ApproxEqual reports whether a and b differ by at most epsilon.
+// NaN is only equal to NaN, and infinities only to themselves.
+func ApproxEqual(a, b, epsilon float64) bool {
+	if a == b {
+		return true
+	}
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) <= epsilon
+}
+
+// ApproximatelyEquals reports whether r and other describe the same outcome:
+// the name, pass/fail verdict and counts match exactly, and the score, length
+// ratio and threshold match within epsilon. Engine, Mode, Details, Resources
+// and Uncertainty are ignored, so results from different engines compare.
+func (r Result) ApproximatelyEquals(other Result, epsilon float64) bool {
+	return len(r.Diff(other, epsilon)) == 0
+}
+
+// Diff lists the fields on which r and other differ, using the same rules as
+// ApproximatelyEquals. Each entry reads "field: mine != theirs", which makes it
+// suitable for test failures and baseline reports.
+func (r Result) Diff(other Result, epsilon float64) []string {
+	var diffs []string
+	if r.Name != other.Name {
+		diffs = append(diffs, fmt.Sprintf("name: %q != %q", r.Name, other.Name))
+	}
+	if !ApproxEqual(r.Score, other.Score, epsilon) {
+		diffs = append(diffs, fmt.Sprintf("score: %v != %v", r.Score, other.Score))
+	}
+	if r.Passed != other.Passed {
+		diffs = append(diffs, fmt.Sprintf("passed: %v != %v", r.Passed, other.Passed))
+	}
+	if r.Inconclusive != other.Inconclusive {
+		diffs = append(diffs, fmt.Sprintf("inconclusive: %v != %v", r.Inconclusive, other.Inconclusive))
+	}
+	if r.OriginalLength != other.OriginalLength {
+		diffs = append(diffs, fmt.Sprintf("original_length: %d != %d", r.OriginalLength, other.OriginalLength))
+	}
+	if r.AugmentedLength != other.AugmentedLength {
+		diffs = append(diffs, fmt.Sprintf("augmented_length: %d != %d", r.AugmentedLength, other.AugmentedLength))
+	}
+	if !ApproxEqual(r.LengthRatio, other.LengthRatio, epsilon) {
+		diffs = append(diffs, fmt.Sprintf("length_ratio: %v != %v", r.LengthRatio, other.LengthRatio))
+	}
+	if !ApproxEqual(r.Threshold, other.Threshold, epsilon) {
+		diffs = append(diffs, fmt.Sprintf("threshold: %v != %v", r.Threshold, other.Threshold))
+	}
+	return diffs
+}
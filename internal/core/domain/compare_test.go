@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestApproxEqual(t *testing.T) {
+	cases := []struct {
+		a, b float64
+		want bool
+	}{
+		{0.7, 0.1 * 7, true},
+		{0.7, 0.71, false},
+		{math.NaN(), math.NaN(), true},
+		{math.NaN(), 0, false},
+		{math.Inf(1), math.Inf(1), true},
+		{math.Inf(1), math.MaxFloat64, false},
+	}
+	for _, c := range cases {
+		if got := ApproxEqual(c.a, c.b, DefaultEpsilon); got != c.want {
+			t.Errorf("ApproxEqual(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestResultDiff(t *testing.T) {
+	base := Result{Name: "length_similarity", Score: 0.7, Passed: true, OriginalLength: 10, AugmentedLength: 7, LengthRatio: 0.7, Threshold: 0.7}
+
+	noisy := base
+	noisy.Score = 0.1 * 7
+	noisy.Details = map[string]interface{}{"ignored": true}
+	if !base.ApproximatelyEquals(noisy, DefaultEpsilon) {
+		t.Errorf("float noise should not count: %v", base.Diff(noisy, DefaultEpsilon))
+	}
+
+	changed := base
+	changed.Score = 0.69
+	changed.Passed = false
+	if diffs := base.Diff(changed, DefaultEpsilon); len(diffs) != 2 {
+		t.Errorf("got %v, want score and passed", diffs)
+	}
+	if base.ApproximatelyEquals(changed, 0.02) {
+		t.Error("a verdict change must never be within epsilon")
+	}
+}
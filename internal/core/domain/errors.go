@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ConfigError reports an invalid configuration value
+type ConfigError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+// NewConfigError creates a configuration error for the given field
+func NewConfigError(field string, value interface{}, reason string) *ConfigError {
+	return &ConfigError{
+		Field:  field,
+		Value:  value,
+		Reason: reason,
+	}
+}
+
+// ValidateThreshold checks that a threshold lies in [0, 1]
+func ValidateThreshold(threshold float64) error {
+	if !(threshold >= 0 && threshold <= 1) {
+		return NewConfigError("threshold", threshold, "must be between 0 and 1")
+	}
+	return nil
+}
+
+// ValidateMaxDiffRatio checks that a maximum difference ratio is positive
+func ValidateMaxDiffRatio(ratio float64) error {
+	if !(ratio > 0) {
+		return NewConfigError("maxDiffRatio", ratio, "must be greater than 0")
+	}
+	return nil
+}
+
+// MaxBytesError is returned when an input stream exceeds its configured size limit
+type MaxBytesError struct {
+	Limit int64
+}
+
+// Error implements the error interface
+func (e *MaxBytesError) Error() string {
+	return fmt.Sprintf("input exceeds the %d byte limit", e.Limit)
+}
+
+// IsTruncated reports whether err means an input ended before its producer
+// said it would: a body shorter than its Content-Length, a cut-off gzip
+// stream, an interrupted io.ReadFull. Truncated inputs must not be scored as
+// if they were complete.
+func IsTruncated(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
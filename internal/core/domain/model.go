@@ -2,7 +2,11 @@ package domain
 
 // Result holds the outcome of a similarity computation.
 type Result struct {
-	Name            string
+	Name string
+	// Engine names the calculator that produced the result (see the Engine constants)
+	Engine string
+	// Mode is how the inputs were processed (see the Mode constants)
+	Mode            string
 	Score           float64
 	Passed          bool
 	OriginalLength  int
@@ -10,4 +14,77 @@ type Result struct {
 	LengthRatio     float64
 	Threshold       float64
 	Details         map[string]interface{}
+	// Resources is nil unless resource reporting is enabled
+	Resources *Resources
+	// TruncatedInput is set when an input stream ended unexpectedly (see
+	// IsTruncated). The score is then 0 and says nothing about similarity.
+	TruncatedInput bool
+	// Inconclusive is set when the original was too short to score (see the
+	// MinOriginalLength options). Passed is then false, but the pair did not fail.
+	Inconclusive bool
+	// Uncertainty is nil unless uncertainty reporting is enabled
+	Uncertainty *Uncertainty
+}
+
+// Engines reported in Result.Engine, so scores from several calculators can
+// be told apart downstream
+const (
+	EngineWord      = "word"
+	EngineCharacter = "character"
+	EngineStreaming = "streaming"
+	EngineEfficient = "efficient"
+	EngineEdit      = "edit"
+	EngineNGram     = "ngram"
+	EngineStructure = "structure"
+)
+
+// Modes reported in Result.Mode
+const (
+	// ModeText scores texts held in memory
+	ModeText = "text"
+	// ModeStream counts readers as they are read, without holding the texts
+	ModeStream = "stream"
+	// ModeChunk, ModeLine and ModeWord are the streaming calculators' modes
+	ModeChunk = "chunk"
+	ModeLine  = "line"
+	ModeWord  = "word"
+)
+
+// Uncertainty describes how coarse a score is given the input sizes. Ratios
+// over a few words jump in large steps, so a short-text score near the
+// threshold is weak evidence either way.
+type Uncertainty struct {
+	// Margin is how far the score moves per unit (word or character) of length difference
+	Margin float64
+	// Low and High bound the score within one margin, clamped to [0, 1]
+	Low  float64
+	High float64
+	// NearThreshold is set when the threshold lies within the bounds, so one
+	// unit of length difference could flip the verdict
+	NearThreshold bool
+}
+
+// NewUncertainty bounds score by margin and checks the bounds against threshold
+func NewUncertainty(score, threshold, margin float64) *Uncertainty {
+	low := max(score-margin, 0)
+	high := min(score+margin, 1)
+	return &Uncertainty{
+		Margin:        margin,
+		Low:           low,
+		High:          high,
+		NearThreshold: low < threshold && threshold <= high,
+	}
+}
+
+// Resources reports what one comparison cost. It is only filled in when
+// resource reporting is enabled on the calculator.
+type Resources struct {
+	// AllocatedBytes and Allocations estimate heap allocations during the comparison.
+	// They are process-wide deltas, so concurrent work inflates them.
+	AllocatedBytes int64
+	Allocations    int64
+	// PeakBufferBytes is the largest working buffer held at once
+	PeakBufferBytes int64
+	// Workers is the number of goroutines that actually processed input
+	Workers int
 }
@@ -2,12 +2,14 @@ package length
 
 import (
 	"context"
-	"errors"
-	"math"
 	"strings"
+	"unicode"
 
+	"github.com/baditaflorin/go_length_similarity/internal/cache"
 	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 )
 
 // SimilarityConfig holds configuration for the length similarity calculator.
@@ -17,36 +19,61 @@ type SimilarityConfig struct {
 	// MinWords prevents boilerplate snippets and one-word templates from
 	// being reported as high-confidence content similarity.
 	MinWords int
+	// MinOriginalLength marks results inconclusive instead of scoring them
+	// when the original has fewer normalized words. 0 disables the guard.
+	MinOriginalLength int
+	// OriginalCacheSize caches the word counts of up to this many originals,
+	// so comparing one source against many candidates only processes the
+	// augmented side. 0 disables the cache.
+	OriginalCacheSize int
+	// Hasher keys the originals cache; nil uses the default (xxhash).
+	Hasher ports.Hasher
 }
 
 // DefaultConfig returns a default configuration.
 func DefaultConfig() SimilarityConfig {
 	return SimilarityConfig{
-		Threshold:    0.7,
-		MaxDiffRatio: 0.3,
+		Threshold:    scoring.DefaultThreshold,
+		MaxDiffRatio: scoring.DefaultMaxDiffRatio,
 		MinWords:     3,
 	}
 }
 
 // Validate checks if the configuration is valid.
 func (c SimilarityConfig) Validate() error {
-	if c.Threshold < 0 || c.Threshold > 1 {
-		return errors.New("threshold must be between 0 and 1")
+	if err := domain.ValidateThreshold(c.Threshold); err != nil {
+		return err
 	}
-	if c.MaxDiffRatio <= 0 {
-		return errors.New("maxDiffRatio must be greater than 0")
+	if err := domain.ValidateMaxDiffRatio(c.MaxDiffRatio); err != nil {
+		return err
 	}
 	if c.MinWords < 1 {
-		return errors.New("minWords must be at least 1")
+		return domain.NewConfigError("minWords", c.MinWords, "must be at least 1")
+	}
+	if c.MinOriginalLength < 0 {
+		return domain.NewConfigError("minOriginalLength", c.MinOriginalLength, "must not be negative")
+	}
+	if c.OriginalCacheSize < 0 {
+		return domain.NewConfigError("originalCacheSize", c.OriginalCacheSize, "must not be negative")
 	}
 	return nil
 }
 
+// scoringConfig returns the shared scoring parameters; word scores are not rounded.
+func (c SimilarityConfig) scoringConfig() scoring.Config {
+	return scoring.Config{
+		Threshold:    c.Threshold,
+		MaxDiffRatio: c.MaxDiffRatio,
+		Precision:    scoring.NoRounding,
+	}
+}
+
 // Calculator implements the word-level length similarity calculation.
 type Calculator struct {
 	config     SimilarityConfig
 	logger     ports.Logger
 	normalizer ports.Normalizer
+	originals  *cache.CountCache
 }
 
 // NewCalculator creates a new length similarity calculator.
@@ -59,9 +86,15 @@ func NewCalculator(config SimilarityConfig, logger ports.Logger, normalizer port
 		config:     config,
 		logger:     logger,
 		normalizer: normalizer,
+		originals:  cache.NewCountCache(config.OriginalCacheSize, config.Hasher),
 	}, nil
 }
 
+// Release empties the originals cache
+func (c *Calculator) Release() {
+	c.originals.Clear()
+}
+
 // Compute calculates the word-level length similarity between two texts.
 func (c *Calculator) Compute(ctx context.Context, original, augmented string) domain.Result {
 	c.logger.Debug("Starting length similarity computation",
@@ -71,11 +104,11 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 
 	details := make(map[string]interface{})
 
-	normalizedOriginal := c.normalizer.Normalize(visibleComparisonText(original))
+	origLen := c.originalLength(ctx, original)
 	normalizedAugmented := c.normalizer.Normalize(visibleComparisonText(augmented))
+	probe.FromContext(ctx).RecordBuffer(len(normalizedAugmented))
 
-	c.logger.Debug("Normalized texts",
-		"normalizedOriginal", normalizedOriginal,
+	c.logger.Debug("Normalized augmented text",
 		"normalizedAugmented", normalizedAugmented,
 	)
 
@@ -94,18 +127,68 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 		// continue
 	}
 
-	origWords := strings.Fields(normalizedOriginal)
-	augWords := strings.Fields(normalizedAugmented)
-	origLen := len(origWords)
-	augLen := len(augWords)
+	augLen := len(strings.Fields(normalizedAugmented))
 
 	c.logger.Debug("Computed word counts",
 		"original_length", origLen,
 		"augmented_length", augLen,
 	)
 
+	return c.ComputeCounts(origLen, augLen)
+}
+
+// originalLength returns the normalized word count of original, using the
+// originals cache when it is enabled.
+func (c *Calculator) originalLength(ctx context.Context, original string) int {
+	if n, ok := c.originals.Get(original); ok {
+		c.logger.Debug("Original word count served from cache", "original_length", n)
+		return n
+	}
+
+	normalizedOriginal := c.normalizer.Normalize(visibleComparisonText(original))
+	probe.FromContext(ctx).RecordBuffer(len(normalizedOriginal))
+	c.logger.Debug("Normalized original text", "normalizedOriginal", normalizedOriginal)
+
+	n := len(strings.Fields(normalizedOriginal))
+	c.originals.Put(original, n)
+	return n
+}
+
+// CountWords returns the normalized word count of text as Compute counts it,
+// using buf as scratch space. It returns buf, possibly grown, so a caller that
+// keeps it avoids allocating on later calls.
+func (c *Calculator) CountWords(text string, buf []byte) (int, []byte) {
+	if strings.IndexByte(text, '<') >= 0 {
+		text = visibleComparisonText(text)
+	}
+	if a, ok := c.normalizer.(ports.AppendNormalizer); ok {
+		buf = a.AppendNormalized(buf[:0], text)
+	} else {
+		buf = append(buf[:0], c.normalizer.Normalize(text)...)
+	}
+
+	// Same rule as strings.Fields
+	n, inWord := 0, false
+	for _, r := range string(buf) {
+		space := unicode.IsSpace(r)
+		if !space && !inWord {
+			n++
+		}
+		inWord = !space
+	}
+	return n, buf
+}
+
+// ComputeCounts scores precomputed normalized word counts with the same rules as Compute.
+// It lets streaming callers count without materializing the texts.
+func (c *Calculator) ComputeCounts(origLen, augLen int) domain.Result {
+	details := make(map[string]interface{})
+
+	if origLen < c.config.MinOriginalLength {
+		return inconclusiveResult(origLen, augLen, c.config.MinOriginalLength, c.config.Threshold)
+	}
 	if origLen == 0 {
-		c.logger.Error("Original text has zero words", "original", original)
+		c.logger.Error("Original text has zero words")
 		details["error"] = "original text has zero words"
 		return domain.Result{
 			Name:    "length_similarity",
@@ -130,21 +213,10 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 		}
 	}
 
-	var lengthRatio float64
-	if origLen > augLen {
-		lengthRatio = float64(augLen) / float64(origLen)
-	} else {
-		lengthRatio = float64(origLen) / float64(augLen)
-	}
-
-	diff := math.Abs(float64(origLen - augLen))
-	diffRatio := diff / (float64(origLen) * c.config.MaxDiffRatio)
-	if diffRatio > 1.0 {
-		diffRatio = 1.0
-	}
-
-	scaledScore := 1.0 - diffRatio
-	passed := scaledScore >= c.config.Threshold
+	outcome := scoring.Evaluate(origLen, augLen, c.config.scoringConfig())
+	lengthRatio := outcome.LengthRatio
+	scaledScore := outcome.Score
+	passed := outcome.Passed
 
 	details["original_length"] = origLen
 	details["augmented_length"] = augLen
@@ -169,6 +241,26 @@ func (c *Calculator) Compute(ctx context.Context, original, augmented string) do
 	}
 }
 
+// inconclusiveResult reports an original below MinOriginalLength, which is too
+// short for a length ratio to mean anything
+func inconclusiveResult(origLen, augLen, minimum int, threshold float64) domain.Result {
+	return domain.Result{
+		Name:            "length_similarity",
+		Score:           0,
+		Passed:          false,
+		Inconclusive:    true,
+		OriginalLength:  origLen,
+		AugmentedLength: augLen,
+		Threshold:       threshold,
+		Details: map[string]interface{}{
+			"inconclusive":            "original shorter than the minimum length",
+			"minimum_original_length": minimum,
+			"original_length":         origLen,
+			"augmented_length":        augLen,
+		},
+	}
+}
+
 // visibleComparisonText removes markup and page-chrome blocks before the
 // normalizer counts words. Length similarity is used as content evidence, so
 // shared navigation, cookie banners, and scripts must not make unrelated
@@ -0,0 +1,145 @@
+// Package scoring is the shared scoring engine used by the word, character and
+// streaming calculators: diff ratio, clamping, precision rounding and the
+// threshold check live here so a fix in one place reaches every engine.
+package scoring
+
+import "math"
+
+// LengthRatio returns the ratio of the shorter length to the longer one.
+// Two empty inputs are considered identical and yield 1.
+func LengthRatio(origLen, augLen int) float64 {
+	if origLen <= 0 && augLen <= 0 {
+		return 1.0
+	}
+	if origLen <= 0 || augLen <= 0 {
+		return 0.0
+	}
+
+	if origLen > augLen {
+		return Clamp01(float64(augLen) / float64(origLen))
+	}
+	return Clamp01(float64(origLen) / float64(augLen))
+}
+
+// DiffRatio returns the length difference relative to the tolerated difference
+// (origLen * maxDiffRatio), clamped to [0, 1]. Degenerate inputs that would divide
+// by zero or produce NaN are treated as the maximum difference.
+func DiffRatio(origLen, augLen int, maxDiffRatio float64) float64 {
+	if origLen == augLen {
+		return 0.0
+	}
+
+	allowed := float64(origLen) * maxDiffRatio
+	if origLen <= 0 || !(allowed > 0) || math.IsInf(allowed, 0) {
+		return 1.0
+	}
+
+	diff := math.Abs(float64(origLen - augLen))
+	return Clamp01(diff / allowed)
+}
+
+// Score returns the scaled similarity score 1 - DiffRatio in [0, 1]
+func Score(origLen, augLen int, maxDiffRatio float64) float64 {
+	return Clamp01(1.0 - DiffRatio(origLen, augLen, maxDiffRatio))
+}
+
+// Clamp01 clamps v to [0, 1], mapping NaN and -Inf to 0 and +Inf to 1
+func Clamp01(v float64) float64 {
+	if math.IsNaN(v) || v < 0 {
+		return 0.0
+	}
+	if v > 1 {
+		return 1.0
+	}
+	return v
+}
+
+// Config holds the parameters of the scoring formula
+type Config struct {
+	Threshold    float64
+	MaxDiffRatio float64
+	// Precision is the number of decimal places kept in Score and LengthRatio.
+	// A negative value disables rounding.
+	Precision int
+}
+
+// Outcome is the result of scoring a pair of lengths
+type Outcome struct {
+	Score       float64
+	LengthRatio float64
+	Passed      bool
+}
+
+// NoRounding disables precision rounding in Config
+const NoRounding = -1
+
+// Defaults every calculator starts from
+const (
+	DefaultThreshold    = 0.7
+	DefaultMaxDiffRatio = 0.3
+)
+
+// Evaluate scores a pair of lengths: diff ratio, clamping, precision rounding and threshold check
+func Evaluate(origLen, augLen int, cfg Config) Outcome {
+	score := Score(origLen, augLen, cfg.MaxDiffRatio)
+	lengthRatio := LengthRatio(origLen, augLen)
+
+	if cfg.Precision >= 0 {
+		score = Round(score, cfg.Precision)
+		lengthRatio = Round(lengthRatio, cfg.Precision)
+	}
+
+	return Outcome{
+		Score:       score,
+		LengthRatio: lengthRatio,
+		Passed:      score >= cfg.Threshold,
+	}
+}
+
+// Scorer turns a pair of counts into an Outcome, so a calculator can apply
+// custom scoring (a target ratio, asymmetric penalties, a calibrated curve)
+// in place of Evaluate
+type Scorer interface {
+	Score(origCount, augCount int, cfg Config) Outcome
+}
+
+// ScorerFunc adapts a function to Scorer
+type ScorerFunc func(origCount, augCount int, cfg Config) Outcome
+
+// Score calls f
+func (f ScorerFunc) Score(origCount, augCount int, cfg Config) Outcome {
+	return f(origCount, augCount, cfg)
+}
+
+// Apply scores a pair with s, or with Evaluate when s is nil. The score and
+// length ratio of a custom scorer are clamped to [0, 1].
+func Apply(s Scorer, origLen, augLen int, cfg Config) Outcome {
+	if s == nil {
+		return Evaluate(origLen, augLen, cfg)
+	}
+	outcome := s.Score(origLen, augLen, cfg)
+	outcome.Score = Clamp01(outcome.Score)
+	outcome.LengthRatio = Clamp01(outcome.LengthRatio)
+	return outcome
+}
+
+// Round rounds v to the given number of decimal places
+func Round(v float64, precision int) float64 {
+	factor := math.Pow(10, float64(precision))
+	rounded := math.Round(v*factor) / factor
+	if math.IsNaN(rounded) || math.IsInf(rounded, 0) {
+		return v
+	}
+	return rounded
+}
+
+// Margin returns how far one unit of length difference moves the score for an
+// original of origLen units: 1 / (origLen * maxDiffRatio), capped at 1. Short
+// originals have coarse scores, so it doubles as their uncertainty.
+func Margin(origLen int, maxDiffRatio float64) float64 {
+	allowed := float64(origLen) * maxDiffRatio
+	if origLen <= 0 || !(allowed > 0) {
+		return 1.0
+	}
+	return Clamp01(1.0 / allowed)
+}
@@ -0,0 +1,69 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoreMatchesLegacyFormula(t *testing.T) {
+	cases := []struct {
+		orig, aug int
+		ratio     float64
+		want      float64
+	}{
+		{10, 10, 0.3, 1.0},
+		{10, 9, 0.3, 1.0 - 1.0/3.0},
+		{10, 13, 0.3, 0.0},
+		{10, 0, 0.3, 0.0},
+	}
+	for _, c := range cases {
+		if got := Score(c.orig, c.aug, c.ratio); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Score(%d, %d, %v) = %v, want %v", c.orig, c.aug, c.ratio, got, c.want)
+		}
+	}
+}
+
+func TestScoreGuardsDegenerateInputs(t *testing.T) {
+	for _, ratio := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		got := Score(10, 5, ratio)
+		if math.IsNaN(got) || got < 0 || got > 1 {
+			t.Fatalf("Score with maxDiffRatio %v escaped [0,1]: %v", ratio, got)
+		}
+	}
+	if got := Score(0, 5, 0.3); got != 0 {
+		t.Fatalf("empty original must score 0, got %v", got)
+	}
+	if got := LengthRatio(0, 0); got != 1 {
+		t.Fatalf("two empty inputs must have ratio 1, got %v", got)
+	}
+	if got := Clamp01(math.Inf(1)); got != 1 {
+		t.Fatalf("Clamp01(+Inf) = %v, want 1", got)
+	}
+}
+
+func TestEvaluateRoundsBeforeThreshold(t *testing.T) {
+	cfg := Config{Threshold: 0.67, MaxDiffRatio: 0.3, Precision: 2}
+	outcome := Evaluate(10, 9, cfg)
+	if outcome.Score != 0.67 || !outcome.Passed {
+		t.Fatalf("expected rounded score 0.67 to pass threshold 0.67, got %#v", outcome)
+	}
+
+	cfg.Precision = NoRounding
+	if outcome := Evaluate(10, 9, cfg); outcome.Passed {
+		t.Fatalf("unrounded score %v must not pass threshold 0.67", outcome.Score)
+	}
+}
+
+func TestApplyUsesScorerAndClamps(t *testing.T) {
+	cfg := Config{Threshold: 0.7, MaxDiffRatio: 0.3, Precision: NoRounding}
+	if got, want := Apply(nil, 10, 9, cfg), Evaluate(10, 9, cfg); got != want {
+		t.Fatalf("nil scorer: got %#v, want %#v", got, want)
+	}
+
+	wild := ScorerFunc(func(origCount, augCount int, cfg Config) Outcome {
+		return Outcome{Score: 2, LengthRatio: math.NaN(), Passed: true}
+	})
+	if got := Apply(wild, 10, 9, cfg); got.Score != 1 || got.LengthRatio != 0 || !got.Passed {
+		t.Fatalf("expected a clamped outcome, got %#v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package structure
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
+)
+
+// FieldScore compares one top-level field of two payloads. Lengths are the
+// runes of the field's content (string values, number and literal text,
+// attribute values and XML text), which formatting does not change, so a
+// score below 1 means the field itself was shortened or padded.
+type FieldScore struct {
+	// Field is the top-level key, "[i]" for the values of a top-level JSON
+	// array, or for XML the root's child element name, "@attribute" or "#text"
+	Field           string
+	OriginalLength  int
+	AugmentedLength int
+	OriginalNodes   int
+	AugmentedNodes  int
+	Score           float64
+	LengthRatio     float64
+	Passed          bool
+	// Missing is "original" or "augmented" when the field is absent on that side
+	Missing string
+}
+
+// FieldReport is the structural result of a pair with one score per
+// top-level field, in the order the fields first appear in the original,
+// followed by those only the augmented payload has
+type FieldReport struct {
+	Result domain.Result
+	Fields []FieldScore
+}
+
+// fieldTally accumulates per-field nodes and content runes. Fields with the
+// same name, such as repeated XML elements or the keys of several JSON Lines
+// records, share one tally. A nil tally ignores every call.
+type fieldTally struct {
+	order   []string
+	byName  map[string]*fieldCount
+	current *fieldCount
+}
+
+type fieldCount struct {
+	nodes, runes int
+}
+
+func newFieldTally() *fieldTally {
+	return &fieldTally{byName: make(map[string]*fieldCount)}
+}
+
+// start attributes the following nodes to name
+func (t *fieldTally) start(name string) {
+	if t == nil {
+		return
+	}
+	fc := t.byName[name]
+	if fc == nil {
+		fc = &fieldCount{}
+		t.byName[name] = fc
+		t.order = append(t.order, name)
+	}
+	t.current = fc
+}
+
+// end stops attributing nodes to the current field
+func (t *fieldTally) end() {
+	if t != nil {
+		t.current = nil
+	}
+}
+
+// node records one node holding runes of content
+func (t *fieldTally) node(runes int) {
+	if t != nil && t.current != nil {
+		t.current.nodes++
+		t.current.runes += runes
+	}
+}
+
+// CompareFields is Compute with a score for every top-level field
+func (c *Calculator) CompareFields(ctx context.Context, original, augmented string) FieldReport {
+	return c.compareFields(ctx, strings.NewReader(original), strings.NewReader(augmented), domain.ModeText)
+}
+
+// CompareFieldsFromReaders is CompareFields over readers
+func (c *Calculator) CompareFieldsFromReaders(ctx context.Context, original, augmented io.Reader) FieldReport {
+	return c.compareFields(ctx, original, augmented, domain.ModeStream)
+}
+
+func (c *Calculator) compareFields(ctx context.Context, original, augmented io.Reader, mode string) FieldReport {
+	origFields, augFields := newFieldTally(), newFieldTally()
+	orig, err := count(ctx, original, c.config.Format, origFields)
+	if err != nil {
+		return FieldReport{Result: c.failed("original", err, mode)}
+	}
+	aug, err := count(ctx, augmented, c.config.Format, augFields)
+	if err != nil {
+		return FieldReport{Result: c.failed("augmented", err, mode)}
+	}
+
+	report := FieldReport{Result: c.result(orig, aug, mode)}
+	cfg := c.scoringConfig()
+	add := func(name string) {
+		o, a := origFields.byName[name], augFields.byName[name]
+		fs := FieldScore{Field: name}
+		switch {
+		case o == nil:
+			fs.Missing = "original"
+			o = &fieldCount{}
+		case a == nil:
+			fs.Missing = "augmented"
+			a = &fieldCount{}
+		}
+		fs.OriginalLength, fs.AugmentedLength = o.runes, a.runes
+		fs.OriginalNodes, fs.AugmentedNodes = o.nodes, a.nodes
+		if fs.Missing == "" {
+			outcome := scoring.Evaluate(o.runes, a.runes, cfg)
+			fs.Score, fs.LengthRatio, fs.Passed = outcome.Score, outcome.LengthRatio, outcome.Passed
+		}
+		report.Fields = append(report.Fields, fs)
+	}
+	for _, name := range origFields.order {
+		add(name)
+	}
+	for _, name := range augFields.order {
+		if origFields.byName[name] == nil {
+			add(name)
+		}
+	}
+	return report
+}
@@ -0,0 +1,409 @@
+// Package structure implements the structural length metric: it parses JSON
+// or XML payloads and compares how many keys, elements and values they hold
+// rather than how many characters, so indentation, key order and other
+// formatting differences do not affect the score.
+package structure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
+)
+
+// Format is the syntax of a structured payload
+type Format string
+
+const (
+	// Auto detects the format of each input from its first non-space byte:
+	// '{' or '[' for JSON, '<' for XML
+	Auto Format = ""
+	JSON Format = "json"
+	XML  Format = "xml"
+)
+
+// ErrUnknownFormat is returned when Auto cannot tell the format of an input
+var ErrUnknownFormat = errors.New("structure: input is neither JSON nor XML")
+
+// Counts is the structure of one payload. JSON payloads fill Objects, Arrays,
+// Keys and Values; XML payloads fill Elements, Attributes and Texts.
+type Counts struct {
+	Format Format
+	// JSON: objects and arrays, object keys, and scalar values
+	Objects int
+	Arrays  int
+	Keys    int
+	Values  int
+	// XML: elements, attributes and non-blank text nodes
+	Elements   int
+	Attributes int
+	Texts      int
+}
+
+// Nodes returns the number of structural nodes, the length the metric compares
+func (c Counts) Nodes() int {
+	return c.Objects + c.Arrays + c.Keys + c.Values + c.Elements + c.Attributes + c.Texts
+}
+
+// details returns the counts for result details
+func (c Counts) details() map[string]interface{} {
+	if c.Format == XML {
+		return map[string]interface{}{"format": string(c.Format), "elements": c.Elements, "attributes": c.Attributes, "texts": c.Texts}
+	}
+	return map[string]interface{}{"format": string(c.Format), "objects": c.Objects, "arrays": c.Arrays, "keys": c.Keys, "values": c.Values}
+}
+
+// Config holds the parameters of the structural metric
+type Config struct {
+	Threshold    float64
+	MaxDiffRatio float64
+	// Format of both inputs; Auto detects each one separately
+	Format Format
+}
+
+// DefaultConfig returns a default configuration
+func DefaultConfig() Config {
+	return Config{
+		Threshold:    scoring.DefaultThreshold,
+		MaxDiffRatio: scoring.DefaultMaxDiffRatio,
+	}
+}
+
+// Validate checks if the configuration is valid
+func (c Config) Validate() error {
+	if err := domain.ValidateThreshold(c.Threshold); err != nil {
+		return err
+	}
+	if err := domain.ValidateMaxDiffRatio(c.MaxDiffRatio); err != nil {
+		return err
+	}
+	switch c.Format {
+	case Auto, JSON, XML:
+	default:
+		return domain.NewConfigError("format", c.Format, "must be json, xml or empty for auto-detection")
+	}
+	return nil
+}
+
+// Calculator scores two payloads by their structural node counts with the
+// same diff-ratio formula as the length metrics
+type Calculator struct {
+	config Config
+}
+
+// NewCalculator creates a new structural calculator
+func NewCalculator(config Config) (*Calculator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Calculator{config: config}, nil
+}
+
+// Compute parses both payloads and compares their node counts
+func (c *Calculator) Compute(ctx context.Context, original, augmented string) domain.Result {
+	return c.compute(ctx, strings.NewReader(original), strings.NewReader(augmented), domain.ModeText)
+}
+
+// ComputeFromReaders is Compute over readers. Payloads are parsed token by
+// token, so they are never held in memory whole.
+func (c *Calculator) ComputeFromReaders(ctx context.Context, original, augmented io.Reader) domain.Result {
+	return c.compute(ctx, original, augmented, domain.ModeStream)
+}
+
+func (c *Calculator) compute(ctx context.Context, original, augmented io.Reader, mode string) domain.Result {
+	orig, err := Count(ctx, original, c.config.Format)
+	if err != nil {
+		return c.failed("original", err, mode)
+	}
+	aug, err := Count(ctx, augmented, c.config.Format)
+	if err != nil {
+		return c.failed("augmented", err, mode)
+	}
+
+	return c.result(orig, aug, mode)
+}
+
+// scoringConfig returns the shared scoring parameters
+func (c *Calculator) scoringConfig() scoring.Config {
+	return scoring.Config{
+		Threshold:    c.config.Threshold,
+		MaxDiffRatio: c.config.MaxDiffRatio,
+		Precision:    2,
+	}
+}
+
+// result scores two payloads by their node counts
+func (c *Calculator) result(orig, aug Counts, mode string) domain.Result {
+	origLen, augLen := orig.Nodes(), aug.Nodes()
+	outcome := scoring.Evaluate(origLen, augLen, c.scoringConfig())
+	return domain.Result{
+		Name:            "structural_similarity",
+		Engine:          domain.EngineStructure,
+		Mode:            mode,
+		Score:           outcome.Score,
+		Passed:          outcome.Passed,
+		OriginalLength:  origLen,
+		AugmentedLength: augLen,
+		LengthRatio:     outcome.LengthRatio,
+		Threshold:       c.config.Threshold,
+		Details: map[string]interface{}{
+			"original_length":  origLen,
+			"augmented_length": augLen,
+			"length_ratio":     outcome.LengthRatio,
+			"threshold":        c.config.Threshold,
+			"original_counts":  orig.details(),
+			"augmented_counts": aug.details(),
+			"original_format":  string(orig.Format),
+			"augmented_format": string(aug.Format),
+		},
+	}
+}
+
+// failed reports an input that could not be parsed or a cancelled computation
+func (c *Calculator) failed(side string, err error, mode string) domain.Result {
+	message := fmt.Sprintf("%s: %v", side, err)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		message = "computation cancelled"
+	}
+	return domain.Result{
+		Name:      "structural_similarity",
+		Engine:    domain.EngineStructure,
+		Mode:      mode,
+		Threshold: c.config.Threshold,
+		Details:   map[string]interface{}{"error": message},
+	}
+}
+
+// Count parses r as format (Auto detects it) and counts its structural nodes.
+// JSON input may hold several top-level values, as JSON Lines does.
+func Count(ctx context.Context, r io.Reader, format Format) (Counts, error) {
+	return count(ctx, r, format, nil)
+}
+
+// count is Count, also attributing every node to its top-level field when
+// fields is not nil
+func count(ctx context.Context, r io.Reader, format Format, fields *fieldTally) (Counts, error) {
+	br := bufio.NewReader(r)
+	if format == Auto {
+		var err error
+		if format, err = detect(br); err != nil {
+			return Counts{Format: Auto}, err
+		}
+	}
+
+	switch format {
+	case JSON:
+		return countJSON(ctx, br, fields)
+	case XML:
+		return countXML(ctx, br, fields)
+	}
+	return Counts{Format: format}, ErrUnknownFormat
+}
+
+// detect peeks at the first non-space byte of r
+func detect(r *bufio.Reader) (Format, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return Auto, ErrUnknownFormat
+			}
+			return Auto, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return JSON, r.UnreadByte()
+		case '<':
+			return XML, r.UnreadByte()
+		case 0xEF:
+			// A UTF-8 byte order mark
+			if rest, err := r.Peek(2); err == nil && rest[0] == 0xBB && rest[1] == 0xBF {
+				r.Discard(2)
+				continue
+			}
+		}
+		return Auto, ErrUnknownFormat
+	}
+}
+
+// checkEvery is how many tokens are read between context checks
+const checkEvery = 1024
+
+// countJSON counts the tokens of a JSON stream. Fields are the keys of
+// top-level objects, or the indexes of top-level arrays.
+func countJSON(ctx context.Context, r io.Reader, fields *fieldTally) (Counts, error) {
+	counts := Counts{Format: JSON}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	// inObject[i] is set when level i is an object; expectKey[i] when its next
+	// string is a key
+	var inObject, expectKey []bool
+	// index counts the values of a top-level array
+	index := 0
+	valueDone := func() {
+		if n := len(inObject); n > 0 && inObject[n-1] {
+			expectKey[n-1] = true
+		} else if n == 1 {
+			index++
+		}
+	}
+	// startValue names the field of a value starting at the current depth
+	startValue := func() {
+		if len(inObject) == 1 && !inObject[0] {
+			fields.start("[" + strconv.Itoa(index) + "]")
+		}
+	}
+
+	for tokens := 0; ; tokens++ {
+		if tokens%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return counts, err
+			}
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(inObject) > 0 {
+				// The stream ended inside an object or array
+				return counts, io.ErrUnexpectedEOF
+			}
+			return counts, nil
+		}
+		if err != nil {
+			return counts, err
+		}
+
+		n := len(inObject)
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				startValue()
+				if t == '{' {
+					counts.Objects++
+				} else {
+					counts.Arrays++
+				}
+				fields.node(0)
+				if n == 0 {
+					index = 0
+				}
+				inObject = append(inObject, t == '{')
+				expectKey = append(expectKey, t == '{')
+			case '}', ']':
+				inObject, expectKey = inObject[:n-1], expectKey[:n-1]
+				if n == 1 {
+					fields.end()
+				}
+				valueDone()
+			}
+		case string:
+			if n > 0 && inObject[n-1] && expectKey[n-1] {
+				if n == 1 {
+					fields.start(t)
+				}
+				counts.Keys++
+				fields.node(0)
+				expectKey[n-1] = false
+				continue
+			}
+			startValue()
+			counts.Values++
+			fields.node(utf8.RuneCountInString(t))
+			valueDone()
+		default:
+			startValue()
+			counts.Values++
+			fields.node(len(jsonLiteral(t)))
+			valueDone()
+		}
+	}
+}
+
+// jsonLiteral returns the text of a scalar token other than a string
+func jsonLiteral(tok json.Token) string {
+	switch t := tok.(type) {
+	case json.Number:
+		return string(t)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	return "null"
+}
+
+// countXML counts the elements, attributes and non-blank text of an XML
+// stream. Fields are the root element's attributes (@name), its children by
+// name and its own text (#text).
+func countXML(ctx context.Context, r io.Reader, fields *fieldTally) (Counts, error) {
+	counts := Counts{Format: XML}
+	dec := xml.NewDecoder(r)
+	depth := 0
+
+	for tokens := 0; ; tokens++ {
+		if tokens%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return counts, err
+			}
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return counts, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				fields.start(t.Name.Local)
+			}
+			counts.Elements++
+			fields.node(0)
+			for _, attr := range t.Attr {
+				// Namespace declarations are syntax, not data
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				if depth == 1 {
+					fields.start("@" + attr.Name.Local)
+				}
+				counts.Attributes++
+				fields.node(utf8.RuneCountInString(attr.Value))
+				if depth == 1 {
+					fields.end()
+				}
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				fields.end()
+			}
+			depth--
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" {
+				continue
+			}
+			if depth == 1 {
+				fields.start("#text")
+			}
+			counts.Texts++
+			fields.node(utf8.RuneCountInString(text))
+			if depth == 1 {
+				fields.end()
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package structure
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPrettyPrintingDoesNotChangeJSONScore(t *testing.T) {
+	compact := `{"user":{"id":7,"tags":["a","b"],"active":true},"note":null}`
+	pretty := "{\n  \"note\": null,\n  \"user\": {\n    \"active\": true,\n    \"id\": 7,\n    \"tags\": [\n      \"a\",\n      \"b\"\n    ]\n  }\n}\n"
+
+	c, err := NewCalculator(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := c.Compute(context.Background(), compact, pretty)
+	if result.Score != 1 || !result.Passed {
+		t.Errorf("got score %v, want 1: %v", result.Score, result.Details)
+	}
+	// 2 objects, 1 array, 5 keys, 5 scalar values
+	if result.OriginalLength != 13 || result.AugmentedLength != 13 {
+		t.Errorf("got %d/%d nodes, want 13/13", result.OriginalLength, result.AugmentedLength)
+	}
+}
+
+func TestCountJSONLines(t *testing.T) {
+	counts, err := Count(context.Background(), strings.NewReader("{\"a\":1}\n{\"a\":2,\"b\":\"x\"}\n"), Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Counts{Format: JSON, Objects: 2, Keys: 3, Values: 3}
+	if counts != want {
+		t.Errorf("got %+v, want %+v", counts, want)
+	}
+}
+
+func TestCountXML(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <!-- two entries -->
+  <entry id="1" lang="en"><title>First</title></entry>
+  <entry id="2"><title><![CDATA[Second]]></title><empty/></entry>
+</feed>`
+	counts, err := Count(context.Background(), iotest.OneByteReader(strings.NewReader(doc)), Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Counts{Format: XML, Elements: 6, Attributes: 3, Texts: 2}
+	if counts != want {
+		t.Errorf("got %+v, want %+v", counts, want)
+	}
+}
+
+func TestStructuralDifferenceLowersScore(t *testing.T) {
+	c, err := NewCalculator(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := c.Compute(context.Background(), `<a><b>1</b><b>2</b><b>3</b><b>4</b></a>`, `<a><b>1</b></a>`)
+	if result.Passed || result.Score >= 1 {
+		t.Errorf("expected a dropped subtree to fail, got %v", result.Score)
+	}
+}
+
+func TestInvalidInputIsReported(t *testing.T) {
+	c, err := NewCalculator(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, augmented := range []string{`{"a":`, `plain text`, `<a><b></a>`} {
+		result := c.Compute(context.Background(), `{"a":1}`, augmented)
+		if result.Passed || result.Details["error"] == nil {
+			t.Errorf("%q: expected an error result, got %v", augmented, result.Details)
+		}
+	}
+
+	if _, err := NewCalculator(Config{Threshold: 0.7, MaxDiffRatio: 0.3, Format: "yaml"}); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
+
+func TestCompareFieldsShowsWhichFieldChanged(t *testing.T) {
+	c, err := NewCalculator(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := `{"title":"Quarterly report","body":"Revenue grew in every region.","tags":["q3","finance"]}`
+	augmented := "{\n  \"tags\": [\"q3\", \"finance\"],\n  \"title\": \"Quarterly report\",\n  \"body\": \"Revenue grew.\",\n  \"extra\": 1\n}"
+
+	report := c.CompareFields(context.Background(), original, augmented)
+	var names []string
+	for _, f := range report.Fields {
+		names = append(names, f.Field)
+	}
+	if got := strings.Join(names, ","); got != "title,body,tags,extra" {
+		t.Fatalf("got fields %s, want title,body,tags,extra", got)
+	}
+
+	title, body, tags, extra := report.Fields[0], report.Fields[1], report.Fields[2], report.Fields[3]
+	if title.Score != 1 || tags.Score != 1 || tags.OriginalNodes != 4 {
+		t.Errorf("unchanged fields should score 1: %+v %+v", title, tags)
+	}
+	if body.OriginalLength != 29 || body.AugmentedLength != 13 || body.Passed {
+		t.Errorf("shortened body: %+v", body)
+	}
+	if extra.Missing != "original" || extra.AugmentedNodes != 2 || extra.AugmentedLength != 1 {
+		t.Errorf("added field: %+v", extra)
+	}
+}
+
+func TestCompareFieldsXML(t *testing.T) {
+	c, err := NewCalculator(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := `<doc lang="en">Intro<item>one</item><item>two</item><note>n</note></doc>`
+	augmented := `<doc lang="en">Intro<item>one</item></doc>`
+
+	report := c.CompareFields(context.Background(), original, augmented)
+	want := []FieldScore{
+		{Field: "@lang", OriginalLength: 2, AugmentedLength: 2, OriginalNodes: 1, AugmentedNodes: 1},
+		{Field: "#text", OriginalLength: 5, AugmentedLength: 5, OriginalNodes: 1, AugmentedNodes: 1},
+		{Field: "item", OriginalLength: 6, AugmentedLength: 3, OriginalNodes: 4, AugmentedNodes: 2},
+		{Field: "note", OriginalLength: 1, OriginalNodes: 2, Missing: "augmented"},
+	}
+	if len(report.Fields) != len(want) {
+		t.Fatalf("got %+v", report.Fields)
+	}
+	for i, w := range want {
+		got := report.Fields[i]
+		got.Score, got.LengthRatio, got.Passed = 0, 0, false
+		if got != w {
+			t.Errorf("field %d: got %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestCompareFieldsReportsParseErrors(t *testing.T) {
+	c, err := NewCalculator(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := c.CompareFields(context.Background(), `{"a":1}`, `{"a":`)
+	if report.Result.Passed || report.Result.Details["error"] == nil || report.Fields != nil {
+		t.Errorf("expected a failed result without fields: %+v", report)
+	}
+}
@@ -0,0 +1,194 @@
+// Package jsonschema generates JSON Schemas from Go structs and validates
+// encoded payloads against them. It covers the subset the result payloads
+// use: objects, arrays, strings, numbers, booleans, free-form maps and
+// time.Time. Properties keep the struct's field order, which is also the
+// order encoding/json writes them in, so a schema documents the field order
+// consumers will see.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// BaseID prefixes the $id of the schemas published in the repository's schema directory
+const BaseID = "https://github.com/baditaflorin/go_length_similarity/schema/"
+
+// Schema is one JSON Schema node
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is a type name, or a list of names when null is allowed too
+	Type                 interface{} `json:"type,omitempty"`
+	Format               string      `json:"format,omitempty"`
+	Properties           Properties  `json:"properties,omitempty"`
+	Required             []string    `json:"required,omitempty"`
+	AdditionalProperties *bool       `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+}
+
+// Property is a named property of an object schema
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties are an object's properties in declaration order
+type Properties []Property
+
+// MarshalJSON writes the properties as an object, keeping their order
+func (p Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// lookup returns the schema of the named property
+func (p Properties) lookup(name string) (*Schema, int, bool) {
+	for i, prop := range p {
+		if prop.Name == name {
+			return prop.Schema, i, true
+		}
+	}
+	return nil, 0, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate returns the schema of the JSON encoding of v's type, with id and
+// title set on the root
+func Generate(v interface{}, id, title string) *Schema {
+	s := generate(reflect.TypeOf(v))
+	s.Schema = Draft
+	s.ID = id
+	s.Title = title
+	return s
+}
+
+// Marshal encodes s as indented JSON ending in a newline, the form the
+// published schema files are stored in
+func Marshal(s *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func generate(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := generate(t.Elem())
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+		return s
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) {
+		// Custom encodings are opaque to reflection
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		return generateStruct(t)
+	}
+	return &Schema{}
+}
+
+func generateStruct(t reflect.Type) *Schema {
+	closed := false
+	s := &Schema{Type: "object", AdditionalProperties: &closed}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := generate(f.Type)
+		omitEmpty := strings.Contains(","+opts+",", ",omitempty,")
+		switch kind := f.Type.Kind(); {
+		case kind == reflect.Pointer && omitEmpty:
+			// An omitted pointer is never written as null
+			if types, ok := prop.Type.([]string); ok {
+				prop.Type = types[0]
+			}
+		case (kind == reflect.Slice || kind == reflect.Map) && !omitEmpty:
+			// A nil slice or map is written as null
+			prop.Type = []string{prop.Type.(string), "null"}
+		}
+		s.Properties = append(s.Properties, Property{Name: name, Schema: prop})
+		if !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// CheckFile reports whether the published schema at path matches s. With
+// update set it rewrites the file instead, for regenerating after a
+// deliberate change to the structs.
+func CheckFile(path string, s *Schema, update bool) error {
+	want, err := Marshal(s)
+	if err != nil {
+		return err
+	}
+	if update {
+		return os.WriteFile(path, want, 0o644)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%s is out of date with its Go struct; if the change is intended, regenerate it with -update", path)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Margin float64 `json:"margin"`
+}
+
+type payload struct {
+	ID       string                 `json:"id"`
+	Count    int                    `json:"count"`
+	Ratio    float64                `json:"ratio"`
+	OK       bool                   `json:"ok"`
+	At       time.Time              `json:"at"`
+	Tags     []string               `json:"tags"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Inner    *inner                 `json:"inner,omitempty"`
+	Maybe    *inner                 `json:"maybe"`
+	Skipped  string                 `json:"-"`
+	Untagged int
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(payload{}, "https://example.com/payload.json", "Payload")
+	data, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	for _, want := range []string{
+		`"$schema": "` + Draft + `"`,
+		`"$id": "https://example.com/payload.json"`,
+		`"format": "date-time"`,
+		`"additionalProperties": false`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("schema lacks %s:\n%s", want, text)
+		}
+	}
+	// Properties keep the struct's order
+	if strings.Index(text, `"id"`) > strings.Index(text, `"count"`) || strings.Index(text, `"inner"`) > strings.Index(text, `"Untagged"`) {
+		t.Errorf("properties are out of declaration order:\n%s", text)
+	}
+	if strings.Contains(text, "Skipped") {
+		t.Error("schema includes a json:\"-\" field")
+	}
+
+	var decoded struct {
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(decoded.Required, ","); got != "id,count,ratio,ok,at,tags,maybe,Untagged" {
+		t.Errorf("required = %s", got)
+	}
+}
+
+func TestValidateEncodedValues(t *testing.T) {
+	s := Generate(payload{}, "", "")
+	for _, v := range []payload{
+		{},
+		{ID: "x", Count: 3, Ratio: 0.5, OK: true, At: time.Now(), Tags: []string{"a"},
+			Details: map[string]interface{}{"z": 1, "a": []int{1}}, Inner: &inner{Margin: 1}, Maybe: &inner{}},
+	} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(s, data); err != nil {
+			t.Errorf("Validate(%s) = %v", data, err)
+		}
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	s := Generate(inner{}, "", "")
+	nested := Generate(payload{}, "", "")
+	valid := `"id":"x","count":1,"ratio":1,"ok":true,"at":"2024-01-02T03:04:05Z","tags":null,"maybe":null,"Untagged":0`
+	tests := []struct {
+		name   string
+		schema *Schema
+		data   string
+	}{
+		{"wrong type", s, `{"margin":"wide"}`},
+		{"missing required", s, `{}`},
+		{"undeclared property", s, `{"margin":1,"extra":true}`},
+		{"float for integer", nested, `{` + strings.Replace(valid, `"count":1`, `"count":1.5`, 1) + `}`},
+		{"bad date-time", nested, `{` + strings.Replace(valid, `2024-01-02T03:04:05Z`, `yesterday`, 1) + `}`},
+		{"out of order", nested, `{"count":1,` + strings.Replace(valid, `"count":1,`, ``, 1) + `}`},
+		{"nested", nested, `{` + valid + `,"inner":{"margin":null}}`},
+		{"trailing data", s, `{"margin":1} {}`},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.schema, []byte(tt.data)); err == nil {
+			t.Errorf("%s: Validate(%s) succeeded", tt.name, tt.data)
+		}
+	}
+	if err := Validate(nested, []byte(`{`+valid+`}`)); err != nil {
+		t.Errorf("valid payload: %v", err)
+	}
+}
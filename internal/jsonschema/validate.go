@@ -0,0 +1,139 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Validate checks that data is a single JSON value matching s. Objects must
+// hold every required property, no undeclared ones when additional
+// properties are closed, and their properties in the schema's order.
+func Validate(s *Schema, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := validateValue(dec, s, "$"); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("$: trailing data after the value")
+	}
+	return nil
+}
+
+func validateValue(dec *json.Decoder, s *Schema, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var kind string
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			kind = "object"
+		} else {
+			kind = "array"
+		}
+	case string:
+		kind = "string"
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", path, v)
+			}
+		}
+	case json.Number:
+		kind = "number"
+		if !strings.ContainsAny(v.String(), ".eE") {
+			kind = "integer"
+		}
+	case bool:
+		kind = "boolean"
+	case nil:
+		kind = "null"
+	}
+	if !s.allows(kind) {
+		return fmt.Errorf("%s: got %s, want %v", path, kind, s.Type)
+	}
+
+	switch kind {
+	case "object":
+		return validateObject(dec, s, path)
+	case "array":
+		items := s.Items
+		if items == nil {
+			items = &Schema{}
+		}
+		for i := 0; dec.More(); i++ {
+			if err := validateValue(dec, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+	return nil
+}
+
+func validateObject(dec *json.Decoder, s *Schema, path string) error {
+	var seen []string
+	last := -1
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		name := tok.(string)
+		seen = append(seen, name)
+
+		prop, index, ok := s.Properties.lookup(name)
+		switch {
+		case ok:
+			if index < last {
+				return fmt.Errorf("%s: property %q is out of order", path, name)
+			}
+			last = index
+		case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+			return fmt.Errorf("%s: undeclared property %q", path, name)
+		default:
+			prop = &Schema{}
+		}
+		if err := validateValue(dec, prop, path+"."+name); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	for _, name := range s.Required {
+		if !slices.Contains(seen, name) {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+	return nil
+}
+
+// allows reports whether a value of kind matches the schema's type; an
+// integer also matches number, and a schema without a type matches anything
+func (s *Schema) allows(kind string) bool {
+	var types []string
+	switch t := s.Type.(type) {
+	case nil:
+		return true
+	case string:
+		types = []string{t}
+	case []string:
+		types = t
+	}
+	for _, typ := range types {
+		if typ == kind || typ == "number" && kind == "integer" {
+			return true
+		}
+	}
+	return false
+}
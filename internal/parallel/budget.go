@@ -0,0 +1,60 @@
+// Package parallel holds the process-wide concurrency budget shared by every
+// component that fans work out to goroutines.
+package parallel
+
+import "sync"
+
+var (
+	mu    sync.Mutex
+	limit int // 0 means unlimited
+	inUse int
+)
+
+// SetMax caps the total number of worker goroutines that parallel components
+// may run at once across the process. n <= 0 removes the cap.
+func SetMax(n int) {
+	mu.Lock()
+	defer mu.Unlock()
+	limit = max(n, 0)
+}
+
+// Max returns the current cap, or 0 when there is none
+func Max() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return limit
+}
+
+// InUse returns the number of workers currently reserved from the budget
+func InUse() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return inUse
+}
+
+// Acquire reserves up to want workers from the budget. It never blocks: it
+// returns how many workers the caller may start and a function that returns
+// them to the budget. The count is always at least 1 so every operation can
+// make progress; when the budget is exhausted that single worker is not
+// reserved, so the cap bounds the extra goroutines rather than the callers.
+func Acquire(want int) (int, func()) {
+	want = max(want, 1)
+
+	mu.Lock()
+	granted := want
+	if limit > 0 {
+		granted = min(want, max(limit-inUse, 0))
+	}
+	inUse += granted
+	mu.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			mu.Lock()
+			inUse -= granted
+			mu.Unlock()
+		})
+	}
+	return max(granted, 1), release
+}
@@ -0,0 +1,34 @@
+package parallel
+
+import "testing"
+
+func TestAcquireRespectsLimit(t *testing.T) {
+	SetMax(4)
+	t.Cleanup(func() { SetMax(0) })
+
+	n1, release1 := Acquire(3)
+	n2, release2 := Acquire(3)
+	n3, release3 := Acquire(3)
+	if n1 != 3 || n2 != 1 || n3 != 1 {
+		t.Fatalf("granted %d, %d, %d; want 3, 1, 1", n1, n2, n3)
+	}
+	if InUse() != 4 {
+		t.Fatalf("InUse = %d, want 4", InUse())
+	}
+
+	release3()
+	release2()
+	release1()
+	release1() // releasing twice is harmless
+	if InUse() != 0 {
+		t.Fatalf("InUse after release = %d, want 0", InUse())
+	}
+}
+
+func TestAcquireUnlimited(t *testing.T) {
+	n, release := Acquire(64)
+	defer release()
+	if n != 64 {
+		t.Fatalf("granted %d, want 64", n)
+	}
+}
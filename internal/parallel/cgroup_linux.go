@@ -0,0 +1,50 @@
+package parallel
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Paths are variables so tests can point them at fixtures
+var (
+	cgroupV2CPUMax   = "/sys/fs/cgroup/cpu.max"
+	cgroupV1QuotaUS  = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cgroupV1PeriodUS = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+)
+
+// readCPUQuota returns the CPU limit from cgroup v2 or v1, or 0 when unlimited or unknown
+func readCPUQuota() float64 {
+	if data, err := os.ReadFile(cgroupV2CPUMax); err == nil {
+		// Format: "<quota|max> <period>"
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			return ratio(fields[0], fields[1])
+		}
+		return 0
+	}
+
+	quota, err := os.ReadFile(cgroupV1QuotaUS)
+	if err != nil {
+		return 0
+	}
+	period, err := os.ReadFile(cgroupV1PeriodUS)
+	if err != nil {
+		return 0
+	}
+	// A quota of -1 means unlimited, which ratio reports as 0
+	return ratio(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+// ratio parses quota/period, returning 0 for non-positive or malformed values
+func ratio(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
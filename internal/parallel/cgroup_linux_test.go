@@ -0,0 +1,49 @@
+package parallel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCPUQuota(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	saved := [3]string{cgroupV2CPUMax, cgroupV1QuotaUS, cgroupV1PeriodUS}
+	t.Cleanup(func() { cgroupV2CPUMax, cgroupV1QuotaUS, cgroupV1PeriodUS = saved[0], saved[1], saved[2] })
+
+	cases := []struct {
+		name              string
+		v2, quota, period string
+		want              float64
+	}{
+		{name: "v2 limited", v2: "150000 100000\n", want: 1.5},
+		{name: "v2 unlimited", v2: "max 100000\n", want: 0},
+		{name: "v1 limited", quota: "200000\n", period: "100000\n", want: 2},
+		{name: "v1 unlimited", quota: "-1\n", period: "100000\n", want: 0},
+		{name: "no cgroup", want: 0},
+	}
+	for _, tc := range cases {
+		cgroupV2CPUMax = filepath.Join(dir, "missing")
+		cgroupV1QuotaUS = filepath.Join(dir, "missing")
+		cgroupV1PeriodUS = filepath.Join(dir, "missing")
+		if tc.v2 != "" {
+			cgroupV2CPUMax = write(tc.name+"-cpu.max", tc.v2)
+		}
+		if tc.quota != "" {
+			cgroupV1QuotaUS = write(tc.name+"-quota", tc.quota)
+			cgroupV1PeriodUS = write(tc.name+"-period", tc.period)
+		}
+
+		if got := readCPUQuota(); got != tc.want {
+			t.Errorf("%s: readCPUQuota() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
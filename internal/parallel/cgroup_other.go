@@ -0,0 +1,8 @@
+//go:build !linux
+
+package parallel
+
+// readCPUQuota reports no limit; cgroups only exist on Linux
+func readCPUQuota() float64 {
+	return 0
+}
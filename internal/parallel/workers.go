@@ -0,0 +1,50 @@
+package parallel
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// cpuQuota is read once; cgroup limits rarely change for the life of a process
+var cpuQuota = sync.OnceValue(readCPUQuota)
+
+// DefaultWorkers returns the number of workers a parallel component should
+// start: GOMAXPROCS (which already honours CPU affinity), further limited by
+// the container's cgroup CPU quota so a pod limited to two CPUs does not run
+// one worker per host core.
+func DefaultWorkers() int {
+	workers := runtime.GOMAXPROCS(0)
+	if quota := cpuQuota(); quota > 0 {
+		workers = min(workers, int(math.Ceil(quota)))
+	}
+	return max(workers, 1)
+}
+
+// CPUQuota returns the cgroup CPU limit in CPUs, or 0 when there is none
+func CPUQuota() float64 {
+	return cpuQuota()
+}
+
+// TuneGOMAXPROCS lowers GOMAXPROCS to the cgroup CPU quota when the quota is
+// smaller, unless the GOMAXPROCS environment variable was set explicitly.
+// It returns the previous and current values.
+func TuneGOMAXPROCS() (before, after int) {
+	before = runtime.GOMAXPROCS(0)
+	if os.Getenv("GOMAXPROCS") != "" {
+		return before, before
+	}
+
+	quota := cpuQuota()
+	if quota <= 0 {
+		return before, before
+	}
+
+	after = max(int(math.Ceil(quota)), 1)
+	if after >= before {
+		return before, before
+	}
+	runtime.GOMAXPROCS(after)
+	return before, after
+}
@@ -0,0 +1,45 @@
+package parallel
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// yieldInterval is how many bytes the counting loops process between yields; 0 disables yielding
+var yieldInterval atomic.Int64
+
+// SetYieldInterval makes the counting loops call runtime.Gosched after every
+// n bytes they read, so one very large comparison leaves room for other
+// goroutines when there are few Ps. n <= 0 disables yielding (the default).
+func SetYieldInterval(n int) {
+	yieldInterval.Store(int64(max(n, 0)))
+}
+
+// YieldInterval returns the interval set by SetYieldInterval, or 0 when yielding is disabled
+func YieldInterval() int {
+	return int(yieldInterval.Load())
+}
+
+// Yielder paces one loop. It reads the interval once, so a loop keeps a
+// steady rhythm when the setting changes mid-stream.
+type Yielder struct {
+	every   int64
+	pending int64
+}
+
+// NewYielder creates a Yielder with the current interval
+func NewYielder() Yielder {
+	return Yielder{every: yieldInterval.Load()}
+}
+
+// Add records n processed bytes, yielding the processor once the interval is reached
+func (y *Yielder) Add(n int) {
+	if y.every <= 0 {
+		return
+	}
+	y.pending += int64(n)
+	if y.pending >= y.every {
+		y.pending = 0
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,30 @@
+package parallel
+
+import "testing"
+
+func TestYielderPacesByInterval(t *testing.T) {
+	SetYieldInterval(100)
+	t.Cleanup(func() { SetYieldInterval(0) })
+
+	y := NewYielder()
+	// A later change does not affect a running loop
+	SetYieldInterval(-5)
+	if YieldInterval() != 0 {
+		t.Fatalf("YieldInterval = %d, want 0 for a negative interval", YieldInterval())
+	}
+
+	y.Add(60)
+	if y.pending != 60 {
+		t.Fatalf("pending = %d, want 60", y.pending)
+	}
+	y.Add(60)
+	if y.pending != 0 {
+		t.Fatalf("pending = %d after passing the interval, want 0", y.pending)
+	}
+
+	off := NewYielder()
+	off.Add(1 << 30)
+	if off.pending != 0 {
+		t.Errorf("a disabled yielder should not track bytes, pending = %d", off.pending)
+	}
+}
@@ -5,28 +5,26 @@ import (
 	"sync"
 )
 
-// BufferPool implements a pool of byte slices for efficient memory reuse
+// BufferPool implements a sharded pool of byte slices for efficient memory reuse
 type BufferPool struct {
-	pool sync.Pool
+	pool *Sharded[*[]byte]
 	size int
 }
 
 // NewBufferPool creates a new buffer pool with buffers of the specified size
 func NewBufferPool(size int) *BufferPool {
 	return &BufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				buffer := make([]byte, 0, size)
-				return &buffer
-			},
-		},
+		pool: NewSharded(func() *[]byte {
+			buffer := make([]byte, 0, size)
+			return &buffer
+		}, ChunkShardCapacity),
 		size: size,
 	}
 }
 
 // Get retrieves a buffer from the pool or creates a new one if none are available
 func (bp *BufferPool) Get() *[]byte {
-	return bp.pool.Get().(*[]byte)
+	return bp.pool.Get()
 }
 
 // Put returns a buffer to the pool for reuse
@@ -36,6 +34,11 @@ func (bp *BufferPool) Put(buffer *[]byte) {
 	bp.pool.Put(buffer)
 }
 
+// Drain drops all pooled buffers
+func (bp *BufferPool) Drain() {
+	bp.pool.Drain()
+}
+
 // StringBuilderPool implements a pool of strings.Builder for efficient string building
 type StringBuilderPool struct {
 	pool sync.Pool
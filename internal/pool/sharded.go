@@ -0,0 +1,150 @@
+package pool
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// Sharded is a pool split into independently locked shards. Get and Put pick
+// a shard at random and never wait for a lock: a contended or empty shard is
+// skipped, so under load a goroutine allocates or drops an item rather than
+// queueing behind other workers. Unlike sync.Pool, pooled items survive
+// garbage collection, so large buffers are not re-allocated after every GC
+// cycle at high request rates.
+type Sharded[T any] struct {
+	shards []shard[T]
+	mask   uint32
+	limit  int
+	new    func() T
+}
+
+// shard is one lock and free list, padded so neighbouring shards do not share a cache line
+type shard[T any] struct {
+	mu    sync.Mutex
+	items []T
+	_     [40]byte
+}
+
+// probes is how many shards Get and Put try before giving up
+const probes = 2
+
+// DefaultShardCapacity is the number of items each shard keeps when no limit is given
+const DefaultShardCapacity = 8
+
+// ChunkShardCapacity bounds chunk-sized buffers per shard so idle pools retain
+// at most a couple of chunks per P
+const ChunkShardCapacity = 2
+
+// NewSharded creates a pool with one shard per P, rounded up to a power of
+// two. Each shard keeps at most perShard items (DefaultShardCapacity if
+// perShard <= 0); newFn creates items when the probed shards are empty.
+func NewSharded[T any](newFn func() T, perShard int) *Sharded[T] {
+	if perShard <= 0 {
+		perShard = DefaultShardCapacity
+	}
+	n := 1
+	for n < runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+	return &Sharded[T]{
+		shards: make([]shard[T], n),
+		mask:   uint32(n - 1),
+		limit:  perShard,
+		new:    newFn,
+	}
+}
+
+// Get returns a pooled item, or a new one when none is free nearby
+func (p *Sharded[T]) Get() T {
+	item, _ := p.TryGet()
+	return item
+}
+
+// TryGet is Get that also reports whether the item was pooled rather than new
+func (p *Sharded[T]) TryGet() (T, bool) {
+	return p.get(rand.Uint32())
+}
+
+// GetHome is Get starting at the shard of home, such as a worker's index.
+// An owner that always passes the same home, with PutHome, keeps reusing
+// buffers its own core touched last instead of ones warm in another's cache.
+func (p *Sharded[T]) GetHome(home int) T {
+	item, _ := p.TryGetHome(home)
+	return item
+}
+
+// TryGetHome is GetHome that also reports whether the item was pooled rather than new
+func (p *Sharded[T]) TryGetHome(home int) (T, bool) {
+	return p.get(uint32(home))
+}
+
+// get probes shards from start, creating an item when they have none free
+func (p *Sharded[T]) get(start uint32) (T, bool) {
+	for i := uint32(0); i < probes; i++ {
+		s := &p.shards[(start+i)&p.mask]
+		if !s.mu.TryLock() {
+			continue
+		}
+		if n := len(s.items); n > 0 {
+			item := s.items[n-1]
+			var zero T
+			s.items[n-1] = zero
+			s.items = s.items[:n-1]
+			s.mu.Unlock()
+			return item, true
+		}
+		s.mu.Unlock()
+	}
+	return p.new(), false
+}
+
+// Put returns an item to the pool. It is dropped when the probed shards are full or busy.
+func (p *Sharded[T]) Put(item T) {
+	p.put(rand.Uint32(), item)
+}
+
+// PutHome returns an item to the shard of home, the owner it was taken for
+func (p *Sharded[T]) PutHome(home int, item T) {
+	p.put(uint32(home), item)
+}
+
+// put probes shards from start
+func (p *Sharded[T]) put(start uint32, item T) {
+	for i := uint32(0); i < probes; i++ {
+		s := &p.shards[(start+i)&p.mask]
+		if !s.mu.TryLock() {
+			continue
+		}
+		if len(s.items) < p.limit {
+			s.items = append(s.items, item)
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Drain drops every pooled item so the memory can be reclaimed. The pool stays
+// usable; later Gets allocate until Puts refill it.
+func (p *Sharded[T]) Drain() {
+	for i := range p.shards {
+		s := &p.shards[i]
+		s.mu.Lock()
+		clear(s.items)
+		s.items = s.items[:0:0]
+		s.mu.Unlock()
+	}
+}
+
+// Len returns the number of pooled items
+func (p *Sharded[T]) Len() int {
+	n := 0
+	for i := range p.shards {
+		s := &p.shards[i]
+		s.mu.Lock()
+		n += len(s.items)
+		s.mu.Unlock()
+	}
+	return n
+}
@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestShardedReusesItems(t *testing.T) {
+	created := 0
+	p := NewSharded(func() *[]byte {
+		created++
+		b := make([]byte, 0, 16)
+		return &b
+	}, 1)
+
+	// With a single goroutine no lock is contended, so at most one item per
+	// shard is ever created before Get starts finding pooled ones
+	for i := 0; i < 100; i++ {
+		b := p.Get()
+		p.Put(b)
+	}
+	if created > len(p.shards) {
+		t.Fatalf("created %d items for %d shards, want reuse", created, len(p.shards))
+	}
+}
+
+func TestShardedDropsWhenFull(t *testing.T) {
+	p := NewSharded(func() int { return 0 }, 1)
+	for i := 0; i < 10*len(p.shards); i++ {
+		p.Put(i + 1)
+	}
+	total := 0
+	for i := range p.shards {
+		if n := len(p.shards[i].items); n > 1 {
+			t.Fatalf("shard %d holds %d items, limit is 1", i, n)
+		} else {
+			total += n
+		}
+	}
+	if total == 0 {
+		t.Fatal("no items retained")
+	}
+}
+
+func TestShardedDrain(t *testing.T) {
+	created := 0
+	p := NewSharded(func() int { created++; return 0 }, 4)
+	for i := 0; i < len(p.shards); i++ {
+		p.Put(i + 1)
+	}
+	if p.Len() == 0 {
+		t.Fatal("no items retained")
+	}
+
+	p.Drain()
+	if n := p.Len(); n != 0 {
+		t.Fatalf("Len after Drain = %d, want 0", n)
+	}
+	// The pool stays usable
+	if v := p.Get(); v != 0 || created != 1 {
+		t.Errorf("Get after Drain = %d with %d created, want a new item", v, created)
+	}
+}
+
+func TestShardedHomeKeepsItemsWithTheirOwner(t *testing.T) {
+	p := NewSharded(func() int { return 0 }, 4)
+	p.PutHome(1, 11)
+	p.PutHome(2, 22)
+	if len(p.shards) > 2 {
+		if got := p.shards[1&p.mask].items; len(got) != 1 || got[0] != 11 {
+			t.Errorf("home 1 shard holds %v, want [11]", got)
+		}
+	}
+	if got := p.GetHome(2); got != 22 {
+		t.Errorf("GetHome(2) = %d, want the item its owner put back", got)
+	}
+	if got := p.GetHome(1); got != 11 {
+		t.Errorf("GetHome(1) = %d, want the item its owner put back", got)
+	}
+}
+
+func TestShardedConcurrent(t *testing.T) {
+	p := NewSharded(func() *[]byte {
+		b := make([]byte, 64)
+		return &b
+	}, 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				b := p.Get()
+				for j := range *b {
+					(*b)[j] = byte(g)
+				}
+				for j := range *b {
+					if (*b)[j] != byte(g) {
+						t.Errorf("buffer shared between goroutines")
+						return
+					}
+				}
+				p.Put(b)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// poolWork touches the buffer and returns request-sized garbage, which drives
+// the GC cycles that empty sync.Pool under a busy server
+func poolWork(buf []byte) []byte {
+	for i := 0; i < len(buf); i += 512 {
+		buf[i] = byte(i)
+	}
+	return make([]byte, 4096)
+}
+
+// BenchmarkPoolContention compares sync.Pool against Sharded for chunk-sized
+// buffers under parallel load. Run with -cpu 1,4,16 to see how each scales.
+func BenchmarkPoolContention(b *testing.B) {
+	const size = 64 * 1024
+
+	b.Run("SyncPool", func(b *testing.B) {
+		p := sync.Pool{New: func() interface{} {
+			buf := make([]byte, size)
+			return &buf
+		}}
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var garbage []byte
+			for pb.Next() {
+				buf := p.Get().(*[]byte)
+				garbage = poolWork(*buf)
+				p.Put(buf)
+			}
+			_ = garbage
+		})
+	})
+
+	b.Run("Sharded", func(b *testing.B) {
+		p := NewSharded(func() *[]byte {
+			buf := make([]byte, size)
+			return &buf
+		}, ChunkShardCapacity)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var garbage []byte
+			for pb.Next() {
+				buf := p.Get()
+				garbage = poolWork(*buf)
+				p.Put(buf)
+			}
+			_ = garbage
+		})
+	})
+}
@@ -9,3 +9,8 @@ import (
 type SimilarityCalculator interface {
 	Compute(ctx context.Context, original, augmented string) domain.Result
 }
+
+// CountScorer scores precomputed normalized lengths with the same rules a calculator applies to texts.
+type CountScorer interface {
+	ComputeCounts(origLen, augLen int) domain.Result
+}
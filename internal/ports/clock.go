@@ -0,0 +1,10 @@
+package ports
+
+import "time"
+
+// Clock tells the time. Calculators read it to time comparisons, so tests
+// can substitute one they control. Implementations must be safe for
+// concurrent use.
+type Clock interface {
+	Now() time.Time
+}
@@ -0,0 +1,10 @@
+package ports
+
+// Hasher fingerprints text for caches, profiles and idempotency keys.
+// Implementations must be safe for concurrent use.
+type Hasher interface {
+	// Sum returns the digest of data
+	Sum(data []byte) []byte
+	// SumString returns the digest of s
+	SumString(s string) []byte
+}
@@ -0,0 +1,14 @@
+package ports
+
+// Metrics records operational measurements, apart from logging. Labels are
+// alternating name and value pairs, like the key-value pairs given to Logger;
+// record a metric with the same label names, in the same order, every time.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Count adds delta to a counter
+	Count(name string, delta float64, labels ...string)
+	// Gauge sets a gauge to value
+	Gauge(name string, value float64, labels ...string)
+	// Observe records value in a histogram
+	Observe(name string, value float64, labels ...string)
+}
@@ -4,3 +4,9 @@ package ports
 type Normalizer interface {
 	Normalize(text string) string
 }
+
+// AppendNormalizer is implemented by normalizers that can write their output
+// into a caller-owned buffer instead of returning a new string.
+type AppendNormalizer interface {
+	AppendNormalized(dst []byte, text string) []byte
+}
@@ -18,6 +18,19 @@ const (
 	WordByWord
 )
 
+// String returns the mode's name: chunk, line or word
+func (m StreamingMode) String() string {
+	switch m {
+	case ChunkByChunk:
+		return "chunk"
+	case LineByLine:
+		return "line"
+	case WordByWord:
+		return "word"
+	}
+	return "unknown"
+}
+
 // StreamProcessor defines the interface for processing text streams
 type StreamProcessor interface {
 	// ProcessStream processes an input stream and returns the length (character or word count)
@@ -37,6 +50,13 @@ type StreamResult struct {
 	LengthRatio     float64
 	Threshold       float64
 	Details         map[string]interface{}
+	// Err is the read or processing error that aborted the comparison, if any
+	Err error
+	// TruncatedInput is set when Err means an input ended unexpectedly
+	TruncatedInput bool
+	// PotentiallyTruncated is set when an input was an io.LimitedReader read
+	// up to its cap, so it may have been cut short
+	PotentiallyTruncated bool
 	// Additional fields relevant to streaming processing
 	BytesProcessed int64
 	ProcessingTime time.Duration
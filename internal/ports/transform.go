@@ -0,0 +1,15 @@
+package ports
+
+import "io"
+
+// Transform rewrites raw text before it is normalized and counted. Apply and
+// Reader must produce the same bytes for the same input, however the stream
+// is split, so in-memory and streaming results agree.
+type Transform interface {
+	// Name identifies the transform in logs and result details
+	Name() string
+	// Apply transforms a whole text
+	Apply(text string) string
+	// Reader transforms a stream
+	Reader(r io.Reader) io.Reader
+}
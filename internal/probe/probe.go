@@ -0,0 +1,126 @@
+// Package probe collects the resources one comparison used. A Probe travels
+// in the context, so processors deep in the pipeline can report buffer sizes
+// and worker counts without widening their signatures.
+package probe
+
+import (
+	"context"
+	"runtime/metrics"
+	"sync/atomic"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// Runtime metrics sampled around a comparison
+const (
+	allocBytesMetric   = "/gc/heap/allocs:bytes"
+	allocObjectsMetric = "/gc/heap/allocs:objects"
+)
+
+type contextKey struct{}
+
+// Probe records resource usage for one comparison. A nil *Probe ignores all
+// records, so callers never need to check whether reporting is enabled.
+type Probe struct {
+	peakBuffer atomic.Int64
+	workers    atomic.Int64
+	poolHits   atomic.Int64
+	poolMisses atomic.Int64
+
+	startBytes   uint64
+	startObjects uint64
+}
+
+// Start creates a probe and samples the allocation counters
+func Start() *Probe {
+	p := &Probe{}
+	p.startBytes, p.startObjects = readAllocs()
+	return p
+}
+
+// NewContext returns a context carrying p
+func NewContext(ctx context.Context, p *Probe) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the probe in ctx, or nil when reporting is disabled
+func FromContext(ctx context.Context) *Probe {
+	p, _ := ctx.Value(contextKey{}).(*Probe)
+	return p
+}
+
+// RecordBuffer notes a buffer of n bytes; the report keeps the largest
+func (p *Probe) RecordBuffer(n int) {
+	if p == nil {
+		return
+	}
+	raiseTo(&p.peakBuffer, int64(n))
+}
+
+// RecordWorkers notes that n workers processed part of the comparison; the report keeps the largest
+func (p *Probe) RecordWorkers(n int) {
+	if p == nil {
+		return
+	}
+	raiseTo(&p.workers, int64(n))
+}
+
+// RecordPoolGet notes a buffer taken from a pool: a hit when a pooled buffer
+// of the right size was reused, a miss when one had to be allocated
+func (p *Probe) RecordPoolGet(hit bool) {
+	if p == nil {
+		return
+	}
+	if hit {
+		p.poolHits.Add(1)
+	} else {
+		p.poolMisses.Add(1)
+	}
+}
+
+// PoolGets returns the pool hits and misses recorded so far
+func (p *Probe) PoolGets() (hits, misses int64) {
+	if p == nil {
+		return 0, 0
+	}
+	return p.poolHits.Load(), p.poolMisses.Load()
+}
+
+// Finish returns the report. Allocation figures are the process-wide delta
+// since Start, so they are only an estimate when comparisons run concurrently.
+func (p *Probe) Finish() *domain.Resources {
+	if p == nil {
+		return nil
+	}
+
+	bytes, objects := readAllocs()
+	return &domain.Resources{
+		AllocatedBytes:  int64(bytes - p.startBytes),
+		Allocations:     int64(objects - p.startObjects),
+		PeakBufferBytes: p.peakBuffer.Load(),
+		Workers:         max(int(p.workers.Load()), 1),
+	}
+}
+
+// raiseTo sets v to n when n is larger
+func raiseTo(v *atomic.Int64, n int64) {
+	for {
+		cur := v.Load()
+		if n <= cur || v.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+// readAllocs returns the cumulative heap allocation counters
+func readAllocs() (bytes, objects uint64) {
+	samples := []metrics.Sample{{Name: allocBytesMetric}, {Name: allocObjectsMetric}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		bytes = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		objects = samples[1].Value.Uint64()
+	}
+	return bytes, objects
+}
@@ -0,0 +1,186 @@
+// Package sketch provides a t-digest for summarizing distributions, such as
+// line lengths, in bounded memory while a stream is processed.
+package sketch
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// DefaultCompression bounds a digest to a few hundred centroids while keeping
+// tail quantiles within a fraction of a percent
+const DefaultCompression = 100
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// TDigest is a merging t-digest (Dunning & Ertl). It is safe for concurrent
+// use, and a nil *TDigest ignores Add so callers need not check for one.
+type TDigest struct {
+	mu          sync.Mutex
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min, max    float64
+}
+
+// New returns an empty digest; compression trades accuracy for size
+func New(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &TDigest{
+		compression: compression,
+		buffer:      make([]centroid, 0, int(5*compression)),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add records one observation
+func (d *TDigest) Add(x float64) {
+	if d == nil || math.IsNaN(x) {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.buffer = append(d.buffer, centroid{mean: x, weight: 1})
+	d.count++
+	d.min = math.Min(d.min, x)
+	d.max = math.Max(d.max, x)
+	if len(d.buffer) == cap(d.buffer) {
+		d.merge()
+	}
+}
+
+// Count returns the number of observations
+func (d *TDigest) Count() int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return int(d.count)
+}
+
+// Quantile estimates the value below which a fraction q of the observations
+// fall. It returns NaN for an empty digest.
+func (d *TDigest) Quantile(q float64) float64 {
+	if d == nil {
+		return math.NaN()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.merge()
+	return d.quantile(q)
+}
+
+// Summary reports the count, extremes and common quantiles, for result
+// details. It returns nil for an empty digest.
+func (d *TDigest) Summary() map[string]float64 {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.count == 0 {
+		return nil
+	}
+	d.merge()
+	return map[string]float64{
+		"count": d.count,
+		"min":   d.min,
+		"p50":   d.quantile(0.5),
+		"p90":   d.quantile(0.9),
+		"p99":   d.quantile(0.99),
+		"max":   d.max,
+	}
+}
+
+// merge folds buffered observations into the centroids, keeping each
+// centroid within one unit of the k1 scale function
+func (d *TDigest) merge() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := append(d.centroids, d.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, len(d.centroids)+1)
+	cur := all[0]
+	soFar := 0.0
+	kLow := d.k(0)
+	for _, c := range all[1:] {
+		if d.k((soFar+cur.weight+c.weight)/d.count)-kLow <= 1 {
+			cur.mean += (c.mean - cur.mean) * c.weight / (cur.weight + c.weight)
+			cur.weight += c.weight
+			continue
+		}
+		soFar += cur.weight
+		kLow = d.k(soFar / d.count)
+		merged = append(merged, cur)
+		cur = c
+	}
+	d.centroids = append(merged, cur)
+	d.buffer = d.buffer[:0]
+}
+
+// k is the k1 scale function, which keeps centroids small near the tails
+func (d *TDigest) k(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*math.Min(1, q)-1)
+}
+
+// quantile interpolates between centroid centers; the caller holds the lock
+// and has merged the buffer
+func (d *TDigest) quantile(q float64) float64 {
+	switch {
+	case len(d.centroids) == 0:
+		return math.NaN()
+	case q <= 0:
+		return d.min
+	case q >= 1:
+		return d.max
+	case len(d.centroids) == 1:
+		return d.centroids[0].mean
+	}
+
+	target := q * d.count
+	first, last := d.centroids[0], d.centroids[len(d.centroids)-1]
+	if target < first.weight/2 {
+		return d.min + (first.mean-d.min)*target/(first.weight/2)
+	}
+	if target > d.count-last.weight/2 {
+		return last.mean + (d.max-last.mean)*(target-(d.count-last.weight/2))/(last.weight/2)
+	}
+
+	cum := 0.0
+	for i := 0; i < len(d.centroids)-1; i++ {
+		a, b := d.centroids[i], d.centroids[i+1]
+		left := cum + a.weight/2
+		right := cum + a.weight + b.weight/2
+		if target <= right {
+			return a.mean + (b.mean-a.mean)*(target-left)/(right-left)
+		}
+		cum += a.weight
+	}
+	return last.mean
+}
+
+type contextKey struct{}
+
+// NewContext attaches a digest that processors feed line lengths into
+func NewContext(ctx context.Context, d *TDigest) context.Context {
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+// FromContext returns the digest attached to ctx, or nil
+func FromContext(ctx context.Context) *TDigest {
+	d, _ := ctx.Value(contextKey{}).(*TDigest)
+	return d
+}
@@ -0,0 +1,58 @@
+package sketch
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestQuantilesTrackExact(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	d := New(DefaultCompression)
+	values := make([]float64, 100000)
+	for i := range values {
+		// Skewed like real line lengths: mostly short, a long tail
+		values[i] = math.Floor(rng.ExpFloat64() * 60)
+		d.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		exact := values[int(q*float64(len(values)))]
+		got := d.Quantile(q)
+		if math.Abs(got-exact) > 0.02*exact+1 {
+			t.Errorf("q%v = %v, exact %v", q, got, exact)
+		}
+	}
+	if len(d.centroids) > 5*DefaultCompression {
+		t.Errorf("digest kept %d centroids", len(d.centroids))
+	}
+}
+
+func TestConcurrentAddAndNil(t *testing.T) {
+	d := New(0)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				d.Add(float64(i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := d.Summary()
+	if s["count"] != 4000 || s["min"] != 0 || s["max"] != 999 {
+		t.Errorf("summary %v", s)
+	}
+
+	var none *TDigest
+	none.Add(1)
+	if none.Summary() != nil || New(0).Summary() != nil {
+		t.Error("nil and empty digests should have no summary")
+	}
+}
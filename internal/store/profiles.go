@@ -0,0 +1,207 @@
+// Package store keeps server-side state that comparisons can reference by ID.
+package store
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// ErrFull is returned by Put when the store already holds its maximum number of profiles
+var ErrFull = errors.New("store: profile limit reached")
+
+// ErrMismatch is returned by PutIf when the stored profile is not the expected one
+var ErrMismatch = errors.New("store: profile does not match the expected fingerprint")
+
+// Profile is a stored original text
+type Profile struct {
+	ID string
+	// Fingerprint is the hex digest of Original; it changes whenever the original does
+	Fingerprint   string
+	OriginalBytes int
+	Original      string
+	CreatedAt     time.Time
+	LastUsedAt    time.Time
+	// TTL is how long the profile is kept after its last use
+	TTL       time.Duration
+	ExpiresAt time.Time
+}
+
+// Profiles is a concurrency-safe in-memory profile store. A profile expires
+// TTL after it was stored or last used.
+type Profiles struct {
+	mu         sync.Mutex
+	defaultTTL time.Duration
+	max        int
+	hasher     ports.Hasher
+	profiles   map[string]*Profile
+}
+
+// NewProfiles creates a store holding at most max profiles, which expire after
+// defaultTTL unless Put is given another TTL. A nil h uses the default hasher.
+func NewProfiles(defaultTTL time.Duration, max int, h ports.Hasher) *Profiles {
+	if h == nil {
+		h = hasher.Default()
+	}
+	return &Profiles{
+		defaultTTL: defaultTTL,
+		max:        max,
+		hasher:     h,
+		profiles:   make(map[string]*Profile),
+	}
+}
+
+// Max returns the maximum number of profiles the store holds
+func (s *Profiles) Max() int {
+	return s.max
+}
+
+// Put stores original under id with the given TTL (0 uses the default),
+// replacing any previous profile with that ID. It fails with ErrFull when the
+// store is full and id is new.
+func (s *Profiles) Put(id, original string, ttl time.Duration) (Profile, error) {
+	profile, _, err := s.PutIf(id, original, ttl, "")
+	return profile, err
+}
+
+// PutIf is Put with a precondition checked under the store's lock. A
+// non-empty expected fingerprint only lets the write replace a live profile
+// with that fingerprint; "*" lets it replace any live profile. Otherwise it
+// fails with ErrMismatch. It reports whether a live profile was replaced; a
+// replaced profile keeps its CreatedAt.
+func (s *Profiles) PutIf(id, original string, ttl time.Duration, expected string) (Profile, bool, error) {
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+	now := time.Now()
+	profile := &Profile{
+		ID:            id,
+		Fingerprint:   hex.EncodeToString(s.hasher.SumString(original)),
+		OriginalBytes: len(original),
+		Original:      original,
+		CreatedAt:     now,
+		LastUsedAt:    now,
+		TTL:           ttl,
+		ExpiresAt:     now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.profiles[id]
+	replaced := exists && !now.After(current.ExpiresAt)
+	if expected != "" && (!replaced || expected != "*" && expected != current.Fingerprint) {
+		return Profile{}, false, ErrMismatch
+	}
+	if replaced {
+		profile.CreatedAt = current.CreatedAt
+	}
+
+	if !exists && len(s.profiles) >= s.max {
+		// Make room from profiles the janitor has not reached yet
+		for oldID, old := range s.profiles {
+			if now.After(old.ExpiresAt) {
+				delete(s.profiles, oldID)
+			}
+		}
+		if len(s.profiles) >= s.max {
+			return Profile{}, false, ErrFull
+		}
+	}
+	s.profiles[id] = profile
+	return *profile, replaced, nil
+}
+
+// Get returns a profile without extending its lifetime
+func (s *Profiles) Get(id string) (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[id]
+	if !ok || time.Now().After(profile.ExpiresAt) {
+		return Profile{}, false
+	}
+	return *profile, true
+}
+
+// Use returns a profile for a comparison and extends its lifetime by its TTL
+func (s *Profiles) Use(id string) (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[id]
+	now := time.Now()
+	if !ok || now.After(profile.ExpiresAt) {
+		return Profile{}, false
+	}
+	profile.LastUsedAt = now
+	profile.ExpiresAt = now.Add(profile.TTL)
+	return *profile, true
+}
+
+// Delete removes a profile, reporting whether it existed
+func (s *Profiles) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.profiles[id]
+	delete(s.profiles, id)
+	return ok
+}
+
+// List returns the live profiles ordered by ID
+func (s *Profiles) List() []Profile {
+	now := time.Now()
+
+	s.mu.Lock()
+	list := make([]Profile, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		if !now.After(profile.ExpiresAt) {
+			list = append(list, *profile)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Len returns the number of stored profiles, including expired ones not yet purged
+func (s *Profiles) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.profiles)
+}
+
+// PurgeExpired drops profiles whose TTL has passed
+func (s *Profiles) PurgeExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, profile := range s.profiles {
+		if now.After(profile.ExpiresAt) {
+			delete(s.profiles, id)
+		}
+	}
+}
+
+// RunJanitor purges expired profiles every interval until ctx is done
+func (s *Profiles) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.PurgeExpired(now)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProfilesPutReplaceAndLimit(t *testing.T) {
+	s := NewProfiles(time.Minute, 1, nil)
+
+	first, err := s.Put("src", "original text", 0)
+	if err != nil || first.OriginalBytes != len("original text") || first.Fingerprint == "" || first.TTL != time.Minute {
+		t.Fatalf("Put = %+v, %v", first, err)
+	}
+	if _, err := s.Put("other", "text", 0); !errors.Is(err, ErrFull) {
+		t.Errorf("Put beyond the limit = %v, want ErrFull", err)
+	}
+
+	// Replacing an existing ID is allowed at the limit and changes the fingerprint
+	second, err := s.Put("src", "changed text", time.Hour)
+	if err != nil || second.Fingerprint == first.Fingerprint || second.TTL != time.Hour {
+		t.Errorf("replace = %+v, %v; want a new fingerprint and TTL", second, err)
+	}
+	if got, _ := s.Use("src"); got.Original != "changed text" {
+		t.Errorf("Use returned original %q", got.Original)
+	}
+}
+
+func TestProfilesPutIf(t *testing.T) {
+	s := NewProfiles(time.Minute, 10, nil)
+
+	if _, _, err := s.PutIf("src", "original text", 0, "*"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("PutIf * on a missing profile = %v, want ErrMismatch", err)
+	}
+	first, replaced, err := s.PutIf("src", "original text", 0, "")
+	if err != nil || replaced {
+		t.Fatalf("first PutIf = %v, replaced %v", err, replaced)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, _, err := s.PutIf("src", "changed text", 0, "0000"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("PutIf with a stale fingerprint = %v, want ErrMismatch", err)
+	}
+	second, replaced, err := s.PutIf("src", "changed text", 0, first.Fingerprint)
+	if err != nil || !replaced {
+		t.Fatalf("PutIf with the current fingerprint = %v, replaced %v", err, replaced)
+	}
+	if !second.CreatedAt.Equal(first.CreatedAt) || !second.LastUsedAt.After(first.LastUsedAt) {
+		t.Errorf("replacement created %v, used %v; want created %v kept and a later use", second.CreatedAt, second.LastUsedAt, first.CreatedAt)
+	}
+
+	// Only one of several concurrent writes to a new ID creates it
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, replaced, err := s.PutIf("race", "text", 0, ""); err == nil && !replaced {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if created.Load() != 1 {
+		t.Errorf("%d concurrent writes reported creating the profile, want 1", created.Load())
+	}
+}
+
+func TestProfilesExpire(t *testing.T) {
+	s := NewProfiles(time.Minute, 2, nil)
+	if _, err := s.Put("short", "a", time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("long", "b", 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, ok := s.Get("short"); ok {
+		t.Error("expired profile still returned")
+	}
+	if list := s.List(); len(list) != 1 || list[0].ID != "long" {
+		t.Errorf("List = %+v, want only long", list)
+	}
+
+	// An expired profile does not count against the limit
+	if _, err := s.Put("new", "c", 0); err != nil {
+		t.Errorf("Put with an expired profile in the way: %v", err)
+	}
+
+	s.PurgeExpired(time.Now().Add(2 * time.Minute))
+	if s.Len() != 0 {
+		t.Errorf("Len after purge = %d, want 0", s.Len())
+	}
+}
+
+func TestProfilesListAndDelete(t *testing.T) {
+	s := NewProfiles(time.Minute, 10, nil)
+	for _, id := range []string{"b", "a", "c"} {
+		if _, err := s.Put(id, "text "+id, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.Delete("c") || s.Delete("c") {
+		t.Error("Delete should succeed once")
+	}
+
+	list := s.List()
+	if len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Errorf("List = %+v, want a, b", list)
+	}
+}
@@ -2,11 +2,14 @@ package warmup
 
 import (
 	"context"
+	"math/rand/v2"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/baditaflorin/go_length_similarity/internal/parallel"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
 )
 
@@ -22,12 +25,14 @@ type WarmupConfig struct {
 	Duration time.Duration
 	// Whether to perform GC after warmup
 	ForceGC bool
+	// Seed for the sample text generators; the same seed always yields the same texts
+	Seed int64
 }
 
 // DefaultWarmupConfig returns the default warmup configuration
 func DefaultWarmupConfig() WarmupConfig {
 	return WarmupConfig{
-		Concurrency:    runtime.NumCPU(),
+		Concurrency:    parallel.DefaultWorkers(),
 		Iterations:     1000,
 		SampleTextSize: 1000,
 		Duration:       5 * time.Second,
@@ -35,6 +40,35 @@ func DefaultWarmupConfig() WarmupConfig {
 	}
 }
 
+// WarmupReport describes one warmup run
+type WarmupReport struct {
+	// Iterations is the number of iterations completed across all components and routines
+	Iterations int64
+	// Duration is the wall time of the whole run, including the forced GC
+	Duration time.Duration
+	// Components holds one entry per kind of component that was warmed up
+	Components []ComponentReport
+	// AllocsBefore and AllocsAfter are the process's cumulative heap
+	// allocations when warmup started and ended
+	AllocsBefore uint64
+	AllocsAfter  uint64
+	// GCCycles is the number of GC cycles completed during warmup
+	GCCycles uint32
+	// Cancelled is set when the duration limit or the context stopped warmup early
+	Cancelled bool
+}
+
+// ComponentReport describes the warmup of one kind of component
+type ComponentReport struct {
+	// Name is "normalizers", "calculators" or "stream processors"
+	Name string
+	// Count is how many components of this kind were registered
+	Count int
+	// Iterations is the number of iterations completed; each runs every registered component once
+	Iterations int64
+	Duration   time.Duration
+}
+
 // Manager handles system warmup operations
 type Manager struct {
 	logger        ports.Logger
@@ -67,8 +101,10 @@ func (wm *Manager) RegisterNormalizer(norm ports.Normalizer) {
 	wm.normalizers = append(wm.normalizers, norm)
 }
 
-// WarmUp runs the warmup process for all registered components
-func (wm *Manager) WarmUp(ctx context.Context) {
+// WarmUp runs the warmup process for all registered components and reports what it did
+func (wm *Manager) WarmUp(ctx context.Context) WarmupReport {
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
 	startTime := time.Now()
 	wm.logger.Info("Starting system warmup",
 		"components", len(wm.calculators)+len(wm.streamingCalc)+len(wm.normalizers),
@@ -86,14 +122,32 @@ func (wm *Manager) WarmUp(ctx context.Context) {
 		warmupCtx = ctx
 	}
 
+	var report WarmupReport
+	run := func(name string, count int, warm func(context.Context, *atomic.Int64)) {
+		if count == 0 {
+			return
+		}
+		var iterations atomic.Int64
+		start := time.Now()
+		warm(warmupCtx, &iterations)
+		report.Components = append(report.Components, ComponentReport{
+			Name:       name,
+			Count:      count,
+			Iterations: iterations.Load(),
+			Duration:   time.Since(start),
+		})
+		report.Iterations += iterations.Load()
+	}
+
 	// Warm up normalizers
-	wm.warmUpNormalizers(warmupCtx)
+	run("normalizers", len(wm.normalizers), wm.warmUpNormalizers)
 
 	// Warm up calculators
-	wm.warmUpCalculators(warmupCtx)
+	run("calculators", len(wm.calculators), wm.warmUpCalculators)
 
 	// Warm up streaming processors
-	wm.warmUpStreamProcessors(warmupCtx)
+	run("stream processors", len(wm.streamingCalc), wm.warmUpStreamProcessors)
+	report.Cancelled = warmupCtx.Err() != nil
 
 	// Force garbage collection if configured
 	if wm.config.ForceGC {
@@ -101,13 +155,23 @@ func (wm *Manager) WarmUp(ctx context.Context) {
 		runtime.GC()
 	}
 
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	report.Duration = time.Since(startTime)
+	report.AllocsBefore, report.AllocsAfter = before.Mallocs, after.Mallocs
+	report.GCCycles = after.NumGC - before.NumGC
+
 	wm.logger.Info("System warmup completed",
-		"duration", time.Since(startTime),
+		"duration", report.Duration,
+		"iterations", report.Iterations,
+		"gc_cycles", report.GCCycles,
+		"cancelled", report.Cancelled,
 	)
+	return report
 }
 
 // warmUpNormalizers runs warmup for all registered normalizers
-func (wm *Manager) warmUpNormalizers(ctx context.Context) {
+func (wm *Manager) warmUpNormalizers(ctx context.Context, iterations *atomic.Int64) {
 	if len(wm.normalizers) == 0 {
 		return
 	}
@@ -115,7 +179,7 @@ func (wm *Manager) warmUpNormalizers(ctx context.Context) {
 	wm.logger.Debug("Warming up normalizers", "count", len(wm.normalizers))
 
 	// Generate sample text
-	sampleText := generateSampleText(wm.config.SampleTextSize)
+	sampleText := generateSampleText(wm.config.SampleTextSize, wm.config.Seed)
 
 	var wg sync.WaitGroup
 	for i := 0; i < wm.config.Concurrency; i++ {
@@ -136,6 +200,7 @@ func (wm *Manager) warmUpNormalizers(ctx context.Context) {
 				for _, normalizer := range wm.normalizers {
 					_ = normalizer.Normalize(sampleText)
 				}
+				iterations.Add(1)
 			}
 		}(i)
 	}
@@ -144,7 +209,7 @@ func (wm *Manager) warmUpNormalizers(ctx context.Context) {
 }
 
 // warmUpCalculators runs warmup for all registered calculators
-func (wm *Manager) warmUpCalculators(ctx context.Context) {
+func (wm *Manager) warmUpCalculators(ctx context.Context, iterations *atomic.Int64) {
 	if len(wm.calculators) == 0 {
 		return
 	}
@@ -152,9 +217,9 @@ func (wm *Manager) warmUpCalculators(ctx context.Context) {
 	wm.logger.Debug("Warming up calculators", "count", len(wm.calculators))
 
 	// Generate sample texts of different similarity levels
-	original := generateSampleText(wm.config.SampleTextSize)
-	similar := generateSimilarText(original, 0.1)   // 10% difference
-	different := generateSimilarText(original, 0.5) // 50% difference
+	original := generateSampleText(wm.config.SampleTextSize, wm.config.Seed)
+	similar := generateSimilarText(original, 0.1, wm.config.Seed)   // 10% difference
+	different := generateSimilarText(original, 0.5, wm.config.Seed) // 50% difference
 
 	var wg sync.WaitGroup
 	for i := 0; i < wm.config.Concurrency; i++ {
@@ -182,6 +247,7 @@ func (wm *Manager) warmUpCalculators(ctx context.Context) {
 						_ = calculator.Compute(ctx, original, different) // Different
 					}
 				}
+				iterations.Add(1)
 			}
 		}(i)
 	}
@@ -190,7 +256,7 @@ func (wm *Manager) warmUpCalculators(ctx context.Context) {
 }
 
 // warmUpStreamProcessors runs warmup for all registered stream processors
-func (wm *Manager) warmUpStreamProcessors(ctx context.Context) {
+func (wm *Manager) warmUpStreamProcessors(ctx context.Context, iterations *atomic.Int64) {
 	if len(wm.streamingCalc) == 0 {
 		return
 	}
@@ -198,7 +264,7 @@ func (wm *Manager) warmUpStreamProcessors(ctx context.Context) {
 	wm.logger.Debug("Warming up stream processors", "count", len(wm.streamingCalc))
 
 	// Generate sample texts
-	original := generateSampleText(wm.config.SampleTextSize)
+	original := generateSampleText(wm.config.SampleTextSize, wm.config.Seed)
 
 	var wg sync.WaitGroup
 	for i := 0; i < wm.config.Concurrency; i++ {
@@ -224,6 +290,7 @@ func (wm *Manager) warmUpStreamProcessors(ctx context.Context) {
 					mode := ports.StreamingMode(j % 3) // Cycle through modes
 					_, _ = processor.ProcessStream(ctx, originalReader, mode)
 				}
+				iterations.Add(1)
 			}
 		}(i)
 	}
@@ -233,8 +300,13 @@ func (wm *Manager) warmUpStreamProcessors(ctx context.Context) {
 
 // Helper functions for generating test data
 
-// generateSampleText creates sample text of the specified size
-func generateSampleText(size int) string {
+// newRand returns a deterministic random source for the given seed
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), 0x5eed))
+}
+
+// generateSampleText creates sample text of the specified size from the given seed
+func generateSampleText(size int, seed int64) string {
 	// Sample words to use in generating text
 	words := []string{
 		"the", "quick", "brown", "fox", "jumps", "over", "lazy", "dog",
@@ -243,6 +315,7 @@ func generateSampleText(size int) string {
 		"ut", "labore", "et", "dolore", "magna", "aliqua",
 	}
 
+	rng := newRand(seed)
 	var sb strings.Builder
 	wordsNeeded := size / 5 // Assuming average word length of 5
 
@@ -250,8 +323,7 @@ func generateSampleText(size int) string {
 		if i > 0 {
 			sb.WriteString(" ")
 		}
-		wordIndex := i % len(words)
-		sb.WriteString(words[wordIndex])
+		sb.WriteString(words[rng.IntN(len(words))])
 	}
 
 	result := sb.String()
@@ -261,8 +333,9 @@ func generateSampleText(size int) string {
 	return result
 }
 
-// generateSimilarText creates a text similar to the original with the specified difference ratio
-func generateSimilarText(original string, diffRatio float64) string {
+// generateSimilarText creates a text similar to the original with the specified difference ratio.
+// The seed chooses which words are replaced.
+func generateSimilarText(original string, diffRatio float64, seed int64) string {
 	words := strings.Fields(original)
 
 	// Number of words to change
@@ -279,13 +352,14 @@ func generateSimilarText(original string, diffRatio float64) string {
 	copy(newWords, words)
 
 	// Replace random words
-	for i := 0; i < changeCount; i++ {
-		if i >= len(newWords) {
+	rng := newRand(seed)
+	for i, idx := range rng.Perm(len(newWords)) {
+		if i >= changeCount {
 			break
 		}
 
 		// Replace with a word from replacements
-		newWords[i] = replacements[i%len(replacements)]
+		newWords[idx] = replacements[rng.IntN(len(replacements))]
 	}
 
 	return strings.Join(newWords, " ")
@@ -0,0 +1,57 @@
+package warmup
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+)
+
+// upperNormalizer is a cheap stand-in normalizer
+type upperNormalizer struct{}
+
+func (upperNormalizer) Normalize(text string) string { return strings.ToUpper(text) }
+
+func TestGeneratorsAreSeeded(t *testing.T) {
+	a := generateSampleText(500, 42)
+	if a != generateSampleText(500, 42) {
+		t.Fatal("same seed produced different sample texts")
+	}
+	if a == generateSampleText(500, 43) {
+		t.Error("different seeds produced identical sample texts")
+	}
+
+	similar := generateSimilarText(a, 0.3, 7)
+	if similar != generateSimilarText(a, 0.3, 7) {
+		t.Fatal("same seed produced different similar texts")
+	}
+	if similar == a {
+		t.Error("similar text has no replacements")
+	}
+}
+
+func TestWarmUpReport(t *testing.T) {
+	config := WarmupConfig{Concurrency: 3, Iterations: 7, SampleTextSize: 100, Duration: time.Minute, ForceGC: true}
+	m := NewManager(logger.NewNopLogger(), config)
+	m.RegisterNormalizer(upperNormalizer{})
+	m.RegisterNormalizer(upperNormalizer{})
+
+	r := m.WarmUp(context.Background())
+	if r.Iterations != 21 || len(r.Components) != 1 || r.Cancelled {
+		t.Fatalf("report = %+v, want 21 iterations of one component", r)
+	}
+	if c := r.Components[0]; c.Name != "normalizers" || c.Count != 2 || c.Iterations != 21 || c.Duration <= 0 {
+		t.Errorf("component = %+v", c)
+	}
+	if r.GCCycles == 0 || r.AllocsAfter < r.AllocsBefore || r.Duration < r.Components[0].Duration {
+		t.Errorf("report = %+v, want the forced GC counted and consistent totals", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if r := m.WarmUp(ctx); !r.Cancelled || r.Iterations != 0 {
+		t.Errorf("cancelled report = %+v, want no iterations", r)
+	}
+}
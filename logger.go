@@ -3,21 +3,17 @@
 package lengthsimilarity
 
 import (
-	"os"
-
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
 	"github.com/baditaflorin/l"
 )
 
 // createDefaultLogger creates and returns a default logger instance.
 func createDefaultLogger() (l.Logger, error) {
-	return l.NewStandardFactory().CreateLogger(l.Config{
-		Output:      os.Stdout,
-		JsonFormat:  false,
-		AsyncWrite:  true,
-		BufferSize:  1024 * 1024,      // 1MB buffer
-		MaxFileSize: 10 * 1024 * 1024, // 10MB max file size
-		MaxBackups:  5,
-		AddSource:   true,
-		Metrics:     true,
-	})
+	return l.NewStandardFactory().CreateLogger(defaultLoggerConfig())
+}
+
+// defaultLoggerConfig is the core's default logger configuration, so the root
+// package and the calculators log the same way
+func defaultLoggerConfig() l.Config {
+	return logger.DefaultConfig()
 }
@@ -0,0 +1,29 @@
+package lengthsimilarity
+
+import (
+	"os"
+	"testing"
+)
+
+// TestCreateDefaultLoggerConfig locks the configuration the root package
+// always created its logger with, now that it comes from the core adapter
+func TestCreateDefaultLoggerConfig(t *testing.T) {
+	cfg := defaultLoggerConfig()
+	if cfg.Output != os.Stdout || cfg.JsonFormat || !cfg.AsyncWrite || !cfg.AddSource || !cfg.Metrics {
+		t.Errorf("output and format changed: %+v", cfg)
+	}
+	if cfg.BufferSize != 1024*1024 || cfg.MaxFileSize != 10*1024*1024 || cfg.MaxBackups != 5 {
+		t.Errorf("buffer = %d, max file size = %d, max backups = %d; want 1MB, 10MB, 5",
+			cfg.BufferSize, cfg.MaxFileSize, cfg.MaxBackups)
+	}
+}
+
+func TestCreateDefaultLogger(t *testing.T) {
+	lg, err := createDefaultLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lg.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
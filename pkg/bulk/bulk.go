@@ -0,0 +1,148 @@
+// Package bulk is the wire format of the server's bulk ingestion mode: a
+// stream of length-prefixed pairs sent over one connection or stored in a
+// file, without per-request HTTP or JSON overhead.
+//
+// A stream starts with the 8-byte Magic, followed by pairs. Each pair is four
+// fields in order, ID, metric, original and augmented, and each field is its
+// length in bytes as an unsigned varint (encoding/binary's Uvarint) followed by
+// that many bytes. The metric is one of the server's metric names: length,
+// character, streaming or efficient.
+//
+//	w := bulk.NewWriter(conn)
+//	for _, p := range pairs {
+//		if err := w.Write(p); err != nil { ... }
+//	}
+//	err := w.Flush()
+package bulk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Magic opens every bulk stream
+const Magic = "SIMBULK1"
+
+// DefaultMaxFieldSize is the largest field a Reader accepts unless told otherwise
+const DefaultMaxFieldSize = 10 * 1024 * 1024
+
+// ErrBadMagic is returned when a stream does not start with Magic
+var ErrBadMagic = errors.New("bulk: stream does not start with " + Magic)
+
+// Pair is one comparison to run
+type Pair struct {
+	ID        string
+	Metric    string
+	Original  string
+	Augmented string
+}
+
+// Writer encodes pairs onto a stream. It buffers; call Flush when done.
+type Writer struct {
+	w       *bufio.Writer
+	started bool
+	scratch [binary.MaxVarintLen64]byte
+}
+
+// NewWriter creates a writer for w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write encodes one pair, opening the stream with Magic on first use
+func (w *Writer) Write(p Pair) error {
+	if !w.started {
+		if _, err := w.w.WriteString(Magic); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	for _, field := range [...]string{p.ID, p.Metric, p.Original, p.Augmented} {
+		n := binary.PutUvarint(w.scratch[:], uint64(len(field)))
+		if _, err := w.w.Write(w.scratch[:n]); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteString(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes buffered pairs, and Magic when no pair was written, so an
+// empty stream is still well formed
+func (w *Writer) Flush() error {
+	if !w.started {
+		if _, err := w.w.WriteString(Magic); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	return w.w.Flush()
+}
+
+// Reader decodes pairs from a stream
+type Reader struct {
+	r            *bufio.Reader
+	maxFieldSize int
+	started      bool
+}
+
+// NewReader creates a reader for r that rejects fields longer than
+// maxFieldSize bytes (0 uses DefaultMaxFieldSize)
+func NewReader(r io.Reader, maxFieldSize int) *Reader {
+	if maxFieldSize <= 0 {
+		maxFieldSize = DefaultMaxFieldSize
+	}
+	return &Reader{r: bufio.NewReaderSize(r, 64*1024), maxFieldSize: maxFieldSize}
+}
+
+// Read decodes the next pair. It returns io.EOF at the clean end of the
+// stream and io.ErrUnexpectedEOF when the stream ends inside a pair.
+func (r *Reader) Read() (Pair, error) {
+	if !r.started {
+		magic := make([]byte, len(Magic))
+		if _, err := io.ReadFull(r.r, magic); err != nil || string(magic) != Magic {
+			return Pair{}, ErrBadMagic
+		}
+		r.started = true
+	}
+
+	var fields [4]string
+	for i := range fields {
+		field, err := r.readField()
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return Pair{}, err
+		}
+		fields[i] = field
+	}
+	return Pair{ID: fields[0], Metric: fields[1], Original: fields[2], Augmented: fields[3]}, nil
+}
+
+// readField decodes one length-prefixed field
+func (r *Reader) readField() (string, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", err
+		}
+		return "", fmt.Errorf("bulk: bad field length: %w", err)
+	}
+	if n > uint64(r.maxFieldSize) {
+		return "", fmt.Errorf("bulk: field of %d bytes exceeds the %d byte limit", n, r.maxFieldSize)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,74 @@
+package bulk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	pairs := []Pair{
+		{ID: "1", Metric: "length", Original: "one two", Augmented: "one"},
+		{ID: "", Metric: "character", Original: "", Augmented: ""},
+		{ID: "big", Metric: "efficient", Original: strings.Repeat("x", 70000), Augmented: "ü\x00\n"},
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, p := range pairs {
+		if err := w.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(&buf, 0)
+	for i, want := range pairs {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("pair %d: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("pair %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("after the last pair: %v, want io.EOF", err)
+	}
+}
+
+func TestEmptyStream(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != Magic {
+		t.Fatalf("empty stream = %q, want just the magic", buf.String())
+	}
+	if _, err := NewReader(&buf, 0).Read(); err != io.EOF {
+		t.Errorf("Read = %v, want io.EOF", err)
+	}
+}
+
+func TestReaderRejectsMalformedStreams(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(Pair{ID: "1", Metric: "length", Original: "abcdef", Augmented: "abc"})
+	w.Flush()
+	stream := buf.Bytes()
+
+	if _, err := NewReader(strings.NewReader("SIMBULK0"), 0).Read(); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("bad magic: %v", err)
+	}
+	for _, cut := range []int{len(Magic) + 1, len(Magic) + 3, len(stream) - 1} {
+		if _, err := NewReader(bytes.NewReader(stream[:cut]), 0).Read(); err != io.ErrUnexpectedEOF {
+			t.Errorf("cut at %d: %v, want io.ErrUnexpectedEOF", cut, err)
+		}
+	}
+	if _, err := NewReader(bytes.NewReader(stream), 4).Read(); err == nil {
+		t.Error("a field over the limit was accepted")
+	}
+}
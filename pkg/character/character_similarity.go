@@ -2,35 +2,87 @@ package character
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
 
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/clock"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/metrics"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/transform"
 	"github.com/baditaflorin/go_length_similarity/internal/core/character"
+	"github.com/baditaflorin/go_length_similarity/internal/core/degrade"
 	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 	"github.com/baditaflorin/go_length_similarity/internal/warmup"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
 	"github.com/baditaflorin/l"
 )
 
 // CharacterSimilarity provides methods to compute a character-level similarity metric.
 type CharacterSimilarity struct {
 	calculator ports.SimilarityCalculator
+	scorer     ports.CountScorer
+	counter    *character.Calculator
 	logger     ports.Logger
 	normalizer ports.Normalizer
-	warmed     bool
+	// warmed is the report of the first warm-up, nil until one ran
+	warmed *warmup.WarmupReport
+
+	reportResources bool
+	transforms      []ports.Transform
+
+	// Uncertainty reporting; maxDiffRatio sets the size of one length step
+	reportUncertainty bool
+	maxDiffRatio      float64
+
+	// Latency-driven degradation; nil unless WithLatencySLO is set
+	governor *degrade.Governor
+	fast     ports.SimilarityCalculator
+
+	// Measurements; nil unless WithMetrics is set, so comparisons are only
+	// timed when something records them
+	metrics ports.Metrics
+	clock   ports.Clock
+
+	// ownsLogger is set when NewCharacterSimilarity created the logger, so Close closes it
+	ownsLogger bool
+	closeOnce  sync.Once
+	closeErr   error
 }
 
 // CharacterSimilarityOption defines a functional option for configuring CharacterSimilarity.
 type CharacterSimilarityOption func(*characterSimilarityConfig)
 
+// ConfigError reports an invalid option value passed to NewCharacterSimilarity
+type ConfigError = domain.ConfigError
+
 type characterSimilarityConfig struct {
-	Threshold    float64
-	MaxDiffRatio float64
-	Precision    int
-	Logger       ports.Logger
-	Normalizer   ports.Normalizer
-	WarmUp       bool
-	WarmUpConfig warmup.WarmupConfig
+	Threshold         float64
+	MaxDiffRatio      float64
+	Precision         int
+	Logger            ports.Logger
+	Normalizer        ports.Normalizer
+	WarmUp            bool
+	WarmUpConfig      warmup.WarmupConfig
+	WarmUpSeed        *int64
+	MinOriginalLength int
+	OriginalCacheSize int
+	Hasher            ports.Hasher
+	LatencySLO        time.Duration
+	ReportResources   bool
+	ReportUncertainty bool
+	Transforms        []ports.Transform
+	Clock             ports.Clock
+	Metrics           ports.Metrics
+	// IgnoreTrailingNewline drops one line break at the end of each text, after the other transforms
+	IgnoreTrailingNewline bool
 }
 
 // WithThreshold sets a custom threshold for character similarity.
@@ -54,6 +106,120 @@ func WithPrecision(p int) CharacterSimilarityOption {
 	}
 }
 
+// WithMinOriginalLength reports an inconclusive result, neither passed nor
+// failed, when the original has fewer than n normalized characters. Length ratios
+// over a few characters say little about similarity. 0 disables the guard.
+func WithMinOriginalLength(n int) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.MinOriginalLength = n
+	}
+}
+
+// WithOriginalCache caches the normalized character counts of up to size originals,
+// keyed by a hash of the text. Use it when one source is compared against many
+// candidates so only the augmented side is processed on each call.
+func WithOriginalCache(size int) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.OriginalCacheSize = size
+	}
+}
+
+// WithHasher sets the hash function used to key the originals cache.
+func WithHasher(h ports.Hasher) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Hasher = h
+	}
+}
+
+// WithSHA256Hasher keys the originals cache with SHA-256 instead of xxhash,
+// for inputs where collision resistance matters.
+func WithSHA256Hasher() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Hasher = hasher.New(hasher.SHA256Type)
+	}
+}
+
+// WithLatencySLO enables degradation under sustained latency pressure. When
+// the moving average of Compute latencies stays above target, Compute first
+// switches to the fast normalizer and then also fails requests whose deadline
+// is shorter than the current average latency. It steps back once latency
+// recovers. Degraded results carry "degraded" and "degradation" in Details.
+// A target of 0 disables degradation.
+func WithLatencySLO(target time.Duration) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.LatencySLO = target
+	}
+}
+
+// WithResourceReport fills Result.Resources with an estimate of the
+// allocations, the peak buffer size and the number of workers each comparison used.
+func WithResourceReport(enable bool) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.ReportResources = enable
+	}
+}
+
+// WithUncertainty fills Result.Uncertainty with how far the score can move per
+// unit of length difference. Short texts move in large steps, so gate their
+// near-threshold scores on Uncertainty.NearThreshold.
+func WithUncertainty(enable bool) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.ReportUncertainty = enable
+	}
+}
+
+// WithNormalizedLineEndings treats CRLF, CR and LF line endings as equal by
+// converting them all to LF before counting, so cross-platform copies of a
+// text are not reported as different.
+func WithNormalizedLineEndings() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.LineEndings())
+	}
+}
+
+// WithTabExpansion replaces tabs with spaces up to the next multiple of width
+// columns before counting, so tab- and space-indented copies of a text compare
+// equal. A width of 0 removes tabs.
+func WithTabExpansion(width int) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.ExpandTabs(width))
+	}
+}
+
+// WithStrippedIndentation drops leading spaces and tabs from every line before
+// counting, so re-indented code or reflowed text compares on its content.
+func WithStrippedIndentation() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripIndentation())
+	}
+}
+
+// WithJoinedHyphenation joins words split across lines by a trailing hyphen,
+// as OCR and PDF extraction leave them, before counting, so "exam-\nple"
+// counts the same characters as "example" on the other side.
+func WithJoinedHyphenation() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.JoinHyphenation())
+	}
+}
+
+// WithStrippedSubtitles removes SRT and WebVTT cue numbers, timing lines and
+// the WEBVTT header before counting, so transcripts compare on their text.
+func WithStrippedSubtitles() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripSubtitles())
+	}
+}
+
+// WithIgnoredTrailingNewline drops one line break at the very end of each
+// text before counting, so a file saved with a final newline has the same
+// character count as the same file without one. Off by default.
+func WithIgnoredTrailingNewline(ignore bool) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.IgnoreTrailingNewline = ignore
+	}
+}
+
 // WithLogger sets a custom logger for character similarity.
 func WithLogger(l l.Logger) CharacterSimilarityOption {
 	return func(cfg *characterSimilarityConfig) {
@@ -84,6 +250,74 @@ func WithOptimizedNormalizer() CharacterSimilarityOption {
 	}
 }
 
+// WithOCRNormalizer sets the normalizer for scanned documents: it removes soft
+// hyphens and zero-width characters, spells out ligatures and folds common
+// recognition confusions ("rn" and "m", 0 and o, 1 and l) before the default
+// normalization. Combine it with WithJoinedHyphenation for words split
+// across lines.
+func WithOCRNormalizer() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		normFactory := normalizer.NewNormalizerFactory()
+		cfg.Normalizer = normFactory.CreateNormalizer(normalizer.OCRNormalizerType)
+	}
+}
+
+// WithLogNormalizer sets the normalizer for log captures: timestamps, UUIDs,
+// IP addresses, numbers and hex IDs become placeholders (TIMESTAMP, UUID, IP,
+// NUM, HEX) before the default normalization, so two runs of the same program
+// compare on the structure of their output.
+func WithLogNormalizer() CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		normFactory := normalizer.NewNormalizerFactory()
+		cfg.Normalizer = normFactory.CreateNormalizer(normalizer.LogNormalizerType)
+	}
+}
+
+// WithOptions applies settings shared with the other calculators
+func WithOptions(opts ...options.Option) CharacterSimilarityOption {
+	common := options.Apply(opts...)
+	return func(cfg *characterSimilarityConfig) {
+		if common.Threshold != nil {
+			cfg.Threshold = *common.Threshold
+		}
+		if common.MaxDiffRatio != nil {
+			cfg.MaxDiffRatio = *common.MaxDiffRatio
+		}
+		if common.Logger != nil {
+			cfg.Logger = logger.FromExisting(common.Logger)
+		}
+		if common.Normalizer != nil {
+			cfg.Normalizer = common.Normalizer
+		}
+		if common.WarmUp != nil {
+			cfg.WarmUp = *common.WarmUp
+		}
+		if common.Clock != nil {
+			cfg.Clock = common.Clock
+		}
+		if common.Metrics != nil {
+			cfg.Metrics = common.Metrics
+		}
+	}
+}
+
+// WithClock sets the clock that times comparisons for WithLatencySLO and
+// WithMetrics, so tests can drive latency tracking deterministically; nil
+// uses the wall clock
+func WithClock(c ports.Clock) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Clock = c
+	}
+}
+
+// WithMetrics records every comparison, by outcome and latency, and the
+// warm-up duration to m, which may be shared with other calculators
+func WithMetrics(m ports.Metrics) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.Metrics = m
+	}
+}
+
 // WithWarmUp enables system warm-up on initialization.
 func WithWarmUp(enable bool) CharacterSimilarityOption {
 	return func(cfg *characterSimilarityConfig) {
@@ -91,6 +325,15 @@ func WithWarmUp(enable bool) CharacterSimilarityOption {
 	}
 }
 
+// WithWarmupSeed sets the seed for the warm-up sample texts so performance
+// characterization runs are reproducible. It applies to any warm-up config,
+// whether set before or after this option.
+func WithWarmupSeed(seed int64) CharacterSimilarityOption {
+	return func(cfg *characterSimilarityConfig) {
+		cfg.WarmUpSeed = &seed
+	}
+}
+
 // WithWarmUpConfig sets a custom warm-up configuration.
 func WithWarmUpConfig(config warmup.WarmupConfig) CharacterSimilarityOption {
 	return func(cfg *characterSimilarityConfig) {
@@ -117,8 +360,31 @@ func NewCharacterSimilarity(opts ...CharacterSimilarityOption) (*CharacterSimila
 		opt(config)
 	}
 
+	// Validate before allocating any resources
+	coreConfig := character.SimilarityConfig{
+		Threshold:         config.Threshold,
+		MaxDiffRatio:      config.MaxDiffRatio,
+		Precision:         config.Precision,
+		MinOriginalLength: config.MinOriginalLength,
+		OriginalCacheSize: config.OriginalCacheSize,
+		Hasher:            config.Hasher,
+	}
+	if err := coreConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if config.LatencySLO < 0 {
+		return nil, domain.NewConfigError("latencySLO", config.LatencySLO, "must not be negative")
+	}
+	if err := transform.Validate(config.Transforms); err != nil {
+		return nil, err
+	}
+	if config.IgnoreTrailingNewline {
+		config.Transforms = append(config.Transforms[:len(config.Transforms):len(config.Transforms)], transform.TrimTrailingNewline())
+	}
+
 	// Set up logger if not provided
-	if config.Logger == nil {
+	ownsLogger := config.Logger == nil
+	if ownsLogger {
 		var err error
 		config.Logger, err = logger.NewStdLogger()
 		if err != nil {
@@ -131,48 +397,224 @@ func NewCharacterSimilarity(opts ...CharacterSimilarityOption) (*CharacterSimila
 		config.Normalizer = normalizer.NewDefaultNormalizer()
 	}
 
-	// Create core calculator
-	coreConfig := character.SimilarityConfig{
-		Threshold:    config.Threshold,
-		MaxDiffRatio: config.MaxDiffRatio,
-		Precision:    config.Precision,
-	}
-	calculator, err := character.NewCalculator(coreConfig, config.Logger, config.Normalizer)
+	// Create core calculator; transforms run before the normalizer
+	calculator, err := character.NewCalculator(coreConfig, config.Logger, transform.Normalizer(config.Normalizer, config.Transforms))
 	if err != nil {
 		return nil, err
 	}
 
 	cs := &CharacterSimilarity{
 		calculator: calculator,
+		scorer:     calculator,
+		counter:    calculator,
 		logger:     config.Logger,
 		normalizer: config.Normalizer,
-		warmed:     false,
+		ownsLogger: ownsLogger,
+
+		reportResources: config.ReportResources,
+		transforms:      config.Transforms,
+
+		reportUncertainty: config.ReportUncertainty,
+		maxDiffRatio:      config.MaxDiffRatio,
+
+		metrics: config.Metrics,
+		clock:   clock.OrSystem(config.Clock),
+	}
+
+	if config.LatencySLO > 0 {
+		fast, err := character.NewCalculator(coreConfig, config.Logger, transform.Normalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.FastNormalizerType), config.Transforms))
+		if err != nil {
+			return nil, err
+		}
+		cs.governor = degrade.NewGovernor(config.LatencySLO, clock.OrSystem(config.Clock))
+		cs.fast = fast
 	}
 
 	// Perform warm-up if configured
 	if config.WarmUp {
+		if config.WarmUpSeed != nil {
+			config.WarmUpConfig.Seed = *config.WarmUpSeed
+		}
 		cs.WarmUp(context.Background(), config.WarmUpConfig)
 	}
 
 	return cs, nil
 }
 
+// Close releases what the calculator holds: its originals cache, and its
+// logger, which is closed if NewCharacterSimilarity created it or flushed if it came from
+// WithLogger. Close is safe to call more than once; do not use the calculator
+// afterwards.
+func (cs *CharacterSimilarity) Close() error {
+	cs.closeOnce.Do(func() {
+		cs.counter.Release()
+		if fast, ok := cs.fast.(interface{ Release() }); ok {
+			fast.Release()
+		}
+		cs.closeErr = logger.Release(cs.logger, cs.ownsLogger)
+	})
+	return cs.closeErr
+}
+
 // Compute calculates the character-level similarity between two texts.
 func (cs *CharacterSimilarity) Compute(ctx context.Context, original, augmented string) domain.Result {
-	return cs.calculator.Compute(ctx, original, augmented)
+	start := cs.now()
+	if !cs.reportResources {
+		return cs.finish(cs.compute(ctx, original, augmented), domain.ModeText, start)
+	}
+
+	pr := probe.Start()
+	result := cs.compute(probe.NewContext(ctx, pr), original, augmented)
+	result.Resources = pr.Finish()
+	return cs.finish(result, domain.ModeText, start)
+}
+
+// compute runs Compute without resource reporting
+func (cs *CharacterSimilarity) compute(ctx context.Context, original, augmented string) domain.Result {
+	if cs.governor == nil {
+		return cs.calculator.Compute(ctx, original, augmented)
+	}
+
+	level := cs.governor.Level()
+	if cs.governor.ShouldExit(ctx, level) {
+		return domain.Result{
+			Name:    "character_similarity",
+			Score:   0,
+			Passed:  false,
+			Details: degrade.Annotate(map[string]interface{}{"error": "deadline shorter than expected latency"}, level),
+		}
+	}
+
+	calculator := cs.calculator
+	if level > degrade.None {
+		calculator = cs.fast
+	}
+
+	start := cs.governor.Now()
+	result := calculator.Compute(ctx, original, augmented)
+	cs.governor.Observe(cs.governor.Now().Sub(start))
+
+	result.Details = degrade.Annotate(result.Details, level)
+	return result
+}
+
+// ComputeFromReaders streams both readers through the configured normalizer
+// and scores the resulting counts exactly like Compute, without holding either
+// text in memory.
+func (cs *CharacterSimilarity) ComputeFromReaders(ctx context.Context, original, augmented io.Reader) domain.Result {
+	start := cs.now()
+	if !cs.reportResources {
+		return cs.finish(cs.computeFromReaders(ctx, original, augmented), domain.ModeStream, start)
+	}
+
+	pr := probe.Start()
+	result := cs.computeFromReaders(probe.NewContext(ctx, pr), original, augmented)
+	result.Resources = pr.Finish()
+	return cs.finish(result, domain.ModeStream, start)
+}
+
+// ComputeFromFS opens the files at original and augmented in fsys, such as an
+// embed.FS or a zip archive, and compares them like ComputeFromReaders.
+// The error reports a file that could not be opened.
+func (cs *CharacterSimilarity) ComputeFromFS(ctx context.Context, fsys fs.FS, original, augmented string) (domain.Result, error) {
+	orig, aug, err := stream.OpenPair(fsys, original, augmented)
+	if err != nil {
+		return domain.Result{}, err
+	}
+	defer orig.Close()
+	defer aug.Close()
+
+	return cs.ComputeFromReaders(ctx, orig, aug), nil
+}
+
+// computeFromReaders runs ComputeFromReaders without resource reporting
+func (cs *CharacterSimilarity) computeFromReaders(ctx context.Context, original, augmented io.Reader) domain.Result {
+	origCounts, err := stream.CountNormalized(ctx, transform.Readers(original, cs.transforms), cs.normalizer, 0)
+	if err != nil {
+		return cs.readErrorResult("original", err)
+	}
+
+	augCounts, err := stream.CountNormalized(ctx, transform.Readers(augmented, cs.transforms), cs.normalizer, 0)
+	if err != nil {
+		return cs.readErrorResult("augmented", err)
+	}
+
+	return cs.scorer.ComputeCounts(origCounts.Runes, augCounts.Runes)
+}
+
+// now reads the clock to time a comparison for the metrics, if there are any
+func (cs *CharacterSimilarity) now() time.Time {
+	if cs.metrics == nil {
+		return time.Time{}
+	}
+	return cs.clock.Now()
+}
+
+// finish stamps the engine and mode on result, fills its uncertainty and
+// records it to the metrics
+func (cs *CharacterSimilarity) finish(result domain.Result, mode string, start time.Time) domain.Result {
+	result.Engine = domain.EngineCharacter
+	result.Mode = mode
+	if cs.metrics != nil {
+		outcome := metrics.Outcome(result.Passed, result.Inconclusive, result.Details["error"] != nil)
+		metrics.RecordComparison(cs.metrics, result.Engine, mode, outcome, cs.clock.Now().Sub(start))
+	}
+	return cs.withUncertainty(result)
+}
+
+// withUncertainty fills result.Uncertainty when uncertainty reporting is
+// enabled and the result carries a score
+func (cs *CharacterSimilarity) withUncertainty(result domain.Result) domain.Result {
+	if cs.reportUncertainty && result.OriginalLength > 0 && !result.Inconclusive && result.Details["error"] == nil {
+		result.Uncertainty = domain.NewUncertainty(result.Score, result.Threshold, scoring.Margin(result.OriginalLength, cs.maxDiffRatio))
+	}
+	return result
+}
+
+// readErrorResult reports a failure to read one of the input streams
+func (cs *CharacterSimilarity) readErrorResult(which string, err error) domain.Result {
+	cs.logger.Error("Error reading "+which+" stream", "error", err)
+	if domain.IsTruncated(err) {
+		return domain.Result{
+			Name:           "character_similarity",
+			Score:          0,
+			Passed:         false,
+			Details:        map[string]interface{}{"error": which + " stream was truncated: " + err.Error(), "truncated_input": which},
+			TruncatedInput: true,
+		}
+	}
+	return domain.Result{
+		Name:    "character_similarity",
+		Score:   0,
+		Passed:  false,
+		Details: map[string]interface{}{"error": "error reading " + which + " stream: " + err.Error()},
+	}
 }
 
-// WarmUp performs system warm-up to optimize performance.
-func (cs *CharacterSimilarity) WarmUp(ctx context.Context, config warmup.WarmupConfig) {
-	if cs.warmed {
+// WarmUp performs system warm-up to optimize performance and reports what it
+// did. Only the first call warms up; later calls return its report.
+func (cs *CharacterSimilarity) WarmUp(ctx context.Context, config warmup.WarmupConfig) warmup.WarmupReport {
+	if cs.warmed != nil {
 		cs.logger.Debug("System already warmed up, skipping")
-		return
+		return *cs.warmed
 	}
 
 	warmupMgr := warmup.NewManager(cs.logger, config)
 	warmupMgr.RegisterCalculator(cs.calculator)
 	warmupMgr.RegisterNormalizer(cs.normalizer)
 
-	warmupMgr.WarmUp(ctx)
-	cs.warmed = true
+	report := warmupMgr.WarmUp(ctx)
+	if cs.metrics != nil {
+		cs.metrics.Gauge(metrics.WarmupSeconds, report.Duration.Seconds(), "engine", domain.EngineCharacter)
+	}
+	cs.warmed = &report
+	return report
+}
+
+// WarmupReport returns the report of the warm-up run, if there was one
+func (cs *CharacterSimilarity) WarmupReport() (warmup.WarmupReport, bool) {
+	if cs.warmed == nil {
+		return warmup.WarmupReport{}, false
+	}
+	return *cs.warmed, true
 }
@@ -0,0 +1,75 @@
+package character
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func TestNormalizedLineEndings(t *testing.T) {
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	cs, err := NewCharacterSimilarity(WithLogger(logger), WithNormalizedLineEndings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unix := strings.Repeat("first line\nsecond line\n", 50)
+	windows := strings.ReplaceAll(unix, "\n", "\r\n")
+	mac := strings.ReplaceAll(unix, "\n", "\r")
+
+	ctx := context.Background()
+	for name, other := range map[string]string{"crlf": windows, "cr": mac} {
+		result := cs.Compute(ctx, unix, other)
+		if result.OriginalLength != result.AugmentedLength || result.Score != 1 {
+			t.Errorf("%s: got %d/%d score %v, want equal lengths and score 1",
+				name, result.OriginalLength, result.AugmentedLength, result.Score)
+		}
+
+		fromReaders := cs.ComputeFromReaders(ctx, strings.NewReader(unix), strings.NewReader(other))
+		if fromReaders.OriginalLength != result.OriginalLength || fromReaders.AugmentedLength != result.AugmentedLength {
+			t.Errorf("%s: readers got %d/%d, strings got %d/%d", name,
+				fromReaders.OriginalLength, fromReaders.AugmentedLength, result.OriginalLength, result.AugmentedLength)
+		}
+	}
+}
+
+func TestIgnoredTrailingNewline(t *testing.T) {
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	ctx := context.Background()
+	without := "first line\nsecond line"
+	with := without + "\r\n"
+
+	plain, err := NewCharacterSimilarity(WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := plain.Compute(ctx, without, with); result.OriginalLength == result.AugmentedLength {
+		t.Fatalf("expected the final newline to count by default, got %d/%d", result.OriginalLength, result.AugmentedLength)
+	}
+
+	cs, err := NewCharacterSimilarity(WithLogger(logger), WithNormalizedLineEndings(), WithIgnoredTrailingNewline(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := cs.Compute(ctx, without, with)
+	if result.OriginalLength != result.AugmentedLength || result.Score != 1 {
+		t.Errorf("got %d/%d score %v, want equal lengths", result.OriginalLength, result.AugmentedLength, result.Score)
+	}
+	fromReaders := cs.ComputeFromReaders(ctx, strings.NewReader(without), strings.NewReader(with))
+	if fromReaders.AugmentedLength != result.AugmentedLength {
+		t.Errorf("readers counted %d, strings %d", fromReaders.AugmentedLength, result.AugmentedLength)
+	}
+}
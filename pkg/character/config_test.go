@@ -0,0 +1,44 @@
+package character
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func TestNewFromConfigJSON(t *testing.T) {
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"threshold": 0.95, "normalizer": "optimized", "strip_indentation": true}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := NewFromConfig(cfg, WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := cs.Compute(context.Background(), "  indented\n  text", "indented\ntext")
+	if result.Threshold != 0.95 || result.Score != 1 {
+		t.Errorf("threshold %v, score %v; want 0.95 and 1 with indentation stripped", result.Threshold, result.Score)
+	}
+}
+
+func TestNegativePrecisionIsAccepted(t *testing.T) {
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Construction-time validation covers the threshold and ratio only; the
+	// character calculator has always accepted any precision
+	if _, err := NewCharacterSimilarity(WithLogger(logger), WithPrecision(-1)); err != nil {
+		t.Errorf("WithPrecision(-1) = %v, want it accepted", err)
+	}
+}
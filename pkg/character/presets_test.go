@@ -0,0 +1,41 @@
+package character
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func TestPresetsResolveToDocumentedSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		preset                  CharacterSimilarityOption
+		threshold, maxDiffRatio float64
+	}{
+		{"Strict", Strict(), 0.9, 0.1},
+		{"Lenient", Lenient(), 0.5, 0.5},
+	} {
+		var cfg characterSimilarityConfig
+		tc.preset(&cfg)
+		if cfg.Threshold != tc.threshold || cfg.MaxDiffRatio != tc.maxDiffRatio || cfg.Normalizer == nil {
+			t.Errorf("%s: threshold %v, ratio %v, normalizer %v; want %v, %v and a normalizer",
+				tc.name, cfg.Threshold, cfg.MaxDiffRatio, cfg.Normalizer, tc.threshold, tc.maxDiffRatio)
+		}
+	}
+}
+
+func TestPresetIsOverriddenByLaterOptions(t *testing.T) {
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := NewCharacterSimilarity(WithLogger(logger), Lenient(), WithThreshold(0.8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := cs.Compute(context.Background(), "same text", "same text"); result.Threshold != 0.8 {
+		t.Errorf("threshold %v, want the later option's 0.8", result.Threshold)
+	}
+}
@@ -0,0 +1,44 @@
+package character
+
+import (
+	"context"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// Session compares pairs on a single goroutine, reusing buffers it owns
+// across calls instead of taking them from shared pools. Use one per worker in
+// tight loops over many short pairs. A Session is not safe for concurrent use.
+type Session struct {
+	cs        *CharacterSimilarity
+	original  []byte
+	augmented []byte
+}
+
+// NewSession returns a Session that scores with cs's configuration
+func (cs *CharacterSimilarity) NewSession() *Session {
+	return &Session{cs: cs}
+}
+
+// Compute scores original against augmented with the same result as
+// CharacterSimilarity.Compute. It bypasses the originals cache, latency
+// degradation, resource reporting and debug logging.
+func (s *Session) Compute(ctx context.Context, original, augmented string) domain.Result {
+	if ctx.Err() != nil {
+		return domain.Result{
+			Name:    "character_similarity",
+			Engine:  domain.EngineCharacter,
+			Mode:    domain.ModeText,
+			Score:   0,
+			Passed:  false,
+			Details: map[string]interface{}{"error": "computation cancelled"},
+		}
+	}
+
+	start := s.cs.now()
+	var origLen, augLen int
+	origLen, s.original = s.cs.counter.CountRunes(original, s.original)
+	augLen, s.augmented = s.cs.counter.CountRunes(augmented, s.augmented)
+
+	return s.cs.finish(s.cs.scorer.ComputeCounts(origLen, augLen), domain.ModeText, start)
+}
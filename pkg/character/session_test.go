@@ -0,0 +1,35 @@
+package character
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func TestSessionMatchesCompute(t *testing.T) {
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	cs, err := NewCharacterSimilarity(WithLogger(logger), WithOptimizedNormalizer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := cs.NewSession()
+
+	ctx := context.Background()
+	for _, p := range [][2]string{
+		{"Hello,   World", "hello world"},
+		{"naïve café", "NAÏVE CAFÉ!!"},
+		{"short", "a considerably longer text"},
+	} {
+		want := cs.Compute(ctx, p[0], p[1])
+		if diff := session.Compute(ctx, p[0], p[1]).Diff(want, 0); diff != nil {
+			t.Errorf("%q: session differs from Compute: %v", p, diff)
+		}
+	}
+}
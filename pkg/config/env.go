@@ -0,0 +1,236 @@
+// Package config loads calculator and server settings from environment
+// variables, for deployments that configure processes without files.
+//
+// Calculator settings are named PREFIX_SECTION_FIELD, where SECTION is WORD,
+// CHARACTER or STREAMING and FIELD is the upper-cased JSON name of the field
+// in word.Config, character.Config or streaming.Config:
+//
+//	SIMILARITY_WORD_THRESHOLD=0.8
+//	SIMILARITY_WORD_NORMALIZER=fast
+//	SIMILARITY_CHARACTER_MAX_DIFF_RATIO=0.5
+//	SIMILARITY_STREAMING_MODE=line
+//	SIMILARITY_STREAMING_LATENCY_SLO=250ms
+//
+// Names lists every variable FromEnv reads.
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+)
+
+// ConfigError reports an environment variable with an invalid value; Field
+// is the variable's name
+type ConfigError = domain.ConfigError
+
+// Calculators holds the settings of each calculator
+type Calculators struct {
+	Word      word.Config
+	Character character.Config
+	Streaming streaming.Config
+}
+
+// sections maps each variable section to its field of Calculators
+var sections = []struct {
+	name  string
+	field string
+}{
+	{"WORD", "Word"},
+	{"CHARACTER", "Character"},
+	{"STREAMING", "Streaming"},
+}
+
+// FromEnv reads calculator settings from the environment. Unset variables
+// keep the calculators' defaults. Malformed values and settings the
+// calculators would reject, such as an unknown normalizer or a threshold
+// outside [0, 1], are reported as a *ConfigError naming the variable.
+func FromEnv(prefix string) (Calculators, error) {
+	var c Calculators
+	v := reflect.ValueOf(&c).Elem()
+	for _, section := range sections {
+		if err := decodeEnv(v.FieldByName(section.field), envName(prefix, section.name)); err != nil {
+			return Calculators{}, err
+		}
+	}
+	if err := c.validate(prefix); err != nil {
+		return Calculators{}, err
+	}
+	return c, nil
+}
+
+// Names returns the variables FromEnv reads for prefix, in section order
+func Names(prefix string) []string {
+	var names []string
+	t := reflect.TypeOf(Calculators{})
+	for _, section := range sections {
+		field, _ := t.FieldByName(section.field)
+		for _, f := range reflect.VisibleFields(field.Type) {
+			if tag := jsonName(f); tag != "" {
+				names = append(names, envName(prefix, section.name, tag))
+			}
+		}
+	}
+	return names
+}
+
+// validate checks the values the calculators would reject at construction
+func (c Calculators) validate(prefix string) error {
+	checks := []struct {
+		section      string
+		threshold    float64
+		maxDiffRatio float64
+		options      func() error
+	}{
+		{"WORD", c.Word.Threshold, c.Word.MaxDiffRatio, func() error { _, err := c.Word.Options(); return err }},
+		{"CHARACTER", c.Character.Threshold, c.Character.MaxDiffRatio, func() error { _, err := c.Character.Options(); return err }},
+		{"STREAMING", c.Streaming.Threshold, c.Streaming.MaxDiffRatio, func() error { _, err := c.Streaming.Options(); return err }},
+	}
+	for _, check := range checks {
+		// The configs read 0 as unset and would silently keep the default, so
+		// a variable set to 0 is rejected rather than ignored
+		name := envName(prefix, check.section, "threshold")
+		if _, set := os.LookupEnv(name); set && check.threshold == 0 {
+			return domain.NewConfigError(name, check.threshold, "must be greater than 0; unset it to keep the default")
+		}
+		if err := domain.ValidateThreshold(check.threshold); err != nil {
+			return rename(err, name)
+		}
+		name = envName(prefix, check.section, "max_diff_ratio")
+		if _, set := os.LookupEnv(name); set || check.maxDiffRatio != 0 {
+			if err := domain.ValidateMaxDiffRatio(check.maxDiffRatio); err != nil {
+				return rename(err, name)
+			}
+		}
+		if err := check.options(); err != nil {
+			if configErr, ok := err.(*ConfigError); ok {
+				return rename(err, envName(prefix, check.section, configErr.Field))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// rename points a configuration error at the variable that caused it
+func rename(err error, name string) error {
+	if configErr, ok := err.(*ConfigError); ok {
+		return domain.NewConfigError(name, configErr.Value, configErr.Reason)
+	}
+	return err
+}
+
+// SetFlagsFromEnv gives each flag in fs that was not set on the command line
+// the value of PREFIX_FLAG_NAME, if that variable is set: -max-body-size
+// reads SIMILARITY_MAX_BODY_SIZE for the prefix SIMILARITY. Call it after
+// fs.Parse, so command-line flags still take precedence.
+func SetFlagsFromEnv(fs *flag.FlagSet, prefix string) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		name := envName(prefix, strings.ReplaceAll(f.Name, "-", "_"))
+		if raw, ok := os.LookupEnv(name); ok {
+			if setErr := fs.Set(f.Name, raw); setErr != nil {
+				err = domain.NewConfigError(name, raw, "is invalid: "+setErr.Error())
+			}
+		}
+	})
+	return err
+}
+
+// decodeEnv sets each tagged field of the struct v from the variable named
+// prefix_FIELD
+func decodeEnv(v reflect.Value, prefix string) error {
+	for _, f := range reflect.VisibleFields(v.Type()) {
+		tag := jsonName(f)
+		if tag == "" {
+			continue
+		}
+		name := envName(prefix, tag)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(v.FieldByIndex(f.Index), raw); err != nil {
+			return domain.NewConfigError(name, raw, err.Error())
+		}
+	}
+	return nil
+}
+
+// setValue parses raw into v, which has one of the kinds the Config structs use
+func setValue(v reflect.Value, raw string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(raw)); err != nil {
+			if configErr, ok := err.(*ConfigError); ok {
+				return errors.New(configErr.Reason)
+			}
+			return fmt.Errorf("is invalid: %v", err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(x)
+	default:
+		return fmt.Errorf("has unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// jsonName returns the JSON name of a struct field, or "" if it has none
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// envName joins prefix and parts into an upper-case variable name
+func envName(prefix string, parts ...string) string {
+	if prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+	return strings.ToUpper(strings.Join(parts, "_"))
+}
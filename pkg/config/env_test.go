@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("SIM_WORD_THRESHOLD", "0.8")
+	t.Setenv("SIM_WORD_NORMALIZER", "fast")
+	t.Setenv("SIM_WORD_LATENCY_SLO", "250ms")
+	t.Setenv("SIM_WORD_WARM_UP", "true")
+	t.Setenv("SIM_WORD_WARM_UP_SEED", "42")
+	t.Setenv("SIM_CHARACTER_PRECISION", "3")
+	t.Setenv("SIM_STREAMING_MODE", "line")
+	t.Setenv("SIM_STREAMING_TAB_WIDTH", "4")
+	t.Setenv("SIM_STREAMING_MAX_BYTES", "1048576")
+
+	cfg, err := FromEnv("SIM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Word.Threshold != 0.8 || cfg.Word.Normalizer != "fast" || cfg.Word.LatencySLO != options.Duration(250*time.Millisecond) {
+		t.Errorf("word config = %+v", cfg.Word)
+	}
+	if !cfg.Word.WarmUp || cfg.Word.WarmUpSeed == nil || *cfg.Word.WarmUpSeed != 42 {
+		t.Errorf("warm up = %v, seed %v; want true, 42", cfg.Word.WarmUp, cfg.Word.WarmUpSeed)
+	}
+	if cfg.Character.Precision != 3 {
+		t.Errorf("precision = %d, want 3", cfg.Character.Precision)
+	}
+	if cfg.Streaming.Mode != "line" || cfg.Streaming.TabWidth == nil || *cfg.Streaming.TabWidth != 4 || cfg.Streaming.MaxBytes != 1<<20 {
+		t.Errorf("streaming config = %+v", cfg.Streaming)
+	}
+}
+
+func TestFromEnvUnsetKeepsDefaults(t *testing.T) {
+	cfg, err := FromEnv("SIM_UNSET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != (Calculators{}) {
+		t.Errorf("FromEnv with nothing set = %+v, want zero configs", cfg)
+	}
+}
+
+func TestFromEnvErrorsNameTheVariable(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{"SIM_WORD_THRESHOLD", "high"},
+		{"SIM_WORD_THRESHOLD", "1.5"},
+		{"SIM_CHARACTER_MAX_DIFF_RATIO", "-1"},
+		{"SIM_WORD_MAX_DIFF_RATIO", "0"},
+		{"SIM_CHARACTER_THRESHOLD", "0"},
+		{"SIM_STREAMING_THRESHOLD", "0.0"},
+		{"SIM_STREAMING_MAX_DIFF_RATIO", "0.0"},
+		{"SIM_WORD_NORMALIZER", "fancy"},
+		{"SIM_STREAMING_MODE", "paragraph"},
+		{"SIM_STREAMING_PARALLEL", "sometimes"},
+		{"SIM_CHARACTER_LATENCY_SLO", "soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			_, err := FromEnv("SIM")
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("err = %v, want a ConfigError", err)
+			}
+			if configErr.Field != tt.name {
+				t.Errorf("error names %q, want %q", configErr.Field, tt.name)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names("SIM")
+	for _, want := range []string{"SIM_WORD_THRESHOLD", "SIM_CHARACTER_PRECISION", "SIM_STREAMING_BATCH_SIZE"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("Names is missing %s", want)
+		}
+	}
+	if got := Names("")[0]; got != "WORD_THRESHOLD" {
+		t.Errorf("Names without a prefix starts with %q", got)
+	}
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	size := fs.Int("max-body-size", 10, "")
+	port := fs.Int("port", 8080, "")
+	t.Setenv("SIM_MAX_BODY_SIZE", "20")
+	t.Setenv("SIM_PORT", "9000")
+	if err := fs.Parse([]string{"-port", "7000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetFlagsFromEnv(fs, "SIM"); err != nil {
+		t.Fatal(err)
+	}
+	if *size != 20 {
+		t.Errorf("max-body-size = %d, want 20 from the environment", *size)
+	}
+	if *port != 7000 {
+		t.Errorf("port = %d, want the command-line 7000", *port)
+	}
+
+	// Set marks the flag as given, so a fresh set sees the variable again
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("max-body-size", 10, "")
+	t.Setenv("SIM_MAX_BODY_SIZE", "big")
+	var configErr *ConfigError
+	if err := SetFlagsFromEnv(fs, "SIM"); !errors.As(err, &configErr) || configErr.Field != "SIM_MAX_BODY_SIZE" {
+		t.Errorf("invalid value: err = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package content stores input texts once, addressed by the SHA-256 of their
+// bytes, so results, profiles and reports can reference large texts by key
+// instead of carrying them:
+//
+//	store, _ := content.NewFileStore("/var/lib/similarity/content")
+//	key, _ := content.PutString(ctx, store, original)
+//	rec := sink.FromResult("pair-42", result)
+//	rec.OriginalRef = key
+//
+// Storing the same text twice returns the same key and keeps one copy.
+// FileStore keeps texts in a local directory and S3Store in an S3 bucket or
+// any S3-compatible object store.
+package content
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when no content is stored under a key
+var ErrNotFound = errors.New("content: not found")
+
+// Key is the lowercase hex SHA-256 of a text's bytes
+type Key string
+
+// KeyOf returns the key data is stored under
+func KeyOf(data []byte) Key {
+	sum := sha256.Sum256(data)
+	return Key(hex.EncodeToString(sum[:]))
+}
+
+// ParseKey checks that s is a well-formed key
+func ParseKey(s string) (Key, error) {
+	if len(s) != sha256.Size*2 || strings.ToLower(s) != s {
+		return "", fmt.Errorf("content: %q is not a lowercase hex SHA-256", s)
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return "", fmt.Errorf("content: %q is not a lowercase hex SHA-256", s)
+	}
+	return Key(s), nil
+}
+
+// ContentStore keeps texts addressed by their key. Implementations are safe
+// for concurrent use.
+type ContentStore interface {
+	// Put stores everything read from r and returns its key. Content that is
+	// already stored is not written again.
+	Put(ctx context.Context, r io.Reader) (Key, error)
+	// Open returns the content stored under key, or ErrNotFound
+	Open(ctx context.Context, key Key) (io.ReadCloser, error)
+	// Has reports whether content is stored under key
+	Has(ctx context.Context, key Key) (bool, error)
+}
+
+// PutString stores an in-memory text
+func PutString(ctx context.Context, s ContentStore, text string) (Key, error) {
+	return s.Put(ctx, strings.NewReader(text))
+}
+
+// Get returns the content stored under key, checking that it still hashes to key
+func Get(ctx context.Context, s ContentStore, key Key) ([]byte, error) {
+	rc, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, rc); err != nil {
+		return nil, err
+	}
+	if got := KeyOf(buf.Bytes()); got != key {
+		return nil, fmt.Errorf("content: %s is corrupt: its bytes hash to %s", key, got)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package content
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// FileStore keeps content in a directory, one file per key under a
+// subdirectory named by the key's first two characters
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, domain.NewConfigError("dir", dir, "must not be empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns where key is stored
+func (s *FileStore) path(key Key) string {
+	return filepath.Join(s.dir, string(key[:2]), string(key[2:]))
+}
+
+// Put implements ContentStore. The content is written to a temporary file
+// while it is hashed, then renamed into place, so readers never see a
+// partial file.
+func (s *FileStore) Put(ctx context.Context, r io.Reader) (Key, error) {
+	tmp, err := os.CreateTemp(s.dir, ".put-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	digest := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, digest), contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	key := Key(hex.EncodeToString(digest.Sum(nil)))
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return key, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Open implements ContentStore
+func (s *FileStore) Open(ctx context.Context, key Key) (io.ReadCloser, error) {
+	if _, err := ParseKey(string(key)); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Has implements ContentStore
+func (s *FileStore) Has(ctx context.Context, key Key) (bool, error) {
+	if _, err := ParseKey(string(key)); err != nil {
+		return false, err
+	}
+	_, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// contextReader stops reading once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
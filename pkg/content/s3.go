@@ -0,0 +1,237 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body, signed for GET and HEAD
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config configures an S3Store
+type S3Config struct {
+	// Endpoint is the object store's base URL; empty uses AWS S3 in Region.
+	// Buckets are addressed path-style, as S3-compatible stores expect.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every object name, such as "similarity/content/"
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is needed with temporary credentials
+	SessionToken string
+	// Client sends the requests; nil uses a client with a one-minute timeout
+	Client *http.Client
+}
+
+// S3Store keeps content as objects in an S3 bucket, signing requests with
+// AWS Signature Version 4
+type S3Store struct {
+	config S3Config
+	base   string
+}
+
+// NewS3Store creates a store in the bucket cfg names
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Region == "" {
+		return nil, domain.NewConfigError("region", cfg.Region, "must not be empty")
+	}
+	if cfg.Bucket == "" || strings.ContainsAny(cfg.Bucket, "/?#") {
+		return nil, domain.NewConfigError("bucket", cfg.Bucket, "must be a bucket name")
+	}
+	if strings.Trim(cfg.Prefix, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./") != "" {
+		return nil, domain.NewConfigError("prefix", cfg.Prefix, "may only hold letters, digits and - _ . /")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, domain.NewConfigError("accessKeyID", cfg.AccessKeyID, "needs both an access key ID and a secret access key")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if _, err := url.Parse(cfg.Endpoint); err != nil {
+		return nil, domain.NewConfigError("endpoint", cfg.Endpoint, err.Error())
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: time.Minute}
+	}
+
+	return &S3Store{
+		config: cfg,
+		base:   strings.TrimSuffix(cfg.Endpoint, "/") + "/" + cfg.Bucket + "/" + cfg.Prefix,
+	}, nil
+}
+
+// Put implements ContentStore. S3 needs the length and hash of an object
+// before it is sent, so the content is held in memory while it is uploaded.
+func (s *S3Store) Put(ctx context.Context, r io.Reader) (Key, error) {
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return "", err
+	}
+	key := KeyOf(data)
+
+	exists, err := s.Has(ctx, key)
+	if err != nil || exists {
+		return key, err
+	}
+
+	// The key is the payload's SHA-256, which is what the signature covers
+	resp, err := s.do(ctx, http.MethodPut, key, data, string(key))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", s.statusError(http.MethodPut, key, resp)
+	}
+	return key, nil
+}
+
+// Open implements ContentStore
+func (s *S3Store) Open(ctx context.Context, key Key) (io.ReadCloser, error) {
+	if _, err := ParseKey(string(key)); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode/100 != 2:
+		defer resp.Body.Close()
+		return nil, s.statusError(http.MethodGet, key, resp)
+	}
+	return resp.Body, nil
+}
+
+// Has implements ContentStore
+func (s *S3Store) Has(ctx context.Context, key Key) (bool, error) {
+	if _, err := ParseKey(string(key)); err != nil {
+		return false, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, key, nil, emptyPayloadHash)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode/100 != 2:
+		return false, s.statusError(http.MethodHead, key, resp)
+	}
+	return true, nil
+}
+
+// do sends a signed request for the object holding key
+func (s *S3Store) do(ctx context.Context, method string, key Key, body []byte, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.base+string(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+	signV4(req, payloadHash, s.config.AccessKeyID, s.config.SecretAccessKey, s.config.Region, time.Now())
+	return s.config.Client.Do(req)
+}
+
+// statusError describes an unexpected response, including the start of its body
+func (s *S3Store) statusError(method string, key Key, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("content: %s %s: %s %s", method, key, resp.Status, bytes.TrimSpace(detail))
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header for the S3
+// service. It signs the Host header and every header already set on req.
+func signV4(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), day)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsEscape(name)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything except the unreserved characters
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
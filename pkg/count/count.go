@@ -0,0 +1,138 @@
+// Package count exposes the fast counters used by the streaming engines as
+// standalone utilities, for callers that only need counts and not similarity.
+package count
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"unicode/utf8"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/wordprocessor"
+	"github.com/baditaflorin/go_length_similarity/internal/pool"
+)
+
+// chunkSize is the number of bytes requested per Read call
+const chunkSize = 64 * 1024
+
+var chunkPool = pool.NewBufferPool(chunkSize)
+
+// CountWords returns the number of words in r, using the same word boundaries
+// as the WordByWord streaming mode.
+func CountWords(r io.Reader) (int, error) {
+	return CountWordsContext(context.Background(), r)
+}
+
+// CountWordsContext is like CountWords but stops when ctx is cancelled.
+func CountWordsContext(ctx context.Context, r io.Reader) (int, error) {
+	proc := wordprocessor.NewProcessor(logger.NewNopLogger(), normalizer.NewDefaultNormalizer(), wordprocessor.ProcessingConfig{
+		ChunkSize: chunkSize,
+	})
+
+	n, _, err := proc.ProcessWords(ctx, r, nil)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// IsWordChar reports whether CountWords and the WordByWord streaming mode
+// count r as part of a word by default: letters, digits, underscores, hyphens
+// and apostrophes. Word classifiers can build on it.
+func IsWordChar(r rune) bool {
+	return wordprocessor.IsWordChar(r)
+}
+
+// CountRunes returns the number of UTF-8 encoded runes in r. Invalid bytes are
+// counted as one rune each, matching utf8.RuneCount.
+func CountRunes(r io.Reader) (int, error) {
+	return CountRunesContext(context.Background(), r)
+}
+
+// CountRunesContext is like CountRunes but stops when ctx is cancelled.
+func CountRunesContext(ctx context.Context, r io.Reader) (int, error) {
+	count := 0
+	err := readChunks(ctx, r, func(chunk []byte, final bool) int {
+		// Hold back a rune split across reads so it is counted once it is complete
+		if !final {
+			if i := lastRuneStart(chunk); i >= 0 && !utf8.FullRune(chunk[i:]) {
+				count += utf8.RuneCount(chunk[:i])
+				return len(chunk) - i
+			}
+		}
+		count += utf8.RuneCount(chunk)
+		return 0
+	})
+	return count, err
+}
+
+// CountLines returns the number of lines in r. A final line without a trailing
+// newline is counted; an empty reader has zero lines.
+func CountLines(r io.Reader) (int, error) {
+	return CountLinesContext(context.Background(), r)
+}
+
+// CountLinesContext is like CountLines but stops when ctx is cancelled.
+func CountLinesContext(ctx context.Context, r io.Reader) (int, error) {
+	count := 0
+	var last byte = '\n'
+
+	err := readChunks(ctx, r, func(chunk []byte, final bool) int {
+		count += bytes.Count(chunk, []byte{'\n'})
+		last = chunk[len(chunk)-1]
+		return 0
+	})
+
+	if last != '\n' {
+		count++
+	}
+	return count, err
+}
+
+// readChunks feeds every non-empty read from r to fn until EOF, an error or
+// cancellation. fn returns how many trailing bytes to carry into the next call;
+// carried bytes still pending at EOF are passed once more with final set.
+func readChunks(ctx context.Context, r io.Reader, fn func(chunk []byte, final bool) int) error {
+	buffer := chunkPool.Get()
+	defer chunkPool.Put(buffer)
+	if cap(*buffer) < chunkSize {
+		*buffer = make([]byte, chunkSize)
+	}
+	buf := (*buffer)[:chunkSize]
+	carried := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := r.Read(buf[carried:])
+		if n > 0 {
+			chunk := buf[:carried+n]
+			keep := fn(chunk, false)
+			carried = copy(buf, chunk[len(chunk)-keep:])
+		}
+		if err == io.EOF {
+			if carried > 0 {
+				fn(buf[:carried], true)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lastRuneStart returns the index of the last rune start byte within the final
+// utf8.UTFMax bytes of b, or -1 if there is none
+func lastRuneStart(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,47 @@
+package count
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+func TestCounters(t *testing.T) {
+	cases := []struct {
+		name                string
+		input               string
+		words, runes, lines int
+	}{
+		{"empty", "", 0, 0, 0},
+		{"single line", "hello world", 2, 11, 1},
+		{"trailing newline", "one two\nthree\n", 3, 14, 2},
+		{"unicode", "héllo wörld\n日本語", 3, 15, 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// OneByteReader splits multi-byte runes across reads
+			if got, err := CountRunes(iotest.OneByteReader(strings.NewReader(tc.input))); err != nil || got != tc.runes {
+				t.Errorf("CountRunes = %d, %v; want %d", got, err, tc.runes)
+			}
+			if got, err := CountLines(strings.NewReader(tc.input)); err != nil || got != tc.lines {
+				t.Errorf("CountLines = %d, %v; want %d", got, err, tc.lines)
+			}
+			if got, err := CountWords(strings.NewReader(tc.input)); err != nil || got != tc.words {
+				t.Errorf("CountWords = %d, %v; want %d", got, err, tc.words)
+			}
+		})
+	}
+}
+
+func TestCountRunesMatchesRuneCount(t *testing.T) {
+	input := strings.Repeat("a€\xffb日", 30000)
+	got, err := CountRunes(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := utf8.RuneCountInString(input); got != want {
+		t.Errorf("CountRunes = %d, want %d", got, want)
+	}
+}
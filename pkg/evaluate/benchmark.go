@@ -0,0 +1,136 @@
+package evaluate
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// Case is one named pair to benchmark
+type Case struct {
+	Name      string
+	Original  string
+	Augmented string
+}
+
+// Timing is the outcome of benchmarking one metric on one case
+type Timing struct {
+	Case       string
+	Iterations int
+	Total      time.Duration
+	Average    time.Duration
+	// BytesPerIteration is the heap allocated per iteration. It is measured
+	// process-wide, so other goroutines running at the same time inflate it.
+	BytesPerIteration uint64
+	// Result is the result of the last iteration
+	Result Result
+}
+
+// BenchmarkOption defines a functional option for Benchmark
+type BenchmarkOption func(*benchmarkConfig)
+
+type benchmarkConfig struct {
+	Iterations int
+	Warmup     int
+}
+
+// WithIterations sets how many times each case is scored. Default 10.
+func WithIterations(n int) BenchmarkOption {
+	return func(cfg *benchmarkConfig) {
+		cfg.Iterations = n
+	}
+}
+
+// WithWarmupIterations sets how many untimed runs precede the timed ones. Default 1.
+func WithWarmupIterations(n int) BenchmarkOption {
+	return func(cfg *benchmarkConfig) {
+		cfg.Warmup = n
+	}
+}
+
+// Benchmark times m on every case, in order. On cancellation it returns the
+// timings of the cases that finished and the context error.
+func Benchmark(ctx context.Context, m Metric, cases []Case, opts ...BenchmarkOption) ([]Timing, error) {
+	config := &benchmarkConfig{
+		Iterations: 10,
+		Warmup:     1,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.Iterations < 1 {
+		return nil, domain.NewConfigError("iterations", config.Iterations, "must be at least 1")
+	}
+	if config.Warmup < 0 {
+		return nil, domain.NewConfigError("warmup", config.Warmup, "must not be negative")
+	}
+
+	timings := make([]Timing, 0, len(cases))
+	var before, after runtime.MemStats
+	for _, c := range cases {
+		for i := 0; i < config.Warmup; i++ {
+			m.Compute(ctx, c.Original, c.Augmented)
+		}
+
+		t := Timing{Case: c.Name, Iterations: config.Iterations}
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		for i := 0; i < config.Iterations; i++ {
+			t.Result = m.Compute(ctx, c.Original, c.Augmented)
+		}
+		t.Total = time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if err := ctx.Err(); err != nil {
+			return timings, err
+		}
+		t.Average = t.Total / time.Duration(config.Iterations)
+		t.BytesPerIteration = (after.TotalAlloc - before.TotalAlloc) / uint64(config.Iterations)
+		timings = append(timings, t)
+	}
+
+	return timings, nil
+}
+
+// sampleWords and sampleReplacements are the vocabulary of SyntheticCase
+var (
+	sampleWords = []string{
+		"the", "quick", "brown", "fox", "jumps", "over", "lazy", "dog",
+		"hello", "world", "lorem", "ipsum", "dolor", "sit", "amet", "consectetur",
+		"adipiscing", "elit", "sed", "do", "eiusmod", "tempor", "incididunt",
+		"ut", "labore", "et", "dolore", "magna", "aliqua", "enim", "minim",
+		"veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris",
+	}
+	sampleReplacements = []string{
+		"modified", "changed", "altered", "different", "updated",
+		"replaced", "revised", "transformed", "adjusted", "varied",
+	}
+)
+
+// SyntheticCase builds a case of words words whose augmented text has the
+// first diffRatio of its words replaced. The output is deterministic.
+func SyntheticCase(name string, words int, diffRatio float64) Case {
+	original := make([]string, words)
+	for i := range original {
+		original[i] = sampleWords[i%len(sampleWords)]
+	}
+
+	augmented := make([]string, words)
+	copy(augmented, original)
+	modified := min(int(float64(words)*diffRatio), words)
+	for i := 0; i < modified; i++ {
+		augmented[i] = sampleReplacements[i%len(sampleReplacements)]
+	}
+
+	return Case{
+		Name:      name,
+		Original:  strings.Join(original, " "),
+		Augmented: strings.Join(augmented, " "),
+	}
+}
@@ -0,0 +1,56 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBenchmarkTimesEveryCase(t *testing.T) {
+	calls := 0
+	m := MetricFunc(func(context.Context, string, string) Result {
+		calls++
+		return Result{Score: 1, Passed: true}
+	})
+	cases := []Case{SyntheticCase("small", 10, 0.1), SyntheticCase("large", 100, 0.1)}
+
+	timings, err := Benchmark(context.Background(), m, cases, WithIterations(3), WithWarmupIterations(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timings) != 2 || timings[1].Case != "large" || timings[0].Iterations != 3 || !timings[0].Result.Passed {
+		t.Errorf("Benchmark() = %+v, want one timing per case", timings)
+	}
+	if calls != 10 {
+		t.Errorf("metric ran %d times, want 10", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if timings, err := Benchmark(ctx, m, cases); len(timings) != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: %d timings, %v; want none and context.Canceled", len(timings), err)
+	}
+
+	var cfgErr *ConfigError
+	if _, err := Benchmark(context.Background(), m, cases, WithIterations(0)); !errors.As(err, &cfgErr) {
+		t.Errorf("expected ConfigError, got %v", err)
+	}
+}
+
+func TestSyntheticCase(t *testing.T) {
+	c := SyntheticCase("ten", 10, 0.2)
+	orig, aug := strings.Fields(c.Original), strings.Fields(c.Augmented)
+	if len(orig) != 10 || len(aug) != 10 {
+		t.Fatalf("SyntheticCase() = %+v, want 10 words on each side", c)
+	}
+	changed := 0
+	for i := range orig {
+		if orig[i] != aug[i] {
+			changed++
+		}
+	}
+	if changed != 2 {
+		t.Errorf("%d words changed, want 2", changed)
+	}
+}
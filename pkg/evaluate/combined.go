@@ -0,0 +1,186 @@
+package evaluate
+
+import (
+	"context"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+)
+
+// MetricFunc adapts a function to the Metric interface, for example to wrap
+// a streaming calculator whose result type differs
+type MetricFunc func(ctx context.Context, original, augmented string) Result
+
+// Compute calls f
+func (f MetricFunc) Compute(ctx context.Context, original, augmented string) Result {
+	return f(ctx, original, augmented)
+}
+
+// Weighted is a metric and its share of a combined score
+type Weighted struct {
+	Name   string
+	Metric Metric
+	Weight float64
+}
+
+// Combined is the outcome of scoring one pair with a Combiner
+type Combined struct {
+	// Score is the weighted mean of the metric scores
+	Score float64
+	// Passed is set when Score reaches the threshold and no metric was inconclusive
+	Passed bool
+	// Inconclusive is set when a metric could not score the pair
+	Inconclusive bool
+	// Names, Weights and Results hold one entry per metric, in the order
+	// given. Weights are normalized to sum to 1.
+	Names   []string
+	Weights []float64
+	Results []Result
+	// Err is the context error if the pair was cancelled before a verdict
+	Err error
+}
+
+// Combiner folds several metrics into one weighted score; it is safe for concurrent use
+type Combiner struct {
+	threshold float64
+	names     []string
+	weights   []float64
+	metrics   []Metric
+}
+
+// CombinerOption defines a functional option for configuring a Combiner
+type CombinerOption func(*combinerConfig)
+
+type combinerConfig struct {
+	Threshold       float64
+	WordWeight      float64
+	CharacterWeight float64
+	Logger          l.Logger
+	Metrics         []Weighted
+}
+
+// WithCombinedThreshold sets the score the weighted mean must reach. Default 0.7.
+func WithCombinedThreshold(th float64) CombinerOption {
+	return func(cfg *combinerConfig) {
+		cfg.Threshold = th
+	}
+}
+
+// WithWeights sets the weights of the default word and character length
+// metrics. They need not sum to 1. Default 0.5 each.
+func WithWeights(wordWeight, characterWeight float64) CombinerOption {
+	return func(cfg *combinerConfig) {
+		cfg.WordWeight = wordWeight
+		cfg.CharacterWeight = characterWeight
+	}
+}
+
+// WithCombinerLogger sets the logger used by the default metrics
+func WithCombinerLogger(l l.Logger) CombinerOption {
+	return func(cfg *combinerConfig) {
+		cfg.Logger = l
+	}
+}
+
+// WithWeightedMetrics replaces the default metrics (word length and character length)
+func WithWeightedMetrics(metrics ...Weighted) CombinerOption {
+	return func(cfg *combinerConfig) {
+		cfg.Metrics = metrics
+	}
+}
+
+// NewCombiner creates a Combiner
+func NewCombiner(opts ...CombinerOption) (*Combiner, error) {
+	config := &combinerConfig{
+		Threshold:       0.7,
+		WordWeight:      0.5,
+		CharacterWeight: 0.5,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if err := domain.ValidateThreshold(config.Threshold); err != nil {
+		return nil, err
+	}
+
+	if config.Metrics == nil {
+		config.Metrics = []Weighted{
+			{Name: "word", Weight: config.WordWeight},
+			{Name: "character", Weight: config.CharacterWeight},
+		}
+	}
+
+	// Validate the weights before building the default metrics
+	sum := 0.0
+	for _, m := range config.Metrics {
+		if m.Weight < 0 {
+			return nil, domain.NewConfigError("weight", m.Weight, "must not be negative")
+		}
+		sum += m.Weight
+	}
+	if sum <= 0 {
+		return nil, domain.NewConfigError("weights", sum, "must sum to more than 0")
+	}
+
+	c := &Combiner{threshold: config.Threshold}
+	for _, m := range config.Metrics {
+		metric := m.Metric
+		if metric == nil {
+			var err error
+			if metric, err = defaultMetric(m.Name, config.Threshold, config.Logger); err != nil {
+				return nil, err
+			}
+		}
+		c.names = append(c.names, m.Name)
+		c.weights = append(c.weights, m.Weight/sum)
+		c.metrics = append(c.metrics, metric)
+	}
+
+	return c, nil
+}
+
+// defaultMetric builds the default metric called name
+func defaultMetric(name string, threshold float64, logger l.Logger) (Metric, error) {
+	switch name {
+	case "word":
+		opts := []word.LengthSimilarityOption{word.WithThreshold(threshold), word.WithFastNormalizer()}
+		if logger != nil {
+			opts = append(opts, word.WithLogger(logger))
+		}
+		return word.New(opts...)
+	case "character":
+		opts := []character.CharacterSimilarityOption{character.WithThreshold(threshold), character.WithFastNormalizer()}
+		if logger != nil {
+			opts = append(opts, character.WithLogger(logger))
+		}
+		return character.NewCharacterSimilarity(opts...)
+	}
+	return nil, domain.NewConfigError("metric", name, "must not be nil")
+}
+
+// Combine scores original against augmented with every metric and folds the
+// scores into their weighted mean
+func (c *Combiner) Combine(ctx context.Context, original, augmented string) Combined {
+	out := Combined{
+		Names:   c.names,
+		Weights: c.weights,
+		Results: make([]Result, 0, len(c.metrics)),
+	}
+	for i, m := range c.metrics {
+		r := m.Compute(ctx, original, augmented)
+		out.Results = append(out.Results, r)
+		out.Score += r.Score * c.weights[i]
+		out.Inconclusive = out.Inconclusive || r.Inconclusive
+	}
+	if err := ctx.Err(); err != nil {
+		return Combined{Names: c.names, Weights: c.weights, Results: out.Results, Err: err}
+	}
+
+	out.Passed = !out.Inconclusive && out.Score >= c.threshold
+	return out
+}
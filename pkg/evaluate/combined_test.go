@@ -0,0 +1,68 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func fixed(score float64) Metric {
+	return MetricFunc(func(context.Context, string, string) Result {
+		return Result{Score: score}
+	})
+}
+
+func TestCombineWeightsScores(t *testing.T) {
+	c, err := NewCombiner(
+		WithCombinedThreshold(0.7),
+		WithWeightedMetrics(
+			Weighted{Name: "low", Metric: fixed(0.5), Weight: 3},
+			Weighted{Name: "high", Metric: fixed(1), Weight: 1},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := c.Combine(context.Background(), "a", "b")
+	if math.Abs(got.Score-0.625) > 1e-9 || got.Passed {
+		t.Errorf("Combine() = %+v, want score 0.625 and a fail", got)
+	}
+	if got.Weights[0] != 0.75 || got.Weights[1] != 0.25 || got.Names[1] != "high" || len(got.Results) != 2 {
+		t.Errorf("Combine() = %+v, want normalized weights and one result per metric", got)
+	}
+}
+
+func TestCombineDefaultMetrics(t *testing.T) {
+	c, err := NewCombiner(WithCombinerLogger(discardLogger(t)), WithWeights(1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := c.Combine(context.Background(), "the quick brown fox jumps", "the quick brown fox jumps")
+	if got.Score != 1 || !got.Passed || got.Names[0] != "word" || got.Weights[1] != 0.75 {
+		t.Errorf("identical texts: %+v, want a pass with score 1", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := c.Combine(ctx, "a b c", "a b c"); got.Passed || !errors.Is(got.Err, context.Canceled) {
+		t.Errorf("cancelled: %+v, want context.Canceled", got)
+	}
+}
+
+func TestNewCombinerRejectsInvalidWeights(t *testing.T) {
+	for _, opt := range []CombinerOption{
+		WithWeights(0, 0),
+		WithWeights(-1, 2),
+		WithCombinedThreshold(1.5),
+		WithWeightedMetrics(Weighted{Name: "custom", Weight: 1}),
+	} {
+		_, err := NewCombiner(WithCombinerLogger(discardLogger(t)), opt)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("expected ConfigError, got %v", err)
+		}
+	}
+}
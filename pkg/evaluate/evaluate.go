@@ -0,0 +1,224 @@
+// Package evaluate runs several similarity metrics over one pair of texts in
+// order of cost. The cheap length metrics run first; the expensive content
+// metrics (edit distance and n-gram overlap) only run when the cheap scores
+// fall inside an inconclusive band, so most pairs at scale never pay for them.
+// A Combiner folds several metrics into one weighted score, and Benchmark
+// times any metric on a set of cases.
+package evaluate
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/core/distance"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+)
+
+// Result is the outcome of one metric
+type Result = domain.Result
+
+// ConfigError reports an invalid option value passed to New
+type ConfigError = domain.ConfigError
+
+// Metric is one similarity measure. *word.LengthSimilarity and
+// *character.CharacterSimilarity satisfy it.
+type Metric interface {
+	Compute(ctx context.Context, original, augmented string) Result
+}
+
+// Evaluation is the outcome of evaluating one pair
+type Evaluation struct {
+	// Passed is the verdict. Without escalation the pair passes when every
+	// cheap score is above the band; with escalation it passes when every
+	// expensive metric passes its own threshold.
+	Passed bool
+	// Escalated is set when the cheap scores were inconclusive and the expensive metrics ran
+	Escalated bool
+	// Results holds every metric that ran, cheap metrics first
+	Results []Result
+	// Err is the context error if the evaluation was cancelled before a verdict
+	Err error
+}
+
+// Evaluator runs metrics in order of cost; it is safe for concurrent use
+type Evaluator struct {
+	low, high float64
+	cheap     []Metric
+	expensive []Metric
+
+	evaluations atomic.Int64
+	escalations atomic.Int64
+}
+
+// Option defines a functional option for configuring an Evaluator
+type Option func(*config)
+
+type config struct {
+	Low       float64
+	High      float64
+	Threshold float64
+	NGramSize int
+	Logger    l.Logger
+	Cheap     []Metric
+	Expensive []Metric
+}
+
+// WithBand sets the inconclusive band. When the lowest cheap score is in
+// [low, high] the expensive metrics decide; below low the pair fails and above
+// high it passes without them. Default [0.5, 0.95]. A high of 1 sends every
+// pair that is not clearly too short or too long to the expensive metrics.
+func WithBand(low, high float64) Option {
+	return func(cfg *config) {
+		cfg.Low = low
+		cfg.High = high
+	}
+}
+
+// WithThreshold sets the pass threshold of the default metrics. Default 0.7.
+func WithThreshold(th float64) Option {
+	return func(cfg *config) {
+		cfg.Threshold = th
+	}
+}
+
+// WithNGramSize sets the number of characters per n-gram in the default
+// n-gram metric. Default 3.
+func WithNGramSize(n int) Option {
+	return func(cfg *config) {
+		cfg.NGramSize = n
+	}
+}
+
+// WithLogger sets the logger used by the default cheap metrics
+func WithLogger(l l.Logger) Option {
+	return func(cfg *config) {
+		cfg.Logger = l
+	}
+}
+
+// WithCheapMetrics replaces the default cheap metrics (word length and character length)
+func WithCheapMetrics(metrics ...Metric) Option {
+	return func(cfg *config) {
+		cfg.Cheap = metrics
+	}
+}
+
+// WithExpensiveMetrics replaces the default expensive metrics (edit distance and n-gram overlap)
+func WithExpensiveMetrics(metrics ...Metric) Option {
+	return func(cfg *config) {
+		cfg.Expensive = metrics
+	}
+}
+
+// New creates an Evaluator
+func New(opts ...Option) (*Evaluator, error) {
+	config := &config{
+		Low:       0.5,
+		High:      0.95,
+		Threshold: 0.7,
+		NGramSize: distance.DefaultNGramSize,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Validate before allocating any resources
+	if err := domain.ValidateThreshold(config.Low); err != nil {
+		return nil, domain.NewConfigError("low", config.Low, "must be between 0 and 1")
+	}
+	if err := domain.ValidateThreshold(config.High); err != nil || config.High < config.Low {
+		return nil, domain.NewConfigError("high", config.High, "must be between low and 1")
+	}
+	contentConfig := distance.Config{Threshold: config.Threshold, NGramSize: config.NGramSize}
+	if err := contentConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Cheap == nil {
+		wordOpts := []word.LengthSimilarityOption{word.WithThreshold(config.Threshold), word.WithFastNormalizer()}
+		charOpts := []character.CharacterSimilarityOption{character.WithThreshold(config.Threshold), character.WithFastNormalizer()}
+		if config.Logger != nil {
+			wordOpts = append(wordOpts, word.WithLogger(config.Logger))
+			charOpts = append(charOpts, character.WithLogger(config.Logger))
+		}
+
+		ls, err := word.New(wordOpts...)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := character.NewCharacterSimilarity(charOpts...)
+		if err != nil {
+			return nil, err
+		}
+		config.Cheap = []Metric{ls, cs}
+	}
+
+	if config.Expensive == nil {
+		norm := normalizer.NewDefaultNormalizer()
+		edit, err := distance.NewEditCalculator(contentConfig, norm)
+		if err != nil {
+			return nil, err
+		}
+		ngram, err := distance.NewNGramCalculator(contentConfig, norm)
+		if err != nil {
+			return nil, err
+		}
+		config.Expensive = []Metric{edit, ngram}
+	}
+
+	return &Evaluator{
+		low:       config.Low,
+		high:      config.High,
+		cheap:     config.Cheap,
+		expensive: config.Expensive,
+	}, nil
+}
+
+// Evaluate scores original against augmented, escalating to the expensive
+// metrics only when the cheap ones are inconclusive
+func (e *Evaluator) Evaluate(ctx context.Context, original, augmented string) Evaluation {
+	e.evaluations.Add(1)
+
+	results := make([]Result, 0, len(e.cheap)+len(e.expensive))
+	lowest := 1.0
+	inconclusive := len(e.cheap) == 0
+	for _, m := range e.cheap {
+		r := m.Compute(ctx, original, augmented)
+		results = append(results, r)
+		lowest = min(lowest, r.Score)
+		inconclusive = inconclusive || r.Inconclusive
+	}
+	if err := ctx.Err(); err != nil {
+		return Evaluation{Results: results, Err: err}
+	}
+
+	// With no cheap metrics, or one too short to score, every pair escalates
+	conclusive := !inconclusive && (lowest < e.low || lowest > e.high)
+	if conclusive || len(e.expensive) == 0 {
+		return Evaluation{Passed: lowest > e.high, Results: results}
+	}
+
+	e.escalations.Add(1)
+	passed := true
+	for _, m := range e.expensive {
+		r := m.Compute(ctx, original, augmented)
+		results = append(results, r)
+		passed = passed && r.Passed
+	}
+	if err := ctx.Err(); err != nil {
+		return Evaluation{Escalated: true, Results: results, Err: err}
+	}
+
+	return Evaluation{Passed: passed, Escalated: true, Results: results}
+}
+
+// Stats reports how many pairs were evaluated and how many of them needed the expensive metrics
+func (e *Evaluator) Stats() (evaluations, escalations int64) {
+	return e.evaluations.Load(), e.escalations.Load()
+}
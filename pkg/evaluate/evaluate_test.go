@@ -0,0 +1,66 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func discardLogger(t *testing.T) l.Logger {
+	t.Helper()
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestEvaluateEscalatesOnlyInsideBand(t *testing.T) {
+	e, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	same := e.Evaluate(ctx, "the quick brown fox jumps", "the quick brown fox jumps")
+	if !same.Passed || same.Escalated || len(same.Results) != 2 {
+		t.Errorf("identical texts: %+v, want a pass from the cheap metrics alone", same)
+	}
+
+	short := e.Evaluate(ctx, "the quick brown fox jumps over the lazy dog today", "a fox")
+	if short.Passed || short.Escalated {
+		t.Errorf("very different lengths: %+v, want a fail from the cheap metrics alone", short)
+	}
+
+	if evaluations, escalations := e.Stats(); evaluations != 2 || escalations != 0 {
+		t.Errorf("Stats() = %d, %d; want 2, 0", evaluations, escalations)
+	}
+
+	// Similar length, different content: the length metrics cannot tell
+	e, err = New(WithLogger(discardLogger(t)), WithBand(0.5, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped := e.Evaluate(ctx, "the quick brown fox jumps over the lazy dog", "my small green cat sleeps under a warm rug")
+	if !swapped.Escalated || swapped.Passed || len(swapped.Results) != 4 {
+		t.Errorf("same-length rewrite: %+v, want escalation and a fail", swapped)
+	}
+
+	if evaluations, escalations := e.Stats(); evaluations != 1 || escalations != 1 {
+		t.Errorf("Stats() = %d, %d; want 1, 1", evaluations, escalations)
+	}
+}
+
+func TestEvaluateRejectsInvalidBand(t *testing.T) {
+	for _, opt := range []Option{WithBand(0.9, 0.5), WithBand(-0.1, 0.5), WithNGramSize(0)} {
+		_, err := New(WithLogger(discardLogger(t)), opt)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("expected ConfigError, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package features gates experimental engines behind named flags, so a
+// service can roll them out gradually: enable one on a few instances through
+// the environment or a config file, watch it, then widen it.
+//
+// A flag list is a comma-separated set of names; a leading "-" turns a flag
+// off. Flags not named keep their default:
+//
+//	SIMILARITY_FEATURES=zero_copy,-parallel_chunks
+package features
+
+import (
+	"os"
+	"strings"
+
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// Flag names an experimental component
+type Flag string
+
+// Known flags
+const (
+	// SIMDNormalizer selects a vectorized normalizer. None is built into this
+	// version, so enabling it has no effect; Unavailable reports it.
+	SIMDNormalizer Flag = "simd_normalizer"
+	// ParallelChunks normalizes the chunks of large inputs on several
+	// goroutines in the allocation-efficient streaming calculator
+	ParallelChunks Flag = "parallel_chunks"
+	// ZeroCopy shares memory between byte slices and strings on the streaming
+	// hot paths. It only takes effect in binaries built with -tags zerocopy.
+	ZeroCopy Flag = "zero_copy"
+)
+
+// ConfigError reports an unknown flag
+type ConfigError = domain.ConfigError
+
+// known lists each flag with its state when a list does not name it, in
+// name order; a flag's position is its bit in a Set
+var known = []struct {
+	flag    Flag
+	enabled bool
+}{
+	{ParallelChunks, true},
+	{SIMDNormalizer, false},
+	{ZeroCopy, false},
+}
+
+// Flags returns the known flags in name order
+func Flags() []Flag {
+	flags := make([]Flag, len(known))
+	for i, k := range known {
+		flags[i] = k.flag
+	}
+	return flags
+}
+
+// bit returns the bit of f in a Set, or 0 if f is unknown
+func bit(f Flag) uint32 {
+	for i, k := range known {
+		if k.flag == f {
+			return 1 << i
+		}
+	}
+	return 0
+}
+
+// Available reports whether this binary contains the component behind f
+func Available(f Flag) bool {
+	switch f {
+	case ParallelChunks:
+		return true
+	case ZeroCopy:
+		return bytesconv.ZeroCopy
+	}
+	return false
+}
+
+// Set is the state of every flag. The zero Set has every flag at its default.
+// It implements flag.Value and encoding.TextUnmarshaler, so it can be filled
+// from a command-line flag or a JSON config string.
+type Set struct {
+	// named has a bit for each flag the list named; on has it for those it enabled
+	named, on uint32
+}
+
+// Parse reads a comma-separated flag list. Unknown names return a *ConfigError.
+func Parse(list string) (Set, error) {
+	var s Set
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		enable := !strings.HasPrefix(name, "-")
+		f := Flag(strings.TrimPrefix(name, "-"))
+		b := bit(f)
+		if b == 0 {
+			return Set{}, domain.NewConfigError("features", name, "names an unknown feature; known features are "+joinFlags(Flags()))
+		}
+		s.named |= b
+		if enable {
+			s.on |= b
+		} else {
+			s.on &^= b
+		}
+	}
+	return s, nil
+}
+
+// FromEnv parses the flag list in the environment variable name; an unset
+// variable leaves every flag at its default
+func FromEnv(name string) (Set, error) {
+	s, err := Parse(os.Getenv(name))
+	if configErr, ok := err.(*ConfigError); ok {
+		configErr.Field = name
+	}
+	return s, err
+}
+
+// Enabled reports whether f is on
+func (s Set) Enabled(f Flag) bool {
+	for i, k := range known {
+		if k.flag == f {
+			if s.named&(1<<i) != 0 {
+				return s.on&(1<<i) != 0
+			}
+			return k.enabled
+		}
+	}
+	return false
+}
+
+// Unavailable returns the enabled flags this binary cannot honor, so callers
+// can warn that they are being ignored
+func (s Set) Unavailable() []Flag {
+	var flags []Flag
+	for _, f := range Flags() {
+		if s.Enabled(f) && !Available(f) {
+			flags = append(flags, f)
+		}
+	}
+	return flags
+}
+
+// Apply puts the process-wide flags into effect. ZeroCopy is the only one;
+// the others are read by whoever builds the calculators.
+func (s Set) Apply() {
+	bytesconv.SetEnabled(s.Enabled(ZeroCopy))
+}
+
+// String lists the flags that differ from their defaults, in the form Parse reads
+func (s Set) String() string {
+	var names []string
+	for _, k := range known {
+		if enable := s.Enabled(k.flag); enable != k.enabled {
+			if enable {
+				names = append(names, string(k.flag))
+			} else {
+				names = append(names, "-"+string(k.flag))
+			}
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Set implements flag.Value
+func (s *Set) Set(list string) error {
+	parsed, err := Parse(list)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// MarshalText writes the set in the form Parse reads
+func (s Set) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads a flag list, as Parse does
+func (s *Set) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
+
+func joinFlags(flags []Flag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
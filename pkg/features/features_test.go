@@ -0,0 +1,96 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/bytesconv"
+)
+
+func TestDefaults(t *testing.T) {
+	var s Set
+	if !s.Enabled(ParallelChunks) || s.Enabled(ZeroCopy) || s.Enabled(SIMDNormalizer) {
+		t.Errorf("zero Set: parallel=%v zero_copy=%v simd=%v", s.Enabled(ParallelChunks), s.Enabled(ZeroCopy), s.Enabled(SIMDNormalizer))
+	}
+	if s.Enabled("unknown") {
+		t.Error("an unknown flag is enabled")
+	}
+	if got := s.String(); got != "" {
+		t.Errorf("String() = %q, want empty", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(" zero_copy , -parallel_chunks,,simd_normalizer,-simd_normalizer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Enabled(ZeroCopy) || s.Enabled(ParallelChunks) || s.Enabled(SIMDNormalizer) {
+		t.Errorf("parsed set = %q", s)
+	}
+	if got, want := s.String(), "-parallel_chunks,zero_copy"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	var configErr *ConfigError
+	if _, err := Parse("zero_copy,turbo"); !errors.As(err, &configErr) || configErr.Value != "turbo" {
+		t.Errorf("unknown flag: err = %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("TEST_FEATURES", "-parallel_chunks")
+	s, err := FromEnv("TEST_FEATURES")
+	if err != nil || s.Enabled(ParallelChunks) {
+		t.Errorf("FromEnv = %q, %v", s, err)
+	}
+
+	t.Setenv("TEST_FEATURES", "nope")
+	var configErr *ConfigError
+	if _, err := FromEnv("TEST_FEATURES"); !errors.As(err, &configErr) || configErr.Field != "TEST_FEATURES" {
+		t.Errorf("err = %v, want a ConfigError naming the variable", err)
+	}
+}
+
+func TestFlagAndJSON(t *testing.T) {
+	var s Set
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&s, "features", "")
+	if err := fs.Parse([]string{"-features", "zero_copy"}); err != nil || !s.Enabled(ZeroCopy) {
+		t.Errorf("flag: %q, %v", s, err)
+	}
+
+	var cfg struct {
+		Features Set `json:"features"`
+	}
+	if err := json.Unmarshal([]byte(`{"features": "-parallel_chunks"}`), &cfg); err != nil || cfg.Features.Enabled(ParallelChunks) {
+		t.Errorf("json: %q, %v", cfg.Features, err)
+	}
+	if data, err := json.Marshal(cfg); err != nil || string(data) != `{"features":"-parallel_chunks"}` {
+		t.Errorf("marshal: %s, %v", data, err)
+	}
+}
+
+func TestUnavailableAndApply(t *testing.T) {
+	defer bytesconv.SetEnabled(bytesconv.Enabled())
+
+	s, _ := Parse("simd_normalizer,zero_copy")
+	want := []Flag{SIMDNormalizer}
+	if !bytesconv.ZeroCopy {
+		want = append(want, ZeroCopy)
+	}
+	if got := s.Unavailable(); len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("Unavailable() = %v, want %v", got, want)
+	}
+
+	s.Apply()
+	if bytesconv.Enabled() != bytesconv.ZeroCopy {
+		t.Errorf("after enabling zero_copy, sharing = %v", bytesconv.Enabled())
+	}
+	Set{}.Apply()
+	if bytesconv.Enabled() {
+		t.Error("zero_copy stays on after applying the defaults")
+	}
+}
@@ -0,0 +1,56 @@
+// Package metrics exposes the measurements calculators record, decoupled from
+// logging. Pass an implementation to a calculator with WithMetrics (or
+// options.WithMetrics) to count comparisons by outcome and time them:
+//
+//	prom := metrics.NewPrometheus()
+//	ls, _ := word.New(word.WithMetrics(prom))
+//	...
+//	prom.WriteTo(w) // in the /metrics handler
+//
+// Calculators record nothing, and read no clock for it, unless given metrics.
+package metrics
+
+import (
+	adapter "github.com/baditaflorin/go_length_similarity/internal/adapters/metrics"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// Metrics receives counters, gauges and histogram observations
+type Metrics = ports.Metrics
+
+// Prometheus renders the measurements in the Prometheus text format
+type Prometheus = adapter.Prometheus
+
+// StatsD sends the measurements to a StatsD server over UDP
+type StatsD = adapter.StatsD
+
+// Names of the metrics the calculators record
+const (
+	Comparisons    = adapter.Comparisons
+	ComputeSeconds = adapter.ComputeSeconds
+	BytesProcessed = adapter.BytesProcessed
+	WarmupSeconds  = adapter.WarmupSeconds
+)
+
+// DefaultBuckets are the histogram bounds, in seconds, NewPrometheus uses
+var DefaultBuckets = adapter.DefaultBuckets
+
+// Nop returns metrics that discard every measurement
+func Nop() Metrics {
+	return adapter.Nop()
+}
+
+// NewPrometheus creates an empty registry; buckets override DefaultBuckets
+func NewPrometheus(buckets ...float64) *Prometheus {
+	return adapter.NewPrometheus(buckets...)
+}
+
+// NewStatsD creates a client sending to addr (host:port), prefixing every name with prefix
+func NewStatsD(addr, prefix string) (*StatsD, error) {
+	return adapter.NewStatsD(addr, prefix)
+}
+
+// Multi returns metrics that record to each of ms, skipping nil ones
+func Multi(ms ...Metrics) Metrics {
+	return adapter.Multi(ms...)
+}
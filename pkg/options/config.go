@@ -0,0 +1,65 @@
+package options
+
+import (
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// Duration is a time.Duration that config files write as a string such as
+// "250ms" or "2s"
+type Duration time.Duration
+
+// MarshalText writes the duration in time.Duration's string form
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses a duration such as "250ms"
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return domain.NewConfigError("duration", string(text), "must be a duration such as 250ms or 2s")
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// NormalizerNamed returns the built-in normalizer a config file names:
+// "default", "fast", "optimized", "ocr" or "logs". An empty name returns nil, which keeps
+// the calculator's default.
+func NormalizerNamed(name string) (ports.Normalizer, error) {
+	factory := normalizer.NewNormalizerFactory()
+	switch name {
+	case "":
+		return nil, nil
+	case "default":
+		return factory.CreateNormalizer(normalizer.DefaultNormalizerType), nil
+	case "fast":
+		return factory.CreateNormalizer(normalizer.FastNormalizerType), nil
+	case "optimized":
+		return factory.CreateNormalizer(normalizer.OptimizedNormalizerType), nil
+	case "ocr":
+		return factory.CreateNormalizer(normalizer.OCRNormalizerType), nil
+	case "logs":
+		return factory.CreateNormalizer(normalizer.LogNormalizerType), nil
+	default:
+		return nil, domain.NewConfigError("normalizer", name, "must be default, fast, optimized, ocr or logs")
+	}
+}
+
+// HasherNamed returns the hasher a config file names: "xxhash" or "sha256".
+// An empty name returns nil, which keeps the default.
+func HasherNamed(name string) (ports.Hasher, error) {
+	if name == "" {
+		return nil, nil
+	}
+	t, err := hasher.ParseType(name)
+	if err != nil {
+		return nil, domain.NewConfigError("hasher", name, "must be xxhash or sha256")
+	}
+	return hasher.New(t), nil
+}
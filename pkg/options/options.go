@@ -0,0 +1,128 @@
+// Package options holds the settings every calculator shares, so one set of
+// options configures the word, character and streaming calculators alike
+// instead of each package's differently named variants:
+//
+//	common := []options.Option{options.WithThreshold(0.8), options.WithLogger(lg)}
+//	ls, _ := word.New(word.WithOptions(common...))
+//	cs, _ := character.NewCharacterSimilarity(character.WithOptions(common...))
+//	ss, _ := streaming.NewStreamingSimilarity(streaming.WithOptions(common...))
+//	es, _ := streaming.NewAllocationEfficientStreamingSimilarity(streaming.WithEfficientOptions(common...))
+//
+// Package-specific options can be mixed in before or after; later options win.
+// The streaming calculators do not warm up, and the allocation-efficient one
+// always uses its own byte normalizer, so they ignore those settings. Only the
+// word calculator and the streaming word mode count words, so the others
+// ignore WithWordClassifier.
+package options
+
+import (
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/l"
+)
+
+// Option sets one shared setting
+type Option func(*Common)
+
+// Common is the result of applying options. Nil fields were not set and
+// leave the calculator's default in place.
+type Common struct {
+	Threshold    *float64
+	MaxDiffRatio *float64
+	Logger       l.Logger
+	Normalizer   ports.Normalizer
+	WarmUp       *bool
+	Clock        ports.Clock
+	Metrics      ports.Metrics
+	// WordClassifier decides which runes are part of a word
+	WordClassifier func(r rune) bool
+}
+
+// Apply collects opts, in order, into their settings
+func Apply(opts ...Option) Common {
+	var c Common
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithThreshold sets the score at or above which a comparison passes
+func WithThreshold(th float64) Option {
+	return func(c *Common) {
+		c.Threshold = &th
+	}
+}
+
+// WithMaxDiffRatio sets the length difference ratio at which the score reaches 0
+func WithMaxDiffRatio(ratio float64) Option {
+	return func(c *Common) {
+		c.MaxDiffRatio = &ratio
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(lg l.Logger) Option {
+	return func(c *Common) {
+		c.Logger = lg
+	}
+}
+
+// WithNormalizer sets the normalizer applied to both texts before counting
+func WithNormalizer(n ports.Normalizer) Option {
+	return func(c *Common) {
+		c.Normalizer = n
+	}
+}
+
+// WithFastNormalizer uses the fast normalizer
+func WithFastNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.FastNormalizerType))
+}
+
+// WithOptimizedNormalizer uses the optimized normalizer
+func WithOptimizedNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OptimizedNormalizerType))
+}
+
+// WithOCRNormalizer uses the OCR normalizer, which folds soft hyphens,
+// ligatures and common recognition confusions before normalizing
+func WithOCRNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OCRNormalizerType))
+}
+
+// WithLogNormalizer uses the log normalizer, which collapses timestamps, IDs,
+// addresses and numbers into placeholders before normalizing
+func WithLogNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.LogNormalizerType))
+}
+
+// WithWarmUp enables or disables warm-up on construction
+func WithWarmUp(enable bool) Option {
+	return func(c *Common) {
+		c.WarmUp = &enable
+	}
+}
+
+// WithClock sets the clock that times comparisons, for deterministic tests
+// (see the fakeclock package); nil uses the wall clock
+func WithClock(clock ports.Clock) Option {
+	return func(c *Common) {
+		c.Clock = clock
+	}
+}
+
+// WithMetrics records comparisons and warm-ups to m (see the metrics package)
+func WithMetrics(m ports.Metrics) Option {
+	return func(c *Common) {
+		c.Metrics = m
+	}
+}
+
+// WithWordClassifier counts a word as a maximal run of runes isWord accepts,
+// alike in memory and streamed (see word.WithWordClassifier)
+func WithWordClassifier(isWord func(r rune) bool) Option {
+	return func(c *Common) {
+		c.WordClassifier = isWord
+	}
+}
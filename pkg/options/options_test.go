@@ -0,0 +1,43 @@
+package options
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestApplyLeavesUnsetFieldsNil(t *testing.T) {
+	c := Apply(WithThreshold(0.8))
+	if c.Threshold == nil || *c.Threshold != 0.8 {
+		t.Errorf("Threshold = %v, want 0.8", c.Threshold)
+	}
+	if c.MaxDiffRatio != nil || c.Logger != nil || c.Normalizer != nil || c.WarmUp != nil {
+		t.Errorf("unset options were set: %+v", c)
+	}
+}
+
+func TestApplyLaterOptionsWin(t *testing.T) {
+	c := Apply(WithMaxDiffRatio(0.2), WithWarmUp(true), WithMaxDiffRatio(0.5), WithWarmUp(false))
+	if *c.MaxDiffRatio != 0.5 || *c.WarmUp {
+		t.Errorf("got ratio %v, warm-up %v; want 0.5, false", *c.MaxDiffRatio, *c.WarmUp)
+	}
+}
+
+func TestDurationText(t *testing.T) {
+	var v struct {
+		D Duration `json:"d"`
+	}
+	if err := json.Unmarshal([]byte(`{"d": "1m30s"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(v.D) != 90*time.Second {
+		t.Errorf("decoded %v, want 1m30s", time.Duration(v.D))
+	}
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `{"d":"1m30s"}` {
+		t.Errorf("encoded %s (%v)", out, err)
+	}
+	if err := json.Unmarshal([]byte(`{"d": "soon"}`), &v); err == nil {
+		t.Error("accepted an invalid duration")
+	}
+}
@@ -0,0 +1,213 @@
+package profile
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
+)
+
+// indexVersion is bumped whenever the saved index layout changes
+const indexVersion = 1
+
+// fingerprintPrefixLen is how many hex digits of a fingerprint pick its
+// bucket: 65536 buckets keep the table small even for millions of profiles
+const fingerprintPrefixLen = 4
+
+// Index answers Nearest queries over a large, fixed set of profiles without
+// scanning all of them. Profiles are bucketed into length bands, one per
+// distinct word count and ordered by character count within a band, so a query
+// walks outwards from its own length and stops as soon as no remaining band
+// can beat the matches it already has. Identical texts are found through
+// buckets keyed by fingerprint prefix.
+//
+// An Index is immutable and safe for concurrent queries. Rebuild it with
+// NewIndex, or load a saved one with LoadIndex, when the corpus changes.
+type Index struct {
+	// profiles is ordered by Words, then Runes, then ID
+	profiles []Profile
+	bands    []band
+	prefixes map[string][]int32
+}
+
+// band is the run of profiles sharing one word count
+type band struct {
+	words      int
+	start, end int
+}
+
+// NewIndex indexes a copy of profiles
+func NewIndex(profiles []Profile) *Index {
+	sorted := append([]Profile(nil), profiles...)
+	sort.Slice(sorted, func(i, j int) bool { return indexLess(sorted[i], sorted[j]) })
+	return newIndex(sorted)
+}
+
+// newIndex builds the bands and fingerprint buckets over sorted profiles
+func newIndex(sorted []Profile) *Index {
+	idx := &Index{profiles: sorted, prefixes: make(map[string][]int32)}
+	for i, p := range sorted {
+		if len(idx.bands) == 0 || idx.bands[len(idx.bands)-1].words != p.Words {
+			idx.bands = append(idx.bands, band{words: p.Words, start: i})
+		}
+		idx.bands[len(idx.bands)-1].end = i + 1
+
+		prefix := fingerprintPrefix(p.Fingerprint)
+		idx.prefixes[prefix] = append(idx.prefixes[prefix], int32(i))
+	}
+	return idx
+}
+
+// indexLess orders profiles by word count, character count and ID
+func indexLess(a, b Profile) bool {
+	if a.Words != b.Words {
+		return a.Words < b.Words
+	}
+	if a.Runes != b.Runes {
+		return a.Runes < b.Runes
+	}
+	return a.ID < b.ID
+}
+
+// fingerprintPrefix returns the bucket key of a fingerprint
+func fingerprintPrefix(fingerprint string) string {
+	return fingerprint[:min(len(fingerprint), fingerprintPrefixLen)]
+}
+
+// Len returns the number of indexed profiles
+func (idx *Index) Len() int {
+	return len(idx.profiles)
+}
+
+// Lookup returns the profiles whose text has the given fingerprint
+func (idx *Index) Lookup(fingerprint string) []Profile {
+	var found []Profile
+	for _, pos := range idx.prefixes[fingerprintPrefix(fingerprint)] {
+		if idx.profiles[pos].Fingerprint == fingerprint {
+			found = append(found, idx.profiles[pos])
+		}
+	}
+	return found
+}
+
+// Nearest returns the same matches as the package-level Nearest over the
+// indexed profiles, typically after looking at a small fraction of them
+func (idx *Index) Nearest(ctx context.Context, query Profile, k int, opts ...NearestOption) ([]Match, error) {
+	cfg, size, err := newNearestConfig(k, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	best := newShortlist(size, len(idx.profiles))
+	if query.Fingerprint != "" {
+		for _, p := range idx.Lookup(query.Fingerprint) {
+			best.offer(cheapMatch(query, p, cfg.maxDiffRatio))
+		}
+	}
+
+	// Walk the bands in order of increasing word count difference. The word
+	// score only falls from here, so once even a perfect character score
+	// cannot reach the shortlist, no later band can either.
+	hi := sort.Search(len(idx.bands), func(i int) bool { return idx.bands[i].words >= query.Words })
+	lo := hi - 1
+	for lo >= 0 || hi < len(idx.bands) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var b band
+		if hi == len(idx.bands) || lo >= 0 && query.Words-idx.bands[lo].words <= idx.bands[hi].words-query.Words {
+			b = idx.bands[lo]
+			lo--
+		} else {
+			b = idx.bands[hi]
+			hi++
+		}
+
+		wordScore := scoring.Score(query.Words, b.words, cfg.maxDiffRatio)
+		if !idx.admissible((wordScore+1)/2, cfg, best) {
+			break
+		}
+		if err := idx.scanBand(ctx, query, b, wordScore, cfg, best); err != nil {
+			return nil, err
+		}
+	}
+	return best.finish(ctx, cfg, k)
+}
+
+// scanBand offers the profiles of one band to the shortlist, walking outwards
+// from the query's character count until the character score is too low
+func (idx *Index) scanBand(ctx context.Context, query Profile, b band, wordScore float64, cfg nearestConfig, best *shortlist) error {
+	profiles := idx.profiles[b.start:b.end]
+	h := sort.Search(len(profiles), func(i int) bool { return profiles[i].Runes >= query.Runes })
+	l := h - 1
+
+	for scanned := 1; l >= 0 || h < len(profiles); scanned++ {
+		if scanned%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		var p Profile
+		if h == len(profiles) || l >= 0 && query.Runes-profiles[l].Runes <= profiles[h].Runes-query.Runes {
+			p = profiles[l]
+			l--
+		} else {
+			p = profiles[h]
+			h++
+		}
+
+		score := (wordScore + scoring.Score(query.Runes, p.Runes, cfg.maxDiffRatio)) / 2
+		if !idx.admissible(score, cfg, best) {
+			return nil
+		}
+		// Identical profiles were offered from the fingerprint buckets
+		if query.Fingerprint != "" && p.Fingerprint == query.Fingerprint {
+			continue
+		}
+		best.offer(cheapMatch(query, p, cfg.maxDiffRatio))
+	}
+	return nil
+}
+
+// admissible reports whether a candidate scoring score could still enter the
+// shortlist. Equal scores are admissible because the ID breaks the tie.
+func (idx *Index) admissible(score float64, cfg nearestConfig, best *shortlist) bool {
+	if score < cfg.minScore {
+		return false
+	}
+	floor, full := best.full()
+	return !full || score >= floor
+}
+
+// indexFile is the saved form of an Index
+type indexFile struct {
+	Version  int
+	Profiles []Profile
+}
+
+// Save writes the index so LoadIndex can restore it without re-sorting
+func (idx *Index) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(indexFile{Version: indexVersion, Profiles: idx.profiles})
+}
+
+// LoadIndex reads an index written by Save
+func LoadIndex(r io.Reader) (*Index, error) {
+	var file indexFile
+	if err := gob.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("profile: reading index: %w", err)
+	}
+	if file.Version != indexVersion {
+		return nil, fmt.Errorf("profile: index version %d is not supported (want %d)", file.Version, indexVersion)
+	}
+	// A file that is not in index order was not written by Save; sort it
+	// rather than answer queries wrongly
+	if !sort.SliceIsSorted(file.Profiles, func(i, j int) bool { return indexLess(file.Profiles[i], file.Profiles[j]) }) {
+		return NewIndex(file.Profiles), nil
+	}
+	return newIndex(file.Profiles), nil
+}
@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomProfiles returns n profiles with clustered lengths and a few duplicated texts
+func randomProfiles(rng *rand.Rand, n int) []Profile {
+	profiles := make([]Profile, n)
+	for i := range profiles {
+		words := rng.Intn(300)
+		profiles[i] = Profile{
+			ID:          fmt.Sprintf("doc-%06d", i),
+			Fingerprint: fmt.Sprintf("%016x", rng.Int63n(int64(n))),
+			Words:       words,
+			Runes:       words*5 + rng.Intn(40),
+		}
+	}
+	return profiles
+}
+
+func TestIndexMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	profiles := randomProfiles(rng, 5000)
+	idx := NewIndex(profiles)
+	ctx := context.Background()
+
+	rescorer := func(_ context.Context, p Profile) (float64, error) {
+		return float64(p.Runes%97) / 97, nil
+	}
+	optionSets := map[string][]NearestOption{
+		"default":   nil,
+		"min score": {WithMinScore(0.8)},
+		"ratio":     {WithMaxDiffRatio(0.05)},
+		"rescored":  {WithRescorer(rescorer), WithShortlist(25)},
+	}
+
+	for name, opts := range optionSets {
+		for q := range 50 {
+			query := profiles[rng.Intn(len(profiles))]
+			if q%2 == 1 {
+				// A query that is not in the corpus
+				query = Profile{Fingerprint: "none", Words: rng.Intn(400), Runes: rng.Intn(2000)}
+			}
+			k := 1 + rng.Intn(20)
+
+			want, err := Nearest(ctx, query, profiles, k, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := idx.Nearest(ctx, query, k, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: query %+v, k=%d:\nindex  %v\nlinear %v", name, query, k, ids(got), ids(want))
+			}
+		}
+	}
+}
+
+func TestIndexLookup(t *testing.T) {
+	idx := NewIndex([]Profile{
+		{ID: "a", Fingerprint: "abcd0001"},
+		{ID: "b", Fingerprint: "abcd0002"},
+		{ID: "c", Fingerprint: "abcd0001"},
+	})
+	if got := len(idx.Lookup("abcd0001")); got != 2 {
+		t.Errorf("Lookup found %d profiles, want 2 sharing the fingerprint", got)
+	}
+	if got := len(idx.Lookup("abcd9999")); got != 0 {
+		t.Errorf("Lookup found %d profiles for a prefix-only match, want 0", got)
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	idx := NewIndex(randomProfiles(rng, 1000))
+
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, idx) {
+		t.Fatal("loaded index differs from the saved one")
+	}
+
+	if _, err := LoadIndex(bytes.NewReader([]byte("not an index"))); err == nil {
+		t.Error("expected an error for a corrupt index")
+	}
+}
+
+func BenchmarkIndexNearest(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	profiles := make([]Profile, 1_000_000)
+	for i := range profiles {
+		words := int(rng.ExpFloat64() * 500)
+		profiles[i] = Profile{ID: fmt.Sprint(i), Fingerprint: fmt.Sprintf("%016x", rng.Uint64()), Words: words, Runes: words*5 + rng.Intn(50)}
+	}
+	idx := NewIndex(profiles)
+	queries := profiles[:1000]
+
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := idx.Nearest(context.Background(), queries[i%len(queries)], 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package profile
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
+)
+
+// DefaultMaxDiffRatio matches the default of the word and character calculators
+const DefaultMaxDiffRatio = 0.3
+
+// DefaultShortlistFactor sets the default shortlist to this many times k
+const DefaultShortlistFactor = 4
+
+// cancelCheckInterval is how many candidates are ranked between context checks
+const cancelCheckInterval = 1024
+
+// Match is a candidate ranked by Nearest
+type Match struct {
+	Profile Profile `json:"profile"`
+	// Score ranks the match: the rescorer's score when the candidate was
+	// rescored, otherwise the mean of WordScore and RuneScore
+	Score     float64 `json:"score"`
+	WordScore float64 `json:"word_score"`
+	RuneScore float64 `json:"rune_score"`
+	// Identical is set when the fingerprints match; such candidates always
+	// rank first and are never rescored
+	Identical bool `json:"identical,omitempty"`
+	Rescored  bool `json:"rescored,omitempty"`
+}
+
+// Rescorer is the expensive comparison Nearest runs on the shortlist, such as
+// a content metric over the candidate's text. It returns a score in [0, 1].
+type Rescorer func(ctx context.Context, candidate Profile) (float64, error)
+
+// NearestOption configures Nearest
+type NearestOption func(*nearestConfig)
+
+type nearestConfig struct {
+	maxDiffRatio float64
+	minScore     float64
+	shortlist    int
+	rescorer     Rescorer
+}
+
+// WithMaxDiffRatio sets the length difference, relative to the query, at which
+// the cheap scores reach 0. Use the ratio your calculators use.
+func WithMaxDiffRatio(ratio float64) NearestOption {
+	return func(cfg *nearestConfig) {
+		cfg.maxDiffRatio = ratio
+	}
+}
+
+// WithMinScore drops candidates whose cheap score is below min before they
+// can reach the shortlist
+func WithMinScore(min float64) NearestOption {
+	return func(cfg *nearestConfig) {
+		cfg.minScore = min
+	}
+}
+
+// WithShortlist sets how many of the best cheap candidates are kept for
+// rescoring. It defaults to DefaultShortlistFactor * k and is never below k.
+func WithShortlist(n int) NearestOption {
+	return func(cfg *nearestConfig) {
+		cfg.shortlist = n
+	}
+}
+
+// WithRescorer re-ranks the shortlist with an expensive comparison
+func WithRescorer(r Rescorer) NearestOption {
+	return func(cfg *nearestConfig) {
+		cfg.rescorer = r
+	}
+}
+
+// Nearest returns up to k candidates most similar to query, best first. Every
+// candidate is ranked by the cheap metrics alone: identical fingerprints, then
+// word and character count similarity. Only the best of them, the shortlist,
+// are passed to the rescorer, so an expensive comparison runs a bounded number
+// of times however many candidates there are. Ties are broken by ID.
+//
+// Nearest scans every candidate; use an Index to query large corpora.
+func Nearest(ctx context.Context, query Profile, candidates []Profile, k int, opts ...NearestOption) ([]Match, error) {
+	cfg, shortlist, err := newNearestConfig(k, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	best := newShortlist(shortlist, len(candidates))
+	for i, candidate := range candidates {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		m := cheapMatch(query, candidate, cfg.maxDiffRatio)
+		if m.Score >= cfg.minScore {
+			best.offer(m)
+		}
+	}
+	return best.finish(ctx, cfg, k)
+}
+
+// newNearestConfig applies options and returns the shortlist size
+func newNearestConfig(k int, opts []NearestOption) (nearestConfig, int, error) {
+	cfg := nearestConfig{maxDiffRatio: DefaultMaxDiffRatio}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if k < 1 {
+		return cfg, 0, errors.New("profile: k must be at least 1")
+	}
+	if !(cfg.maxDiffRatio > 0) {
+		return cfg, 0, errors.New("profile: max diff ratio must be positive")
+	}
+	shortlist := k
+	if cfg.rescorer != nil {
+		shortlist = cfg.shortlist
+		if shortlist <= 0 {
+			shortlist = DefaultShortlistFactor * k
+		}
+		shortlist = max(shortlist, k)
+	}
+	return cfg, shortlist, nil
+}
+
+// shortlist keeps the best size matches in a min-heap, so the worst kept
+// match is the one a better newcomer replaces
+type shortlist struct {
+	size int
+	heap matchHeap
+}
+
+func newShortlist(size, candidates int) *shortlist {
+	return &shortlist{size: size, heap: make(matchHeap, 0, min(size, candidates))}
+}
+
+// offer keeps m if the shortlist has room or m beats its worst match
+func (s *shortlist) offer(m Match) {
+	if len(s.heap) < s.size {
+		heap.Push(&s.heap, m)
+	} else if better(m, s.heap[0]) {
+		s.heap[0] = m
+		heap.Fix(&s.heap, 0)
+	}
+}
+
+// full reports whether a candidate scoring below floor can no longer get in:
+// the shortlist is full and its worst match scores floor
+func (s *shortlist) full() (floor float64, ok bool) {
+	if len(s.heap) < s.size {
+		return 0, false
+	}
+	if s.heap[0].Identical {
+		// Nothing that is not identical can displace an identical match
+		return 2, true
+	}
+	return s.heap[0].Score, true
+}
+
+// finish rescores the shortlist when configured and returns the best k matches
+func (s *shortlist) finish(ctx context.Context, cfg nearestConfig, k int) ([]Match, error) {
+	matches := []Match(s.heap)
+	if cfg.rescorer != nil {
+		for i := range matches {
+			if matches[i].Identical {
+				continue
+			}
+			score, err := cfg.rescorer(ctx, matches[i].Profile)
+			if err != nil {
+				return nil, err
+			}
+			matches[i].Score = scoring.Clamp01(score)
+			matches[i].Rescored = true
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return better(matches[i], matches[j]) })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// cheapMatch scores a candidate from its counts and fingerprint alone
+func cheapMatch(query, candidate Profile, maxDiffRatio float64) Match {
+	if query.Fingerprint != "" && candidate.Fingerprint == query.Fingerprint {
+		return Match{Profile: candidate, Score: 1, WordScore: 1, RuneScore: 1, Identical: true}
+	}
+	wordScore := scoring.Score(query.Words, candidate.Words, maxDiffRatio)
+	runeScore := scoring.Score(query.Runes, candidate.Runes, maxDiffRatio)
+	return Match{
+		Profile:   candidate,
+		Score:     (wordScore + runeScore) / 2,
+		WordScore: wordScore,
+		RuneScore: runeScore,
+	}
+}
+
+// better orders matches: identical first, then by score, then by ID
+func better(a, b Match) bool {
+	if a.Identical != b.Identical {
+		return a.Identical
+	}
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Profile.ID < b.Profile.ID
+}
+
+// matchHeap is a min-heap of matches with the worst match on top
+type matchHeap []Match
+
+func (h matchHeap) Len() int            { return len(h) }
+func (h matchHeap) Less(i, j int) bool  { return better(h[j], h[i]) }
+func (h matchHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x interface{}) { *h = append(*h, x.(Match)) }
+func (h *matchHeap) Pop() interface{} {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
@@ -0,0 +1,124 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func counts(id string, words, runes int) Profile {
+	return Profile{ID: id, Fingerprint: "fp-" + id, Words: words, Runes: runes}
+}
+
+func ids(matches []Match) []string {
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.Profile.ID
+	}
+	return out
+}
+
+func TestNearestRanksByCheapScore(t *testing.T) {
+	query := counts("q", 100, 500)
+	candidates := []Profile{
+		counts("far", 10, 50),
+		counts("close", 98, 495),
+		counts("closer", 100, 498),
+		counts("mid", 80, 400),
+		{ID: "same", Fingerprint: query.Fingerprint, Words: 1, Runes: 1},
+	}
+
+	matches, err := Nearest(context.Background(), query, candidates, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(ids(matches)), "[same closer close]"; got != want {
+		t.Fatalf("Nearest = %s, want %s", got, want)
+	}
+	if !matches[0].Identical || matches[0].Score != 1 {
+		t.Errorf("identical fingerprint should rank first with score 1, got %+v", matches[0])
+	}
+}
+
+func TestNearestMinScoreFilters(t *testing.T) {
+	query := counts("q", 100, 500)
+	candidates := []Profile{counts("close", 99, 499), counts("far", 10, 50)}
+
+	matches, err := Nearest(context.Background(), query, candidates, 5, WithMinScore(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(ids(matches)); got != "[close]" {
+		t.Fatalf("Nearest = %s, want only close", got)
+	}
+}
+
+func TestNearestRescoresOnlyShortlist(t *testing.T) {
+	query := counts("q", 100, 500)
+	var candidates []Profile
+	for i := range 50 {
+		candidates = append(candidates, counts(fmt.Sprintf("c%02d", i), 100-i, 500-5*i))
+	}
+
+	var rescored []string
+	rescorer := func(_ context.Context, p Profile) (float64, error) {
+		rescored = append(rescored, p.ID)
+		// Invert the cheap order to show the rescorer decides the final rank
+		if p.ID == "c03" {
+			return 0.99, nil
+		}
+		return 0.5, nil
+	}
+
+	matches, err := Nearest(context.Background(), query, candidates, 2, WithRescorer(rescorer), WithShortlist(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rescored) != 4 {
+		t.Fatalf("rescored %d candidates, want the shortlist of 4", len(rescored))
+	}
+	if got := fmt.Sprint(ids(matches)); got != "[c03 c00]" {
+		t.Fatalf("Nearest = %s, want [c03 c00]", got)
+	}
+	if !matches[0].Rescored {
+		t.Error("shortlisted matches should be marked rescored")
+	}
+}
+
+func TestNearestErrors(t *testing.T) {
+	ctx := context.Background()
+	candidates := []Profile{counts("a", 1, 1)}
+
+	if _, err := Nearest(ctx, counts("q", 1, 1), candidates, 0); err == nil {
+		t.Error("expected an error for k = 0")
+	}
+
+	failing := errors.New("rescore failed")
+	_, err := Nearest(ctx, counts("q", 1, 1), candidates, 1, WithRescorer(func(context.Context, Profile) (float64, error) {
+		return 0, failing
+	}))
+	if !errors.Is(err, failing) {
+		t.Errorf("err = %v, want the rescorer's error", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Nearest(cancelled, counts("q", 1, 1), candidates, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func BenchmarkNearest(b *testing.B) {
+	query := counts("q", 1000, 5000)
+	candidates := make([]Profile, 100000)
+	for i := range candidates {
+		candidates[i] = counts(fmt.Sprint(i), i%2000, (i*7)%10000)
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := Nearest(context.Background(), query, candidates, 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package profile precomputes what the length metrics need to know about an
+// original text, so one source can be compared against many candidates, or
+// many stored sources searched, without reading the originals again.
+package profile
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/pkg/content"
+	"github.com/cespare/xxhash/v2"
+)
+
+// Profile summarizes one text
+type Profile struct {
+	// ID names the text, such as its path within a corpus
+	ID string `json:"id"`
+	// Fingerprint is the hex xxHash of the raw text, as the server's default
+	// hasher computes it
+	Fingerprint string `json:"fingerprint"`
+	Bytes       int64  `json:"bytes"`
+	// Words is the normalized word count the word length metric compares
+	Words int `json:"words"`
+	// Runes is the normalized character count the character metric compares
+	Runes int `json:"runes"`
+	// Content is the text's key in the content store it was built with, if any
+	Content content.Key `json:"content,omitempty"`
+}
+
+// Option configures Build
+type Option func(*config)
+
+type config struct {
+	normalizer ports.Normalizer
+	store      content.ContentStore
+}
+
+// WithNormalizer counts with a custom normalizer. Use the same normalizer as
+// the calculators the profile will be compared with.
+func WithNormalizer(n ports.Normalizer) Option {
+	return func(cfg *config) {
+		cfg.normalizer = n
+	}
+}
+
+// WithFastNormalizer counts with the fast normalizer
+func WithFastNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.FastNormalizerType))
+}
+
+// WithOptimizedNormalizer counts with the optimized normalizer
+func WithOptimizedNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OptimizedNormalizerType))
+}
+
+// WithOCRNormalizer counts with the OCR normalizer
+func WithOCRNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OCRNormalizerType))
+}
+
+// WithLogNormalizer counts with the log normalizer
+func WithLogNormalizer() Option {
+	return WithNormalizer(normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.LogNormalizerType))
+}
+
+// WithContentStore also stores the text in s while it is read, setting the
+// profile's Content key, so the original can be fetched again later
+func WithContentStore(s content.ContentStore) Option {
+	return func(cfg *config) {
+		cfg.store = s
+	}
+}
+
+// Build reads r once and returns its profile. The counts match what
+// ComputeFromReaders in the word and character packages counts for the same
+// text and normalizer.
+func Build(ctx context.Context, id string, r io.Reader, opts ...Option) (Profile, error) {
+	cfg := config{normalizer: normalizer.NewDefaultNormalizer()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	digest := xxhash.New()
+	var key content.Key
+	count := func(r io.Reader) (stream.NormalizedCounts, error) {
+		return stream.CountNormalized(ctx, io.TeeReader(r, digest), cfg.normalizer, 0)
+	}
+	var counts stream.NormalizedCounts
+	var err error
+	if cfg.store != nil {
+		key, counts, err = storeWhileCounting(ctx, cfg.store, r, count)
+	} else {
+		counts, err = count(r)
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+
+	return Profile{
+		ID:          id,
+		Fingerprint: hex.EncodeToString(binary.BigEndian.AppendUint64(nil, digest.Sum64())),
+		Bytes:       counts.BytesProcessed,
+		Words:       counts.Words,
+		Runes:       counts.Runes,
+		Content:     key,
+	}, nil
+}
+
+// storeWhileCounting feeds r to count and to s.Put at the same time, so the
+// text is read once
+func storeWhileCounting(ctx context.Context, s content.ContentStore, r io.Reader, count func(io.Reader) (stream.NormalizedCounts, error)) (content.Key, stream.NormalizedCounts, error) {
+	pr, pw := io.Pipe()
+	type stored struct {
+		key content.Key
+		err error
+	}
+	done := make(chan stored, 1)
+	go func() {
+		key, err := s.Put(ctx, pr)
+		// Unblock the counter if Put gave up early
+		pr.CloseWithError(err)
+		done <- stored{key, err}
+	}()
+
+	counts, err := count(io.TeeReader(r, pw))
+	pw.CloseWithError(err)
+	put := <-done
+	if put.err != nil {
+		return "", stream.NormalizedCounts{}, put.err
+	}
+	return put.key, counts, err
+}
+
+// BuildString returns the profile of an in-memory text
+func BuildString(ctx context.Context, id, text string, opts ...Option) (Profile, error) {
+	return Build(ctx, id, strings.NewReader(text), opts...)
+}
+
+// Write stores profiles as JSON Lines, one profile per line
+func Write(w io.Writer, profiles []Profile) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, p := range profiles {
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Read loads profiles written by Write
+func Read(r io.Reader) ([]Profile, error) {
+	var profiles []Profile
+	dec := json.NewDecoder(r)
+	for {
+		var p Profile
+		err := dec.Decode(&p)
+		if err == io.EOF {
+			return profiles, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("profile %d: %w", len(profiles)+1, err)
+		}
+		profiles = append(profiles, p)
+	}
+}
@@ -0,0 +1,122 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/content"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+)
+
+const sample = "The quick brown fox,  jumps over\tthe lazy dog!\nÉcole, café and naïve text."
+
+func discardLogger(t *testing.T) l.Logger {
+	t.Helper()
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestBuildMatchesCalculators(t *testing.T) {
+	ctx := context.Background()
+	p, err := Build(ctx, "sample", strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := word.New(word.WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ws.ComputeFromReaders(ctx, strings.NewReader(sample), strings.NewReader(sample)).OriginalLength; p.Words != want {
+		t.Errorf("Words = %d, word metric counts %d", p.Words, want)
+	}
+
+	cs, err := character.NewCharacterSimilarity(character.WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cs.ComputeFromReaders(ctx, strings.NewReader(sample), strings.NewReader(sample)).OriginalLength; p.Runes != want {
+		t.Errorf("Runes = %d, character metric counts %d", p.Runes, want)
+	}
+
+	if p.Bytes != int64(len(sample)) {
+		t.Errorf("Bytes = %d, want %d", p.Bytes, len(sample))
+	}
+	if want := hex.EncodeToString(hasher.Default().SumString(sample)); p.Fingerprint != want {
+		t.Errorf("Fingerprint = %s, want the default hasher's %s", p.Fingerprint, want)
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	var profiles []Profile
+	for _, text := range []string{"one", "two words", sample} {
+		p, err := Build(ctx, text[:3], strings.NewReader(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, profiles); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(profiles) {
+		t.Fatalf("wrote %d lines, want one per profile", lines)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, profiles) {
+		t.Errorf("Read = %+v, want %+v", got, profiles)
+	}
+}
+
+func TestReadRejectsCorruptLine(t *testing.T) {
+	if _, err := Read(strings.NewReader("{\"id\":\"a\"}\nnot json\n")); err == nil {
+		t.Fatal("expected an error for a corrupt profile line")
+	}
+}
+
+func TestBuildWithContentStore(t *testing.T) {
+	store, err := content.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("a stored original text\n", 5000)
+
+	p, err := BuildString(context.Background(), "doc", text, WithContentStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := BuildString(context.Background(), "doc", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Words != plain.Words || p.Runes != plain.Runes || p.Fingerprint != plain.Fingerprint || plain.Content != "" {
+		t.Errorf("storing changed the profile: %+v, without a store %+v", p, plain)
+	}
+
+	stored, err := content.Get(context.Background(), store, p.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stored) != text {
+		t.Errorf("stored %d bytes, want the %d read", len(stored), len(text))
+	}
+}
@@ -0,0 +1,247 @@
+// Package resultdiff compares two result sets produced from the same inputs,
+// such as the JSONL files a sink wrote before and after a configuration or
+// engine change. It reports how each score moved, which pairs flipped between
+// pass and fail, and summary statistics, so a change can be judged before it
+// rolls out:
+//
+//	base, _ := sink.ReadJSONLFile("before.jsonl")
+//	candidate, _ := sink.ReadJSONLFile("after.jsonl")
+//	report, err := resultdiff.Compare(base, candidate, resultdiff.DefaultEpsilon)
+//
+// Records are matched by ID and metric.
+package resultdiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+// DefaultEpsilon is the score movement below which a pair counts as unchanged
+const DefaultEpsilon = domain.DefaultEpsilon
+
+// Key identifies a record in both result sets
+type Key struct {
+	ID     string `json:"id"`
+	Metric string `json:"metric"`
+}
+
+// Change is a pair whose score moved by more than epsilon or whose verdict flipped
+type Change struct {
+	Key
+	BaseScore       float64 `json:"base_score"`
+	CandidateScore  float64 `json:"candidate_score"`
+	Delta           float64 `json:"delta"`
+	BasePassed      bool    `json:"base_passed"`
+	CandidatePassed bool    `json:"candidate_passed"`
+}
+
+// Flipped reports whether the pair changed between pass and fail
+func (c Change) Flipped() bool {
+	return c.BasePassed != c.CandidatePassed
+}
+
+// Stats summarizes the pairs found in both result sets
+type Stats struct {
+	Compared int `json:"compared"`
+	// Changed counts pairs whose score moved by more than epsilon
+	Changed      int     `json:"changed"`
+	NewlyPassing int     `json:"newly_passing"`
+	NewlyFailing int     `json:"newly_failing"`
+	MeanDelta    float64 `json:"mean_delta"`
+	MeanAbsDelta float64 `json:"mean_abs_delta"`
+	MaxAbsDelta  float64 `json:"max_abs_delta"`
+}
+
+// Flips is the number of pairs that changed between pass and fail
+func (s Stats) Flips() int {
+	return s.NewlyPassing + s.NewlyFailing
+}
+
+// add accumulates one compared pair; the means are sums until finish
+func (s *Stats) add(delta, epsilon float64, basePassed, candidatePassed bool) {
+	s.Compared++
+	if math.Abs(delta) > epsilon {
+		s.Changed++
+	}
+	switch {
+	case !basePassed && candidatePassed:
+		s.NewlyPassing++
+	case basePassed && !candidatePassed:
+		s.NewlyFailing++
+	}
+	s.MeanDelta += delta
+	s.MeanAbsDelta += math.Abs(delta)
+	s.MaxAbsDelta = math.Max(s.MaxAbsDelta, math.Abs(delta))
+}
+
+// finish turns the accumulated sums into means
+func (s *Stats) finish() {
+	if s.Compared > 0 {
+		s.MeanDelta /= float64(s.Compared)
+		s.MeanAbsDelta /= float64(s.Compared)
+	}
+}
+
+// Report is the outcome of comparing two result sets
+type Report struct {
+	Epsilon float64 `json:"epsilon"`
+	// Overall covers every compared pair; ByMetric splits it by metric
+	Overall  Stats            `json:"overall"`
+	ByMetric map[string]Stats `json:"by_metric"`
+	// Changes lists the moved or flipped pairs, flips first, then by
+	// decreasing score movement
+	Changes []Change `json:"changes"`
+	// OnlyInBase and OnlyInCandidate list records without a counterpart
+	OnlyInBase      []Key `json:"only_in_base"`
+	OnlyInCandidate []Key `json:"only_in_candidate"`
+}
+
+// Compare matches base and candidate records by ID and metric and reports how
+// the candidate differs. Scores closer than epsilon count as unchanged. A key
+// that appears twice in one set is an error, since it cannot be matched.
+func Compare(base, candidate []sink.Record, epsilon float64) (*Report, error) {
+	if epsilon < 0 || math.IsNaN(epsilon) {
+		return nil, domain.NewConfigError("epsilon", epsilon, "must not be negative")
+	}
+	baseByKey, err := index("base", base)
+	if err != nil {
+		return nil, err
+	}
+	candidateByKey, err := index("candidate", candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Epsilon:         epsilon,
+		ByMetric:        make(map[string]Stats),
+		Changes:         []Change{},
+		OnlyInBase:      []Key{},
+		OnlyInCandidate: []Key{},
+	}
+	for _, b := range base {
+		key := Key{ID: b.ID, Metric: b.Metric}
+		c, ok := candidateByKey[key]
+		if !ok {
+			report.OnlyInBase = append(report.OnlyInBase, key)
+			continue
+		}
+
+		delta := c.Score - b.Score
+		report.Overall.add(delta, epsilon, b.Passed, c.Passed)
+		metric := report.ByMetric[key.Metric]
+		metric.add(delta, epsilon, b.Passed, c.Passed)
+		report.ByMetric[key.Metric] = metric
+
+		change := Change{
+			Key:             key,
+			BaseScore:       b.Score,
+			CandidateScore:  c.Score,
+			Delta:           delta,
+			BasePassed:      b.Passed,
+			CandidatePassed: c.Passed,
+		}
+		if math.Abs(delta) > epsilon || change.Flipped() {
+			report.Changes = append(report.Changes, change)
+		}
+	}
+	for _, c := range candidate {
+		key := Key{ID: c.ID, Metric: c.Metric}
+		if _, ok := baseByKey[key]; !ok {
+			report.OnlyInCandidate = append(report.OnlyInCandidate, key)
+		}
+	}
+
+	report.Overall.finish()
+	for metric, stats := range report.ByMetric {
+		stats.finish()
+		report.ByMetric[metric] = stats
+	}
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		a, b := report.Changes[i], report.Changes[j]
+		if a.Flipped() != b.Flipped() {
+			return a.Flipped()
+		}
+		return math.Abs(a.Delta) > math.Abs(b.Delta)
+	})
+	return report, nil
+}
+
+// index maps records by key, rejecting duplicates
+func index(set string, records []sink.Record) (map[Key]sink.Record, error) {
+	byKey := make(map[Key]sink.Record, len(records))
+	for _, rec := range records {
+		key := Key{ID: rec.ID, Metric: rec.Metric}
+		if _, ok := byKey[key]; ok {
+			return nil, fmt.Errorf("%s has more than one record for id %q and metric %q", set, key.ID, key.Metric)
+		}
+		byKey[key] = rec
+	}
+	return byKey, nil
+}
+
+// WriteText writes a human-readable summary with at most top changes; a
+// negative top lists them all
+func (r *Report) WriteText(w io.Writer, top int) error {
+	ew := &errWriter{w: w}
+	ew.printf("compared %d pairs: %d changed, %d flipped (%d newly passing, %d newly failing)\n",
+		r.Overall.Compared, r.Overall.Changed, r.Overall.Flips(), r.Overall.NewlyPassing, r.Overall.NewlyFailing)
+	ew.printf("score delta: mean %+.4f, mean absolute %.4f, max absolute %.4f\n",
+		r.Overall.MeanDelta, r.Overall.MeanAbsDelta, r.Overall.MaxAbsDelta)
+
+	if len(r.ByMetric) > 1 {
+		metrics := make([]string, 0, len(r.ByMetric))
+		for metric := range r.ByMetric {
+			metrics = append(metrics, metric)
+		}
+		sort.Strings(metrics)
+		for _, metric := range metrics {
+			s := r.ByMetric[metric]
+			ew.printf("  %s: %d compared, %d changed, %d flipped, mean delta %+.4f\n",
+				metric, s.Compared, s.Changed, s.Flips(), s.MeanDelta)
+		}
+	}
+	if len(r.OnlyInBase) > 0 || len(r.OnlyInCandidate) > 0 {
+		ew.printf("unmatched: %d only in base, %d only in candidate\n", len(r.OnlyInBase), len(r.OnlyInCandidate))
+	}
+
+	changes := r.Changes
+	if top >= 0 && len(changes) > top {
+		changes = changes[:top]
+	}
+	for _, c := range changes {
+		verdict := ""
+		if c.Flipped() {
+			verdict = fmt.Sprintf("  %s -> %s", passLabel(c.BasePassed), passLabel(c.CandidatePassed))
+		}
+		ew.printf("%s %s: %.4f -> %.4f (%+.4f)%s\n", c.Metric, c.ID, c.BaseScore, c.CandidateScore, c.Delta, verdict)
+	}
+	if len(changes) < len(r.Changes) {
+		ew.printf("... %d more changes\n", len(r.Changes)-len(changes))
+	}
+	return ew.err
+}
+
+func passLabel(passed bool) string {
+	if passed {
+		return "pass"
+	}
+	return "fail"
+}
+
+// errWriter keeps the first write error so WriteText can check once
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
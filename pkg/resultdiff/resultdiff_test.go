@@ -0,0 +1,108 @@
+package resultdiff
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+func TestCompare(t *testing.T) {
+	base := []sink.Record{
+		{ID: "a", Metric: "length_similarity", Score: 0.80, Passed: true},
+		{ID: "b", Metric: "length_similarity", Score: 0.72, Passed: true},
+		{ID: "c", Metric: "length_similarity", Score: 0.50, Passed: false},
+		{ID: "a", Metric: "character_similarity", Score: 0.90, Passed: true},
+		{ID: "gone", Metric: "length_similarity", Score: 1, Passed: true},
+	}
+	candidate := []sink.Record{
+		{ID: "a", Metric: "length_similarity", Score: 0.80, Passed: true},
+		{ID: "b", Metric: "length_similarity", Score: 0.62, Passed: false},
+		{ID: "c", Metric: "length_similarity", Score: 0.75, Passed: true},
+		{ID: "a", Metric: "character_similarity", Score: 0.95, Passed: true},
+		{ID: "new", Metric: "length_similarity", Score: 1, Passed: true},
+	}
+
+	report, err := Compare(base, candidate, DefaultEpsilon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overall := report.Overall
+	if overall.Compared != 4 || overall.Changed != 3 || overall.NewlyPassing != 1 || overall.NewlyFailing != 1 {
+		t.Errorf("overall = %+v, want 4 compared, 3 changed, 1 newly passing and 1 newly failing", overall)
+	}
+	if want := (-0.10 + 0.25 + 0.05) / 4; math.Abs(overall.MeanDelta-want) > 1e-9 {
+		t.Errorf("mean delta = %v, want %v", overall.MeanDelta, want)
+	}
+	if math.Abs(overall.MaxAbsDelta-0.25) > 1e-9 {
+		t.Errorf("max absolute delta = %v, want 0.25", overall.MaxAbsDelta)
+	}
+	if s := report.ByMetric["character_similarity"]; s.Compared != 1 || s.Changed != 1 || s.Flips() != 0 {
+		t.Errorf("character stats = %+v", s)
+	}
+
+	var order []string
+	for _, c := range report.Changes {
+		order = append(order, c.ID+"/"+c.Metric)
+	}
+	// Flips come first, by decreasing movement
+	want := []string{"c/length_similarity", "b/length_similarity", "a/character_similarity"}
+	if strings.Join(order, " ") != strings.Join(want, " ") {
+		t.Errorf("changes = %v, want %v", order, want)
+	}
+
+	if len(report.OnlyInBase) != 1 || report.OnlyInBase[0].ID != "gone" {
+		t.Errorf("only in base = %v", report.OnlyInBase)
+	}
+	if len(report.OnlyInCandidate) != 1 || report.OnlyInCandidate[0].ID != "new" {
+		t.Errorf("only in candidate = %v", report.OnlyInCandidate)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"compared 4 pairs: 3 changed, 2 flipped",
+		"length_similarity c: 0.5000 -> 0.7500 (+0.2500)  fail -> pass",
+		"... 2 more changes",
+		"1 only in base, 1 only in candidate",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("summary lacks %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestCompareEpsilon(t *testing.T) {
+	base := []sink.Record{{ID: "a", Score: 0.7}}
+	candidate := []sink.Record{{ID: "a", Score: 0.7 + 1e-12}}
+
+	report, err := Compare(base, candidate, DefaultEpsilon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Overall.Changed != 0 || len(report.Changes) != 0 {
+		t.Errorf("rounding noise counted as a change: %+v", report)
+	}
+
+	var configErr *domain.ConfigError
+	if _, err := Compare(base, candidate, -1); !errors.As(err, &configErr) {
+		t.Errorf("negative epsilon: error %v, want a *ConfigError", err)
+	}
+}
+
+func TestCompareRejectsDuplicateKeys(t *testing.T) {
+	dup := []sink.Record{{ID: "a", Metric: "m"}, {ID: "a", Metric: "m"}}
+	if _, err := Compare(dup, nil, DefaultEpsilon); err == nil {
+		t.Error("duplicate base keys were accepted")
+	}
+	if _, err := Compare(nil, dup, DefaultEpsilon); err == nil {
+		t.Error("duplicate candidate keys were accepted")
+	}
+}
@@ -0,0 +1,249 @@
+// Package resultquery answers ad-hoc questions about the results an SQLSink
+// stored, such as the pass rate per metric or how the mean score moved day by
+// day, without exporting the data first:
+//
+//	db, _ := sql.Open("sqlite", "results.db")
+//	table, err := resultquery.Query(ctx, db, resultquery.DefaultLimit,
+//		"SELECT metric, AVG(passed) AS pass_rate FROM similarity_results GROUP BY metric")
+//	_ = table.WriteText(os.Stdout)
+//
+// Like SQLSink, the package is driver-agnostic; the caller registers the
+// database/sql driver. Only single read statements are accepted, but the check
+// is lexical and a WITH clause can lead into a write, so open the database
+// read-only when the queries are not trusted.
+package resultquery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/sink"
+)
+
+// DefaultLimit is how many rows a query returns when the caller has no preference
+const DefaultLimit = 10000
+
+// ErrNotReadOnly is returned for statements other than SELECT, WITH and EXPLAIN
+var ErrNotReadOnly = errors.New("resultquery: only SELECT, WITH and EXPLAIN statements are allowed")
+
+// presets are common questions, with %s standing for the results table
+var presets = map[string]string{
+	// pass-rate summarizes every metric
+	"pass-rate": `SELECT metric, COUNT(*) AS pairs, AVG(passed) AS pass_rate, AVG(score) AS mean_score
+FROM %s GROUP BY metric ORDER BY metric`,
+	// daily shows how scores and the pass rate drift from day to day
+	"daily": `SELECT substr(created_at, 1, 10) AS day, metric, COUNT(*) AS pairs, AVG(score) AS mean_score, AVG(passed) AS pass_rate
+FROM %s GROUP BY substr(created_at, 1, 10), metric ORDER BY day, metric`,
+}
+
+// Presets returns the names of the built-in queries, sorted
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Preset returns the named built-in query over table (sink.DefaultTable when empty)
+func Preset(name, table string) (string, bool) {
+	query, ok := presets[name]
+	if !ok {
+		return "", false
+	}
+	if table == "" {
+		table = sink.DefaultTable
+	}
+	return fmt.Sprintf(query, table), true
+}
+
+// Table is the result of a query
+type Table struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	// Truncated is set when the query had more rows than the limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Query runs a read-only query and collects at most limit rows; a limit of
+// zero or less collects them all
+func Query(ctx context.Context, db *sql.DB, limit int, query string, args ...interface{}) (*Table, error) {
+	if !readOnly(query) {
+		return nil, ErrNotReadOnly
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{Columns: columns, Rows: [][]interface{}{}}
+	for rows.Next() {
+		if limit > 0 && len(table.Rows) == limit {
+			table.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			// Drivers return TEXT columns as bytes, which would encode as base64
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	return table, rows.Err()
+}
+
+// readOnly reports whether the statement starts with a read keyword, after
+// any comments, and is a single statement. It only catches mistakes early:
+// WITH can still lead into a write, so untrusted SQL needs a read-only database.
+func readOnly(query string) bool {
+	q := strings.TrimSpace(query)
+	for {
+		switch {
+		case strings.HasPrefix(q, "--"):
+			end := strings.IndexByte(q, '\n')
+			if end < 0 {
+				return false
+			}
+			q = strings.TrimSpace(q[end:])
+		case strings.HasPrefix(q, "/*"):
+			end := strings.Index(q, "*/")
+			if end < 0 {
+				return false
+			}
+			q = strings.TrimSpace(q[end+2:])
+		default:
+			if i := statementEnd(q); i >= 0 && strings.TrimSpace(q[i+1:]) != "" {
+				return false
+			}
+			word := q
+			if end := strings.IndexFunc(q, func(r rune) bool { return !unicode.IsLetter(r) }); end >= 0 {
+				word = q[:end]
+			}
+			switch strings.ToUpper(word) {
+			case "SELECT", "WITH", "EXPLAIN":
+				return true
+			}
+			return false
+		}
+	}
+}
+
+// statementEnd returns the index of the first semicolon outside string
+// literals, quoted identifiers and comments, or -1 if there is none
+func statementEnd(q string) int {
+	for i := 0; i < len(q); i++ {
+		var closer string
+		switch {
+		case q[i] == ';':
+			return i
+		case q[i] == '\'' || q[i] == '"' || q[i] == '`':
+			closer = q[i : i+1]
+		case q[i] == '[':
+			closer = "]"
+		case strings.HasPrefix(q[i:], "--"):
+			closer = "\n"
+		case strings.HasPrefix(q[i:], "/*"):
+			closer = "*/"
+			i++
+		default:
+			continue
+		}
+		// A doubled quote inside a literal escapes it, which this skips as
+		// a closing quote followed by a new literal
+		end := strings.Index(q[i+1:], closer)
+		if end < 0 {
+			return -1
+		}
+		i += end + len(closer)
+	}
+	return -1
+}
+
+// WriteText prints the table with aligned columns
+func (t *Table) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Columns, "\t"))
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = formatValue(v, "NULL", 6)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if t.Truncated {
+		_, err := fmt.Fprintf(w, "(truncated to %d rows)\n", len(t.Rows))
+		return err
+	}
+	return nil
+}
+
+// WriteCSV prints the table as CSV with a header row
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = formatValue(v, "", -1)
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON prints the table as indented JSON
+func (t *Table) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// formatValue renders a cell, with floats to prec significant digits (-1 for
+// as many as needed)
+func formatValue(v interface{}, null string, prec int) string {
+	switch v := v.(type) {
+	case nil:
+		return null
+	case float64:
+		return strconv.FormatFloat(v, 'g', prec, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
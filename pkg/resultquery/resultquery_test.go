@@ -0,0 +1,167 @@
+package resultquery
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestQueryCollectsRows(t *testing.T) {
+	db := openFake(t)
+	table, err := Query(context.Background(), db, 0, "SELECT metric, pass_rate, note FROM results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(table.Columns, ",") != "metric,pass_rate,note" || len(table.Rows) != 3 {
+		t.Fatalf("unexpected table %+v", table)
+	}
+	if table.Rows[0][0] != "character" {
+		t.Errorf("expected text columns as strings, got %T", table.Rows[0][0])
+	}
+
+	table, err = Query(context.Background(), db, 2, "select * from results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 2 || !table.Truncated {
+		t.Errorf("expected 2 rows and truncation, got %d rows, truncated %v", len(table.Rows), table.Truncated)
+	}
+}
+
+func TestQueryRejectsWrites(t *testing.T) {
+	db := openFake(t)
+	for _, q := range []string{
+		"DELETE FROM results",
+		"  drop table results",
+		"SELECT 1; DELETE FROM results",
+		"/* SELECT */ UPDATE results SET score = 1",
+		"-- comment only",
+		"SELECT ';'; DELETE FROM results",
+		"SELECT 1 /* ; */; DROP TABLE results",
+	} {
+		if _, err := Query(context.Background(), db, 0, q); !errors.Is(err, ErrNotReadOnly) {
+			t.Errorf("%q: expected ErrNotReadOnly, got %v", q, err)
+		}
+	}
+	for _, q := range []string{
+		"-- pass rate\nSELECT * FROM results;",
+		"WITH r AS (SELECT * FROM results) SELECT * FROM r",
+		"explain select * from results",
+		"SELECT\t*\tFROM results",
+		"SELECT * FROM results WHERE metric <> ';x'",
+		"SELECT \"a;b\", 'it''s; fine' FROM results -- trailing; comment",
+	} {
+		if _, err := Query(context.Background(), db, 0, q); err != nil {
+			t.Errorf("%q: %v", q, err)
+		}
+	}
+}
+
+func TestPreset(t *testing.T) {
+	if len(Presets()) == 0 {
+		t.Fatal("expected built-in presets")
+	}
+	for _, name := range Presets() {
+		q, ok := Preset(name, "")
+		if !ok || !strings.Contains(q, "FROM similarity_results") || !readOnly(q) {
+			t.Errorf("preset %s: %q", name, q)
+		}
+	}
+	if q, _ := Preset("daily", "shard_7"); !strings.Contains(q, "FROM shard_7") {
+		t.Errorf("expected the table to be substituted, got %q", q)
+	}
+	if _, ok := Preset("nope", ""); ok {
+		t.Error("expected unknown preset to be reported")
+	}
+}
+
+func TestTableWriters(t *testing.T) {
+	table := &Table{
+		Columns: []string{"metric", "pass_rate", "note"},
+		Rows:    [][]interface{}{{"length", 2.0 / 3, nil}},
+	}
+
+	var text bytes.Buffer
+	if err := table.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "length  0.666667   NULL") {
+		t.Errorf("unexpected text:\n%s", text.String())
+	}
+
+	var csv bytes.Buffer
+	if err := table.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	if want := "metric,pass_rate,note\nlength,0.6666666666666666,\n"; csv.String() != want {
+		t.Errorf("CSV = %q, want %q", csv.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := table.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Table
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded.Rows) != 1 {
+		t.Errorf("JSON did not round-trip: %v %+v", err, decoded)
+	}
+}
+
+// fakeDriver answers every query with the same three rows
+type fakeDriver struct{}
+
+func init() {
+	sql.Register("resultquery-fake", fakeDriver{})
+}
+
+func openFake(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("resultquery-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("not supported") }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: [][]driver.Value{
+		{[]byte("character"), 0.5, nil},
+		{[]byte("length"), 0.75, []byte("x")},
+		{[]byte("stream"), 1.0, nil},
+	}}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"metric", "pass_rate", "note"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,33 @@
+package similarity
+
+import "github.com/baditaflorin/go_length_similarity/internal/parallel"
+
+// SetMaxParallelism caps the total number of worker goroutines that the
+// parallel line and word processors and batch APIs may run at once, across the
+// whole process. Embedding applications use it to bound the CPU the library
+// takes. n <= 0 removes the cap (the default).
+//
+// Every operation still gets at least one worker, so a saturated budget slows
+// callers down rather than blocking them.
+func SetMaxParallelism(n int) {
+	parallel.SetMax(n)
+}
+
+// MaxParallelism returns the cap set by SetMaxParallelism, or 0 when there is none
+func MaxParallelism() int {
+	return parallel.Max()
+}
+
+// SetYieldInterval makes the streaming line, word and chunk loops call
+// runtime.Gosched after every n bytes they read, so one multi-gigabyte
+// comparison does not starve other goroutines of an embedding application
+// running with few Ps. Around 1 MiB costs little throughput. n <= 0 disables
+// yielding (the default).
+func SetYieldInterval(n int) {
+	parallel.SetYieldInterval(n)
+}
+
+// YieldInterval returns the interval set by SetYieldInterval, or 0 when yielding is disabled
+func YieldInterval() int {
+	return parallel.YieldInterval()
+}
@@ -0,0 +1,121 @@
+// Package similarity is a thin façade over the word, character and streaming
+// packages for the common case: default thresholds, a fast normalizer and no
+// logging output. Reach for the underlying packages when you need to tune them.
+package similarity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+)
+
+// Result is the outcome of an in-memory comparison
+type Result = domain.Result
+
+// StreamResult is the outcome of a streaming comparison
+type StreamResult = streaming.StreamResult
+
+// DefaultEpsilon is a tolerance for comparing scores that absorbs floating
+// point noise, for use with ApproxEqual and Result.ApproximatelyEquals
+const DefaultEpsilon = domain.DefaultEpsilon
+
+// ApproxEqual reports whether two scores or ratios differ by at most epsilon
+func ApproxEqual(a, b, epsilon float64) bool {
+	return domain.ApproxEqual(a, b, epsilon)
+}
+
+// Calculators are built lazily on first use and shared; they are safe for concurrent use
+var (
+	defaultWords = sync.OnceValues(func() (*word.LengthSimilarity, error) {
+		lg, err := discardLogger()
+		if err != nil {
+			return nil, err
+		}
+		return word.New(word.WithLogger(lg), word.WithFastNormalizer())
+	})
+
+	defaultChars = sync.OnceValues(func() (*character.CharacterSimilarity, error) {
+		lg, err := discardLogger()
+		if err != nil {
+			return nil, err
+		}
+		return character.NewCharacterSimilarity(character.WithLogger(lg), character.WithFastNormalizer())
+	})
+
+	defaultStreaming = sync.OnceValues(func() (*streaming.StreamingSimilarity, error) {
+		lg, err := discardLogger()
+		if err != nil {
+			return nil, err
+		}
+		return streaming.NewStreamingSimilarity(
+			streaming.WithStreamingLogger(lg),
+			streaming.WithOptimizedNormalizer(),
+			streaming.WithStreamingMode(streaming.LineByLine),
+		)
+	})
+)
+
+// Words compares the word counts of a and b using the default word settings
+func Words(ctx context.Context, a, b string) (Result, error) {
+	ls, err := defaultWords()
+	if err != nil {
+		return Result{}, err
+	}
+	return ls.Compute(ctx, a, b), nil
+}
+
+// Chars compares the character counts of a and b using the default character settings
+func Chars(ctx context.Context, a, b string) (Result, error) {
+	cs, err := defaultChars()
+	if err != nil {
+		return Result{}, err
+	}
+	return cs.Compute(ctx, a, b), nil
+}
+
+// Files streams the files at p1 and p2 and compares their character counts
+// without loading either file into memory
+func Files(ctx context.Context, p1, p2 string) (StreamResult, error) {
+	ss, err := defaultStreaming()
+	if err != nil {
+		return StreamResult{}, err
+	}
+
+	f1, err := os.Open(p1)
+	if err != nil {
+		return StreamResult{}, fmt.Errorf("open original: %w", err)
+	}
+	defer f1.Close()
+
+	f2, err := os.Open(p2)
+	if err != nil {
+		return StreamResult{}, fmt.Errorf("open augmented: %w", err)
+	}
+	defer f2.Close()
+
+	return ss.ComputeFromReaders(ctx, f1, f2), nil
+}
+
+// FilesFS is Files for a virtual filesystem such as an embed.FS, a zip archive
+// or an object-store wrapper; p1 and p2 are fs.FS paths
+func FilesFS(ctx context.Context, fsys fs.FS, p1, p2 string) (StreamResult, error) {
+	ss, err := defaultStreaming()
+	if err != nil {
+		return StreamResult{}, err
+	}
+	return ss.ComputeFromFS(ctx, fsys, p1, p2)
+}
+
+// discardLogger creates a logger that drops all output
+func discardLogger() (l.Logger, error) {
+	return l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+}
@@ -0,0 +1,198 @@
+package similarity
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	corecharacter "github.com/baditaflorin/go_length_similarity/internal/core/character"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+)
+
+func TestWordsAndChars(t *testing.T) {
+	ctx := context.Background()
+
+	wr, err := Words(ctx, "the quick brown fox", "the quick brown fox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wr.Score != 1 || !wr.Passed {
+		t.Errorf("Words identical = %+v, want score 1 and passed", wr)
+	}
+
+	cr, err := Chars(ctx, "abcdef", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Passed {
+		t.Errorf("Chars halved = %+v, want not passed", cr)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	p1 := filepath.Join(dir, "a.txt")
+	p2 := filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(p1, []byte("line one\nline two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p2, []byte("line one\nline two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Files(context.Background(), p1, p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Score != 1 || !res.Passed {
+		t.Errorf("Files identical = %+v, want score 1 and passed", res)
+	}
+
+	if _, err := Files(context.Background(), filepath.Join(dir, "missing"), p2); err == nil {
+		t.Error("Files with missing path: want error")
+	}
+}
+
+func TestFilesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"corpus/a.txt": {Data: []byte("line one\nline two\n")},
+		"corpus/b.txt": {Data: []byte("line one\nline two\n")},
+	}
+
+	res, err := FilesFS(context.Background(), fsys, "corpus/a.txt", "corpus/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Score != 1 || !res.Passed {
+		t.Errorf("FilesFS identical = %+v, want score 1 and passed", res)
+	}
+
+	if _, err := FilesFS(context.Background(), fsys, "corpus/a.txt", "corpus/missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("FilesFS with missing path: err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+// TestCharacterEntryPointsAgree checks that every character similarity entry
+// point scores a shared corpus exactly like the core calculator they wrap
+func TestCharacterEntryPointsAgree(t *testing.T) {
+	ctx := context.Background()
+	corpus := []struct{ original, augmented string }{
+		{"abcdef", "abcdef"},
+		{"abcdef", "abcde"},
+		{"The quick brown fox jumps over the lazy dog.", "The quick brown fox jumped over a lazy dog!"},
+		{"  Leading and   trailing  spaces  ", "Leading and trailing spaces"},
+		{"Héllo wörld, ünïcode ✓", "Hello world, unicode"},
+		{"line one\r\nline two\r\n", "line one\nline two\n"},
+		{"<p>markup</p> stays <b>text</b>", "markup stays text"},
+		{strings.Repeat("long paragraph text ", 500), strings.Repeat("long paragraph text ", 420)},
+		{"short", strings.Repeat("much longer augmented text ", 10)},
+	}
+
+	lg, err := discardLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast := normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.FastNormalizerType)
+	core, err := corecharacter.NewCalculator(corecharacter.DefaultConfig(), logger.FromExisting(lg), fast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := character.NewCharacterSimilarity(character.WithLogger(lg), character.WithFastNormalizer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := character.NewCharacterSimilarity(character.WithLogger(lg), character.WithFastNormalizer(), character.WithOriginalCache(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := cs.NewSession()
+
+	entryPoints := map[string]func(original, augmented string) domain.Result{
+		"character.Compute": func(o, a string) domain.Result { return cs.Compute(ctx, o, a) },
+		"character.ComputeFromReaders": func(o, a string) domain.Result {
+			return cs.ComputeFromReaders(ctx, strings.NewReader(o), strings.NewReader(a))
+		},
+		"character.Session": func(o, a string) domain.Result { return session.Compute(ctx, o, a) },
+		"character with original cache": func(o, a string) domain.Result {
+			cached.Compute(ctx, o, a)
+			return cached.Compute(ctx, o, a)
+		},
+		"similarity.Chars": func(o, a string) domain.Result {
+			r, err := Chars(ctx, o, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return r
+		},
+	}
+
+	for _, pair := range corpus {
+		want := core.Compute(ctx, pair.original, pair.augmented)
+		for name, compute := range entryPoints {
+			got := compute(pair.original, pair.augmented)
+			if got.Score != want.Score || got.Passed != want.Passed ||
+				got.OriginalLength != want.OriginalLength || got.AugmentedLength != want.AugmentedLength {
+				t.Errorf("%s(%.20q, %.20q) = score %v, passed %v, lengths %d/%d; core gives %v, %v, %d/%d",
+					name, pair.original, pair.augmented, got.Score, got.Passed, got.OriginalLength, got.AugmentedLength,
+					want.Score, want.Passed, want.OriginalLength, want.AugmentedLength)
+			}
+		}
+	}
+}
+
+func TestResultsIdentifyEngineAndMode(t *testing.T) {
+	ctx := context.Background()
+	a, b := "one two three four", "one two three"
+
+	words, err := Words(ctx, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chars, err := Chars(ctx, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg, err := discardLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := character.NewCharacterSimilarity(character.WithLogger(lg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromReaders := cs.ComputeFromReaders(ctx, strings.NewReader(a), strings.NewReader(b))
+	fsys := fstest.MapFS{"a.txt": {Data: []byte(a)}, "b.txt": {Data: []byte(b)}}
+	streamed, err := FilesFS(ctx, fsys, "a.txt", "b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := streaming.NewAllocationEfficientStreamingSimilarity(streaming.WithEfficientMode(streaming.WordByWord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	efficient := es.ComputeFromStrings(ctx, a, b)
+
+	for _, tc := range []struct {
+		name, engine, mode, wantEngine, wantMode string
+	}{
+		{"Words", words.Engine, words.Mode, domain.EngineWord, domain.ModeText},
+		{"Chars", chars.Engine, chars.Mode, domain.EngineCharacter, domain.ModeText},
+		{"character.ComputeFromReaders", fromReaders.Engine, fromReaders.Mode, domain.EngineCharacter, domain.ModeStream},
+		{"FilesFS", streamed.Engine, streamed.Mode, domain.EngineStreaming, domain.ModeLine},
+		// The efficient engine counts lines whatever mode it is given
+		{"efficient", efficient.Engine, efficient.Mode, domain.EngineEfficient, domain.ModeLine},
+	} {
+		if tc.engine != tc.wantEngine || tc.mode != tc.wantMode {
+			t.Errorf("%s: engine %q, mode %q; want %q, %q", tc.name, tc.engine, tc.mode, tc.wantEngine, tc.wantMode)
+		}
+	}
+}
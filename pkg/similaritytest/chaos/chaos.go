@@ -0,0 +1,188 @@
+// Package chaos provides test-only io.Reader and io.Writer wrappers that inject
+// delays, short reads and mid-stream errors, so callers can verify their
+// pipelines handle the engine's error paths.
+//
+// The wrappers are deterministic: the same options always produce the same
+// sequence of reads and failures, which keeps tests reproducible.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrInjected is the default error returned once a configured failure point is reached
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Option defines a functional option for configuring a chaos Reader or Writer
+type Option func(*config)
+
+type config struct {
+	Delay     time.Duration
+	Ctx       context.Context
+	MaxChunk  int
+	FailAfter int64
+	Err       error
+}
+
+// WithDelay sleeps for d before every read from or write to the wrapped stream
+func WithDelay(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.Delay = d
+	}
+}
+
+// WithContext stops waiting out delays once ctx is done, returning its error
+func WithContext(ctx context.Context) Option {
+	return func(cfg *config) {
+		cfg.Ctx = ctx
+	}
+}
+
+// WithShortReads caps every Read (or underlying Write) at n bytes, exercising
+// callers that assume a full buffer per call
+func WithShortReads(n int) Option {
+	return func(cfg *config) {
+		cfg.MaxChunk = n
+	}
+}
+
+// WithFailAfter returns err after n bytes have been transferred.
+// A nil err uses ErrInjected.
+func WithFailAfter(n int64, err error) Option {
+	return func(cfg *config) {
+		cfg.FailAfter = n
+		cfg.Err = err
+	}
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{FailAfter: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Err == nil {
+		cfg.Err = ErrInjected
+	}
+	return cfg
+}
+
+// wait applies the configured delay
+func (c *config) wait() error {
+	if c.Delay <= 0 {
+		return nil
+	}
+	if c.Ctx == nil {
+		time.Sleep(c.Delay)
+		return nil
+	}
+
+	timer := time.NewTimer(c.Delay)
+	defer timer.Stop()
+	select {
+	case <-c.Ctx.Done():
+		return c.Ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// limit returns how many of want bytes may be transferred after done bytes,
+// and whether the failure point has been reached
+func (c *config) limit(done int64, want int) (int, bool) {
+	if c.MaxChunk > 0 && want > c.MaxChunk {
+		want = c.MaxChunk
+	}
+	if c.FailAfter >= 0 {
+		remaining := c.FailAfter - done
+		if remaining <= 0 {
+			return 0, true
+		}
+		if int64(want) > remaining {
+			want = int(remaining)
+		}
+	}
+	return want, false
+}
+
+// Reader wraps an io.Reader and injects faults according to its options
+type Reader struct {
+	r    io.Reader
+	cfg  config
+	read int64
+}
+
+// NewReader wraps r with the given fault injection options
+func NewReader(r io.Reader, opts ...Option) *Reader {
+	return &Reader{r: r, cfg: newConfig(opts)}
+}
+
+// Read implements io.Reader
+func (cr *Reader) Read(p []byte) (int, error) {
+	if err := cr.cfg.wait(); err != nil {
+		return 0, err
+	}
+
+	n, fail := cr.cfg.limit(cr.read, len(p))
+	if fail {
+		return 0, cr.cfg.Err
+	}
+	if n == 0 {
+		return cr.r.Read(p[:0])
+	}
+
+	n, err := cr.r.Read(p[:n])
+	cr.read += int64(n)
+	return n, err
+}
+
+// BytesRead returns the number of bytes passed through so far
+func (cr *Reader) BytesRead() int64 {
+	return cr.read
+}
+
+// Writer wraps an io.Writer and injects faults according to its options.
+// WithShortReads splits each Write into several smaller writes to the wrapped
+// writer; a failure part way through reports the bytes written before it.
+type Writer struct {
+	w       io.Writer
+	cfg     config
+	written int64
+}
+
+// NewWriter wraps w with the given fault injection options
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	return &Writer{w: w, cfg: newConfig(opts)}
+}
+
+// Write implements io.Writer
+func (cw *Writer) Write(p []byte) (int, error) {
+	total := 0
+	for {
+		if err := cw.cfg.wait(); err != nil {
+			return total, err
+		}
+
+		n, fail := cw.cfg.limit(cw.written, len(p)-total)
+		if fail {
+			return total, cw.cfg.Err
+		}
+		if n == 0 {
+			return total, nil
+		}
+
+		n, err := cw.w.Write(p[total : total+n])
+		total += n
+		cw.written += int64(n)
+		if err != nil || total == len(p) {
+			return total, err
+		}
+	}
+}
+
+// BytesWritten returns the number of bytes passed through so far
+func (cw *Writer) BytesWritten() int64 {
+	return cw.written
+}
@@ -0,0 +1,87 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/baditaflorin/l"
+)
+
+func TestReaderShortReadsAndFailure(t *testing.T) {
+	r := NewReader(strings.NewReader("abcdefghij"), WithShortReads(3), WithFailAfter(7, nil))
+
+	buf := make([]byte, 16)
+	var got []int
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			if !errors.Is(err, ErrInjected) {
+				t.Fatalf("err = %v, want ErrInjected", err)
+			}
+			break
+		}
+		got = append(got, n)
+	}
+
+	if want := []int{3, 3, 1}; !slices.Equal(got, want) {
+		t.Errorf("read sizes = %v, want %v", got, want)
+	}
+	if r.BytesRead() != 7 {
+		t.Errorf("BytesRead = %d, want 7", r.BytesRead())
+	}
+}
+
+func TestWriterSplitsAndFails(t *testing.T) {
+	var dst bytes.Buffer
+	w := NewWriter(&dst, WithShortReads(2), WithFailAfter(5, io.ErrClosedPipe))
+
+	n, err := w.Write([]byte("abcdefg"))
+	if n != 5 || !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("Write = %d, %v; want 5, ErrClosedPipe", n, err)
+	}
+	if dst.String() != "abcde" {
+		t.Errorf("written = %q", dst.String())
+	}
+}
+
+func TestReaderDelayHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewReader(strings.NewReader("abc"), WithDelay(time.Hour), WithContext(ctx))
+	if _, err := r.Read(make([]byte, 4)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamingSurfacesInjectedErrors(t *testing.T) {
+	lg, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := streaming.NewStreamingSimilarity(streaming.WithStreamingLogger(lg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 200)
+	ctx := context.Background()
+
+	want := ss.ComputeFromStrings(ctx, text, text)
+	short := ss.ComputeFromReaders(ctx, NewReader(strings.NewReader(text), WithShortReads(7)), strings.NewReader(text))
+	if short.Score != want.Score || short.OriginalLength != want.OriginalLength {
+		t.Errorf("short reads changed the result: got %+v, want %+v", short, want)
+	}
+
+	failed := ss.ComputeFromReaders(ctx, NewReader(strings.NewReader(text), WithFailAfter(100, nil)), strings.NewReader(text))
+	if failed.Passed || failed.Details["error"] == nil {
+		t.Errorf("mid-stream failure not reported: %+v", failed)
+	}
+}
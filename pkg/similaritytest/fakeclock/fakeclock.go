@@ -0,0 +1,59 @@
+// Package fakeclock provides a manually driven clock for deterministic tests.
+// Pass it to a calculator's clock option and processing times and latency
+// tracking follow the clock instead of the wall:
+//
+//	clk := fakeclock.New(time.Unix(0, 0))
+//	clk.SetStep(time.Millisecond) // every reading advances the clock by 1ms
+//	ss, _ := streaming.NewStreamingSimilarity(streaming.WithStreamingClock(clk))
+//	result := ss.ComputeFromReaders(ctx, original, augmented) // ProcessingTime is a whole number of steps
+//
+// Context deadlines are still measured on the wall clock.
+package fakeclock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock that only moves when told to. It is safe for concurrent use.
+type Clock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// New creates a clock reading start
+func New(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the current time, then advances the clock by the step
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// SetStep makes every call to Now advance the clock by d afterwards; 0, the
+// default, keeps it still between calls to Advance and Set
+func (c *Clock) SetStep(d time.Duration) {
+	c.mu.Lock()
+	c.step = d
+	c.mu.Unlock()
+}
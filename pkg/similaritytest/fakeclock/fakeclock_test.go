@@ -0,0 +1,30 @@
+package fakeclock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(start)
+	if !c.Now().Equal(start) || !c.Now().Equal(start) {
+		t.Fatal("clock moved without a step")
+	}
+
+	c.Advance(time.Minute)
+	if got := c.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("after Advance: %v", got)
+	}
+
+	c.SetStep(time.Second)
+	first, second := c.Now(), c.Now()
+	if second.Sub(first) != time.Second {
+		t.Errorf("step: readings %v apart, want 1s", second.Sub(first))
+	}
+
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("after Set: %v", got)
+	}
+}
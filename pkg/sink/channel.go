@@ -0,0 +1,71 @@
+package sink
+
+import (
+	"context"
+	"sync"
+)
+
+// ChannelSink delivers records to a channel for in-process consumers
+type ChannelSink struct {
+	mu     sync.RWMutex
+	ch     chan Record
+	closed bool
+
+	// done is closed first on Close, releasing writers blocked on a consumer
+	// that stopped reading
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewChannelSink creates a channel sink with the given buffer size
+func NewChannelSink(buffer int) *ChannelSink {
+	return &ChannelSink{
+		ch:   make(chan Record, buffer),
+		done: make(chan struct{}),
+	}
+}
+
+// Records returns the channel the records are delivered on; it is closed by Close
+func (s *ChannelSink) Records() <-chan Record {
+	return s.ch
+}
+
+// Write sends the record, blocking until it is received, ctx is cancelled or
+// the sink is closed
+func (s *ChannelSink) Write(ctx context.Context, rec Record) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case s.ch <- rec:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+		return ErrSinkClosed
+	}
+}
+
+// Flush is a no-op for channel sinks
+func (s *ChannelSink) Flush() error {
+	return nil
+}
+
+// Close closes the records channel. Writes still blocked on the consumer
+// fail with ErrSinkClosed.
+func (s *ChannelSink) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+	return nil
+}
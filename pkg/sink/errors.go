@@ -0,0 +1,6 @@
+package sink
+
+import "errors"
+
+// ErrSinkClosed is returned when writing to a sink that has been closed
+var ErrSinkClosed = errors.New("sink is closed")
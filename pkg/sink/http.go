@@ -0,0 +1,92 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSink POSTs each record as a JSON document to an endpoint
+type HTTPSink struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+// HTTPOption defines a functional option for configuring an HTTPSink
+type HTTPOption func(*HTTPSink)
+
+// WithHTTPClient sets a custom HTTP client
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(s *HTTPSink) {
+		s.client = client
+	}
+}
+
+// WithHeader adds a header sent with every request
+func WithHeader(key, value string) HTTPOption {
+	return func(s *HTTPSink) {
+		s.header.Add(key, value)
+	}
+}
+
+// NewHTTPSink creates a sink posting records to url
+func NewHTTPSink(url string, opts ...HTTPOption) *HTTPSink {
+	s := &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		header: make(http.Header),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Write posts the record and fails on any non-2xx response
+func (s *HTTPSink) Write(ctx context.Context, rec Record) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range s.header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush is a no-op; records are delivered synchronously
+func (s *HTTPSink) Flush() error {
+	return nil
+}
+
+// Close releases idle connections
+func (s *HTTPSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
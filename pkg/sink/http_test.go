@@ -0,0 +1,49 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPSinkPostsRecords(t *testing.T) {
+	var got []Record
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("request %s with headers %v, want a JSON POST with the configured header", r.Method, r.Header)
+		}
+		var rec Record
+		if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+			t.Error(err)
+		}
+		got = append(got, rec)
+		if rec.ID == "reject" {
+			rw.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewHTTPSink(srv.URL, WithHeader("Authorization", "Bearer t"), WithHTTPClient(srv.Client()))
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.Write(ctx, Record{ID: "a", Metric: "length_similarity", Score: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "a" || got[0].Score != 0.5 {
+		t.Errorf("server received %+v, want record a", got)
+	}
+
+	if err := s.Write(ctx, Record{ID: "reject"}); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Write() = %v, want the 502 status", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Write(cancelled, Record{ID: "b"}); err == nil {
+		t.Error("Write with a cancelled context succeeded")
+	}
+}
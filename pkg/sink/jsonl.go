@@ -0,0 +1,177 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONLSink writes one JSON object per line
+type JSONLSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	closer io.Closer
+	writer *bufio.Writer
+	enc    *json.Encoder
+	closed bool
+
+	// flushEvery controls how many records are buffered before a flush
+	flushEvery int
+	pending    int
+}
+
+// JSONLOption defines a functional option for configuring a JSONLSink
+type JSONLOption func(*JSONLSink)
+
+// WithFlushEvery flushes the buffered writer after every n records (n <= 1 flushes on each write)
+func WithFlushEvery(n int) JSONLOption {
+	return func(s *JSONLSink) {
+		s.flushEvery = n
+	}
+}
+
+// NewJSONLFileSink creates (or appends to) a JSONL file at path
+func NewJSONLFileSink(path string, opts ...JSONLOption) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newJSONLSink(file, file, opts...)
+	s.file = file
+	return s, nil
+}
+
+// NewJSONLSink creates a sink writing to w; w is closed on Close if it implements io.Closer
+func NewJSONLSink(w io.Writer, opts ...JSONLOption) *JSONLSink {
+	closer, _ := w.(io.Closer)
+	return newJSONLSink(w, closer, opts...)
+}
+
+func newJSONLSink(w io.Writer, closer io.Closer, opts ...JSONLOption) *JSONLSink {
+	writer := bufio.NewWriter(w)
+	s := &JSONLSink{
+		closer:     closer,
+		writer:     writer,
+		enc:        json.NewEncoder(writer),
+		flushEvery: 1,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Write encodes the record as a single line
+func (s *JSONLSink) Write(ctx context.Context, rec Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	if err := s.enc.Encode(rec); err != nil {
+		return err
+	}
+
+	s.pending++
+	if s.pending >= s.flushEvery {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+// Flush writes buffered records and syncs the file when writing to one
+func (s *JSONLSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	return s.flushAndSyncLocked()
+}
+
+// Close flushes and closes the underlying writer. The writer is closed even
+// when the flush fails; both errors are returned. Later writes fail with
+// ErrSinkClosed and later Close calls do nothing.
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.flushAndSyncLocked()
+	if s.closer != nil {
+		err = errors.Join(err, s.closer.Close())
+	}
+	return err
+}
+
+func (s *JSONLSink) flushAndSyncLocked() error {
+	if err := s.flushLocked(); err != nil {
+		return err
+	}
+	if s.file != nil {
+		return s.file.Sync()
+	}
+	return nil
+}
+
+func (s *JSONLSink) flushLocked() error {
+	s.pending = 0
+	return s.writer.Flush()
+}
+
+// ReadJSONL reads the records a JSONLSink wrote to r. Blank lines are
+// skipped; a line that is not a record fails with its line number.
+func ReadJSONL(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var records []Record
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ReadJSONLFile reads the records of the JSONL file at path
+func ReadJSONLFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := ReadJSONL(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, nil
+}
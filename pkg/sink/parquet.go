@@ -0,0 +1,532 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// DefaultRowGroupSize is how many records a ParquetSink buffers per row group
+const DefaultRowGroupSize = 64 * 1024
+
+// parquetMagic opens and closes every Parquet file
+const parquetMagic = "PAR1"
+
+// ParquetCompression is the codec applied to Parquet pages
+type ParquetCompression int
+
+const (
+	// ParquetUncompressed stores pages as they are
+	ParquetUncompressed ParquetCompression = 0
+	// ParquetGzip compresses every page with gzip
+	ParquetGzip ParquetCompression = 2
+)
+
+// ParquetSink writes records to a Parquet file, one column per Record field,
+// for analysis in Spark, DuckDB and the like. Records are buffered and
+// written a row group at a time. Parquet keeps its index in a footer, so the
+// file can only be read once Close has written it; Flush writes the buffered
+// records as a row group but does not make the file readable on its own.
+//
+// Every column is required. Details are stored as a JSON string, and the
+// timestamp as microseconds since the epoch in UTC.
+type ParquetSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	closer io.Closer
+	writer *bufio.Writer
+	offset int64
+	closed bool
+
+	rowGroupSize int
+	compression  ParquetCompression
+
+	columns   []*parquetColumn
+	rows      int
+	rowGroups []parquetRowGroup
+	totalRows int64
+}
+
+// ParquetOption defines a functional option for configuring a ParquetSink
+type ParquetOption func(*ParquetSink)
+
+// WithRowGroupSize sets how many records are buffered per row group (default DefaultRowGroupSize)
+func WithRowGroupSize(n int) ParquetOption {
+	return func(s *ParquetSink) {
+		if n > 0 {
+			s.rowGroupSize = n
+		}
+	}
+}
+
+// WithParquetCompression sets the page codec (default ParquetUncompressed)
+func WithParquetCompression(c ParquetCompression) ParquetOption {
+	return func(s *ParquetSink) {
+		s.compression = c
+	}
+}
+
+// NewParquetFileSink creates, or truncates, a Parquet file at path
+func NewParquetFileSink(path string, opts ...ParquetOption) (*ParquetSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newParquetSink(file, file, opts...)
+	s.file = file
+	return s, nil
+}
+
+// NewParquetSink creates a sink writing to w; w is closed on Close if it implements io.Closer
+func NewParquetSink(w io.Writer, opts ...ParquetOption) *ParquetSink {
+	closer, _ := w.(io.Closer)
+	return newParquetSink(w, closer, opts...)
+}
+
+func newParquetSink(w io.Writer, closer io.Closer, opts ...ParquetOption) *ParquetSink {
+	s := &ParquetSink{
+		closer:       closer,
+		writer:       bufio.NewWriter(w),
+		rowGroupSize: DefaultRowGroupSize,
+		columns:      newParquetColumns(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// The buffer is empty, so this cannot fail
+	s.writer.WriteString(parquetMagic)
+	s.offset = int64(len(parquetMagic))
+	return s
+}
+
+// Write buffers the record, writing a row group once enough are buffered
+func (s *ParquetSink) Write(ctx context.Context, rec Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	details, err := json.Marshal(rec.Details)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+	for _, c := range s.columns {
+		c.add(rec, details)
+	}
+	s.rows++
+	if s.rows >= s.rowGroupSize {
+		return s.writeRowGroupLocked()
+	}
+	return nil
+}
+
+// Flush writes the buffered records as a row group and syncs the file when
+// writing to one. The file is only readable after Close.
+func (s *ParquetSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	if err := s.writeRowGroupLocked(); err != nil {
+		return err
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if s.file != nil {
+		return s.file.Sync()
+	}
+	return nil
+}
+
+// Close writes the remaining records and the footer, then closes the
+// underlying writer
+func (s *ParquetSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.writeRowGroupLocked()
+	if err == nil {
+		err = s.writeFooterLocked()
+	}
+	if err == nil {
+		err = s.writer.Flush()
+	}
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// write appends p to the file, tracking the offset the footer refers to
+func (s *ParquetSink) write(p []byte) error {
+	n, err := s.writer.Write(p)
+	s.offset += int64(n)
+	return err
+}
+
+// writeRowGroupLocked writes the buffered records as one row group, one
+// data page per column
+func (s *ParquetSink) writeRowGroupLocked() error {
+	if s.rows == 0 {
+		return nil
+	}
+
+	group := parquetRowGroup{rows: int64(s.rows)}
+	for _, c := range s.columns {
+		c.pad()
+		raw := c.values.Bytes()
+		page := raw
+		if s.compression == ParquetGzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write(raw); err != nil {
+				return err
+			}
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			page = buf.Bytes()
+		}
+		if len(raw) > math.MaxInt32 || len(page) > math.MaxInt32 {
+			return errors.New("sink: parquet page exceeds 2GB; use a smaller row group size")
+		}
+
+		var header thriftWriter
+		header.begin()
+		header.i32(1, 0) // type: DATA_PAGE
+		header.i32(2, int32(len(raw)))
+		header.i32(3, int32(len(page)))
+		header.structField(5) // data_page_header
+		header.i32(1, int32(s.rows))
+		header.i32(2, 0) // encoding: PLAIN
+		header.i32(3, 3) // definition_level_encoding: RLE
+		header.i32(4, 3) // repetition_level_encoding: RLE
+		header.end()
+		header.end()
+
+		chunk := parquetChunk{
+			column:       c,
+			offset:       s.offset,
+			uncompressed: int64(len(header.buf) + len(raw)),
+			compressed:   int64(len(header.buf) + len(page)),
+		}
+		if err := s.write(header.buf); err != nil {
+			return err
+		}
+		if err := s.write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		c.reset()
+	}
+
+	s.rowGroups = append(s.rowGroups, group)
+	s.totalRows += group.rows
+	s.rows = 0
+	return nil
+}
+
+// writeFooterLocked writes the file metadata, its length and the closing magic
+func (s *ParquetSink) writeFooterLocked() error {
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1) // version
+
+	meta.listField(2, thriftStruct, len(s.columns)+1) // schema
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(s.columns)))
+	meta.end()
+	for _, c := range s.columns {
+		meta.begin()
+		meta.i32(1, c.kind)
+		meta.i32(3, 0) // repetition_type: REQUIRED
+		meta.binary(4, c.name)
+		if c.converted >= 0 {
+			meta.i32(6, c.converted)
+		}
+		meta.end()
+	}
+
+	meta.i64(3, s.totalRows)
+
+	meta.listField(4, thriftStruct, len(s.rowGroups)) // row_groups
+	for _, g := range s.rowGroups {
+		var uncompressed, compressed int64
+		meta.begin()
+		meta.listField(1, thriftStruct, len(g.chunks)) // columns
+		for _, ch := range g.chunks {
+			meta.begin()
+			meta.i64(2, ch.offset) // file_offset
+			meta.structField(3)    // meta_data
+			meta.i32(1, ch.column.kind)
+			meta.listField(2, thriftI32, 1) // encodings
+			meta.listI32(0)                 // PLAIN
+			meta.listField(3, thriftBinary, 1)
+			meta.listBinary(ch.column.name) // path_in_schema
+			meta.i32(4, int32(s.compression))
+			meta.i64(5, g.rows)
+			meta.i64(6, ch.uncompressed)
+			meta.i64(7, ch.compressed)
+			meta.i64(9, ch.offset) // data_page_offset
+			meta.end()
+			meta.end()
+			uncompressed += ch.uncompressed
+			compressed += ch.compressed
+		}
+		meta.i64(2, uncompressed)
+		meta.i64(3, g.rows)
+		meta.i64(5, g.chunks[0].offset)
+		meta.i64(6, compressed)
+		meta.end()
+	}
+
+	meta.binary(6, "go_length_similarity")
+	meta.end()
+
+	if err := s.write(meta.buf); err != nil {
+		return err
+	}
+	var tail [8]byte
+	binary.LittleEndian.PutUint32(tail[:4], uint32(len(meta.buf)))
+	copy(tail[4:], parquetMagic)
+	return s.write(tail[:])
+}
+
+// Parquet physical types and converted types used by the columns
+const (
+	parquetBoolean   int32 = 0
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+
+	parquetUTF8            int32 = 0
+	parquetTimestampMicros int32 = 10
+	parquetNoConversion    int32 = -1
+)
+
+// parquetColumn buffers one column's PLAIN-encoded values for the current row group
+type parquetColumn struct {
+	name      string
+	kind      int32
+	converted int32
+	add       func(rec Record, details []byte)
+
+	values bytes.Buffer
+	// bits holds booleans not yet packed into a full byte
+	bits, nbits byte
+}
+
+// newParquetColumns returns the columns of a results file, in Record field order
+func newParquetColumns() []*parquetColumn {
+	var columns []*parquetColumn
+	str := func(name string, get func(Record, []byte) string) {
+		c := &parquetColumn{name: name, kind: parquetByteArray, converted: parquetUTF8}
+		c.add = func(rec Record, details []byte) { c.putString(get(rec, details)) }
+		columns = append(columns, c)
+	}
+	i64 := func(name string, converted int32, get func(Record) int64) {
+		c := &parquetColumn{name: name, kind: parquetInt64, converted: converted}
+		c.add = func(rec Record, _ []byte) { c.putUint64(uint64(get(rec))) }
+		columns = append(columns, c)
+	}
+	f64 := func(name string, get func(Record) float64) {
+		c := &parquetColumn{name: name, kind: parquetDouble, converted: parquetNoConversion}
+		c.add = func(rec Record, _ []byte) { c.putUint64(math.Float64bits(get(rec))) }
+		columns = append(columns, c)
+	}
+
+	str("id", func(r Record, _ []byte) string { return r.ID })
+	str("metric", func(r Record, _ []byte) string { return r.Metric })
+	str("engine", func(r Record, _ []byte) string { return r.Engine })
+	str("mode", func(r Record, _ []byte) string { return r.Mode })
+	f64("score", func(r Record) float64 { return r.Score })
+	passed := &parquetColumn{name: "passed", kind: parquetBoolean, converted: parquetNoConversion}
+	passed.add = func(rec Record, _ []byte) { passed.putBool(rec.Passed) }
+	columns = append(columns, passed)
+	i64("original_length", parquetNoConversion, func(r Record) int64 { return int64(r.OriginalLength) })
+	i64("augmented_length", parquetNoConversion, func(r Record) int64 { return int64(r.AugmentedLength) })
+	f64("length_ratio", func(r Record) float64 { return r.LengthRatio })
+	f64("threshold", func(r Record) float64 { return r.Threshold })
+	i64("bytes_processed", parquetNoConversion, func(r Record) int64 { return r.BytesProcessed })
+	str("processing_time", func(r Record, _ []byte) string { return r.ProcessingTime })
+	str("details", func(_ Record, details []byte) string { return string(details) })
+	str("original_ref", func(r Record, _ []byte) string { return string(r.OriginalRef) })
+	str("augmented_ref", func(r Record, _ []byte) string { return string(r.AugmentedRef) })
+	i64("timestamp", parquetTimestampMicros, func(r Record) int64 { return r.Timestamp.UnixMicro() })
+	return columns
+}
+
+// putString appends a length-prefixed byte array
+func (c *parquetColumn) putString(v string) {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(v)))
+	c.values.Write(n[:])
+	c.values.WriteString(v)
+}
+
+// putUint64 appends 8 little-endian bytes
+func (c *parquetColumn) putUint64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	c.values.Write(b[:])
+}
+
+// putBool appends one bit; PLAIN booleans are packed eight to a byte, least significant bit first
+func (c *parquetColumn) putBool(v bool) {
+	if v {
+		c.bits |= 1 << c.nbits
+	}
+	c.nbits++
+	if c.nbits == 8 {
+		c.values.WriteByte(c.bits)
+		c.bits, c.nbits = 0, 0
+	}
+}
+
+// pad writes out a partly filled byte of booleans
+func (c *parquetColumn) pad() {
+	if c.nbits > 0 {
+		c.values.WriteByte(c.bits)
+		c.bits, c.nbits = 0, 0
+	}
+}
+
+// reset empties the column for the next row group
+func (c *parquetColumn) reset() {
+	c.values.Reset()
+	c.bits, c.nbits = 0, 0
+}
+
+// parquetRowGroup records where a written row group's column chunks are
+type parquetRowGroup struct {
+	rows   int64
+	chunks []parquetChunk
+}
+
+// parquetChunk is one column's data in a row group
+type parquetChunk struct {
+	column       *parquetColumn
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+// Thrift compact protocol types, as used in Parquet metadata
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol, the encoding
+// of Parquet's page headers and footer. Only the types Parquet metadata
+// needs are supported.
+type thriftWriter struct {
+	buf []byte
+	// last holds the previous field id of each open struct
+	last []int16
+}
+
+// begin opens a struct, either the top-level one or a list element
+func (w *thriftWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+// end closes the innermost struct
+func (w *thriftWriter) end() {
+	w.buf = append(w.buf, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+// field writes a field header, as a delta from the previous field when it fits
+func (w *thriftWriter) field(id int16, typ byte) {
+	top := len(w.last) - 1
+	if delta := id - w.last[top]; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.buf = binary.AppendUvarint(w.buf, zigzag(int64(id)))
+	}
+	w.last[top] = id
+}
+
+// i32 writes an i32 field
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.buf = binary.AppendUvarint(w.buf, zigzag(int64(v)))
+}
+
+// i64 writes an i64 field
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.buf = binary.AppendUvarint(w.buf, zigzag(v))
+}
+
+// binary writes a string field
+func (w *thriftWriter) binary(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.listBinary(v)
+}
+
+// structField opens a struct-valued field; close it with end
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+// listField writes the header of a list field of size elements of elem;
+// the elements follow, written with listI32, listBinary or begin and end
+func (w *thriftWriter) listField(id int16, elem byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf = append(w.buf, byte(size)<<4|elem)
+	} else {
+		w.buf = append(w.buf, 0xf0|elem)
+		w.buf = binary.AppendUvarint(w.buf, uint64(size))
+	}
+}
+
+// listI32 writes an i32 list element
+func (w *thriftWriter) listI32(v int32) {
+	w.buf = binary.AppendUvarint(w.buf, zigzag(int64(v)))
+}
+
+// listBinary writes a string list element
+func (w *thriftWriter) listBinary(v string) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// zigzag maps signed integers to unsigned so small magnitudes encode short
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,362 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParquetSinkRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []ParquetOption
+	}{
+		{name: "uncompressed"},
+		{name: "gzip", opts: []ParquetOption{WithParquetCompression(ParquetGzip)}},
+		{name: "small row groups", opts: []ParquetOption{WithRowGroupSize(4)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			s := NewParquetSink(&buf, tc.opts...)
+			at := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+			for i := 0; i < 10; i++ {
+				rec := Record{
+					ID:             string(rune('a' + i)),
+					Metric:         "length_similarity",
+					Score:          float64(i) / 10,
+					Passed:         i%3 == 0,
+					OriginalLength: i * 100,
+					Details:        map[string]interface{}{"n": i},
+					Timestamp:      at,
+				}
+				if err := s.Write(context.Background(), rec); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			file := readParquet(t, buf.Bytes())
+			if file.rows != 10 {
+				t.Fatalf("expected 10 rows in the footer, got %d", file.rows)
+			}
+			ids := file.strings(t, "id")
+			if len(ids) != 10 || ids[0] != "a" || ids[9] != "j" {
+				t.Fatalf("unexpected ids %v", ids)
+			}
+			if d := file.strings(t, "details"); d[7] != `{"n":7}` {
+				t.Fatalf("expected details as JSON, got %q", d[7])
+			}
+			if score := file.fixed(t, "score"); math.Float64frombits(score[5]) != 0.5 {
+				t.Fatalf("expected score 0.5, got %v", math.Float64frombits(score[5]))
+			}
+			if lengths := file.fixed(t, "original_length"); lengths[9] != 900 {
+				t.Fatalf("expected original length 900, got %d", lengths[9])
+			}
+			if ts := file.fixed(t, "timestamp"); int64(ts[0]) != at.UnixMicro() {
+				t.Fatalf("expected timestamp %d, got %d", at.UnixMicro(), ts[0])
+			}
+			passed := file.bools(t, "passed")
+			for i, p := range passed {
+				if p != (i%3 == 0) {
+					t.Fatalf("row %d: expected passed %v, got %v", i, i%3 == 0, p)
+				}
+			}
+		})
+	}
+}
+
+func TestParquetSinkWithoutRecordsIsReadable(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewParquetSink(&buf)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if file := readParquet(t, buf.Bytes()); file.rows != 0 || len(file.groups) != 0 {
+		t.Fatalf("expected an empty file, got %d rows in %d row groups", file.rows, len(file.groups))
+	}
+}
+
+func TestParquetSinkFlushWritesARowGroup(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewParquetSink(&buf)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := s.Write(context.Background(), Record{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+		if id == "b" {
+			if err := s.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), Record{ID: "d"}); err != ErrSinkClosed {
+		t.Fatalf("expected ErrSinkClosed, got %v", err)
+	}
+
+	file := readParquet(t, buf.Bytes())
+	if len(file.groups) != 2 {
+		t.Fatalf("expected a row group per flush, got %d", len(file.groups))
+	}
+	if ids := file.strings(t, "id"); len(ids) != 3 || ids[2] != "c" {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
+
+// parquetGolden is a small file written by the sink. readParquet decodes it
+// with the same assumptions the writer makes, so the file is what ties the
+// encoder to real readers: after a deliberate format change, rewrite it with
+// -update and check it opens before committing, for example with
+//
+//	duckdb -c "SELECT * FROM 'pkg/sink/testdata/records.parquet'"
+//	python -c "import pyarrow.parquet as pq; print(pq.read_table('pkg/sink/testdata/records.parquet'))"
+const parquetGolden = "testdata/records.parquet"
+
+func TestParquetSinkMatchesGolden(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewParquetSink(&buf, WithRowGroupSize(2))
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, rec := range []Record{
+		{ID: "doc-1", Metric: "length_similarity", Engine: "word", Mode: "word", Score: 0.75, Passed: true,
+			OriginalLength: 12, AugmentedLength: 9, LengthRatio: 0.75, Threshold: 0.7,
+			ProcessingTime: "1ms", Details: map[string]interface{}{"note": "ok"}, OriginalRef: "0a1b"},
+		{ID: "doc-2", Metric: "character_similarity", Engine: "character", Score: 0.5,
+			OriginalLength: 40, AugmentedLength: 20, LengthRatio: 0.5, Threshold: 0.8, BytesProcessed: 60},
+		{ID: "doc-3", Metric: "streaming_similarity", Engine: "streaming", Mode: "character", Score: 1, Passed: true,
+			OriginalLength: 5, AugmentedLength: 5, LengthRatio: 1, Threshold: 0.7, AugmentedRef: "2c3d"},
+	} {
+		rec.Timestamp = at.Add(time.Duration(i) * time.Second)
+		if err := s.Write(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(parquetGolden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(parquetGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		at := 0
+		for at < len(got) && at < len(want) && got[at] == want[at] {
+			at++
+		}
+		t.Fatalf("output (%d bytes) differs from %s (%d bytes) at offset %d; if the format change is deliberate, run with -update and check the file opens in a Parquet reader",
+			len(got), parquetGolden, len(want), at)
+	}
+
+	file := readParquet(t, want)
+	if file.rows != 3 || len(file.groups) != 2 {
+		t.Fatalf("expected 3 rows in 2 row groups, got %d in %d", file.rows, len(file.groups))
+	}
+	if ids := file.strings(t, "id"); len(ids) != 3 || ids[0] != "doc-1" || ids[2] != "doc-3" {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+	if passed := file.bools(t, "passed"); len(passed) != 3 || !passed[0] || passed[1] || !passed[2] {
+		t.Fatalf("unexpected passed %v", passed)
+	}
+}
+
+// parquetFile is what the tests read back: the footer and the raw file
+type parquetFile struct {
+	data   []byte
+	rows   int64
+	names  []string
+	codec  int64
+	groups []map[int16]interface{}
+}
+
+// readParquet checks the framing and decodes the footer
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("missing PAR1 magic")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := decodeThrift(t, data[len(data)-8-n:len(data)-8])
+
+	file := &parquetFile{data: data, rows: meta[3].(int64)}
+	schema := meta[2].([]interface{})
+	if root := schema[0].(map[int16]interface{}); root[5].(int64) != int64(len(schema)-1) {
+		t.Fatalf("root schema element counts %d children, have %d", root[5], len(schema)-1)
+	}
+	for _, el := range schema[1:] {
+		file.names = append(file.names, string(el.(map[int16]interface{})[4].([]byte)))
+	}
+	groups, _ := meta[4].([]interface{})
+	for _, g := range groups {
+		file.groups = append(file.groups, g.(map[int16]interface{}))
+	}
+	return file
+}
+
+// column returns the decoded page data of the named column in each row group
+func (f *parquetFile) column(t *testing.T, name string) [][]byte {
+	t.Helper()
+	idx := -1
+	for i, n := range f.names {
+		if n == name {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		t.Fatalf("no column %q in %v", name, f.names)
+	}
+
+	var pages [][]byte
+	for _, g := range f.groups {
+		chunk := g[1].([]interface{})[idx].(map[int16]interface{})
+		meta := chunk[3].(map[int16]interface{})
+		offset := meta[9].(int64)
+		size := meta[7].(int64)
+
+		r := &thriftReader{t: t, buf: f.data[offset : offset+size]}
+		header := r.readStruct()
+		page := r.buf[r.pos : r.pos+int(header[3].(int64))]
+		if meta[4].(int64) == int64(ParquetGzip) {
+			zr, err := gzip.NewReader(bytes.NewReader(page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page, err = io.ReadAll(zr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if int64(len(page)) != header[2].(int64) {
+			t.Fatalf("page is %d bytes, header says %d", len(page), header[2])
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func (f *parquetFile) strings(t *testing.T, name string) []string {
+	var out []string
+	for _, page := range f.column(t, name) {
+		for len(page) > 0 {
+			n := binary.LittleEndian.Uint32(page)
+			out = append(out, string(page[4:4+n]))
+			page = page[4+n:]
+		}
+	}
+	return out
+}
+
+func (f *parquetFile) fixed(t *testing.T, name string) []uint64 {
+	var out []uint64
+	for _, page := range f.column(t, name) {
+		for ; len(page) > 0; page = page[8:] {
+			out = append(out, binary.LittleEndian.Uint64(page))
+		}
+	}
+	return out
+}
+
+func (f *parquetFile) bools(t *testing.T, name string) []bool {
+	var out []bool
+	for i, page := range f.column(t, name) {
+		rows := f.groups[i][3].(int64)
+		for j := int64(0); j < rows; j++ {
+			out = append(out, page[j/8]&(1<<(j%8)) != 0)
+		}
+	}
+	return out
+}
+
+// decodeThrift decodes a compact-protocol struct into field id -> value
+func decodeThrift(t *testing.T, buf []byte) map[int16]interface{} {
+	r := &thriftReader{t: t, buf: buf}
+	return r.readStruct()
+}
+
+// thriftReader is a minimal compact-protocol decoder, independent of the writer
+type thriftReader struct {
+	t   *testing.T
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		r.t.Fatalf("bad varint at %d", r.pos)
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, 5, 6:
+		return r.varint()
+	case 8:
+		n := int(r.uvarint())
+		v := r.buf[r.pos : r.pos+n]
+		r.pos += n
+		return v
+	case 9:
+		h := r.byte()
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	r.t.Fatalf("unsupported thrift type %d", typ)
+	return nil
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
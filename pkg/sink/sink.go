@@ -0,0 +1,127 @@
+// Package sink provides incremental result writers for corpus and batch runs.
+// Results are written as they are produced so that long-running jobs leave
+// durable output behind even when they are interrupted.
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/content"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+)
+
+// Record is the serializable form of a single similarity result
+type Record struct {
+	ID              string                 `json:"id"`
+	Metric          string                 `json:"metric"`
+	Engine          string                 `json:"engine,omitempty"`
+	Mode            string                 `json:"mode,omitempty"`
+	Score           float64                `json:"score"`
+	Passed          bool                   `json:"passed"`
+	OriginalLength  int                    `json:"original_length"`
+	AugmentedLength int                    `json:"augmented_length"`
+	LengthRatio     float64                `json:"length_ratio"`
+	Threshold       float64                `json:"threshold"`
+	BytesProcessed  int64                  `json:"bytes_processed,omitempty"`
+	ProcessingTime  string                 `json:"processing_time,omitempty"`
+	Details         map[string]interface{} `json:"details,omitempty"`
+	// OriginalRef and AugmentedRef are the content store keys of the inputs,
+	// when the caller stored them
+	OriginalRef  content.Key `json:"original_ref,omitempty"`
+	AugmentedRef content.Key `json:"augmented_ref,omitempty"`
+	Timestamp    time.Time   `json:"timestamp"`
+}
+
+// ResultSink receives results incrementally during a run
+type ResultSink interface {
+	// Write stores a single record
+	Write(ctx context.Context, rec Record) error
+
+	// Flush makes all records written so far durable
+	Flush() error
+
+	// Close flushes and releases the sink
+	Close() error
+}
+
+// FromResult converts a word or character similarity result into a record
+func FromResult(id string, r domain.Result) Record {
+	return Record{
+		ID:              id,
+		Metric:          r.Name,
+		Engine:          r.Engine,
+		Mode:            r.Mode,
+		Score:           r.Score,
+		Passed:          r.Passed,
+		OriginalLength:  r.OriginalLength,
+		AugmentedLength: r.AugmentedLength,
+		LengthRatio:     r.LengthRatio,
+		Threshold:       r.Threshold,
+		Details:         r.Details,
+		Timestamp:       time.Now(),
+	}
+}
+
+// FromStreamResult converts a streaming similarity result into a record
+func FromStreamResult(id string, r streaming.StreamResult) Record {
+	return Record{
+		ID:              id,
+		Metric:          r.Name,
+		Engine:          r.Engine,
+		Mode:            r.Mode,
+		Score:           r.Score,
+		Passed:          r.Passed,
+		OriginalLength:  r.OriginalLength,
+		AugmentedLength: r.AugmentedLength,
+		LengthRatio:     r.LengthRatio,
+		Threshold:       r.Threshold,
+		BytesProcessed:  r.BytesProcessed,
+		ProcessingTime:  r.ProcessingTime,
+		Details:         r.Details,
+		Timestamp:       time.Now(),
+	}
+}
+
+// MultiSink fans records out to several sinks
+type MultiSink struct {
+	sinks []ResultSink
+}
+
+// NewMultiSink creates a sink that writes every record to all of the given sinks
+func NewMultiSink(sinks ...ResultSink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// Write writes the record to every sink, stopping at the first error
+func (m *MultiSink) Write(ctx context.Context, rec Record) error {
+	for _, s := range m.sinks {
+		if err := s.Write(ctx, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush flushes every sink and returns the first error encountered
+func (m *MultiSink) Flush() error {
+	var firstErr error
+	for _, s := range m.sinks {
+		if err := s.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes every sink and returns the first error encountered
+func (m *MultiSink) Close() error {
+	var firstErr error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,193 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/jsonschema"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+)
+
+func TestJSONLSinkWritesOneRecordPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewJSONLSink(&buf)
+
+	for _, id := range []string{"a", "b"} {
+		if err := s.Write(context.Background(), Record{ID: id, Metric: "length_similarity", Score: 0.5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	var ids []string
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line is not valid JSON: %v", err)
+		}
+		ids = append(ids, rec.ID)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("expected records a and b in order, got %v", ids)
+	}
+}
+
+func TestChannelSinkRejectsWritesAfterClose(t *testing.T) {
+	s := NewChannelSink(1)
+	if err := s.Write(context.Background(), Record{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if rec := <-s.Records(); rec.ID != "a" {
+		t.Fatalf("expected buffered record to be delivered, got %#v", rec)
+	}
+	if err := s.Write(context.Background(), Record{ID: "b"}); err != ErrSinkClosed {
+		t.Fatalf("expected ErrSinkClosed, got %v", err)
+	}
+}
+
+func TestChannelSinkCloseReleasesBlockedWriter(t *testing.T) {
+	s := NewChannelSink(0)
+
+	// Nobody reads, so the write blocks until Close
+	written := make(chan error, 1)
+	go func() { written <- s.Write(context.Background(), Record{ID: "a"}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+	for _, ch := range []chan error{closed, written} {
+		select {
+		case err := <-ch:
+			if err != nil && !errors.Is(err, ErrSinkClosed) {
+				t.Errorf("got %v, want nil or ErrSinkClosed", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close deadlocked with a writer blocked on the channel")
+		}
+	}
+}
+
+// failingWriter fails every write and records whether it was closed
+type failingWriter struct{ closed bool }
+
+func (w *failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (w *failingWriter) Close() error              { w.closed = true; return nil }
+
+func TestJSONLSinkCloseClosesWriterAfterFlushError(t *testing.T) {
+	w := &failingWriter{}
+	s := NewJSONLSink(w, WithFlushEvery(10))
+	if err := s.Write(context.Background(), Record{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Close() = %v, want the flush error", err)
+	}
+	if !w.closed {
+		t.Error("writer not closed after the flush failed")
+	}
+}
+
+func TestJSONLSinkRejectsWritesAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := NewJSONLFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), Record{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Write(context.Background(), Record{ID: "b"}); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("Write after Close = %v, want ErrSinkClosed", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+	if records, err := ReadJSONLFile(path); err != nil || len(records) != 1 {
+		t.Errorf("file holds %d records (%v), want only the one written before Close", len(records), err)
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the published schemas and golden files")
+
+func TestRecordSchema(t *testing.T) {
+	s := jsonschema.Generate(Record{}, jsonschema.BaseID+"record.schema.json", "Similarity result record")
+	if err := jsonschema.CheckFile("../../schema/record.schema.json", s, *update); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	sink := NewJSONLSink(&buf)
+	rec := FromStreamResult("doc-1", streaming.StreamResult{
+		Name: "streaming_similarity", Score: 0.8, BytesProcessed: 42, ProcessingTime: "1ms",
+		Details: map[string]interface{}{"mode": 1},
+	})
+	if err := sink.Write(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(context.Background(), FromResult("doc-2", domain.Result{Name: "length_similarity"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if err := jsonschema.Validate(s, line); err != nil {
+			t.Errorf("record %s: %v", line, err)
+		}
+	}
+}
+
+func TestReadJSONLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewJSONLSink(&buf)
+	want := []Record{
+		{ID: "a", Metric: "length_similarity", Engine: domain.EngineWord, Score: 0.5, Passed: false},
+		{ID: "b", Metric: "character_similarity", Engine: domain.EngineCharacter, Score: 0.9, Passed: true},
+	}
+	for _, rec := range want {
+		if err := s.Write(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\n")
+
+	got, err := ReadJSONL(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Engine != want[i].Engine || got[i].Score != want[i].Score || got[i].Passed != want[i].Passed {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if _, err := ReadJSONL(bytes.NewBufferString("{\"id\":\"a\"}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error %v, want one naming line 2", err)
+	}
+}
@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTable is the table used by SQLSink when none is configured
+const DefaultTable = "similarity_results"
+
+// SQLSink inserts records into a database/sql table.
+// Statements use '?' placeholders, which matches SQLite and MySQL drivers.
+// The driver itself is registered by the caller, so this package stays free of cgo.
+type SQLSink struct {
+	db    *sql.DB
+	table string
+	stmt  *sql.Stmt
+}
+
+// sqlColumns are the results table's columns, in insert order. Columns added
+// after the first release go at the end; NewSQLSink adds them to older tables.
+var sqlColumns = []struct{ name, typ string }{
+	{"id", "TEXT"},
+	{"metric", "TEXT"},
+	{"engine", "TEXT"},
+	{"mode", "TEXT"},
+	{"score", "REAL"},
+	{"passed", "INTEGER"},
+	{"original_length", "INTEGER"},
+	{"augmented_length", "INTEGER"},
+	{"length_ratio", "REAL"},
+	{"threshold", "REAL"},
+	{"bytes_processed", "INTEGER"},
+	{"processing_time", "TEXT"},
+	{"details", "TEXT"},
+	{"original_ref", "TEXT"},
+	{"augmented_ref", "TEXT"},
+	{"created_at", "TEXT"},
+}
+
+// NewSQLSink creates the results table if needed, adds any columns a table
+// created by an earlier release lacks, and prepares the insert statement
+func NewSQLSink(ctx context.Context, db *sql.DB, table string) (*SQLSink, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+
+	defs := make([]string, len(sqlColumns))
+	names := make([]string, len(sqlColumns))
+	for i, col := range sqlColumns {
+		defs[i] = "\t" + col.name + " " + col.typ
+		names[i] = col.name
+	}
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", table, strings.Join(defs, ",\n"))
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("failed to create results table: %w", err)
+	}
+	if err := addMissingColumns(ctx, db, table); err != nil {
+		return nil, err
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s)\n\tVALUES (?%s)", table, strings.Join(names, ", "), strings.Repeat(", ?", len(names)-1))
+	stmt, err := db.PrepareContext(ctx, insert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+
+	return &SQLSink{
+		db:    db,
+		table: table,
+		stmt:  stmt,
+	}, nil
+}
+
+// addMissingColumns adds the sqlColumns an existing table lacks. It reads the
+// table's columns from an empty query, which every database/sql driver supports.
+func addMissingColumns(ctx context.Context, db *sql.DB, table string) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", table))
+	if err != nil {
+		return fmt.Errorf("failed to read results table columns: %w", err)
+	}
+	existing, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read results table columns: %w", err)
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[strings.ToLower(name)] = true
+	}
+	for _, col := range sqlColumns {
+		if have[col.name] {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.typ)); err != nil {
+			return fmt.Errorf("failed to add column %s to results table: %w", col.name, err)
+		}
+	}
+	return nil
+}
+
+// Write inserts a single record
+func (s *SQLSink) Write(ctx context.Context, rec Record) error {
+	details, err := json.Marshal(rec.Details)
+	if err != nil {
+		return err
+	}
+
+	passed := 0
+	if rec.Passed {
+		passed = 1
+	}
+
+	_, err = s.stmt.ExecContext(ctx,
+		rec.ID,
+		rec.Metric,
+		rec.Engine,
+		rec.Mode,
+		rec.Score,
+		passed,
+		rec.OriginalLength,
+		rec.AugmentedLength,
+		rec.LengthRatio,
+		rec.Threshold,
+		rec.BytesProcessed,
+		rec.ProcessingTime,
+		string(details),
+		string(rec.OriginalRef),
+		string(rec.AugmentedRef),
+		rec.Timestamp.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// Flush is a no-op; every insert is committed as it is written
+func (s *SQLSink) Flush() error {
+	return nil
+}
+
+// Close releases the prepared statement; the *sql.DB remains owned by the caller
+func (s *SQLSink) Close() error {
+	return s.stmt.Close()
+}
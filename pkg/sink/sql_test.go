@@ -0,0 +1,182 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/content"
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite opens an empty SQLite database for a test
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLSinkStoresEngineAndMode(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+	s, err := NewSQLSink(ctx, db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := Record{ID: "a", Metric: "streaming_similarity", Engine: "efficient", Mode: "line", Score: 0.75, Timestamp: time.Now()}
+	if err := s.Write(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var engine, mode string
+	if err := db.QueryRow("SELECT engine, mode FROM "+DefaultTable+" WHERE id = 'a'").Scan(&engine, &mode); err != nil {
+		t.Fatal(err)
+	}
+	if engine != "efficient" || mode != "line" {
+		t.Errorf("stored engine %q, mode %q; want efficient, line", engine, mode)
+	}
+}
+
+func TestSQLSinkStoresContentRefs(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+	s, err := NewSQLSink(ctx, db, "refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := Record{ID: "a", Metric: "length_similarity", OriginalRef: "0a1b", AugmentedRef: "2c3d", Timestamp: time.Now()}
+	if err := s.Write(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(ctx, Record{ID: "b", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var orig, aug content.Key
+	if err := db.QueryRow("SELECT original_ref, augmented_ref FROM refs WHERE id = 'a'").Scan(&orig, &aug); err != nil {
+		t.Fatal(err)
+	}
+	if orig != rec.OriginalRef || aug != rec.AugmentedRef {
+		t.Errorf("stored refs %q, %q; want %q, %q", orig, aug, rec.OriginalRef, rec.AugmentedRef)
+	}
+	if err := db.QueryRow("SELECT original_ref FROM refs WHERE id = 'b'").Scan(&orig); err != nil || orig != "" {
+		t.Errorf("record without refs stored %q, %v; want an empty ref", orig, err)
+	}
+}
+
+func TestSQLSinkWritesRecords(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+	s, err := NewSQLSink(ctx, db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rec := Record{ID: "a", Metric: "length_similarity", Score: 0.8, Passed: true, OriginalLength: 10, AugmentedLength: 8,
+		LengthRatio: 0.8, Threshold: 0.7, Details: map[string]interface{}{"k": "v"}, Timestamp: ts}
+	if err := s.Write(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	// A second sink on the same table reuses it
+	again, err := NewSQLSink(ctx, db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := again.Write(ctx, Record{ID: "b", Timestamp: ts}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*SQLSink{s, again} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		id, metric, details, created string
+		score                        float64
+		passed, origLen, augLen      int
+	)
+	row := db.QueryRow("SELECT id, metric, score, passed, original_length, augmented_length, details, created_at FROM " + DefaultTable + " ORDER BY id LIMIT 1")
+	if err := row.Scan(&id, &metric, &score, &passed, &origLen, &augLen, &details, &created); err != nil {
+		t.Fatal(err)
+	}
+	if id != "a" || metric != rec.Metric || score != 0.8 || passed != 1 || origLen != 10 || augLen != 8 ||
+		details != `{"k":"v"}` || created != "2024-05-01T12:00:00Z" {
+		t.Errorf("stored %s %s %v %d %d %d %s %s; want record a", id, metric, score, passed, origLen, augLen, details, created)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + DefaultTable).Scan(&count); err != nil || count != 2 {
+		t.Errorf("stored %d rows, %v; want 2", count, err)
+	}
+	if err := s.Write(ctx, rec); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+func TestSQLSinkUpgradesOldTable(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+	// The schema of the first SQLSink release, before engine, mode and the content refs
+	if _, err := db.Exec(`CREATE TABLE ` + DefaultTable + ` (
+	id TEXT,
+	metric TEXT,
+	score REAL,
+	passed INTEGER,
+	original_length INTEGER,
+	augmented_length INTEGER,
+	length_ratio REAL,
+	threshold REAL,
+	bytes_processed INTEGER,
+	processing_time TEXT,
+	details TEXT,
+	created_at TEXT
+)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO ` + DefaultTable + ` (id, metric, score) VALUES ('old', 'length', 0.5)`); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSQLSink(ctx, db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := Record{ID: "new", Metric: "length", Engine: "word", Mode: "text", OriginalRef: "0a1b", Timestamp: time.Now()}
+	if err := s.Write(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var engine, ref string
+	if err := db.QueryRow("SELECT engine, original_ref FROM "+DefaultTable+" WHERE id = 'new'").Scan(&engine, &ref); err != nil {
+		t.Fatal(err)
+	}
+	if engine != "word" || ref != "0a1b" {
+		t.Errorf("stored engine %q, original_ref %q; want word, 0a1b", engine, ref)
+	}
+	var score float64
+	if err := db.QueryRow("SELECT score FROM " + DefaultTable + " WHERE id = 'old'").Scan(&score); err != nil || score != 0.5 {
+		t.Errorf("old row score = %v, %v; want it kept", score, err)
+	}
+
+	// A second sink on the upgraded table finds nothing to add
+	if s, err = NewSQLSink(ctx, db, ""); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+}
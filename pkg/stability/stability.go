@@ -0,0 +1,257 @@
+// Package stability scores one pair under several normalization profiles
+// and reports how much the score moves between them. A pair whose verdict
+// flips with the profile sits on the threshold only because of how the text
+// was cleaned up, which is worth knowing before trusting that threshold.
+package stability
+
+import (
+	"context"
+	"math"
+
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+	"github.com/baditaflorin/l"
+)
+
+// Result is the outcome of one profile
+type Result = domain.Result
+
+// ConfigError reports an invalid option value passed to New
+type ConfigError = domain.ConfigError
+
+// Normalizer cleans up text before it is measured
+type Normalizer = ports.Normalizer
+
+// Metric is one similarity measure. *word.LengthSimilarity and
+// *character.CharacterSimilarity satisfy it.
+type Metric interface {
+	Compute(ctx context.Context, original, augmented string) Result
+}
+
+// Profile is one way of normalizing a pair, expressed as a metric configured for it
+type Profile struct {
+	Name   string
+	Metric Metric
+}
+
+// Score is a pair's result under one profile
+type Score struct {
+	Profile string
+	Result  Result
+}
+
+// Report is the outcome of comparing one pair under every profile
+type Report struct {
+	// Scores holds one entry per profile, in the order the profiles were given
+	Scores []Score
+	// Mean and Variance are over the scores; Variance is the population variance
+	Mean     float64
+	Variance float64
+	Min, Max float64
+	// Unstable is set when some profiles pass the pair and others fail it.
+	// Inconclusive results take no side.
+	Unstable bool
+	// Err is the context error if the comparison was cancelled before every profile ran
+	Err error
+}
+
+// Spread returns the gap between the highest and lowest score
+func (r Report) Spread() float64 {
+	return r.Max - r.Min
+}
+
+// Checker compares pairs across profiles; it is safe for concurrent use
+type Checker struct {
+	profiles []Profile
+}
+
+// Option defines a functional option for configuring a Checker
+type Option func(*config)
+
+type config struct {
+	Threshold  float64
+	Characters bool
+	Logger     l.Logger
+	Profiles   []Profile
+}
+
+// WithThreshold sets the pass threshold of the default profiles. Default 0.7.
+func WithThreshold(th float64) Option {
+	return func(cfg *config) {
+		cfg.Threshold = th
+	}
+}
+
+// WithCharacters builds the default profiles on character length instead of word length
+func WithCharacters() Option {
+	return func(cfg *config) {
+		cfg.Characters = true
+	}
+}
+
+// WithLogger sets the logger used by the default profiles
+func WithLogger(l l.Logger) Option {
+	return func(cfg *config) {
+		cfg.Logger = l
+	}
+}
+
+// WithProfiles replaces the default profiles. At least two are needed.
+func WithProfiles(profiles ...Profile) Option {
+	return func(cfg *config) {
+		cfg.Profiles = profiles
+	}
+}
+
+// New creates a Checker. Unless WithProfiles is given, word length is
+// compared with the default normalizer against the raw text ("default" and
+// "none"), and character length with the default normalizer against
+// normalized line endings and stripped indentation ("default" and "whitespace").
+func New(opts ...Option) (*Checker, error) {
+	config := &config{
+		Threshold: 0.7,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.Profiles != nil {
+		if len(config.Profiles) < 2 {
+			return nil, domain.NewConfigError("profiles", len(config.Profiles), "at least two profiles are needed")
+		}
+		seen := make(map[string]bool, len(config.Profiles))
+		for _, p := range config.Profiles {
+			if p.Metric == nil {
+				return nil, domain.NewConfigError("profiles", p.Name, "profile has no metric")
+			}
+			if seen[p.Name] {
+				return nil, domain.NewConfigError("profiles", p.Name, "profile names must be unique")
+			}
+			seen[p.Name] = true
+		}
+		return &Checker{profiles: config.Profiles}, nil
+	}
+
+	if err := domain.ValidateThreshold(config.Threshold); err != nil {
+		return nil, domain.NewConfigError("threshold", config.Threshold, "must be between 0 and 1")
+	}
+	var (
+		profiles []Profile
+		err      error
+	)
+	if config.Characters {
+		profiles, err = characterProfiles(config)
+	} else {
+		profiles, err = wordProfiles(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{profiles: profiles}, nil
+}
+
+// wordProfiles builds the default word length profiles
+func wordProfiles(config *config) ([]Profile, error) {
+	build := func(norm Normalizer) (Metric, error) {
+		opts := []word.LengthSimilarityOption{word.WithThreshold(config.Threshold), word.WithNormalizer(norm)}
+		if config.Logger != nil {
+			opts = append(opts, word.WithLogger(config.Logger))
+		}
+		return word.New(opts...)
+	}
+
+	normalized, err := build(normalizer.NewDefaultNormalizer())
+	if err != nil {
+		return nil, err
+	}
+	raw, err := build(rawNormalizer{})
+	if err != nil {
+		return nil, err
+	}
+	return []Profile{{Name: "default", Metric: normalized}, {Name: "none", Metric: raw}}, nil
+}
+
+// characterProfiles builds the default character length profiles
+func characterProfiles(config *config) ([]Profile, error) {
+	build := func(extra ...character.CharacterSimilarityOption) (Metric, error) {
+		opts := append([]character.CharacterSimilarityOption{character.WithThreshold(config.Threshold)}, extra...)
+		if config.Logger != nil {
+			opts = append(opts, character.WithLogger(config.Logger))
+		}
+		return character.NewCharacterSimilarity(opts...)
+	}
+
+	normalized, err := build()
+	if err != nil {
+		return nil, err
+	}
+	whitespace, err := build(character.WithNormalizedLineEndings(), character.WithStrippedIndentation())
+	if err != nil {
+		return nil, err
+	}
+	return []Profile{{Name: "default", Metric: normalized}, {Name: "whitespace", Metric: whitespace}}, nil
+}
+
+// rawNormalizer leaves text as it is
+type rawNormalizer struct{}
+
+// Normalize returns text unchanged
+func (rawNormalizer) Normalize(text string) string {
+	return text
+}
+
+// Profiles returns the names of the profiles in the order they run
+func (c *Checker) Profiles() []string {
+	names := make([]string, len(c.profiles))
+	for i, p := range c.profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Compare scores original against augmented under every profile
+func (c *Checker) Compare(ctx context.Context, original, augmented string) Report {
+	report := Report{Scores: make([]Score, 0, len(c.profiles))}
+	for _, p := range c.profiles {
+		if err := ctx.Err(); err != nil {
+			report.Err = err
+			break
+		}
+		report.Scores = append(report.Scores, Score{Profile: p.Name, Result: p.Metric.Compute(ctx, original, augmented)})
+	}
+	if report.Err == nil {
+		report.Err = ctx.Err()
+	}
+	if len(report.Scores) == 0 {
+		return report
+	}
+
+	report.Min, report.Max = math.Inf(1), math.Inf(-1)
+	var sum float64
+	passed, failed := false, false
+	for _, s := range report.Scores {
+		sum += s.Result.Score
+		report.Min = min(report.Min, s.Result.Score)
+		report.Max = max(report.Max, s.Result.Score)
+		switch {
+		case s.Result.Inconclusive:
+		case s.Result.Passed:
+			passed = true
+		default:
+			failed = true
+		}
+	}
+	report.Mean = sum / float64(len(report.Scores))
+	for _, s := range report.Scores {
+		d := s.Result.Score - report.Mean
+		report.Variance += d * d
+	}
+	report.Variance /= float64(len(report.Scores))
+	report.Unstable = passed && failed
+	return report
+}
@@ -0,0 +1,115 @@
+package stability
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func discardLogger(t *testing.T) l.Logger {
+	t.Helper()
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+// fixedMetric returns the same result for every pair
+type fixedMetric Result
+
+func (m fixedMetric) Compute(context.Context, string, string) Result {
+	return Result(m)
+}
+
+func TestCompareFlagsNormalizationDependentVerdicts(t *testing.T) {
+	ctx := context.Background()
+
+	words, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hyphens split words only once punctuation is normalized away
+	r := words.Compare(ctx, "a well-known state-of-the-art tool", "a well known state of the art tool")
+	if !r.Unstable || len(r.Scores) != 2 || r.Scores[0].Profile != "default" || r.Scores[1].Profile != "none" {
+		t.Errorf("hyphenated words: %+v, want an unstable verdict across default and none", r)
+	}
+	r = words.Compare(ctx, "the quick brown fox", "the quick brown fox")
+	if r.Unstable || r.Variance != 0 || r.Mean != 1 {
+		t.Errorf("identical texts: %+v, want a stable score of 1", r)
+	}
+
+	chars, err := New(WithCharacters(), WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tab- against space-indented code differs only before indentation is stripped
+	r = chars.Compare(ctx, "func main() {\n\treturn\n}\n", "func main() {\n        return\n}\n")
+	if !r.Unstable || r.Scores[1].Profile != "whitespace" || !r.Scores[1].Result.Passed {
+		t.Errorf("re-indented code: %+v, want a pass only under whitespace", r)
+	}
+}
+
+func TestCompareStatistics(t *testing.T) {
+	c, err := New(WithProfiles(
+		Profile{Name: "a", Metric: fixedMetric{Score: 0.2}},
+		Profile{Name: "b", Metric: fixedMetric{Score: 0.4}},
+		Profile{Name: "c", Metric: fixedMetric{Score: 0.9, Passed: true}},
+		Profile{Name: "d", Metric: fixedMetric{Score: 0.5, Inconclusive: true}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := c.Compare(context.Background(), "x", "y")
+	if r.Mean != 0.5 || r.Min != 0.2 || r.Max != 0.9 || math.Abs(r.Spread()-0.7) > 1e-9 {
+		t.Errorf("Mean, Min, Max, Spread = %v, %v, %v, %v; want 0.5, 0.2, 0.9, 0.7", r.Mean, r.Min, r.Max, r.Spread())
+	}
+	if want := (0.09 + 0.01 + 0.16 + 0) / 4; math.Abs(r.Variance-want) > 1e-9 {
+		t.Errorf("Variance = %v, want %v", r.Variance, want)
+	}
+	if !r.Unstable {
+		t.Error("a pass and a fail should be unstable")
+	}
+
+	// An inconclusive result takes no side
+	c, _ = New(WithProfiles(
+		Profile{Name: "a", Metric: fixedMetric{Score: 0.9, Passed: true}},
+		Profile{Name: "b", Metric: fixedMetric{Inconclusive: true}},
+	))
+	if r := c.Compare(context.Background(), "x", "y"); r.Unstable {
+		t.Errorf("pass and inconclusive: %+v, want stable", r)
+	}
+}
+
+func TestCompareStopsWhenCancelled(t *testing.T) {
+	c, _ := New(WithProfiles(
+		Profile{Name: "a", Metric: fixedMetric{Score: 1}},
+		Profile{Name: "b", Metric: fixedMetric{Score: 1}},
+	))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if r := c.Compare(ctx, "x", "y"); !errors.Is(r.Err, context.Canceled) || len(r.Scores) != 0 {
+		t.Errorf("cancelled: %+v, want no scores and context.Canceled", r)
+	}
+}
+
+func TestNewRejectsInvalidProfiles(t *testing.T) {
+	one := Profile{Name: "a", Metric: fixedMetric{}}
+	for _, opt := range []Option{
+		WithProfiles(one),
+		WithProfiles(one, one),
+		WithProfiles(one, Profile{Name: "b"}),
+		WithThreshold(1.5),
+	} {
+		_, err := New(WithLogger(discardLogger(t)), opt)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("expected ConfigError, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package streaming
+
+import (
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
+)
+
+// Config is the plain-struct form of the options of both streaming
+// calculators, for driving them from JSON or YAML configuration. Zero values
+// keep the defaults. A logger cannot come from a file; pass it as an option.
+type Config struct {
+	Threshold    float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	MaxDiffRatio float64 `json:"max_diff_ratio,omitempty" yaml:"max_diff_ratio,omitempty"`
+	ChunkSize    int     `json:"chunk_size,omitempty" yaml:"chunk_size,omitempty"`
+	// Mode is "chunk", "line" or "word"
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// EmptyAugmented is "fail" or "score"
+	EmptyAugmented    string `json:"empty_augmented,omitempty" yaml:"empty_augmented,omitempty"`
+	MaxBytes          int64  `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
+	ReportResources   bool   `json:"report_resources,omitempty" yaml:"report_resources,omitempty"`
+	ReportUncertainty bool   `json:"report_uncertainty,omitempty" yaml:"report_uncertainty,omitempty"`
+	// The transforms run in this order when enabled
+	NormalizeLineEndings bool `json:"normalize_line_endings,omitempty" yaml:"normalize_line_endings,omitempty"`
+	// TabWidth expands tabs when set; 0 removes them
+	TabWidth         *int `json:"tab_width,omitempty" yaml:"tab_width,omitempty"`
+	StripIndentation bool `json:"strip_indentation,omitempty" yaml:"strip_indentation,omitempty"`
+	// JoinHyphenation joins words split across lines by a trailing hyphen
+	JoinHyphenation bool `json:"join_hyphenation,omitempty" yaml:"join_hyphenation,omitempty"`
+	// StripSubtitles removes SRT and WebVTT cue numbers and timing lines
+	StripSubtitles bool `json:"strip_subtitles,omitempty" yaml:"strip_subtitles,omitempty"`
+	// IgnoreTrailingNewline drops one line break at the end of each stream;
+	// unset keeps the default, on in line mode and for the allocation-efficient calculator
+	IgnoreTrailingNewline *bool `json:"ignore_trailing_newline,omitempty" yaml:"ignore_trailing_newline,omitempty"`
+
+	// Normalizer is "default", "fast", "optimized", "ocr" or "logs"; the allocation-efficient
+	// calculator always uses its own
+	Normalizer string `json:"normalizer,omitempty" yaml:"normalizer,omitempty"`
+	// Parallel, BatchSize, Precision and Details only apply to the
+	// allocation-efficient calculator
+	Parallel  *bool `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	BatchSize int   `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	// Precision rounds scores to this many decimal places when set
+	Precision *int `json:"precision,omitempty" yaml:"precision,omitempty"`
+	// Details is "full", "basic", "none" or "debug"
+	Details string `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// parsed holds the named settings of a Config after validation
+type parsed struct {
+	mode           StreamingMode
+	hasMode        bool
+	emptyAugmented EmptyAugmentedPolicy
+	hasEmpty       bool
+	details        DetailLevel
+	hasDetails     bool
+}
+
+func (c Config) parse() (parsed, error) {
+	var p parsed
+	switch c.Mode {
+	case "":
+	case "chunk":
+		p.mode, p.hasMode = ChunkByChunk, true
+	case "line":
+		p.mode, p.hasMode = LineByLine, true
+	case "word":
+		p.mode, p.hasMode = WordByWord, true
+	default:
+		return p, domain.NewConfigError("mode", c.Mode, "must be chunk, line or word")
+	}
+	switch c.EmptyAugmented {
+	case "":
+	case "fail":
+		p.emptyAugmented, p.hasEmpty = EmptyAugmentedFail, true
+	case "score":
+		p.emptyAugmented, p.hasEmpty = EmptyAugmentedScore, true
+	default:
+		return p, domain.NewConfigError("empty_augmented", c.EmptyAugmented, "must be fail or score")
+	}
+	switch c.Details {
+	case "":
+	case "full":
+		p.details, p.hasDetails = DetailsFull, true
+	case "basic":
+		p.details, p.hasDetails = DetailsBasic, true
+	case "none":
+		p.details, p.hasDetails = DetailsNone, true
+	case "debug":
+		p.details, p.hasDetails = DetailsDebug, true
+	default:
+		return p, domain.NewConfigError("details", c.Details, "must be full, basic, none or debug")
+	}
+	return p, nil
+}
+
+// Options returns the StreamingSimilarity options equivalent to c
+func (c Config) Options() ([]StreamingOption, error) {
+	p, err := c.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []StreamingOption
+	if c.Threshold != 0 {
+		opts = append(opts, WithStreamingThreshold(c.Threshold))
+	}
+	if c.MaxDiffRatio != 0 {
+		opts = append(opts, WithStreamingMaxDiffRatio(c.MaxDiffRatio))
+	}
+	if c.ChunkSize != 0 {
+		opts = append(opts, WithStreamingChunkSize(c.ChunkSize))
+	}
+	if p.hasMode {
+		opts = append(opts, WithStreamingMode(p.mode))
+	}
+	if p.hasEmpty {
+		opts = append(opts, WithEmptyAugmentedPolicy(p.emptyAugmented))
+	}
+	if c.MaxBytes != 0 {
+		opts = append(opts, WithMaxBytes(c.MaxBytes))
+	}
+	if c.ReportResources {
+		opts = append(opts, WithStreamingResourceReport(true))
+	}
+	if c.ReportUncertainty {
+		opts = append(opts, WithStreamingUncertainty(true))
+	}
+	if c.NormalizeLineEndings {
+		opts = append(opts, WithNormalizedLineEndings())
+	}
+	if c.TabWidth != nil {
+		opts = append(opts, WithTabExpansion(*c.TabWidth))
+	}
+	if c.StripIndentation {
+		opts = append(opts, WithStrippedIndentation())
+	}
+	if c.JoinHyphenation {
+		opts = append(opts, WithJoinedHyphenation())
+	}
+	if c.StripSubtitles {
+		opts = append(opts, WithStrippedSubtitles())
+	}
+	if c.IgnoreTrailingNewline != nil {
+		opts = append(opts, WithIgnoredTrailingNewline(*c.IgnoreTrailingNewline))
+	}
+	n, err := options.NormalizerNamed(c.Normalizer)
+	if err != nil {
+		return nil, err
+	}
+	if n != nil {
+		opts = append(opts, WithStreamingNormalizer(n))
+	}
+	return opts, nil
+}
+
+// EfficientOptions returns the AllocationEfficientStreamingSimilarity options
+// equivalent to c
+func (c Config) EfficientOptions() ([]AllocationEfficientOption, error) {
+	p, err := c.parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := options.NormalizerNamed(c.Normalizer); err != nil {
+		return nil, err
+	}
+
+	var opts []AllocationEfficientOption
+	if c.Threshold != 0 {
+		opts = append(opts, WithEfficientThreshold(c.Threshold))
+	}
+	if c.MaxDiffRatio != 0 {
+		opts = append(opts, WithEfficientMaxDiffRatio(c.MaxDiffRatio))
+	}
+	if c.ChunkSize != 0 {
+		opts = append(opts, WithEfficientChunkSize(c.ChunkSize))
+	}
+	if p.hasMode {
+		opts = append(opts, WithEfficientMode(p.mode))
+	}
+	if p.hasEmpty {
+		opts = append(opts, WithEfficientEmptyAugmentedPolicy(p.emptyAugmented))
+	}
+	if c.MaxBytes != 0 {
+		opts = append(opts, WithEfficientMaxBytes(c.MaxBytes))
+	}
+	if c.ReportResources {
+		opts = append(opts, WithEfficientResourceReport(true))
+	}
+	if c.ReportUncertainty {
+		opts = append(opts, WithEfficientUncertainty(true))
+	}
+	if c.NormalizeLineEndings {
+		opts = append(opts, WithEfficientNormalizedLineEndings())
+	}
+	if c.TabWidth != nil {
+		opts = append(opts, WithEfficientTabExpansion(*c.TabWidth))
+	}
+	if c.StripIndentation {
+		opts = append(opts, WithEfficientStrippedIndentation())
+	}
+	if c.JoinHyphenation {
+		opts = append(opts, WithEfficientJoinedHyphenation())
+	}
+	if c.StripSubtitles {
+		opts = append(opts, WithEfficientStrippedSubtitles())
+	}
+	if c.IgnoreTrailingNewline != nil {
+		opts = append(opts, WithEfficientIgnoredTrailingNewline(*c.IgnoreTrailingNewline))
+	}
+	if c.Parallel != nil {
+		opts = append(opts, WithEfficientParallel(*c.Parallel))
+	}
+	if c.BatchSize != 0 {
+		opts = append(opts, WithEfficientBatchSize(c.BatchSize))
+	}
+	if c.Precision != nil {
+		opts = append(opts, WithEfficientPrecision(*c.Precision))
+	}
+	if p.hasDetails {
+		opts = append(opts, WithEfficientDetails(p.details))
+	}
+	return opts, nil
+}
+
+// NewStreamingFromConfig creates a StreamingSimilarity from cfg. opts are
+// applied after cfg, for settings a file cannot hold such as the logger.
+func NewStreamingFromConfig(cfg Config, opts ...StreamingOption) (*StreamingSimilarity, error) {
+	configured, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamingSimilarity(append(configured, opts...)...)
+}
+
+// NewAllocationEfficientFromConfig creates an
+// AllocationEfficientStreamingSimilarity from cfg. opts are applied after cfg.
+func NewAllocationEfficientFromConfig(cfg Config, opts ...AllocationEfficientOption) (*AllocationEfficientStreamingSimilarity, error) {
+	configured, err := cfg.EfficientOptions()
+	if err != nil {
+		return nil, err
+	}
+	return NewAllocationEfficientStreamingSimilarity(append(configured, opts...)...)
+}
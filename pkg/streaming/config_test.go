@@ -0,0 +1,120 @@
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+func TestNewFromConfigBothCalculators(t *testing.T) {
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"threshold": 0.9, "mode": "word", "parallel": false}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := NewStreamingFromConfig(cfg, WithStreamingLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := NewAllocationEfficientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if es.config.Mode != ports.WordByWord || es.config.UseParallel {
+		t.Errorf("efficient config %+v does not follow the file", es.config)
+	}
+
+	ctx := context.Background()
+	for name, result := range map[string]StreamResult{
+		"streaming": ss.ComputeFromStrings(ctx, "one two", "one two"),
+		"efficient": es.ComputeFromStrings(ctx, "one two", "one two"),
+	} {
+		if result.Threshold != 0.9 {
+			t.Errorf("%s: threshold %v, want 0.9", name, result.Threshold)
+		}
+	}
+}
+
+func TestConfigRejectsUnknownNames(t *testing.T) {
+	for _, cfg := range []Config{{Mode: "page"}, {EmptyAugmented: "skip"}, {Normalizer: "turbo"}, {Details: "verbose"}} {
+		var configErr *ConfigError
+		if _, err := cfg.Options(); !errors.As(err, &configErr) {
+			t.Errorf("%+v: Options error %v, want a *ConfigError", cfg, err)
+		}
+		if _, err := cfg.EfficientOptions(); !errors.As(err, &configErr) {
+			t.Errorf("%+v: EfficientOptions error %v, want a *ConfigError", cfg, err)
+		}
+	}
+}
+
+func TestEfficientPrecisionAndDetails(t *testing.T) {
+	ctx := context.Background()
+	original, augmented := "abcdefghijklm\nnopqrstuvwxyz\n", "abcdefghijklm\nnopqrstuvw\n"
+
+	unrounded, err := NewAllocationEfficientStreamingSimilarity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"precision": 2, "details": "basic"}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	rounded, err := NewAllocationEfficientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := unrounded.ComputeFromStrings(ctx, original, augmented)
+	got := rounded.ComputeFromStrings(ctx, original, augmented)
+	if want.Score == scoring.Round(want.Score, 2) {
+		t.Fatalf("score %v needs no rounding; pick texts that do", want.Score)
+	}
+	if got.Score != scoring.Round(want.Score, 2) || got.LengthRatio != scoring.Round(want.LengthRatio, 2) {
+		t.Errorf("rounded to 2 places: score %v, ratio %v; want %v, %v",
+			got.Score, got.LengthRatio, scoring.Round(want.Score, 2), scoring.Round(want.LengthRatio, 2))
+	}
+	if _, ok := want.Details["original_line_lengths"]; !ok {
+		t.Errorf("full details lack line lengths: %v", want.Details)
+	}
+	if _, ok := got.Details["original_line_lengths"]; ok || got.Details["original_length"] != got.OriginalLength {
+		t.Errorf("basic details = %v, want the counts without line lengths", got.Details)
+	}
+
+	none, err := NewAllocationEfficientStreamingSimilarity(WithEfficientDetails(DetailsNone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := none.ComputeFromStrings(ctx, original, augmented); result.Details != nil || result.Score != want.Score {
+		t.Errorf("no details: score %v, details %v; want %v and nil", result.Score, result.Details, want.Score)
+	}
+
+	var debugCfg Config
+	if err := json.Unmarshal([]byte(`{"details": "debug"}`), &debugCfg); err != nil {
+		t.Fatal(err)
+	}
+	debug, err := NewAllocationEfficientFromConfig(debugCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := debug.ComputeFromStrings(ctx, original, augmented)
+	firstHits, _ := first.Details["pool_hits"].(int64)
+	firstMisses, _ := first.Details["pool_misses"].(int64)
+	if _, ok := first.Details["original_line_lengths"]; !ok || firstHits+firstMisses == 0 || first.Resources != nil {
+		t.Errorf("debug details = %v, resources %v; want full details with pool counts and no resources", first.Details, first.Resources)
+	}
+	// The second comparison reuses the buffers the first returned
+	if second := debug.ComputeFromStrings(ctx, original, augmented); second.Details["pool_hits"].(int64) == 0 {
+		t.Errorf("second comparison details = %v, want pool hits", second.Details)
+	}
+
+	for _, opt := range []AllocationEfficientOption{WithEfficientPrecision(-2), WithEfficientDetails(DetailLevel(7))} {
+		var configErr *ConfigError
+		if _, err := NewAllocationEfficientStreamingSimilarity(opt); !errors.As(err, &configErr) {
+			t.Errorf("error %v, want a *ConfigError", err)
+		}
+	}
+}
@@ -4,13 +4,23 @@ package streaming
 import (
 	"context"
 	"io"
+	"io/fs"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/clock"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream/lineprocessor"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/transform"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
-	"github.com/baditaflorin/l"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+	"github.com/baditaflorin/go_length_similarity/internal/sketch"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
 )
 
 // AllocationEfficientStreamingSimilarity provides a highly optimized streaming similarity implementation
@@ -20,6 +30,9 @@ type AllocationEfficientStreamingSimilarity struct {
 	byteNormalizer normalizer.ByteNormalizer
 	lineProcessor  *lineprocessor.OptimizedProcessor
 	config         AllocationEfficientConfig
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // AllocationEfficientConfig holds configuration for the allocation-efficient streaming similarity
@@ -30,6 +43,101 @@ type AllocationEfficientConfig struct {
 	Mode         ports.StreamingMode
 	UseParallel  bool
 	BatchSize    int
+	// Precision is the number of decimal places kept in Score and LengthRatio;
+	// NoRounding keeps them unrounded
+	Precision int
+	// Details selects how much StreamResult.Details reports
+	Details DetailLevel
+	// EmptyAugmented controls how an empty augmented stream is scored
+	EmptyAugmented EmptyAugmentedPolicy
+	// ReportResources fills StreamResult.Resources
+	ReportResources bool
+	// ReportUncertainty fills StreamResult.Uncertainty
+	ReportUncertainty bool
+	// MaxBytes aborts a comparison once either stream exceeds this many bytes (0 = no limit)
+	MaxBytes int64
+	// Transforms rewrite both streams before they are processed
+	Transforms []ports.Transform
+	// Logger receives the calculator's log output (nil = no logging)
+	Logger ports.Logger
+	// Clock times each comparison for ProcessingTime (nil = wall clock)
+	Clock ports.Clock
+	// Metrics records every comparison and the bytes read (nil = none)
+	Metrics ports.Metrics
+	// Scorer turns the two line counts into a score (nil = the default formula)
+	Scorer Scorer
+	// IgnoreTrailingNewline drops one line break at the end of each stream.
+	// Nil means the same default as StreamingConfig: on in line mode only.
+	IgnoreTrailingNewline *bool
+}
+
+// NoRounding disables precision rounding, the streaming calculators' default
+const NoRounding = scoring.NoRounding
+
+// DetailLevel selects how much StreamResult.Details reports
+type DetailLevel int
+
+const (
+	// DetailsFull reports the lengths, settings, bytes processed and, in line
+	// mode, a summary of each side's line lengths
+	DetailsFull DetailLevel = iota
+	// DetailsBasic leaves out the line length summaries, which also skips
+	// sketching the line lengths while counting
+	DetailsBasic
+	// DetailsNone leaves Details nil except for errors and a reached input limit
+	DetailsNone
+	// DetailsDebug reports the full details plus pool_hits and pool_misses:
+	// how many buffers the comparison reused from the processor's pools and
+	// how many it had to allocate. Constant misses mean the chunk size or
+	// parallelism gives the pools nothing to reuse.
+	DetailsDebug
+)
+
+// defaultBatchSize is the number of lines handed to a worker at a time
+const defaultBatchSize = 100
+
+// DefaultEfficientConfig returns the configuration
+// NewAllocationEfficientStreamingSimilarity starts from
+func DefaultEfficientConfig() AllocationEfficientConfig {
+	return AllocationEfficientConfig{
+		Threshold:    scoring.DefaultThreshold,
+		MaxDiffRatio: scoring.DefaultMaxDiffRatio,
+		ChunkSize:    stream.DefaultModeChunkSize(ports.LineByLine),
+		Mode:         ports.LineByLine,
+		UseParallel:  true,
+		BatchSize:    defaultBatchSize,
+		Precision:    NoRounding,
+		Details:      DetailsFull,
+	}
+}
+
+// Validate checks if the configuration is valid
+func (c AllocationEfficientConfig) Validate() error {
+	if err := domain.ValidateThreshold(c.Threshold); err != nil {
+		return err
+	}
+	if err := domain.ValidateMaxDiffRatio(c.MaxDiffRatio); err != nil {
+		return err
+	}
+	if c.ChunkSize < 0 {
+		return domain.NewConfigError("chunkSize", c.ChunkSize, "must not be negative")
+	}
+	if c.BatchSize < 0 {
+		return domain.NewConfigError("batchSize", c.BatchSize, "must not be negative")
+	}
+	if c.Precision < NoRounding {
+		return domain.NewConfigError("precision", c.Precision, "must not be negative, except NoRounding")
+	}
+	if c.Details < DetailsFull || c.Details > DetailsDebug {
+		return domain.NewConfigError("details", c.Details, "must be DetailsFull, DetailsBasic, DetailsNone or DetailsDebug")
+	}
+	if c.MaxBytes < 0 {
+		return domain.NewConfigError("maxBytes", c.MaxBytes, "must not be negative")
+	}
+	if err := transform.Validate(c.Transforms); err != nil {
+		return err
+	}
+	return nil
 }
 
 // AllocationEfficientOption defines a functional option for configuring AllocationEfficientStreamingSimilarity
@@ -56,7 +164,9 @@ func WithEfficientChunkSize(size int) AllocationEfficientOption {
 	}
 }
 
-// WithEfficientMode sets a custom streaming mode
+// WithEfficientMode sets a custom streaming mode. The allocation-efficient
+// calculator always counts lines, so results report line mode whatever is set;
+// the mode still selects the trailing newline default.
 func WithEfficientMode(mode StreamingMode) AllocationEfficientOption {
 	return func(cfg *AllocationEfficientConfig) {
 		cfg.Mode = ports.StreamingMode(mode)
@@ -77,21 +187,180 @@ func WithEfficientBatchSize(size int) AllocationEfficientOption {
 	}
 }
 
-// NewAllocationEfficientStreamingSimilarity creates a new allocation-efficient streaming similarity calculator
-func NewAllocationEfficientStreamingSimilarity(logger l.Logger, opts ...AllocationEfficientOption) (*AllocationEfficientStreamingSimilarity, error) {
-	// Default configuration
-	config := &AllocationEfficientConfig{
-		Threshold:    0.7,
-		MaxDiffRatio: 0.3,
-		ChunkSize:    64 * 1024, // 64KB chunks
-		Mode:         ports.LineByLine,
-		UseParallel:  true,
-		BatchSize:    100,
+// WithEfficientPrecision rounds Score and LengthRatio to p decimal places.
+// NoRounding, the default, keeps them unrounded.
+func WithEfficientPrecision(p int) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Precision = p
+	}
+}
+
+// WithEfficientDetails sets how much StreamResult.Details reports
+func WithEfficientDetails(level DetailLevel) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Details = level
+	}
+}
+
+// WithEfficientEmptyAugmentedPolicy sets how an empty augmented stream is scored
+func WithEfficientEmptyAugmentedPolicy(policy EmptyAugmentedPolicy) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.EmptyAugmented = policy
 	}
+}
+
+// WithEfficientMaxBytes aborts a comparison once either stream exceeds n bytes,
+// reporting a *MaxBytesError in StreamResult.Err. 0 means no limit.
+func WithEfficientMaxBytes(n int64) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.MaxBytes = n
+	}
+}
+
+// WithEfficientNormalizedLineEndings treats CRLF, CR and LF line endings as
+// equal by converting them all to LF before counting
+func WithEfficientNormalizedLineEndings() AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.LineEndings())
+	}
+}
+
+// WithEfficientTabExpansion replaces tabs with spaces up to the next multiple of
+// width columns before counting. A width of 0 removes tabs.
+func WithEfficientTabExpansion(width int) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.ExpandTabs(width))
+	}
+}
+
+// WithEfficientStrippedIndentation drops leading spaces and tabs from every line before counting
+func WithEfficientStrippedIndentation() AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripIndentation())
+	}
+}
+
+// WithEfficientJoinedHyphenation joins words split across lines by a trailing
+// hyphen, as OCR and PDF extraction leave them, before counting
+func WithEfficientJoinedHyphenation() AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.JoinHyphenation())
+	}
+}
+
+// WithEfficientStrippedSubtitles removes SRT and WebVTT cue numbers, timing
+// lines and the WEBVTT header before counting
+func WithEfficientStrippedSubtitles() AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripSubtitles())
+	}
+}
+
+// WithEfficientIgnoredTrailingNewline sets whether one line break at the very
+// end of each stream is dropped before counting, so a final newline does not
+// change the count. Line mode, the default, drops it unless told otherwise.
+func WithEfficientIgnoredTrailingNewline(ignore bool) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.IgnoreTrailingNewline = &ignore
+	}
+}
+
+// WithEfficientResourceReport fills StreamResult.Resources with an estimate of the
+// allocations, the peak buffer size and the number of workers each comparison used
+func WithEfficientResourceReport(enable bool) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.ReportResources = enable
+	}
+}
+
+// WithEfficientUncertainty fills StreamResult.Uncertainty with how far the score
+// can move per unit of length difference
+func WithEfficientUncertainty(enable bool) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.ReportUncertainty = enable
+	}
+}
+
+// WithEfficientLogger sets the logger; without it the calculator does not log
+func WithEfficientLogger(logger ports.Logger) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Logger = logger
+	}
+}
+
+// WithEfficientOptions applies settings shared with the other calculators.
+// The calculator always uses its own byte normalizer and does not warm up,
+// so WithNormalizer and WithWarmUp are ignored.
+func WithEfficientOptions(opts ...options.Option) AllocationEfficientOption {
+	common := options.Apply(opts...)
+	return func(cfg *AllocationEfficientConfig) {
+		if common.Threshold != nil {
+			cfg.Threshold = *common.Threshold
+		}
+		if common.MaxDiffRatio != nil {
+			cfg.MaxDiffRatio = *common.MaxDiffRatio
+		}
+		if common.Logger != nil {
+			cfg.Logger = common.Logger
+		}
+		if common.Clock != nil {
+			cfg.Clock = common.Clock
+		}
+		if common.Metrics != nil {
+			cfg.Metrics = common.Metrics
+		}
+	}
+}
+
+// WithEfficientClock sets the clock that ProcessingTime is measured on, so
+// tests can make it deterministic; nil uses the wall clock
+func WithEfficientClock(c ports.Clock) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Clock = c
+	}
+}
+
+// WithEfficientMetrics records every comparison, by outcome and latency, and
+// the bytes read to m
+func WithEfficientMetrics(m ports.Metrics) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Metrics = m
+	}
+}
+
+// WithEfficientScorer scores the two line counts with s instead of the
+// default formula (see WithStreamingScorer)
+func WithEfficientScorer(s Scorer) AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Scorer = s
+	}
+}
+
+// NewAllocationEfficientStreamingSimilarity creates a new allocation-efficient streaming similarity calculator
+func NewAllocationEfficientStreamingSimilarity(opts ...AllocationEfficientOption) (*AllocationEfficientStreamingSimilarity, error) {
+	config := DefaultEfficientConfig()
 
 	// Apply options
 	for _, opt := range opts {
-		opt(config)
+		opt(&config)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Discard log output unless a logger was provided
+	if config.Logger == nil {
+		config.Logger = logger.NewNopLogger()
+	}
+	config.Clock = clock.OrSystem(config.Clock)
+	// Line mode ignores a final newline unless told otherwise, as in NewStreamingSimilarity
+	ignoreTrailingNewline := config.Mode == ports.LineByLine
+	if config.IgnoreTrailingNewline != nil {
+		ignoreTrailingNewline = *config.IgnoreTrailingNewline
+	}
+	if ignoreTrailingNewline {
+		config.Transforms = append(config.Transforms[:len(config.Transforms):len(config.Transforms)], transform.TrimTrailingNewline())
 	}
 
 	// Create the allocation-efficient normalizer
@@ -100,7 +369,7 @@ func NewAllocationEfficientStreamingSimilarity(logger l.Logger, opts ...Allocati
 
 	// Create the optimized line processor
 	lineProc := lineprocessor.NewOptimizedProcessor(
-		logger,
+		config.Logger,
 		byteNorm.(ports.Normalizer),
 		lineprocessor.ProcessingConfig{
 			ChunkSize:   config.ChunkSize,
@@ -110,42 +379,95 @@ func NewAllocationEfficientStreamingSimilarity(logger l.Logger, opts ...Allocati
 	)
 
 	return &AllocationEfficientStreamingSimilarity{
-		logger:         logger,
+		logger:         config.Logger,
 		normalizer:     byteNorm.(ports.Normalizer),
 		byteNormalizer: byteNorm,
 		lineProcessor:  lineProc,
-		config:         *config,
+		config:         config,
 	}, nil
 }
 
+// Close releases the line processor's pooled buffers and flushes the logger.
+// The logger belongs to the caller and is not closed. Close is safe to call
+// more than once; do not use the calculator afterwards.
+func (aes *AllocationEfficientStreamingSimilarity) Close() error {
+	aes.closeOnce.Do(func() {
+		aes.lineProcessor.Release()
+		aes.closeErr = logger.Release(aes.logger, false)
+	})
+	return aes.closeErr
+}
+
 // ComputeFromReaders calculates the streaming similarity between two text readers
 func (aes *AllocationEfficientStreamingSimilarity) ComputeFromReaders(ctx context.Context, original io.Reader, augmented io.Reader) StreamResult {
-	startTime := time.Now()
+	var pr *probe.Probe
+	if aes.config.ReportResources || aes.config.Details == DetailsDebug {
+		pr = probe.Start()
+		ctx = probe.NewContext(ctx, pr)
+	}
+
+	var start time.Time
+	if aes.config.Metrics != nil {
+		start = aes.config.Clock.Now()
+	}
+	result := aes.computeFromReaders(ctx, original, augmented)
+	// Counting is always by lines, whatever mode was configured
+	result.Engine, result.Mode = domain.EngineEfficient, ports.LineByLine.String()
+	if aes.config.Metrics != nil {
+		recordStreamResult(aes.config.Metrics, result, aes.config.Clock.Now().Sub(start))
+	}
+	if aes.config.ReportResources {
+		result.Resources = pr.Finish()
+	}
+	if aes.config.Details == DetailsDebug && result.Details != nil {
+		result.Details["pool_hits"], result.Details["pool_misses"] = pr.PoolGets()
+	}
+	return withUncertainty(result, aes.config.ReportUncertainty, aes.config.MaxDiffRatio)
+}
+
+// ComputeFromFS opens the files at original and augmented in fsys and streams
+// them like ComputeFromReaders. The error reports a file that could not be opened.
+func (aes *AllocationEfficientStreamingSimilarity) ComputeFromFS(ctx context.Context, fsys fs.FS, original, augmented string) (StreamResult, error) {
+	orig, aug, err := stream.OpenPair(fsys, original, augmented)
+	if err != nil {
+		return StreamResult{}, err
+	}
+	defer orig.Close()
+	defer aug.Close()
+
+	return aes.ComputeFromReaders(ctx, orig, aug), nil
+}
+
+// computeFromReaders runs ComputeFromReaders without resource reporting
+func (aes *AllocationEfficientStreamingSimilarity) computeFromReaders(ctx context.Context, original io.Reader, augmented io.Reader) StreamResult {
+	startTime := aes.config.Clock.Now()
+	callerOriginal, callerAugmented := original, augmented
+
+	// Bound unknown-length inputs and read them in full buffers, so results
+	// do not depend on how the producer writes
+	original = stream.FillReader(transform.Readers(stream.LimitReader(original, aes.config.MaxBytes), aes.config.Transforms))
+	augmented = stream.FillReader(transform.Readers(stream.LimitReader(augmented, aes.config.MaxBytes), aes.config.Transforms))
+
+	// Sketch each side's line lengths for the full details
+	var origLines, augLines *sketch.TDigest
+	origCtx, augCtx := ctx, ctx
+	if aes.config.Details == DetailsFull || aes.config.Details == DetailsDebug {
+		origLines, augLines = sketch.New(sketch.DefaultCompression), sketch.New(sketch.DefaultCompression)
+		origCtx, augCtx = sketch.NewContext(ctx, origLines), sketch.NewContext(ctx, augLines)
+	}
 
 	// Process original text stream
-	origCount, origBytes, err := aes.lineProcessor.ProcessLines(ctx, original, nil)
+	origCount, origBytes, err := aes.lineProcessor.ProcessLines(origCtx, original, nil)
 	if err != nil && err != io.EOF {
 		aes.logger.Error("Error processing original stream", "error", err)
-		return StreamResult{
-			Name:           "streaming_similarity",
-			Score:          0,
-			Passed:         false,
-			Details:        map[string]interface{}{"error": "error processing original stream: " + err.Error()},
-			ProcessingTime: time.Since(startTime).String(),
-		}
+		return toStreamResult(stream.ErrorResult("original", err, make(map[string]interface{}), aes.config.Clock.Now().Sub(startTime)))
 	}
 
 	// Process augmented text stream
-	augCount, augBytes, err := aes.lineProcessor.ProcessLines(ctx, augmented, nil)
+	augCount, augBytes, err := aes.lineProcessor.ProcessLines(augCtx, augmented, nil)
 	if err != nil && err != io.EOF {
 		aes.logger.Error("Error processing augmented stream", "error", err)
-		return StreamResult{
-			Name:           "streaming_similarity",
-			Score:          0,
-			Passed:         false,
-			Details:        map[string]interface{}{"error": "error processing augmented stream: " + err.Error()},
-			ProcessingTime: time.Since(startTime).String(),
-		}
+		return toStreamResult(stream.ErrorResult("augmented", err, make(map[string]interface{}), aes.config.Clock.Now().Sub(startTime)))
 	}
 
 	// Calculate similarity using the similar algorithm as the regular version
@@ -163,42 +485,49 @@ func (aes *AllocationEfficientStreamingSimilarity) ComputeFromReaders(ctx contex
 		lengthRatio = 0.0
 		score = 0.0
 		passed = false
+	} else if augCount == 0 && aes.config.EmptyAugmented == EmptyAugmentedFail {
+		// Augmented text is empty
+		lengthRatio = 0.0
+		score = 0.0
+		passed = false
 	} else {
 		// Standard calculation
-		if origCount > augCount {
-			lengthRatio = float64(augCount) / float64(origCount)
-		} else {
-			lengthRatio = float64(origCount) / float64(augCount)
-		}
-
-		diff := float64(origCount - augCount)
-		if diff < 0 {
-			diff = -diff
-		}
+		outcome := scoring.Apply(aes.config.Scorer, origCount, augCount, scoring.Config{
+			Threshold:    aes.config.Threshold,
+			MaxDiffRatio: aes.config.MaxDiffRatio,
+			Precision:    aes.config.Precision,
+		})
+		lengthRatio = outcome.LengthRatio
+		score = outcome.Score
+		passed = outcome.Passed
+	}
 
-		diffRatio := diff / (float64(origCount) * aes.config.MaxDiffRatio)
-		if diffRatio > 1.0 {
-			diffRatio = 1.0
+	var details map[string]interface{}
+	if aes.config.Details != DetailsNone {
+		details = map[string]interface{}{
+			"original_length":           origCount,
+			"augmented_length":          augCount,
+			"length_ratio":              lengthRatio,
+			"threshold":                 aes.config.Threshold,
+			"mode":                      ports.LineByLine,
+			"parallel":                  aes.config.UseParallel,
+			"bytes_processed_original":  origBytes,
+			"bytes_processed_augmented": augBytes,
 		}
-
-		score = 1.0 - diffRatio
-		passed = score >= aes.config.Threshold
+		stream.AddLineLengths(details, origLines, augLines)
 	}
 
-	// Create detailed result
-	details := map[string]interface{}{
-		"original_length":           origCount,
-		"augmented_length":          augCount,
-		"length_ratio":              lengthRatio,
-		"threshold":                 aes.config.Threshold,
-		"mode":                      aes.config.Mode,
-		"parallel":                  aes.config.UseParallel,
-		"bytes_processed_original":  origBytes,
-		"bytes_processed_augmented": augBytes,
+	// Inputs the caller wrapped in io.LimitReader may have been cut at the cap
+	limitReached := stream.LimitReached(callerOriginal, callerAugmented)
+	if limitReached != "" {
+		if details == nil {
+			details = make(map[string]interface{})
+		}
+		details["limit_reached"] = limitReached
 	}
 
 	totalBytes := origBytes + augBytes
-	duration := time.Since(startTime)
+	duration := aes.config.Clock.Now().Sub(startTime)
 
 	aes.logger.Debug("Computed allocation-efficient streaming similarity",
 		"score", score,
@@ -208,16 +537,17 @@ func (aes *AllocationEfficientStreamingSimilarity) ComputeFromReaders(ctx contex
 	)
 
 	return StreamResult{
-		Name:            "streaming_similarity",
-		Score:           score,
-		Passed:          passed,
-		OriginalLength:  origCount,
-		AugmentedLength: augCount,
-		LengthRatio:     lengthRatio,
-		Threshold:       aes.config.Threshold,
-		ProcessingTime:  duration.String(),
-		BytesProcessed:  totalBytes,
-		Details:         details,
+		Name:                 "streaming_similarity",
+		Score:                score,
+		Passed:               passed,
+		OriginalLength:       origCount,
+		AugmentedLength:      augCount,
+		LengthRatio:          lengthRatio,
+		Threshold:            aes.config.Threshold,
+		ProcessingTime:       duration.String(),
+		BytesProcessed:       totalBytes,
+		Details:              details,
+		PotentiallyTruncated: limitReached != "",
 	}
 }
 
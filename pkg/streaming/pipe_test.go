@@ -0,0 +1,188 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/baditaflorin/l"
+)
+
+func discardLogger(t *testing.T) l.Logger {
+	t.Helper()
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+// pipeOf streams text through an io.Pipe in writes of the given size, so the
+// reader has no known length and every Read returns a short chunk
+func pipeOf(text string, writeSize int) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		for len(text) > 0 {
+			n := min(writeSize, len(text))
+			if _, err := pw.Write([]byte(text[:n])); err != nil {
+				return
+			}
+			text = text[n:]
+		}
+		pw.Close()
+	}()
+	return pr
+}
+
+// pipeText has multi-byte runes and long lines so odd write sizes split both
+var pipeText = strings.Repeat("Ünïcödé lines — with dashes, commas and emoji 🚀 that span writes\r\n", 300)
+
+func TestPipeMatchesStrings(t *testing.T) {
+	ctx := context.Background()
+	augmented := pipeText[:len(pipeText)*9/10]
+
+	for _, mode := range []StreamingMode{ChunkByChunk, LineByLine, WordByWord} {
+		ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingMode(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := ss.ComputeFromStrings(ctx, pipeText, augmented)
+
+		for _, writeSize := range []int{1, 7, 4093} {
+			got := ss.ComputeFromReaders(ctx, pipeOf(pipeText, writeSize), pipeOf(augmented, writeSize))
+			if got.Err != nil || got.OriginalLength != want.OriginalLength || got.AugmentedLength != want.AugmentedLength {
+				t.Errorf("mode %d, writes of %d: got %d/%d (err %v), want %d/%d",
+					mode, writeSize, got.OriginalLength, got.AugmentedLength, got.Err, want.OriginalLength, want.AugmentedLength)
+			}
+		}
+	}
+
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := aes.ComputeFromStrings(ctx, pipeText, augmented)
+	got := aes.ComputeFromReaders(ctx, pipeOf(pipeText, 7), pipeOf(augmented, 7))
+	if got.Err != nil || got.OriginalLength != want.OriginalLength || got.AugmentedLength != want.AugmentedLength {
+		t.Errorf("efficient: got %d/%d (err %v), want %d/%d",
+			got.OriginalLength, got.AugmentedLength, got.Err, want.OriginalLength, want.AugmentedLength)
+	}
+}
+
+func TestPipeProducerError(t *testing.T) {
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	producerErr := errors.New("upstream reset")
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("partial body without a trailing newline"))
+		pw.CloseWithError(producerErr)
+	}()
+
+	result := ss.ComputeFromReaders(context.Background(), pr, strings.NewReader("text"))
+	if !errors.Is(result.Err, producerErr) || result.Passed || result.TruncatedInput {
+		t.Fatalf("expected the producer error, got %+v", result)
+	}
+}
+
+func TestTruncatedInput(t *testing.T) {
+	ctx := context.Background()
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A body that stops short of its declared length, as net/http reports it
+	truncated := func() io.Reader {
+		pr, pw := io.Pipe()
+		go func() {
+			_, _ = pw.Write([]byte(pipeText[:len(pipeText)/3]))
+			pw.CloseWithError(io.ErrUnexpectedEOF)
+		}()
+		return pr
+	}
+
+	for name, compute := range map[string]func(o, a io.Reader) StreamResult{
+		"streaming": func(o, a io.Reader) StreamResult { return ss.ComputeFromReaders(ctx, o, a) },
+		"efficient": func(o, a io.Reader) StreamResult { return aes.ComputeFromReaders(ctx, o, a) },
+	} {
+		result := compute(strings.NewReader(pipeText), truncated())
+		if !result.TruncatedInput || result.Passed || result.Details["truncated_input"] != "augmented" {
+			t.Errorf("%s: truncated augmented stream not flagged: %+v", name, result)
+		}
+	}
+}
+
+func TestLimitReaderReached(t *testing.T) {
+	ctx := context.Background()
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, compute := range map[string]func(o, a io.Reader) StreamResult{
+		"streaming": func(o, a io.Reader) StreamResult { return ss.ComputeFromReaders(ctx, o, a) },
+		"efficient": func(o, a io.Reader) StreamResult { return aes.ComputeFromReaders(ctx, o, a) },
+	} {
+		// The cap cuts the augmented text short
+		result := compute(strings.NewReader(pipeText), io.LimitReader(strings.NewReader(pipeText), 100))
+		if !result.PotentiallyTruncated || result.Details["limit_reached"] != "augmented" || result.Err != nil {
+			t.Errorf("%s: capped augmented stream not flagged: %+v", name, result)
+		}
+
+		// A stream that ends before the cap is complete
+		result = compute(io.LimitReader(strings.NewReader(pipeText), int64(len(pipeText)+1)), strings.NewReader(pipeText))
+		if result.PotentiallyTruncated || result.Details["limit_reached"] != nil {
+			t.Errorf("%s: stream shorter than its cap flagged: %+v", name, result)
+		}
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	limit := int64(len(pipeText) / 2)
+
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithMaxBytes(limit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientLogger(discardLogger(t)), WithEfficientMaxBytes(limit))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, compute := range map[string]func(o, a io.Reader) StreamResult{
+		"streaming": func(o, a io.Reader) StreamResult { return ss.ComputeFromReaders(ctx, o, a) },
+		"efficient": func(o, a io.Reader) StreamResult { return aes.ComputeFromReaders(ctx, o, a) },
+	} {
+		result := compute(pipeOf(pipeText, 512), strings.NewReader("short"))
+		var maxErr *MaxBytesError
+		if !errors.As(result.Err, &maxErr) || maxErr.Limit != limit {
+			t.Errorf("%s: expected *MaxBytesError, got %v", name, result.Err)
+		}
+
+		// Exactly at the limit is allowed
+		exact := pipeText[:limit]
+		if result := compute(pipeOf(exact, 512), strings.NewReader(exact)); result.Err != nil {
+			t.Errorf("%s: input at the limit failed: %v", name, result.Err)
+		}
+	}
+
+	if _, err := NewStreamingSimilarity(WithMaxBytes(-1)); err == nil {
+		t.Error("negative limit accepted")
+	}
+}
@@ -0,0 +1,29 @@
+package streaming
+
+import (
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+// largeFileChunkSize is the read size used by the LargeFiles presets
+const largeFileChunkSize = 256 * 1024
+
+// LargeFiles is a preset for multi-megabyte inputs: line-by-line counting with
+// 256KB reads and the optimized normalizer. Later options override individual settings.
+func LargeFiles() StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Mode = ports.LineByLine
+		cfg.ChunkSize = largeFileChunkSize
+		cfg.Normalizer = normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OptimizedNormalizerType)
+	}
+}
+
+// EfficientLargeFiles is the LargeFiles preset for the allocation-efficient
+// engine, adding parallel line processing.
+func EfficientLargeFiles() AllocationEfficientOption {
+	return func(cfg *AllocationEfficientConfig) {
+		cfg.Mode = ports.LineByLine
+		cfg.ChunkSize = largeFileChunkSize
+		cfg.UseParallel = true
+	}
+}
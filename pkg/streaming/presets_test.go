@@ -0,0 +1,23 @@
+package streaming
+
+import (
+	"testing"
+
+	"github.com/baditaflorin/go_length_similarity/internal/ports"
+)
+
+func TestLargeFilesPresets(t *testing.T) {
+	var cfg streamingConfig
+	LargeFiles()(&cfg)
+	if cfg.Mode != ports.LineByLine || cfg.ChunkSize != 256*1024 || cfg.Normalizer == nil {
+		t.Errorf("LargeFiles: mode %v, chunk size %d, normalizer %v; want line mode, 256KB and a normalizer",
+			cfg.Mode, cfg.ChunkSize, cfg.Normalizer)
+	}
+
+	var efficient AllocationEfficientConfig
+	EfficientLargeFiles()(&efficient)
+	if efficient.Mode != ports.LineByLine || efficient.ChunkSize != 256*1024 || !efficient.UseParallel {
+		t.Errorf("EfficientLargeFiles: mode %v, chunk size %d, parallel %v; want line mode, 256KB and parallel",
+			efficient.Mode, efficient.ChunkSize, efficient.UseParallel)
+	}
+}
@@ -3,12 +3,21 @@ package streaming
 import (
 	"context"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/metrics"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/transform"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
 	"github.com/baditaflorin/l"
 	"io"
+	"io/fs"
 	"strings"
+	"sync"
+	"time"
 )
 
 // StreamingMode represents different modes for processing input streams
@@ -23,9 +32,55 @@ const (
 	WordByWord
 )
 
+// EmptyAugmentedPolicy controls how an empty augmented stream is scored when the original is not empty
+type EmptyAugmentedPolicy int
+
+const (
+	// EmptyAugmentedFail scores an empty augmented stream as 0 and never passes (default).
+	// This mirrors the handling of an empty original stream.
+	EmptyAugmentedFail EmptyAugmentedPolicy = iota
+	// EmptyAugmentedScore applies the regular length formula. With a MaxDiffRatio of 1
+	// or more this can produce a nonzero score for an empty augmented stream.
+	EmptyAugmentedScore
+)
+
+// ConfigError reports an invalid option value passed to a streaming constructor
+type ConfigError = domain.ConfigError
+
+// MaxBytesError is the error reported in StreamResult.Err when a stream exceeds its WithMaxBytes limit
+type MaxBytesError = domain.MaxBytesError
+
+// Resources reports what one comparison cost, when resource reporting is enabled
+type Resources = domain.Resources
+
+// Uncertainty describes how coarse a score is given the input sizes, when uncertainty reporting is enabled
+type Uncertainty = domain.Uncertainty
+
+// Scorer turns a pair of counts into a score; see WithStreamingScorer
+type Scorer = scoring.Scorer
+
+// ScorerFunc adapts a function to Scorer
+type ScorerFunc = scoring.ScorerFunc
+
+// ScoringConfig is what a Scorer is given: the threshold, the max diff ratio
+// and the precision the calculator rounds to (NoRounding by default)
+type ScoringConfig = scoring.Config
+
+// Outcome is what a Scorer returns
+type Outcome = scoring.Outcome
+
+// Evaluate is the default scoring formula, for scorers that adjust it
+func Evaluate(origCount, augCount int, cfg ScoringConfig) Outcome {
+	return scoring.Evaluate(origCount, augCount, cfg)
+}
+
 // StreamResult represents the result of a streaming similarity computation
 type StreamResult struct {
-	Name            string
+	Name string
+	// Engine is "streaming" or "efficient", the calculator that produced the result
+	Engine string
+	// Mode is the streaming mode: "chunk", "line" or "word"
+	Mode            string
 	Score           float64
 	Passed          bool
 	OriginalLength  int
@@ -35,24 +90,62 @@ type StreamResult struct {
 	ProcessingTime  string // Duration as string for easy display
 	BytesProcessed  int64
 	Details         map[string]interface{}
+	// Resources is nil unless resource reporting is enabled
+	Resources *Resources
+	// Err is the read or processing error that aborted the comparison, if any.
+	// Use errors.As with *MaxBytesError to detect an exceeded WithMaxBytes limit.
+	Err error
+	// TruncatedInput is set when a stream ended unexpectedly (io.ErrUnexpectedEOF),
+	// such as a body shorter than its Content-Length or a cut-off gzip stream.
+	// The score is then 0 and says nothing about similarity.
+	TruncatedInput bool
+	// PotentiallyTruncated is set when an input was wrapped in io.LimitReader
+	// and read up to its cap. The stream may have held more data, so the score
+	// may not reflect the full input; Details["limit_reached"] names the side.
+	PotentiallyTruncated bool
+	// Uncertainty is nil unless uncertainty reporting is enabled
+	Uncertainty *Uncertainty
 }
 
 // StreamingSimilarity provides methods for streaming similarity computation
 type StreamingSimilarity struct {
-	calculator *stream.StreamingCalculator
-	logger     ports.Logger
+	calculator   *stream.StreamingCalculator
+	mode         ports.StreamingMode
+	logger       ports.Logger
+	resources    bool
+	uncertainty  bool
+	maxDiffRatio float64
+	// metrics is nil unless WithStreamingMetrics is set
+	metrics ports.Metrics
+
+	// ownsLogger is set when the constructor created the logger, so Close closes it
+	ownsLogger bool
+	closeOnce  sync.Once
+	closeErr   error
 }
 
 // StreamingOption defines a functional option for configuring StreamingSimilarity
 type StreamingOption func(*streamingConfig)
 
 type streamingConfig struct {
-	Threshold    float64
-	MaxDiffRatio float64
-	ChunkSize    int
-	Mode         ports.StreamingMode
-	Logger       ports.Logger
-	Normalizer   ports.Normalizer
+	Threshold      float64
+	MaxDiffRatio   float64
+	ChunkSize      int
+	ModeChunkSizes map[ports.StreamingMode]int
+	Mode           ports.StreamingMode
+	Logger         ports.Logger
+	Normalizer     ports.Normalizer
+	EmptyAugmented EmptyAugmentedPolicy
+	Resources      bool
+	Uncertainty    bool
+	MaxBytes       int64
+	Transforms     []ports.Transform
+	Clock          ports.Clock
+	Metrics        ports.Metrics
+	WordClassifier func(r rune) bool
+	Scorer         Scorer
+	// IgnoreTrailingNewline is nil unless set; line mode then defaults to true
+	IgnoreTrailingNewline *bool
 }
 
 // WithStreamingThreshold sets a custom threshold for streaming similarity
@@ -69,13 +162,25 @@ func WithStreamingMaxDiffRatio(ratio float64) StreamingOption {
 	}
 }
 
-// WithStreamingChunkSize sets a custom chunk size for streaming
+// WithStreamingChunkSize sets the number of bytes read per Read call in every mode.
+// A size of 0 keeps the per-mode defaults (8KB for chunk mode, 64KB for line and word modes).
+// Line and word modes use the size as given; before per-mode sizes they read 8 times as much.
 func WithStreamingChunkSize(size int) StreamingOption {
 	return func(cfg *streamingConfig) {
 		cfg.ChunkSize = size
 	}
 }
 
+// WithStreamingModeChunkSize overrides the bytes read per Read call for a single mode
+func WithStreamingModeChunkSize(mode StreamingMode, size int) StreamingOption {
+	return func(cfg *streamingConfig) {
+		if cfg.ModeChunkSizes == nil {
+			cfg.ModeChunkSizes = make(map[ports.StreamingMode]int)
+		}
+		cfg.ModeChunkSizes[ports.StreamingMode(mode)] = size
+	}
+}
+
 // WithStreamingMode sets a custom streaming mode
 func WithStreamingMode(mode StreamingMode) StreamingOption {
 	return func(cfg *streamingConfig) {
@@ -83,6 +188,88 @@ func WithStreamingMode(mode StreamingMode) StreamingOption {
 	}
 }
 
+// WithEmptyAugmentedPolicy sets how an empty augmented stream is scored
+func WithEmptyAugmentedPolicy(policy EmptyAugmentedPolicy) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.EmptyAugmented = policy
+	}
+}
+
+// WithMaxBytes aborts a comparison once either stream exceeds n bytes, reporting
+// a *MaxBytesError in StreamResult.Err. It guards unknown-length inputs such as
+// chunked HTTP bodies and pipes. 0 means no limit.
+func WithMaxBytes(n int64) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.MaxBytes = n
+	}
+}
+
+// WithNormalizedLineEndings treats CRLF, CR and LF line endings as equal by
+// converting them all to LF before counting
+func WithNormalizedLineEndings() StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.LineEndings())
+	}
+}
+
+// WithTabExpansion replaces tabs with spaces up to the next multiple of width
+// columns before counting. A width of 0 removes tabs.
+func WithTabExpansion(width int) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.ExpandTabs(width))
+	}
+}
+
+// WithStrippedIndentation drops leading spaces and tabs from every line before counting
+func WithStrippedIndentation() StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripIndentation())
+	}
+}
+
+// WithJoinedHyphenation joins words split across lines by a trailing hyphen,
+// as OCR and PDF extraction leave them, before counting. The join holds
+// across reads, so it does not depend on where the stream is split.
+func WithJoinedHyphenation() StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.JoinHyphenation())
+	}
+}
+
+// WithStrippedSubtitles removes SRT and WebVTT cue numbers, timing lines and
+// the WEBVTT header before counting, so transcripts compare on their text
+func WithStrippedSubtitles() StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripSubtitles())
+	}
+}
+
+// WithIgnoredTrailingNewline drops one line break at the very end of each
+// stream before counting, so a file saved with a final newline compares equal
+// to the same file without one. It is on by default in line mode, where a
+// final newline does not end a line, and off in chunk and word mode.
+func WithIgnoredTrailingNewline(ignore bool) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.IgnoreTrailingNewline = &ignore
+	}
+}
+
+// WithStreamingResourceReport fills StreamResult.Resources with an estimate of the
+// allocations, the peak buffer size and the number of workers each comparison used.
+func WithStreamingResourceReport(enable bool) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Resources = enable
+	}
+}
+
+// WithStreamingUncertainty fills StreamResult.Uncertainty with how far the score
+// can move per unit of length difference
+func WithStreamingUncertainty(enable bool) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Uncertainty = enable
+	}
+}
+
 // WithStreamingLogger sets a custom logger for streaming similarity
 func WithStreamingLogger(l l.Logger) StreamingOption {
 	return func(cfg *streamingConfig) {
@@ -97,6 +284,73 @@ func WithStreamingNormalizer(normalizer ports.Normalizer) StreamingOption {
 	}
 }
 
+// WithOptions applies settings shared with the other calculators. There is no
+// warm-up for streaming, so WithWarmUp is ignored.
+func WithOptions(opts ...options.Option) StreamingOption {
+	common := options.Apply(opts...)
+	return func(cfg *streamingConfig) {
+		if common.Threshold != nil {
+			cfg.Threshold = *common.Threshold
+		}
+		if common.MaxDiffRatio != nil {
+			cfg.MaxDiffRatio = *common.MaxDiffRatio
+		}
+		if common.Logger != nil {
+			cfg.Logger = logger.FromExisting(common.Logger)
+		}
+		if common.Normalizer != nil {
+			cfg.Normalizer = common.Normalizer
+		}
+		if common.Clock != nil {
+			cfg.Clock = common.Clock
+		}
+		if common.Metrics != nil {
+			cfg.Metrics = common.Metrics
+		}
+		if common.WordClassifier != nil {
+			cfg.WordClassifier = common.WordClassifier
+		}
+	}
+}
+
+// WithStreamingClock sets the clock that ProcessingTime is measured on, so
+// tests can make it deterministic; nil uses the wall clock
+func WithStreamingClock(c ports.Clock) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Clock = c
+	}
+}
+
+// WithStreamingWordClassifier sets which runes word mode counts as part of a
+// word, so a word is a maximal run of runes isWord accepts. Use the same
+// function with word.WithWordClassifier to count words alike in memory and
+// streamed. nil restores the default: letters, digits, underscores, hyphens
+// and apostrophes.
+func WithStreamingWordClassifier(isWord func(r rune) bool) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.WordClassifier = isWord
+	}
+}
+
+// WithStreamingScorer scores the two counts with s instead of the default
+// formula, for target ratios, asymmetric penalties or calibrated curves.
+// s receives the counts and the configured threshold and max diff ratio;
+// its score and length ratio are clamped to [0, 1]. Empty inputs are still
+// scored by the empty-input rules before s is consulted.
+func WithStreamingScorer(s Scorer) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Scorer = s
+	}
+}
+
+// WithStreamingMetrics records every comparison, by outcome and latency, and
+// the bytes read to m
+func WithStreamingMetrics(m ports.Metrics) StreamingOption {
+	return func(cfg *streamingConfig) {
+		cfg.Metrics = m
+	}
+}
+
 // WithOptimizedNormalizer sets the optimized normalizer.
 func WithOptimizedNormalizer() StreamingOption {
 	return func(cfg *streamingConfig) {
@@ -105,13 +359,36 @@ func WithOptimizedNormalizer() StreamingOption {
 	}
 }
 
+// WithOCRNormalizer sets the normalizer for scanned documents: it removes soft
+// hyphens and zero-width characters, spells out ligatures and folds common
+// recognition confusions ("rn" and "m", 0 and o, 1 and l) before the default
+// normalization. Combine it with WithJoinedHyphenation for words split
+// across lines.
+func WithOCRNormalizer() StreamingOption {
+	return func(cfg *streamingConfig) {
+		normFactory := normalizer.NewNormalizerFactory()
+		cfg.Normalizer = normFactory.CreateNormalizer(normalizer.OCRNormalizerType)
+	}
+}
+
+// WithLogNormalizer sets the normalizer for log captures: timestamps, UUIDs,
+// IP addresses, numbers and hex IDs become placeholders (TIMESTAMP, UUID, IP,
+// NUM, HEX) before the default normalization, so two runs of the same program
+// compare on the structure of their output.
+func WithLogNormalizer() StreamingOption {
+	return func(cfg *streamingConfig) {
+		normFactory := normalizer.NewNormalizerFactory()
+		cfg.Normalizer = normFactory.CreateNormalizer(normalizer.LogNormalizerType)
+	}
+}
+
 // NewStreamingSimilarity creates a new StreamingSimilarity instance
 func NewStreamingSimilarity(opts ...StreamingOption) (*StreamingSimilarity, error) {
 	// Default configuration
 	config := &streamingConfig{
-		Threshold:    0.7,
-		MaxDiffRatio: 0.3,
-		ChunkSize:    8192,
+		Threshold:    scoring.DefaultThreshold,
+		MaxDiffRatio: scoring.DefaultMaxDiffRatio,
+		ChunkSize:    0, // Per-mode defaults
 		Mode:         ports.LineByLine,
 	}
 
@@ -120,8 +397,37 @@ func NewStreamingSimilarity(opts ...StreamingOption) (*StreamingSimilarity, erro
 		opt(config)
 	}
 
+	// Line mode ignores a final newline unless told otherwise
+	transforms := config.Transforms
+	ignoreTrailingNewline := config.Mode == ports.LineByLine
+	if config.IgnoreTrailingNewline != nil {
+		ignoreTrailingNewline = *config.IgnoreTrailingNewline
+	}
+	if ignoreTrailingNewline {
+		transforms = append(transforms[:len(transforms):len(transforms)], transform.TrimTrailingNewline())
+	}
+
+	// Validate before allocating any resources
+	streamingConfig := stream.StreamingConfig{
+		Threshold:      config.Threshold,
+		MaxDiffRatio:   config.MaxDiffRatio,
+		ChunkSize:      config.ChunkSize,
+		ModeChunkSizes: config.ModeChunkSizes,
+		Mode:           config.Mode,
+		EmptyAugmented: stream.EmptyAugmentedPolicy(config.EmptyAugmented),
+		MaxBytes:       config.MaxBytes,
+		Transforms:     transforms,
+		Clock:          config.Clock,
+		WordClassifier: config.WordClassifier,
+		Scorer:         config.Scorer,
+	}
+	if err := streamingConfig.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Set up logger if not provided
-	if config.Logger == nil {
+	ownsLogger := config.Logger == nil
+	if ownsLogger {
 		var err error
 		config.Logger, err = logger.NewStdLogger()
 		if err != nil {
@@ -136,39 +442,98 @@ func NewStreamingSimilarity(opts ...StreamingOption) (*StreamingSimilarity, erro
 	}
 
 	// Create core calculator
-	streamingConfig := stream.StreamingConfig{
-		Threshold:    config.Threshold,
-		MaxDiffRatio: config.MaxDiffRatio,
-		ChunkSize:    config.ChunkSize,
-		Mode:         config.Mode,
-	}
 	calculator, err := stream.NewStreamingCalculator(streamingConfig, config.Logger, config.Normalizer)
 	if err != nil {
 		return nil, err
 	}
 
 	return &StreamingSimilarity{
-		calculator: calculator,
-		logger:     config.Logger,
+		calculator:   calculator,
+		mode:         config.Mode,
+		logger:       config.Logger,
+		resources:    config.Resources,
+		uncertainty:  config.Uncertainty,
+		maxDiffRatio: config.MaxDiffRatio,
+		metrics:      config.Metrics,
+		ownsLogger:   ownsLogger,
 	}, nil
 }
 
+// Close releases the calculator's pooled buffers and its logger, which is
+// closed if the constructor created it or flushed if it came from WithLogger.
+// Close is safe to call more than once; do not use the calculator afterwards.
+func (ss *StreamingSimilarity) Close() error {
+	ss.closeOnce.Do(func() {
+		ss.calculator.Release()
+		ss.closeErr = logger.Release(ss.logger, ss.ownsLogger)
+	})
+	return ss.closeErr
+}
+
 // ComputeFromReaders calculates the streaming similarity between two text readers
 func (ss *StreamingSimilarity) ComputeFromReaders(ctx context.Context, original io.Reader, augmented io.Reader) StreamResult {
-	result := ss.calculator.ComputeStreaming(ctx, original, augmented)
+	var pr *probe.Probe
+	if ss.resources {
+		pr = probe.Start()
+		ctx = probe.NewContext(ctx, pr)
+	}
+
+	raw := ss.calculator.ComputeStreaming(ctx, original, augmented)
+	result := toStreamResult(raw)
+	result.Engine, result.Mode = domain.EngineStreaming, ss.mode.String()
+	result.Resources = pr.Finish()
+	recordStreamResult(ss.metrics, result, raw.ProcessingTime)
+	return withUncertainty(result, ss.uncertainty, ss.maxDiffRatio)
+}
+
+// ComputeFromFS opens the files at original and augmented in fsys, such as an
+// embed.FS or a zip archive, and streams them like ComputeFromReaders.
+// The error reports a file that could not be opened.
+func (ss *StreamingSimilarity) ComputeFromFS(ctx context.Context, fsys fs.FS, original, augmented string) (StreamResult, error) {
+	orig, aug, err := stream.OpenPair(fsys, original, augmented)
+	if err != nil {
+		return StreamResult{}, err
+	}
+	defer orig.Close()
+	defer aug.Close()
+
+	return ss.ComputeFromReaders(ctx, orig, aug), nil
+}
+
+// recordStreamResult records a comparison and the bytes it read to m, if set
+func recordStreamResult(m ports.Metrics, result StreamResult, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	outcome := metrics.Outcome(result.Passed, false, result.Err != nil || result.Details["error"] != nil)
+	metrics.RecordComparison(m, result.Engine, result.Mode, outcome, elapsed)
+	m.Count(metrics.BytesProcessed, float64(result.BytesProcessed), "engine", result.Engine)
+}
+
+// withUncertainty fills result.Uncertainty when enabled and the result carries a score
+func withUncertainty(result StreamResult, enable bool, maxDiffRatio float64) StreamResult {
+	if enable && result.Err == nil && result.OriginalLength > 0 && result.Details["error"] == nil {
+		result.Uncertainty = domain.NewUncertainty(result.Score, result.Threshold, scoring.Margin(result.OriginalLength, maxDiffRatio))
+	}
+	return result
+}
 
-	// Convert internal result to public result
+// toStreamResult converts an internal result to the public result
+func toStreamResult(result ports.StreamResult) StreamResult {
 	return StreamResult{
-		Name:            result.Name,
-		Score:           result.Score,
-		Passed:          result.Passed,
-		OriginalLength:  result.OriginalLength,
-		AugmentedLength: result.AugmentedLength,
-		LengthRatio:     result.LengthRatio,
-		Threshold:       result.Threshold,
-		ProcessingTime:  result.ProcessingTime.String(),
-		BytesProcessed:  result.BytesProcessed,
-		Details:         result.Details,
+		Name:                 result.Name,
+		Score:                result.Score,
+		Passed:               result.Passed,
+		OriginalLength:       result.OriginalLength,
+		AugmentedLength:      result.AugmentedLength,
+		LengthRatio:          result.LengthRatio,
+		Threshold:            result.Threshold,
+		ProcessingTime:       result.ProcessingTime.String(),
+		BytesProcessed:       result.BytesProcessed,
+		Details:              result.Details,
+		Err:                  result.Err,
+		TruncatedInput:       result.TruncatedInput,
+		PotentiallyTruncated: result.PotentiallyTruncated,
 	}
 }
 
@@ -0,0 +1,344 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/character"
+	"github.com/baditaflorin/go_length_similarity/pkg/metrics"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
+	"github.com/baditaflorin/go_length_similarity/pkg/similaritytest/fakeclock"
+	"github.com/baditaflorin/go_length_similarity/pkg/word"
+)
+
+func TestLineLengthsRevealCollapsedLines(t *testing.T) {
+	original := strings.Repeat("a short line of text\n", 100)
+	collapsed := strings.ReplaceAll(original, "\n", " ")
+
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingMode(LineByLine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ss.ComputeFromStrings(context.Background(), original, collapsed)
+
+	orig, _ := result.Details["original_line_lengths"].(map[string]float64)
+	aug, _ := result.Details["augmented_line_lengths"].(map[string]float64)
+	if orig == nil || aug == nil {
+		t.Fatalf("missing line length details: %v", result.Details)
+	}
+	if orig["count"] != 100 || orig["p50"] != 20 {
+		t.Errorf("original lines %v, want 100 lines of 20", orig)
+	}
+	if aug["count"] != 1 || aug["max"] < 2000 {
+		t.Errorf("augmented lines %v, want one long line", aug)
+	}
+}
+
+func TestCloseReleasesStreamingCalculators(t *testing.T) {
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.ComputeFromStrings(context.Background(), "one two three", "one two")
+	aes.ComputeFromStrings(context.Background(), "one two three", "one two")
+
+	for i := 0; i < 2; i++ {
+		if err := ss.Close(); err != nil {
+			t.Fatalf("StreamingSimilarity.Close: %v", err)
+		}
+		if err := aes.Close(); err != nil {
+			t.Fatalf("AllocationEfficientStreamingSimilarity.Close: %v", err)
+		}
+	}
+}
+
+func TestWithOptionsConfiguresBothStreamingCalculators(t *testing.T) {
+	common := []options.Option{options.WithThreshold(0.9), options.WithLogger(discardLogger(t))}
+	ss, err := NewStreamingSimilarity(WithOptions(common...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := NewAllocationEfficientStreamingSimilarity(WithEfficientOptions(common...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for name, result := range map[string]StreamResult{
+		"streaming": ss.ComputeFromStrings(ctx, "one two three", "one two three"),
+		"efficient": es.ComputeFromStrings(ctx, "one two three", "one two three"),
+	} {
+		if result.Threshold != 0.9 {
+			t.Errorf("%s: threshold = %v, want 0.9", name, result.Threshold)
+		}
+	}
+}
+
+func TestMetricsRecordStreamedComparisons(t *testing.T) {
+	ctx := context.Background()
+	prom := metrics.NewPrometheus()
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingMetrics(prom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientOptions(options.WithMetrics(prom)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.ComputeFromStrings(ctx, "one two three\n", "one two three\n")
+	aes.ComputeFromStrings(ctx, "one two three\n", "one\n")
+
+	var buf bytes.Buffer
+	if _, err := prom.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`similarity_comparisons_total{engine="streaming",mode="line",outcome="passed"} 1`,
+		`similarity_comparisons_total{engine="efficient",mode="line",outcome="failed"} 1`,
+		// The final newline of each side is dropped before it is read
+		`similarity_bytes_processed_total{engine="efficient"} 16`,
+		`similarity_compute_seconds_count{engine="streaming",mode="line"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestClockDrivesProcessingTime(t *testing.T) {
+	ctx := context.Background()
+	original, augmented := strings.Repeat("one two three\n", 50), strings.Repeat("one two\n", 50)
+
+	// A clock that never moves gives the same processing time on every run
+	still := fakeclock.New(time.Unix(0, 0))
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingClock(still))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientOptions(options.WithClock(still)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if d := ss.ComputeFromStrings(ctx, original, augmented).ProcessingTime; d != "0s" {
+			t.Fatalf("streaming ProcessingTime = %v on a still clock", d)
+		}
+		if d := aes.ComputeFromReaders(ctx, strings.NewReader(original), strings.NewReader(augmented)).ProcessingTime; d != "0s" {
+			t.Fatalf("efficient ProcessingTime = %v on a still clock", d)
+		}
+	}
+
+	stepping := fakeclock.New(time.Unix(0, 0))
+	stepping.SetStep(time.Millisecond)
+	aes, err = NewAllocationEfficientStreamingSimilarity(WithEfficientClock(stepping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := time.ParseDuration(aes.ComputeFromReaders(ctx, strings.NewReader(original), strings.NewReader(augmented)).ProcessingTime)
+	if err != nil || d <= 0 || d%time.Millisecond != 0 {
+		t.Errorf("ProcessingTime = %v (%v), want whole clock steps", d, err)
+	}
+}
+
+func TestScorerReplacesTheDefaultFormula(t *testing.T) {
+	ctx := context.Background()
+	original, augmented := strings.Repeat("line\n", 10), strings.Repeat("line\n", 12)
+
+	// Padding is tolerated, shortening is not
+	asymmetric := ScorerFunc(func(origCount, augCount int, cfg ScoringConfig) Outcome {
+		if augCount >= origCount {
+			outcome := Evaluate(origCount, augCount, cfg)
+			outcome.Score, outcome.Passed = 1, true
+			return outcome
+		}
+		return Evaluate(origCount, augCount, cfg)
+	})
+
+	ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingScorer(asymmetric))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientScorer(asymmetric))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	padded := []StreamResult{ss.ComputeFromStrings(ctx, original, augmented), aes.ComputeFromStrings(ctx, original, augmented)}
+	for _, result := range padded {
+		if result.Score != 1 || !result.Passed || result.LengthRatio == 1 {
+			t.Errorf("%s: padding should score 1 with the real length ratio, got %+v", result.Engine, result)
+		}
+	}
+	shortened := []StreamResult{ss.ComputeFromStrings(ctx, augmented, original), aes.ComputeFromStrings(ctx, augmented, original)}
+	for _, result := range shortened {
+		if result.Score == 1 || result.Passed {
+			t.Errorf("%s: shortening should keep the default score, got %+v", result.Engine, result)
+		}
+	}
+}
+
+func TestTrailingNewlineIsIgnoredInLineMode(t *testing.T) {
+	ctx := context.Background()
+	without := strings.Repeat("alpha beta\ngamma delta\n", 40) + "last line"
+	variants := []string{without + "\n", without + "\r\n"}
+
+	check := func(name string, compute func(ctx context.Context, a, b string) StreamResult, wantEqual bool) {
+		t.Helper()
+		for _, with := range variants {
+			result := compute(ctx, without, with)
+			if equal := result.OriginalLength == result.AugmentedLength; equal != wantEqual {
+				t.Errorf("%s %q: lengths %d/%d, want equal %v", name, with[len(with)-2:], result.OriginalLength, result.AugmentedLength, wantEqual)
+			}
+		}
+	}
+
+	for _, mode := range []StreamingMode{ChunkByChunk, LineByLine, WordByWord} {
+		ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingMode(mode), WithStreamingChunkSize(7), WithIgnoredTrailingNewline(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		compute := func(ctx context.Context, a, b string) StreamResult {
+			return ss.ComputeFromReaders(ctx, strings.NewReader(a), iotest.OneByteReader(strings.NewReader(b)))
+		}
+		check(fmt.Sprintf("mode %v", mode), compute, true)
+	}
+
+	// The default in line mode
+	lines, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("line default", lines.ComputeFromStrings, true)
+
+	// Off by default in chunk mode, where the newline is a character
+	chunks, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingMode(ChunkByChunk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("chunk default", chunks.ComputeFromStrings, false)
+
+	for _, parallel := range []bool{false, true} {
+		aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientParallel(parallel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(fmt.Sprintf("efficient parallel=%v", parallel), aes.ComputeFromStrings, true)
+
+		kept, err := NewAllocationEfficientStreamingSimilarity(WithEfficientParallel(parallel), WithEfficientIgnoredTrailingNewline(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(fmt.Sprintf("efficient kept parallel=%v", parallel), kept.ComputeFromStrings, false)
+	}
+}
+
+func TestStreamingCalculatorsShareTrailingNewlineDefault(t *testing.T) {
+	ctx := context.Background()
+	without := strings.Repeat("alpha beta\ngamma delta\n", 40) + "last line"
+	with := without + "\n"
+
+	// Both calculators see the trailing newline in chunk mode, where it is a
+	// character, and in line mode, where it would end an empty line. In word
+	// mode it never makes a word, so only the defaults themselves can differ.
+	for _, mode := range []StreamingMode{ChunkByChunk, LineByLine} {
+		ss, err := NewStreamingSimilarity(WithStreamingLogger(discardLogger(t)), WithStreamingMode(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientMode(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		streamed := ss.ComputeFromStrings(ctx, without, with)
+		efficient := aes.ComputeFromStrings(ctx, without, with)
+		streamedIgnores := streamed.OriginalLength == streamed.AugmentedLength
+		efficientIgnores := efficient.OriginalLength == efficient.AugmentedLength
+		if streamedIgnores != efficientIgnores || streamedIgnores != (mode == LineByLine) {
+			t.Errorf("mode %v: streaming ignores the trailing newline %v, efficient %v; want both %v",
+				mode, streamedIgnores, efficientIgnores, mode == LineByLine)
+		}
+	}
+
+	// In word mode the efficient calculator counts lines, so its default shows
+	aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientMode(WordByWord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := aes.ComputeFromStrings(ctx, without, with); r.OriginalLength == r.AugmentedLength {
+		t.Errorf("efficient word mode ignored the trailing newline: lengths %d/%d", r.OriginalLength, r.AugmentedLength)
+	}
+}
+
+func TestEmptyAugmentedPolicy(t *testing.T) {
+	ctx := context.Background()
+	lg := discardLogger(t)
+	original := "one two\nthree four\n"
+
+	// A MaxDiffRatio of 2 lets the regular formula score an empty augmented
+	// text 0.5, which a threshold of 0.4 passes
+	streamed := func(p EmptyAugmentedPolicy) func() (float64, bool) {
+		return func() (float64, bool) {
+			ss, err := NewStreamingSimilarity(WithStreamingLogger(lg), WithStreamingThreshold(0.4), WithStreamingMaxDiffRatio(2), WithEmptyAugmentedPolicy(p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := ss.ComputeFromStrings(ctx, original, "")
+			return r.Score, r.Passed
+		}
+	}
+	efficient := func(p EmptyAugmentedPolicy) func() (float64, bool) {
+		return func() (float64, bool) {
+			aes, err := NewAllocationEfficientStreamingSimilarity(WithEfficientLogger(lg), WithEfficientThreshold(0.4), WithEfficientMaxDiffRatio(2), WithEfficientEmptyAugmentedPolicy(p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := aes.ComputeFromStrings(ctx, original, "")
+			return r.Score, r.Passed
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		compute    func() (float64, bool)
+		wantScore  float64
+		wantPassed bool
+	}{
+		{"streaming fail", streamed(EmptyAugmentedFail), 0, false},
+		{"streaming score", streamed(EmptyAugmentedScore), 0.5, true},
+		{"efficient fail", efficient(EmptyAugmentedFail), 0, false},
+		{"efficient score", efficient(EmptyAugmentedScore), 0.5, true},
+		// The in-memory calculators have no policy: the word calculator
+		// rejects an empty text as too short, the character calculator
+		// applies the regular formula
+		{"word", func() (float64, bool) {
+			ls, err := word.New(word.WithLogger(lg), word.WithThreshold(0.4), word.WithMaxDiffRatio(2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := ls.Compute(ctx, original, "")
+			return r.Score, r.Passed
+		}, 0, false},
+		{"character", func() (float64, bool) {
+			cs, err := character.NewCharacterSimilarity(character.WithLogger(lg), character.WithThreshold(0.4), character.WithMaxDiffRatio(2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := cs.Compute(ctx, original, "")
+			return r.Score, r.Passed
+		}, 0.5, true},
+	} {
+		if score, passed := tc.compute(); score != tc.wantScore || passed != tc.wantPassed {
+			t.Errorf("%s: score %v, passed %v; want %v, %v", tc.name, score, passed, tc.wantScore, tc.wantPassed)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package structural compares JSON and XML payloads by their structure: it
+// parses both and scores how many keys, elements and values they hold rather
+// than how many characters, so pretty-printing, indentation and key order do
+// not affect the score:
+//
+//	ss, _ := structural.New(structural.WithThreshold(0.9))
+//	result := ss.Compute(ctx, compactJSON, prettyJSON) // Score 1
+//
+// Payloads that fail to parse yield a failed result with Details["error"].
+package structural
+
+import (
+	"context"
+	"io"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/internal/core/structure"
+)
+
+// Result is the outcome of a comparison
+type Result = domain.Result
+
+// ConfigError reports an invalid option value passed to New
+type ConfigError = domain.ConfigError
+
+// Format is the syntax of a payload
+type Format = structure.Format
+
+// Formats accepted by WithFormat
+const (
+	// Auto detects each payload's format from its first non-space byte
+	Auto = structure.Auto
+	JSON = structure.JSON
+	XML  = structure.XML
+)
+
+// Counts is the structure of one payload
+type Counts = structure.Counts
+
+// FieldScore compares one top-level field of two payloads by its content length
+type FieldScore = structure.FieldScore
+
+// FieldReport is a comparison with one score per top-level field
+type FieldReport = structure.FieldReport
+
+// ErrUnknownFormat is returned when Auto cannot tell the format of a payload
+var ErrUnknownFormat = structure.ErrUnknownFormat
+
+// Option defines a functional option for configuring StructuralSimilarity
+type Option func(*structure.Config)
+
+// WithThreshold sets the score at or above which a comparison passes
+func WithThreshold(th float64) Option {
+	return func(cfg *structure.Config) {
+		cfg.Threshold = th
+	}
+}
+
+// WithMaxDiffRatio sets the node count difference ratio at which the score reaches 0
+func WithMaxDiffRatio(ratio float64) Option {
+	return func(cfg *structure.Config) {
+		cfg.MaxDiffRatio = ratio
+	}
+}
+
+// WithFormat parses both payloads as format instead of detecting each one
+func WithFormat(format Format) Option {
+	return func(cfg *structure.Config) {
+		cfg.Format = format
+	}
+}
+
+// StructuralSimilarity compares payloads by their node counts; it is safe for concurrent use
+type StructuralSimilarity struct {
+	calculator *structure.Calculator
+}
+
+// New creates a StructuralSimilarity
+func New(opts ...Option) (*StructuralSimilarity, error) {
+	config := structure.DefaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	calculator, err := structure.NewCalculator(config)
+	if err != nil {
+		return nil, err
+	}
+	return &StructuralSimilarity{calculator: calculator}, nil
+}
+
+// Compute parses both payloads and compares their node counts
+func (ss *StructuralSimilarity) Compute(ctx context.Context, original, augmented string) Result {
+	return ss.calculator.Compute(ctx, original, augmented)
+}
+
+// ComputeFromReaders is Compute over readers, parsing them token by token
+// without holding them in memory
+func (ss *StructuralSimilarity) ComputeFromReaders(ctx context.Context, original, augmented io.Reader) Result {
+	return ss.calculator.ComputeFromReaders(ctx, original, augmented)
+}
+
+// CompareFields is Compute with a score for every top-level field: JSON
+// object keys, top-level array indexes ("[0]"), or the XML root's child
+// elements, attributes ("@id") and text ("#text"). Fields compare the runes
+// of their content, so the report shows which part of a document was
+// shortened or padded.
+func (ss *StructuralSimilarity) CompareFields(ctx context.Context, original, augmented string) FieldReport {
+	return ss.calculator.CompareFields(ctx, original, augmented)
+}
+
+// CompareFieldsFromReaders is CompareFields over readers
+func (ss *StructuralSimilarity) CompareFieldsFromReaders(ctx context.Context, original, augmented io.Reader) FieldReport {
+	return ss.calculator.CompareFieldsFromReaders(ctx, original, augmented)
+}
+
+// Count parses r as format (Auto detects it) and returns its structure
+func Count(ctx context.Context, r io.Reader, format Format) (Counts, error) {
+	return structure.Count(ctx, r, format)
+}
@@ -0,0 +1,111 @@
+package word
+
+import (
+	"time"
+
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
+)
+
+// Config is the plain-struct form of the options, for driving the calculator
+// from JSON or YAML configuration. Zero values keep the defaults. A logger
+// cannot come from a file; pass WithLogger to NewFromConfig.
+type Config struct {
+	Threshold         float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	MaxDiffRatio      float64 `json:"max_diff_ratio,omitempty" yaml:"max_diff_ratio,omitempty"`
+	MinWords          int     `json:"min_words,omitempty" yaml:"min_words,omitempty"`
+	MinOriginalLength int     `json:"min_original_length,omitempty" yaml:"min_original_length,omitempty"`
+	OriginalCacheSize int     `json:"original_cache_size,omitempty" yaml:"original_cache_size,omitempty"`
+	// Hasher keys the originals cache: "xxhash" or "sha256"
+	Hasher     string           `json:"hasher,omitempty" yaml:"hasher,omitempty"`
+	LatencySLO options.Duration `json:"latency_slo,omitempty" yaml:"latency_slo,omitempty"`
+	// Normalizer is "default", "fast", "optimized", "ocr" or "logs"
+	Normalizer        string `json:"normalizer,omitempty" yaml:"normalizer,omitempty"`
+	WarmUp            bool   `json:"warm_up,omitempty" yaml:"warm_up,omitempty"`
+	WarmUpSeed        *int64 `json:"warm_up_seed,omitempty" yaml:"warm_up_seed,omitempty"`
+	ReportResources   bool   `json:"report_resources,omitempty" yaml:"report_resources,omitempty"`
+	ReportUncertainty bool   `json:"report_uncertainty,omitempty" yaml:"report_uncertainty,omitempty"`
+	// The transforms run in this order when enabled
+	NormalizeLineEndings bool `json:"normalize_line_endings,omitempty" yaml:"normalize_line_endings,omitempty"`
+	// TabWidth expands tabs when set; 0 removes them
+	TabWidth         *int `json:"tab_width,omitempty" yaml:"tab_width,omitempty"`
+	StripIndentation bool `json:"strip_indentation,omitempty" yaml:"strip_indentation,omitempty"`
+	// JoinHyphenation joins words split across lines by a trailing hyphen
+	JoinHyphenation bool `json:"join_hyphenation,omitempty" yaml:"join_hyphenation,omitempty"`
+	// StripSubtitles removes SRT and WebVTT cue numbers and timing lines
+	StripSubtitles bool `json:"strip_subtitles,omitempty" yaml:"strip_subtitles,omitempty"`
+}
+
+// Options returns the functional options equivalent to c
+func (c Config) Options() ([]LengthSimilarityOption, error) {
+	var opts []LengthSimilarityOption
+	if c.Threshold != 0 {
+		opts = append(opts, WithThreshold(c.Threshold))
+	}
+	if c.MaxDiffRatio != 0 {
+		opts = append(opts, WithMaxDiffRatio(c.MaxDiffRatio))
+	}
+	if c.MinWords != 0 {
+		opts = append(opts, WithMinWords(c.MinWords))
+	}
+	if c.MinOriginalLength != 0 {
+		opts = append(opts, WithMinOriginalLength(c.MinOriginalLength))
+	}
+	if c.OriginalCacheSize != 0 {
+		opts = append(opts, WithOriginalCache(c.OriginalCacheSize))
+	}
+	h, err := options.HasherNamed(c.Hasher)
+	if err != nil {
+		return nil, err
+	}
+	if h != nil {
+		opts = append(opts, WithHasher(h))
+	}
+	if c.LatencySLO != 0 {
+		opts = append(opts, WithLatencySLO(time.Duration(c.LatencySLO)))
+	}
+	n, err := options.NormalizerNamed(c.Normalizer)
+	if err != nil {
+		return nil, err
+	}
+	if n != nil {
+		opts = append(opts, WithNormalizer(n))
+	}
+	if c.WarmUp {
+		opts = append(opts, WithWarmUp(true))
+	}
+	if c.WarmUpSeed != nil {
+		opts = append(opts, WithWarmupSeed(*c.WarmUpSeed))
+	}
+	if c.ReportResources {
+		opts = append(opts, WithResourceReport(true))
+	}
+	if c.ReportUncertainty {
+		opts = append(opts, WithUncertainty(true))
+	}
+	if c.NormalizeLineEndings {
+		opts = append(opts, WithNormalizedLineEndings())
+	}
+	if c.TabWidth != nil {
+		opts = append(opts, WithTabExpansion(*c.TabWidth))
+	}
+	if c.StripIndentation {
+		opts = append(opts, WithStrippedIndentation())
+	}
+	if c.JoinHyphenation {
+		opts = append(opts, WithJoinedHyphenation())
+	}
+	if c.StripSubtitles {
+		opts = append(opts, WithStrippedSubtitles())
+	}
+	return opts, nil
+}
+
+// NewFromConfig creates a LengthSimilarity from cfg. opts are applied after
+// cfg, for settings a file cannot hold such as the logger.
+func NewFromConfig(cfg Config, opts ...LengthSimilarityOption) (*LengthSimilarity, error) {
+	configured, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return New(append(configured, opts...)...)
+}
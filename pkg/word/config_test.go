@@ -0,0 +1,39 @@
+package word
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewFromConfigJSON(t *testing.T) {
+	var cfg Config
+	data := `{"threshold": 0.9, "max_diff_ratio": 0.5, "normalizer": "fast", "latency_slo": "250ms", "tab_width": 4}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(cfg.LatencySLO) != 250*time.Millisecond || cfg.TabWidth == nil || *cfg.TabWidth != 4 {
+		t.Fatalf("decoded %+v", cfg)
+	}
+
+	ls, err := NewFromConfig(cfg, WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ls.Compute(context.Background(), "one two three four", "one two three")
+	if result.Threshold != 0.9 || result.Score != 0.5 {
+		t.Errorf("threshold %v, score %v; want 0.9 and 0.5", result.Threshold, result.Score)
+	}
+}
+
+func TestNewFromConfigRejectsUnknownNames(t *testing.T) {
+	for _, cfg := range []Config{{Normalizer: "turbo"}, {Hasher: "md5"}} {
+		_, err := NewFromConfig(cfg)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%+v: error %v, want a *ConfigError", cfg, err)
+		}
+	}
+}
@@ -2,34 +2,86 @@ package word
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
 
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/clock"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/hasher"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/logger"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/metrics"
 	"github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/stream"
+	"github.com/baditaflorin/go_length_similarity/internal/adapters/transform"
+	"github.com/baditaflorin/go_length_similarity/internal/core/degrade"
 	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
 	"github.com/baditaflorin/go_length_similarity/internal/core/length"
+	"github.com/baditaflorin/go_length_similarity/internal/core/scoring"
 	"github.com/baditaflorin/go_length_similarity/internal/ports"
+	"github.com/baditaflorin/go_length_similarity/internal/probe"
 	"github.com/baditaflorin/go_length_similarity/internal/warmup"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
 	"github.com/baditaflorin/l"
 )
 
 // LengthSimilarity provides methods to compute a word-level length similarity metric.
 type LengthSimilarity struct {
 	calculator ports.SimilarityCalculator
+	scorer     ports.CountScorer
+	counter    *length.Calculator
 	logger     ports.Logger
 	normalizer ports.Normalizer
-	warmed     bool
+	// warmed is the report of the first warm-up, nil until one ran
+	warmed *warmup.WarmupReport
+
+	reportResources bool
+	transforms      []ports.Transform
+
+	// Uncertainty reporting; maxDiffRatio sets the size of one length step
+	reportUncertainty bool
+	maxDiffRatio      float64
+
+	// Latency-driven degradation; nil unless WithLatencySLO is set
+	governor *degrade.Governor
+	fast     ports.SimilarityCalculator
+
+	// Measurements; nil unless WithMetrics is set, so comparisons are only
+	// timed when something records them
+	metrics ports.Metrics
+	clock   ports.Clock
+
+	// ownsLogger is set when New created the logger, so Close closes it
+	ownsLogger bool
+	closeOnce  sync.Once
+	closeErr   error
 }
 
 // LengthSimilarityOption defines a functional option for configuring LengthSimilarity.
 type LengthSimilarityOption func(*lengthSimilarityConfig)
 
+// ConfigError reports an invalid option value passed to New
+type ConfigError = domain.ConfigError
+
 type lengthSimilarityConfig struct {
-	Threshold    float64
-	MaxDiffRatio float64
-	Logger       ports.Logger
-	Normalizer   ports.Normalizer
-	WarmUp       bool
-	WarmUpConfig warmup.WarmupConfig
+	Threshold         float64
+	MaxDiffRatio      float64
+	MinWords          int
+	Logger            ports.Logger
+	Normalizer        ports.Normalizer
+	WarmUp            bool
+	WarmUpConfig      warmup.WarmupConfig
+	WarmUpSeed        *int64
+	MinOriginalLength int
+	OriginalCacheSize int
+	Hasher            ports.Hasher
+	LatencySLO        time.Duration
+	ReportResources   bool
+	ReportUncertainty bool
+	Transforms        []ports.Transform
+	Clock             ports.Clock
+	Metrics           ports.Metrics
+	WordClassifier    func(r rune) bool
 }
 
 // WithThreshold sets a custom threshold for length similarity.
@@ -46,6 +98,119 @@ func WithMaxDiffRatio(ratio float64) LengthSimilarityOption {
 	}
 }
 
+// WithMinWords sets the minimum number of normalized words both texts need
+// before a similarity score is reported.
+func WithMinWords(n int) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.MinWords = n
+	}
+}
+
+// WithMinOriginalLength reports an inconclusive result, neither passed nor
+// failed, when the original has fewer than n normalized words. Length ratios
+// over a few words say little about similarity. 0 disables the guard.
+func WithMinOriginalLength(n int) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.MinOriginalLength = n
+	}
+}
+
+// WithOriginalCache caches the normalized word counts of up to size originals,
+// keyed by a hash of the text. Use it when one source is compared against many
+// candidates so only the augmented side is processed on each call.
+func WithOriginalCache(size int) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.OriginalCacheSize = size
+	}
+}
+
+// WithHasher sets the hash function used to key the originals cache.
+func WithHasher(h ports.Hasher) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Hasher = h
+	}
+}
+
+// WithSHA256Hasher keys the originals cache with SHA-256 instead of xxhash,
+// for inputs where collision resistance matters.
+func WithSHA256Hasher() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Hasher = hasher.New(hasher.SHA256Type)
+	}
+}
+
+// WithLatencySLO enables degradation under sustained latency pressure. When
+// the moving average of Compute latencies stays above target, Compute first
+// switches to the fast normalizer and then also fails requests whose deadline
+// is shorter than the current average latency. It steps back once latency
+// recovers. Degraded results carry "degraded" and "degradation" in Details.
+// A target of 0 disables degradation.
+func WithLatencySLO(target time.Duration) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.LatencySLO = target
+	}
+}
+
+// WithResourceReport fills Result.Resources with an estimate of the
+// allocations, the peak buffer size and the number of workers each comparison used.
+func WithResourceReport(enable bool) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.ReportResources = enable
+	}
+}
+
+// WithUncertainty fills Result.Uncertainty with how far the score can move per
+// unit of length difference. Short texts move in large steps, so gate their
+// near-threshold scores on Uncertainty.NearThreshold.
+func WithUncertainty(enable bool) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.ReportUncertainty = enable
+	}
+}
+
+// WithNormalizedLineEndings treats CRLF, CR and LF line endings as equal by
+// converting them all to LF before counting, so cross-platform copies of a
+// text are not reported as different.
+func WithNormalizedLineEndings() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.LineEndings())
+	}
+}
+
+// WithTabExpansion replaces tabs with spaces up to the next multiple of width
+// columns before counting, so tab- and space-indented copies of a text compare
+// equal. A width of 0 removes tabs.
+func WithTabExpansion(width int) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.ExpandTabs(width))
+	}
+}
+
+// WithStrippedIndentation drops leading spaces and tabs from every line before
+// counting, so re-indented code or reflowed text compares on its content.
+func WithStrippedIndentation() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripIndentation())
+	}
+}
+
+// WithJoinedHyphenation joins words split across lines by a trailing hyphen,
+// as OCR and PDF extraction leave them, before counting, so "exam-\nple"
+// counts as one word like "example" on the other side.
+func WithJoinedHyphenation() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.JoinHyphenation())
+	}
+}
+
+// WithStrippedSubtitles removes SRT and WebVTT cue numbers, timing lines and
+// the WEBVTT header before counting, so transcripts compare on their words.
+func WithStrippedSubtitles() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Transforms = append(cfg.Transforms, transform.StripSubtitles())
+	}
+}
+
 // WithLogger sets a custom logger for length similarity.
 func WithLogger(l l.Logger) LengthSimilarityOption {
 	return func(cfg *lengthSimilarityConfig) {
@@ -76,6 +241,89 @@ func WithOptimizedNormalizer() LengthSimilarityOption {
 	}
 }
 
+// WithOCRNormalizer sets the normalizer for scanned documents: it removes soft
+// hyphens and zero-width characters, spells out ligatures and folds common
+// recognition confusions ("rn" and "m", 0 and o, 1 and l) before the default
+// normalization. Combine it with WithJoinedHyphenation for words split
+// across lines.
+func WithOCRNormalizer() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		normFactory := normalizer.NewNormalizerFactory()
+		cfg.Normalizer = normFactory.CreateNormalizer(normalizer.OCRNormalizerType)
+	}
+}
+
+// WithLogNormalizer sets the normalizer for log captures: timestamps, UUIDs,
+// IP addresses, numbers and hex IDs become placeholders (TIMESTAMP, UUID, IP,
+// NUM, HEX) before the default normalization, so two runs of the same program
+// compare on the structure of their output.
+func WithLogNormalizer() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		normFactory := normalizer.NewNormalizerFactory()
+		cfg.Normalizer = normFactory.CreateNormalizer(normalizer.LogNormalizerType)
+	}
+}
+
+// WithOptions applies settings shared with the other calculators
+func WithOptions(opts ...options.Option) LengthSimilarityOption {
+	common := options.Apply(opts...)
+	return func(cfg *lengthSimilarityConfig) {
+		if common.Threshold != nil {
+			cfg.Threshold = *common.Threshold
+		}
+		if common.MaxDiffRatio != nil {
+			cfg.MaxDiffRatio = *common.MaxDiffRatio
+		}
+		if common.Logger != nil {
+			cfg.Logger = logger.FromExisting(common.Logger)
+		}
+		if common.Normalizer != nil {
+			cfg.Normalizer = common.Normalizer
+		}
+		if common.WarmUp != nil {
+			cfg.WarmUp = *common.WarmUp
+		}
+		if common.Clock != nil {
+			cfg.Clock = common.Clock
+		}
+		if common.Metrics != nil {
+			cfg.Metrics = common.Metrics
+		}
+		if common.WordClassifier != nil {
+			cfg.WordClassifier = common.WordClassifier
+		}
+	}
+}
+
+// WithWordClassifier counts a word as a maximal run of runes isWord accepts,
+// in place of the normalizer's word boundaries, both in Compute and
+// ComputeFromReaders. Pass the same function to
+// streaming.WithStreamingWordClassifier to count streamed words alike; see
+// count.IsWordChar for the default streaming rule. Whitespace always
+// separates words.
+func WithWordClassifier(isWord func(r rune) bool) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.WordClassifier = isWord
+	}
+}
+
+// WithClock sets the clock that times comparisons for WithLatencySLO and
+// WithMetrics, so tests can drive latency tracking deterministically; nil
+// uses the wall clock
+func WithClock(c ports.Clock) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Clock = c
+	}
+}
+
+// WithMetrics records every comparison, by outcome and latency, and the
+// warm-up duration to m, which may be shared with other calculators
+func WithMetrics(m ports.Metrics) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Metrics = m
+	}
+}
+
 // WithWarmUp enables system warm-up on initialization.
 func WithWarmUp(enable bool) LengthSimilarityOption {
 	return func(cfg *lengthSimilarityConfig) {
@@ -83,6 +331,15 @@ func WithWarmUp(enable bool) LengthSimilarityOption {
 	}
 }
 
+// WithWarmupSeed sets the seed for the warm-up sample texts so performance
+// characterization runs are reproducible. It applies to any warm-up config,
+// whether set before or after this option.
+func WithWarmupSeed(seed int64) LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.WarmUpSeed = &seed
+	}
+}
+
 // WithWarmUpConfig sets a custom warm-up configuration.
 func WithWarmUpConfig(config warmup.WarmupConfig) LengthSimilarityOption {
 	return func(cfg *lengthSimilarityConfig) {
@@ -99,6 +356,7 @@ func New(opts ...LengthSimilarityOption) (*LengthSimilarity, error) {
 	config := &lengthSimilarityConfig{
 		Threshold:    defaultConfig.Threshold,
 		MaxDiffRatio: defaultConfig.MaxDiffRatio,
+		MinWords:     defaultConfig.MinWords,
 		WarmUp:       false,
 		WarmUpConfig: warmup.DefaultWarmupConfig(),
 	}
@@ -108,8 +366,28 @@ func New(opts ...LengthSimilarityOption) (*LengthSimilarity, error) {
 		opt(config)
 	}
 
+	// Validate before allocating any resources
+	coreConfig := length.SimilarityConfig{
+		Threshold:         config.Threshold,
+		MaxDiffRatio:      config.MaxDiffRatio,
+		MinWords:          config.MinWords,
+		MinOriginalLength: config.MinOriginalLength,
+		OriginalCacheSize: config.OriginalCacheSize,
+		Hasher:            config.Hasher,
+	}
+	if err := coreConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if config.LatencySLO < 0 {
+		return nil, domain.NewConfigError("latencySLO", config.LatencySLO, "must not be negative")
+	}
+	if err := transform.Validate(config.Transforms); err != nil {
+		return nil, err
+	}
+
 	// Set up logger if not provided
-	if config.Logger == nil {
+	ownsLogger := config.Logger == nil
+	if ownsLogger {
 		var err error
 		config.Logger, err = logger.NewStdLogger()
 		if err != nil {
@@ -117,52 +395,237 @@ func New(opts ...LengthSimilarityOption) (*LengthSimilarity, error) {
 		}
 	}
 
-	// Set up normalizer if not provided
+	// Set up normalizer if not provided; a word classifier replaces it
+	if config.WordClassifier != nil {
+		config.Normalizer = normalizer.NewClassifierNormalizer(config.WordClassifier)
+	}
 	if config.Normalizer == nil {
 		config.Normalizer = normalizer.NewDefaultNormalizer()
 	}
 
-	// Create core calculator
-	coreConfig := length.SimilarityConfig{
-		Threshold:    config.Threshold,
-		MaxDiffRatio: config.MaxDiffRatio,
-	}
-	calculator, err := length.NewCalculator(coreConfig, config.Logger, config.Normalizer)
+	// Create core calculator; transforms run before the normalizer
+	calculator, err := length.NewCalculator(coreConfig, config.Logger, transform.Normalizer(config.Normalizer, config.Transforms))
 	if err != nil {
 		return nil, err
 	}
 
 	ls := &LengthSimilarity{
 		calculator: calculator,
+		scorer:     calculator,
+		counter:    calculator,
 		logger:     config.Logger,
 		normalizer: config.Normalizer,
-		warmed:     false,
+		ownsLogger: ownsLogger,
+
+		reportResources: config.ReportResources,
+		transforms:      config.Transforms,
+
+		reportUncertainty: config.ReportUncertainty,
+		maxDiffRatio:      config.MaxDiffRatio,
+
+		metrics: config.Metrics,
+		clock:   clock.OrSystem(config.Clock),
+	}
+
+	if config.LatencySLO > 0 {
+		// The fallback keeps a word classifier's boundaries
+		fastNorm := normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.FastNormalizerType)
+		if config.WordClassifier != nil {
+			fastNorm = config.Normalizer
+		}
+		fast, err := length.NewCalculator(coreConfig, config.Logger, transform.Normalizer(fastNorm, config.Transforms))
+		if err != nil {
+			return nil, err
+		}
+		ls.governor = degrade.NewGovernor(config.LatencySLO, clock.OrSystem(config.Clock))
+		ls.fast = fast
 	}
 
 	// Perform warm-up if configured
 	if config.WarmUp {
+		if config.WarmUpSeed != nil {
+			config.WarmUpConfig.Seed = *config.WarmUpSeed
+		}
 		ls.WarmUp(context.Background(), config.WarmUpConfig)
 	}
 
 	return ls, nil
 }
 
+// Close releases what the calculator holds: its originals cache, and its
+// logger, which is closed if New created it or flushed if it came from
+// WithLogger. Close is safe to call more than once; do not use the calculator
+// afterwards.
+func (ls *LengthSimilarity) Close() error {
+	ls.closeOnce.Do(func() {
+		ls.counter.Release()
+		if fast, ok := ls.fast.(interface{ Release() }); ok {
+			fast.Release()
+		}
+		ls.closeErr = logger.Release(ls.logger, ls.ownsLogger)
+	})
+	return ls.closeErr
+}
+
 // Compute calculates the word-level length similarity between two texts.
 func (ls *LengthSimilarity) Compute(ctx context.Context, original, augmented string) domain.Result {
-	return ls.calculator.Compute(ctx, original, augmented)
+	start := ls.now()
+	if !ls.reportResources {
+		return ls.finish(ls.compute(ctx, original, augmented), domain.ModeText, start)
+	}
+
+	pr := probe.Start()
+	result := ls.compute(probe.NewContext(ctx, pr), original, augmented)
+	result.Resources = pr.Finish()
+	return ls.finish(result, domain.ModeText, start)
 }
 
-// WarmUp performs system warm-up to optimize performance.
-func (ls *LengthSimilarity) WarmUp(ctx context.Context, config warmup.WarmupConfig) {
-	if ls.warmed {
+// compute runs Compute without resource reporting
+func (ls *LengthSimilarity) compute(ctx context.Context, original, augmented string) domain.Result {
+	if ls.governor == nil {
+		return ls.calculator.Compute(ctx, original, augmented)
+	}
+
+	level := ls.governor.Level()
+	if ls.governor.ShouldExit(ctx, level) {
+		return domain.Result{
+			Name:    "length_similarity",
+			Score:   0,
+			Passed:  false,
+			Details: degrade.Annotate(map[string]interface{}{"error": "deadline shorter than expected latency"}, level),
+		}
+	}
+
+	calculator := ls.calculator
+	if level > degrade.None {
+		calculator = ls.fast
+	}
+
+	start := ls.governor.Now()
+	result := calculator.Compute(ctx, original, augmented)
+	ls.governor.Observe(ls.governor.Now().Sub(start))
+
+	result.Details = degrade.Annotate(result.Details, level)
+	return result
+}
+
+// ComputeFromReaders streams both readers through the configured normalizer
+// and scores the resulting counts exactly like Compute, without holding either
+// text in memory. Markup is not stripped when streaming, so HTML input should go through Compute.
+func (ls *LengthSimilarity) ComputeFromReaders(ctx context.Context, original, augmented io.Reader) domain.Result {
+	start := ls.now()
+	if !ls.reportResources {
+		return ls.finish(ls.computeFromReaders(ctx, original, augmented), domain.ModeStream, start)
+	}
+
+	pr := probe.Start()
+	result := ls.computeFromReaders(probe.NewContext(ctx, pr), original, augmented)
+	result.Resources = pr.Finish()
+	return ls.finish(result, domain.ModeStream, start)
+}
+
+// ComputeFromFS opens the files at original and augmented in fsys, such as an
+// embed.FS or a zip archive, and compares them like ComputeFromReaders.
+// The error reports a file that could not be opened.
+func (ls *LengthSimilarity) ComputeFromFS(ctx context.Context, fsys fs.FS, original, augmented string) (domain.Result, error) {
+	orig, aug, err := stream.OpenPair(fsys, original, augmented)
+	if err != nil {
+		return domain.Result{}, err
+	}
+	defer orig.Close()
+	defer aug.Close()
+
+	return ls.ComputeFromReaders(ctx, orig, aug), nil
+}
+
+// computeFromReaders runs ComputeFromReaders without resource reporting
+func (ls *LengthSimilarity) computeFromReaders(ctx context.Context, original, augmented io.Reader) domain.Result {
+	origCounts, err := stream.CountNormalized(ctx, transform.Readers(original, ls.transforms), ls.normalizer, 0)
+	if err != nil {
+		return ls.readErrorResult("original", err)
+	}
+
+	augCounts, err := stream.CountNormalized(ctx, transform.Readers(augmented, ls.transforms), ls.normalizer, 0)
+	if err != nil {
+		return ls.readErrorResult("augmented", err)
+	}
+
+	return ls.scorer.ComputeCounts(origCounts.Words, augCounts.Words)
+}
+
+// now reads the clock to time a comparison for the metrics, if there are any
+func (ls *LengthSimilarity) now() time.Time {
+	if ls.metrics == nil {
+		return time.Time{}
+	}
+	return ls.clock.Now()
+}
+
+// finish stamps the engine and mode on result, fills its uncertainty and
+// records it to the metrics
+func (ls *LengthSimilarity) finish(result domain.Result, mode string, start time.Time) domain.Result {
+	result.Engine = domain.EngineWord
+	result.Mode = mode
+	if ls.metrics != nil {
+		outcome := metrics.Outcome(result.Passed, result.Inconclusive, result.Details["error"] != nil)
+		metrics.RecordComparison(ls.metrics, result.Engine, mode, outcome, ls.clock.Now().Sub(start))
+	}
+	return ls.withUncertainty(result)
+}
+
+// withUncertainty fills result.Uncertainty when uncertainty reporting is
+// enabled and the result carries a score
+func (ls *LengthSimilarity) withUncertainty(result domain.Result) domain.Result {
+	if ls.reportUncertainty && result.OriginalLength > 0 && !result.Inconclusive && result.Details["error"] == nil {
+		result.Uncertainty = domain.NewUncertainty(result.Score, result.Threshold, scoring.Margin(result.OriginalLength, ls.maxDiffRatio))
+	}
+	return result
+}
+
+// readErrorResult reports a failure to read one of the input streams
+func (ls *LengthSimilarity) readErrorResult(which string, err error) domain.Result {
+	ls.logger.Error("Error reading "+which+" stream", "error", err)
+	if domain.IsTruncated(err) {
+		return domain.Result{
+			Name:           "length_similarity",
+			Score:          0,
+			Passed:         false,
+			Details:        map[string]interface{}{"error": which + " stream was truncated: " + err.Error(), "truncated_input": which},
+			TruncatedInput: true,
+		}
+	}
+	return domain.Result{
+		Name:    "length_similarity",
+		Score:   0,
+		Passed:  false,
+		Details: map[string]interface{}{"error": "error reading " + which + " stream: " + err.Error()},
+	}
+}
+
+// WarmUp performs system warm-up to optimize performance and reports what it
+// did. Only the first call warms up; later calls return its report.
+func (ls *LengthSimilarity) WarmUp(ctx context.Context, config warmup.WarmupConfig) warmup.WarmupReport {
+	if ls.warmed != nil {
 		ls.logger.Debug("System already warmed up, skipping")
-		return
+		return *ls.warmed
 	}
 
 	warmupMgr := warmup.NewManager(ls.logger, config)
 	warmupMgr.RegisterCalculator(ls.calculator)
 	warmupMgr.RegisterNormalizer(ls.normalizer)
 
-	warmupMgr.WarmUp(ctx)
-	ls.warmed = true
+	report := warmupMgr.WarmUp(ctx)
+	if ls.metrics != nil {
+		ls.metrics.Gauge(metrics.WarmupSeconds, report.Duration.Seconds(), "engine", domain.EngineWord)
+	}
+	ls.warmed = &report
+	return report
+}
+
+// WarmupReport returns the report of the warm-up run, if there was one
+func (ls *LengthSimilarity) WarmupReport() (warmup.WarmupReport, bool) {
+	if ls.warmed == nil {
+		return warmup.WarmupReport{}, false
+	}
+	return *ls.warmed, true
 }
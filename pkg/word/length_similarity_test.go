@@ -0,0 +1,377 @@
+package word
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+	"unicode"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/degrade"
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+	"github.com/baditaflorin/go_length_similarity/pkg/metrics"
+	"github.com/baditaflorin/go_length_similarity/pkg/options"
+	"github.com/baditaflorin/go_length_similarity/pkg/similaritytest/fakeclock"
+	"github.com/baditaflorin/go_length_similarity/pkg/streaming"
+	"github.com/baditaflorin/l"
+)
+
+func discardLogger(t *testing.T) l.Logger {
+	t.Helper()
+	logger, err := l.NewStandardFactory().CreateLogger(l.Config{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestNewAcceptsDefaults(t *testing.T) {
+	if _, err := New(WithLogger(discardLogger(t))); err != nil {
+		t.Fatalf("default configuration must be valid: %v", err)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	cases := map[string]LengthSimilarityOption{
+		"threshold":         WithThreshold(1.5),
+		"maxDiffRatio":      WithMaxDiffRatio(0),
+		"minWords":          WithMinWords(0),
+		"minOriginalLength": WithMinOriginalLength(-1),
+		"originalCacheSize": WithOriginalCache(-1),
+		"latencySLO":        WithLatencySLO(-time.Second),
+	}
+	for field, opt := range cases {
+		_, err := New(WithLogger(discardLogger(t)), opt)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) || cfgErr.Field != field {
+			t.Errorf("expected ConfigError for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestOriginalCacheMatchesUncached(t *testing.T) {
+	plain, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := New(WithLogger(discardLogger(t)), WithOriginalCache(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	original := "<p>One source text, compared against <b>many</b> candidates.</p>"
+	for _, augmented := range []string{"one source text compared", "a much shorter one", original} {
+		// Twice, so the second call is served from the cache
+		for i := 0; i < 2; i++ {
+			got := cached.Compute(ctx, original, augmented)
+			want := plain.Compute(ctx, original, augmented)
+			if diffs := got.Diff(want, domain.DefaultEpsilon); len(diffs) > 0 {
+				t.Errorf("cached result differs from uncached: %v", diffs)
+			}
+		}
+	}
+}
+
+func TestLatencySLODegradesUnderPressure(t *testing.T) {
+	// Every call misses a 1ns target, so the governor must escalate
+	ls, err := New(WithLogger(discardLogger(t)), WithLatencySLO(time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	original, augmented := "the quick brown fox jumps", "the quick brown fox"
+	first := ls.Compute(ctx, original, augmented)
+	if first.Details["degraded"] != nil {
+		t.Fatalf("first call already degraded: %v", first.Details)
+	}
+
+	var last domain.Result
+	for i := 0; i < 100; i++ {
+		last = ls.Compute(ctx, original, augmented)
+	}
+	if last.Details["degraded"] != true || last.Details["degradation"] != "early_exit" {
+		t.Fatalf("expected early_exit degradation, got %v", last.Details)
+	}
+	// Without a deadline nothing is shed, and the fast path scores the same
+	if last.Score != first.Score {
+		t.Errorf("degraded score %v != %v", last.Score, first.Score)
+	}
+}
+
+func TestLatencySLOReadsTheClock(t *testing.T) {
+	ctx := context.Background()
+	run := func(step time.Duration) domain.Result {
+		clk := fakeclock.New(time.Unix(0, 0))
+		clk.SetStep(step)
+		ls, err := New(WithLogger(discardLogger(t)), WithLatencySLO(5*time.Millisecond), WithClock(clk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last domain.Result
+		for i := 0; i < 2*degrade.SustainedSamples+1; i++ {
+			last = ls.Compute(ctx, "the quick brown fox jumps", "the quick brown fox")
+		}
+		return last
+	}
+
+	// Every computation takes exactly one step on the clock
+	if r := run(10 * time.Millisecond); r.Details["degradation"] != "early_exit" {
+		t.Errorf("10ms per call against a 5ms SLO: %v, want early_exit", r.Details)
+	}
+	if r := run(time.Millisecond); r.Details["degraded"] != nil {
+		t.Errorf("1ms per call against a 5ms SLO: %v, want no degradation", r.Details)
+	}
+}
+
+func TestMetricsRecordComparisons(t *testing.T) {
+	ctx := context.Background()
+	clk := fakeclock.New(time.Unix(0, 0))
+	clk.SetStep(2 * time.Millisecond)
+	prom := metrics.NewPrometheus()
+	ls, err := New(WithLogger(discardLogger(t)), WithMetrics(prom), WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls.Compute(ctx, "the quick brown fox jumps", "the quick brown fox jumps")
+	ls.Compute(ctx, "the quick brown fox jumps over the lazy dog", "the quick brown fox jumps")
+	ls.ComputeFromReaders(ctx, strings.NewReader("one two three"), strings.NewReader("one two three"))
+
+	var buf bytes.Buffer
+	if _, err := prom.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`similarity_comparisons_total{engine="word",mode="text",outcome="passed"} 1`,
+		`similarity_comparisons_total{engine="word",mode="text",outcome="failed"} 1`,
+		`similarity_comparisons_total{engine="word",mode="stream",outcome="passed"} 1`,
+		// Each comparison reads the clock twice, one 2ms step apart
+		`similarity_compute_seconds_sum{engine="word",mode="text"} 0.004`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestResourceReport(t *testing.T) {
+	ls, err := New(WithLogger(discardLogger(t)), WithResourceReport(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := ls.Compute(context.Background(), "one two three four", "one two three")
+	if result.Resources == nil {
+		t.Fatal("Resources not reported")
+	}
+	if result.Resources.PeakBufferBytes == 0 || result.Resources.Workers != 1 {
+		t.Errorf("unexpected resources %+v", *result.Resources)
+	}
+
+	plain, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Compute(context.Background(), "one two three", "one two three").Resources != nil {
+		t.Error("Resources reported without WithResourceReport")
+	}
+}
+
+func TestUncertaintyShrinksWithLength(t *testing.T) {
+	ls, err := New(WithLogger(discardLogger(t)), WithUncertainty(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	short := ls.Compute(ctx, "one two three four", "one two three four five")
+	if short.Uncertainty == nil || !short.Uncertainty.NearThreshold {
+		t.Fatalf("short texts: %+v, want an uncertainty spanning the threshold", short.Uncertainty)
+	}
+
+	long := ls.Compute(ctx, strings.Repeat("word ", 100), strings.Repeat("word ", 95))
+	if long.Uncertainty == nil || long.Uncertainty.NearThreshold {
+		t.Fatalf("long texts: %+v, want a clear verdict", long.Uncertainty)
+	}
+	if long.Uncertainty.Margin >= short.Uncertainty.Margin {
+		t.Errorf("margin %v for 100 words is not below %v for 4 words", long.Uncertainty.Margin, short.Uncertainty.Margin)
+	}
+
+	// Results without a score carry no uncertainty
+	if r := ls.Compute(ctx, "one", "one"); r.Uncertainty != nil {
+		t.Errorf("insufficient text reported uncertainty %+v", r.Uncertainty)
+	}
+}
+
+func TestMinOriginalLengthIsInconclusive(t *testing.T) {
+	ls, err := New(WithLogger(discardLogger(t)), WithMinOriginalLength(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	r := ls.Compute(ctx, "one two three four", "one two three four")
+	if !r.Inconclusive || r.Passed {
+		t.Errorf("4-word original: %+v, want inconclusive and not passed", r)
+	}
+	if _, isError := r.Details["error"]; isError {
+		t.Errorf("inconclusive result reported an error: %v", r.Details)
+	}
+	if s := ls.ComputeFromReaders(ctx, strings.NewReader("one two three four"), strings.NewReader("one")); !s.Inconclusive {
+		t.Errorf("streamed 4-word original: %+v, want inconclusive", s)
+	}
+
+	if r := ls.Compute(ctx, "one two three four five", "one two three four five"); r.Inconclusive || !r.Passed {
+		t.Errorf("5-word original: %+v, want a scored pass", r)
+	}
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	ls, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Compute(context.Background(), "one two three", "one two")
+
+	if err := ls.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := ls.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	ls, err := New(
+		WithThreshold(0.5),
+		WithOptions(options.WithThreshold(0.9), options.WithMaxDiffRatio(0.5), options.WithLogger(discardLogger(t))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ls.Compute(context.Background(), "one two three four", "one two three")
+	if result.Threshold != 0.9 {
+		t.Errorf("threshold = %v, want the shared option's 0.9", result.Threshold)
+	}
+	// 1 - 0.25/0.5
+	if !domain.ApproxEqual(result.Score, 0.5, domain.DefaultEpsilon) {
+		t.Errorf("score = %v, want 0.5 with a max diff ratio of 0.5", result.Score)
+	}
+}
+
+func TestWordClassifierMatchesStreamingWords(t *testing.T) {
+	ctx := context.Background()
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '#'
+	}
+	text := "Rename snake_case_name to #refactor, then ship v2-final."
+
+	ls, err := New(WithLogger(discardLogger(t)), WithWordClassifier(isWord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rename snake_case_name to #refactor then ship v2 final
+	const want = 8
+	if got := ls.Compute(ctx, text, text).OriginalLength; got != want {
+		t.Errorf("Compute counted %d words, want %d", got, want)
+	}
+	if got := ls.ComputeFromReaders(ctx, iotest.OneByteReader(strings.NewReader(text)), strings.NewReader(text)).OriginalLength; got != want {
+		t.Errorf("ComputeFromReaders counted %d words, want %d", got, want)
+	}
+
+	for _, chunkSize := range []int{0, 3} {
+		ss, err := streaming.NewStreamingSimilarity(
+			streaming.WithStreamingLogger(discardLogger(t)),
+			streaming.WithStreamingMode(streaming.WordByWord),
+			streaming.WithStreamingChunkSize(chunkSize),
+			streaming.WithOptions(options.WithWordClassifier(isWord)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ss.ComputeFromStrings(ctx, text, text).OriginalLength; got != want {
+			t.Errorf("streaming with %d byte chunks counted %d words, want %d", chunkSize, got, want)
+		}
+	}
+
+	// The default boundaries split identifiers and drop the hashtag sign
+	plain, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plain.Compute(ctx, text, text).OriginalLength; got != 10 {
+		t.Errorf("default normalizer counted %d words, want 10", got)
+	}
+}
+
+func TestJoinedHyphenation(t *testing.T) {
+	ctx := context.Background()
+	clean := "the committee reviewed the preliminary examination results"
+	ocr := "the com-\nmittee reviewed the pre-\r\n  liminary exam-\nination results"
+
+	plain, err := New(WithLogger(discardLogger(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := plain.Compute(ctx, clean, ocr); result.AugmentedLength == result.OriginalLength {
+		t.Fatalf("expected the hyphenated copy to count more words, got %d", result.AugmentedLength)
+	}
+
+	ls, err := New(WithLogger(discardLogger(t)), WithJoinedHyphenation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ls.Compute(ctx, clean, ocr)
+	if result.OriginalLength != 7 || result.AugmentedLength != 7 {
+		t.Errorf("got %d/%d words, want 7/7", result.OriginalLength, result.AugmentedLength)
+	}
+	fromReaders := ls.ComputeFromReaders(ctx, strings.NewReader(clean), iotest.OneByteReader(strings.NewReader(ocr)))
+	if fromReaders.AugmentedLength != result.AugmentedLength {
+		t.Errorf("readers counted %d words, strings %d", fromReaders.AugmentedLength, result.AugmentedLength)
+	}
+}
+
+func TestStrippedSubtitles(t *testing.T) {
+	ctx := context.Background()
+	transcript := "Hello there.\nGeneral Kenobi!\n"
+	srt := "1\n00:00:01,000 --> 00:00:04,000\nHello there.\n\n2\n00:00:05,500 --> 00:00:07,250\nGeneral Kenobi!\n"
+
+	ls, err := New(WithLogger(discardLogger(t)), WithStrippedSubtitles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ls.Compute(ctx, transcript, srt)
+	if result.OriginalLength != 4 || result.AugmentedLength != 4 {
+		t.Errorf("got %d/%d words, want 4/4", result.OriginalLength, result.AugmentedLength)
+	}
+	fromReaders := ls.ComputeFromReaders(ctx, strings.NewReader(transcript), iotest.OneByteReader(strings.NewReader(srt)))
+	if fromReaders.AugmentedLength != result.AugmentedLength {
+		t.Errorf("readers counted %d words, strings %d", fromReaders.AugmentedLength, result.AugmentedLength)
+	}
+}
+
+func TestLogNormalizerCountsAlikeFromReaders(t *testing.T) {
+	ctx := context.Background()
+	capture := strings.Repeat("2024-03-01T12:00:05.120Z WARN retry=3 peer=[2001:db8::1]:443 after 1.5s\n", 20)
+
+	ls, err := New(WithLogger(discardLogger(t)), WithLogNormalizer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ls.Compute(ctx, capture, capture)
+	// TIMESTAMP WARN retry=NUM peer=IP after NUM
+	if result.OriginalLength != 6*20 {
+		t.Errorf("Compute counted %d words, want %d", result.OriginalLength, 6*20)
+	}
+	fromReaders := ls.ComputeFromReaders(ctx, iotest.OneByteReader(strings.NewReader(capture)), strings.NewReader(capture))
+	if fromReaders.OriginalLength != result.OriginalLength {
+		t.Errorf("readers counted %d words, strings %d", fromReaders.OriginalLength, result.OriginalLength)
+	}
+}
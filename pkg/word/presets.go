@@ -0,0 +1,25 @@
+package word
+
+import "github.com/baditaflorin/go_length_similarity/internal/adapters/normalizer"
+
+// Strict is a preset for near-verbatim rewrites: the augmented text may differ
+// from the original by at most 10% of its words and must score at least 0.9.
+// Later options override individual settings.
+func Strict() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Threshold = 0.9
+		cfg.MaxDiffRatio = 0.1
+		cfg.Normalizer = normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.OptimizedNormalizerType)
+	}
+}
+
+// Lenient is a preset for summaries and paraphrases where the length may
+// change by up to half and a score of 0.5 passes. Later options override
+// individual settings.
+func Lenient() LengthSimilarityOption {
+	return func(cfg *lengthSimilarityConfig) {
+		cfg.Threshold = 0.5
+		cfg.MaxDiffRatio = 0.5
+		cfg.Normalizer = normalizer.NewNormalizerFactory().CreateNormalizer(normalizer.FastNormalizerType)
+	}
+}
@@ -0,0 +1,34 @@
+package word
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPresetsResolveToDocumentedSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		preset                  LengthSimilarityOption
+		threshold, maxDiffRatio float64
+	}{
+		{"Strict", Strict(), 0.9, 0.1},
+		{"Lenient", Lenient(), 0.5, 0.5},
+	} {
+		var cfg lengthSimilarityConfig
+		tc.preset(&cfg)
+		if cfg.Threshold != tc.threshold || cfg.MaxDiffRatio != tc.maxDiffRatio || cfg.Normalizer == nil {
+			t.Errorf("%s: threshold %v, ratio %v, normalizer %v; want %v, %v and a normalizer",
+				tc.name, cfg.Threshold, cfg.MaxDiffRatio, cfg.Normalizer, tc.threshold, tc.maxDiffRatio)
+		}
+	}
+}
+
+func TestPresetIsOverriddenByLaterOptions(t *testing.T) {
+	ls, err := New(WithLogger(discardLogger(t)), Strict(), WithThreshold(0.8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := ls.Compute(context.Background(), "one two three", "one two three"); result.Threshold != 0.8 {
+		t.Errorf("threshold %v, want the later option's 0.8", result.Threshold)
+	}
+}
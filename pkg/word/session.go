@@ -0,0 +1,44 @@
+package word
+
+import (
+	"context"
+
+	"github.com/baditaflorin/go_length_similarity/internal/core/domain"
+)
+
+// Session compares pairs on a single goroutine, reusing buffers it owns
+// across calls instead of taking them from shared pools. Use one per worker in
+// tight loops over many short pairs. A Session is not safe for concurrent use.
+type Session struct {
+	ls        *LengthSimilarity
+	original  []byte
+	augmented []byte
+}
+
+// NewSession returns a Session that scores with ls's configuration
+func (ls *LengthSimilarity) NewSession() *Session {
+	return &Session{ls: ls}
+}
+
+// Compute scores original against augmented with the same result as
+// LengthSimilarity.Compute. It bypasses the originals cache, latency
+// degradation, resource reporting and debug logging.
+func (s *Session) Compute(ctx context.Context, original, augmented string) domain.Result {
+	if ctx.Err() != nil {
+		return domain.Result{
+			Name:    "length_similarity",
+			Engine:  domain.EngineWord,
+			Mode:    domain.ModeText,
+			Score:   0,
+			Passed:  false,
+			Details: map[string]interface{}{"error": "computation cancelled"},
+		}
+	}
+
+	start := s.ls.now()
+	var origLen, augLen int
+	origLen, s.original = s.ls.counter.CountWords(original, s.original)
+	augLen, s.augmented = s.ls.counter.CountWords(augmented, s.augmented)
+
+	return s.ls.finish(s.ls.scorer.ComputeCounts(origLen, augLen), domain.ModeText, start)
+}
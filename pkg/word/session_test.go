@@ -0,0 +1,39 @@
+package word
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessionMatchesCompute(t *testing.T) {
+	pairs := [][2]string{
+		{"The quick brown fox jumps", "the quick, brown fox!"},
+		{"<p>Shared <nav>menu</nav> content here</p>", "shared content here too"},
+		{"ÉCOLE d'été — très bien", "école dété très bien fait"},
+		{"one two", "one two three"},
+		{"", "anything"},
+	}
+	normalizers := map[string]LengthSimilarityOption{
+		"default":   WithNormalizer(nil),
+		"fast":      WithFastNormalizer(),
+		"optimized": WithOptimizedNormalizer(),
+	}
+
+	ctx := context.Background()
+	for name, opt := range normalizers {
+		ls, err := New(WithLogger(discardLogger(t)), opt, WithNormalizedLineEndings())
+		if err != nil {
+			t.Fatal(err)
+		}
+		session := ls.NewSession()
+		// Twice, so the second pass runs on reused buffers
+		for range 2 {
+			for _, p := range pairs {
+				want := ls.Compute(ctx, p[0], p[1])
+				if diff := session.Compute(ctx, p[0], p[1]).Diff(want, 0); diff != nil {
+					t.Errorf("%s %q: session differs from Compute: %v", name, p, diff)
+				}
+			}
+		}
+	}
+}